@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/utils/query"
+	"senkou-catalyst-be/utils/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AnalyticsController struct {
+	AnalyticsService services.AnalyticsService
+}
+
+func NewAnalyticsController(analyticsService services.AnalyticsService) *AnalyticsController {
+	return &AnalyticsController{
+		AnalyticsService: analyticsService,
+	}
+}
+
+// Get payment analytics
+// @Summary Get payment analytics
+// @Description Get revenue, refunds, MRR, paid subscribers, churn and conversion per time bucket, broken down by plan or payment method
+// @Tags Analytics
+// @Produce json
+// @Security BearerAuth
+// @Param date_from query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param date_to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Param interval query string false "Bucket size: day, week or month"
+// @Param group_by query string false "Breakdown: none, plan, payment_type or payment_channel"
+// @Success 200 {object} fiber.Map{message=string,data=dtos.PaymentAnalyticsReport}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /analytics/payments [get]
+func (h *AnalyticsController) GetPaymentAnalytics(c *fiber.Ctx) error {
+	report, appError := h.AnalyticsService.GetPaymentAnalytics(query.ParseQueryParams(c))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, "Failed to get payment analytics", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment analytics retrieved successfully",
+		"data":    report,
+	})
+}
+
+// Export payment analytics
+// @Summary Export payment analytics as CSV
+// @Description Export the payment analytics rows as a CSV file, accepting the same filters as the report
+// @Tags Analytics
+// @Produce text/csv
+// @Security BearerAuth
+// @Param date_from query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param date_to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Param interval query string false "Bucket size: day, week or month"
+// @Param group_by query string false "Breakdown: none, plan, payment_type or payment_channel"
+// @Success 200 {file} file "Payment analytics CSV"
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /analytics/payments/export [get]
+func (h *AnalyticsController) ExportPaymentAnalytics(c *fiber.Ctx) error {
+	file, filename, appError := h.AnalyticsService.ExportPaymentAnalytics(query.ParseQueryParams(c))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, "Failed to export payment analytics", appError.Details)
+		}
+	}
+
+	c.Set("Content-Type", "text/csv")
+	c.Set("Content-Disposition", "attachment; filename="+filename)
+	return c.Send(file)
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type AuthController struct {
@@ -60,7 +61,7 @@ func (h *AuthController) Login(c *fiber.Ctx) error {
 		return response.Forbidden(c, "Email not verified. Please verify your email to proceed.")
 	}
 
-	accessToken, refreshToken, appError := h.AuthService.GenerateToken(userID)
+	accessToken, refreshToken, appError := h.AuthService.GenerateToken(userID, c.Get(fiber.HeaderUserAgent), c.IP())
 
 	if appError != nil {
 		return response.InternalError(c, "Failed to generate token", appError.Details)
@@ -87,6 +88,7 @@ func (h *AuthController) Login(c *fiber.Ctx) error {
 // @Param request body dtos.RefreshTokenRequestDTO true "Request to refresh access token"
 // @Success 200 {object} dtos.LoginResponseDTO "Token refreshed successfully"
 // @Failure 400 {object} map[string]string "Validation failed"
+// @Failure 401 {object} map[string]string "Refresh token is invalid, expired or was already used"
 // @Router /auth/refresh [put]
 func (h *AuthController) RefreshToken(c *fiber.Ctx) error {
 	refreshTokenRequestDTO := new(dtos.RefreshTokenRequestDTO)
@@ -101,32 +103,19 @@ func (h *AuthController) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
-	userID, err := h.AuthService.ValidateRefreshToken(refreshTokenRequestDTO.RefreshToken)
-
-	if userID == 0 || err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid refresh token",
-		})
-	}
-
-	userID, refreshError := h.AuthService.ValidateRefreshToken(refreshTokenRequestDTO.RefreshToken)
+	// The refresh token is rotated on every use, presenting one that was already used ends its session
+	accessToken, refreshToken, refreshError := h.AuthService.RotateRefreshToken(refreshTokenRequestDTO.RefreshToken, c.Get(fiber.HeaderUserAgent), c.IP())
 
 	if refreshError != nil {
 		if refreshError.Code == 401 {
 			return response.Unauthorized(c, fmt.Sprintf("Cannot continue to update your token because of %v", refreshError.Details))
 		}
 
-		return response.InternalError(c, "Failed to validate refresh token", map[string]any{
+		return response.InternalError(c, "Failed to refresh token", map[string]any{
 			"error": refreshError.Details,
 		})
 	}
 
-	accessToken, refreshToken, tokenError := h.AuthService.GenerateToken(userID)
-
-	if tokenError != nil {
-		return response.InternalError(c, "Failed to generate token", tokenError.Details)
-	}
-
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Token refreshed successfully",
 		"data": dtos.LoginResponseDTO{
@@ -141,10 +130,11 @@ func (h *AuthController) RefreshToken(c *fiber.Ctx) error {
 // Logout user
 // @Summary Logout user
 // @Version 1.0
-// @Description Logout user by invalidating their session
+// @Description Logout user by invalidating the session of the current device, other devices stay logged in
 // @Tags Auth
 // @Security BearerAuth
 // @Success 200 {object} map[string]string "Logout successful response"
+// @Failure 404 {object} map[string]string "Session not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /auth/logout [delete]
 func (h *AuthController) Logout(c *fiber.Ctx) error {
@@ -155,13 +145,102 @@ func (h *AuthController) Logout(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Cannot continue to logout user", "User ID is not valid")
 	}
 
-	if err := h.AuthService.InvalidateSession(uint32(userID)); err != nil {
-		return response.InternalError(c, "Failed to logout user", map[string]any{
-			"error": err.Details,
-		})
+	sessionID, err := currentSessionID(c)
+
+	if err != nil {
+		return response.BadRequest(c, "Cannot continue to logout user", "Session ID is not valid")
+	}
+
+	if appError := h.AuthService.InvalidateSession(uint32(userID), sessionID); appError != nil {
+		switch appError.Code {
+		case 404:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to logout user", map[string]any{
+				"error": appError.Details,
+			})
+		}
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Logout successful",
 	})
 }
+
+// Forgot password
+// @Summary Request a password reset link
+// @Version 1.0
+// @Description Send a password reset link to the email, the response is the same whether or not the email is registered
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.ForgotPasswordDTO true "Email of the account"
+// @Success 200 {object} map[string]string "Password reset requested"
+// @Failure 400 {object} map[string]string "Validation failed"
+// @Failure 429 {object} map[string]string "Too many requests"
+// @Router /auth/password/forgot [post]
+func (h *AuthController) ForgotPassword(c *fiber.Ctx) error {
+	forgotPasswordDTO := new(dtos.ForgotPasswordDTO)
+
+	if err := validator.Validate(c, forgotPasswordDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.InternalError(c, "Internal server error", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	if appError := h.AuthService.ForgotPassword(forgotPasswordDTO.Email, c.IP()); appError != nil {
+		return response.InternalError(c, "Failed to request password reset", appError.Details)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "If the email is registered, a password reset link has been sent to it",
+	})
+}
+
+// Reset password
+// @Summary Reset password
+// @Version 1.0
+// @Description Choose a new password with the token from a password reset email, every device of the user is logged out
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dtos.ResetPasswordDTO true "Password reset token and the new password"
+// @Success 200 {object} map[string]string "Password reset successfully"
+// @Failure 400 {object} map[string]string "Invalid or expired token"
+// @Failure 429 {object} map[string]string "Too many requests"
+// @Router /auth/password/reset [post]
+func (h *AuthController) ResetPassword(c *fiber.Ctx) error {
+	resetPasswordDTO := new(dtos.ResetPasswordDTO)
+
+	if err := validator.Validate(c, resetPasswordDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.InternalError(c, "Internal server error", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	if appError := h.AuthService.ResetPassword(resetPasswordDTO); appError != nil {
+		switch appError.Code {
+		case 400:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, "Failed to reset password", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Password reset successfully, please log in with your new password",
+	})
+}
+
+// Get the ID of the session the access token of the request was issued for
+func currentSessionID(c *fiber.Ctx) (uuid.UUID, error) {
+	return uuid.Parse(fmt.Sprintf("%v", c.Locals("sessionID")))
+}
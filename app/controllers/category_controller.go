@@ -35,6 +35,7 @@ func NewCategoryController(categoryService services.CategoryService, merchantSer
 // @Param CreateCategoryDTO body dtos.CreateCategoryDTO true "Create Category DTO"
 // @Success 200 {object} fiber.Map{data=fiber.Map{category=models.Category}}
 // @Failure 400 {object} fiber.Map{message=string, errors=[]string}
+// @Failure 403 {object} fiber.Map{message=string, error=any}
 // @Failure 500 {object} fiber.Map{message=string, error=string}
 // @Router /merchants/{merchantID}/categories [post]
 func (h *CategoryController) CreateCategory(c *fiber.Ctx) error {
@@ -66,7 +67,17 @@ func (h *CategoryController) CreateCategory(c *fiber.Ctx) error {
 
 	category, appError := h.CategoryService.CreateNewCategory(createCategoryDTO, merchantID)
 	if appError != nil {
-		return response.InternalError(c, "Cannot create the category due to internal error", appError.Details)
+		switch appError.Code {
+		case fiber.StatusForbidden:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": appError.Message,
+				"error":   appError.Details,
+			})
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Cannot create the category due to internal error", appError.Details)
+		}
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -88,6 +99,7 @@ func (h *CategoryController) CreateCategory(c *fiber.Ctx) error {
 // @Param CreateCategoryByMerchantUsernameDTO body dtos.CreateCategoryByMerchantUsernameDTO true "Create Category by Merchant Username DTO"
 // @Success 200 {object} fiber.Map{data=fiber.Map{category=models.Category}}
 // @Failure 400 {object} fiber.Map{message=string, errors=[]string}
+// @Failure 403 {object} fiber.Map{message=string, error=any}
 // @Failure 500 {object} fiber.Map{message=string, error=string}
 // @Router /merchants/username/{username}/categories [post]
 func (h *CategoryController) CreateCategoryWithMerchantUsername(c *fiber.Ctx) error {
@@ -118,7 +130,17 @@ func (h *CategoryController) CreateCategoryWithMerchantUsername(c *fiber.Ctx) er
 
 	category, appError := h.CategoryService.CreateCategoryWithMerchantUsername(request.Name, username)
 	if appError != nil {
-		return response.InternalError(c, "Cannot create the category due to internal error", appError.Details)
+		switch appError.Code {
+		case fiber.StatusForbidden:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": appError.Message,
+				"error":   appError.Details,
+			})
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Cannot create the category due to internal error", appError.Details)
+		}
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
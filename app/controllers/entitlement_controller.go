@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"fmt"
+	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/utils/response"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EntitlementController struct {
+	EntitlementService services.EntitlementService
+}
+
+func NewEntitlementController(entitlementService services.EntitlementService) *EntitlementController {
+	return &EntitlementController{
+		EntitlementService: entitlementService,
+	}
+}
+
+// Get the entitlements of the authenticated user
+// @Summary Get my entitlements
+// @Description Get every plan limit of the current subscription next to its current usage, including the products hidden from the storefront because they are over quota
+// @Tags Subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string,data=dtos.UserEntitlementsDTO}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /users/me/entitlements [get]
+func (h *EntitlementController) GetMyEntitlements(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		return response.Unauthorized(c, "You are not authorized to access this resource")
+	}
+
+	usage, appError := h.EntitlementService.GetEntitlementUsage(uint32(userID))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.Unauthorized(c, "You are not authorized to access this resource")
+		default:
+			return response.InternalError(c, "Failed to get entitlements", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Entitlements retrieved successfully",
+		"data":    usage,
+	})
+}
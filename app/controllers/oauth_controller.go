@@ -99,7 +99,7 @@ func loginAndRedirect(ctx *fiber.Ctx, authService services.AuthService, user *mo
 			return response.InternalError(ctx, "Failed to generate user session", fmt.Sprintf("Invalid key length: got %d bytes, need 32 bytes", len(appKey)))
 		}
 
-		accessToken, refreshToken, appError := authService.GenerateToken(user.ID)
+		accessToken, refreshToken, appError := authService.GenerateToken(user.ID, ctx.Get(fiber.HeaderUserAgent), ctx.IP())
 		if appError != nil {
 			return response.InternalError(ctx, "Failed to generate user session", nil)
 		}
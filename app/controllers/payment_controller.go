@@ -5,76 +5,234 @@ import (
 	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
 	"senkou-catalyst-be/app/services"
-	"senkou-catalyst-be/integrations/midtrans"
-	"senkou-catalyst-be/utils/converter"
+	"senkou-catalyst-be/integrations/gateway"
 	"senkou-catalyst-be/utils/response"
-	"time"
+	"senkou-catalyst-be/utils/validator"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type PaymentController struct {
-	PaymentService services.PaymentService
+	PaymentService             services.PaymentService
+	PaymentNotificationService services.PaymentNotificationService
+	SubscriptionOrderService   services.SubscriptionOrderService
 }
 
-func NewPaymentController(paymentService services.PaymentService) *PaymentController {
+func NewPaymentController(
+	paymentService services.PaymentService,
+	paymentNotificationService services.PaymentNotificationService,
+	subscriptionOrderService services.SubscriptionOrderService,
+) *PaymentController {
 	return &PaymentController{
-		PaymentService: paymentService,
+		PaymentService:             paymentService,
+		PaymentNotificationService: paymentNotificationService,
+		SubscriptionOrderService:   subscriptionOrderService,
 	}
 }
 
-func (p *PaymentController) PaymentNotifications(c *fiber.Ctx) error {
-	midtransNotification := new(dtos.BaseMidtransNotification)
+// Midtrans payment notifications
+// @Summary Receive Midtrans payment notifications
+// @Description Apply a signed Midtrans payment notification to its transaction and subscription order
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Success 200 {object} fiber.Map{status=string,message=string}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /api/v1/payments/notifications/midtrans [post]
+func (p *PaymentController) MidtransNotifications(c *fiber.Ctx) error {
+	return p.handleNotification(c, gateway.ProviderMidtrans)
+}
 
-	if err := c.BodyParser(midtransNotification); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Invalid request body: " + err.Error(),
-		})
-	}
+// Xendit payment notifications
+// @Summary Receive Xendit payment notifications
+// @Description Apply a Xendit payment callback carrying the callback token to its transaction and subscription order
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Success 200 {object} fiber.Map{status=string,message=string}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /api/v1/payments/notifications/xendit [post]
+func (p *PaymentController) XenditNotifications(c *fiber.Ctx) error {
+	return p.handleNotification(c, gateway.ProviderXendit)
+}
 
-	var settlementTime, transactionTime *time.Time
+// Fake payment notifications
+// @Summary Receive fake payment notifications
+// @Description Apply a notification of the local fake gateway, only available when the fake gateway is enabled
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Success 200 {object} fiber.Map{status=string,message=string}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Router /api/v1/payments/notifications/fake [post]
+func (p *PaymentController) FakeNotifications(c *fiber.Ctx) error {
+	return p.handleNotification(c, gateway.ProviderFake)
+}
 
-	if midtransNotification.SettlementTime != "" {
-		convertedTime, err := converter.ParseMidtransTime(midtransNotification.SettlementTime)
-		if err != nil {
-			fmt.Println(err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Invalid settlement time: " + err.Error(),
-			})
+// Store a notification of a payment provider, verify it and apply it to the subscription order
+// Duplicates and notifications that would move the payment backward are acknowledged so they are not retried
+func (p *PaymentController) handleNotification(c *fiber.Ctx, provider gateway.Provider) error {
+	headers := make(map[string]string)
+	for name, values := range c.GetReqHeaders() {
+		if len(values) > 0 {
+			headers[name] = values[0]
+		}
+	}
+
+	notification, err := p.PaymentNotificationService.HandleNotification(provider, &gateway.NotificationRequest{
+		Body:    c.Body(),
+		Headers: headers,
+	})
+	if err != nil {
+		switch err.Code {
+		case fiber.StatusUnauthorized:
+			return response.Unauthorized(c, err.Message)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, err.Message)
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, err.Message, err.Details)
+		default:
+			return response.InternalError(c, "Failed to update payment transaction", err.Details)
 		}
+	}
 
-		settlementTime = &convertedTime
+	message := "Successfully update transaction"
+	switch notification.Status {
+	case models.PaymentNotificationStatusDuplicate:
+		message = "Notification already processed"
+	case models.PaymentNotificationStatusIgnored:
+		message = "Notification ignored"
 	}
 
-	if midtransNotification.TransactionTime != "" {
-		convertedTime, err := converter.ParseMidtransTime(midtransNotification.TransactionTime)
-		if err != nil {
-			fmt.Println(err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Invalid transaction time: " + err.Error(),
-			})
+	return c.Status(200).JSON(fiber.Map{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// Get payment notifications
+// @Summary Get the notifications of a payment
+// @Description Get every raw notification received for a payment transaction along with its processing outcome
+// @Tags Payment
+// @Produce json
+// @Security BearerAuth
+// @Param transactionID path string true "Payment transaction ID"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{notifications=[]models.PaymentNotification}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payments/{transactionID}/notifications [get]
+func (p *PaymentController) GetPaymentNotifications(c *fiber.Ctx) error {
+	notifications, err := p.PaymentNotificationService.GetTransactionNotifications(c.Params("transactionID"))
+	if err != nil {
+		switch err.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, err.Message, err.Details)
+		default:
+			return response.InternalError(c, "Failed to get payment notifications", err.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment notifications retrieved successfully",
+		"data": fiber.Map{
+			"notifications": notifications,
+		},
+	})
+}
+
+// Replay a payment notification
+// @Summary Replay a stored payment notification
+// @Description Apply a stored payment notification again, the payment status still never moves backward
+// @Tags Payment
+// @Produce json
+// @Security BearerAuth
+// @Param notificationID path string true "Payment notification ID"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{notification=models.PaymentNotification}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payments/notifications/{notificationID}/replay [post]
+func (p *PaymentController) ReplayPaymentNotification(c *fiber.Ctx) error {
+	notification, err := p.PaymentNotificationService.ReplayNotification(c.Params("notificationID"))
+	if err != nil {
+		switch err.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, err.Message, err.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, err.Message)
+		default:
+			return response.InternalError(c, "Failed to replay payment notification", err.Details)
 		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment notification replayed successfully",
+		"data": fiber.Map{
+			"notification": notification,
+		},
+	})
+}
 
-		transactionTime = &convertedTime
+// Refund a payment
+// @Summary Refund a payment
+// @Description Refund a settled payment fully or partially and shorten the subscription bought with it
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param transactionID path string true "Payment transaction ID"
+// @Param RefundPaymentDTO body dtos.RefundPaymentDTO true "Refund amount and reason, the remaining amount is refunded when amount is omitted"
+// @Success 201 {object} fiber.Map{message=string,data=fiber.Map{refund=models.PaymentRefund}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payments/{transactionID}/refunds [post]
+func (p *PaymentController) RefundPayment(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to refund payment", "Failed to parse user ID")
 	}
 
-	updatedPaymentTransaction := &models.PaymentTransaction{
-		Status:          string(midtrans.ParseStatus(midtransNotification.TransactionStatus)),
-		FraudStatus:     midtransNotification.FraudStatus,
-		SignatureKey:    &midtransNotification.SignatureKey,
-		TransactionTime: transactionTime,
-		SettledAt:       settlementTime,
+	refundRequest := new(dtos.RefundPaymentDTO)
+
+	if err := validator.Validate(c, refundRequest); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.BadRequest(c, "Validation failed", map[string]any{
+				"errors": vErr.Errors,
+			})
+		}
+
+		return response.InternalError(c, "Internal server error", map[string]any{
+			"error": err.Error(),
+		})
 	}
 
-	if err := p.PaymentService.UpdatePayment(midtransNotification.TransactionID, updatedPaymentTransaction); err != nil {
-		return response.InternalError(c, "Failed to update payment transaction", err.Error())
+	refund, appError := p.SubscriptionOrderService.RefundPaymentTransaction(c.Params("transactionID"), refundRequest, uint32(userID))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to refund payment", appError.Details)
+		}
 	}
 
-	return c.Status(200).JSON(fiber.Map{
-		"status":  "success",
-		"message": "Successfully update transaction",
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Payment refunded successfully",
+		"data": fiber.Map{
+			"refund": refund,
+		},
 	})
 }
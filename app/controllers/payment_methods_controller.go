@@ -1,8 +1,14 @@
 package controllers
 
 import (
+	"fmt"
+	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/services"
 	"senkou-catalyst-be/utils/response"
+	"senkou-catalyst-be/utils/storage"
+	"senkou-catalyst-be/utils/validator"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -19,7 +25,7 @@ func NewPaymentMethodsController(paymentMethodsService services.PaymentMethodsSe
 
 // GetAllAvailablePaymentMethods retrieves all available payment methods
 // @Summary Get all available payment methods
-// @Description Get all available payment methods
+// @Description Get the enabled payment methods that are not under maintenance, sorted by their display order
 // @Tags Payment Methods
 // @Success 200 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -82,3 +88,304 @@ func (pc *PaymentMethodsController) GetPaymentMethodTypes(c *fiber.Ctx) error {
 		},
 	})
 }
+
+// GetPaymentMethods retrieves every payment method for admins
+// @Summary Get every payment method
+// @Description Get every payment method including the disabled ones and their upcoming maintenance windows
+// @Tags Payment Methods
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{payment_methods=[]models.PaymentMethod}}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payment-methods/manage [get]
+func (pc *PaymentMethodsController) GetPaymentMethods(c *fiber.Ctx) error {
+	methods, appError := pc.PaymentMethodsService.GetPaymentMethods()
+	if appError != nil {
+		return response.InternalError(c, "Failed to retrieve payment methods", appError.Details)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment methods retrieved successfully",
+		"data": fiber.Map{
+			"payment_methods": methods,
+		},
+	})
+}
+
+// CreatePaymentMethod creates a new payment method
+// @Summary Create a payment method
+// @Description Create a new payment method, the payment channel must be unique
+// @Tags Payment Methods
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param CreatePaymentMethodDTO body dtos.CreatePaymentMethodDTO true "Create payment method request object"
+// @Success 201 {object} fiber.Map{message=string,data=fiber.Map{payment_method=models.PaymentMethod}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payment-methods/manage [post]
+func (pc *PaymentMethodsController) CreatePaymentMethod(c *fiber.Ctx) error {
+	createDTO := new(dtos.CreatePaymentMethodDTO)
+
+	if err := validator.Validate(c, createDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	method, appError := pc.PaymentMethodsService.CreatePaymentMethod(createDTO)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest, fiber.StatusConflict:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, "Failed to create payment method", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Payment method created successfully",
+		"data": fiber.Map{
+			"payment_method": method,
+		},
+	})
+}
+
+// UpdatePaymentMethod updates a payment method
+// @Summary Update a payment method
+// @Description Update the given fields of a payment method, e.g. enable or disable it, its display order or its fee
+// @Tags Payment Methods
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param methodID path int true "Payment method ID"
+// @Param UpdatePaymentMethodDTO body dtos.UpdatePaymentMethodDTO true "Update payment method request object"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{payment_method=models.PaymentMethod}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payment-methods/manage/{methodID} [put]
+func (pc *PaymentMethodsController) UpdatePaymentMethod(c *fiber.Ctx) error {
+	methodID, err := strconv.ParseUint(c.Params("methodID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid payment method ID", err.Error())
+	}
+
+	updateDTO := new(dtos.UpdatePaymentMethodDTO)
+
+	if err := validator.Validate(c, updateDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	method, appError := pc.PaymentMethodsService.UpdatePaymentMethod(uint32(methodID), updateDTO)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to update payment method", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment method updated successfully",
+		"data": fiber.Map{
+			"payment_method": method,
+		},
+	})
+}
+
+// DeletePaymentMethod deletes a payment method
+// @Summary Delete a payment method
+// @Description Delete a payment method along with its maintenance windows, disable it instead to keep it for later
+// @Tags Payment Methods
+// @Produce json
+// @Security BearerAuth
+// @Param methodID path int true "Payment method ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payment-methods/manage/{methodID} [delete]
+func (pc *PaymentMethodsController) DeletePaymentMethod(c *fiber.Ctx) error {
+	methodID, err := strconv.ParseUint(c.Params("methodID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid payment method ID", err.Error())
+	}
+
+	if appError := pc.PaymentMethodsService.DeletePaymentMethod(uint32(methodID)); appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to delete payment method", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment method deleted successfully",
+	})
+}
+
+// UploadPaymentMethodLogo uploads the logo of a payment method
+// @Summary Upload a payment method logo
+// @Description Upload the logo of a payment method, the previous uploaded logo is removed
+// @Tags Payment Methods
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param methodID path int true "Payment method ID"
+// @Param logo formData file true "Logo image"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{payment_method=models.PaymentMethod}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payment-methods/manage/{methodID}/logo [post]
+func (pc *PaymentMethodsController) UploadPaymentMethodLogo(c *fiber.Ctx) error {
+	methodID, err := strconv.ParseUint(c.Params("methodID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid payment method ID", err.Error())
+	}
+
+	logo, err := c.FormFile("logo")
+	if err != nil {
+		return response.BadRequest(c, "Failed to parse logo", err.Error())
+	}
+
+	if !storage.IsValidImageExtension(logo.Filename) {
+		return response.BadRequest(c, "Invalid image format", fmt.Sprintf("File %s has an unsupported format", logo.Filename))
+	}
+
+	method, appError := pc.PaymentMethodsService.GetPaymentMethodByID(uint32(methodID))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to retrieve payment method", appError.Details)
+		}
+	}
+
+	logoPath, err := storage.UploadFileToStorage(logo, "payment-methods", "PM", nil)
+	if err != nil {
+		return response.InternalError(c, "Failed to upload payment method logo", err.Error())
+	}
+
+	previousLogo := method.LogoURL
+
+	method, appError = pc.PaymentMethodsService.UpdatePaymentMethod(uint32(methodID), &dtos.UpdatePaymentMethodDTO{
+		LogoURL: &logoPath,
+	})
+	if appError != nil {
+		storage.RemoveFileFromStorage(logoPath)
+		return response.InternalError(c, "Failed to update payment method", appError.Details)
+	}
+
+	// Logos seeded as external URLs are not stored by us, only uploaded logos are removed
+	if previousLogo != "" && !strings.HasPrefix(previousLogo, "http://") && !strings.HasPrefix(previousLogo, "https://") {
+		storage.RemoveFileFromStorage(previousLogo)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment method logo uploaded successfully",
+		"data": fiber.Map{
+			"payment_method": method,
+		},
+	})
+}
+
+// CreateMaintenanceWindow schedules a maintenance window of a payment method
+// @Summary Schedule a payment method maintenance
+// @Description Schedule a window in which the payment method is hidden from customers and cannot be charged
+// @Tags Payment Methods
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param methodID path int true "Payment method ID"
+// @Param CreateMaintenanceWindowDTO body dtos.CreateMaintenanceWindowDTO true "Maintenance window request object"
+// @Success 201 {object} fiber.Map{message=string,data=fiber.Map{maintenance_window=models.PaymentMethodMaintenanceWindow}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payment-methods/manage/{methodID}/maintenance-windows [post]
+func (pc *PaymentMethodsController) CreateMaintenanceWindow(c *fiber.Ctx) error {
+	methodID, err := strconv.ParseUint(c.Params("methodID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid payment method ID", err.Error())
+	}
+
+	windowDTO := new(dtos.CreateMaintenanceWindowDTO)
+
+	if err := validator.Validate(c, windowDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	window, appError := pc.PaymentMethodsService.CreateMaintenanceWindow(uint32(methodID), windowDTO)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to create maintenance window", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Maintenance window created successfully",
+		"data": fiber.Map{
+			"maintenance_window": window,
+		},
+	})
+}
+
+// DeleteMaintenanceWindow removes a maintenance window of a payment method
+// @Summary Remove a payment method maintenance
+// @Description Remove a maintenance window, e.g. when the maintenance ended earlier than planned
+// @Tags Payment Methods
+// @Produce json
+// @Security BearerAuth
+// @Param methodID path int true "Payment method ID"
+// @Param windowID path int true "Maintenance window ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /payment-methods/manage/{methodID}/maintenance-windows/{windowID} [delete]
+func (pc *PaymentMethodsController) DeleteMaintenanceWindow(c *fiber.Ctx) error {
+	methodID, err := strconv.ParseUint(c.Params("methodID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid payment method ID", err.Error())
+	}
+
+	windowID, err := strconv.ParseUint(c.Params("windowID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid maintenance window ID", err.Error())
+	}
+
+	if appError := pc.PaymentMethodsService.DeleteMaintenanceWindow(uint32(methodID), uint32(windowID)); appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to delete maintenance window", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Maintenance window deleted successfully",
+	})
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	"log"
 	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/services"
 	"senkou-catalyst-be/utils/query"
@@ -38,6 +39,7 @@ func NewProductController(productService services.ProductService, userService se
 // @Param product body dtos.CreateProductDTO true "Product data"
 // @Success 201 {object} fiber.Map{message=string,data=fiber.Map{product=models.Product}}
 // @Failure 400 {object} fiber.Map{error=string,details=any}
+// @Failure 403 {object} fiber.Map{message=string,error=any}
 // @Failure 500 {object} fiber.Map{error=string,details=any}
 // @Router /products [post]
 func (h *ProductController) CreateProduct(c *fiber.Ctx) error {
@@ -95,6 +97,20 @@ func (h *ProductController) CreateProduct(c *fiber.Ctx) error {
 	createdProduct, appError := h.ProductService.CreateProduct(createProductDTO, user.Merchants[0].ID)
 
 	if appError != nil {
+		// The product was not stored, so its photos are not referenced anywhere
+		for _, photoPath := range photoPaths {
+			if err := storage.RemoveFileFromStorage(photoPath); err != nil {
+				log.Printf("Failed to remove photo %s of a product that was not created: %v", photoPath, err)
+			}
+		}
+
+		if appError.Code == fiber.StatusForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": appError.Message,
+				"error":   appError.Details,
+			})
+		}
+
 		return response.InternalError(c, "Failed to create product", appError.Details)
 	}
 
@@ -117,6 +133,7 @@ func (h *ProductController) CreateProduct(c *fiber.Ctx) error {
 // @Param photo formData file true "Product photo"
 // @Success 200 {object} fiber.Map{message=string}
 // @Failure 400 {object} fiber.Map{error=string,details=any}
+// @Failure 403 {object} fiber.Map{message=string,error=any}
 // @Failure 500 {object} fiber.Map{error=string,details=any}
 // @Router /products/{productID}/photos [post]
 func (h *ProductController) UploadProductPhoto(c *fiber.Ctx) error {
@@ -145,8 +162,6 @@ func (h *ProductController) UploadProductPhoto(c *fiber.Ctx) error {
 		}
 	} else if product == nil {
 		return response.NotFound(c, "Product not found")
-	} else if len(product.Photos) >= 5 {
-		return response.BadRequest(c, "Cannot upload more photos", "Maximum of 5 photos allowed per product")
 	}
 
 	photoPath, err := storage.UploadFileToStorage(photo, "products", "PD", nil)
@@ -154,10 +169,19 @@ func (h *ProductController) UploadProductPhoto(c *fiber.Ctx) error {
 		return response.InternalError(c, "Failed to upload product photo", err.Error())
 	}
 
-	product.Photos.AddPhoto(photoPath)
+	if appError := h.ProductService.AddProductPhoto(product, photoPath); appError != nil {
+		if err := storage.RemoveFileFromStorage(photoPath); err != nil {
+			log.Printf("Failed to remove photo %s that was not added to product %s: %v", photoPath, product.ID, err)
+		}
 
-	if err := h.ProductService.UpdateProductPhotos(product); err != nil {
-		return response.InternalError(c, "Failed to update product", err.Details)
+		if appError.Code == fiber.StatusForbidden {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": appError.Message,
+				"error":   appError.Details,
+			})
+		}
+
+		return response.InternalError(c, "Failed to update product", appError.Details)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -372,7 +396,7 @@ func (h *ProductController) GetProductByID(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Cannot continue to retrieve product information", "Product ID is required")
 	}
 
-	product, appError := h.ProductService.GetProductByID(productID)
+	product, appError := h.ProductService.GetPublishedProductByID(productID)
 
 	if appError != nil {
 		switch appError.Code {
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"fmt"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/utils/response"
+	"senkou-catalyst-be/utils/validator"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PromoCodeController struct {
+	PromoCodeService services.PromoCodeService
+}
+
+func NewPromoCodeController(promoCodeService services.PromoCodeService) *PromoCodeController {
+	return &PromoCodeController{
+		PromoCodeService: promoCodeService,
+	}
+}
+
+// ValidatePromoCode validates a promo code on a subscription
+// @Summary Validate a promo code
+// @Description Check the current user can redeem a promo code on a subscription and get the discounted price, PPN and the fee of the payment method are added on top of it
+// @Tags Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param ValidatePromoCodeDTO body dtos.ValidatePromoCodeDTO true "Promo code and subscription"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{promo_code=dtos.PromoCodeQuoteDTO}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /promo-codes/validate [post]
+func (pc *PromoCodeController) ValidatePromoCode(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot validate promo code", "Failed to parse user ID")
+	}
+
+	validateDTO := new(dtos.ValidatePromoCodeDTO)
+
+	if err := validator.Validate(c, validateDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	quote, appError := pc.PromoCodeService.QuotePromoCode(uint32(userID), validateDTO)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to validate promo code", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Promo code is valid",
+		"data": fiber.Map{
+			"promo_code": quote,
+		},
+	})
+}
+
+// GetPromoCodes retrieves every promo code for admins
+// @Summary Get every promo code
+// @Description Get every promo code and voucher including the inactive ones
+// @Tags Promo Codes
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{promo_codes=[]models.PromoCode}}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /promo-codes/manage [get]
+func (pc *PromoCodeController) GetPromoCodes(c *fiber.Ctx) error {
+	promoCodes, appError := pc.PromoCodeService.GetPromoCodes()
+	if appError != nil {
+		return response.InternalError(c, "Failed to retrieve promo codes", appError.Details)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Promo codes retrieved successfully",
+		"data": fiber.Map{
+			"promo_codes": promoCodes,
+		},
+	})
+}
+
+// CreatePromoCode creates a new promo code
+// @Summary Create a promo code
+// @Description Create a percent or fixed promo code, or a voucher that grants days of a subscription without a payment. The code must be unique
+// @Tags Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param CreatePromoCodeDTO body dtos.CreatePromoCodeDTO true "Create promo code request object"
+// @Success 201 {object} fiber.Map{message=string,data=fiber.Map{promo_code=models.PromoCode}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /promo-codes/manage [post]
+func (pc *PromoCodeController) CreatePromoCode(c *fiber.Ctx) error {
+	createDTO := new(dtos.CreatePromoCodeDTO)
+
+	if err := validator.Validate(c, createDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	promoCode, appError := pc.PromoCodeService.CreatePromoCode(createDTO)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest, fiber.StatusConflict:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, "Failed to create promo code", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Promo code created successfully",
+		"data": fiber.Map{
+			"promo_code": promoCode,
+		},
+	})
+}
+
+// UpdatePromoCode updates a promo code
+// @Summary Update a promo code
+// @Description Update the given fields of a promo code, e.g. deactivate it or change its limits. The code and its type cannot be changed
+// @Tags Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param promoID path int true "Promo code ID"
+// @Param UpdatePromoCodeDTO body dtos.UpdatePromoCodeDTO true "Update promo code request object"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{promo_code=models.PromoCode}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /promo-codes/manage/{promoID} [put]
+func (pc *PromoCodeController) UpdatePromoCode(c *fiber.Ctx) error {
+	promoID, err := strconv.ParseUint(c.Params("promoID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid promo code ID", err.Error())
+	}
+
+	updateDTO := new(dtos.UpdatePromoCodeDTO)
+
+	if err := validator.Validate(c, updateDTO); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	promoCode, appError := pc.PromoCodeService.UpdatePromoCode(uint32(promoID), updateDTO)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to update promo code", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Promo code updated successfully",
+		"data": fiber.Map{
+			"promo_code": promoCode,
+		},
+	})
+}
+
+// DeletePromoCode deletes a promo code
+// @Summary Delete a promo code
+// @Description Delete a promo code so it can no longer be redeemed, the orders that redeemed it keep their discount
+// @Tags Promo Codes
+// @Produce json
+// @Security BearerAuth
+// @Param promoID path int true "Promo code ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /promo-codes/manage/{promoID} [delete]
+func (pc *PromoCodeController) DeletePromoCode(c *fiber.Ctx) error {
+	promoID, err := strconv.ParseUint(c.Params("promoID"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid promo code ID", err.Error())
+	}
+
+	if appError := pc.PromoCodeService.DeletePromoCode(uint32(promoID)); appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to delete promo code", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Promo code deleted successfully",
+	})
+}
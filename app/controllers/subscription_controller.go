@@ -2,9 +2,13 @@ package controllers
 
 import (
 	"fmt"
+	"log"
 	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
 	"senkou-catalyst-be/app/services"
 	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/utils/query"
 	"senkou-catalyst-be/utils/response"
 	"senkou-catalyst-be/utils/validator"
 	"strconv"
@@ -13,18 +17,28 @@ import (
 )
 
 type SubscriptionController struct {
-	UserService              services.UserService
-	SubscriptionService      services.SubscriptionService
-	SubscriptionOrderService services.SubscriptionOrderService
-	PaymentService           services.PaymentService
+	UserService               services.UserService
+	SubscriptionService       services.SubscriptionService
+	SubscriptionOrderService  services.SubscriptionOrderService
+	SubscriptionChangeService services.SubscriptionChangeService
+	SubscriptionTrialService  services.SubscriptionTrialService
+	PromoCodeService          services.PromoCodeService
+	PaymentService            services.PaymentService
+	PaymentMethodsService     services.PaymentMethodsService
+	InvoiceService            services.InvoiceService
 }
 
-func NewSubscriptionController(userService services.UserService, subService services.SubscriptionService, subOrderService services.SubscriptionOrderService, paymentService services.PaymentService) *SubscriptionController {
+func NewSubscriptionController(userService services.UserService, subService services.SubscriptionService, subOrderService services.SubscriptionOrderService, subChangeService services.SubscriptionChangeService, subTrialService services.SubscriptionTrialService, promoCodeService services.PromoCodeService, paymentService services.PaymentService, paymentMethodsService services.PaymentMethodsService, invoiceService services.InvoiceService) *SubscriptionController {
 	return &SubscriptionController{
-		UserService:              userService,
-		SubscriptionService:      subService,
-		SubscriptionOrderService: subOrderService,
-		PaymentService:           paymentService,
+		UserService:               userService,
+		SubscriptionService:       subService,
+		SubscriptionOrderService:  subOrderService,
+		SubscriptionChangeService: subChangeService,
+		SubscriptionTrialService:  subTrialService,
+		PromoCodeService:          promoCodeService,
+		PaymentService:            paymentService,
+		PaymentMethodsService:     paymentMethodsService,
+		InvoiceService:            invoiceService,
 	}
 }
 
@@ -38,6 +52,7 @@ func NewSubscriptionController(userService services.UserService, subService serv
 // @Param CreateSubscriptionDTO body dtos.CreateSubscriptionDTO true "Create subscription request object"
 // @Success 201 {object} fiber.Map{message=string,data=fiber.Map{subscription=models.Subscription}}
 // @Failure 400 {object} fiber.Map{message=string,errors=[]string}
+// @Failure 409 {object} fiber.Map{message=string,error=any}
 // @Failure 500 {object} fiber.Map{message=string,error=string}
 // @Router /subscriptions [post]
 func (h *SubscriptionController) CreateSubscription(c *fiber.Ctx) error {
@@ -55,12 +70,9 @@ func (h *SubscriptionController) CreateSubscription(c *fiber.Ctx) error {
 		})
 	}
 
-	subscription, err := h.SubscriptionService.CreateNewSubscription(createSubscriptionDTO)
-
-	if err != nil && err.Code == 500 {
-		return response.InternalError(c, "Failed to create subscription", map[string]any{
-			"error": err.Details,
-		})
+	subscription, appError := h.SubscriptionService.CreateNewSubscription(createSubscriptionDTO)
+	if appError != nil {
+		return subscriptionCatalogError(c, appError, "Failed to create subscription")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -73,23 +85,23 @@ func (h *SubscriptionController) CreateSubscription(c *fiber.Ctx) error {
 
 // Create subscription plan
 // @Summary Create a subscription plan
-// @Description Create a new subscription plan for a subscription
+// @Description Add a feature to a subscription. The name must be an entitlement known to the registry and the value must match its kind. A subscription that was already bought gets a new version instead, its subscribers keep the version they bought until they renew
 // @Tags Subscription
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param planID path string true "Plan ID"
+// @Param subID path string true "Subscription ID"
 // @Param CreateSubscriptionPlanDTO body dtos.CreateSubscriptionPlanDTO true "Create subscription plan request object"
-// @Success 201 {object} fiber.Map{message=string}
+// @Success 201 {object} fiber.Map{message=string,data=fiber.Map{subscription=models.Subscription}}
 // @Failure 400 {object} fiber.Map{message=string,errors=[]string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 409 {object} fiber.Map{message=string,error=any}
 // @Failure 500 {object} fiber.Map{message=string,error=string}
-// @Router /subscriptions/{planID}/plans [post]
+// @Router /subscriptions/{subID}/plans [post]
 func (h *SubscriptionController) CreateSubscriptionPlan(c *fiber.Ctx) error {
-	planIDStr := fmt.Sprintf("%v", c.Locals("planID"))
-	planID, err := strconv.ParseUint(planIDStr, 10, 32)
-
-	if err != nil {
-		return response.BadRequest(c, "Cannot continue to create subscription plan", "Failed to parse subscription plan ID")
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to create subscription plan", "Failed to parse subscription ID")
 	}
 
 	planRequest := new(dtos.CreateSubscriptionPlanDTO)
@@ -106,31 +118,135 @@ func (h *SubscriptionController) CreateSubscriptionPlan(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.SubscriptionService.CreateSubscriptionPlan(planRequest, uint32(planID)); err != nil {
-		if err.Code == 400 {
-			return response.BadRequest(c, "Subscription plan already exists", map[string]any{
-				"errors": err.Details,
+	subscription, appError := h.SubscriptionService.CreateSubscriptionPlan(planRequest, uint32(subID))
+	if appError != nil {
+		return subscriptionCatalogError(c, appError, "Failed to create subscription plan")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Subscription plan created successfully",
+		"data": fiber.Map{
+			"subscription": subscription,
+		},
+	})
+}
+
+// Update subscription plan
+// @Summary Update a subscription plan
+// @Description Change the value of a feature of a subscription. A subscription that was already bought gets a new version instead, its subscribers keep the version they bought until they renew
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subID path string true "Subscription ID"
+// @Param planID path string true "Subscription plan ID"
+// @Param UpdateSubscriptionPlanDTO body dtos.UpdateSubscriptionPlanDTO true "Update subscription plan request object"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{subscription=models.Subscription}}
+// @Failure 400 {object} fiber.Map{message=string,errors=[]string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /subscriptions/{subID}/plans/{planID} [put]
+func (h *SubscriptionController) UpdateSubscriptionPlan(c *fiber.Ctx) error {
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to update subscription plan", "Failed to parse subscription ID")
+	}
+
+	planID, err := strconv.ParseUint(c.Params("planID"), 10, 32)
+	if planID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to update subscription plan", "Failed to parse subscription plan ID")
+	}
+
+	planRequest := new(dtos.UpdateSubscriptionPlanDTO)
+
+	if err := validator.Validate(c, planRequest); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.BadRequest(c, "Validation failed", map[string]any{
+				"errors": vErr.Errors,
 			})
 		}
 
-		return response.InternalError(c, "Failed to create subscription plan", map[string]any{
-			"error": err.Details,
+		return response.InternalError(c, "Internal server error", map[string]any{
+			"error": err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Subscription plan created successfully",
+	subscription, appError := h.SubscriptionService.UpdateSubscriptionPlan(planRequest, uint32(subID), uint32(planID))
+	if appError != nil {
+		return subscriptionCatalogError(c, appError, "Failed to update subscription plan")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscription plan updated successfully",
+		"data": fiber.Map{
+			"subscription": subscription,
+		},
 	})
 }
 
+// Delete subscription plan
+// @Summary Delete a subscription plan
+// @Description Remove a feature from a subscription, the feature falls back to its default value. A subscription that was already bought gets a new version instead, its subscribers keep the version they bought until they renew
+// @Tags Subscription
+// @Produce json
+// @Security BearerAuth
+// @Param subID path string true "Subscription ID"
+// @Param planID path string true "Subscription plan ID"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{subscription=models.Subscription}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /subscriptions/{subID}/plans/{planID} [delete]
+func (h *SubscriptionController) DeleteSubscriptionPlan(c *fiber.Ctx) error {
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to delete subscription plan", "Failed to parse subscription ID")
+	}
+
+	planID, err := strconv.ParseUint(c.Params("planID"), 10, 32)
+	if planID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to delete subscription plan", "Failed to parse subscription plan ID")
+	}
+
+	subscription, appError := h.SubscriptionService.DeleteSubscriptionPlan(uint32(subID), uint32(planID))
+	if appError != nil {
+		return subscriptionCatalogError(c, appError, "Failed to delete subscription plan")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscription plan deleted successfully",
+		"data": fiber.Map{
+			"subscription": subscription,
+		},
+	})
+}
+
+// Respond with an error of the subscription catalog management
+func subscriptionCatalogError(c *fiber.Ctx, appError *errors.CustomError, message string) error {
+	switch appError.Code {
+	case fiber.StatusBadRequest:
+		return response.BadRequest(c, appError.Message, appError.Details)
+	case fiber.StatusNotFound:
+		return response.NotFound(c, appError.Message)
+	case fiber.StatusConflict:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": appError.Message,
+			"error":   appError.Details,
+		})
+	default:
+		return response.InternalError(c, message, appError.Details)
+	}
+}
+
 // Subscribe to a subscription
 // @Summary Subscribe to a subscription
-// Description Subscribe a user to a subscription
+// Description Subscribe a user to a subscription, a promo code discounts the payment and a voucher code grants the subscription without a payment
 // @Tags Subscription
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param subID path string true "Subscription ID"
+// @Param SubscribeSubscriptionDTO body dtos.SubscribeSubscriptionDTO true "Payment method and promo code of the order"
 // @Success 200 {object} fiber.Map{message=string}
 // @Failure 400 {object} fiber.Map{message=string,error=string}
 // @Failure 500 {object} fiber.Map{message=string,error=string}
@@ -150,7 +266,7 @@ func (h *SubscriptionController) SubscribeSubscription(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Cannot continue to subscribe user subscription", "Failed to parse subscription ID")
 	}
 
-	subscribeRequest := new(dtos.CreateSubscriptionOrderDTO)
+	subscribeRequest := new(dtos.SubscribeSubscriptionDTO)
 
 	if err := validator.Validate(c, subscribeRequest); err != nil {
 		if vErr, ok := err.(*validator.ValidationError); ok {
@@ -170,56 +286,587 @@ func (h *SubscriptionController) SubscribeSubscription(c *fiber.Ctx) error {
 		return response.NotFound(c, "Cannot continue to subscribe user into subscription due to not found subscription")
 	}
 
+	if appError := h.SubscriptionChangeService.VerifyCanSubscribe(uint32(userID), subscription); appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, appError.Message, appError.Details)
+		}
+	}
+
 	user, userError := h.UserService.GetUserDetail(uint32(userID))
 
 	if userError != nil {
 		return response.NotFound(c, "Cannot continue to subscribe user into subscription due to error getting user details")
 	}
 
-	subOrder, subOrderError := h.SubscriptionOrderService.GetOrderByUserAndSubscription(user.ID, subscription.ID)
-	if subOrderError != nil {
-		if subOrderError.Code != 404 {
-			return response.InternalError(c, "Failed to get subscription order", subOrderError.Details)
+	var promoCode *models.PromoCode
+	if subscribeRequest.PromoCode != "" {
+		var promoError *errors.CustomError
+
+		promoCode, promoError = h.PromoCodeService.GetRedeemablePromoCode(user.ID, subscribeRequest.PromoCode, subscription)
+		if promoError != nil {
+			switch promoError.Code {
+			case fiber.StatusBadRequest:
+				return response.BadRequest(c, promoError.Message, promoError.Details)
+			default:
+				return response.InternalError(c, "Failed to get promo code", promoError.Details)
+			}
+		}
+	}
+
+	if promoCode != nil && promoCode.IsVoucher() {
+		order, appError := h.SubscriptionOrderService.RedeemVoucher(user.ID, subscription, promoCode)
+		if appError != nil {
+			switch appError.Code {
+			case fiber.StatusBadRequest:
+				return response.BadRequest(c, appError.Message, appError.Details)
+			default:
+				return response.InternalError(c, appError.Message, appError.Details)
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Voucher redeemed successfully",
+			"data": fiber.Map{
+				"order": order,
+			},
+		})
+	}
+
+	if subscribeRequest.PaymentChannel == "" {
+		return response.BadRequest(c, "Validation failed", map[string]any{
+			"errors": map[string]string{"payment_channel": "Payment channel is required"},
+		})
+	}
+
+	order, paymentInstruction, appError := h.placeSubscriptionOrder(
+		user,
+		subscribeRequest.OrderRequest(),
+		func(paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError) {
+			return h.SubscriptionOrderService.PrepareOrderItems(subscription, promoCode, paymentMethod)
+		},
+		func(transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError) {
+			return h.SubscriptionOrderService.CreateNewSubscriptionOrder(user.ID, subscription, promoCode, transaction, items)
+		},
+	)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, appError.Message, appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "User subscribed to subscription successfully",
+		"data": fiber.Map{
+			"order":               order,
+			"payment_instruction": paymentInstruction,
+		},
+	})
+}
+
+// Renew my subscription
+// @Summary Renew the subscription of the current user
+// @Description Create a renewal order of the active paid subscription, once paid it extends the subscription from its current expiry. A subscription that has a newer version is renewed onto that version
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param CreateSubscriptionOrderDTO body dtos.CreateSubscriptionOrderDTO true "Payment method of the order"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{order=models.SubscriptionOrder,payment_instruction=midtrans.PaymentInstruction}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /users/me/subscription/renew [post]
+func (h *SubscriptionController) RenewMySubscription(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to renew subscription", "Failed to parse user ID")
+	}
+
+	renewRequest := new(dtos.CreateSubscriptionOrderDTO)
+
+	if err := validator.Validate(c, renewRequest); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.BadRequest(c, "Validation failed", map[string]any{
+				"errors": vErr.Errors,
+			})
 		}
+
+		return response.InternalError(c, "Internal server error", map[string]any{
+			"error": err.Error(),
+		})
 	}
 
-	if subOrder != nil && subOrder.Status == string(midtrans.PaymentStatusSettled) {
-		return response.BadRequest(c, "User already has an active subscription", "User already subscribed to this subscription")
+	userSubscription, subscription, appError := h.SubscriptionOrderService.GetRenewableSubscription(uint32(userID))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to get active subscription", appError.Details)
+		}
+	}
+
+	user, userError := h.UserService.GetUserDetail(uint32(userID))
+	if userError != nil {
+		return response.NotFound(c, "Cannot continue to renew subscription due to error getting user details")
+	}
+
+	order, paymentInstruction, appError := h.placeSubscriptionOrder(
+		user,
+		renewRequest,
+		func(paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError) {
+			return h.SubscriptionOrderService.PrepareOrderItems(subscription, nil, paymentMethod)
+		},
+		func(transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError) {
+			return h.SubscriptionOrderService.CreateRenewalSubscriptionOrder(userSubscription, subscription, transaction, items)
+		},
+	)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, appError.Message, appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscription renewal created successfully",
+		"data": fiber.Map{
+			"order":               order,
+			"payment_instruction": paymentInstruction,
+		},
+	})
+}
+
+// Prepare the line items of an order once its payment method is known
+type prepareOrderItemsFunc func(paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError)
+
+// Store an order charged by a payment transaction
+type storeOrderFunc func(transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError)
+
+// Charge a subscription order and store it
+// The items are priced by prepare and the charged order is stored by store, so new subscriptions,
+// renewals and plan changes all go through the same payment flow. A user can only have one pending order
+// It returns the order with its payment instruction, or an error with the message to respond with
+func (h *SubscriptionController) placeSubscriptionOrder(user *models.User, request *dtos.CreateSubscriptionOrderDTO, prepare prepareOrderItemsFunc, store storeOrderFunc) (*models.SubscriptionOrder, *midtrans.PaymentInstruction, *errors.CustomError) {
+	subOrder, subOrderError := h.SubscriptionOrderService.GetPendingUserOrder(user.ID)
+	if subOrderError != nil && subOrderError.Code != fiber.StatusNotFound {
+		return nil, nil, errors.Internal("Failed to get subscription order", subOrderError.Details)
 	}
 
-	chargeReceipt, paymentErr := h.PaymentService.CreatePayment(user, &midtrans.PaymentMethodConfig{
-		PaymentType: subscribeRequest.PaymentType,
-		Channel:     subscribeRequest.PaymentChannel,
-	}, subscribeRequest.Amount)
+	if subOrder != nil {
+		return nil, nil, errors.BadRequest("User already has a pending payment", "Complete or wait for the previous payment to expire")
+	}
+
+	paymentMethod, methodError := h.PaymentMethodsService.GetAvailablePaymentMethod(request.PaymentType, request.PaymentChannel)
+	if methodError != nil {
+		if methodError.Code == fiber.StatusBadRequest {
+			return nil, nil, methodError
+		}
+
+		return nil, nil, errors.Internal("Failed to get payment method", methodError.Details)
+	}
+
+	orderItems, itemsError := prepare(paymentMethod)
+	if itemsError != nil {
+		return nil, nil, itemsError
+	}
+
+	paymentItems := make([]dtos.PaymentItemDTO, 0, len(orderItems))
+	for _, item := range orderItems {
+		paymentItems = append(paymentItems, dtos.PaymentItemDTO{
+			ID:       item.Type,
+			Name:     item.Name,
+			Price:    item.Price,
+			Quantity: item.Quantity,
+		})
+	}
+
+	transaction, paymentInstruction, paymentErr := h.PaymentService.CreatePayment(user, paymentMethod, paymentItems, &midtrans.ChargeOptions{
+		VANumber: request.VANumber,
+	})
 	if paymentErr != nil {
-		return response.InternalError(c, "Failed to create payment", paymentErr)
+		if paymentErr.Code == fiber.StatusBadRequest {
+			return nil, nil, paymentErr
+		}
+
+		return nil, nil, errors.Internal("Failed to create payment", paymentErr.Details)
+	}
+
+	order, appError := store(transaction, orderItems)
+	if appError != nil {
+		// Nothing refers to the payment without its order, so it must not be paid anymore
+		if cancelError := h.PaymentService.CancelPayment(transaction.ID.String()); cancelError != nil {
+			log.Printf("Failed to cancel payment %s of an order that was not stored: %v", transaction.ID, cancelError.Details)
+		}
+
+		if appError.Code == fiber.StatusBadRequest {
+			return nil, nil, appError
+		}
+
+		return nil, nil, errors.Internal("Failed to create subscription order", appError.Details)
+	}
+
+	return order, paymentInstruction, nil
+}
+
+// Start a trial
+// @Summary Start the trial of a subscription
+// @Description Try a paid subscription without a payment. A trial is offered once per user and email to users with a verified email on the free tier, by default only to users who never paid for a subscription. The user moves back to the free tier when the trial ends unless they subscribe
+// @Tags Subscription
+// @Produce json
+// @Security BearerAuth
+// @Param subID path string true "Subscription ID"
+// @Success 201 {object} fiber.Map{message=string,data=fiber.Map{subscription=models.UserSubscription}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 403 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /subscriptions/{subID}/trial [post]
+func (h *SubscriptionController) StartSubscriptionTrial(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to start trial", "Failed to parse user ID")
+	}
+
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to start trial", "Failed to parse subscription ID")
+	}
+
+	trial, appError := h.SubscriptionTrialService.StartTrial(uint32(userID), uint32(subID))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusForbidden:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": appError.Message,
+				"error":   appError.Details,
+			})
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, appError.Message, appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Trial started successfully",
+		"data": fiber.Map{
+			"subscription": trial,
+		},
+	})
+}
+
+// Preview a change of my subscription
+// @Summary Preview a change of the subscription of the current user
+// @Description Show the price and effective date of moving the active paid subscription to another subscription. A downgrade also lists the quotas the merchants of the user exceed and the features they lose
+// @Tags Subscription
+// @Produce json
+// @Security BearerAuth
+// @Param subID path string true "Subscription ID to change to"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{preview=dtos.SubscriptionChangePreviewDTO}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /users/me/subscription/change/{subID}/preview [get]
+func (h *SubscriptionController) PreviewMySubscriptionChange(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to preview subscription change", "Failed to parse user ID")
+	}
+
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to preview subscription change", "Failed to parse subscription ID")
 	}
 
-	appError := h.SubscriptionOrderService.CreateNewSubscriptionOrder(uint32(userID), uint32(subID), subscribeRequest)
+	preview, appError := h.SubscriptionChangeService.PreviewSubscriptionChange(uint32(userID), uint32(subID))
 	if appError != nil {
-		return response.InternalError(c, "Failed to create subscription order", appError.Details)
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, appError.Message, appError.Details)
+		}
 	}
 
-	// if err := h.SubscriptionService.SubscribeUserToSubscription(uint32(userID), uint32(subID)); err != nil {
-	// return response.InternalError(c, "Failed to subscribe user to subscription", "User could not be subscribed to subscription")
-	// switch err.Code {
-	// case 400:
-	// 	return response.BadRequest(c, "User already has an active subscription", "Could not subscribe user to subscription")
-	// case 404:
-	// 	return response.NotFound(c, "Subscription not found")
-	// }
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscription change previewed successfully",
+		"data": fiber.Map{
+			"preview":               preview,
+			"requires_confirmation": preview.RequiresConfirmation(),
+		},
+	})
+}
 
-	// return response.InternalError(c, "Failed to subscribe user to subscription", "User could not be subscribed to subscription")
-	// }
+// Change my subscription
+// @Summary Change the subscription of the current user
+// @Description Create the order of an upgrade or downgrade of the active paid subscription. An upgrade charges the prorated difference and takes effect once paid, a downgrade takes effect when the current subscription expires. A downgrade that exceeds quotas or removes features must be confirmed
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subID path string true "Subscription ID to change to"
+// @Param ChangeSubscriptionDTO body dtos.ChangeSubscriptionDTO true "Payment method of the order and the confirmation of the change"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{order=models.SubscriptionOrder,payment_instruction=midtrans.PaymentInstruction,preview=dtos.SubscriptionChangePreviewDTO}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /users/me/subscription/change/{subID} [post]
+func (h *SubscriptionController) ChangeMySubscription(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to change subscription", "Failed to parse user ID")
+	}
+
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to change subscription", "Failed to parse subscription ID")
+	}
+
+	changeRequest := new(dtos.ChangeSubscriptionDTO)
+
+	if err := validator.Validate(c, changeRequest); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.BadRequest(c, "Validation failed", map[string]any{
+				"errors": vErr.Errors,
+			})
+		}
+
+		return response.InternalError(c, "Internal server error", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	preview, appError := h.SubscriptionChangeService.PreviewSubscriptionChange(uint32(userID), uint32(subID))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, appError.Message, appError.Details)
+		}
+	}
+
+	if preview.RequiresConfirmation() && !changeRequest.Confirm {
+		return response.BadRequest(c, "Subscription change must be confirmed", map[string]any{
+			"preview": preview,
+		})
+	}
+
+	user, userError := h.UserService.GetUserDetail(uint32(userID))
+	if userError != nil {
+		return response.NotFound(c, "Cannot continue to change subscription due to error getting user details")
+	}
+
+	order, paymentInstruction, appError := h.placeSubscriptionOrder(
+		user,
+		&changeRequest.CreateSubscriptionOrderDTO,
+		func(paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError) {
+			return h.SubscriptionOrderService.PrepareChangeOrderItems(preview, paymentMethod)
+		},
+		func(transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError) {
+			return h.SubscriptionOrderService.CreateChangeSubscriptionOrder(user.ID, preview, transaction, items)
+		},
+	)
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		default:
+			return response.InternalError(c, appError.Message, appError.Details)
+		}
+	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "User subscribed to subscription successfully",
+		"message": "Subscription change created successfully",
+		"data": fiber.Map{
+			"order":               order,
+			"payment_instruction": paymentInstruction,
+			"preview":             preview,
+		},
+	})
+}
+
+// Cancel a subscription order
+// @Summary Cancel a pending subscription order
+// @Description Cancel the pending payment of a subscription order owned by the user
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orderID path string true "Subscription order ID"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{order=models.SubscriptionOrder}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /subscriptions/orders/{orderID}/cancel [post]
+func (h *SubscriptionController) CancelSubscriptionOrder(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to cancel subscription order", "Failed to parse user ID")
+	}
+
+	order, appError := h.SubscriptionOrderService.CancelSubscriptionOrder(uint32(userID), c.Params("orderID"))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to cancel subscription order", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscription order canceled successfully",
+		"data": fiber.Map{
+			"order": order,
+		},
+	})
+}
+
+// Get my orders
+// @Summary Get the billing history of the current user
+// @Description Retrieve the subscription orders of the current user along with their payment transaction
+// @Tags Subscription
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param order query string false "Sort order by creation date, asc or desc"
+// @Param status query string false "Order status"
+// @Param payment_type query string false "Payment type"
+// @Param date_from query string false "Created from date (YYYY-MM-DD)"
+// @Param date_to query string false "Created until date (YYYY-MM-DD)"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{orders=[]models.SubscriptionOrder,pagination=query.PaginationResponse}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /users/me/orders [get]
+func (h *SubscriptionController) GetMyOrders(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to get orders", "Failed to parse user ID")
+	}
+
+	params := query.ParseQueryParams(c)
+
+	orders, pagination, appError := h.SubscriptionOrderService.GetUserOrders(uint32(userID), params)
+	if appError != nil {
+		return response.InternalError(c, "Failed to get orders", appError.Details)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Orders retrieved successfully",
 		"data": fiber.Map{
-			"payment_request": chargeReceipt,
+			"orders":     orders,
+			"pagination": pagination,
 		},
 	})
 }
 
+// Get my order
+// @Summary Get an order of the current user
+// @Description Retrieve a subscription order of the current user with its items and payment transaction
+// @Tags Subscription
+// @Produce json
+// @Security BearerAuth
+// @Param orderID path string true "Order ID"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{order=models.SubscriptionOrder}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Router /users/me/orders/{orderID} [get]
+func (h *SubscriptionController) GetMyOrder(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to get order", "Failed to parse user ID")
+	}
+
+	order, appError := h.SubscriptionOrderService.GetUserOrder(uint32(userID), c.Params("orderID"))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to get order", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Order retrieved successfully",
+		"data": fiber.Map{
+			"order": order,
+		},
+	})
+}
+
+// Download my order invoice
+// @Summary Download the invoice of an order
+// @Description Download the PDF invoice of a settled subscription order of the current user
+// @Tags Subscription
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param orderID path string true "Order ID"
+// @Success 200 {file} file "Invoice PDF"
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /users/me/orders/{orderID}/invoice [get]
+func (h *SubscriptionController) DownloadMyOrderInvoice(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to download invoice", "Failed to parse user ID")
+	}
+
+	file, filename, appError := h.InvoiceService.GetUserOrderInvoice(uint32(userID), c.Params("orderID"))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to download invoice", appError.Details)
+		}
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", "attachment; filename="+filename)
+	return c.Send(file)
+}
+
 // Get all subscriptions
 // @Summary Get all subscriptions
 // @Description Retrieve all available subscriptions
@@ -244,17 +891,101 @@ func (h *SubscriptionController) GetSubscriptions(c *fiber.Ctx) error {
 	})
 }
 
+// Compare subscriptions
+// @Summary Compare the subscriptions on offer
+// @Description List every subscription on offer, cheapest first, with the value of each feature. Flags are booleans, quotas and sizes in bytes are numbers
+// @Tags Subscription
+// @Produce json
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{comparison=dtos.SubscriptionComparisonDTO}}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /subscriptions/compare [get]
+func (h *SubscriptionController) CompareSubscriptions(c *fiber.Ctx) error {
+	comparison, appError := h.SubscriptionService.CompareSubscriptions()
+	if appError != nil {
+		return response.InternalError(c, "Failed to compare subscriptions", appError.Details)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscriptions compared successfully",
+		"data": fiber.Map{
+			"comparison": comparison,
+		},
+	})
+}
+
+// Get subscription
+// @Summary Get a subscription
+// @Description Retrieve a subscription along with its plans, including versions that are no longer offered
+// @Tags Subscription
+// @Produce json
+// @Param subID path string true "Subscription ID"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{subscription=models.Subscription}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Router /subscriptions/{subID} [get]
+func (h *SubscriptionController) GetSubscription(c *fiber.Ctx) error {
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to get subscription", "Failed to parse subscription ID")
+	}
+
+	subscription, appError := h.SubscriptionService.GetSubscriptionByID(uint32(subID))
+	if appError != nil {
+		return response.NotFound(c, appError.Message)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscription retrieved successfully",
+		"data": fiber.Map{
+			"subscription": subscription,
+		},
+	})
+}
+
+// Get subscription versions
+// @Summary Get the versions of a subscription
+// @Description Retrieve every version of a subscription, oldest first
+// @Tags Subscription
+// @Produce json
+// @Security BearerAuth
+// @Param subID path string true "Subscription ID"
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{subscriptions=[]models.Subscription}}
+// @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string,error=string}
+// @Router /subscriptions/{subID}/versions [get]
+func (h *SubscriptionController) GetSubscriptionVersions(c *fiber.Ctx) error {
+	subID, err := strconv.ParseUint(c.Params("subID"), 10, 32)
+	if subID == 0 || err != nil {
+		return response.BadRequest(c, "Cannot continue to get subscription versions", "Failed to parse subscription ID")
+	}
+
+	versions, appError := h.SubscriptionService.GetSubscriptionVersions(uint32(subID))
+	if appError != nil {
+		return subscriptionCatalogError(c, appError, "Failed to get subscription versions")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Subscription versions retrieved successfully",
+		"data": fiber.Map{
+			"subscriptions": versions,
+		},
+	})
+}
+
 // Update subscription
 // @Summary Update a subscription
-// @Description Update an existing subscription
+// @Description Update the given fields of a subscription. Changing the price or the duration of a subscription that was already bought creates a new version instead, its subscribers keep the version they bought until they renew
 // @Tags Subscription
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param subID path string true "Subscription ID"
 // @Param UpdateSubscriptionDTO body dtos.UpdateSubscriptionDTO true "Update subscription request object"
-// @Success 200 {object} fiber.Map{message=string}
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{subscription=models.Subscription}}
 // @Failure 400 {object} fiber.Map{message=string,errors=[]string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 409 {object} fiber.Map{message=string,error=any}
 // @Failure 500 {object} fiber.Map{message=string,error=string}
 // @Router /subscriptions/{subID} [put]
 func (h *SubscriptionController) UpdateSubscription(c *fiber.Ctx) error {
@@ -281,18 +1012,22 @@ func (h *SubscriptionController) UpdateSubscription(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.SubscriptionService.UpdateSubscription(updateSubscriptionDTO, uint32(subID)); err != nil {
-		return response.InternalError(c, "Failed to update subscription", "Could not update subscription")
+	subscription, appError := h.SubscriptionService.UpdateSubscription(updateSubscriptionDTO, uint32(subID))
+	if appError != nil {
+		return subscriptionCatalogError(c, appError, "Failed to update subscription")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Subscription updated successfully",
+		"data": fiber.Map{
+			"subscription": subscription,
+		},
 	})
 }
 
 // Delete subscription
 // @Summary Delete a subscription
-// @Description Delete a subscription by its ID
+// @Description Delete a subscription by its ID. A subscription that was already bought is retired instead, it is no longer offered but its subscribers keep it until it expires
 // @Tags Subscription
 // @Accept json
 // @Produce json
@@ -300,6 +1035,7 @@ func (h *SubscriptionController) UpdateSubscription(c *fiber.Ctx) error {
 // @Param subID path string true "Subscription ID"
 // @Success 204 {object} fiber.Map{message=string}
 // @Failure 400 {object} fiber.Map{message=string,error=string}
+// @Failure 404 {object} fiber.Map{message=string}
 // @Failure 500 {object} fiber.Map{message=string,error=string}
 // @Router /subscriptions/{subID} [delete]
 func (h *SubscriptionController) DeleteSubscription(c *fiber.Ctx) error {
@@ -312,10 +1048,8 @@ func (h *SubscriptionController) DeleteSubscription(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.SubscriptionService.DeleteSubscription(uint32(subID)); err != nil {
-		return response.InternalError(c, "Failed to delete subscription", map[string]any{
-			// "error": err.Error(),
-		})
+	if appError := h.SubscriptionService.DeleteSubscription(uint32(subID)); appError != nil {
+		return subscriptionCatalogError(c, appError, "Failed to delete subscription")
 	}
 
 	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
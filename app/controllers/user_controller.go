@@ -5,6 +5,7 @@ import (
 	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
 	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/utils/query"
 	"senkou-catalyst-be/utils/response"
 	"senkou-catalyst-be/utils/validator"
@@ -13,24 +14,29 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type UserController struct {
 	userService     services.UserService
 	merchantService services.MerchantService
 	subService      services.SubscriptionService
+	referralService services.ReferralService
+	authService     services.AuthService
 }
 
-func NewUserController(userService services.UserService, merchantService services.MerchantService, subService services.SubscriptionService) *UserController {
+func NewUserController(userService services.UserService, merchantService services.MerchantService, subService services.SubscriptionService, referralService services.ReferralService, authService services.AuthService) *UserController {
 	return &UserController{
 		userService:     userService,
 		merchantService: merchantService,
 		subService:      subService,
+		referralService: referralService,
+		authService:     authService,
 	}
 }
 
 // @Summary Create user
-// @Description Create a new user with the provided details
+// @Description Create a new user with the provided details, a referral code of another user rewards them once the new user pays for a subscription
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -58,6 +64,21 @@ func (h *UserController) CreateUser(c *fiber.Ctx) error {
 		}
 	}
 
+	var referrer *models.User
+	if userRequest.ReferralCode != nil && *userRequest.ReferralCode != "" {
+		var referralError *errors.CustomError
+
+		referrer, referralError = h.referralService.GetReferrer(*userRequest.ReferralCode)
+		if referralError != nil {
+			switch referralError.Code {
+			case fiber.StatusBadRequest:
+				return response.BadRequest(c, "Cannot continue to register user, invalid referral code", referralError.Details)
+			default:
+				return response.InternalError(c, "Failed to get referral code", referralError.Details)
+			}
+		}
+	}
+
 	user := &models.User{
 		Name:     userRequest.Name,
 		Email:    userRequest.Email,
@@ -85,6 +106,12 @@ func (h *UserController) CreateUser(c *fiber.Ctx) error {
 		}
 	}
 
+	if referrer != nil {
+		if err := h.referralService.CreateReferral(referrer, newUser); err != nil {
+			return response.InternalError(c, "Failed to record referral", err.Details)
+		}
+	}
+
 	if err := h.userService.SendEmailActivation(newUser); err != nil {
 		return response.InternalError(c, "Failed to send email activation", err.Details)
 	}
@@ -193,3 +220,196 @@ func (h *UserController) GetUserDetail(c *fiber.Ctx) error {
 		},
 	})
 }
+
+// @Summary Get my referrals
+// @Description Get the referral code of the current user, the users who signed up with it and the days earned from them
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{referrals=dtos.ReferralSummaryDTO}}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string, error=string}
+// @Router /users/me/referrals [get]
+func (h *UserController) GetMyReferrals(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.Unauthorized(c, "You must be logged in to access this resource")
+	}
+
+	summary, appError := h.referralService.GetReferralSummary(uint32(userID))
+	if appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to retrieve referrals", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Referrals retrieved successfully",
+		"data": fiber.Map{
+			"referrals": summary,
+		},
+	})
+}
+
+// @Summary Get my sessions
+// @Description Get the devices the current user is logged in on, the session of the request is flagged as current
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string,data=fiber.Map{sessions=[]dtos.UserSessionDTO}}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string, error=string}
+// @Router /users/me/sessions [get]
+func (h *UserController) GetMySessions(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.Unauthorized(c, "You must be logged in to access this resource")
+	}
+
+	// Tokens issued before sessions were introduced do not carry one, none of the sessions is current then
+	sessionID, _ := currentSessionID(c)
+
+	sessions, appError := h.authService.GetSessions(uint32(userID), sessionID)
+	if appError != nil {
+		return response.InternalError(c, "Failed to retrieve sessions", appError.Details)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Sessions retrieved successfully",
+		"data": fiber.Map{
+			"sessions": sessions,
+		},
+	})
+}
+
+// @Summary Revoke one of my sessions
+// @Description Log out one of the devices of the current user, its refresh token can no longer be used
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param sessionID path string true "Session ID"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 400 {object} fiber.Map{message=string}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 404 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string, error=string}
+// @Router /users/me/sessions/{sessionID} [delete]
+func (h *UserController) RevokeMySession(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.Unauthorized(c, "You must be logged in to access this resource")
+	}
+
+	sessionID, err := uuid.Parse(c.Params("sessionID"))
+	if err != nil {
+		return response.BadRequest(c, "Invalid session ID", "Session ID must be a valid UUID")
+	}
+
+	if appError := h.authService.RevokeSession(uint32(userID), sessionID); appError != nil {
+		switch appError.Code {
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to revoke session", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Session revoked successfully",
+	})
+}
+
+// @Summary Log out my other devices
+// @Description Revoke every session of the current user except the one the request was made from
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map{message=string,data=dtos.RevokedSessionsDTO}
+// @Failure 400 {object} fiber.Map{message=string}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string, error=string}
+// @Router /users/me/sessions [delete]
+func (h *UserController) RevokeMyOtherSessions(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.Unauthorized(c, "You must be logged in to access this resource")
+	}
+
+	sessionID, err := currentSessionID(c)
+	if err != nil {
+		return response.BadRequest(c, "Cannot continue to log out other devices", "Session ID is not valid")
+	}
+
+	revoked, appError := h.authService.RevokeOtherSessions(uint32(userID), sessionID)
+	if appError != nil {
+		return response.InternalError(c, "Failed to revoke sessions", appError.Details)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Other devices logged out successfully",
+		"data": dtos.RevokedSessionsDTO{
+			RevokedSessions: revoked,
+		},
+	})
+}
+
+// @Summary Change my password
+// @Description Change the password of the current user, every other session of the user is revoked
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dtos.ChangePasswordDTO true "Current and new password"
+// @Success 200 {object} fiber.Map{message=string}
+// @Failure 400 {object} fiber.Map{message=string}
+// @Failure 401 {object} fiber.Map{message=string}
+// @Failure 500 {object} fiber.Map{message=string, error=string}
+// @Router /users/me/password [put]
+func (h *UserController) ChangeMyPassword(c *fiber.Ctx) error {
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+
+	if userID == 0 || err != nil {
+		return response.Unauthorized(c, "You must be logged in to access this resource")
+	}
+
+	request := new(dtos.ChangePasswordDTO)
+
+	if err := validator.Validate(c, request); err != nil {
+		if vErr, ok := err.(*validator.ValidationError); ok {
+			return response.ValidationError(c, "Validation failed", vErr.Errors)
+		}
+
+		return response.InternalError(c, "Internal server error", err.Error())
+	}
+
+	// Without a session every session of the user is revoked, including the one of the request
+	sessionID, _ := currentSessionID(c)
+
+	if appError := h.authService.ChangePassword(uint32(userID), sessionID, request); appError != nil {
+		switch appError.Code {
+		case fiber.StatusBadRequest:
+			return response.BadRequest(c, appError.Message, appError.Details)
+		case fiber.StatusNotFound:
+			return response.NotFound(c, appError.Message)
+		default:
+			return response.InternalError(c, "Failed to change password", appError.Details)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Password changed successfully, your other devices have been logged out",
+	})
+}
@@ -0,0 +1,57 @@
+package dtos
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"time"
+)
+
+// The range, bucket size and breakdown of a payment analytics report
+// From is inclusive and To is exclusive
+type PaymentAnalyticsQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	GroupBy  string
+}
+
+// The metrics of one group within one time bucket
+// Flow metrics (revenue, refunds, payments and churn) count what happened within the bucket,
+// snapshot metrics (active subscribers and MRR) describe the end of the bucket
+// Amounts are reported in the default currency, the queries select their minor units as <name>_amount
+type PaymentAnalyticsRow struct {
+	Bucket              time.Time   `json:"bucket"`
+	Group               string      `json:"group" gorm:"column:group_value"`
+	GrossRevenue        money.Money `json:"gross_revenue" gorm:"embedded;embeddedPrefix:gross_revenue_"`
+	Refunds             money.Money `json:"refunds" gorm:"embedded;embeddedPrefix:refunds_"`
+	NetRevenue          money.Money `json:"net_revenue" gorm:"embedded;embeddedPrefix:net_revenue_"`
+	MRR                 money.Money `json:"mrr" gorm:"embedded;embeddedPrefix:mrr_"`
+	ActiveSubscribers   int64       `json:"active_subscribers"`
+	StartingSubscribers int64       `json:"starting_subscribers"`
+	ChurnedSubscribers  int64       `json:"churned_subscribers"`
+	ChurnRate           float64     `json:"churn_rate"`
+	CreatedPayments     int64       `json:"created_payments"`
+	ConvertedPayments   int64       `json:"converted_payments"`
+	ConversionRate      float64     `json:"conversion_rate"`
+}
+
+type PaymentAnalyticsSummary struct {
+	GrossRevenue       money.Money `json:"gross_revenue"`
+	Refunds            money.Money `json:"refunds"`
+	NetRevenue         money.Money `json:"net_revenue"`
+	MRR                money.Money `json:"mrr"`
+	ActiveSubscribers  int64       `json:"active_subscribers"`
+	ChurnedSubscribers int64       `json:"churned_subscribers"`
+	ChurnRate          float64     `json:"churn_rate"`
+	CreatedPayments    int64       `json:"created_payments"`
+	ConvertedPayments  int64       `json:"converted_payments"`
+	ConversionRate     float64     `json:"conversion_rate"`
+}
+
+type PaymentAnalyticsReport struct {
+	DateFrom string                  `json:"date_from"`
+	DateTo   string                  `json:"date_to"`
+	Interval string                  `json:"interval"`
+	GroupBy  string                  `json:"group_by"`
+	Summary  PaymentAnalyticsSummary `json:"summary"`
+	Rows     []PaymentAnalyticsRow   `json:"rows"`
+}
@@ -36,3 +36,31 @@ func (dto *RefreshTokenRequestDTO) ErrorMessages() map[string]string {
 		"RefreshToken.required": "Refresh token is required",
 	}
 }
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (dto *ForgotPasswordDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"Email.required": "Email is required",
+		"Email.email":    "Email must be a valid email address",
+	}
+}
+
+type ResetPasswordDTO struct {
+	Token                string `json:"token" validate:"required"`
+	Password             string `json:"password" validate:"required,min=8,max=100"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
+}
+
+func (dto *ResetPasswordDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"Token.required":                "Token is required",
+		"Password.required":             "Password is required",
+		"Password.min":                  "Password must be at least 8 characters",
+		"Password.max":                  "Password cannot exceed 100 characters",
+		"PasswordConfirmation.required": "Password confirmation is required",
+		"PasswordConfirmation.eqfield":  "Password confirmation must match the password",
+	}
+}
@@ -0,0 +1,23 @@
+package dtos
+
+// The entitlements of a user next to their current usage
+type UserEntitlementsDTO struct {
+	SubscriptionID   uint32 `json:"subscription_id,omitempty"`
+	SubscriptionName string `json:"subscription_name,omitempty"`
+	// Administrators are not limited by any subscription
+	Unrestricted bool                  `json:"unrestricted"`
+	Entitlements []EntitlementUsageDTO `json:"entitlements"`
+}
+
+// An entitlement of a user next to its current usage
+// Used is only reported for quotas and Enabled only for flags. Hidden is the number of products
+// hidden from the storefront because they are beyond the product slot quota
+type EntitlementUsageDTO struct {
+	Key         string `json:"key"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Limit       int64  `json:"limit"`
+	Used        *int64 `json:"used,omitempty"`
+	Enabled     *bool  `json:"enabled,omitempty"`
+	Hidden      int64  `json:"hidden,omitempty"`
+}
@@ -1,5 +1,10 @@
 package dtos
 
+import (
+	"senkou-catalyst-be/utils/money"
+	"time"
+)
+
 type BaseMidtransNotification struct {
 	Currency          string `json:"currency"`
 	CustomField1      string `json:"custom_field1"`
@@ -36,12 +41,40 @@ type UpdateSubscriptionOrderDTO struct {
 	FraudStatus string `json:"fraud_status" validate:"required"`
 
 	// Transaction details
-	TransactionID     *string `json:"transaction_id"`
-	TransactionStatus *string `json:"transaction_status"`
-	TransactionTime   *string `json:"transaction_time"`
+	TransactionID     *string    `json:"transaction_id"`
+	TransactionStatus *string    `json:"transaction_status"`
+	TransactionTime   *time.Time `json:"transaction_time"`
 
 	// Metadata
-	SignatureKey   *string `json:"signature_key"`
-	SettlementTime *string `json:"settlement_time"`
-	ExpiryTime     *string `json:"expiry_time"`
+	SignatureKey   *string    `json:"signature_key"`
+	SettlementTime *time.Time `json:"settlement_time"`
+	ExpiryTime     *time.Time `json:"expiry_time"`
+}
+
+type PaymentItemDTO struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Price    money.Money `json:"price"`
+	Quantity int         `json:"quantity"`
+}
+
+type PaymentReconciliationResultDTO struct {
+	Checked   int `json:"checked"`
+	Updated   int `json:"updated"`
+	Expired   int `json:"expired"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+// The whole remaining amount is refunded when no amount is given
+type RefundPaymentDTO struct {
+	Amount *money.Money `json:"amount"`
+	Reason string       `json:"reason" validate:"required,max=255"`
+}
+
+func (dto *RefundPaymentDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"Reason.required": "Reason is required",
+		"Reason.max":      "Reason must be at most 255 characters long",
+	}
 }
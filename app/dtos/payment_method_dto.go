@@ -0,0 +1,77 @@
+package dtos
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"time"
+)
+
+type CreatePaymentMethodDTO struct {
+	Name              string      `json:"name" validate:"required,max=100"`
+	PaymentType       string      `json:"payment_type" validate:"required,max=50"`
+	Channel           string      `json:"channel" validate:"required,max=50"`
+	Provider          string      `json:"provider" validate:"required,oneof=midtrans xendit fake"`
+	MinAmount         money.Money `json:"min_amount"`
+	MaxAmount         money.Money `json:"max_amount"`
+	Description       string      `json:"description" validate:"omitempty,max=255"`
+	ExpiryDuration    int         `json:"expiry_duration" validate:"gte=0"`
+	ExpiryUnit        string      `json:"expiry_unit" validate:"omitempty,oneof=second minute hour day"`
+	VANumberMinLength int         `json:"va_number_min_length" validate:"gte=0"`
+	VANumberMaxLength int         `json:"va_number_max_length" validate:"gte=0"`
+	FeeFlat           money.Money `json:"fee_flat"`
+	FeePercentage     float64     `json:"fee_percentage" validate:"gte=0,lte=100"`
+	IsEnabled         *bool       `json:"is_enabled"`
+	DisplayOrder      int         `json:"display_order"`
+}
+
+func (dto *CreatePaymentMethodDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"Name.required":        "Name is required",
+		"PaymentType.required": "Payment type is required",
+		"Channel.required":     "Payment channel is required",
+		"Provider.required":    "Provider is required",
+		"Provider.oneof":       "Provider must be one of midtrans, xendit or fake",
+		"ExpiryUnit.oneof":     "Expiry unit must be one of second, minute, hour or day",
+		"FeePercentage.lte":    "Fee percentage must not exceed 100",
+	}
+}
+
+// Every field is optional, only the given fields are changed
+type UpdatePaymentMethodDTO struct {
+	Name              *string      `json:"name" validate:"omitempty,min=1,max=100"`
+	PaymentType       *string      `json:"payment_type" validate:"omitempty,min=1,max=50"`
+	Provider          *string      `json:"provider" validate:"omitempty,oneof=midtrans xendit fake"`
+	MinAmount         *money.Money `json:"min_amount"`
+	MaxAmount         *money.Money `json:"max_amount"`
+	Description       *string      `json:"description" validate:"omitempty,max=255"`
+	LogoURL           *string      `json:"logo_url" validate:"omitempty,max=2048"`
+	ExpiryDuration    *int         `json:"expiry_duration" validate:"omitempty,gte=0"`
+	ExpiryUnit        *string      `json:"expiry_unit" validate:"omitempty,oneof=second minute hour day"`
+	VANumberMinLength *int         `json:"va_number_min_length" validate:"omitempty,gte=0"`
+	VANumberMaxLength *int         `json:"va_number_max_length" validate:"omitempty,gte=0"`
+	FeeFlat           *money.Money `json:"fee_flat"`
+	FeePercentage     *float64     `json:"fee_percentage" validate:"omitempty,gte=0,lte=100"`
+	IsEnabled         *bool        `json:"is_enabled"`
+	DisplayOrder      *int         `json:"display_order"`
+}
+
+func (dto *UpdatePaymentMethodDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"Provider.oneof":    "Provider must be one of midtrans, xendit or fake",
+		"ExpiryUnit.oneof":  "Expiry unit must be one of second, minute, hour or day",
+		"FeePercentage.lte": "Fee percentage must not exceed 100",
+	}
+}
+
+type CreateMaintenanceWindowDTO struct {
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+	Reason   string    `json:"reason" validate:"omitempty,max=255"`
+}
+
+func (dto *CreateMaintenanceWindowDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"StartsAt.required": "Start time is required",
+		"EndsAt.required":   "End time is required",
+		"EndsAt.gtfield":    "End time must be after the start time",
+	}
+}
@@ -1,12 +1,14 @@
 package dtos
 
+import "senkou-catalyst-be/utils/money"
+
 type CreateProductDTO struct {
-	Title        string   `json:"title" validate:"required,max=150"`
-	Description  string   `json:"description" validate:"omitempty,max=500"`
-	Price        string   `json:"price" validate:"required,number,min=0"`
-	AffiliateURL string   `json:"affiliate_url" validate:"required,url"`
-	Photos       []string `json:"photos" validate:"omitempty"`
-	CategoryID   *uint32  `json:"category_id" validate:"omitempty,number,min=1"`
+	Title        string       `json:"title" validate:"required,max=150"`
+	Description  string       `json:"description" validate:"omitempty,max=500"`
+	Price        *money.Money `json:"price" validate:"required"`
+	AffiliateURL string       `json:"affiliate_url" validate:"required,url"`
+	Photos       []string     `json:"photos" validate:"omitempty"`
+	CategoryID   *uint32      `json:"category_id" validate:"omitempty,number,min=1"`
 }
 
 func (dto *CreateProductDTO) ErrorMessages() map[string]string {
@@ -15,8 +17,6 @@ func (dto *CreateProductDTO) ErrorMessages() map[string]string {
 		"title.max":              "Title must be at most 150 characters long",
 		"description.max":        "Description must be at most 500 characters long",
 		"price.required":         "Price is required",
-		"price.number":           "Price must be a valid number",
-		"price.min":              "Price must be greater than or equal to 0",
 		"affiliate_url.required": "Affiliate URL is required",
 		"affiliate_url.url":      "Affiliate URL must be a valid URL",
 		"category_id.number":     "Category ID must be a valid number",
@@ -24,20 +24,18 @@ func (dto *CreateProductDTO) ErrorMessages() map[string]string {
 }
 
 type UpdateProductDTO struct {
-	ID           string  `json:"id"`
-	Title        *string `json:"title" validate:"omitempty,max=150"`
-	Description  *string `json:"description" validate:"omitempty,max=500"`
-	Price        *string `json:"price" validate:"omitempty,number,min=0"`
-	AffiliateURL *string `json:"affiliate_url" validate:"omitempty,url"`
-	CategoryID   *uint32 `json:"category_id" validate:"omitempty,number"`
+	ID           string       `json:"id"`
+	Title        *string      `json:"title" validate:"omitempty,max=150"`
+	Description  *string      `json:"description" validate:"omitempty,max=500"`
+	Price        *money.Money `json:"price"`
+	AffiliateURL *string      `json:"affiliate_url" validate:"omitempty,url"`
+	CategoryID   *uint32      `json:"category_id" validate:"omitempty,number"`
 }
 
 func (dto *UpdateProductDTO) ErrorMessages() map[string]string {
 	return map[string]string{
 		"title.max":          "Title must be at most 150 characters long",
 		"description.max":    "Description must be at most 500 characters long",
-		"price.number":       "Price must be a valid number",
-		"price.min":          "Price must be greater than or equal to 0",
 		"affiliate_url.url":  "Affiliate URL must be a valid URL",
 		"category_id.number": "Category ID must be a valid number",
 	}
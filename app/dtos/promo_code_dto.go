@@ -0,0 +1,90 @@
+package dtos
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"time"
+)
+
+// A percent or fixed discount, or a voucher that grants days of a single subscription without a payment
+// Subscriptions restrict the code to the given subscriptions and their newer versions, no subscription means every subscription
+type CreatePromoCodeDTO struct {
+	Code                  string       `json:"code" validate:"required,min=3,max=50"`
+	Description           string       `json:"description" validate:"omitempty,max=255"`
+	Type                  string       `json:"type" validate:"required,oneof=percent fixed voucher"`
+	PercentOff            int16        `json:"percent_off" validate:"omitempty,min=1,max=99"`
+	AmountOff             *money.Money `json:"amount_off"`
+	GrantedDays           int16        `json:"granted_days" validate:"omitempty,min=1,max=366"`
+	SubscriptionIDs       []uint32     `json:"subscription_ids"`
+	MaxRedemptions        *int         `json:"max_redemptions" validate:"omitempty,min=1"`
+	MaxRedemptionsPerUser *int         `json:"max_redemptions_per_user" validate:"omitempty,min=1"`
+	StartsAt              *time.Time   `json:"starts_at"`
+	EndsAt                *time.Time   `json:"ends_at"`
+	IsActive              *bool        `json:"is_active"`
+}
+
+func (dto *CreatePromoCodeDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"Code.required":             "Code is required",
+		"Code.min":                  "Code must be at least 3 characters long",
+		"Code.max":                  "Code must be at most 50 characters long",
+		"Type.required":             "Type is required",
+		"Type.oneof":                "Type must be one of percent, fixed or voucher",
+		"PercentOff.min":            "Percent off must be at least 1",
+		"PercentOff.max":            "Percent off must be at most 99, use a voucher to give a subscription away",
+		"GrantedDays.min":           "Granted days must be at least 1",
+		"GrantedDays.max":           "Granted days must be at most 366",
+		"MaxRedemptions.min":        "Max redemptions must be at least 1",
+		"MaxRedemptionsPerUser.min": "Max redemptions per user must be at least 1",
+	}
+}
+
+// Every field is optional, only the given fields are changed
+// The code and its type cannot be changed since orders refer to them, a max redemptions of 0 removes the limit
+type UpdatePromoCodeDTO struct {
+	Description           *string      `json:"description" validate:"omitempty,max=255"`
+	PercentOff            *int16       `json:"percent_off" validate:"omitempty,min=1,max=99"`
+	AmountOff             *money.Money `json:"amount_off"`
+	GrantedDays           *int16       `json:"granted_days" validate:"omitempty,min=1,max=366"`
+	SubscriptionIDs       *[]uint32    `json:"subscription_ids"`
+	MaxRedemptions        *int         `json:"max_redemptions" validate:"omitempty,min=0"`
+	MaxRedemptionsPerUser *int         `json:"max_redemptions_per_user" validate:"omitempty,min=1"`
+	StartsAt              *time.Time   `json:"starts_at"`
+	EndsAt                *time.Time   `json:"ends_at"`
+	IsActive              *bool        `json:"is_active"`
+}
+
+func (dto *UpdatePromoCodeDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"PercentOff.min":            "Percent off must be at least 1",
+		"PercentOff.max":            "Percent off must be at most 99, use a voucher to give a subscription away",
+		"GrantedDays.min":           "Granted days must be at least 1",
+		"GrantedDays.max":           "Granted days must be at most 366",
+		"MaxRedemptions.min":        "Max redemptions must not be negative",
+		"MaxRedemptionsPerUser.min": "Max redemptions per user must be at least 1",
+	}
+}
+
+type ValidatePromoCodeDTO struct {
+	Code           string `json:"code" validate:"required,max=50"`
+	SubscriptionID uint32 `json:"subscription_id" validate:"required"`
+}
+
+func (dto *ValidatePromoCodeDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"Code.required":           "Code is required",
+		"Code.max":                "Code must be at most 50 characters long",
+		"SubscriptionID.required": "Subscription ID is required",
+	}
+}
+
+// What a promo code takes off the price of a subscription
+// PPN and the fee of the payment method are added on top of the discounted price
+type PromoCodeQuoteDTO struct {
+	Code            string      `json:"code"`
+	Type            string      `json:"type"`
+	SubscriptionID  uint32      `json:"subscription_id"`
+	Price           money.Money `json:"price"`
+	Discount        money.Money `json:"discount"`
+	DiscountedPrice money.Money `json:"discounted_price"`
+	GrantedDays     int16       `json:"granted_days,omitempty"`
+}
@@ -0,0 +1,24 @@
+package dtos
+
+import "time"
+
+// A user referred by the current user
+// Only the first name of the referred user is shown, a rejected referral is never rewarded
+type ReferralDTO struct {
+	ID         uint32     `json:"id"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	RewardDays int16      `json:"reward_days"`
+	RewardedAt *time.Time `json:"rewarded_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ReferralSummaryDTO struct {
+	ReferralCode      string        `json:"referral_code"`
+	RewardDays        int           `json:"reward_days"`
+	TotalReferrals    int           `json:"total_referrals"`
+	PendingReferrals  int           `json:"pending_referrals"`
+	RewardedReferrals int           `json:"rewarded_referrals"`
+	EarnedDays        int           `json:"earned_days"`
+	Referrals         []ReferralDTO `json:"referrals"`
+}
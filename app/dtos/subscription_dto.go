@@ -1,10 +1,18 @@
 package dtos
 
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/money"
+	"time"
+)
+
 type CreateSubscriptionDTO struct {
-	Name        string  `json:"name" validate:"required,max=100"`
-	Price       float64 `json:"price" validate:"required,number,min=0"`
-	Description string  `json:"description" validate:"omitempty,max=500"`
-	Duration    int16   `json:"duration" validate:"required,number,min=1"`
+	Name              string       `json:"name" validate:"required,max=100"`
+	Price             *money.Money `json:"price" validate:"required"`
+	Description       string       `json:"description" validate:"omitempty,max=500"`
+	Duration          int16        `json:"duration" validate:"required,number,min=1"`
+	TrialDays         int16        `json:"trial_days" validate:"omitempty,min=0,max=90"`
+	TrialForPaidUsers bool         `json:"trial_for_paid_users"`
 }
 
 func (dto *CreateSubscriptionDTO) ErrorMessages() map[string]string {
@@ -12,18 +20,102 @@ func (dto *CreateSubscriptionDTO) ErrorMessages() map[string]string {
 		"name.required":     "Name is required",
 		"name.max":          "Name must be at most 100 characters long",
 		"price.required":    "Price is required",
-		"price.number":      "Price must be a valid number",
-		"price.min":         "Price must be greater than or equal to 0",
 		"description.max":   "Description must be at most 500 characters long",
 		"duration.required": "Duration is required",
 		"duration.number":   "Duration must be a valid number",
 		"duration.min":      "Duration must be at least 1 month",
+		"trial_days.min":    "Trial days must not be negative",
+		"trial_days.max":    "Trial days must be at most 90 days",
 	}
 }
 
+// Only the fields that are sent are updated
+// Changing the price or the duration of a subscription that was already bought stores a new version of it,
+// the trial settings only apply to trials started afterwards and are changed in place
 type UpdateSubscriptionDTO struct {
-	Name        *string  `json:"name" validate:"required,max=100"`
-	Price       *float64 `json:"price" validate:"omitempty,number,min=0"`
-	Description *string  `json:"description" validate:"omitempty,max=500"`
-	Duration    *int16   `json:"duration" validate:"omitempty,number,min=1"`
+	Name              *string      `json:"name" validate:"omitempty,min=1,max=100"`
+	Price             *money.Money `json:"price"`
+	Description       *string      `json:"description" validate:"omitempty,max=500"`
+	Duration          *int16       `json:"duration" validate:"omitempty,number,min=1"`
+	TrialDays         *int16       `json:"trial_days" validate:"omitempty,min=0,max=90"`
+	TrialForPaidUsers *bool        `json:"trial_for_paid_users"`
+}
+
+func (dto *UpdateSubscriptionDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"name.min":        "Name must not be empty",
+		"name.max":        "Name must be at most 100 characters long",
+		"description.max": "Description must be at most 500 characters long",
+		"duration.number": "Duration must be a valid number",
+		"duration.min":    "Duration must be at least 1 day",
+		"trial_days.min":  "Trial days must not be negative",
+		"trial_days.max":  "Trial days must be at most 90 days",
+	}
+}
+
+// The subscriptions on offer side by side
+// Features lists every entitlement in the order they are compared
+type SubscriptionComparisonDTO struct {
+	Features      []SubscriptionFeatureDTO        `json:"features"`
+	Subscriptions []SubscriptionComparisonItemDTO `json:"subscriptions"`
+}
+
+type SubscriptionFeatureDTO struct {
+	Key         string `json:"key"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+}
+
+// A subscription on offer with the value of each feature
+// Flags are booleans while quotas and sizes in bytes are numbers, a feature the subscription does not declare has its default value
+type SubscriptionComparisonItemDTO struct {
+	ID          uint32         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Price       money.Money    `json:"price"`
+	Duration    int16          `json:"duration"`
+	Version     int            `json:"version"`
+	Features    map[string]any `json:"features"`
+}
+
+type SubscriptionExpiryResultDTO struct {
+	Reminded int `json:"reminded"`
+	Expired  int `json:"expired"`
+	Failed   int `json:"failed"`
+}
+
+// The effect of a subscription change, shown to the user before the change is confirmed
+// An upgrade charges the prorated difference for the remaining period and takes effect once paid.
+// A downgrade charges the price of the new subscription and takes effect when the current one expires
+type SubscriptionChangePreviewDTO struct {
+	Type                string                       `json:"type"`
+	UserSubscriptionID  uint32                       `json:"user_subscription_id"`
+	CurrentSubscription *models.Subscription         `json:"current_subscription"`
+	TargetSubscription  *models.Subscription         `json:"target_subscription"`
+	RemainingDays       int                          `json:"remaining_days"`
+	Credit              money.Money                  `json:"credit"`
+	Amount              money.Money                  `json:"amount"`
+	EffectiveAt         time.Time                    `json:"effective_at"`
+	ExpiredAt           time.Time                    `json:"expired_at"`
+	QuotaEffects        []SubscriptionQuotaEffectDTO `json:"quota_effects"`
+	LostFeatures        []string                     `json:"lost_features"`
+}
+
+// Check whether the change takes something away from the user that needs to be confirmed
+func (dto *SubscriptionChangePreviewDTO) RequiresConfirmation() bool {
+	return len(dto.QuotaEffects) > 0 || len(dto.LostFeatures) > 0
+}
+
+// A quota of a merchant that the merchant exceeds on the new subscription
+type SubscriptionQuotaEffectDTO struct {
+	MerchantID string `json:"merchant_id"`
+	Plan       string `json:"plan"`
+	Used       int    `json:"used"`
+	Limit      int    `json:"limit"`
+	OverQuota  int    `json:"over_quota"`
+}
+
+type ChangeSubscriptionDTO struct {
+	CreateSubscriptionOrderDTO
+	Confirm bool `json:"confirm"`
 }
@@ -1,16 +1,43 @@
 package dtos
 
 type CreateSubscriptionOrderDTO struct {
-	PaymentType    string  `json:"payment_type" validate:"required"`
-	PaymentChannel string  `json:"payment_channel" validate:"required"`
-	Amount         float64 `json:"amount" validate:"required,gt=0"`
+	PaymentType    string `json:"payment_type" validate:"required"`
+	PaymentChannel string `json:"payment_channel" validate:"required"`
+	VANumber       string `json:"va_number" validate:"omitempty,numeric,max=13"`
 }
 
 func (dto *CreateSubscriptionOrderDTO) ErrorMessages() map[string]string {
 	return map[string]string{
 		"PaymentType.required":    "Payment type is required",
 		"PaymentChannel.required": "Payment channel is required",
-		"Amount.required":         "Amount is required",
-		"Amount.gt":               "Amount must be greater than 0",
+		"VANumber.numeric":        "VA number must only contain digits",
+		"VANumber.max":            "VA number must be at most 13 digits long",
+	}
+}
+
+// A new subscription is paid with a payment method, a voucher code grants it without a payment
+type SubscribeSubscriptionDTO struct {
+	PaymentType    string `json:"payment_type" validate:"required_without=PromoCode"`
+	PaymentChannel string `json:"payment_channel" validate:"required_without=PromoCode"`
+	VANumber       string `json:"va_number" validate:"omitempty,numeric,max=13"`
+	PromoCode      string `json:"promo_code" validate:"omitempty,max=50"`
+}
+
+func (dto *SubscribeSubscriptionDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"PaymentType.required_without":    "Payment type is required",
+		"PaymentChannel.required_without": "Payment channel is required",
+		"VANumber.numeric":                "VA number must only contain digits",
+		"VANumber.max":                    "VA number must be at most 13 digits long",
+		"PromoCode.max":                   "Promo code must be at most 50 characters long",
+	}
+}
+
+// The payment of the order
+func (dto *SubscribeSubscriptionDTO) OrderRequest() *CreateSubscriptionOrderDTO {
+	return &CreateSubscriptionOrderDTO{
+		PaymentType:    dto.PaymentType,
+		PaymentChannel: dto.PaymentChannel,
+		VANumber:       dto.VANumber,
 	}
 }
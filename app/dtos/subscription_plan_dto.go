@@ -12,3 +12,13 @@ func (dto *CreateSubscriptionPlanDTO) ErrorMessages() map[string]string {
 		"value.required": "Value is required",
 	}
 }
+
+type UpdateSubscriptionPlanDTO struct {
+	Value string `json:"value" validate:"required"`
+}
+
+func (dto *UpdateSubscriptionPlanDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"value.required": "Value is required",
+	}
+}
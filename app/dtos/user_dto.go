@@ -8,6 +8,7 @@ type RegisterUserDTO struct {
 	Password             string  `json:"password" validate:"required,min=8,max=100"`
 	PasswordConfirmation string  `json:"password_confirmation" validate:"required,eqfield=Password"`
 	IsOAuth              bool    `json:"is_oauth,omitempty"`
+	ReferralCode         *string `json:"referral_code,omitempty" validate:"omitempty,max=12"`
 }
 
 func (dto *RegisterUserDTO) ErrorMessages() map[string]string {
@@ -28,9 +29,27 @@ func (dto *RegisterUserDTO) ErrorMessages() map[string]string {
 		"PasswordConfirmation.required": "Password confirmation is required",
 		"PasswordConfirmation.eqfield":  "Password confirmation must match the password",
 		"PasswordConfirmation.min":      "Password confirmation must be at least 8 characters",
+		"ReferralCode.max":              "Referral code cannot exceed 12 characters",
 	}
 }
 
 type AccountActivationDTO struct {
 	Token string `json:"token" validate:"required"`
 }
+
+type ChangePasswordDTO struct {
+	CurrentPassword      string `json:"current_password" validate:"required"`
+	Password             string `json:"password" validate:"required,min=8,max=100"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
+}
+
+func (dto *ChangePasswordDTO) ErrorMessages() map[string]string {
+	return map[string]string{
+		"CurrentPassword.required":      "Current password is required",
+		"Password.required":             "Password is required",
+		"Password.min":                  "Password must be at least 8 characters",
+		"Password.max":                  "Password cannot exceed 100 characters",
+		"PasswordConfirmation.required": "Password confirmation is required",
+		"PasswordConfirmation.eqfield":  "Password confirmation must match the password",
+	}
+}
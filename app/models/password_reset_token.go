@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// A single use token that lets a user who forgot their password choose a new one
+// Only the SHA-256 hash of the token is stored, the token itself is only sent by email
+type PasswordResetToken struct {
+	ID        uint32         `json:"id"         gorm:"primaryKey;autoIncrement"`
+	UserID    uint32         `json:"user_id"    gorm:"not null;index"`
+	User      User           `json:"-"          gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	TokenHash string         `json:"-"          gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time      `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time     `json:"used_at"`
+	IPAddress string         `json:"ip_address" gorm:"type:varchar(45);not null;default:''"`
+	CreatedAt time.Time      `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// Check whether the token can still be used at the given time
+func (t *PasswordResetToken) IsUsableAt(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
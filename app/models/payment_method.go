@@ -0,0 +1,58 @@
+package models
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"time"
+)
+
+type PaymentMethod struct {
+	ID                uint32      `json:"id" gorm:"primaryKey"`
+	Name              string      `json:"name" gorm:"type:varchar(100);not null"`
+	PaymentType       string      `json:"payment_type" gorm:"type:varchar(50);not null"`
+	Channel           string      `json:"channel" gorm:"column:payment_channel;type:varchar(50);not null;unique"`
+	Provider          string      `json:"provider" gorm:"type:varchar(20);not null"`
+	MinAmount         money.Money `json:"min_amount" gorm:"embedded;embeddedPrefix:min_"`
+	MaxAmount         money.Money `json:"max_amount" gorm:"embedded;embeddedPrefix:max_"`
+	Description       string      `json:"description" gorm:"type:varchar(255)"`
+	LogoURL           string      `json:"logo_url" gorm:"type:text"`
+	ExpiryDuration    int         `json:"expiry_duration" gorm:"type:int;not null;default:0"`
+	ExpiryUnit        string      `json:"expiry_unit" gorm:"type:varchar(10)"`
+	VANumberMinLength int         `json:"va_number_min_length" gorm:"column:va_number_min_length;type:int;not null;default:0"`
+	VANumberMaxLength int         `json:"va_number_max_length" gorm:"column:va_number_max_length;type:int;not null;default:0"`
+	FeeFlat           money.Money `json:"fee_flat" gorm:"embedded;embeddedPrefix:fee_flat_"`
+	FeePercentage     float64     `json:"fee_percentage" gorm:"type:decimal(5,2);not null;default:0"`
+	IsEnabled         bool        `json:"is_enabled" gorm:"not null"`
+	DisplayOrder      int         `json:"display_order" gorm:"type:int;not null;default:0"`
+	CreatedAt         time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+
+	MaintenanceWindows []PaymentMethodMaintenanceWindow `json:"maintenance_windows,omitempty" gorm:"foreignKey:PaymentMethodID"`
+}
+
+// Find the maintenance window the payment method is in at the given time
+// It returns nil when the payment method is not under maintenance
+func (pm *PaymentMethod) ActiveMaintenanceWindow(at time.Time) *PaymentMethodMaintenanceWindow {
+	for i := range pm.MaintenanceWindows {
+		if pm.MaintenanceWindows[i].Covers(at) {
+			return &pm.MaintenanceWindows[i]
+		}
+	}
+
+	return nil
+}
+
+// A period in which a payment method cannot be used, e.g. a scheduled downtime of the bank
+type PaymentMethodMaintenanceWindow struct {
+	ID              uint32    `json:"id" gorm:"primaryKey"`
+	PaymentMethodID uint32    `json:"payment_method_id" gorm:"type:int;not null;index"`
+	StartsAt        time.Time `json:"starts_at" gorm:"type:timestamp;not null"`
+	EndsAt          time.Time `json:"ends_at" gorm:"type:timestamp;not null"`
+	Reason          string    `json:"reason" gorm:"type:varchar(255)"`
+	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Check whether the maintenance window is ongoing at the given time
+func (w *PaymentMethodMaintenanceWindow) Covers(at time.Time) bool {
+	return !at.Before(w.StartsAt) && at.Before(w.EndsAt)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Processing statuses of a payment notification
+const (
+	PaymentNotificationStatusReceived  = "received"
+	PaymentNotificationStatusProcessed = "processed"
+	PaymentNotificationStatusDuplicate = "duplicate"
+	PaymentNotificationStatusIgnored   = "ignored"
+	PaymentNotificationStatusRejected  = "rejected"
+	PaymentNotificationStatusFailed    = "failed"
+)
+
+type PaymentNotification struct {
+	ID                   uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Provider             string     `json:"provider" gorm:"type:varchar(20);not null"`
+	PaymentTransactionID *uuid.UUID `json:"payment_transaction_id,omitempty" gorm:"type:uuid;index"`
+	TransactionID        *string    `json:"transaction_id,omitempty" gorm:"type:varchar(100)"`
+	TransactionStatus    *string    `json:"transaction_status,omitempty" gorm:"type:varchar(20)"`
+	DedupeKey            *string    `json:"-" gorm:"type:varchar(64);index"`
+	Payload              string     `json:"payload" gorm:"type:text;not null"`
+	Status               string     `json:"status" gorm:"type:varchar(20);not null"`
+	Error                *string    `json:"error,omitempty" gorm:"type:text"`
+	Attempts             int        `json:"attempts" gorm:"type:int;not null;default:0"`
+	ProcessedAt          *time.Time `json:"processed_at,omitempty" gorm:"type:timestamp"`
+	CreatedAt            time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt            time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
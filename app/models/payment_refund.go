@@ -0,0 +1,22 @@
+package models
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PaymentRefund struct {
+	ID                   uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PaymentTransactionID uuid.UUID   `json:"payment_transaction_id" gorm:"type:uuid;not null;index"`
+	RefundKey            string      `json:"refund_key" gorm:"type:varchar(100);not null;unique"`
+	Amount               money.Money `json:"amount" gorm:"embedded"`
+	Reason               string      `json:"reason" gorm:"type:varchar(255);not null"`
+	Status               string      `json:"status" gorm:"type:varchar(20);not null"`
+	RefundedBy           *uint32     `json:"refunded_by,omitempty" gorm:"type:int"`
+	CreatedAt            time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt            time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+
+	PaymentTransaction *PaymentTransaction `json:"payment_transaction,omitempty" gorm:"foreignKey:PaymentTransactionID"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"senkou-catalyst-be/utils/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,11 +10,11 @@ import (
 
 type PaymentTransaction struct {
 	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Provider        string         `json:"provider" gorm:"type:varchar(20);not null;default:'midtrans'"`
 	PaymentType     string         `json:"payment_type" gorm:"type:varchar(50);not null"`
 	PaymentChannel  string         `json:"payment_channel" gorm:"type:varchar(50);not null"`
 	FraudStatus     string         `json:"fraud_status" gorm:"type:varchar(20);default:'pending'"`
-	Amount          float64        `json:"amount" gorm:"type:decimal(15,2);not null"`
-	Currency        string         `json:"currency" gorm:"type:varchar(10);not null;default:'IDR'"`
+	Amount          money.Money    `json:"amount" gorm:"embedded"`
 	Status          string         `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
 	TransactionID   *string        `json:"transaction_id,omitempty" gorm:"type:varchar(100)"`
 	TransactionTime *time.Time     `json:"transaction_time,omitempty" gorm:"type:timestamp"`
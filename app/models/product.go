@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"senkou-catalyst-be/utils/money"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,7 +18,7 @@ type Product struct {
 	Category     *Category       `json:"-" gorm:"foreignKey:CategoryID;references:ID"`
 	Interactions []ProductMetric `json:"-" gorm:"foreignKey:ProductID;references:ID"`
 	Title        string          `json:"title" gorm:"type:varchar(150);not null"`
-	Price        string          `json:"price" gorm:"type:varchar(30);not null"`
+	Price        money.Money     `json:"price" gorm:"embedded;embeddedPrefix:price_"`
 	Description  string          `json:"description" gorm:"type:text"`
 	AffiliateURL string          `json:"affiliate_url" gorm:"type:text;not null"`
 	Photos       PhotoArray      `json:"photos" gorm:"type:json;default:'[]'"`
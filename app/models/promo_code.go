@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"senkou-catalyst-be/utils/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PromoCodeTypePercent = "percent"
+	PromoCodeTypeFixed   = "fixed"
+	PromoCodeTypeVoucher = "voucher"
+)
+
+// A code that discounts the purchase of a subscription, or a prepaid voucher that grants days of a subscription without a payment
+// A code can be restricted to some subscriptions, by family so it keeps applying to their newer versions.
+// Every redemption is an order that refers to the code, an order that failed no longer counts against the limits
+type PromoCode struct {
+	ID                    uint32         `json:"id"                       gorm:"type:int;primaryKey"`
+	Code                  string         `json:"code"                     gorm:"type:varchar(50);not null"`
+	Description           string         `json:"description"              gorm:"type:varchar(255)"`
+	Type                  string         `json:"type"                     gorm:"type:varchar(20);not null"`
+	PercentOff            int16          `json:"percent_off"              gorm:"type:smallint;not null;default:0"`
+	AmountOff             money.Money    `json:"amount_off"               gorm:"embedded;embeddedPrefix:amount_off_"`
+	GrantedDays           int16          `json:"granted_days"             gorm:"type:smallint;not null;default:0"`
+	SubscriptionFamilyIDs IDArray        `json:"subscription_family_ids"  gorm:"type:json;not null;default:'[]'"`
+	MaxRedemptions        *int           `json:"max_redemptions"          gorm:"type:int"`
+	MaxRedemptionsPerUser int            `json:"max_redemptions_per_user" gorm:"type:int;not null;default:1"`
+	StartsAt              *time.Time     `json:"starts_at"                gorm:"type:timestamp"`
+	EndsAt                *time.Time     `json:"ends_at"                  gorm:"type:timestamp"`
+	IsActive              bool           `json:"is_active"                gorm:"type:boolean;not null;default:true"`
+	CreatedAt             time.Time      `json:"created_at"               gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedAt             time.Time      `json:"updated_at"               gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	DeletedAt             gorm.DeletedAt `json:"-"                        gorm:"type:timestamp;index"`
+}
+
+// Check whether the code is a voucher that grants days without a payment
+func (p *PromoCode) IsVoucher() bool {
+	return p.Type == PromoCodeTypeVoucher
+}
+
+// Check whether the code can be redeemed at the given time
+func (p *PromoCode) IsRedeemableAt(at time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+
+	if p.StartsAt != nil && at.Before(*p.StartsAt) {
+		return false
+	}
+
+	return p.EndsAt == nil || at.Before(*p.EndsAt)
+}
+
+// Check whether the code applies to a subscription
+// A code without subscriptions applies to every subscription
+func (p *PromoCode) AppliesTo(subscription *Subscription) bool {
+	if len(p.SubscriptionFamilyIDs) == 0 {
+		return true
+	}
+
+	for _, familyID := range p.SubscriptionFamilyIDs {
+		if familyID == subscription.Family() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the discount of the code on a price
+// A fixed discount never exceeds the price and a voucher covers the whole price
+func (p *PromoCode) Discount(price money.Money) money.Money {
+	switch p.Type {
+	case PromoCodeTypePercent:
+		return price.Percent(float64(p.PercentOff)).RoundToMajor()
+	case PromoCodeTypeFixed:
+		if !p.AmountOff.SameCurrency(price) {
+			return money.Zero(price.CurrencyCode())
+		}
+
+		if p.AmountOff.Cmp(price) > 0 {
+			return price
+		}
+
+		return p.AmountOff
+	case PromoCodeTypeVoucher:
+		return price
+	}
+
+	return money.Zero(price.CurrencyCode())
+}
+
+type IDArray []uint32
+
+func (a IDArray) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "[]", nil
+	}
+	return json.Marshal([]uint32(a))
+}
+
+func (a *IDArray) Scan(value any) error {
+	if value == nil {
+		*a = IDArray{}
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("cannot scan id array")
+	}
+
+	return json.Unmarshal(bytes, a)
+}
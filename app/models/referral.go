@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReferralStatusPending  = "pending"
+	ReferralStatusRewarded = "rewarded"
+	ReferralStatusRejected = "rejected"
+)
+
+// Why a referral was not rewarded, the reasons are kept from users so the fraud guards cannot be probed
+const (
+	ReferralRejectSelfReferral    = "self_referral"
+	ReferralRejectSameEmailDomain = "same_email_domain"
+	ReferralRejectSamePhone       = "same_phone"
+	ReferralRejectReferrerRemoved = "referrer_removed"
+	ReferralRejectRewardLimit     = "reward_limit"
+)
+
+// A user who signed up with the referral code of another user
+// The referrer is rewarded once, when the first paid order of the referred user settles
+type Referral struct {
+	ID                 uint32     `json:"id"                   gorm:"type:int;primaryKey"`
+	ReferrerID         uint32     `json:"referrer_id"          gorm:"type:int;not null;index"`
+	ReferredID         uint32     `json:"referred_id"          gorm:"type:int;not null;unique"`
+	Status             string     `json:"status"               gorm:"type:varchar(20);not null;default:'pending'"`
+	RejectReason       *string    `json:"-"                    gorm:"type:varchar(50)"`
+	RewardDays         int16      `json:"reward_days"          gorm:"type:smallint;not null;default:0"`
+	OrderID            *uuid.UUID `json:"order_id,omitempty"   gorm:"type:uuid"`
+	UserSubscriptionID *uint32    `json:"-"                    gorm:"type:int"`
+	RewardedAt         *time.Time `json:"rewarded_at"          gorm:"type:timestamp"`
+	CreatedAt          time.Time  `json:"created_at"           gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time  `json:"updated_at"           gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+
+	Referrer *User `json:"-"                  gorm:"foreignKey:ReferrerID"`
+	Referred *User `json:"referred,omitempty" gorm:"foreignKey:ReferredID"`
+}
+
+// Reject the referral so it is never rewarded
+func (r *Referral) Reject(reason string) {
+	r.Status = ReferralStatusRejected
+	r.RejectReason = &reason
+}
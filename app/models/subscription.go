@@ -1,19 +1,60 @@
 package models
 
 import (
+	"senkou-catalyst-be/utils/money"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// The name of the subscription every user falls back to when a paid subscription ends
+const FreeTierSubscriptionName = "Free tier"
+
+// A subscription on offer
+// A subscription with trial days can be tried once per user and email without a payment, by default only by users
+// who never paid for a subscription
+// Changing the price, the duration or the plans of a subscription that was already bought stores a new version
+// in the same family and retires the old one, so its subscribers keep the terms they bought until they renew
 type Subscription struct {
-	ID          uint32             `json:"id"           gorm:"type:int;primaryKey"`
-	Name        string             `json:"name"         gorm:"type:varchar(100);not null"`
-	Price       float32            `json:"price"        gorm:"type:decimal(10,2);not null"`
-	Description string             `json:"description"  gorm:"type:text"`
-	Duration    int16              `json:"duration"     gorm:"type:int;not null"`
-	Plans       []SubscriptionPlan `json:"plans"        gorm:"foreignKey:SubID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	CreatedAt   time.Time          `json:"created_at"   gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time          `json:"updated_at"   gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
-	DeletedAt   gorm.DeletedAt     `json:"-"            gorm:"type:timestamp;index"`
+	ID                uint32             `json:"id"                       gorm:"type:int;primaryKey"`
+	FamilyID          uint32             `json:"family_id"                gorm:"type:int"`
+	Version           int                `json:"version"                  gorm:"type:int;not null;default:1"`
+	Name              string             `json:"name"                     gorm:"type:varchar(100);not null"`
+	Price             money.Money        `json:"price"                    gorm:"embedded;embeddedPrefix:price_"`
+	Description       string             `json:"description"              gorm:"type:text"`
+	Duration          int16              `json:"duration"                 gorm:"type:int;not null"`
+	TrialDays         int16              `json:"trial_days"               gorm:"type:smallint;not null;default:0"`
+	TrialForPaidUsers bool               `json:"trial_for_paid_users"     gorm:"type:boolean;not null;default:false"`
+	Plans             []SubscriptionPlan `json:"plans"                    gorm:"foreignKey:SubID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	SupersededByID    *uint32            `json:"superseded_by_id,omitempty" gorm:"type:int"`
+	RetiredAt         *time.Time         `json:"retired_at,omitempty"     gorm:"type:timestamp"`
+	CreatedAt         time.Time          `json:"created_at"               gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedAt         time.Time          `json:"updated_at"               gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	DeletedAt         gorm.DeletedAt     `json:"-"                        gorm:"type:timestamp;index"`
+}
+
+// Get the family of the subscription
+// Every version of a subscription shares the ID of its first version as family
+func (s *Subscription) Family() uint32 {
+	if s.FamilyID == 0 {
+		return s.ID
+	}
+
+	return s.FamilyID
+}
+
+// Check whether the subscription is no longer offered
+// A retired subscription is kept for its subscribers until they renew or their subscription expires
+func (s *Subscription) IsRetired() bool {
+	return s.RetiredAt != nil
+}
+
+// Check whether the subscription is the free tier
+func (s *Subscription) IsFreeTier() bool {
+	return s.Name == FreeTierSubscriptionName && s.Price.IsZero()
+}
+
+// Check whether the subscription can be tried without a payment
+func (s *Subscription) OffersTrial() bool {
+	return s.TrialDays > 0 && s.Price.IsPositive() && !s.IsRetired()
 }
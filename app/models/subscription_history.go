@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SubscriptionEventRenewed            = "renewed"
+	SubscriptionEventExpired            = "expired"
+	SubscriptionEventUpgraded           = "upgraded"
+	SubscriptionEventDowngradeScheduled = "downgrade_scheduled"
+	SubscriptionEventDowngraded         = "downgraded"
+	SubscriptionEventTrialStarted       = "trial_started"
+	SubscriptionEventTrialEnded         = "trial_ended"
+	SubscriptionEventTrialConverted     = "trial_converted"
+	SubscriptionEventReferralReward     = "referral_reward"
+)
+
+// A change in the lifetime of a user subscription, e.g. a renewal, a plan change or an expiry
+type SubscriptionHistory struct {
+	ID                 uint32     `json:"id" gorm:"primaryKey"`
+	UserID             uint32     `json:"user_id" gorm:"type:int;not null;index"`
+	UserSubscriptionID uint32     `json:"user_subscription_id" gorm:"type:int;not null;index"`
+	SubscriptionID     uint32     `json:"subscription_id" gorm:"type:int;not null"`
+	OrderID            *uuid.UUID `json:"order_id,omitempty" gorm:"type:uuid"`
+	Event              string     `json:"event" gorm:"type:varchar(20);not null"`
+	StartedAt          time.Time  `json:"started_at" gorm:"type:timestamp;not null"`
+	ExpiredAt          time.Time  `json:"expired_at" gorm:"type:timestamp;not null"`
+	CreatedAt          time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// A reminder sent to a user before the subscription expires
+// A reminder is sent once per expiry date, so a renewed subscription is reminded again before its new expiry
+type SubscriptionExpiryReminder struct {
+	ID                 uint32    `json:"id" gorm:"primaryKey"`
+	UserSubscriptionID uint32    `json:"user_subscription_id" gorm:"type:int;not null;uniqueIndex:idx_subscription_expiry_reminders_unique"`
+	ExpiredAt          time.Time `json:"expired_at" gorm:"type:timestamp;not null;uniqueIndex:idx_subscription_expiry_reminders_unique"`
+	DaysBefore         int       `json:"days_before" gorm:"type:int;not null;uniqueIndex:idx_subscription_expiry_reminders_unique"`
+	CreatedAt          time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
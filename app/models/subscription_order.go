@@ -1,24 +1,45 @@
 package models
 
 import (
+	"senkou-catalyst-be/utils/money"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+const (
+	OrderTypeNew       = "new"
+	OrderTypeRenewal   = "renewal"
+	OrderTypeUpgrade   = "upgrade"
+	OrderTypeDowngrade = "downgrade"
+	OrderTypeVoucher   = "voucher"
+)
+
+// An order of a subscription
+// An order placed with a promo code records the code and its discount, a voucher order grants GrantedDays without a payment
 type SubscriptionOrder struct {
 	ID                   uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	UserID               uint32         `json:"user_id" gorm:"not null;index"`
 	SubscriptionID       uint32         `json:"subscription_id" gorm:"not null;index"`
 	PaymentTransactionID *uuid.UUID     `json:"payment_transaction_id,omitempty" gorm:"type:uuid;index"`
-	Amount               float64        `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Type                 string         `json:"type" gorm:"type:varchar(20);not null;default:'new'"`
+	UserSubscriptionID   *uint32        `json:"user_subscription_id,omitempty" gorm:"type:int"`
+	Amount               money.Money    `json:"amount" gorm:"embedded"`
+	PromoCodeID          *uint32        `json:"promo_code_id,omitempty" gorm:"type:int"`
+	Discount             money.Money    `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	GrantedDays          int16          `json:"granted_days,omitempty" gorm:"type:smallint;not null;default:0"`
 	Status               string         `json:"status" gorm:"type:varchar(20);default:'pending'"`
+	InvoiceNumber        *string        `json:"invoice_number,omitempty" gorm:"type:varchar(30);unique"`
+	InvoicePath          *string        `json:"-" gorm:"type:varchar(255)"`
+	InvoiceSentAt        *time.Time     `json:"invoice_sent_at,omitempty" gorm:"type:timestamp"`
 	CreatedAt            time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt            time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt            gorm.DeletedAt `json:"-" gorm:"index"`
 
-	User               *User               `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Subscription       *Subscription       `json:"subscription,omitempty" gorm:"foreignKey:SubscriptionID"`
-	PaymentTransaction *PaymentTransaction `json:"payment_transaction,omitempty" gorm:"foreignKey:PaymentTransactionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	User               *User                   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Subscription       *Subscription           `json:"subscription,omitempty" gorm:"foreignKey:SubscriptionID"`
+	PromoCode          *PromoCode              `json:"promo_code,omitempty" gorm:"foreignKey:PromoCodeID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	PaymentTransaction *PaymentTransaction     `json:"payment_transaction,omitempty" gorm:"foreignKey:PaymentTransactionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	Items              []SubscriptionOrderItem `json:"items,omitempty" gorm:"foreignKey:OrderID"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OrderItemTypeSubscription = "subscription"
+	OrderItemTypeTax          = "tax"
+	OrderItemTypeFee          = "fee"
+	OrderItemTypeDiscount     = "discount"
+)
+
+type SubscriptionOrderItem struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	OrderID   uuid.UUID   `json:"order_id" gorm:"type:uuid;not null;index"`
+	Type      string      `json:"type" gorm:"type:varchar(20);not null"`
+	Name      string      `json:"name" gorm:"type:varchar(100);not null"`
+	Quantity  int         `json:"quantity" gorm:"type:int;not null;default:1"`
+	Price     money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Amount    money.Money `json:"amount" gorm:"embedded"`
+	CreatedAt time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -18,6 +18,7 @@ type User struct {
 	Role            string         `json:"role"         gorm:"type:varchar(20);not null;default:user"`
 	IsOauth         bool           `json:"is_oauth"     gorm:"type:boolean;not null;default:false"`
 	EmailVerifiedAt *time.Time     `json:"email_verified_at" gorm:"type:timestamp;default:null"`
+	ReferralCode    string         `json:"referral_code" gorm:"type:varchar(12);unique;not null"`
 	CreatedAt       time.Time      `json:"created_at"   gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
 	UpdatedAt       time.Time      `json:"updated_at"   gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
 	DeletedAt       gorm.DeletedAt `json:"-"            gorm:"type:timestamp;index"`
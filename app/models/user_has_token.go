@@ -2,13 +2,20 @@ package models
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// A refresh token of a user session, only the SHA-256 hash of the token is stored
+// A token is rotated once it is used, the session keeps every token it was issued so a reused one can be recognized
 type UserHasToken struct {
-	ID        uint      `json:"id"         gorm:"primaryKey"`
-	UserID    uint32    `json:"user_id"    gorm:"not null"`
-	User      User      `json:"user"       gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Token     string    `json:"token"      gorm:"type:varchar(255);not null;unique"`
-	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	ID        uint       `json:"id"         gorm:"primaryKey"`
+	UserID    uint32     `json:"user_id"    gorm:"not null"`
+	User      User       `json:"user"       gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	SessionID uuid.UUID  `json:"session_id" gorm:"type:uuid;not null;index"`
+	TokenHash string     `json:"-"          gorm:"type:varchar(64);not null;unique"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"type:timestamp;not null"`
+	RotatedAt *time.Time `json:"rotated_at" gorm:"type:timestamp"`
+	CreatedAt time.Time  `json:"created_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
 }
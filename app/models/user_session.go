@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Why a session was ended before it expired
+const (
+	SessionRevokeLogout          = "logout"
+	SessionRevokeTokenReuse      = "token_reuse"
+	SessionRevokeByUser          = "revoked_by_user"
+	SessionRevokeOtherDevices    = "logout_other_devices"
+	SessionRevokePasswordChanged = "password_changed"
+	SessionRevokePasswordReset   = "password_reset"
+)
+
+// A login of a user on a device, every refresh token rotated from the login belongs to the same session
+// Revoking the session ends the whole family of its refresh tokens
+type UserSession struct {
+	ID           uuid.UUID  `json:"id"            gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID       uint32     `json:"user_id"       gorm:"type:int;not null;index"`
+	UserAgent    string     `json:"user_agent"    gorm:"type:varchar(512);not null;default:''"`
+	IPAddress    string     `json:"ip_address"    gorm:"type:varchar(45);not null;default:''"`
+	LastUsedAt   time.Time  `json:"last_used_at"  gorm:"type:timestamp;not null"`
+	ExpiresAt    time.Time  `json:"expires_at"    gorm:"type:timestamp;not null"`
+	RevokedAt    *time.Time `json:"revoked_at"    gorm:"type:timestamp"`
+	RevokeReason *string    `json:"revoke_reason" gorm:"type:varchar(50)"`
+	CreatedAt    time.Time  `json:"created_at"    gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time  `json:"updated_at"    gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+}
+
+// Check whether the session can still be refreshed at the given time
+func (s *UserSession) IsActiveAt(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Revoke the session so none of its refresh tokens can be used again
+func (s *UserSession) Revoke(reason string, now time.Time) {
+	s.RevokedAt = &now
+	s.RevokeReason = &reason
+}
@@ -4,16 +4,27 @@ import (
 	"time"
 )
 
+// A subscription of a user
+// A trial keeps TrialEndsAt and TrialEmail for good, so the same user or email cannot try a subscription twice
 type UserSubscription struct {
-	ID            uint32       `json:"id"             gorm:"type:int;primaryKey"`
-	UserID        uint32       `json:"user_id"        gorm:"type:bigint;not null"`
-	User          User         `json:"user"           gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	SubID         uint32       `json:"sub_id"         gorm:"type:bigint;not null"`
-	Sub           Subscription `json:"sub"            gorm:"foreignKey:SubID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	StartedAt     time.Time    `json:"started_at"     gorm:"type:timestamp;not null"`
-	ExpiredAt     time.Time    `json:"expired_at"     gorm:"type:timestamp;not null"`
-	IsActive      bool         `json:"is_active"      gorm:"type:boolean;default:false"`
-	PaymentStatus string       `json:"payment_status" gorm:"type:varchar(50);not null;default:'pending'"`
-	CreatedAt     time.Time    `json:"created_at"     gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
-	UpdatedAt     time.Time    `json:"updated_at"     gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	ID               uint32       `json:"id"             gorm:"type:int;primaryKey"`
+	UserID           uint32       `json:"user_id"        gorm:"type:bigint;not null"`
+	User             User         `json:"user"           gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	SubID            uint32       `json:"sub_id"         gorm:"type:bigint;not null"`
+	Sub              Subscription `json:"sub"            gorm:"foreignKey:SubID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	StartedAt        time.Time    `json:"started_at"     gorm:"type:timestamp;not null"`
+	ExpiredAt        time.Time    `json:"expired_at"     gorm:"type:timestamp;not null"`
+	IsActive         bool         `json:"is_active"      gorm:"type:boolean;default:false"`
+	IsScheduled      bool         `json:"is_scheduled"   gorm:"type:boolean;not null;default:false"`
+	PaymentStatus    string       `json:"payment_status" gorm:"type:varchar(50);not null;default:'pending'"`
+	TrialEndsAt      *time.Time   `json:"trial_ends_at,omitempty"      gorm:"type:timestamp"`
+	TrialConvertedAt *time.Time   `json:"trial_converted_at,omitempty" gorm:"type:timestamp"`
+	TrialEmail       *string      `json:"-"                            gorm:"type:varchar(255)"`
+	CreatedAt        time.Time    `json:"created_at"     gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time    `json:"updated_at"     gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+}
+
+// Check whether the subscription is a trial that was not paid for yet
+func (s *UserSubscription) IsTrial() bool {
+	return s.TrialEndsAt != nil && s.TrialConvertedAt == nil
 }
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/converter"
+	"senkou-catalyst-be/utils/query"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The longest range of every interval, in buckets, so a single report stays cheap to compute
+var analyticsIntervalLimits = map[string]int{
+	"day":   366,
+	"week":  260,
+	"month": 120,
+}
+
+var analyticsGroups = []string{"none", "plan", "payment_type", "payment_channel"}
+
+type AnalyticsService interface {
+	GetPaymentAnalytics(params *query.QueryParams) (*dtos.PaymentAnalyticsReport, *errors.CustomError)
+	ExportPaymentAnalytics(params *query.QueryParams) ([]byte, string, *errors.CustomError)
+}
+
+type AnalyticsServiceInstance struct {
+	AnalyticsRepository repositories.AnalyticsRepository
+}
+
+func NewAnalyticsService(analyticsRepository repositories.AnalyticsRepository) AnalyticsService {
+	return &AnalyticsServiceInstance{
+		AnalyticsRepository: analyticsRepository,
+	}
+}
+
+// Get the payment analytics report
+// This function reports revenue, refunds, MRR, paid subscribers, churn and conversion for every
+// time bucket between date_from and date_to, broken down by the group_by filter
+// The interval filter sets the bucket size (day, week or month), the range defaults to the last 30 days
+// It returns the report with its rows and a summary of the whole range
+func (s *AnalyticsServiceInstance) GetPaymentAnalytics(params *query.QueryParams) (*dtos.PaymentAnalyticsReport, *errors.CustomError) {
+	analyticsQuery, appError := newPaymentAnalyticsQuery(params)
+	if appError != nil {
+		return nil, appError
+	}
+
+	series := []func(*dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error){
+		s.AnalyticsRepository.GetRevenueSeries,
+		s.AnalyticsRepository.GetRefundSeries,
+		s.AnalyticsRepository.GetConversionSeries,
+		s.AnalyticsRepository.GetSubscriberSeries,
+	}
+
+	rows := make([]dtos.PaymentAnalyticsRow, 0)
+	for _, getSeries := range series {
+		seriesRows, err := getSeries(analyticsQuery)
+		if err != nil {
+			return nil, errors.Internal("Failed to get payment analytics", err.Error())
+		}
+
+		rows = append(rows, seriesRows...)
+	}
+
+	rows = mergeAnalyticsRows(rows)
+
+	return &dtos.PaymentAnalyticsReport{
+		DateFrom: analyticsQuery.From.Format("2006-01-02"),
+		DateTo:   analyticsQuery.To.AddDate(0, 0, -1).Format("2006-01-02"),
+		Interval: analyticsQuery.Interval,
+		GroupBy:  analyticsQuery.GroupBy,
+		Summary:  summarizeAnalyticsRows(rows),
+		Rows:     rows,
+	}, nil
+}
+
+// Export the payment analytics report as CSV
+// This function builds the same report as GetPaymentAnalytics and writes one line per row
+// It returns the CSV content along with its file name
+func (s *AnalyticsServiceInstance) ExportPaymentAnalytics(params *query.QueryParams) ([]byte, string, *errors.CustomError) {
+	report, appError := s.GetPaymentAnalytics(params)
+	if appError != nil {
+		return nil, "", appError
+	}
+
+	content, err := writeAnalyticsCSV(report.Rows)
+	if err != nil {
+		return nil, "", errors.Internal("Failed to export payment analytics", err.Error())
+	}
+
+	filename := "payment-analytics-" + report.DateFrom + "-" + report.DateTo + ".csv"
+
+	return content, filename, nil
+}
+
+// Build the analytics query from the request parameters
+func newPaymentAnalyticsQuery(params *query.QueryParams) (*dtos.PaymentAnalyticsQuery, *errors.CustomError) {
+	interval := params.Filters["interval"]
+	if interval == "" {
+		interval = "day"
+	}
+
+	limit, ok := analyticsIntervalLimits[interval]
+	if !ok {
+		return nil, errors.BadRequest("Invalid interval", "Interval must be one of day, week or month")
+	}
+
+	groupBy := params.Filters["group_by"]
+	if groupBy == "" {
+		groupBy = "none"
+	}
+
+	if !slices.Contains(analyticsGroups, groupBy) {
+		return nil, errors.BadRequest("Invalid group", "Group must be one of none, plan, payment_type or payment_channel")
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	dateTo := converter.ParseDate(params.DateTo, today)
+	dateFrom := converter.ParseDate(params.DateFrom, dateTo.AddDate(0, 0, -29))
+
+	if dateFrom.After(dateTo) {
+		return nil, errors.BadRequest("Invalid date range", "date_from must not be after date_to")
+	}
+
+	if countAnalyticsBuckets(dateFrom, dateTo, interval) > limit {
+		return nil, errors.BadRequest("Date range is too long", map[string]any{
+			"interval":    interval,
+			"max_buckets": limit,
+		})
+	}
+
+	return &dtos.PaymentAnalyticsQuery{
+		From:     dateFrom,
+		To:       dateTo.AddDate(0, 0, 1),
+		Interval: interval,
+		GroupBy:  groupBy,
+	}, nil
+}
+
+// Count the buckets of an interval between two dates, both inclusive
+func countAnalyticsBuckets(from, to time.Time, interval string) int {
+	switch interval {
+	case "week":
+		return int(to.Sub(from).Hours()/(24*7)) + 1
+	case "month":
+		return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	default:
+		return int(to.Sub(from).Hours()/24) + 1
+	}
+}
+
+// Merge the rows of every series into one row per bucket and group, then compute the derived metrics
+// Rows are sorted by bucket and then by group
+func mergeAnalyticsRows(rows []dtos.PaymentAnalyticsRow) []dtos.PaymentAnalyticsRow {
+	merged := make(map[string]*dtos.PaymentAnalyticsRow)
+	keys := make([]string, 0)
+
+	for _, row := range rows {
+		key := row.Bucket.UTC().Format(time.RFC3339) + "|" + row.Group
+
+		current, ok := merged[key]
+		if !ok {
+			current = &dtos.PaymentAnalyticsRow{Bucket: row.Bucket, Group: row.Group}
+			merged[key] = current
+			keys = append(keys, key)
+		}
+
+		current.GrossRevenue = current.GrossRevenue.Add(row.GrossRevenue)
+		current.Refunds = current.Refunds.Add(row.Refunds)
+		current.MRR = current.MRR.Add(row.MRR)
+		current.ActiveSubscribers += row.ActiveSubscribers
+		current.StartingSubscribers += row.StartingSubscribers
+		current.ChurnedSubscribers += row.ChurnedSubscribers
+		current.CreatedPayments += row.CreatedPayments
+		current.ConvertedPayments += row.ConvertedPayments
+	}
+
+	sort.Strings(keys)
+
+	result := make([]dtos.PaymentAnalyticsRow, 0, len(keys))
+	for _, key := range keys {
+		row := merged[key]
+		row.NetRevenue = row.GrossRevenue.Sub(row.Refunds)
+		row.ChurnRate = analyticsRate(row.ChurnedSubscribers, row.StartingSubscribers)
+		row.ConversionRate = analyticsRate(row.ConvertedPayments, row.CreatedPayments)
+
+		result = append(result, *row)
+	}
+
+	return result
+}
+
+// Summarize the rows of a report
+// Flow metrics are summed over the whole range, snapshot metrics are taken from the last bucket and
+// the churn rate is measured against the subscribers at the start of the first bucket
+func summarizeAnalyticsRows(rows []dtos.PaymentAnalyticsRow) dtos.PaymentAnalyticsSummary {
+	summary := dtos.PaymentAnalyticsSummary{}
+	if len(rows) == 0 {
+		return summary
+	}
+
+	firstBucket, lastBucket := rows[0].Bucket, rows[len(rows)-1].Bucket
+	var startingSubscribers int64
+
+	for _, row := range rows {
+		summary.GrossRevenue = summary.GrossRevenue.Add(row.GrossRevenue)
+		summary.Refunds = summary.Refunds.Add(row.Refunds)
+		summary.ChurnedSubscribers += row.ChurnedSubscribers
+		summary.CreatedPayments += row.CreatedPayments
+		summary.ConvertedPayments += row.ConvertedPayments
+
+		if row.Bucket.Equal(firstBucket) {
+			startingSubscribers += row.StartingSubscribers
+		}
+
+		if row.Bucket.Equal(lastBucket) {
+			summary.MRR = summary.MRR.Add(row.MRR)
+			summary.ActiveSubscribers += row.ActiveSubscribers
+		}
+	}
+
+	summary.NetRevenue = summary.GrossRevenue.Sub(summary.Refunds)
+	summary.ChurnRate = analyticsRate(summary.ChurnedSubscribers, startingSubscribers)
+	summary.ConversionRate = analyticsRate(summary.ConvertedPayments, summary.CreatedPayments)
+
+	return summary
+}
+
+// Compute a rate rounded to four decimals, a rate over nothing is zero
+func analyticsRate(count, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return math.Round(float64(count)/float64(total)*10000) / 10000
+}
+
+// Write the rows of a report as CSV
+func writeAnalyticsCSV(rows []dtos.PaymentAnalyticsRow) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	writer := csv.NewWriter(buffer)
+
+	header := []string{
+		"bucket", "group", "gross_revenue", "refunds", "net_revenue", "mrr",
+		"active_subscribers", "starting_subscribers", "churned_subscribers", "churn_rate",
+		"created_payments", "converted_payments", "conversion_rate",
+	}
+
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		record := []string{
+			row.Bucket.Format("2006-01-02"),
+			row.Group,
+			row.GrossRevenue.Decimal(),
+			row.Refunds.Decimal(),
+			row.NetRevenue.Decimal(),
+			row.MRR.Decimal(),
+			strconv.FormatInt(row.ActiveSubscribers, 10),
+			strconv.FormatInt(row.StartingSubscribers, 10),
+			strconv.FormatInt(row.ChurnedSubscribers, 10),
+			strconv.FormatFloat(row.ChurnRate, 'f', 4, 64),
+			strconv.FormatInt(row.CreatedPayments, 10),
+			strconv.FormatInt(row.ConvertedPayments, 10),
+			strconv.FormatFloat(row.ConversionRate, 'f', 4, 64),
+		}
+
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/utils/money"
+	"senkou-catalyst-be/utils/query"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAnalyticsParams(dateFrom, dateTo string, filters map[string]string) *query.QueryParams {
+	params := &query.QueryParams{Filters: filters}
+
+	if dateFrom != "" {
+		from, _ := time.Parse("2006-01-02", dateFrom)
+		params.DateFrom = &from
+	}
+
+	if dateTo != "" {
+		to, _ := time.Parse("2006-01-02", dateTo)
+		params.DateTo = &to
+	}
+
+	return params
+}
+
+func newAnalyticsFixture() *fakeAnalyticsRepository {
+	first := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	second := time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC)
+
+	return &fakeAnalyticsRepository{
+		revenue: []dtos.PaymentAnalyticsRow{
+			{Bucket: first, Group: "bank_transfer", GrossRevenue: money.FromMajor(30000, money.IDR)},
+			{Bucket: second, Group: "bank_transfer", GrossRevenue: money.FromMajor(10000, money.IDR)},
+			{Bucket: second, Group: "qris", GrossRevenue: money.FromMajor(10000, money.IDR)},
+		},
+		refunds: []dtos.PaymentAnalyticsRow{
+			{Bucket: second, Group: "bank_transfer", Refunds: money.FromMajor(5000, money.IDR)},
+		},
+		conversions: []dtos.PaymentAnalyticsRow{
+			{Bucket: first, Group: "bank_transfer", CreatedPayments: 4, ConvertedPayments: 3},
+			{Bucket: second, Group: "qris", CreatedPayments: 2, ConvertedPayments: 1},
+		},
+		subscribers: []dtos.PaymentAnalyticsRow{
+			{Bucket: first, Group: "bank_transfer", StartingSubscribers: 4, ActiveSubscribers: 5, MRR: money.FromMajor(50000, money.IDR)},
+			{Bucket: second, Group: "bank_transfer", StartingSubscribers: 5, ActiveSubscribers: 4, ChurnedSubscribers: 1, MRR: money.FromMajor(40000, money.IDR)},
+			{Bucket: second, Group: "qris", StartingSubscribers: 0, ActiveSubscribers: 1, MRR: money.New(1071429, money.IDR)},
+		},
+	}
+}
+
+func TestGetPaymentAnalytics(t *testing.T) {
+	t.Run("Should merge every series into one row per bucket and group", func(t *testing.T) {
+		service := NewAnalyticsService(newAnalyticsFixture())
+
+		report, err := service.GetPaymentAnalytics(newAnalyticsParams("2025-09-01", "2025-09-02", map[string]string{
+			"group_by": "payment_type",
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(report.Rows) != 3 {
+			t.Fatalf("Expected 3 rows, got %d", len(report.Rows))
+		}
+
+		row := report.Rows[1]
+		if row.Group != "bank_transfer" {
+			t.Errorf("Expected bank_transfer, got %s", row.Group)
+		}
+
+		if !row.NetRevenue.Equal(money.FromMajor(5000, money.IDR)) {
+			t.Errorf("Expected IDR 5000.00, got %s", row.NetRevenue)
+		}
+
+		if row.ChurnRate != 0.2 {
+			t.Errorf("Expected 0.2, got %v", row.ChurnRate)
+		}
+
+		if report.Rows[2].ConversionRate != 0.5 {
+			t.Errorf("Expected 0.5, got %v", report.Rows[2].ConversionRate)
+		}
+
+		if !report.Rows[2].MRR.Equal(money.New(1071429, money.IDR)) {
+			t.Errorf("Expected IDR 10714.29, got %s", report.Rows[2].MRR)
+		}
+	})
+
+	t.Run("Should summarize flow metrics over the range and snapshot metrics at its end", func(t *testing.T) {
+		service := NewAnalyticsService(newAnalyticsFixture())
+
+		report, err := service.GetPaymentAnalytics(newAnalyticsParams("2025-09-01", "2025-09-02", nil))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		summary := report.Summary
+		if !summary.GrossRevenue.Equal(money.FromMajor(50000, money.IDR)) {
+			t.Errorf("Expected IDR 50000.00, got %s", summary.GrossRevenue)
+		}
+
+		if !summary.Refunds.Equal(money.FromMajor(5000, money.IDR)) {
+			t.Errorf("Expected IDR 5000.00, got %s", summary.Refunds)
+		}
+
+		if !summary.NetRevenue.Equal(money.FromMajor(45000, money.IDR)) {
+			t.Errorf("Expected IDR 45000.00, got %s", summary.NetRevenue)
+		}
+
+		if summary.ActiveSubscribers != 5 {
+			t.Errorf("Expected 5, got %d", summary.ActiveSubscribers)
+		}
+
+		if !summary.MRR.Equal(money.New(5071429, money.IDR)) {
+			t.Errorf("Expected IDR 50714.29, got %s", summary.MRR)
+		}
+
+		if summary.ChurnRate != 0.25 {
+			t.Errorf("Expected 0.25, got %v", summary.ChurnRate)
+		}
+
+		if summary.ConversionRate != 0.6667 {
+			t.Errorf("Expected 0.6667, got %v", summary.ConversionRate)
+		}
+	})
+
+	t.Run("Should query the whole last day of the range", func(t *testing.T) {
+		repository := newAnalyticsFixture()
+		service := NewAnalyticsService(repository)
+
+		if _, err := service.GetPaymentAnalytics(newAnalyticsParams("2025-09-01", "2025-09-30", map[string]string{
+			"interval": "week",
+		})); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if to := repository.lastQuery.To.Format("2006-01-02"); to != "2025-10-01" {
+			t.Errorf("Expected 2025-10-01, got %s", to)
+		}
+
+		if repository.lastQuery.Interval != "week" {
+			t.Errorf("Expected week, got %s", repository.lastQuery.Interval)
+		}
+	})
+
+	t.Run("Should reject unknown intervals, groups and ranges that are too long", func(t *testing.T) {
+		service := NewAnalyticsService(newAnalyticsFixture())
+
+		cases := []*query.QueryParams{
+			newAnalyticsParams("", "", map[string]string{"interval": "hour"}),
+			newAnalyticsParams("", "", map[string]string{"group_by": "user_id"}),
+			newAnalyticsParams("2025-09-02", "2025-09-01", nil),
+			newAnalyticsParams("2020-01-01", "2025-09-01", nil),
+		}
+
+		for _, params := range cases {
+			if _, err := service.GetPaymentAnalytics(params); err == nil || err.Code != 400 {
+				t.Errorf("Expected bad request error, got %v", err)
+			}
+		}
+	})
+}
+
+func TestExportPaymentAnalytics(t *testing.T) {
+	t.Run("Should export one CSV line per row", func(t *testing.T) {
+		service := NewAnalyticsService(newAnalyticsFixture())
+
+		content, filename, err := service.ExportPaymentAnalytics(newAnalyticsParams("2025-09-01", "2025-09-02", map[string]string{
+			"group_by": "payment_type",
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if filename != "payment-analytics-2025-09-01-2025-09-02.csv" {
+			t.Errorf("Expected payment-analytics-2025-09-01-2025-09-02.csv, got %s", filename)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("Expected 4 lines, got %d", len(lines))
+		}
+
+		expected := "2025-09-01,bank_transfer,30000.00,0.00,30000.00,50000.00,5,4,0,0.0000,4,3,0.7500"
+		if lines[1] != expected {
+			t.Errorf("Expected %s, got %s", expected, lines[1])
+		}
+	})
+}
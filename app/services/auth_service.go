@@ -1,77 +1,539 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
 	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
 	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/repositories"
 	"senkou-catalyst-be/utils/auth"
+	"senkou-catalyst-be/utils/config"
+	"senkou-catalyst-be/utils/queue"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	GenerateToken(userID uint32) (*dtos.GeneratedToken, *dtos.GeneratedToken, *errors.CustomError)
-	ValidateRefreshToken(token string) (uint32, *errors.CustomError)
-	InvalidateSession(userID uint32) *errors.CustomError
+	GenerateToken(userID uint32, userAgent string, ipAddress string) (*dtos.GeneratedToken, *dtos.GeneratedToken, *errors.CustomError)
+	RotateRefreshToken(refreshToken string, userAgent string, ipAddress string) (*dtos.GeneratedToken, *dtos.GeneratedToken, *errors.CustomError)
+	VerifySession(sessionID uuid.UUID) *errors.CustomError
+	InvalidateSession(userID uint32, sessionID uuid.UUID) *errors.CustomError
+	GetSessions(userID uint32, currentSessionID uuid.UUID) ([]dtos.UserSessionDTO, *errors.CustomError)
+	RevokeSession(userID uint32, sessionID uuid.UUID) *errors.CustomError
+	RevokeOtherSessions(userID uint32, currentSessionID uuid.UUID) (int64, *errors.CustomError)
+	ChangePassword(userID uint32, currentSessionID uuid.UUID, request *dtos.ChangePasswordDTO) *errors.CustomError
+	ForgotPassword(email string, ipAddress string) *errors.CustomError
+	ResetPassword(request *dtos.ResetPasswordDTO) *errors.CustomError
 }
 
 type AuthServiceInstance struct {
-	AuthRepository repositories.AuthRepository
-	JwtManager     *auth.JWTManager
+	AuthRepository          repositories.AuthRepository
+	UserRepository          repositories.UserRepository
+	PasswordResetRepository repositories.PasswordResetRepository
+	JwtManager              *auth.JWTManager
+	TransactionManager      repositories.TransactionManager
+	QueueService            *queue.QueueService
 }
 
-func NewAuthService(authRepository repositories.AuthRepository, jwtManager *auth.JWTManager) AuthService {
+func NewAuthService(authRepository repositories.AuthRepository, userRepository repositories.UserRepository, passwordResetRepository repositories.PasswordResetRepository, jwtManager *auth.JWTManager, transactionManager repositories.TransactionManager, queueService *queue.QueueService) AuthService {
 	return &AuthServiceInstance{
-		AuthRepository: authRepository,
-		JwtManager:     jwtManager,
+		AuthRepository:          authRepository,
+		UserRepository:          userRepository,
+		PasswordResetRepository: passwordResetRepository,
+		JwtManager:              jwtManager,
+		TransactionManager:      transactionManager,
+		QueueService:            queueService,
 	}
 }
 
+const (
+	accessTokenLifetime  = 24 * time.Hour
+	refreshTokenLifetime = 30 * 24 * time.Hour
+)
+
 // Generate token and refresh token for the user
-// This function generates a JWT token and a refresh token for the user
-// It stores the refresh token in the database for later validation
-func (s *AuthServiceInstance) GenerateToken(userID uint32) (*dtos.GeneratedToken, *dtos.GeneratedToken, *errors.CustomError) {
-	token, err := s.JwtManager.GenerateToken(userID, time.Now().Add(24*time.Hour))
+// This function starts a new session for the device the user logged in from
+// The access token carries the session ID and the refresh token is stored hashed for later rotation
+func (s *AuthServiceInstance) GenerateToken(userID uint32, userAgent string, ipAddress string) (*dtos.GeneratedToken, *dtos.GeneratedToken, *errors.CustomError) {
+	now := time.Now()
+	session := &models.UserSession{
+		ID:         uuid.New(),
+		UserID:     userID,
+		UserAgent:  truncateUserAgent(userAgent),
+		IPAddress:  ipAddress,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenLifetime),
+	}
+
+	var refreshToken *dtos.GeneratedToken
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		authRepository := s.AuthRepository.WithTx(tx)
+
+		if err := authRepository.StoreSession(session); err != nil {
+			return err
+		}
+
+		token, err := issueRefreshToken(authRepository, session)
+		if err != nil {
+			return err
+		}
+
+		refreshToken = token
+		return nil
+	})
+
+	if txErr != nil {
+		return nil, nil, errors.Internal("Failed to store session", txErr.Error())
+	}
+
+	token, err := s.JwtManager.GenerateSessionToken(userID, session.ID.String(), now.Add(accessTokenLifetime))
 	if err != nil {
 		return nil, nil, errors.Internal("Failed to generate token", err.Error())
 	}
 
-	refreshToken, err := s.JwtManager.GenerateToken(userID, time.Now().Add(30*24*time.Hour))
+	return token, refreshToken, nil
+}
+
+// Exchange a refresh token for a new access token and refresh token
+// The refresh token is rotated, it cannot be used again and the new one belongs to the same session.
+// Presenting a refresh token that was already rotated means it leaked, so the whole session is revoked
+// It returns the new tokens or an unauthorized error if the refresh token cannot be used
+func (s *AuthServiceInstance) RotateRefreshToken(refreshToken string, userAgent string, ipAddress string) (*dtos.GeneratedToken, *dtos.GeneratedToken, *errors.CustomError) {
+	now := time.Now()
+
+	var appError *errors.CustomError
+	var session *models.UserSession
+	var newRefreshToken *dtos.GeneratedToken
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		authRepository := s.AuthRepository.WithTx(tx)
+
+		storedToken, err := authRepository.FindTokenByHashForUpdate(hashToken(refreshToken))
+		if err == gorm.ErrRecordNotFound {
+			appError = errors.Unauthorized("Invalid refresh token").WithDetails("The refresh token does not exist")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		session, err = authRepository.FindSessionByIDForUpdate(storedToken.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if storedToken.RotatedAt != nil {
+			if session.RevokedAt == nil {
+				session.Revoke(models.SessionRevokeTokenReuse, now)
+
+				if err := authRepository.UpdateSession(session); err != nil {
+					return err
+				}
+			}
+
+			appError = errors.Unauthorized("Invalid refresh token").WithDetails("The refresh token was already used, the session has been ended")
+			return nil
+		}
+
+		if !session.IsActiveAt(now) || !now.Before(storedToken.ExpiresAt) {
+			appError = errors.Unauthorized("Invalid refresh token").WithDetails("The session has ended")
+			return nil
+		}
+
+		storedToken.RotatedAt = &now
+		if err := authRepository.UpdateToken(storedToken); err != nil {
+			return err
+		}
+
+		session.UserAgent = truncateUserAgent(userAgent)
+		session.IPAddress = ipAddress
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(refreshTokenLifetime)
+
+		if err := authRepository.UpdateSession(session); err != nil {
+			return err
+		}
+
+		newRefreshToken, err = issueRefreshToken(authRepository, session)
+		return err
+	})
+
+	if appError != nil {
+		return nil, nil, appError
+	}
+
+	if txErr != nil {
+		return nil, nil, errors.Internal("Failed to refresh session", txErr.Error())
+	}
+
+	token, err := s.JwtManager.GenerateSessionToken(session.UserID, session.ID.String(), now.Add(accessTokenLifetime))
 	if err != nil {
-		return nil, nil, errors.Internal("Failed to generate refresh token", err.Error())
+		return nil, nil, errors.Internal("Failed to generate token", err.Error())
 	}
 
-	if err := s.AuthRepository.StoreSession(userID, refreshToken.Token); err != nil {
-		return nil, nil, errors.Internal("Failed to store session", err.Error())
+	return token, newRefreshToken, nil
+}
+
+// Check that the session an access token belongs to is still active
+// Access tokens outlive a revoked session, so every authenticated request checks its session
+// It returns an unauthorized error if the session does not exist, was revoked or expired
+func (s *AuthServiceInstance) VerifySession(sessionID uuid.UUID) *errors.CustomError {
+	session, err := s.AuthRepository.FindSessionByID(sessionID)
+	if err == gorm.ErrRecordNotFound {
+		return errors.Unauthorized("Invalid access token").WithDetails("The session does not exist")
+	}
+	if err != nil {
+		return errors.Internal("Failed to get session", err.Error())
 	}
 
-	return token, refreshToken, nil
+	if !session.IsActiveAt(time.Now()) {
+		return errors.Unauthorized("Invalid access token").WithDetails("The session has ended")
+	}
+
+	return nil
 }
 
-// Validate the refresh token
-// This function checks if the provided refresh token exists in the database
-// It returns the userID if the token is valid, otherwise it returns an error
-func (s *AuthServiceInstance) ValidateRefreshToken(token string) (uint32, *errors.CustomError) {
-	session, err := s.AuthRepository.FindSessionByToken(token)
+// Invalidate the current session of the user
+// This function revokes the session so none of its refresh tokens can be used again
+// The other sessions of the user, on other devices, are left untouched
+// If the session is successfully revoked, it returns nil, otherwise it returns an error
+func (s *AuthServiceInstance) InvalidateSession(userID uint32, sessionID uuid.UUID) *errors.CustomError {
+	return s.revokeSession(userID, sessionID, models.SessionRevokeLogout)
+}
 
+// Get the active sessions of the user
+// The session the request was made from is flagged as the current one
+// It returns the sessions sorted from the most recently used
+func (s *AuthServiceInstance) GetSessions(userID uint32, currentSessionID uuid.UUID) ([]dtos.UserSessionDTO, *errors.CustomError) {
+	sessions, err := s.AuthRepository.FindActiveSessionsByUserID(userID, time.Now())
 	if err != nil {
-		return 0, errors.Internal("Failed to validate refresh token", err.Error())
+		return nil, errors.Internal("Failed to get sessions", err.Error())
 	}
 
-	if session == nil {
-		return 0, errors.Unauthorized("Invalid refresh token")
+	result := make([]dtos.UserSessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, dtos.UserSessionDTO{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			IsCurrent:  session.ID == currentSessionID,
+		})
+	}
+
+	return result, nil
+}
+
+// Revoke one of the sessions of the user, such as a device they no longer use
+// It returns a not found error if the session does not belong to the user
+func (s *AuthServiceInstance) RevokeSession(userID uint32, sessionID uuid.UUID) *errors.CustomError {
+	return s.revokeSession(userID, sessionID, models.SessionRevokeByUser)
+}
+
+// Revoke every session of the user except the current one, logging out all of their other devices
+// It returns the number of sessions revoked
+func (s *AuthServiceInstance) RevokeOtherSessions(userID uint32, currentSessionID uuid.UUID) (int64, *errors.CustomError) {
+	revoked, err := s.AuthRepository.RevokeUserSessions(userID, currentSessionID, models.SessionRevokeOtherDevices, time.Now())
+	if err != nil {
+		return 0, errors.Internal("Failed to revoke sessions", err.Error())
 	}
 
-	return session.UserID, nil
+	return revoked, nil
 }
 
-// Invalidate the session for the user
-// This function deletes the user's session from the database
-// It is used to log out the user by removing their refresh token
-// If the session is successfully deleted, it returns nil, otherwise it returns an error
-func (s *AuthServiceInstance) InvalidateSession(userID uint32) *errors.CustomError {
-	if err := s.AuthRepository.DeleteUserSession(userID); err != nil {
-		return errors.Internal("Failed to invalidate session", err.Error())
+// Change the password of the user
+// The current password must be provided, every other session of the user is revoked along with the change
+// so a device that knew the old password cannot keep refreshing its tokens
+// It returns a bad request error if the current password is wrong
+func (s *AuthServiceInstance) ChangePassword(userID uint32, currentSessionID uuid.UUID, request *dtos.ChangePasswordDTO) *errors.CustomError {
+	user, err := s.UserRepository.FindByID(userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.NotFound("User not found")
+		}
+
+		return errors.Internal("Failed to get user", err.Error())
+	}
+
+	if !user.CheckPassword(request.CurrentPassword) {
+		return errors.BadRequest("Invalid password", "The current password is incorrect")
+	}
+
+	if request.Password == request.CurrentPassword {
+		return errors.BadRequest("Invalid password", "The new password must be different from the current password")
+	}
+
+	user.Password = []byte(request.Password)
+
+	hashedPassword, err := user.HashPassword()
+	if err != nil {
+		return errors.Internal("Failed to hash password", err.Error())
+	}
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		if err := s.UserRepository.WithTx(tx).UpdatePassword(userID, hashedPassword); err != nil {
+			return err
+		}
+
+		_, err := s.AuthRepository.WithTx(tx).RevokeUserSessions(userID, currentSessionID, models.SessionRevokePasswordChanged, time.Now())
+		return err
+	})
+
+	if txErr != nil {
+		return errors.Internal("Failed to change password", txErr.Error())
 	}
 
 	return nil
 }
+
+// Send a password reset link to the user with the given email
+// The result is the same whether or not the email is registered, so the endpoint cannot be used to find accounts.
+// A user is sent at most PASSWORD_RESET_MAX_PER_HOUR links per hour, further requests are silently ignored
+// It returns an error only when the request could not be processed at all
+func (s *AuthServiceInstance) ForgotPassword(email string, ipAddress string) *errors.CustomError {
+	if s.QueueService == nil {
+		return errors.Internal("Queue service is not available", "Queue service is nil")
+	}
+
+	user, err := s.UserRepository.FindByEmail(strings.TrimSpace(email))
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Internal("Failed to process password reset request", err.Error())
+	}
+
+	token, err := issuePasswordResetToken(s.TransactionManager, s.PasswordResetRepository, user.ID, ipAddress, time.Now())
+	if err != nil {
+		return errors.Internal("Failed to process password reset request", err.Error())
+	}
+
+	if token == "" {
+		return nil
+	}
+
+	emailPayload := map[string]interface{}{
+		"user_id":            user.ID,
+		"email":              user.Email,
+		"user_name":          user.Name,
+		"reset_link":         config.MustGetEnv("APP_FE_URL") + "/reset-password?token=" + token,
+		"expires_in_minutes": passwordResetTokenLifetime().Minutes(),
+		"support_email":      config.GetEnv("SUPPORT_EMAIL", "support@catalyst.com"),
+	}
+
+	job := s.QueueService.NewJobBuilder("email:send_password_reset").
+		WithPayload(emailPayload).
+		WithPriority(queue.PriorityHigh).
+		WithMaxRetry(3).
+		WithTimeout(60 * time.Second).
+		WithQueue("high")
+
+	// A failure is only logged, answering differently would tell the email is registered
+	if _, err := job.Enqueue(context.Background()); err != nil {
+		log.Printf("Failed to queue password reset email for user %d: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+// Reset the password of a user with a token from a password reset email
+// The token can only be used once, every session of the user is revoked along with the reset
+// It returns a bad request error if the token is unknown, expired or already used
+func (s *AuthServiceInstance) ResetPassword(request *dtos.ResetPasswordDTO) *errors.CustomError {
+	hashedPassword, err := (&models.User{Password: []byte(request.Password)}).HashPassword()
+	if err != nil {
+		return errors.Internal("Failed to hash password", err.Error())
+	}
+
+	now := time.Now()
+
+	var appError *errors.CustomError
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		passwordResetRepository := s.PasswordResetRepository.WithTx(tx)
+
+		token, err := passwordResetRepository.FindByTokenHashForUpdate(hashToken(request.Token))
+		if err == gorm.ErrRecordNotFound || (err == nil && !token.IsUsableAt(now)) {
+			appError = errors.BadRequest("Invalid or expired password reset token", "Request a new password reset link")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.UserRepository.WithTx(tx).UpdatePassword(token.UserID, hashedPassword); err != nil {
+			return err
+		}
+
+		token.UsedAt = &now
+		if err := passwordResetRepository.Update(token); err != nil {
+			return err
+		}
+
+		if err := passwordResetRepository.InvalidateUserTokens(token.UserID, now); err != nil {
+			return err
+		}
+
+		_, err = s.AuthRepository.WithTx(tx).RevokeUserSessions(token.UserID, uuid.Nil, models.SessionRevokePasswordReset, now)
+		return err
+	})
+
+	if appError != nil {
+		return appError
+	}
+
+	if txErr != nil {
+		return errors.Internal("Failed to reset password", txErr.Error())
+	}
+
+	return nil
+}
+
+// Get how long a password reset link stays valid, read from PASSWORD_RESET_TOKEN_TTL_MINUTES
+func passwordResetTokenLifetime() time.Duration {
+	return time.Duration(config.GetEnvAsInt("PASSWORD_RESET_TOKEN_TTL_MINUTES", 60)) * time.Minute
+}
+
+// Issue a new password reset token for a user, the links sent before stop working
+// It returns the token to send to the user, or an empty string if the user asked for too many links within the last hour
+func issuePasswordResetToken(transactionManager repositories.TransactionManager, passwordResetRepository repositories.PasswordResetRepository, userID uint32, ipAddress string, now time.Time) (string, error) {
+	token, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	issued := false
+
+	txErr := transactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		passwordResetRepository := passwordResetRepository.WithTx(tx)
+
+		if limit := config.GetEnvAsInt("PASSWORD_RESET_MAX_PER_HOUR", 3); limit > 0 {
+			count, err := passwordResetRepository.CountCreatedSince(userID, now.Add(-time.Hour))
+			if err != nil {
+				return err
+			}
+
+			if count >= int64(limit) {
+				return nil
+			}
+		}
+
+		if err := passwordResetRepository.InvalidateUserTokens(userID, now); err != nil {
+			return err
+		}
+
+		if err := passwordResetRepository.Create(&models.PasswordResetToken{
+			UserID:    userID,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(passwordResetTokenLifetime()),
+			IPAddress: ipAddress,
+		}); err != nil {
+			return err
+		}
+
+		issued = true
+		return nil
+	})
+
+	if txErr != nil || !issued {
+		return "", txErr
+	}
+
+	return token, nil
+}
+
+// Revoke a session of the user for the given reason
+// Revoking a session that was already revoked does nothing
+func (s *AuthServiceInstance) revokeSession(userID uint32, sessionID uuid.UUID, reason string) *errors.CustomError {
+	var appError *errors.CustomError
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		authRepository := s.AuthRepository.WithTx(tx)
+
+		session, err := authRepository.FindSessionByIDForUpdate(sessionID)
+		if err == gorm.ErrRecordNotFound || (err == nil && session.UserID != userID) {
+			appError = errors.NotFound("Session not found")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if session.RevokedAt != nil {
+			return nil
+		}
+
+		session.Revoke(reason, time.Now())
+		return authRepository.UpdateSession(session)
+	})
+
+	if appError != nil {
+		return appError
+	}
+
+	if txErr != nil {
+		return errors.Internal("Failed to revoke session", txErr.Error())
+	}
+
+	return nil
+}
+
+// Issue a new refresh token for a session
+// Only the hash of the token is stored, the token itself is handed to the user once
+func issueRefreshToken(authRepository repositories.AuthRepository, session *models.UserSession) (*dtos.GeneratedToken, error) {
+	token, err := newRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authRepository.StoreToken(&models.UserHasToken{
+		UserID:    session.UserID,
+		SessionID: session.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: session.ExpiresAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &dtos.GeneratedToken{
+		Token:     token,
+		ExpiresAt: fmt.Sprintf("%d", session.ExpiresAt.Unix()),
+	}, nil
+}
+
+// Generate a random token that is safe to put in a URL
+func newRandomToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// Hash a refresh or password reset token the way it is stored
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Cut a user agent down to the size of its column
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > 512 {
+		return userAgent[:512]
+	}
+
+	return userAgent
+}
@@ -0,0 +1,502 @@
+package services
+
+import (
+	"fmt"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/utils/auth"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestAuthService(t *testing.T) (*AuthServiceInstance, *fakeAuthRepository) {
+	jwtManager, err := auth.NewJWTManager("secret")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	repository := newFakeAuthRepository()
+
+	user := &models.User{ID: 10, Password: []byte("old-password")}
+	hashedPassword, err := user.HashPassword()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	user.Password = hashedPassword
+
+	return &AuthServiceInstance{
+		AuthRepository:     repository,
+		UserRepository:     &fakeUserRepository{users: map[uint32]*models.User{10: user}},
+		JwtManager:         jwtManager,
+		TransactionManager: &fakeTransactionManager{},
+	}, repository
+}
+
+// Authenticate an access token the way JWTProtected does, by checking its signature and then its session
+func verifyAccessToken(t *testing.T, service *AuthServiceInstance, accessToken string) *errors.CustomError {
+	claims, err := service.JwtManager.ValidateToken(accessToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	sessionID, err := uuid.Parse(fmt.Sprintf("%v", claims["sid"]))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return service.VerifySession(sessionID)
+}
+
+func TestGenerateToken(t *testing.T) {
+	t.Run("Should store only the hash of the refresh token along with the device", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		accessToken, refreshToken, err := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		stored, ok := repository.tokens[hashToken(refreshToken.Token)]
+		if !ok {
+			t.Fatalf("Expected 1 stored refresh token, got %d", len(repository.tokens))
+		}
+
+		if len(stored.TokenHash) != 64 {
+			t.Errorf("Expected 64, got %d", len(stored.TokenHash))
+		}
+
+		if stored.TokenHash == refreshToken.Token {
+			t.Error("Expected the hash of the refresh token, got the refresh token itself")
+		}
+
+		session := repository.sessions[stored.SessionID]
+		if session.UserID != 10 {
+			t.Errorf("Expected 10, got %d", session.UserID)
+		}
+
+		if session.UserAgent != "Mozilla/5.0" {
+			t.Errorf("Expected Mozilla/5.0, got %s", session.UserAgent)
+		}
+
+		if session.IPAddress != "10.0.0.1" {
+			t.Errorf("Expected 10.0.0.1, got %s", session.IPAddress)
+		}
+
+		claims, validateErr := service.JwtManager.ValidateToken(accessToken.Token)
+		if validateErr != nil {
+			t.Fatalf("Expected no error, got %v", validateErr)
+		}
+
+		if claims["sid"] != session.ID.String() {
+			t.Errorf("Expected %s, got %v", session.ID, claims["sid"])
+		}
+	})
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	t.Run("Should rotate the refresh token within the same session", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, refreshToken, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+
+		_, rotated, err := service.RotateRefreshToken(refreshToken.Token, "Mozilla/5.0", "10.0.0.2")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		previous := repository.tokens[hashToken(refreshToken.Token)]
+		next := repository.tokens[hashToken(rotated.Token)]
+
+		if next == nil {
+			t.Fatal("Expected a stored refresh token, got nil")
+		}
+
+		if previous.RotatedAt == nil {
+			t.Error("Expected a rotation time, got nil")
+		}
+
+		if next.SessionID != previous.SessionID {
+			t.Errorf("Expected %s, got %s", previous.SessionID, next.SessionID)
+		}
+
+		if session := repository.sessions[next.SessionID]; session.IPAddress != "10.0.0.2" {
+			t.Errorf("Expected 10.0.0.2, got %s", session.IPAddress)
+		}
+	})
+
+	t.Run("Should revoke the session when a rotated refresh token is reused", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, refreshToken, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		_, rotated, _ := service.RotateRefreshToken(refreshToken.Token, "Mozilla/5.0", "10.0.0.1")
+
+		if _, _, err := service.RotateRefreshToken(refreshToken.Token, "curl/8.0", "192.168.1.1"); err == nil || err.Code != 401 {
+			t.Fatalf("Expected unauthorized error, got %v", err)
+		}
+
+		session := repository.sessions[repository.tokens[hashToken(rotated.Token)].SessionID]
+		if session.RevokedAt == nil || session.RevokeReason == nil {
+			t.Fatal("Expected a revoked session, got nil")
+		}
+
+		if *session.RevokeReason != models.SessionRevokeTokenReuse {
+			t.Errorf("Expected %s, got %s", models.SessionRevokeTokenReuse, *session.RevokeReason)
+		}
+
+		if _, _, err := service.RotateRefreshToken(rotated.Token, "Mozilla/5.0", "10.0.0.1"); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse an unknown refresh token", func(t *testing.T) {
+		service, _ := newTestAuthService(t)
+
+		if _, _, err := service.RotateRefreshToken("unknown", "Mozilla/5.0", "10.0.0.1"); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+}
+
+func TestVerifySession(t *testing.T) {
+	t.Run("Should accept the session of a new login", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, refreshToken, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+
+		if err := service.VerifySession(repository.tokens[hashToken(refreshToken.Token)].SessionID); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should reject a revoked session", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, refreshToken, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		sessionID := repository.tokens[hashToken(refreshToken.Token)].SessionID
+
+		if err := service.InvalidateSession(10, sessionID); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := service.VerifySession(sessionID); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+
+	t.Run("Should reject an expired session", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, refreshToken, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		sessionID := repository.tokens[hashToken(refreshToken.Token)].SessionID
+		repository.sessions[sessionID].ExpiresAt = time.Now().Add(-time.Minute)
+
+		if err := service.VerifySession(sessionID); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+
+	t.Run("Should reject an unknown session", func(t *testing.T) {
+		service, _ := newTestAuthService(t)
+
+		if err := service.VerifySession(uuid.New()); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+}
+
+func TestInvalidateSession(t *testing.T) {
+	t.Run("Should only end the current session", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, laptop, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		_, phone, _ := service.GenerateToken(10, "Catalyst/1.0 (Android)", "10.0.0.2")
+
+		laptopSession := repository.tokens[hashToken(laptop.Token)].SessionID
+
+		if err := service.InvalidateSession(10, laptopSession); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, _, err := service.RotateRefreshToken(laptop.Token, "Mozilla/5.0", "10.0.0.1"); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+
+		if _, _, err := service.RotateRefreshToken(phone.Token, "Catalyst/1.0 (Android)", "10.0.0.2"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should not end the session of another user", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, refreshToken, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+
+		if err := service.InvalidateSession(20, repository.tokens[hashToken(refreshToken.Token)].SessionID); err == nil || err.Code != 404 {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
+
+func TestRevokeSession(t *testing.T) {
+	t.Run("Should reject the access token of the revoked device", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		laptopAccess, laptop, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		phoneAccess, phone, _ := service.GenerateToken(10, "Catalyst/1.0 (Android)", "10.0.0.2")
+
+		if err := service.RevokeSession(10, repository.tokens[hashToken(phone.Token)].SessionID); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := verifyAccessToken(t, service, phoneAccess.Token); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+
+		if err := verifyAccessToken(t, service, laptopAccess.Token); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if session := repository.sessions[repository.tokens[hashToken(laptop.Token)].SessionID]; session.RevokedAt != nil {
+			t.Errorf("Expected nil, got %v", *session.RevokedAt)
+		}
+	})
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	t.Run("Should keep only the current session active", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, laptop, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		service.GenerateToken(10, "Catalyst/1.0 (Android)", "10.0.0.2")
+		service.GenerateToken(10, "Catalyst/1.0 (iOS)", "10.0.0.3")
+		service.GenerateToken(20, "Mozilla/5.0", "10.0.0.4")
+
+		current := repository.tokens[hashToken(laptop.Token)].SessionID
+
+		revoked, err := service.RevokeOtherSessions(10, current)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if revoked != 2 {
+			t.Errorf("Expected 2, got %d", revoked)
+		}
+
+		sessions, _ := service.GetSessions(10, current)
+		if len(sessions) != 1 {
+			t.Fatalf("Expected 1, got %d", len(sessions))
+		}
+
+		if sessions[0].ID != current {
+			t.Errorf("Expected %s, got %s", current, sessions[0].ID)
+		}
+
+		if !sessions[0].IsCurrent {
+			t.Errorf("Expected true, got %t", sessions[0].IsCurrent)
+		}
+
+		if others, _ := service.GetSessions(20, uuid.Nil); len(others) != 1 {
+			t.Errorf("Expected 1, got %d", len(others))
+		}
+	})
+
+	t.Run("Should reject the access tokens of the other devices", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		laptopAccess, laptop, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		phoneAccess, _, _ := service.GenerateToken(10, "Catalyst/1.0 (Android)", "10.0.0.2")
+
+		if _, err := service.RevokeOtherSessions(10, repository.tokens[hashToken(laptop.Token)].SessionID); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := verifyAccessToken(t, service, phoneAccess.Token); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+
+		if err := verifyAccessToken(t, service, laptopAccess.Token); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Run("Should revoke every other session of the user", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, laptop, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		_, phone, _ := service.GenerateToken(10, "Catalyst/1.0 (Android)", "10.0.0.2")
+
+		current := repository.tokens[hashToken(laptop.Token)].SessionID
+
+		if err := service.ChangePassword(10, current, &dtos.ChangePasswordDTO{CurrentPassword: "old-password", Password: "new-password"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		user, _ := service.UserRepository.FindByID(10)
+		if !user.CheckPassword("new-password") {
+			t.Errorf("Expected true, got %t", false)
+		}
+
+		if _, _, err := service.RotateRefreshToken(phone.Token, "Catalyst/1.0 (Android)", "10.0.0.2"); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+
+		if _, _, err := service.RotateRefreshToken(laptop.Token, "Mozilla/5.0", "10.0.0.1"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a wrong current password", func(t *testing.T) {
+		service, repository := newTestAuthService(t)
+
+		_, phone, _ := service.GenerateToken(10, "Catalyst/1.0 (Android)", "10.0.0.2")
+
+		if err := service.ChangePassword(10, uuid.Nil, &dtos.ChangePasswordDTO{CurrentPassword: "wrong-password", Password: "new-password"}); err == nil || err.Code != 400 {
+			t.Fatalf("Expected bad request error, got %v", err)
+		}
+
+		if session := repository.sessions[repository.tokens[hashToken(phone.Token)].SessionID]; session.RevokedAt != nil {
+			t.Errorf("Expected nil, got %v", *session.RevokedAt)
+		}
+	})
+}
+
+func TestIssuePasswordResetToken(t *testing.T) {
+	t.Run("Should only keep the latest link working", func(t *testing.T) {
+		repository := &fakePasswordResetRepository{}
+
+		first, err := issuePasswordResetToken(&fakeTransactionManager{}, repository, 10, "10.0.0.1", time.Now())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(first) != 43 {
+			t.Fatalf("Expected 43, got %d", len(first))
+		}
+
+		second, _ := issuePasswordResetToken(&fakeTransactionManager{}, repository, 10, "10.0.0.1", time.Now())
+
+		if repository.tokens[0].TokenHash != hashToken(first) {
+			t.Errorf("Expected %s, got %s", hashToken(first), repository.tokens[0].TokenHash)
+		}
+
+		if repository.tokens[0].IsUsableAt(time.Now()) {
+			t.Errorf("Expected false, got %t", true)
+		}
+
+		if repository.tokens[1].TokenHash != hashToken(second) {
+			t.Errorf("Expected %s, got %s", hashToken(second), repository.tokens[1].TokenHash)
+		}
+
+		if !repository.tokens[1].IsUsableAt(time.Now()) {
+			t.Errorf("Expected true, got %t", false)
+		}
+	})
+
+	t.Run("Should silently stop issuing links over the hourly limit", func(t *testing.T) {
+		t.Setenv("PASSWORD_RESET_MAX_PER_HOUR", "2")
+
+		repository := &fakePasswordResetRepository{}
+
+		for i := 0; i < 2; i++ {
+			issuePasswordResetToken(&fakeTransactionManager{}, repository, 10, "10.0.0.1", time.Now())
+		}
+
+		token, err := issuePasswordResetToken(&fakeTransactionManager{}, repository, 10, "10.0.0.1", time.Now())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if token != "" {
+			t.Errorf("Expected an empty token, got %d characters", len(token))
+		}
+
+		if len(repository.tokens) != 2 {
+			t.Errorf("Expected 2, got %d", len(repository.tokens))
+		}
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	newResetService := func(t *testing.T) (*AuthServiceInstance, *fakeAuthRepository, string) {
+		service, repository := newTestAuthService(t)
+		service.PasswordResetRepository = &fakePasswordResetRepository{}
+
+		token, err := issuePasswordResetToken(service.TransactionManager, service.PasswordResetRepository, 10, "10.0.0.1", time.Now())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		return service, repository, token
+	}
+
+	t.Run("Should change the password and revoke every session", func(t *testing.T) {
+		service, _, token := newResetService(t)
+
+		_, laptop, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+		_, phone, _ := service.GenerateToken(10, "Catalyst/1.0 (Android)", "10.0.0.2")
+
+		if err := service.ResetPassword(&dtos.ResetPasswordDTO{Token: token, Password: "new-password"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		user, _ := service.UserRepository.FindByID(10)
+		if !user.CheckPassword("new-password") {
+			t.Errorf("Expected true, got %t", false)
+		}
+
+		for _, refreshToken := range []string{laptop.Token, phone.Token} {
+			if _, _, err := service.RotateRefreshToken(refreshToken, "Mozilla/5.0", "10.0.0.1"); err == nil || err.Code != 401 {
+				t.Errorf("Expected unauthorized error, got %v", err)
+			}
+		}
+	})
+
+	t.Run("Should reject an access token issued before the reset", func(t *testing.T) {
+		service, _, token := newResetService(t)
+
+		accessToken, _, _ := service.GenerateToken(10, "Mozilla/5.0", "10.0.0.1")
+
+		if err := verifyAccessToken(t, service, accessToken.Token); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := service.ResetPassword(&dtos.ResetPasswordDTO{Token: token, Password: "new-password"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := verifyAccessToken(t, service, accessToken.Token); err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+
+	t.Run("Should only accept a token once", func(t *testing.T) {
+		service, _, token := newResetService(t)
+
+		if err := service.ResetPassword(&dtos.ResetPasswordDTO{Token: token, Password: "new-password"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if err := service.ResetPassword(&dtos.ResetPasswordDTO{Token: token, Password: "another-password"}); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse an expired token", func(t *testing.T) {
+		service, _, token := newResetService(t)
+		service.PasswordResetRepository.(*fakePasswordResetRepository).tokens[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+		if err := service.ResetPassword(&dtos.ResetPasswordDTO{Token: token, Password: "new-password"}); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+
+		user, _ := service.UserRepository.FindByID(10)
+		if !user.CheckPassword("old-password") {
+			t.Errorf("Expected true, got %t", false)
+		}
+	})
+}
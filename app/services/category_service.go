@@ -5,6 +5,7 @@ import (
 
 	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
 	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/repositories"
 
@@ -26,12 +27,16 @@ type CategoryService interface {
 type CategoryServiceInstance struct {
 	CategoryRepository repositories.CategoryRepository
 	MerchantRepository repositories.MerchantRepository
+	TransactionManager repositories.TransactionManager
+	EntitlementService EntitlementService
 }
 
-func NewCategoryService(categoryRepository repositories.CategoryRepository, merchantRepository repositories.MerchantRepository) CategoryService {
+func NewCategoryService(categoryRepository repositories.CategoryRepository, merchantRepository repositories.MerchantRepository, transactionManager repositories.TransactionManager, entitlementService EntitlementService) CategoryService {
 	return &CategoryServiceInstance{
 		CategoryRepository: categoryRepository,
 		MerchantRepository: merchantRepository,
+		TransactionManager: transactionManager,
+		EntitlementService: entitlementService,
 	}
 }
 
@@ -44,13 +49,7 @@ func (s *CategoryServiceInstance) CreateNewCategory(category *dtos.CreateCategor
 		MerchantID: merchantID,
 	}
 
-	newCategory, err := s.CategoryRepository.StoreCategory(categoryModel)
-
-	if err != nil {
-		return nil, errors.Internal("Failed to create category", err.Error())
-	}
-
-	return newCategory, nil
+	return s.storeCategory(categoryModel)
 }
 
 // Create a new category using merchant username
@@ -72,9 +71,37 @@ func (s *CategoryServiceInstance) CreateCategoryWithMerchantUsername(name, usern
 		MerchantID: merchant.ID,
 	}
 
-	newCategory, err := s.CategoryRepository.StoreCategory(category)
-	if err != nil {
-		return nil, errors.Internal("Failed to create category", err.Error())
+	return s.storeCategory(category)
+}
+
+// Store a category within the category limit of its merchant
+// The limit is enforced within the same transaction as the insert, so concurrent creations cannot exceed it
+// It returns the stored category or an error if the limit is reached or the operation fails.
+func (s *CategoryServiceInstance) storeCategory(category *models.Category) (*models.Category, *errors.CustomError) {
+	var newCategory *models.Category
+	var appError *errors.CustomError
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		if appError = s.EntitlementService.EnforceQuota(tx, category.MerchantID, constants.SubscriptionCategoryLimit); appError != nil {
+			return appError
+		}
+
+		stored, err := s.CategoryRepository.WithTx(tx).StoreCategory(category)
+		if err != nil {
+			appError = errors.Internal("Failed to create category", err.Error())
+			return err
+		}
+
+		newCategory = stored
+		return nil
+	})
+
+	if appError != nil {
+		return nil, appError
+	}
+
+	if txErr != nil {
+		return nil, errors.Internal("Failed to create category", txErr.Error())
 	}
 
 	return newCategory, nil
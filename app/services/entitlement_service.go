@@ -0,0 +1,315 @@
+package services
+
+import (
+	stderror "errors"
+
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/platform/entitlements"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+
+	"gorm.io/gorm"
+)
+
+type EntitlementService interface {
+	ResolveEntitlements(userID uint32) (*entitlements.Entitlements, *errors.CustomError)
+	GetQuotaUsage(userID uint32, key constants.SubscriptionPlan) (int64, *errors.CustomError)
+	AttachUpgradeOptions(current *entitlements.Entitlements, denial *entitlements.Denial) *errors.CustomError
+	EnforceQuota(tx *gorm.DB, merchantID string, key constants.SubscriptionPlan) *errors.CustomError
+	EnforceCount(merchantID string, key constants.SubscriptionPlan, count int64) *errors.CustomError
+	ResolveMerchantEntitlements(merchantID string) (*entitlements.Entitlements, *errors.CustomError)
+	GetEntitlementUsage(userID uint32) (*dtos.UserEntitlementsDTO, *errors.CustomError)
+}
+
+type EntitlementServiceInstance struct {
+	UserRepository         repositories.UserRepository
+	SubscriptionRepository repositories.SubscriptionRepository
+	MerchantRepository     repositories.MerchantRepository
+	ProductRepository      repositories.ProductRepository
+	CategoryRepository     repositories.CategoryRepository
+}
+
+func NewEntitlementService(
+	userRepository repositories.UserRepository,
+	subscriptionRepository repositories.SubscriptionRepository,
+	merchantRepository repositories.MerchantRepository,
+	productRepository repositories.ProductRepository,
+	categoryRepository repositories.CategoryRepository,
+) EntitlementService {
+	return &EntitlementServiceInstance{
+		UserRepository:         userRepository,
+		SubscriptionRepository: subscriptionRepository,
+		MerchantRepository:     merchantRepository,
+		ProductRepository:      productRepository,
+		CategoryRepository:     categoryRepository,
+	}
+}
+
+// Resolve the effective entitlements of a user
+// Administrators are unrestricted. Everyone else gets the entitlements of the active subscription,
+// or the defaults of the registry when there is no active subscription
+// It returns the entitlements or an error if they cannot be resolved
+func (s *EntitlementServiceInstance) ResolveEntitlements(userID uint32) (*entitlements.Entitlements, *errors.CustomError) {
+	user, err := s.UserRepository.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, errors.NotFound("User not found")
+	}
+
+	if user.Role == "admin" {
+		return entitlements.Unrestricted(userID), nil
+	}
+
+	userSubscription, err := s.SubscriptionRepository.FindActiveUserSubscription(userID)
+	if err == gorm.ErrRecordNotFound {
+		return entitlements.New(userID, nil), nil
+	}
+	if err != nil {
+		return nil, errors.Internal("Failed to get active subscription", err.Error())
+	}
+
+	subscription, err := s.SubscriptionRepository.FindByID(userSubscription.SubID)
+	if err != nil {
+		return nil, errors.Internal("Failed to get subscription", err.Error())
+	}
+
+	return entitlements.New(userID, subscription), nil
+}
+
+// Count how much of a quota a user uses
+// Quotas are counted across every merchant of the user
+// It returns the usage or an error if the quota cannot be counted
+func (s *EntitlementServiceInstance) GetQuotaUsage(userID uint32, key constants.SubscriptionPlan) (int64, *errors.CustomError) {
+	merchants, err := s.MerchantRepository.FindByUserID(userID)
+	if err != nil {
+		return 0, errors.Internal("Failed to get merchants", err.Error())
+	}
+
+	return s.countQuotaUsage(nil, merchants, key)
+}
+
+// Check that one more item fits in a quota of the owner of a merchant
+// This function must be called within the transaction that stores the item. It locks the merchants of the owner
+// so concurrent creations wait for each other, then counts the usage within the transaction
+// It returns a forbidden error carrying the denial when the quota is used up, or nil when the item fits
+func (s *EntitlementServiceInstance) EnforceQuota(tx *gorm.DB, merchantID string, key constants.SubscriptionPlan) *errors.CustomError {
+	merchantRepository := s.MerchantRepository.WithTx(tx)
+
+	merchant, err := merchantRepository.FindByID(merchantID)
+	if err != nil {
+		if stderror.Is(err, gorm.ErrRecordNotFound) {
+			return errors.NotFound("Merchant not found")
+		}
+
+		return errors.Internal("Failed to get merchant", err.Error())
+	}
+
+	merchants, err := merchantRepository.FindByUserIDForUpdate(merchant.OwnerID)
+	if err != nil {
+		return errors.Internal("Failed to lock merchants", err.Error())
+	}
+
+	ownerEntitlements, appError := s.ResolveEntitlements(merchant.OwnerID)
+	if appError != nil {
+		return appError
+	}
+
+	if ownerEntitlements.Unrestricted {
+		return nil
+	}
+
+	used, appError := s.countQuotaUsage(tx, merchants, key)
+	if appError != nil {
+		return appError
+	}
+
+	return s.forbid(ownerEntitlements, ownerEntitlements.RequireQuota(key, used))
+}
+
+// Check that a number of items fits in a quota of the owner of a merchant at once, e.g. the photos of a product
+// It returns a forbidden error carrying the denial when count is above the quota, or nil when the items fit
+func (s *EntitlementServiceInstance) EnforceCount(merchantID string, key constants.SubscriptionPlan, count int64) *errors.CustomError {
+	ownerEntitlements, appError := s.ResolveMerchantEntitlements(merchantID)
+	if appError != nil {
+		return appError
+	}
+
+	return s.forbid(ownerEntitlements, ownerEntitlements.RequireCount(key, count))
+}
+
+// Resolve the effective entitlements of the owner of a merchant
+// It returns the entitlements or an error if the merchant or the entitlements cannot be found
+func (s *EntitlementServiceInstance) ResolveMerchantEntitlements(merchantID string) (*entitlements.Entitlements, *errors.CustomError) {
+	merchant, err := s.MerchantRepository.FindByID(merchantID)
+	if err != nil {
+		if stderror.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFound("Merchant not found")
+		}
+
+		return nil, errors.Internal("Failed to get merchant", err.Error())
+	}
+
+	return s.ResolveEntitlements(merchant.OwnerID)
+}
+
+// Get every entitlement of a user next to its current usage
+// Quotas are reported with their usage, and the product slots with the products hidden from the storefront
+// because they are beyond the quota, e.g. after a downgrade
+// It returns the entitlements with their usage or an error if they cannot be resolved
+func (s *EntitlementServiceInstance) GetEntitlementUsage(userID uint32) (*dtos.UserEntitlementsDTO, *errors.CustomError) {
+	userEntitlements, appError := s.ResolveEntitlements(userID)
+	if appError != nil {
+		return nil, appError
+	}
+
+	merchants, err := s.MerchantRepository.FindByUserID(userID)
+	if err != nil {
+		return nil, errors.Internal("Failed to get merchants", err.Error())
+	}
+
+	usage := &dtos.UserEntitlementsDTO{
+		SubscriptionID:   userEntitlements.SubscriptionID,
+		SubscriptionName: userEntitlements.SubscriptionName,
+		Unrestricted:     userEntitlements.Unrestricted,
+		Entitlements:     make([]dtos.EntitlementUsageDTO, 0),
+	}
+
+	for _, definition := range entitlements.Definitions() {
+		entitlement := userEntitlements.Get(definition.Key)
+		item := dtos.EntitlementUsageDTO{
+			Key:         string(definition.Key),
+			Kind:        string(definition.Kind),
+			Description: definition.Description,
+			Limit:       entitlement.Limit,
+		}
+
+		if definition.Kind == entitlements.KindFlag {
+			enabled := userEntitlements.Feature(definition.Key)
+			item.Enabled = &enabled
+		}
+
+		switch definition.Key {
+		case constants.SubscriptionProductSlot, constants.SubscriptionCategoryLimit:
+			used, appError := s.countQuotaUsage(nil, merchants, definition.Key)
+			if appError != nil {
+				return nil, appError
+			}
+
+			item.Used = &used
+		case constants.SubscriptionPhotosPerProduct:
+			used, appError := s.maxPhotosPerProduct(merchants)
+			if appError != nil {
+				return nil, appError
+			}
+
+			item.Used = &used
+		}
+
+		if definition.Key == constants.SubscriptionProductSlot && !userEntitlements.Unrestricted {
+			hidden, appError := s.countHiddenProducts(merchants, entitlement.Limit)
+			if appError != nil {
+				return nil, appError
+			}
+
+			item.Hidden = hidden
+		}
+
+		usage.Entitlements = append(usage.Entitlements, item)
+	}
+
+	return usage, nil
+}
+
+// Count the products hidden from the storefront because they are beyond the product slot quota of their owner
+// The quota is shared across the merchants of the owner, so the products are counted as one total
+func (s *EntitlementServiceInstance) countHiddenProducts(merchants []*models.Merchant, limit int64) (int64, *errors.CustomError) {
+	total := int64(0)
+	for _, merchant := range merchants {
+		count, err := s.ProductRepository.CountProductsByMerchantID(merchant.ID)
+		if err != nil {
+			return 0, errors.Internal("Failed to count products", err.Error())
+		}
+
+		total += count
+	}
+
+	return max(0, total-limit), nil
+}
+
+// Find the largest number of photos of a product across merchants
+func (s *EntitlementServiceInstance) maxPhotosPerProduct(merchants []*models.Merchant) (int64, *errors.CustomError) {
+	photos := int64(0)
+	for _, merchant := range merchants {
+		count, err := s.ProductRepository.FindMaxPhotosByMerchantID(merchant.ID)
+		if err != nil {
+			return 0, errors.Internal("Failed to count product photos", err.Error())
+		}
+
+		photos = max(photos, count)
+	}
+
+	return photos, nil
+}
+
+// Turn a denial into a forbidden error carrying the subscriptions that would lift it
+// It returns nil when there is no denial
+func (s *EntitlementServiceInstance) forbid(current *entitlements.Entitlements, denial *entitlements.Denial) *errors.CustomError {
+	if denial == nil {
+		return nil
+	}
+
+	if appError := s.AttachUpgradeOptions(current, denial); appError != nil {
+		return appError
+	}
+
+	return errors.Forbidden("Your subscription does not include this feature").WithDetails(denial)
+}
+
+// Count the usage of a quota across merchants, within tx when it is given
+func (s *EntitlementServiceInstance) countQuotaUsage(tx *gorm.DB, merchants []*models.Merchant, key constants.SubscriptionPlan) (int64, *errors.CustomError) {
+	productRepository, categoryRepository := s.ProductRepository, s.CategoryRepository
+	if tx != nil {
+		productRepository, categoryRepository = productRepository.WithTx(tx), categoryRepository.WithTx(tx)
+	}
+
+	var count func(merchantID string) (int64, error)
+	switch key {
+	case constants.SubscriptionProductSlot:
+		count = productRepository.CountProductsByMerchantID
+	case constants.SubscriptionCategoryLimit:
+		count = categoryRepository.CountCategoriesByMerchantID
+	default:
+		return 0, errors.Internal("Failed to count quota usage", "Quota is not countable: "+string(key))
+	}
+
+	usage := int64(0)
+	for _, merchant := range merchants {
+		used, err := count(merchant.ID)
+		if err != nil {
+			return 0, errors.Internal("Failed to count quota usage", err.Error())
+		}
+
+		usage += used
+	}
+
+	return usage, nil
+}
+
+// Attach the subscriptions a user can upgrade to in order to lift a denial
+// It returns an error if the subscriptions cannot be loaded
+func (s *EntitlementServiceInstance) AttachUpgradeOptions(current *entitlements.Entitlements, denial *entitlements.Denial) *errors.CustomError {
+	catalog, err := s.SubscriptionRepository.FindAllSubscriptionsWithPlans()
+	if err != nil {
+		return errors.Internal("Failed to get subscriptions", err.Error())
+	}
+
+	currentSubscriptionID := uint32(0)
+	if current != nil {
+		currentSubscriptionID = current.SubscriptionID
+	}
+
+	denial.WithUpgradeOptions(catalog, currentSubscriptionID)
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/platform/entitlements"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+)
+
+func newQuotaEntitlementService(role string, products int) (*EntitlementServiceInstance, *fakeMerchantRepository, *fakeProductRepository) {
+	merchantRepository := &fakeMerchantRepository{merchants: []*models.Merchant{{ID: "merchant-a", OwnerID: 10}}}
+	productRepository := &fakeProductRepository{products: map[string]int{"merchant-a": products}}
+
+	service := &EntitlementServiceInstance{
+		UserRepository: &fakeUserRepository{users: map[uint32]*models.User{10: {ID: 10, Role: role}}},
+		SubscriptionRepository: newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: time.Now().AddDate(0, 0, 14), IsActive: true,
+		}),
+		MerchantRepository: merchantRepository,
+		ProductRepository:  productRepository,
+		CategoryRepository: &fakeCategoryRepository{categories: map[string]int{"merchant-a": 3}},
+	}
+
+	return service, merchantRepository, productRepository
+}
+
+func TestEnforceQuota(t *testing.T) {
+	t.Run("Should lock the merchants of the owner and allow an item that fits", func(t *testing.T) {
+		service, merchantRepository, _ := newQuotaEntitlementService("user", 99)
+
+		if err := service.EnforceQuota(nil, "merchant-a", constants.SubscriptionProductSlot); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if !merchantRepository.locked {
+			t.Errorf("Expected true, got %t", merchantRepository.locked)
+		}
+	})
+
+	t.Run("Should refuse an item over the quota with the subscriptions to upgrade to", func(t *testing.T) {
+		service, _, _ := newQuotaEntitlementService("user", 100)
+
+		err := service.EnforceQuota(nil, "merchant-a", constants.SubscriptionProductSlot)
+		if err == nil || err.Code != 403 {
+			t.Fatalf("Expected forbidden error, got %v", err)
+		}
+
+		denial, ok := err.Details.(*entitlements.Denial)
+		if !ok {
+			t.Fatalf("Expected *entitlements.Denial, got %T", err.Details)
+		}
+
+		if denial.Used != 100 {
+			t.Errorf("Expected 100, got %d", denial.Used)
+		}
+
+		if len(denial.UpgradeTo) != 1 || denial.UpgradeTo[0].SubscriptionID != 3 {
+			t.Errorf("Expected an upgrade to subscription 3, got %+v", denial.UpgradeTo)
+		}
+	})
+
+	t.Run("Should count categories against the category limit", func(t *testing.T) {
+		service, _, _ := newQuotaEntitlementService("user", 0)
+
+		if err := service.EnforceQuota(nil, "merchant-a", constants.SubscriptionCategoryLimit); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should let administrators through", func(t *testing.T) {
+		service, _, _ := newQuotaEntitlementService("admin", 1000)
+
+		if err := service.EnforceQuota(nil, "merchant-a", constants.SubscriptionProductSlot); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should report an unknown merchant", func(t *testing.T) {
+		service, _, _ := newQuotaEntitlementService("user", 0)
+
+		if err := service.EnforceQuota(nil, "merchant-z", constants.SubscriptionProductSlot); err == nil || err.Code != 404 {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
+
+func TestCreateProductQuota(t *testing.T) {
+	t.Run("Should not store a product over the quota", func(t *testing.T) {
+		entitlementService, _, productRepository := newQuotaEntitlementService("user", 100)
+		service := &ProductServiceInstance{
+			ProductRepository:  productRepository,
+			TransactionManager: &fakeTransactionManager{},
+			EntitlementService: entitlementService,
+		}
+
+		price := money.Zero(money.IDR)
+		_, err := service.CreateProduct(&dtos.CreateProductDTO{Title: "Mug", Price: &price}, "merchant-a")
+		if err == nil || err.Code != 403 {
+			t.Errorf("Expected forbidden error, got %v", err)
+		}
+
+		if len(productRepository.stored) != 0 {
+			t.Errorf("Expected 0 stored products, got %d", len(productRepository.stored))
+		}
+	})
+
+	t.Run("Should store a product that fits in the quota", func(t *testing.T) {
+		entitlementService, _, productRepository := newQuotaEntitlementService("user", 10)
+		service := &ProductServiceInstance{
+			ProductRepository:  productRepository,
+			TransactionManager: &fakeTransactionManager{},
+			EntitlementService: entitlementService,
+		}
+
+		price := money.Zero(money.IDR)
+		if _, err := service.CreateProduct(&dtos.CreateProductDTO{Title: "Mug", Price: &price}, "merchant-a"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if len(productRepository.stored) != 1 {
+			t.Errorf("Expected 1 stored product, got %d", len(productRepository.stored))
+		}
+	})
+}
+
+func TestGetEntitlementUsage(t *testing.T) {
+	t.Run("Should report each limit next to its usage and the products hidden over the quota", func(t *testing.T) {
+		service, _, productRepository := newQuotaEntitlementService("user", 120)
+		productRepository.maxPhotos = 4
+
+		usage, err := service.GetEntitlementUsage(10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if usage.SubscriptionID != 2 {
+			t.Errorf("Expected 2, got %d", usage.SubscriptionID)
+		}
+
+		if usage.Unrestricted {
+			t.Errorf("Expected false, got %t", usage.Unrestricted)
+		}
+
+		items := make(map[string]dtos.EntitlementUsageDTO)
+		for _, item := range usage.Entitlements {
+			items[item.Key] = item
+		}
+
+		products := items[string(constants.SubscriptionProductSlot)]
+		if products.Limit != 100 {
+			t.Errorf("Expected 100, got %d", products.Limit)
+		}
+
+		if products.Used == nil {
+			t.Fatal("Expected 120, got nil")
+		}
+
+		if *products.Used != 120 {
+			t.Errorf("Expected 120, got %d", *products.Used)
+		}
+
+		if products.Hidden != 20 {
+			t.Errorf("Expected 20, got %d", products.Hidden)
+		}
+
+		categories := items[string(constants.SubscriptionCategoryLimit)]
+		if categories.Limit != 10 {
+			t.Errorf("Expected 10, got %d", categories.Limit)
+		}
+
+		if categories.Used == nil {
+			t.Fatal("Expected 3, got nil")
+		}
+
+		if *categories.Used != 3 {
+			t.Errorf("Expected 3, got %d", *categories.Used)
+		}
+
+		photos := items[string(constants.SubscriptionPhotosPerProduct)]
+		if photos.Used == nil {
+			t.Fatal("Expected 4, got nil")
+		}
+
+		if *photos.Used != 4 {
+			t.Errorf("Expected 4, got %d", *photos.Used)
+		}
+
+		analytics := items[string(constants.SubscriptionAnalytics)]
+		if analytics.Enabled == nil {
+			t.Fatal("Expected true, got nil")
+		}
+
+		if !*analytics.Enabled {
+			t.Errorf("Expected true, got %t", *analytics.Enabled)
+		}
+
+		if analytics.Used != nil {
+			t.Errorf("Expected nil, got %d", *analytics.Used)
+		}
+	})
+
+	t.Run("Should count the hidden products over the quota shared by every merchant of the owner", func(t *testing.T) {
+		service, merchantRepository, productRepository := newQuotaEntitlementService("user", 60)
+		merchantRepository.merchants = append(merchantRepository.merchants, &models.Merchant{ID: "merchant-b", OwnerID: 10})
+		productRepository.products["merchant-b"] = 50
+
+		usage, err := service.GetEntitlementUsage(10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		for _, item := range usage.Entitlements {
+			if item.Key != string(constants.SubscriptionProductSlot) {
+				continue
+			}
+
+			if item.Hidden != 10 {
+				t.Errorf("Expected 10, got %d", item.Hidden)
+			}
+		}
+	})
+}
+
+func TestPublishedProducts(t *testing.T) {
+	t.Run("Should only publish the products within the product slot quota", func(t *testing.T) {
+		entitlementService, _, productRepository := newQuotaEntitlementService("user", 120)
+		service := &ProductServiceInstance{ProductRepository: productRepository, EntitlementService: entitlementService}
+
+		if _, err := service.GetPublishedProductByID("product-a"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if productRepository.publishLimit != 100 {
+			t.Errorf("Expected 100, got %d", productRepository.publishLimit)
+		}
+	})
+
+	t.Run("Should publish every product of an unrestricted owner", func(t *testing.T) {
+		entitlementService, _, productRepository := newQuotaEntitlementService("admin", 120)
+		service := &ProductServiceInstance{ProductRepository: productRepository, EntitlementService: entitlementService}
+
+		if _, err := service.GetPublishedProductByID("product-a"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if productRepository.publishLimit >= 0 {
+			t.Errorf("Expected a negative limit, got %d", productRepository.publishLimit)
+		}
+	})
+}
+
+func TestAddProductPhoto(t *testing.T) {
+	t.Run("Should refuse a photo over the photos per product quota", func(t *testing.T) {
+		entitlementService, _, productRepository := newQuotaEntitlementService("user", 1)
+		service := &ProductServiceInstance{ProductRepository: productRepository, EntitlementService: entitlementService}
+
+		product := &models.Product{ID: "product-a", MerchantID: "merchant-a", Photos: models.PhotoArray{"1", "2", "3", "4", "5"}}
+		if err := service.AddProductPhoto(product, "6"); err == nil || err.Code != 403 {
+			t.Errorf("Expected forbidden error, got %v", err)
+		}
+
+		if len(product.Photos) != 5 {
+			t.Errorf("Expected 5 photos, got %d", len(product.Photos))
+		}
+	})
+}
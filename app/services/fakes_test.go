@@ -0,0 +1,877 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// In-memory fakes shared by the service tests
+// Each fake embeds its repository interface, so calling a method it does not implement panics
+
+type fakeTransactionManager struct{}
+
+func (m *fakeTransactionManager) WithinTransaction(fn func(tx *gorm.DB) error) error {
+	return fn(nil)
+}
+
+type fakeUserRepository struct {
+	repositories.UserRepository
+	users map[uint32]*models.User
+}
+
+func (r *fakeUserRepository) WithTx(tx *gorm.DB) repositories.UserRepository {
+	return r
+}
+
+func (r *fakeUserRepository) FindByID(id uint32) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return user, nil
+}
+
+func (r *fakeUserRepository) UpdatePassword(userID uint32, password []byte) error {
+	r.users[userID].Password = password
+	return nil
+}
+
+// A subscription repository serving the seeded catalog and the user subscriptions given to it
+type fakeSubscriptionRepository struct {
+	repositories.SubscriptionRepository
+	subscriptions     map[uint32]*models.Subscription
+	userSubscriptions []*models.UserSubscription
+	histories         []*models.SubscriptionHistory
+	reminders         []*models.SubscriptionExpiryReminder
+	inUse             map[uint32]bool
+	updated           []*models.Subscription
+
+	// Returned by FindExpiredUserSubscriptions when set, to load rows that changed before they were locked
+	staleExpired []*models.UserSubscription
+
+	// Returned by FindFreeTierSubscription when set
+	freeTierErr error
+}
+
+func newFakeSubscriptionRepository(userSubscriptions ...*models.UserSubscription) *fakeSubscriptionRepository {
+	return &fakeSubscriptionRepository{
+		userSubscriptions: userSubscriptions,
+		inUse:             make(map[uint32]bool),
+		subscriptions: map[uint32]*models.Subscription{
+			1: {ID: 1, Name: "Free tier", Price: money.Zero(money.IDR), Duration: 28},
+			2: {ID: 2, Name: "Content Creator", Price: money.FromMajor(10000, money.IDR), Duration: 28, Plans: []models.SubscriptionPlan{
+				{Name: string(constants.SubscriptionProductSlot), Value: "100"},
+				{Name: string(constants.SubscriptionCategoryLimit), Value: "10"},
+				{Name: string(constants.SubscriptionAnalytics), Value: "true"},
+			}},
+			3: {ID: 3, Name: "Business", Price: money.FromMajor(30000, money.IDR), Duration: 28, Plans: []models.SubscriptionPlan{
+				{Name: string(constants.SubscriptionProductSlot), Value: "500"},
+				{Name: string(constants.SubscriptionCategoryLimit), Value: "50"},
+				{Name: string(constants.SubscriptionAnalytics), Value: "true"},
+				{Name: string(constants.SubscriptionInteractionMetrics), Value: "true"},
+			}},
+		},
+	}
+}
+
+func freeTierUserSubscription(userID uint32) *models.UserSubscription {
+	return &models.UserSubscription{
+		ID: 1, UserID: userID, SubID: 1, ExpiredAt: time.Now().AddDate(100, 0, 0), IsActive: true,
+		Sub: models.Subscription{ID: 1, Price: money.Zero(money.IDR)},
+	}
+}
+
+func (r *fakeSubscriptionRepository) WithTx(tx *gorm.DB) repositories.SubscriptionRepository {
+	return r
+}
+
+func (r *fakeSubscriptionRepository) FindByID(id uint32) (*models.Subscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return subscription, nil
+}
+
+func (r *fakeSubscriptionRepository) FindByIDForUpdate(id uint32) (*models.Subscription, error) {
+	return r.FindByID(id)
+}
+
+func (r *fakeSubscriptionRepository) FindAllSubscriptionsWithPlans() ([]*models.Subscription, error) {
+	subscriptions := make([]*models.Subscription, 0, len(r.subscriptions))
+	for _, subscription := range r.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *fakeSubscriptionRepository) FindCurrentSubscriptionByName(name string) (*models.Subscription, error) {
+	for _, subscription := range r.subscriptions {
+		if subscription.Name == name && !subscription.IsRetired() {
+			return subscription, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSubscriptionRepository) FindCurrentVersion(familyID uint32) (*models.Subscription, error) {
+	for _, subscription := range r.subscriptions {
+		if subscription.Family() == familyID && !subscription.IsRetired() {
+			return subscription, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSubscriptionRepository) IsSubscriptionInUse(id uint32) (bool, error) {
+	return r.inUse[id], nil
+}
+
+func (r *fakeSubscriptionRepository) UpdateSubscription(subscription *models.Subscription) (*models.Subscription, error) {
+	r.updated = append(r.updated, subscription)
+	return subscription, nil
+}
+
+func (r *fakeSubscriptionRepository) StoreSubscriptionVersion(subscription *models.Subscription) error {
+	subscription.ID = uint32(len(r.subscriptions) + 1)
+	r.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) RetireSubscription(id uint32, supersededByID *uint32, at time.Time) error {
+	r.subscriptions[id].RetiredAt = &at
+	r.subscriptions[id].SupersededByID = supersededByID
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) FindFreeTierSubscription() (*models.Subscription, error) {
+	if r.freeTierErr != nil {
+		return nil, r.freeTierErr
+	}
+
+	return r.subscriptions[1], nil
+}
+
+func (r *fakeSubscriptionRepository) FindUserSubscriptionForUpdate(id uint32) (*models.UserSubscription, error) {
+	for _, userSubscription := range r.userSubscriptions {
+		if userSubscription.ID == id {
+			return userSubscription, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSubscriptionRepository) FindActiveUserSubscription(userID uint32) (*models.UserSubscription, error) {
+	for _, userSubscription := range r.userSubscriptions {
+		if userSubscription.UserID == userID && userSubscription.IsActive {
+			return userSubscription, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSubscriptionRepository) FindScheduledUserSubscription(userID uint32) (*models.UserSubscription, error) {
+	for _, userSubscription := range r.userSubscriptions {
+		if userSubscription.UserID == userID && userSubscription.IsScheduled {
+			return userSubscription, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSubscriptionRepository) FindExpiringUserSubscriptions(from time.Time, to time.Time, limit int) ([]*models.UserSubscription, error) {
+	userSubscriptions := make([]*models.UserSubscription, 0)
+	for _, userSubscription := range r.userSubscriptions {
+		if userSubscription.IsActive && userSubscription.ExpiredAt.After(from) && !userSubscription.ExpiredAt.After(to) {
+			userSubscriptions = append(userSubscriptions, userSubscription)
+		}
+	}
+
+	return userSubscriptions, nil
+}
+
+func (r *fakeSubscriptionRepository) FindExpiredUserSubscriptions(at time.Time, limit int) ([]*models.UserSubscription, error) {
+	if r.staleExpired != nil {
+		return r.staleExpired, nil
+	}
+
+	userSubscriptions := make([]*models.UserSubscription, 0)
+	for _, userSubscription := range r.userSubscriptions {
+		if userSubscription.IsActive && !userSubscription.ExpiredAt.After(at) {
+			copied := *userSubscription
+			userSubscriptions = append(userSubscriptions, &copied)
+		}
+	}
+
+	return userSubscriptions, nil
+}
+
+func (r *fakeSubscriptionRepository) UpdateUserSubscription(userSubscription *models.UserSubscription) error {
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) DeactivateUserSubscriptions(userID uint32) error {
+	for _, userSubscription := range r.userSubscriptions {
+		if userSubscription.UserID == userID {
+			userSubscription.IsActive = false
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) SubscribeUser(userSubscription *models.UserSubscription) error {
+	userSubscription.ID = uint32(len(r.userSubscriptions) + 1)
+	r.userSubscriptions = append(r.userSubscriptions, userSubscription)
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) HasUsedTrial(userID uint32, email string) (bool, error) {
+	for _, userSubscription := range r.userSubscriptions {
+		if userSubscription.TrialEndsAt == nil {
+			continue
+		}
+
+		if userSubscription.UserID == userID || (userSubscription.TrialEmail != nil && *userSubscription.TrialEmail == email) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *fakeSubscriptionRepository) StoreHistory(history *models.SubscriptionHistory) error {
+	r.histories = append(r.histories, history)
+	return nil
+}
+
+func (r *fakeSubscriptionRepository) FindHistoryByOrderID(orderID uuid.UUID) (*models.SubscriptionHistory, error) {
+	for i := len(r.histories) - 1; i >= 0; i-- {
+		if r.histories[i].OrderID != nil && *r.histories[i].OrderID == orderID {
+			return r.histories[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Every reminder is reported as already sent, so nothing is queued
+func (r *fakeSubscriptionRepository) StoreExpiryReminder(reminder *models.SubscriptionExpiryReminder) (bool, error) {
+	r.reminders = append(r.reminders, reminder)
+	return false, nil
+}
+
+func historyEvents(histories []*models.SubscriptionHistory) []string {
+	events := make([]string, 0, len(histories))
+	for _, history := range histories {
+		events = append(events, history.Event)
+	}
+
+	return events
+}
+
+type fakeSubscriptionPlanRepository struct {
+	repositories.SubscriptionPlanRepository
+	replaced map[uint32][]models.SubscriptionPlan
+}
+
+func (r *fakeSubscriptionPlanRepository) WithTx(tx *gorm.DB) repositories.SubscriptionPlanRepository {
+	return r
+}
+
+func (r *fakeSubscriptionPlanRepository) ReplacePlans(subID uint32, plans []models.SubscriptionPlan) error {
+	r.replaced[subID] = plans
+	return nil
+}
+
+// A subscription order repository that redeems the promo code of every stored order
+type fakeSubscriptionOrderRepository struct {
+	repositories.SubscriptionOrderRepository
+	promoCodes *fakePromoCodeRepository
+	orders     []*models.SubscriptionOrder
+	paid       bool
+}
+
+func (r *fakeSubscriptionOrderRepository) WithTx(tx *gorm.DB) repositories.SubscriptionOrderRepository {
+	return r
+}
+
+func (r *fakeSubscriptionOrderRepository) StoreNewSubscriptionOrder(order *models.SubscriptionOrder) error {
+	if order.PromoCodeID != nil {
+		r.promoCodes.redemptions[*order.PromoCodeID] = append(r.promoCodes.redemptions[*order.PromoCodeID], order.UserID)
+	}
+
+	r.orders = append(r.orders, order)
+	return nil
+}
+
+func (r *fakeSubscriptionOrderRepository) HasPaidOrder(userID uint32) (bool, error) {
+	return r.paid, nil
+}
+
+func (r *fakeSubscriptionOrderRepository) FindByPaymentTransactionID(paymentTransactionID string) (*models.SubscriptionOrder, error) {
+	for _, order := range r.orders {
+		if order.PaymentTransactionID != nil && order.PaymentTransactionID.String() == paymentTransactionID {
+			return order, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSubscriptionOrderRepository) UpdateOrderTransaction(orderID string, update *models.SubscriptionOrder) error {
+	for _, order := range r.orders {
+		if order.ID.String() == orderID {
+			order.Status = update.Status
+		}
+	}
+
+	return nil
+}
+
+// Records the status applied to every order
+// Given the current statuses, it also refuses the transitions the payment state machine refuses
+type fakeSubscriptionOrderService struct {
+	SubscriptionOrderService
+	statuses map[string]midtrans.PaymentStatus
+	updates  map[string]string
+	applied  int
+}
+
+func (s *fakeSubscriptionOrderService) UpdateSubscriptionOrder(orderID string, request *dtos.UpdateSubscriptionOrderDTO) *errors.CustomError {
+	if s.statuses != nil {
+		status := midtrans.ParseStatus(*request.TransactionStatus)
+		if !s.statuses[orderID].CanTransitionTo(status) {
+			return errors.Conflict("Payment status cannot change", nil)
+		}
+
+		s.statuses[orderID] = status
+	}
+
+	if s.updates != nil {
+		s.updates[orderID] = *request.TransactionStatus
+	}
+
+	s.applied++
+	return nil
+}
+
+type fakeMerchantRepository struct {
+	repositories.MerchantRepository
+	merchants []*models.Merchant
+	locked    bool
+}
+
+func (r *fakeMerchantRepository) WithTx(tx *gorm.DB) repositories.MerchantRepository {
+	return r
+}
+
+func (r *fakeMerchantRepository) FindByUserID(userID uint32) ([]*models.Merchant, error) {
+	return r.merchants, nil
+}
+
+func (r *fakeMerchantRepository) FindByUserIDForUpdate(userID uint32) ([]*models.Merchant, error) {
+	r.locked = true
+	return r.merchants, nil
+}
+
+func (r *fakeMerchantRepository) FindByID(merchantID string) (*models.Merchant, error) {
+	for _, merchant := range r.merchants {
+		if merchant.ID == merchantID {
+			return merchant, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeProductRepository struct {
+	repositories.ProductRepository
+	products     map[string]int
+	maxPhotos    int64
+	stored       []*models.Product
+	publishLimit int64
+}
+
+func (r *fakeProductRepository) WithTx(tx *gorm.DB) repositories.ProductRepository {
+	return r
+}
+
+func (r *fakeProductRepository) CountProductsByMerchantID(merchantID string) (int64, error) {
+	return int64(r.products[merchantID]), nil
+}
+
+func (r *fakeProductRepository) FindMaxPhotosByMerchantID(merchantID string) (int64, error) {
+	return r.maxPhotos, nil
+}
+
+func (r *fakeProductRepository) FindMerchantByProductID(productID string) (*models.Merchant, error) {
+	return &models.Merchant{ID: "merchant-a"}, nil
+}
+
+func (r *fakeProductRepository) FindPublishedProductByID(productID string, publishLimit int64) (*models.Product, error) {
+	r.publishLimit = publishLimit
+	return &models.Product{ID: productID, MerchantID: "merchant-a"}, nil
+}
+
+func (r *fakeProductRepository) StoreProduct(product *models.Product) (*models.Product, error) {
+	r.stored = append(r.stored, product)
+	return product, nil
+}
+
+type fakeCategoryRepository struct {
+	repositories.CategoryRepository
+	categories map[string]int
+}
+
+func (r *fakeCategoryRepository) WithTx(tx *gorm.DB) repositories.CategoryRepository {
+	return r
+}
+
+func (r *fakeCategoryRepository) CountCategoriesByMerchantID(merchantID string) (int64, error) {
+	return int64(r.categories[merchantID]), nil
+}
+
+// A promo code repository that counts the redemptions of its codes per user
+type fakePromoCodeRepository struct {
+	repositories.PromoCodeRepository
+	promoCodes  map[uint32]*models.PromoCode
+	redemptions map[uint32][]uint32
+}
+
+func newFakePromoCodeRepository(promoCodes ...*models.PromoCode) *fakePromoCodeRepository {
+	repository := &fakePromoCodeRepository{
+		promoCodes:  make(map[uint32]*models.PromoCode),
+		redemptions: make(map[uint32][]uint32),
+	}
+
+	for _, promoCode := range promoCodes {
+		repository.promoCodes[promoCode.ID] = promoCode
+	}
+
+	return repository
+}
+
+func (r *fakePromoCodeRepository) WithTx(tx *gorm.DB) repositories.PromoCodeRepository {
+	return r
+}
+
+func (r *fakePromoCodeRepository) FindByIDForUpdate(id uint32) (*models.PromoCode, error) {
+	promoCode, ok := r.promoCodes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return promoCode, nil
+}
+
+func (r *fakePromoCodeRepository) FindByCode(code string) (*models.PromoCode, error) {
+	for _, promoCode := range r.promoCodes {
+		if promoCode.Code == code {
+			return promoCode, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePromoCodeRepository) StorePromoCode(promoCode *models.PromoCode) error {
+	promoCode.ID = uint32(len(r.promoCodes) + 1)
+	r.promoCodes[promoCode.ID] = promoCode
+	return nil
+}
+
+func (r *fakePromoCodeRepository) CountRedemptions(promoCodeID uint32) (int64, error) {
+	return int64(len(r.redemptions[promoCodeID])), nil
+}
+
+func (r *fakePromoCodeRepository) CountUserRedemptions(promoCodeID uint32, userID uint32) (int64, error) {
+	var count int64
+	for _, redeemedBy := range r.redemptions[promoCodeID] {
+		if redeemedBy == userID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+type fakeReferralRepository struct {
+	repositories.ReferralRepository
+	referrals []*models.Referral
+	referrers map[uint32]*models.User
+}
+
+func newFakeReferralRepository(referrals ...*models.Referral) *fakeReferralRepository {
+	return &fakeReferralRepository{
+		referrals: referrals,
+		referrers: map[uint32]*models.User{10: {ID: 10, Email: "jane@example.com"}},
+	}
+}
+
+func (r *fakeReferralRepository) WithTx(tx *gorm.DB) repositories.ReferralRepository {
+	return r
+}
+
+func (r *fakeReferralRepository) FindPendingByReferredIDForUpdate(referredID uint32) (*models.Referral, error) {
+	for _, referral := range r.referrals {
+		if referral.ReferredID == referredID && referral.Status == models.ReferralStatusPending {
+			referral.Referrer = r.referrers[referral.ReferrerID]
+			return referral, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeReferralRepository) UpdateReferral(referral *models.Referral) error {
+	return nil
+}
+
+func (r *fakeReferralRepository) CountRewardedSince(referrerID uint32, since time.Time) (int64, error) {
+	var count int64
+	for _, referral := range r.referrals {
+		if referral.ReferrerID == referrerID && referral.Status == models.ReferralStatusRewarded && !referral.RewardedAt.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+type fakeAuthRepository struct {
+	repositories.AuthRepository
+	sessions map[uuid.UUID]*models.UserSession
+	tokens   map[string]*models.UserHasToken
+}
+
+func newFakeAuthRepository() *fakeAuthRepository {
+	return &fakeAuthRepository{
+		sessions: make(map[uuid.UUID]*models.UserSession),
+		tokens:   make(map[string]*models.UserHasToken),
+	}
+}
+
+func (r *fakeAuthRepository) WithTx(tx *gorm.DB) repositories.AuthRepository {
+	return r
+}
+
+func (r *fakeAuthRepository) StoreSession(session *models.UserSession) error {
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r *fakeAuthRepository) UpdateSession(session *models.UserSession) error {
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r *fakeAuthRepository) FindSessionByID(id uuid.UUID) (*models.UserSession, error) {
+	return r.FindSessionByIDForUpdate(id)
+}
+
+func (r *fakeAuthRepository) FindSessionByIDForUpdate(id uuid.UUID) (*models.UserSession, error) {
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return session, nil
+}
+
+func (r *fakeAuthRepository) FindActiveSessionsByUserID(userID uint32, now time.Time) ([]*models.UserSession, error) {
+	sessions := make([]*models.UserSession, 0)
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.IsActiveAt(now) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *fakeAuthRepository) RevokeUserSessions(userID uint32, exceptSessionID uuid.UUID, reason string, revokedAt time.Time) (int64, error) {
+	var revoked int64
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ID != exceptSessionID {
+			session.Revoke(reason, revokedAt)
+			revoked++
+		}
+	}
+
+	return revoked, nil
+}
+
+func (r *fakeAuthRepository) StoreToken(token *models.UserHasToken) error {
+	r.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (r *fakeAuthRepository) UpdateToken(token *models.UserHasToken) error {
+	r.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (r *fakeAuthRepository) FindTokenByHashForUpdate(tokenHash string) (*models.UserHasToken, error) {
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return token, nil
+}
+
+type fakePasswordResetRepository struct {
+	repositories.PasswordResetRepository
+	tokens []*models.PasswordResetToken
+}
+
+func (r *fakePasswordResetRepository) WithTx(tx *gorm.DB) repositories.PasswordResetRepository {
+	return r
+}
+
+func (r *fakePasswordResetRepository) Create(token *models.PasswordResetToken) error {
+	token.ID = uint32(len(r.tokens) + 1)
+	token.CreatedAt = time.Now()
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *fakePasswordResetRepository) Update(token *models.PasswordResetToken) error {
+	return nil
+}
+
+func (r *fakePasswordResetRepository) FindByTokenHashForUpdate(tokenHash string) (*models.PasswordResetToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePasswordResetRepository) CountCreatedSince(userID uint32, since time.Time) (int64, error) {
+	var count int64
+	for _, token := range r.tokens {
+		if token.UserID == userID && !token.CreatedAt.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *fakePasswordResetRepository) InvalidateUserTokens(userID uint32, usedAt time.Time) error {
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+		}
+	}
+
+	return nil
+}
+
+type fakePaymentMethodRepository struct {
+	repositories.PaymentMethodRepository
+	methods []*models.PaymentMethod
+	updated *models.PaymentMethod
+}
+
+func (r *fakePaymentMethodRepository) FindAll() ([]*models.PaymentMethod, error) {
+	return r.methods, nil
+}
+
+func (r *fakePaymentMethodRepository) FindEnabled() ([]*models.PaymentMethod, error) {
+	methods := make([]*models.PaymentMethod, 0)
+	for _, method := range r.methods {
+		if method.IsEnabled {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods, nil
+}
+
+func (r *fakePaymentMethodRepository) FindByID(id uint32) (*models.PaymentMethod, error) {
+	for _, method := range r.methods {
+		if method.ID == id {
+			copied := *method
+			return &copied, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePaymentMethodRepository) FindByChannel(channel string) (*models.PaymentMethod, error) {
+	for _, method := range r.methods {
+		if method.Channel == channel {
+			return method, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePaymentMethodRepository) StorePaymentMethod(method *models.PaymentMethod) error {
+	method.ID = uint32(len(r.methods) + 1)
+	r.methods = append(r.methods, method)
+	return nil
+}
+
+func (r *fakePaymentMethodRepository) UpdatePaymentMethod(method *models.PaymentMethod) error {
+	r.updated = method
+	return nil
+}
+
+type fakePaymentTransactionRepository struct {
+	repositories.PaymentTransactionRepository
+	transactions []*models.PaymentTransaction
+}
+
+func (r *fakePaymentTransactionRepository) WithTx(tx *gorm.DB) repositories.PaymentTransactionRepository {
+	return r
+}
+
+func (r *fakePaymentTransactionRepository) FindByOrderID(orderID string) (*models.PaymentTransaction, error) {
+	for _, transaction := range r.transactions {
+		if transaction.ID.String() == orderID {
+			return transaction, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePaymentTransactionRepository) FindByOrderIDForUpdate(orderID string) (*models.PaymentTransaction, error) {
+	return r.FindByOrderID(orderID)
+}
+
+func (r *fakePaymentTransactionRepository) Update(transaction *models.PaymentTransaction) error {
+	return nil
+}
+
+func (r *fakePaymentTransactionRepository) FindPendingTransactions(createdBefore time.Time, expiredBefore time.Time, limit int) ([]*models.PaymentTransaction, error) {
+	return r.transactions, nil
+}
+
+// A refund repository that sums the refunds stored in it by status
+type fakePaymentRefundRepository struct {
+	repositories.PaymentRefundRepository
+	refunds []*models.PaymentRefund
+}
+
+func (r *fakePaymentRefundRepository) WithTx(tx *gorm.DB) repositories.PaymentRefundRepository {
+	return r
+}
+
+func (r *fakePaymentRefundRepository) StoreRefund(refund *models.PaymentRefund) error {
+	r.refunds = append(r.refunds, refund)
+	return nil
+}
+
+func (r *fakePaymentRefundRepository) UpdateRefund(refund *models.PaymentRefund) error {
+	return nil
+}
+
+func (r *fakePaymentRefundRepository) SumRefundedAmount(paymentTransactionID string, currency string) (money.Money, error) {
+	return r.sum(paymentTransactionID, currency, func(status string) bool {
+		return status != string(midtrans.PaymentStatusPending) && status != string(midtrans.PaymentStatusFailed)
+	}), nil
+}
+
+func (r *fakePaymentRefundRepository) SumPendingRefundAmount(paymentTransactionID string, currency string) (money.Money, error) {
+	return r.sum(paymentTransactionID, currency, func(status string) bool {
+		return status == string(midtrans.PaymentStatusPending)
+	}), nil
+}
+
+func (r *fakePaymentRefundRepository) sum(paymentTransactionID string, currency string, counted func(status string) bool) money.Money {
+	amount := money.Zero(currency)
+	for _, refund := range r.refunds {
+		if refund.PaymentTransactionID.String() == paymentTransactionID && counted(refund.Status) {
+			amount = amount.Add(refund.Amount)
+		}
+	}
+
+	return amount
+}
+
+type fakePaymentNotificationRepository struct {
+	repositories.PaymentNotificationRepository
+	notifications []*models.PaymentNotification
+}
+
+func (r *fakePaymentNotificationRepository) StoreNotification(notification *models.PaymentNotification) error {
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func (r *fakePaymentNotificationRepository) FindByID(notificationID string) (*models.PaymentNotification, error) {
+	for _, notification := range r.notifications {
+		if notification.ID.String() == notificationID {
+			return notification, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePaymentNotificationRepository) IsProcessed(dedupeKey string) (bool, error) {
+	for _, notification := range r.notifications {
+		if notification.DedupeKey != nil && *notification.DedupeKey == dedupeKey &&
+			(notification.Status == models.PaymentNotificationStatusProcessed || notification.Status == models.PaymentNotificationStatusIgnored) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *fakePaymentNotificationRepository) Update(notification *models.PaymentNotification) error {
+	return nil
+}
+
+type fakeAnalyticsRepository struct {
+	repositories.AnalyticsRepository
+	revenue     []dtos.PaymentAnalyticsRow
+	refunds     []dtos.PaymentAnalyticsRow
+	conversions []dtos.PaymentAnalyticsRow
+	subscribers []dtos.PaymentAnalyticsRow
+	lastQuery   *dtos.PaymentAnalyticsQuery
+}
+
+func (r *fakeAnalyticsRepository) GetRevenueSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	r.lastQuery = params
+	return r.revenue, nil
+}
+
+func (r *fakeAnalyticsRepository) GetRefundSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	return r.refunds, nil
+}
+
+func (r *fakeAnalyticsRepository) GetConversionSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	return r.conversions, nil
+}
+
+func (r *fakeAnalyticsRepository) GetSubscriberSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	return r.subscribers, nil
+}
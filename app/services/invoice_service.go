@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/config"
+	"senkou-catalyst-be/utils/mailer"
+	"senkou-catalyst-be/utils/pdf"
+	"senkou-catalyst-be/utils/queue"
+	"senkou-catalyst-be/utils/storage"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const TaskSendOrderInvoice = "payment:send_invoice"
+
+const invoiceTemplate = "payment-invoice.html"
+
+type InvoiceService interface {
+	QueueOrderInvoice(orderID string) *errors.CustomError
+	IssueOrderInvoice(orderID string) (*models.SubscriptionOrder, *errors.CustomError)
+	GetUserOrderInvoice(userID uint32, orderID string) ([]byte, string, *errors.CustomError)
+	HandleSendOrderInvoice(ctx context.Context, task *asynq.Task) error
+}
+
+type InvoiceServiceInstance struct {
+	SubscriptionOrderRepository repositories.SubscriptionOrderRepository
+	QueueService                *queue.QueueService
+}
+
+func NewInvoiceService(subscriptionOrderRepository repositories.SubscriptionOrderRepository, queueService *queue.QueueService) InvoiceService {
+	return &InvoiceServiceInstance{
+		SubscriptionOrderRepository: subscriptionOrderRepository,
+		QueueService:                queueService,
+	}
+}
+
+// Queue the invoice email of a settled order
+// The invoice is generated and sent by the queue worker so a slow mail server never delays a payment notification
+// It returns an error if the task could not be queued
+func (s *InvoiceServiceInstance) QueueOrderInvoice(orderID string) *errors.CustomError {
+	job := s.QueueService.NewJobBuilder(TaskSendOrderInvoice).
+		WithPayload(map[string]interface{}{
+			"order_id": orderID,
+		}).
+		WithPriority(queue.PriorityNormal).
+		WithMaxRetry(5).
+		WithTimeout(2 * time.Minute)
+
+	if _, err := job.Enqueue(context.Background()); err != nil {
+		return errors.Internal("Failed to queue invoice email", err.Error())
+	}
+
+	return nil
+}
+
+// Issue the invoice of a settled order
+// This function renders the invoice as a PDF and stores it, an order keeps the same invoice once issued
+// It returns the order with its invoice or an error if the order has not been settled
+func (s *InvoiceServiceInstance) IssueOrderInvoice(orderID string) (*models.SubscriptionOrder, *errors.CustomError) {
+	order, err := s.SubscriptionOrderRepository.FindInvoiceOrder(orderID)
+	if err != nil {
+		return nil, errors.NotFound("Subscription order not found")
+	}
+
+	if order.InvoicePath != nil {
+		return order, nil
+	}
+
+	if order.Status != string(midtrans.PaymentStatusSettled) {
+		return nil, errors.BadRequest("Invoice is not available", "Only settled orders have an invoice")
+	}
+
+	invoiceNumber := newInvoiceNumber(order)
+
+	folder := "invoices"
+	invoicePath, err := storage.UploadBytesToStorage(renderOrderInvoice(order, invoiceNumber), invoiceNumber+".pdf", "invoice", &folder)
+	if err != nil {
+		return nil, errors.Internal("Failed to store invoice", err.Error())
+	}
+
+	if err := s.SubscriptionOrderRepository.UpdateOrderTransaction(orderID, &models.SubscriptionOrder{
+		InvoiceNumber: &invoiceNumber,
+		InvoicePath:   &invoicePath,
+	}); err != nil {
+		return nil, errors.Internal("Failed to update subscription order", err.Error())
+	}
+
+	order.InvoiceNumber = &invoiceNumber
+	order.InvoicePath = &invoicePath
+
+	return order, nil
+}
+
+// Get the invoice of an order of a user
+// Invoices of orders settled before invoices existed are issued on the first download
+// It returns the PDF file and its filename
+func (s *InvoiceServiceInstance) GetUserOrderInvoice(userID uint32, orderID string) ([]byte, string, *errors.CustomError) {
+	if _, err := uuid.Parse(orderID); err != nil {
+		return nil, "", errors.NotFound("Subscription order not found")
+	}
+
+	order, err := s.SubscriptionOrderRepository.FindUserOrder(userID, orderID)
+	if err != nil {
+		return nil, "", errors.NotFound("Subscription order not found")
+	}
+
+	if order.InvoicePath == nil {
+		issuedOrder, appError := s.IssueOrderInvoice(orderID)
+		if appError != nil {
+			return nil, "", appError
+		}
+
+		order = issuedOrder
+	}
+
+	file, _, err := storage.DownloadFileFromStorage(*order.InvoicePath)
+	if err != nil {
+		return nil, "", errors.Internal("Failed to get invoice", err.Error())
+	}
+
+	return file, *order.InvoiceNumber + ".pdf", nil
+}
+
+// Handle the invoice email task
+// This function issues the invoice if needed and emails it to the owner of the order, an invoice is only emailed once
+// It returns an error so the task is retried when the invoice could not be issued or sent
+func (s *InvoiceServiceInstance) HandleSendOrderInvoice(ctx context.Context, task *asynq.Task) error {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal invoice payload: %w", err)
+	}
+
+	orderID, ok := payload["order_id"].(string)
+	if !ok || orderID == "" {
+		return fmt.Errorf("invalid order_id in payload")
+	}
+
+	order, appError := s.IssueOrderInvoice(orderID)
+	if appError != nil {
+		return fmt.Errorf("failed to issue invoice of order %s: %s", orderID, appError.Message)
+	}
+
+	if order.InvoiceSentAt != nil || order.User == nil {
+		return nil
+	}
+
+	mailerService, err := mailer.NewMailerService()
+	if err != nil {
+		return fmt.Errorf("failed to initialize mailer service: %w", err)
+	}
+
+	if !mailerService.TemplateExists(invoiceTemplate) {
+		return fmt.Errorf("email template not found: %s", invoiceTemplate)
+	}
+
+	subscriptionName := ""
+	if order.Subscription != nil {
+		subscriptionName = order.Subscription.Name
+	}
+
+	templateData := map[string]interface{}{
+		"UserName":         order.User.Name,
+		"InvoiceNumber":    *order.InvoiceNumber,
+		"SubscriptionName": subscriptionName,
+		"Amount":           order.Amount.Format(),
+		"PaidAt":           invoiceDate(order).Format("02 January 2006"),
+		"InvoiceLink":      config.GetEnv("APP_URL", "http://localhost:8080") + "/files/" + *order.InvoicePath,
+		"SupportEmail":     config.GetEnv("SUPPORT_EMAIL", "support@catalyst.com"),
+	}
+
+	if err := mailerService.SendTemplate(
+		order.User.Email,
+		"Catalyst - Invoice "+*order.InvoiceNumber,
+		invoiceTemplate,
+		templateData,
+	); err != nil {
+		return fmt.Errorf("failed to send invoice to %s: %w", order.User.Email, err)
+	}
+
+	sentAt := time.Now()
+	if err := s.SubscriptionOrderRepository.UpdateOrderTransaction(orderID, &models.SubscriptionOrder{
+		InvoiceSentAt: &sentAt,
+	}); err != nil {
+		log.Printf("Failed to record invoice email of order %s: %v", orderID, err)
+	}
+
+	log.Printf("Successfully sent invoice %s to %s", *order.InvoiceNumber, order.User.Email)
+	return nil
+}
+
+// Get the date an order was paid, falling back to its last update for orders without a settlement time
+func invoiceDate(order *models.SubscriptionOrder) time.Time {
+	if order.PaymentTransaction != nil && order.PaymentTransaction.SettledAt != nil {
+		return *order.PaymentTransaction.SettledAt
+	}
+
+	return order.UpdatedAt
+}
+
+// Build the invoice number of an order, e.g. INV-20250921-1A2B3C4D
+// The number is derived from the payment date and the order ID so issuing it again gives the same number
+func newInvoiceNumber(order *models.SubscriptionOrder) string {
+	return fmt.Sprintf("INV-%s-%s", invoiceDate(order).Format("20060102"), strings.ToUpper(order.ID.String()[:8]))
+}
+
+// Render the invoice of an order as a PDF
+func renderOrderInvoice(order *models.SubscriptionOrder, invoiceNumber string) []byte {
+	const (
+		left  = 50.0
+		right = pdf.PageWidth - 50
+	)
+
+	document := pdf.NewDocument()
+
+	document.Text(left, 80, pdf.FontBold, 24, "INVOICE")
+	document.TextRight(right, 72, pdf.FontBold, 14, config.GetEnv("APP_NAME", "Senkou Catalyst"))
+	document.TextRight(right, 90, pdf.FontRegular, 10, config.GetEnv("SUPPORT_EMAIL", "support@catalyst.com"))
+	document.Line(left, 105, right, 105, 1)
+
+	y := 135.0
+	details := [][2]string{
+		{"Invoice number", invoiceNumber},
+		{"Order ID", order.ID.String()},
+		{"Paid at", invoiceDate(order).Format("02 January 2006 15:04")},
+		{"Status", strings.ToUpper(order.Status)},
+	}
+
+	if transaction := order.PaymentTransaction; transaction != nil {
+		details = append(details, [2]string{
+			"Payment method",
+			strings.ToUpper(strings.ReplaceAll(transaction.PaymentType+" "+transaction.PaymentChannel, "_", " ")),
+		})
+	}
+
+	for _, detail := range details {
+		document.Text(left, y, pdf.FontBold, 10, detail[0])
+		document.Text(left+110, y, pdf.FontRegular, 10, detail[1])
+		y += 16
+	}
+
+	if user := order.User; user != nil {
+		y += 14
+		document.Text(left, y, pdf.FontBold, 11, "Billed to")
+		document.Text(left, y+16, pdf.FontRegular, 10, user.Name)
+		document.Text(left, y+30, pdf.FontRegular, 10, user.Email)
+		y += 44
+	}
+
+	y += 20
+	document.Text(left, y, pdf.FontBold, 10, "Description")
+	document.TextRight(right-200, y, pdf.FontBold, 10, "Qty")
+	document.TextRight(right-100, y, pdf.FontBold, 10, "Price")
+	document.TextRight(right, y, pdf.FontBold, 10, "Amount")
+	document.Line(left, y+8, right, y+8, 0.5)
+	y += 26
+
+	for _, item := range order.Items {
+		document.Text(left, y, pdf.FontRegular, 10, item.Name)
+		document.TextRight(right-200, y, pdf.FontRegular, 10, fmt.Sprintf("%d", item.Quantity))
+		document.TextRight(right-100, y, pdf.FontRegular, 10, item.Price.Format())
+		document.TextRight(right, y, pdf.FontRegular, 10, item.Amount.Format())
+		y += 20
+	}
+
+	document.Line(left, y-8, right, y-8, 0.5)
+	y += 10
+	document.TextRight(right-100, y, pdf.FontBold, 11, "Total")
+	document.TextRight(right, y, pdf.FontBold, 11, order.Amount.Format())
+
+	document.Text(left, pdf.PageHeight-60, pdf.FontRegular, 9, "Thank you for your purchase. This invoice was issued electronically and is valid without a signature.")
+
+	return document.Bytes()
+}
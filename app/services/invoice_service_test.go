@@ -0,0 +1,71 @@
+package services
+
+import (
+	"bytes"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newInvoiceOrder() *models.SubscriptionOrder {
+	settledAt := time.Date(2025, 9, 21, 10, 30, 0, 0, time.UTC)
+
+	return &models.SubscriptionOrder{
+		ID:     uuid.MustParse("1a2b3c4d-0000-4000-8000-000000000000"),
+		Amount: money.FromMajor(150000, money.IDR),
+		Status: "settled",
+		User: &models.User{
+			Name:  "Jane Doe",
+			Email: "jane@example.com",
+		},
+		PaymentTransaction: &models.PaymentTransaction{
+			PaymentType:    "bank_transfer",
+			PaymentChannel: "bca",
+			SettledAt:      &settledAt,
+		},
+		Items: []models.SubscriptionOrderItem{
+			{Name: "Pro Plan", Quantity: 1, Price: money.FromMajor(150000, money.IDR), Amount: money.FromMajor(150000, money.IDR)},
+		},
+	}
+}
+
+func TestNewInvoiceNumber(t *testing.T) {
+	t.Run("Should derive the number from the payment date and the order ID", func(t *testing.T) {
+		number := newInvoiceNumber(newInvoiceOrder())
+
+		if number != "INV-20250921-1A2B3C4D" {
+			t.Errorf("Expected INV-20250921-1A2B3C4D, got %s", number)
+		}
+	})
+
+	t.Run("Should fall back to the last update of the order", func(t *testing.T) {
+		order := newInvoiceOrder()
+		order.PaymentTransaction = nil
+		order.UpdatedAt = time.Date(2025, 9, 22, 0, 0, 0, 0, time.UTC)
+
+		number := newInvoiceNumber(order)
+
+		if number != "INV-20250922-1A2B3C4D" {
+			t.Errorf("Expected INV-20250922-1A2B3C4D, got %s", number)
+		}
+	})
+}
+
+func TestRenderOrderInvoice(t *testing.T) {
+	t.Run("Should render a PDF with the invoice details", func(t *testing.T) {
+		invoice := renderOrderInvoice(newInvoiceOrder(), "INV-20250921-1A2B3C4D")
+
+		if !bytes.HasPrefix(invoice, []byte("%PDF-")) {
+			t.Errorf("Expected %%PDF-, got %q", invoice[:5])
+		}
+
+		for _, text := range []string{"INV-20250921-1A2B3C4D", "Pro Plan", "Jane Doe", "Rp 150.000"} {
+			if !bytes.Contains(invoice, []byte(text)) {
+				t.Errorf("Expected the invoice to contain %s", text)
+			}
+		}
+	})
+}
@@ -1,47 +1,85 @@
 package services
 
 import (
+	stderr "errors"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
 	"senkou-catalyst-be/integrations/midtrans"
 	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/money"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type PaymentMethodsService interface {
 	GetAllAvailablePaymentMethods() ([]midtrans.PaymentMethodConfig, *errors.CustomError)
 	GetPaymentMethodsByType(paymentType string) ([]midtrans.PaymentMethodConfig, *errors.CustomError)
-	GetPaymentMethodByChannelCode(channelCode string) (*midtrans.PaymentMethodConfig, *errors.CustomError)
 	GetPaymentMethodTypes() ([]string, *errors.CustomError)
+	GetAvailablePaymentMethod(paymentType string, channel string) (*midtrans.PaymentMethodConfig, *errors.CustomError)
+	GetPaymentMethods() ([]*models.PaymentMethod, *errors.CustomError)
+	GetPaymentMethodByID(id uint32) (*models.PaymentMethod, *errors.CustomError)
+	CreatePaymentMethod(dto *dtos.CreatePaymentMethodDTO) (*models.PaymentMethod, *errors.CustomError)
+	UpdatePaymentMethod(id uint32, dto *dtos.UpdatePaymentMethodDTO) (*models.PaymentMethod, *errors.CustomError)
+	DeletePaymentMethod(id uint32) *errors.CustomError
+	CreateMaintenanceWindow(id uint32, dto *dtos.CreateMaintenanceWindowDTO) (*models.PaymentMethodMaintenanceWindow, *errors.CustomError)
+	DeleteMaintenanceWindow(id uint32, windowID uint32) *errors.CustomError
 }
 
-type PaymentMethodsServiceInstance struct{}
+type PaymentMethodsServiceInstance struct {
+	PaymentMethodRepository repositories.PaymentMethodRepository
+	Gateways                *gateway.Registry
+}
 
-func NewPaymentMethodsService() PaymentMethodsService {
-	return &PaymentMethodsServiceInstance{}
+func NewPaymentMethodsService(paymentMethodRepository repositories.PaymentMethodRepository, gateways *gateway.Registry) PaymentMethodsService {
+	return &PaymentMethodsServiceInstance{
+		PaymentMethodRepository: paymentMethodRepository,
+		Gateways:                gateways,
+	}
 }
 
+// Get the payment methods customers can pay with right now
+// Disabled payment methods, payment methods under maintenance and payment methods
+// of a provider whose gateway is not configured are left out
+// It returns the payment methods sorted by their display order
 func (s *PaymentMethodsServiceInstance) GetAllAvailablePaymentMethods() ([]midtrans.PaymentMethodConfig, *errors.CustomError) {
-	methods, err := midtrans.GetPaymentMethods()
+	methods, err := s.PaymentMethodRepository.FindEnabled()
 	if err != nil {
 		return nil, errors.Internal("Failed to get payment methods", err.Error())
 	}
-	return methods, nil
+
+	now := time.Now()
+	available := make([]midtrans.PaymentMethodConfig, 0, len(methods))
+
+	for _, method := range methods {
+		if s.Gateways.Has(gateway.Provider(method.Provider)) && method.ActiveMaintenanceWindow(now) == nil {
+			available = append(available, toPaymentMethodConfig(method))
+		}
+	}
+
+	return available, nil
 }
 
 func (s *PaymentMethodsServiceInstance) GetPaymentMethodsByType(paymentType string) ([]midtrans.PaymentMethodConfig, *errors.CustomError) {
-	methods, err := midtrans.GetPaymentMethodsByType(paymentType)
+	methods, err := s.GetAllAvailablePaymentMethods()
 	if err != nil {
-		return nil, errors.Internal("Failed to get payment methods by type", err.Error())
+		return nil, err
 	}
-	return methods, nil
-}
 
-func (s *PaymentMethodsServiceInstance) GetPaymentMethodByChannelCode(channel string) (*midtrans.PaymentMethodConfig, *errors.CustomError) {
-	method, err := midtrans.GetPaymentMethodByChannel(channel)
-	if err != nil {
-		return nil, errors.Internal("Failed to get payment method by channel", err.Error())
+	filteredMethods := make([]midtrans.PaymentMethodConfig, 0)
+	for _, method := range methods {
+		if method.PaymentType == paymentType {
+			filteredMethods = append(filteredMethods, method)
+		}
 	}
-	return method, nil
+
+	return filteredMethods, nil
 }
 
+// Get the payment types of the available payment methods
+// The types keep the display order of their first payment method
 func (s *PaymentMethodsServiceInstance) GetPaymentMethodTypes() ([]string, *errors.CustomError) {
 	methods, err := s.GetAllAvailablePaymentMethods()
 	if err != nil {
@@ -49,14 +87,285 @@ func (s *PaymentMethodsServiceInstance) GetPaymentMethodTypes() ([]string, *erro
 	}
 
 	typeMap := make(map[string]bool)
+	types := make([]string, 0)
+
 	for _, method := range methods {
-		typeMap[method.PaymentType] = true
+		if !typeMap[method.PaymentType] {
+			typeMap[method.PaymentType] = true
+			types = append(types, method.PaymentType)
+		}
 	}
 
-	var types []string
-	for paymentType := range typeMap {
-		types = append(types, paymentType)
+	return types, nil
+}
+
+// Get a payment method a customer wants to pay with
+// This function rejects payment methods that are unknown, disabled, of another payment type,
+// of a provider whose gateway is not configured or under maintenance
+// It returns the payment method configuration used to charge the payment
+func (s *PaymentMethodsServiceInstance) GetAvailablePaymentMethod(paymentType string, channel string) (*midtrans.PaymentMethodConfig, *errors.CustomError) {
+	unsupported := errors.BadRequest("Unsupported payment method", map[string]any{
+		"payment_type":    paymentType,
+		"payment_channel": channel,
+	})
+
+	if channel == "" {
+		return nil, unsupported
 	}
 
-	return types, nil
+	method, err := s.PaymentMethodRepository.FindByChannel(channel)
+	if err != nil {
+		if stderr.Is(err, gorm.ErrRecordNotFound) {
+			return nil, unsupported
+		}
+
+		return nil, errors.Internal("Failed to get payment method", err.Error())
+	}
+
+	if !method.IsEnabled || method.PaymentType != paymentType || !s.Gateways.Has(gateway.Provider(method.Provider)) {
+		return nil, unsupported
+	}
+
+	if window := method.ActiveMaintenanceWindow(time.Now()); window != nil {
+		return nil, errors.BadRequest("Payment method is under maintenance", map[string]any{
+			"payment_channel": method.Channel,
+			"ends_at":         window.EndsAt,
+			"reason":          window.Reason,
+		})
+	}
+
+	config := toPaymentMethodConfig(method)
+	return &config, nil
+}
+
+// Get every payment method including the disabled ones, for admins
+func (s *PaymentMethodsServiceInstance) GetPaymentMethods() ([]*models.PaymentMethod, *errors.CustomError) {
+	methods, err := s.PaymentMethodRepository.FindAll()
+	if err != nil {
+		return nil, errors.Internal("Failed to get payment methods", err.Error())
+	}
+
+	return methods, nil
+}
+
+func (s *PaymentMethodsServiceInstance) GetPaymentMethodByID(id uint32) (*models.PaymentMethod, *errors.CustomError) {
+	method, err := s.PaymentMethodRepository.FindByID(id)
+	if err != nil {
+		if stderr.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFound("Payment method not found")
+		}
+
+		return nil, errors.Internal("Failed to get payment method", err.Error())
+	}
+
+	return method, nil
+}
+
+// Create a new payment method
+// The payment channel must be unique, a new payment method is enabled unless stated otherwise
+// It returns the created payment method
+func (s *PaymentMethodsServiceInstance) CreatePaymentMethod(dto *dtos.CreatePaymentMethodDTO) (*models.PaymentMethod, *errors.CustomError) {
+	if _, err := s.PaymentMethodRepository.FindByChannel(dto.Channel); err == nil {
+		return nil, errors.Conflict("Payment method already exists", map[string]any{
+			"payment_channel": dto.Channel,
+		})
+	} else if !stderr.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Internal("Failed to get payment method", err.Error())
+	}
+
+	method := &models.PaymentMethod{
+		Name:              dto.Name,
+		PaymentType:       dto.PaymentType,
+		Channel:           dto.Channel,
+		Provider:          dto.Provider,
+		MinAmount:         dto.MinAmount,
+		MaxAmount:         dto.MaxAmount,
+		Description:       dto.Description,
+		ExpiryDuration:    dto.ExpiryDuration,
+		ExpiryUnit:        dto.ExpiryUnit,
+		VANumberMinLength: dto.VANumberMinLength,
+		VANumberMaxLength: dto.VANumberMaxLength,
+		FeeFlat:           dto.FeeFlat,
+		FeePercentage:     dto.FeePercentage,
+		IsEnabled:         dto.IsEnabled == nil || *dto.IsEnabled,
+		DisplayOrder:      dto.DisplayOrder,
+	}
+
+	if err := validatePaymentMethod(method); err != nil {
+		return nil, err
+	}
+
+	if err := s.PaymentMethodRepository.StorePaymentMethod(method); err != nil {
+		return nil, errors.Internal("Failed to create payment method", err.Error())
+	}
+
+	return method, nil
+}
+
+// Update a payment method
+// Only the given fields are changed, the payment channel cannot be changed since payments refer to it
+// It returns the updated payment method
+func (s *PaymentMethodsServiceInstance) UpdatePaymentMethod(id uint32, dto *dtos.UpdatePaymentMethodDTO) (*models.PaymentMethod, *errors.CustomError) {
+	method, appError := s.GetPaymentMethodByID(id)
+	if appError != nil {
+		return nil, appError
+	}
+
+	if dto.Name != nil {
+		method.Name = *dto.Name
+	}
+	if dto.PaymentType != nil {
+		method.PaymentType = *dto.PaymentType
+	}
+	if dto.Provider != nil {
+		method.Provider = *dto.Provider
+	}
+	if dto.MinAmount != nil {
+		method.MinAmount = *dto.MinAmount
+	}
+	if dto.MaxAmount != nil {
+		method.MaxAmount = *dto.MaxAmount
+	}
+	if dto.Description != nil {
+		method.Description = *dto.Description
+	}
+	if dto.LogoURL != nil {
+		method.LogoURL = *dto.LogoURL
+	}
+	if dto.ExpiryDuration != nil {
+		method.ExpiryDuration = *dto.ExpiryDuration
+	}
+	if dto.ExpiryUnit != nil {
+		method.ExpiryUnit = *dto.ExpiryUnit
+	}
+	if dto.VANumberMinLength != nil {
+		method.VANumberMinLength = *dto.VANumberMinLength
+	}
+	if dto.VANumberMaxLength != nil {
+		method.VANumberMaxLength = *dto.VANumberMaxLength
+	}
+	if dto.FeeFlat != nil {
+		method.FeeFlat = *dto.FeeFlat
+	}
+	if dto.FeePercentage != nil {
+		method.FeePercentage = *dto.FeePercentage
+	}
+	if dto.IsEnabled != nil {
+		method.IsEnabled = *dto.IsEnabled
+	}
+	if dto.DisplayOrder != nil {
+		method.DisplayOrder = *dto.DisplayOrder
+	}
+
+	if err := validatePaymentMethod(method); err != nil {
+		return nil, err
+	}
+
+	if err := s.PaymentMethodRepository.UpdatePaymentMethod(method); err != nil {
+		return nil, errors.Internal("Failed to update payment method", err.Error())
+	}
+
+	return method, nil
+}
+
+func (s *PaymentMethodsServiceInstance) DeletePaymentMethod(id uint32) *errors.CustomError {
+	if _, appError := s.GetPaymentMethodByID(id); appError != nil {
+		return appError
+	}
+
+	if err := s.PaymentMethodRepository.DeletePaymentMethod(id); err != nil {
+		return errors.Internal("Failed to delete payment method", err.Error())
+	}
+
+	return nil
+}
+
+// Schedule a maintenance window of a payment method
+// The payment method is hidden from customers and cannot be charged while the window is ongoing
+// It returns the created maintenance window
+func (s *PaymentMethodsServiceInstance) CreateMaintenanceWindow(id uint32, dto *dtos.CreateMaintenanceWindowDTO) (*models.PaymentMethodMaintenanceWindow, *errors.CustomError) {
+	if _, appError := s.GetPaymentMethodByID(id); appError != nil {
+		return nil, appError
+	}
+
+	if !dto.EndsAt.After(dto.StartsAt) {
+		return nil, errors.BadRequest("Invalid maintenance window", "End time must be after the start time")
+	}
+
+	if !dto.EndsAt.After(time.Now()) {
+		return nil, errors.BadRequest("Invalid maintenance window", "Maintenance window already ended")
+	}
+
+	window := &models.PaymentMethodMaintenanceWindow{
+		PaymentMethodID: id,
+		StartsAt:        dto.StartsAt,
+		EndsAt:          dto.EndsAt,
+		Reason:          dto.Reason,
+	}
+
+	if err := s.PaymentMethodRepository.StoreMaintenanceWindow(window); err != nil {
+		return nil, errors.Internal("Failed to create maintenance window", err.Error())
+	}
+
+	return window, nil
+}
+
+func (s *PaymentMethodsServiceInstance) DeleteMaintenanceWindow(id uint32, windowID uint32) *errors.CustomError {
+	deleted, err := s.PaymentMethodRepository.DeleteMaintenanceWindow(id, windowID)
+	if err != nil {
+		return errors.Internal("Failed to delete maintenance window", err.Error())
+	}
+
+	if !deleted {
+		return errors.NotFound("Maintenance window not found")
+	}
+
+	return nil
+}
+
+// Check the amounts and limits of a payment method are consistent
+func validatePaymentMethod(method *models.PaymentMethod) *errors.CustomError {
+	for _, amount := range []money.Money{method.MinAmount, method.MaxAmount, method.FeeFlat} {
+		if amount.IsNegative() {
+			return errors.BadRequest("Invalid payment method", "Amounts must be greater than or equal to 0")
+		}
+
+		if amount.CurrencyCode() != money.IDR || !amount.IsWholeMajor() {
+			return errors.BadRequest("Invalid payment method", "Amounts must be in whole rupiah")
+		}
+	}
+
+	if method.MaxAmount.IsPositive() && method.MinAmount.Cmp(method.MaxAmount) > 0 {
+		return errors.BadRequest("Invalid payment method", "Minimum amount must not exceed the maximum amount")
+	}
+
+	if method.VANumberMinLength > method.VANumberMaxLength {
+		return errors.BadRequest("Invalid payment method", "Minimum VA number length must not exceed the maximum length")
+	}
+
+	if method.ExpiryDuration > 0 && method.ExpiryUnit == "" {
+		return errors.BadRequest("Invalid payment method", "Expiry unit is required when an expiry duration is set")
+	}
+
+	return nil
+}
+
+// Convert a stored payment method into the configuration the payment gateways charge with
+func toPaymentMethodConfig(method *models.PaymentMethod) midtrans.PaymentMethodConfig {
+	return midtrans.PaymentMethodConfig{
+		Name:              method.Name,
+		PaymentType:       method.PaymentType,
+		Channel:           method.Channel,
+		Provider:          method.Provider,
+		MinAmount:         method.MinAmount,
+		MaxAmount:         method.MaxAmount,
+		Description:       method.Description,
+		LogoURL:           method.LogoURL,
+		ExpiryDuration:    method.ExpiryDuration,
+		ExpiryUnit:        method.ExpiryUnit,
+		VANumberMinLength: method.VANumberMinLength,
+		VANumberMaxLength: method.VANumberMaxLength,
+		FeeFlat:           method.FeeFlat,
+		FeePercentage:     method.FeePercentage,
+	}
 }
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+)
+
+func newPaymentMethodsFixture() *fakePaymentMethodRepository {
+	now := time.Now()
+
+	return &fakePaymentMethodRepository{
+		methods: []*models.PaymentMethod{
+			{ID: 1, Name: "BCA", PaymentType: "bank_transfer", Channel: "bca", Provider: "midtrans", IsEnabled: true, FeeFlat: money.FromMajor(4000, money.IDR)},
+			{ID: 2, Name: "BNI", PaymentType: "bank_transfer", Channel: "bni", Provider: "midtrans", IsEnabled: false},
+			{ID: 3, Name: "QRIS", PaymentType: "qris", Channel: "qris", Provider: "midtrans", IsEnabled: true, MaintenanceWindows: []models.PaymentMethodMaintenanceWindow{
+				{ID: 1, PaymentMethodID: 3, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Reason: "Acquirer downtime"},
+			}},
+			{ID: 4, Name: "GoPay", PaymentType: "e_wallet_gopay", Channel: "gopay", Provider: "midtrans", IsEnabled: true, MaintenanceWindows: []models.PaymentMethodMaintenanceWindow{
+				{ID: 2, PaymentMethodID: 4, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+			}},
+			{ID: 5, Name: "OVO", PaymentType: "e_wallet_ovo", Channel: "ovo", Provider: "xendit", IsEnabled: true},
+		},
+	}
+}
+
+// Only Midtrans is configured, the Xendit method of the fixture has no gateway
+func newTestPaymentMethodsService(repository *fakePaymentMethodRepository) PaymentMethodsService {
+	return NewPaymentMethodsService(repository, gateway.NewRegistry(gateway.NewMidtransGateway(nil)))
+}
+
+func TestGetAllAvailablePaymentMethods(t *testing.T) {
+	t.Run("Should leave out disabled methods, methods under maintenance and methods without a gateway", func(t *testing.T) {
+		service := newTestPaymentMethodsService(newPaymentMethodsFixture())
+
+		methods, err := service.GetAllAvailablePaymentMethods()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(methods) != 2 {
+			t.Fatalf("Expected 2 methods, got %d", len(methods))
+		}
+
+		if methods[0].Channel != "bca" || methods[1].Channel != "gopay" {
+			t.Errorf("Expected bca and gopay, got %s and %s", methods[0].Channel, methods[1].Channel)
+		}
+
+		if !methods[0].FeeFlat.Equal(money.FromMajor(4000, money.IDR)) {
+			t.Errorf("Expected IDR 4000.00, got %s", methods[0].FeeFlat)
+		}
+	})
+
+	t.Run("Should only list the types of available methods", func(t *testing.T) {
+		service := newTestPaymentMethodsService(newPaymentMethodsFixture())
+
+		types, err := service.GetPaymentMethodTypes()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(types) != 2 || types[0] != "bank_transfer" || types[1] != "e_wallet_gopay" {
+			t.Errorf("Expected [bank_transfer e_wallet_gopay], got %v", types)
+		}
+	})
+}
+
+func TestGetAvailablePaymentMethod(t *testing.T) {
+	service := newTestPaymentMethodsService(newPaymentMethodsFixture())
+
+	t.Run("Should return an enabled method of the given type", func(t *testing.T) {
+		method, err := service.GetAvailablePaymentMethod("bank_transfer", "bca")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if method.Channel != "bca" {
+			t.Errorf("Expected bca, got %s", method.Channel)
+		}
+
+		if method.Provider != "midtrans" {
+			t.Errorf("Expected midtrans, got %s", method.Provider)
+		}
+	})
+
+	t.Run("Should reject unknown, disabled, mismatched and unconfigured methods", func(t *testing.T) {
+		cases := [][2]string{
+			{"bank_transfer", "mandiri"},
+			{"bank_transfer", "bni"},
+			{"qris", "bca"},
+			{"e_wallet_ovo", "ovo"},
+		}
+
+		for _, c := range cases {
+			if _, err := service.GetAvailablePaymentMethod(c[0], c[1]); err == nil || err.Code != 400 || err.Message != "Unsupported payment method" {
+				t.Errorf("Expected Unsupported payment method, got %v", err)
+			}
+		}
+	})
+
+	t.Run("Should reject a method under maintenance", func(t *testing.T) {
+		if _, err := service.GetAvailablePaymentMethod("qris", "qris"); err == nil || err.Message != "Payment method is under maintenance" {
+			t.Errorf("Expected Payment method is under maintenance, got %v", err)
+		}
+	})
+}
+
+func TestUpdatePaymentMethod(t *testing.T) {
+	t.Run("Should only change the given fields", func(t *testing.T) {
+		repository := newPaymentMethodsFixture()
+		service := newTestPaymentMethodsService(repository)
+
+		enabled := false
+		order := 5
+		if _, err := service.UpdatePaymentMethod(1, &dtos.UpdatePaymentMethodDTO{IsEnabled: &enabled, DisplayOrder: &order}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if repository.updated.IsEnabled {
+			t.Errorf("Expected false, got %t", repository.updated.IsEnabled)
+		}
+
+		if repository.updated.DisplayOrder != 5 {
+			t.Errorf("Expected 5, got %d", repository.updated.DisplayOrder)
+		}
+
+		if !repository.updated.FeeFlat.Equal(money.FromMajor(4000, money.IDR)) {
+			t.Errorf("Expected IDR 4000.00, got %s", repository.updated.FeeFlat)
+		}
+	})
+
+	t.Run("Should reject inconsistent limits", func(t *testing.T) {
+		service := newTestPaymentMethodsService(newPaymentMethodsFixture())
+
+		minAmount := money.FromMajor(10000, money.IDR)
+		maxAmount := money.FromMajor(5000, money.IDR)
+		if _, err := service.UpdatePaymentMethod(1, &dtos.UpdatePaymentMethodDTO{MinAmount: &minAmount, MaxAmount: &maxAmount}); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should return not found for an unknown method", func(t *testing.T) {
+		service := newTestPaymentMethodsService(newPaymentMethodsFixture())
+
+		if _, err := service.UpdatePaymentMethod(99, &dtos.UpdatePaymentMethodDTO{}); err == nil || err.Code != 404 {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
+
+func TestCreatePaymentMethod(t *testing.T) {
+	t.Run("Should reject a channel that already exists", func(t *testing.T) {
+		service := newTestPaymentMethodsService(newPaymentMethodsFixture())
+
+		if _, err := service.CreatePaymentMethod(&dtos.CreatePaymentMethodDTO{Name: "BCA", PaymentType: "bank_transfer", Channel: "bca", Provider: "midtrans"}); err == nil || err.Code != 409 {
+			t.Errorf("Expected conflict error, got %v", err)
+		}
+	})
+
+	t.Run("Should enable a new method by default", func(t *testing.T) {
+		service := newTestPaymentMethodsService(newPaymentMethodsFixture())
+
+		method, err := service.CreatePaymentMethod(&dtos.CreatePaymentMethodDTO{Name: "Mandiri", PaymentType: "bank_transfer", Channel: "mandiri", Provider: "midtrans"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !method.IsEnabled {
+			t.Errorf("Expected true, got %t", method.IsEnabled)
+		}
+
+		if method.ID != 6 {
+			t.Errorf("Expected 6, got %d", method.ID)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PaymentNotificationService interface {
+	HandleNotification(provider gateway.Provider, notification *gateway.NotificationRequest) (*models.PaymentNotification, *errors.CustomError)
+	ReplayNotification(notificationID string) (*models.PaymentNotification, *errors.CustomError)
+	GetTransactionNotifications(transactionID string) ([]*models.PaymentNotification, *errors.CustomError)
+}
+
+type PaymentNotificationServiceInstance struct {
+	PaymentNotificationRepository repositories.PaymentNotificationRepository
+	PaymentService                PaymentService
+	SubscriptionOrderService      SubscriptionOrderService
+}
+
+func NewPaymentNotificationService(
+	paymentNotificationRepository repositories.PaymentNotificationRepository,
+	paymentService PaymentService,
+	subscriptionOrderService SubscriptionOrderService,
+) PaymentNotificationService {
+	return &PaymentNotificationServiceInstance{
+		PaymentNotificationRepository: paymentNotificationRepository,
+		PaymentService:                paymentService,
+		SubscriptionOrderService:      subscriptionOrderService,
+	}
+}
+
+// Handle an incoming payment notification
+// This function stores the raw notification before anything else, then verifies it and applies it
+// to its subscription order. Notifications that were already applied are recorded as duplicates,
+// notifications that would move the payment backward are recorded as ignored
+// It returns the stored notification or an error when the provider should retry the notification
+func (s *PaymentNotificationServiceInstance) HandleNotification(provider gateway.Provider, notification *gateway.NotificationRequest) (*models.PaymentNotification, *errors.CustomError) {
+	if notification == nil || len(notification.Body) == 0 {
+		return nil, errors.BadRequest("Notification is required", nil)
+	}
+
+	record := &models.PaymentNotification{
+		ID:       uuid.New(),
+		Provider: string(provider),
+		Payload:  string(notification.Body),
+		Status:   models.PaymentNotificationStatusReceived,
+	}
+
+	if err := s.PaymentNotificationRepository.StoreNotification(record); err != nil {
+		return nil, errors.Internal("Failed to store payment notification", err.Error())
+	}
+
+	update, appError := s.PaymentService.VerifyNotification(provider, notification)
+	if appError != nil {
+		s.finish(record, models.PaymentNotificationStatusRejected, appError)
+		return record, appError
+	}
+
+	describeNotification(record, update, notification.Body)
+
+	processed, err := s.PaymentNotificationRepository.IsProcessed(*record.DedupeKey)
+	if err != nil {
+		appError := errors.Internal("Failed to check payment notification", err.Error())
+		s.finish(record, models.PaymentNotificationStatusFailed, appError)
+		return record, appError
+	}
+
+	if processed {
+		s.finish(record, models.PaymentNotificationStatusDuplicate, nil)
+		return record, nil
+	}
+
+	if appError := s.apply(record, update); appError != nil {
+		return record, appError
+	}
+
+	return record, nil
+}
+
+// Replay a stored payment notification
+// This function applies the stored payload again regardless of duplicates, the payment state machine still
+// refuses to move the payment backward. Only notifications that passed authentication can be replayed
+// It returns the notification with its new processing outcome
+func (s *PaymentNotificationServiceInstance) ReplayNotification(notificationID string) (*models.PaymentNotification, *errors.CustomError) {
+	if _, err := uuid.Parse(notificationID); err != nil {
+		return nil, errors.BadRequest("Invalid notification ID", err.Error())
+	}
+
+	record, err := s.PaymentNotificationRepository.FindByID(notificationID)
+	if err != nil {
+		return nil, errors.NotFound("Payment notification not found")
+	}
+
+	if record.DedupeKey == nil {
+		return nil, errors.BadRequest("Payment notification cannot be replayed", "Only authenticated notifications can be replayed")
+	}
+
+	update, appError := s.PaymentService.ParseNotification(gateway.Provider(record.Provider), &gateway.NotificationRequest{
+		Body: []byte(record.Payload),
+	})
+	if appError != nil {
+		return nil, appError
+	}
+
+	if appError := s.apply(record, update); appError != nil {
+		return nil, appError
+	}
+
+	return record, nil
+}
+
+// Get every notification received for a payment transaction
+// It returns the notifications ordered from the oldest
+func (s *PaymentNotificationServiceInstance) GetTransactionNotifications(transactionID string) ([]*models.PaymentNotification, *errors.CustomError) {
+	if _, err := uuid.Parse(transactionID); err != nil {
+		return nil, errors.BadRequest("Invalid transaction ID", err.Error())
+	}
+
+	notifications, err := s.PaymentNotificationRepository.FindByPaymentTransactionID(transactionID)
+	if err != nil {
+		return nil, errors.Internal("Failed to get payment notifications", err.Error())
+	}
+
+	return notifications, nil
+}
+
+// Apply a verified notification to its subscription order and record the outcome
+// A refused backward transition is not an error, retrying it would never succeed
+func (s *PaymentNotificationServiceInstance) apply(record *models.PaymentNotification, update *gateway.PaymentUpdate) *errors.CustomError {
+	record.Attempts++
+
+	appError := s.SubscriptionOrderService.UpdateSubscriptionOrder(update.OrderID, NewUpdateSubscriptionOrderDTO(update))
+
+	switch {
+	case appError == nil:
+		s.finish(record, models.PaymentNotificationStatusProcessed, nil)
+	case appError.Code == http.StatusConflict:
+		s.finish(record, models.PaymentNotificationStatusIgnored, appError)
+	default:
+		s.finish(record, models.PaymentNotificationStatusFailed, appError)
+		return appError
+	}
+
+	return nil
+}
+
+// Record the processing outcome of a notification
+// Failing to record the outcome is only logged, the outcome itself was already applied
+func (s *PaymentNotificationServiceInstance) finish(record *models.PaymentNotification, status string, appError *errors.CustomError) {
+	now := time.Now()
+
+	record.Status = status
+	record.ProcessedAt = &now
+	record.Error = nil
+
+	if appError != nil {
+		message := appError.Message
+		record.Error = &message
+	}
+
+	if err := s.PaymentNotificationRepository.Update(record); err != nil {
+		log.Printf("Failed to record payment notification %s as %s: %v", record.ID, status, err)
+	}
+}
+
+// Fill the transaction details and the dedupe key of a verified notification
+// Retries of the same notification share the transaction ID, status and signature, which form the dedupe key
+// Providers that do not sign their notifications are deduped by the payload instead
+func describeNotification(record *models.PaymentNotification, update *gateway.PaymentUpdate, body []byte) {
+	if transactionID, err := uuid.Parse(update.OrderID); err == nil {
+		record.PaymentTransactionID = &transactionID
+	}
+
+	if update.TransactionID != "" {
+		record.TransactionID = &update.TransactionID
+	}
+
+	status := string(update.Status)
+	record.TransactionStatus = &status
+
+	signature := update.SignatureKey
+	if signature == "" {
+		payloadHash := sha256.Sum256(body)
+		signature = hex.EncodeToString(payloadHash[:])
+	}
+
+	reference := update.TransactionID
+	if reference == "" {
+		reference = update.OrderID
+	}
+
+	dedupeHash := sha256.Sum256([]byte(strings.Join([]string{record.Provider, reference, status, signature}, "|")))
+	dedupeKey := hex.EncodeToString(dedupeHash[:])
+	record.DedupeKey = &dedupeKey
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleNotification(t *testing.T) {
+	transaction := &models.PaymentTransaction{
+		ID:       uuid.New(),
+		Provider: string(gateway.ProviderFake),
+		Amount:   money.FromMajor(11100, money.IDR),
+	}
+	orderID := transaction.ID.String()
+
+	notificationRepository := &fakePaymentNotificationRepository{}
+	orderService := &fakeSubscriptionOrderService{statuses: map[string]midtrans.PaymentStatus{orderID: midtrans.PaymentStatusPending}}
+
+	service := &PaymentNotificationServiceInstance{
+		PaymentNotificationRepository: notificationRepository,
+		PaymentService: &PaymentServiceInstance{
+			Gateways:              gateway.NewRegistry(gateway.NewFakeGateway("secret")),
+			TransactionRepository: &fakePaymentTransactionRepository{transactions: []*models.PaymentTransaction{transaction}},
+		},
+		SubscriptionOrderService: orderService,
+	}
+
+	settlement := map[string]any{"order_id": orderID, "status": "settled", "gross_amount": 11100}
+
+	t.Run("Should store and apply a new notification", func(t *testing.T) {
+		notification, err := service.HandleNotification(gateway.ProviderFake, newFakeNotification(t, "secret", settlement))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if notification.Status != models.PaymentNotificationStatusProcessed {
+			t.Errorf("Expected %s, got %s", models.PaymentNotificationStatusProcessed, notification.Status)
+		}
+
+		if orderService.statuses[orderID] != midtrans.PaymentStatusSettled {
+			t.Errorf("Expected settled, got %s", orderService.statuses[orderID])
+		}
+	})
+
+	t.Run("Should not apply a retried notification twice", func(t *testing.T) {
+		notification, err := service.HandleNotification(gateway.ProviderFake, newFakeNotification(t, "secret", settlement))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if notification.Status != models.PaymentNotificationStatusDuplicate {
+			t.Errorf("Expected %s, got %s", models.PaymentNotificationStatusDuplicate, notification.Status)
+		}
+
+		if orderService.applied != 1 {
+			t.Errorf("Expected 1 applied update, got %d", orderService.applied)
+		}
+	})
+
+	t.Run("Should ignore a pending notification arriving after settlement", func(t *testing.T) {
+		notification, err := service.HandleNotification(gateway.ProviderFake, newFakeNotification(t, "secret", map[string]any{
+			"order_id": orderID,
+			"status":   "pending",
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if notification.Status != models.PaymentNotificationStatusIgnored {
+			t.Errorf("Expected %s, got %s", models.PaymentNotificationStatusIgnored, notification.Status)
+		}
+
+		if orderService.statuses[orderID] != midtrans.PaymentStatusSettled {
+			t.Errorf("Expected settled, got %s", orderService.statuses[orderID])
+		}
+	})
+
+	t.Run("Should store a rejected notification", func(t *testing.T) {
+		_, err := service.HandleNotification(gateway.ProviderFake, newFakeNotification(t, "forged", settlement))
+		if err == nil || err.Code != 401 {
+			t.Fatalf("Expected unauthorized error, got %v", err)
+		}
+
+		last := notificationRepository.notifications[len(notificationRepository.notifications)-1]
+		if last.Status != models.PaymentNotificationStatusRejected || last.DedupeKey != nil {
+			t.Errorf("Expected a rejected notification without dedupe key, got %+v", last)
+		}
+	})
+
+	t.Run("Should replay a stored notification", func(t *testing.T) {
+		first := notificationRepository.notifications[0]
+
+		notification, err := service.ReplayNotification(first.ID.String())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if notification.Attempts != 2 {
+			t.Errorf("Expected 2 attempts, got %d", notification.Attempts)
+		}
+	})
+
+	t.Run("Should refuse to replay a rejected notification", func(t *testing.T) {
+		rejected := notificationRepository.notifications[len(notificationRepository.notifications)-1]
+
+		_, err := service.ReplayNotification(rejected.ID.String())
+		if err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/config"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const TaskReconcilePendingPayments = "payment:reconcile_pending"
+
+type PaymentReconciliationService interface {
+	ReconcilePendingPayments(ctx context.Context) (*dtos.PaymentReconciliationResultDTO, *errors.CustomError)
+	HandleReconcilePendingPayments(ctx context.Context, task *asynq.Task) error
+}
+
+type PaymentReconciliationServiceInstance struct {
+	Gateways                 *gateway.Registry
+	TransactionRepository    repositories.PaymentTransactionRepository
+	SubscriptionOrderService SubscriptionOrderService
+	PendingAfter             time.Duration
+	StaleAfter               time.Duration
+	BatchSize                int
+}
+
+func NewPaymentReconciliationService(
+	gateways *gateway.Registry,
+	transactionRepository repositories.PaymentTransactionRepository,
+	subscriptionOrderService SubscriptionOrderService,
+) PaymentReconciliationService {
+	return &PaymentReconciliationServiceInstance{
+		Gateways:                 gateways,
+		TransactionRepository:    transactionRepository,
+		SubscriptionOrderService: subscriptionOrderService,
+		PendingAfter:             config.GetEnvAsDuration("PAYMENT_RECONCILE_PENDING_AFTER", 15*time.Minute),
+		StaleAfter:               config.GetEnvAsDuration("PAYMENT_RECONCILE_STALE_AFTER", 48*time.Hour),
+		BatchSize:                config.GetEnvAsInt("PAYMENT_RECONCILE_BATCH_SIZE", 100),
+	}
+}
+
+// Reconcile pending payments with their payment gateway
+// This function checks the gateway status of pending transactions that are older than PendingAfter
+// or past their expiry, and applies the same transition a payment notification would.
+// Transactions that are still pending after their expiry, or older than StaleAfter, are expired
+// It returns a summary of the reconciliation
+func (s *PaymentReconciliationServiceInstance) ReconcilePendingPayments(ctx context.Context) (*dtos.PaymentReconciliationResultDTO, *errors.CustomError) {
+	now := time.Now()
+
+	transactions, err := s.TransactionRepository.FindPendingTransactions(now.Add(-s.PendingAfter), now, s.BatchSize)
+	if err != nil {
+		return nil, errors.Internal("Failed to get pending transactions", err.Error())
+	}
+
+	result := new(dtos.PaymentReconciliationResultDTO)
+
+	for _, transaction := range transactions {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result.Checked++
+		orderID := transaction.ID.String()
+		isStale := s.isStale(transaction, now)
+
+		paymentGateway, err := s.Gateways.Get(gateway.Provider(transaction.Provider))
+		if err != nil {
+			log.Printf("Failed to check transaction %s: %v", orderID, err)
+			result.Failed++
+			continue
+		}
+
+		reference := newReference(transaction)
+
+		status, err := paymentGateway.Status(reference)
+		if err != nil {
+			// The gateway never received the charge, there is nothing left to wait for
+			if gateway.IsNotFound(err) && isStale {
+				if appError := s.expire(orderID); appError != nil {
+					log.Printf("Failed to expire transaction %s: %v", orderID, appError)
+					result.Failed++
+					continue
+				}
+
+				result.Expired++
+				continue
+			}
+
+			log.Printf("Failed to check transaction %s: %v", orderID, err)
+			result.Failed++
+			continue
+		}
+
+		if status.Status == midtrans.PaymentStatusPending {
+			if !isStale {
+				result.Unchanged++
+				continue
+			}
+
+			if err := paymentGateway.Expire(reference); err != nil {
+				log.Printf("Failed to expire transaction %s on %s: %v", orderID, paymentGateway.Provider(), err)
+				result.Failed++
+				continue
+			}
+
+			if appError := s.expire(orderID); appError != nil {
+				log.Printf("Failed to expire transaction %s: %v", orderID, appError)
+				result.Failed++
+				continue
+			}
+
+			result.Expired++
+			continue
+		}
+
+		if appError := s.SubscriptionOrderService.UpdateSubscriptionOrder(orderID, NewUpdateSubscriptionOrderDTO(status)); appError != nil {
+			// A notification already moved the transaction past the checked status
+			if appError.Code == http.StatusConflict {
+				result.Unchanged++
+				continue
+			}
+
+			log.Printf("Failed to update transaction %s: %v", orderID, appError)
+			result.Failed++
+			continue
+		}
+
+		result.Updated++
+	}
+
+	return result, nil
+}
+
+// Handle the periodic reconciliation task
+// It returns an error so the task is retried when the pending transactions cannot be loaded
+func (s *PaymentReconciliationServiceInstance) HandleReconcilePendingPayments(ctx context.Context, task *asynq.Task) error {
+	result, err := s.ReconcilePendingPayments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile pending payments: %s", err.Error())
+	}
+
+	log.Printf(
+		"Reconciled pending payments: checked=%d updated=%d expired=%d unchanged=%d failed=%d",
+		result.Checked, result.Updated, result.Expired, result.Unchanged, result.Failed,
+	)
+
+	return nil
+}
+
+// Check whether a pending transaction should not be waited for anymore
+func (s *PaymentReconciliationServiceInstance) isStale(transaction *models.PaymentTransaction, now time.Time) bool {
+	if transaction.ExpiredAt != nil && transaction.ExpiredAt.Before(now) {
+		return true
+	}
+
+	return transaction.CreatedAt.Before(now.Add(-s.StaleAfter))
+}
+
+// Expire a transaction and its order locally
+func (s *PaymentReconciliationServiceInstance) expire(orderID string) *errors.CustomError {
+	expireStatus := string(midtrans.PaymentStatusExpired)
+
+	return s.SubscriptionOrderService.UpdateSubscriptionOrder(orderID, &dtos.UpdateSubscriptionOrderDTO{
+		OrderID:           orderID,
+		TransactionStatus: &expireStatus,
+	})
+}
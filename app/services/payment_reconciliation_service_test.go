@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReconcilePendingPayments(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	settled := &models.PaymentTransaction{ID: uuid.New(), CreatedAt: now.Add(-30 * time.Minute), ExpiredAt: &future}
+	stillPending := &models.PaymentTransaction{ID: uuid.New(), CreatedAt: now.Add(-30 * time.Minute), ExpiredAt: &future}
+	expiredPending := &models.PaymentTransaction{ID: uuid.New(), CreatedAt: now.Add(-25 * time.Hour), ExpiredAt: &past}
+	missingStale := &models.PaymentTransaction{ID: uuid.New(), CreatedAt: now.Add(-72 * time.Hour)}
+	missingRecent := &models.PaymentTransaction{ID: uuid.New(), CreatedAt: now.Add(-30 * time.Minute)}
+
+	fakeGateway := gateway.NewFakeGateway("")
+	for _, transaction := range []*models.PaymentTransaction{settled, stillPending, expiredPending} {
+		fakeGateway.AddPayment(&gateway.PaymentUpdate{
+			OrderID:       transaction.ID.String(),
+			TransactionID: "fake-" + transaction.ID.String(),
+			Status:        midtrans.PaymentStatusPending,
+			GrossAmount:   money.FromMajor(11100, money.IDR),
+		})
+
+		transaction.Provider = string(gateway.ProviderFake)
+	}
+	fakeGateway.SetStatus(settled.ID.String(), midtrans.PaymentStatusSettled)
+
+	missingStale.Provider = string(gateway.ProviderFake)
+	missingRecent.Provider = string(gateway.ProviderFake)
+
+	orderService := &fakeSubscriptionOrderService{updates: map[string]string{}}
+
+	service := &PaymentReconciliationServiceInstance{
+		Gateways: gateway.NewRegistry(fakeGateway),
+		TransactionRepository: &fakePaymentTransactionRepository{
+			transactions: []*models.PaymentTransaction{settled, stillPending, expiredPending, missingStale, missingRecent},
+		},
+		SubscriptionOrderService: orderService,
+		PendingAfter:             15 * time.Minute,
+		StaleAfter:               48 * time.Hour,
+		BatchSize:                100,
+	}
+
+	result, err := service.ReconcilePendingPayments(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("Should summarize the reconciliation", func(t *testing.T) {
+		expected := dtos.PaymentReconciliationResultDTO{Checked: 5, Updated: 1, Expired: 2, Unchanged: 1, Failed: 1}
+		if *result != expected {
+			t.Errorf("Expected %+v, got %+v", expected, *result)
+		}
+	})
+
+	t.Run("Should apply the gateway status of settled payments", func(t *testing.T) {
+		if status := orderService.updates[settled.ID.String()]; status != string(midtrans.PaymentStatusSettled) {
+			t.Errorf("Expected settled, got %q", status)
+		}
+	})
+
+	t.Run("Should leave recent pending payments untouched", func(t *testing.T) {
+		if _, ok := orderService.updates[stillPending.ID.String()]; ok {
+			t.Error("Expected pending payment to be left untouched")
+		}
+	})
+
+	t.Run("Should expire stale pending payments on the gateway and locally", func(t *testing.T) {
+		status, err := fakeGateway.Status(gateway.Reference{OrderID: expiredPending.ID.String()})
+		if err != nil || status.Status != midtrans.PaymentStatusExpired {
+			t.Errorf("Expected the payment to be expired on the gateway, got %v", status)
+		}
+
+		if status := orderService.updates[expiredPending.ID.String()]; status != string(midtrans.PaymentStatusExpired) {
+			t.Errorf("Expected expired, got %q", status)
+		}
+	})
+
+	t.Run("Should expire stale payments unknown to the gateway", func(t *testing.T) {
+		if status := orderService.updates[missingStale.ID.String()]; status != string(midtrans.PaymentStatusExpired) {
+			t.Errorf("Expected expired, got %q", status)
+		}
+	})
+
+	t.Run("Should keep recent payments unknown to the gateway", func(t *testing.T) {
+		if _, ok := orderService.updates[missingRecent.ID.String()]; ok {
+			t.Error("Expected recent unknown payment to be left untouched")
+		}
+	})
+}
@@ -1,94 +1,111 @@
 package services
 
 import (
+	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
 	"senkou-catalyst-be/integrations/midtrans"
 	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/repositories"
-	"time"
+	"senkou-catalyst-be/utils/money"
 
 	"github.com/google/uuid"
 )
 
 type PaymentService interface {
-	CreatePayment(user *models.User, pm *midtrans.PaymentMethodConfig, amount float64) (any, *errors.CustomError)
-	UpdatePayment(transactionID string, updateData *models.PaymentTransaction) *errors.CustomError
+	CreatePayment(user *models.User, pm *midtrans.PaymentMethodConfig, items []dtos.PaymentItemDTO, options *midtrans.ChargeOptions) (*models.PaymentTransaction, *midtrans.PaymentInstruction, *errors.CustomError)
+	VerifyNotification(provider gateway.Provider, notification *gateway.NotificationRequest) (*gateway.PaymentUpdate, *errors.CustomError)
+	ParseNotification(provider gateway.Provider, notification *gateway.NotificationRequest) (*gateway.PaymentUpdate, *errors.CustomError)
+	CancelPayment(orderID string) *errors.CustomError
+	RefundPayment(orderID string, refundKey string, amount money.Money, reason string) *errors.CustomError
 }
 
 type PaymentServiceInstance struct {
-	Client                *midtrans.MidtransClient
-	Builder               *midtrans.PaymentBuilder
+	Gateways              *gateway.Registry
 	TransactionRepository repositories.PaymentTransactionRepository
+	PaymentMethods        PaymentMethodsService
 }
 
-func NewPaymentService(client *midtrans.MidtransClient, transactionRepository repositories.PaymentTransactionRepository) PaymentService {
+func NewPaymentService(gateways *gateway.Registry, transactionRepository repositories.PaymentTransactionRepository, paymentMethods PaymentMethodsService) PaymentService {
 	return &PaymentServiceInstance{
-		Client:                client,
-		Builder:               midtrans.NewPaymentBuilder(client),
+		Gateways:              gateways,
 		TransactionRepository: transactionRepository,
+		PaymentMethods:        paymentMethods,
 	}
 }
 
-func (s *PaymentServiceInstance) CreatePayment(user *models.User, pm *midtrans.PaymentMethodConfig, amount float64) (any, *errors.CustomError) {
-	orderID := uuid.New().String()
+// Create a new payment
+// This function charges the payment through the gateway of the chosen payment method and stores the payment transaction
+// The payment method must be enabled and not under maintenance
+// The amount is the sum of the given items and must fit the limits of the chosen channel
+// The generated order ID is used as the payment transaction ID
+// It returns the stored transaction along with the instruction to complete the payment
+func (s *PaymentServiceInstance) CreatePayment(user *models.User, pm *midtrans.PaymentMethodConfig, items []dtos.PaymentItemDTO, options *midtrans.ChargeOptions) (*models.PaymentTransaction, *midtrans.PaymentInstruction, *errors.CustomError) {
+	transactionID := uuid.New()
 
-	if err := s.validateInputs(user, pm, amount); err != nil {
-		return nil, err
+	if pm == nil {
+		return nil, nil, errors.BadRequest("Payment method is required", nil)
 	}
 
-	chargeReq, err := s.Builder.BuildChargeRequest(user, *pm, amount, orderID)
-	if err != nil {
-		return nil, errors.Internal("Failed to build charge request", err.Error())
+	paymentMethod, appError := s.PaymentMethods.GetAvailablePaymentMethod(pm.PaymentType, pm.Channel)
+	if appError != nil {
+		return nil, nil, appError
 	}
 
-	transactionID, err := uuid.Parse(orderID)
-	if err != nil {
-		return nil, errors.BadRequest("Invalid order ID format", err.Error())
+	amount, appError := calculateItemsAmount(items)
+	if appError != nil {
+		return nil, nil, appError
 	}
 
-	coreAPI := s.Client.GetCoreAPIClient()
-	chargeResp, chargeRequestError := coreAPI.ChargeTransaction(chargeReq)
-	if chargeRequestError != nil {
-		return nil, errors.Internal("Failed to charge transaction", chargeRequestError.Error())
+	if err := s.validateInputs(user, paymentMethod, amount); err != nil {
+		return nil, nil, err
 	}
 
-	var transactionTimePtr *time.Time
-	if chargeResp.TransactionTime != "" {
-		parsedTime, err := time.Parse("2006-01-02 15:04:05", chargeResp.TransactionTime)
-		if err == nil {
-			transactionTimePtr = &parsedTime
-		}
+	if options == nil {
+		options = &midtrans.ChargeOptions{}
 	}
 
-	var expiredAtPtr *time.Time
-	if chargeResp.ExpiryTime != "" {
-		parsedExpiry, err := time.Parse("2006-01-02 15:04:05", chargeResp.ExpiryTime)
-		if err == nil {
-			expiredAtPtr = &parsedExpiry
-		}
+	if err := paymentMethod.ValidateVANumber(options.VANumber); err != nil {
+		return nil, nil, errors.BadRequest("Invalid VA number", err.Error())
+	}
+
+	paymentGateway, err := s.Gateways.Get(gateway.Provider(paymentMethod.Provider))
+	if err != nil {
+		return nil, nil, errors.BadRequest("Payment method is not available", err.Error())
+	}
+
+	chargeResult, err := paymentGateway.Charge(&gateway.ChargeRequest{
+		OrderID:  transactionID.String(),
+		Customer: user,
+		Method:   *paymentMethod,
+		Items:    items,
+		VANumber: options.VANumber,
+	})
+	if err != nil {
+		return nil, nil, gatewayError("Failed to charge transaction", err)
 	}
 
 	transaction := &models.PaymentTransaction{
 		ID:              transactionID,
+		Provider:        string(paymentGateway.Provider()),
 		Amount:          amount,
-		Currency:        chargeResp.Currency,
-		ExpiredAt:       expiredAtPtr,
-		FraudStatus:     chargeResp.FraudStatus,
-		PaymentChannel:  pm.Channel,
-		PaymentType:     pm.PaymentType,
-		Status:          chargeResp.TransactionStatus,
-		TransactionID:   &chargeResp.TransactionID,
-		TransactionTime: transactionTimePtr,
+		ExpiredAt:       chargeResult.Instruction.ExpiresAt,
+		FraudStatus:     chargeResult.FraudStatus,
+		PaymentChannel:  paymentMethod.Channel,
+		PaymentType:     paymentMethod.PaymentType,
+		Status:          string(chargeResult.Status),
+		TransactionID:   &chargeResult.TransactionID,
+		TransactionTime: chargeResult.TransactionTime,
 	}
 
 	if err := s.TransactionRepository.CreateTransaction(transaction); err != nil {
-		return nil, errors.Internal("Failed to create payment transaction", err.Error())
+		return nil, nil, errors.Internal("Failed to create payment transaction", err.Error())
 	}
 
-	return chargeResp, nil
+	return transaction, chargeResult.Instruction, nil
 }
 
-func (s *PaymentServiceInstance) validateInputs(user *models.User, pm *midtrans.PaymentMethodConfig, amount float64) *errors.CustomError {
+func (s *PaymentServiceInstance) validateInputs(user *models.User, pm *midtrans.PaymentMethodConfig, amount money.Money) *errors.CustomError {
 	if user == nil {
 		return errors.BadRequest("User is required", nil)
 	}
@@ -98,47 +115,184 @@ func (s *PaymentServiceInstance) validateInputs(user *models.User, pm *midtrans.
 	if pm == nil {
 		return errors.BadRequest("Payment method is required", nil)
 	}
-	if amount <= 0 {
+	if !amount.IsPositive() {
 		return errors.BadRequest("Amount must be greater than 0", nil)
 	}
+	if !amount.SameCurrency(pm.MinAmount) {
+		return errors.BadRequest("Currency is not supported by the payment method", map[string]any{
+			"currency": amount.CurrencyCode(),
+		})
+	}
+	if amount.Cmp(pm.MinAmount) < 0 || (pm.MaxAmount.IsPositive() && amount.Cmp(pm.MaxAmount) > 0) {
+		return errors.BadRequest("Amount is outside the limit of the payment method", map[string]any{
+			"amount":     amount,
+			"min_amount": pm.MinAmount,
+			"max_amount": pm.MaxAmount,
+		})
+	}
 	return nil
 }
 
-func (s *PaymentServiceInstance) UpdatePayment(transactionID string, updateData *models.PaymentTransaction) *errors.CustomError {
-	if updateData == nil {
-		return errors.BadRequest("Update data is required", nil)
+// Calculate the total amount of payment items
+// Prices are added up exactly, every item must have the same currency
+func calculateItemsAmount(items []dtos.PaymentItemDTO) (money.Money, *errors.CustomError) {
+	if len(items) == 0 {
+		return money.Zero(money.DefaultCurrency), nil
+	}
+
+	amount := money.Zero(items[0].Price.CurrencyCode())
+	for _, item := range items {
+		if !item.Price.SameCurrency(amount) {
+			return money.Money{}, errors.BadRequest("Every item must have the same currency", nil)
+		}
+
+		amount = amount.Add(item.Price.Mul(int64(item.Quantity)))
+	}
+
+	return amount, nil
+}
+
+// Verify an incoming payment notification
+// This function lets the gateway of the provider authenticate the notification, then parses it
+// and cross checks it against the stored transaction
+// It returns the normalized payment update or an error if the notification cannot be trusted
+func (s *PaymentServiceInstance) VerifyNotification(provider gateway.Provider, notification *gateway.NotificationRequest) (*gateway.PaymentUpdate, *errors.CustomError) {
+	if notification == nil || len(notification.Body) == 0 {
+		return nil, errors.BadRequest("Notification is required", nil)
+	}
+
+	paymentGateway, err := s.Gateways.Get(provider)
+	if err != nil {
+		return nil, errors.NotFound("Payment provider not found")
+	}
+
+	if err := paymentGateway.VerifyNotification(notification); err != nil {
+		return nil, errors.Unauthorized("Invalid notification signature")
+	}
+
+	return s.ParseNotification(provider, notification)
+}
+
+// Parse a payment notification that was already authenticated
+// This function cross checks the provider, transaction ID and gross amount against the stored transaction
+// It is used to replay stored notifications, whose authentication headers are not kept
+// It returns the normalized payment update or an error if the notification does not match its transaction
+func (s *PaymentServiceInstance) ParseNotification(provider gateway.Provider, notification *gateway.NotificationRequest) (*gateway.PaymentUpdate, *errors.CustomError) {
+	if notification == nil || len(notification.Body) == 0 {
+		return nil, errors.BadRequest("Notification is required", nil)
+	}
+
+	paymentGateway, err := s.Gateways.Get(provider)
+	if err != nil {
+		return nil, errors.NotFound("Payment provider not found")
+	}
+
+	update, err := paymentGateway.ParseNotification(notification)
+	if err != nil {
+		return nil, errors.BadRequest("Invalid notification", err.Error())
 	}
 
-	existingTransaction, err := s.TransactionRepository.FindByID(transactionID)
+	transaction, err := s.TransactionRepository.FindByOrderID(update.OrderID)
 	if err != nil {
-		return errors.NotFound("Transaction not found")
+		return nil, errors.NotFound("Transaction not found")
 	}
 
-	if updateData.Status != "" {
-		existingTransaction.Status = updateData.Status
+	if gateway.Provider(transaction.Provider) != paymentGateway.Provider() {
+		return nil, errors.BadRequest("Notification provider does not match the transaction", nil)
 	}
-	if updateData.FraudStatus != "" {
-		existingTransaction.FraudStatus = updateData.FraudStatus
+
+	if update.TransactionID != "" && transaction.TransactionID != nil && *transaction.TransactionID != update.TransactionID {
+		return nil, errors.BadRequest("Notification order does not match the transaction", nil)
 	}
-	if updateData.TransactionID != nil {
-		existingTransaction.TransactionID = updateData.TransactionID
+
+	// Settlements must always carry the paid amount, other updates may omit it
+	if !update.GrossAmount.IsZero() || update.Status == midtrans.PaymentStatusSettled {
+		if !update.GrossAmount.Equal(transaction.Amount) {
+			return nil, errors.BadRequest("Notification amount does not match the transaction", map[string]any{
+				"expected": transaction.Amount,
+				"received": update.GrossAmount,
+			})
+		}
 	}
-	if updateData.TransactionTime != nil {
-		existingTransaction.TransactionTime = updateData.TransactionTime
+
+	return update, nil
+}
+
+// Cancel a pending payment
+// This function asks the gateway of the payment to cancel it so it can no longer be paid
+// It returns an error if the gateway refuses or cannot be reached
+func (s *PaymentServiceInstance) CancelPayment(orderID string) *errors.CustomError {
+	transaction, paymentGateway, appError := s.resolveTransaction(orderID)
+	if appError != nil {
+		return appError
 	}
-	if updateData.SignatureKey != nil {
-		existingTransaction.SignatureKey = updateData.SignatureKey
+
+	if err := paymentGateway.Cancel(newReference(transaction)); err != nil {
+		return gatewayError("Payment could not be canceled", err)
 	}
-	if updateData.ExpiredAt != nil {
-		existingTransaction.ExpiredAt = updateData.ExpiredAt
+
+	return nil
+}
+
+// Refund a settled payment
+// This function asks the gateway of the payment to refund the given amount
+// The refund key makes the request idempotent, retrying with the same key never refunds twice
+// It returns an error if the gateway refuses or cannot be reached
+func (s *PaymentServiceInstance) RefundPayment(orderID string, refundKey string, amount money.Money, reason string) *errors.CustomError {
+	if !amount.IsPositive() {
+		return errors.BadRequest("Refund amount must be greater than 0", nil)
 	}
-	if updateData.SettledAt != nil {
-		existingTransaction.SettledAt = updateData.SettledAt
+
+	transaction, paymentGateway, appError := s.resolveTransaction(orderID)
+	if appError != nil {
+		return appError
 	}
 
-	if err := s.TransactionRepository.Update(existingTransaction); err != nil {
-		return errors.Internal("Failed to update transaction", err.Error())
+	if err := paymentGateway.Refund(newReference(transaction), &gateway.RefundRequest{
+		RefundKey: refundKey,
+		Amount:    amount,
+		Reason:    reason,
+	}); err != nil {
+		return gatewayError("Payment could not be refunded", err)
 	}
 
 	return nil
 }
+
+// Find a payment transaction along with the gateway it was charged through
+func (s *PaymentServiceInstance) resolveTransaction(orderID string) (*models.PaymentTransaction, gateway.PaymentGateway, *errors.CustomError) {
+	transaction, err := s.TransactionRepository.FindByOrderID(orderID)
+	if err != nil {
+		return nil, nil, errors.NotFound("Transaction not found")
+	}
+
+	paymentGateway, err := s.Gateways.Get(gateway.Provider(transaction.Provider))
+	if err != nil {
+		return nil, nil, errors.Internal("Payment provider is not available", err.Error())
+	}
+
+	return transaction, paymentGateway, nil
+}
+
+// Build the gateway reference of a payment transaction
+func newReference(transaction *models.PaymentTransaction) gateway.Reference {
+	reference := gateway.Reference{
+		OrderID: transaction.ID.String(),
+	}
+
+	if transaction.TransactionID != nil {
+		reference.TransactionID = *transaction.TransactionID
+	}
+
+	return reference
+}
+
+// Convert a gateway error into an application error
+// Requests rejected by the provider are reported as bad requests, anything else as an internal error
+func gatewayError(message string, err error) *errors.CustomError {
+	if gateway.IsRejected(err) {
+		return errors.BadRequest(message, err.Error())
+	}
+
+	return errors.Internal(message, err.Error())
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"encoding/json"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newFakeNotification(t *testing.T, token string, body map[string]any) *gateway.NotificationRequest {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to encode notification: %v", err)
+	}
+
+	return &gateway.NotificationRequest{
+		Body:    encoded,
+		Headers: map[string]string{"X-Fake-Notification-Token": token},
+	}
+}
+
+func TestVerifyNotification(t *testing.T) {
+	transactionID := "fake-transaction"
+	transaction := &models.PaymentTransaction{
+		ID:            uuid.New(),
+		Provider:      string(gateway.ProviderFake),
+		Amount:        money.FromMajor(11100, money.IDR),
+		TransactionID: &transactionID,
+	}
+
+	service := &PaymentServiceInstance{
+		Gateways:              gateway.NewRegistry(gateway.NewFakeGateway("secret")),
+		TransactionRepository: &fakePaymentTransactionRepository{transactions: []*models.PaymentTransaction{transaction}},
+	}
+
+	t.Run("Should accept a trusted notification", func(t *testing.T) {
+		update, err := service.VerifyNotification(gateway.ProviderFake, newFakeNotification(t, "secret", map[string]any{
+			"order_id":       transaction.ID.String(),
+			"transaction_id": transactionID,
+			"status":         "settled",
+			"gross_amount":   11100,
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if update.Status != midtrans.PaymentStatusSettled {
+			t.Errorf("Expected settled, got %s", update.Status)
+		}
+	})
+
+	t.Run("Should reject a notification with an invalid token", func(t *testing.T) {
+		_, err := service.VerifyNotification(gateway.ProviderFake, newFakeNotification(t, "forged", map[string]any{
+			"order_id": transaction.ID.String(),
+			"status":   "settled",
+		}))
+		if err == nil || err.Code != 401 {
+			t.Errorf("Expected unauthorized error, got %v", err)
+		}
+	})
+
+	t.Run("Should reject a settlement with a different amount", func(t *testing.T) {
+		_, err := service.VerifyNotification(gateway.ProviderFake, newFakeNotification(t, "secret", map[string]any{
+			"order_id":     transaction.ID.String(),
+			"status":       "settled",
+			"gross_amount": 100,
+		}))
+		if err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should reject a notification sent to another provider", func(t *testing.T) {
+		_, err := service.VerifyNotification(gateway.ProviderXendit, newFakeNotification(t, "secret", map[string]any{
+			"order_id": transaction.ID.String(),
+			"status":   "settled",
+		}))
+		if err == nil || err.Code != 404 {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("Should reject a notification of an unknown transaction", func(t *testing.T) {
+		_, err := service.VerifyNotification(gateway.ProviderFake, newFakeNotification(t, "secret", map[string]any{
+			"order_id": uuid.New().String(),
+			"status":   "settled",
+		}))
+		if err == nil || err.Code != 404 {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
+
+func TestRefundPayment(t *testing.T) {
+	fakeGateway := gateway.NewFakeGateway("")
+	transaction := &models.PaymentTransaction{
+		ID:       uuid.New(),
+		Provider: string(gateway.ProviderFake),
+		Amount:   money.FromMajor(11100, money.IDR),
+	}
+
+	fakeGateway.AddPayment(&gateway.PaymentUpdate{
+		OrderID:     transaction.ID.String(),
+		Status:      midtrans.PaymentStatusSettled,
+		GrossAmount: transaction.Amount,
+	})
+
+	service := &PaymentServiceInstance{
+		Gateways:              gateway.NewRegistry(fakeGateway),
+		TransactionRepository: &fakePaymentTransactionRepository{transactions: []*models.PaymentTransaction{transaction}},
+	}
+
+	t.Run("Should refund through the gateway of the transaction", func(t *testing.T) {
+		if err := service.RefundPayment(transaction.ID.String(), "refund-1", money.FromMajor(5000, money.IDR), "Requested by customer"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if refunds := fakeGateway.Refunds(transaction.ID.String()); len(refunds) != 1 || !refunds[0].Amount.Equal(money.FromMajor(5000, money.IDR)) {
+			t.Errorf("Expected one refund of 5000, got %+v", refunds)
+		}
+	})
+
+	t.Run("Should report refunds rejected by the gateway as bad requests", func(t *testing.T) {
+		err := service.RefundPayment(transaction.ID.String(), "refund-2", money.FromMajor(10000, money.IDR), "Requested by customer")
+		if err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
+
+func newRefundOrderService() (*SubscriptionOrderServiceInstance, *gateway.FakeGateway, *models.PaymentTransaction, *fakePaymentRefundRepository) {
+	fakeGateway := gateway.NewFakeGateway("")
+	transaction := &models.PaymentTransaction{
+		ID:       uuid.New(),
+		Provider: string(gateway.ProviderFake),
+		Amount:   money.FromMajor(11100, money.IDR),
+		Status:   string(midtrans.PaymentStatusSettled),
+	}
+
+	fakeGateway.AddPayment(&gateway.PaymentUpdate{
+		OrderID:     transaction.ID.String(),
+		Status:      midtrans.PaymentStatusSettled,
+		GrossAmount: transaction.Amount,
+	})
+
+	transactionRepository := &fakePaymentTransactionRepository{transactions: []*models.PaymentTransaction{transaction}}
+	refundRepository := &fakePaymentRefundRepository{}
+
+	service := &SubscriptionOrderServiceInstance{
+		SubscriptionOrderRepository:  &fakeSubscriptionOrderRepository{},
+		PaymentTransactionRepository: transactionRepository,
+		PaymentRefundRepository:      refundRepository,
+		SubscriptionRepository:       newFakeSubscriptionRepository(),
+		TransactionManager:           &fakeTransactionManager{},
+		PaymentService: &PaymentServiceInstance{
+			Gateways:              gateway.NewRegistry(fakeGateway),
+			TransactionRepository: transactionRepository,
+		},
+	}
+
+	return service, fakeGateway, transaction, refundRepository
+}
+
+func TestRefundPaymentTransaction(t *testing.T) {
+	t.Run("Should settle the refunds accepted by the gateway until the transaction is refunded", func(t *testing.T) {
+		service, fakeGateway, transaction, refundRepository := newRefundOrderService()
+
+		amount := money.FromMajor(5000, money.IDR)
+		refund, err := service.RefundPaymentTransaction(transaction.ID.String(), &dtos.RefundPaymentDTO{Amount: &amount, Reason: "Requested by customer"}, 1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if refund.Status != string(midtrans.PaymentStatusPartiallyRefunded) {
+			t.Errorf("Expected partially_refunded, got %s", refund.Status)
+		}
+
+		if transaction.Status != string(midtrans.PaymentStatusPartiallyRefunded) {
+			t.Errorf("Expected partially_refunded, got %s", transaction.Status)
+		}
+
+		refund, err = service.RefundPaymentTransaction(transaction.ID.String(), &dtos.RefundPaymentDTO{Reason: "Requested by customer"}, 1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !refund.Amount.Equal(money.FromMajor(6100, money.IDR)) {
+			t.Errorf("Expected 6100, got %s", refund.Amount.Format())
+		}
+
+		if refund.Status != string(midtrans.PaymentStatusRefunded) {
+			t.Errorf("Expected refunded, got %s", refund.Status)
+		}
+
+		if transaction.Status != string(midtrans.PaymentStatusRefunded) {
+			t.Errorf("Expected refunded, got %s", transaction.Status)
+		}
+
+		if len(refundRepository.refunds) != 2 {
+			t.Errorf("Expected 2 stored refunds, got %d", len(refundRepository.refunds))
+		}
+
+		if refunds := fakeGateway.Refunds(transaction.ID.String()); len(refunds) != 2 {
+			t.Errorf("Expected 2 gateway refunds, got %d", len(refunds))
+		}
+	})
+
+	t.Run("Should shorten an upgraded subscription by the share of the remaining period the upgrade charged", func(t *testing.T) {
+		service, _, transaction, _ := newRefundOrderService()
+
+		now := time.Now()
+		order := &models.SubscriptionOrder{
+			ID: uuid.New(), UserID: 10, SubscriptionID: 3, PaymentTransactionID: &transaction.ID,
+			Type: models.OrderTypeUpgrade, Amount: transaction.Amount, Status: string(midtrans.PaymentStatusSettled),
+		}
+		upgraded := &models.UserSubscription{ID: 1, UserID: 10, SubID: 3, StartedAt: now, ExpiredAt: now.AddDate(0, 0, 10), IsActive: true}
+
+		subscriptionRepository := newFakeSubscriptionRepository(upgraded)
+		subscriptionRepository.histories = append(subscriptionRepository.histories, &models.SubscriptionHistory{
+			UserID: 10, UserSubscriptionID: 1, SubscriptionID: 3, OrderID: &order.ID,
+			Event: models.SubscriptionEventUpgraded, StartedAt: upgraded.StartedAt, ExpiredAt: upgraded.ExpiredAt,
+		})
+		service.SubscriptionRepository = subscriptionRepository
+		service.SubscriptionOrderRepository = &fakeSubscriptionOrderRepository{orders: []*models.SubscriptionOrder{order}}
+
+		amount := money.FromMajor(5550, money.IDR)
+		if _, err := service.RefundPaymentTransaction(transaction.ID.String(), &dtos.RefundPaymentDTO{Amount: &amount, Reason: "Requested by customer"}, 1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !upgraded.IsActive {
+			t.Errorf("Expected true, got %t", upgraded.IsActive)
+		}
+
+		if expected := now.AddDate(0, 0, 5); !upgraded.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, upgraded.ExpiredAt)
+		}
+	})
+
+	t.Run("Should remove the granted days of an order placed with a promo code on a full refund", func(t *testing.T) {
+		service, _, transaction, _ := newRefundOrderService()
+
+		now := time.Now()
+		order := &models.SubscriptionOrder{
+			ID: uuid.New(), UserID: 10, SubscriptionID: 2, PaymentTransactionID: &transaction.ID,
+			Type: models.OrderTypeNew, Amount: transaction.Amount, GrantedDays: 7, Status: string(midtrans.PaymentStatusSettled),
+		}
+		userSubscription := &models.UserSubscription{ID: 1, UserID: 10, SubID: 2, StartedAt: now, ExpiredAt: now.AddDate(0, 0, 30), IsActive: true}
+
+		service.SubscriptionRepository = newFakeSubscriptionRepository(userSubscription)
+		service.SubscriptionOrderRepository = &fakeSubscriptionOrderRepository{orders: []*models.SubscriptionOrder{order}}
+
+		if _, err := service.RefundPaymentTransaction(transaction.ID.String(), &dtos.RefundPaymentDTO{Reason: "Requested by customer"}, 1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if expected := now.AddDate(0, 0, 23); !userSubscription.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, userSubscription.ExpiredAt)
+		}
+
+		if order.Status != string(midtrans.PaymentStatusRefunded) {
+			t.Errorf("Expected refunded, got %s", order.Status)
+		}
+	})
+
+	t.Run("Should keep a refund the gateway refuses as failed", func(t *testing.T) {
+		service, fakeGateway, transaction, refundRepository := newRefundOrderService()
+		fakeGateway.SetStatus(transaction.ID.String(), midtrans.PaymentStatusExpired)
+
+		amount := money.FromMajor(5000, money.IDR)
+		if _, err := service.RefundPaymentTransaction(transaction.ID.String(), &dtos.RefundPaymentDTO{Amount: &amount, Reason: "Requested by customer"}, 1); err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		if len(refundRepository.refunds) != 1 {
+			t.Fatalf("Expected 1 stored refund, got %d", len(refundRepository.refunds))
+		}
+
+		if refundRepository.refunds[0].Status != string(midtrans.PaymentStatusFailed) {
+			t.Errorf("Expected failed, got %s", refundRepository.refunds[0].Status)
+		}
+
+		if transaction.Status != string(midtrans.PaymentStatusSettled) {
+			t.Errorf("Expected settled, got %s", transaction.Status)
+		}
+	})
+
+	t.Run("Should not refund the amount reserved by a pending refund", func(t *testing.T) {
+		service, fakeGateway, transaction, refundRepository := newRefundOrderService()
+		refundRepository.refunds = append(refundRepository.refunds, &models.PaymentRefund{
+			PaymentTransactionID: transaction.ID,
+			Amount:               money.FromMajor(10000, money.IDR),
+			Status:               string(midtrans.PaymentStatusPending),
+		})
+
+		amount := money.FromMajor(5000, money.IDR)
+		_, err := service.RefundPaymentTransaction(transaction.ID.String(), &dtos.RefundPaymentDTO{Amount: &amount, Reason: "Requested by customer"}, 1)
+		if err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+
+		if refunds := fakeGateway.Refunds(transaction.ID.String()); len(refunds) != 0 {
+			t.Errorf("Expected 0 gateway refunds, got %d", len(refunds))
+		}
+	})
+}
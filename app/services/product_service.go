@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
 	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/repositories"
 	"senkou-catalyst-be/utils/query"
@@ -15,6 +16,7 @@ import (
 type ProductService interface {
 	CreateProduct(product *dtos.CreateProductDTO, merchantID string) (*models.Product, *errors.CustomError)
 	GetProductByID(productID string) (*models.Product, *errors.CustomError)
+	GetPublishedProductByID(productID string) (*models.Product, *errors.CustomError)
 	GetProductsByMerchantID(merchantID string) ([]*models.Product, *errors.CustomError)
 	GetAllProducts(params *query.QueryParams) ([]*models.Product, *query.PaginationResponse, *errors.CustomError)
 	GetProductsByMerchantUsername(username string) ([]*models.Product, *errors.CustomError)
@@ -22,6 +24,7 @@ type ProductService interface {
 	GetRecentProducts(username string) ([]*models.Product, *errors.CustomError)
 	UpdateProduct(updatedProduct *dtos.UpdateProductDTO, productID string) (*models.Product, *errors.CustomError)
 	UpdateProductPhotos(product *models.Product) *errors.CustomError
+	AddProductPhoto(product *models.Product, photoPath string) *errors.CustomError
 	DeleteProduct(productID string) *errors.CustomError
 	VerifyProductOwnership(productID string, userID uint32) *errors.CustomError
 }
@@ -30,35 +33,70 @@ type ProductServiceInstance struct {
 	UserRepository          repositories.UserRepository
 	ProductRepository       repositories.ProductRepository
 	ProductMetricRepository repositories.ProductInteractionRepository
+	MerchantRepository      repositories.MerchantRepository
+	TransactionManager      repositories.TransactionManager
+	EntitlementService      EntitlementService
 }
 
-func NewProductService(productRepository repositories.ProductRepository, userRepository repositories.UserRepository, productMetricRepository repositories.ProductInteractionRepository) ProductService {
+func NewProductService(productRepository repositories.ProductRepository, userRepository repositories.UserRepository, productMetricRepository repositories.ProductInteractionRepository, merchantRepository repositories.MerchantRepository, transactionManager repositories.TransactionManager, entitlementService EntitlementService) ProductService {
 	return &ProductServiceInstance{
 		ProductRepository:       productRepository,
 		UserRepository:          userRepository,
 		ProductMetricRepository: productMetricRepository,
+		MerchantRepository:      merchantRepository,
+		TransactionManager:      transactionManager,
+		EntitlementService:      entitlementService,
 	}
 }
 
 // Create a new product
 // This function will be used to create a new product via repository
+// The product slot quota of the merchant is enforced within the same transaction as the insert
 // It returns the created product and an error if any
 func (s *ProductServiceInstance) CreateProduct(product *dtos.CreateProductDTO, merchantID string) (*models.Product, *errors.CustomError) {
+	if product.Price == nil || product.Price.IsNegative() {
+		return nil, errors.BadRequest("Price must be greater than or equal to 0", nil)
+	}
+
+	if appError := s.EntitlementService.EnforceCount(merchantID, constants.SubscriptionPhotosPerProduct, int64(len(product.Photos))); appError != nil {
+		return nil, appError
+	}
+
 	newProduct := &models.Product{
 		ID:           uuid.New().String(),
 		Title:        product.Title,
 		Description:  product.Description,
-		Price:        product.Price,
+		Price:        *product.Price,
 		Photos:       product.Photos,
 		AffiliateURL: product.AffiliateURL,
 		CategoryID:   product.CategoryID,
 		MerchantID:   merchantID,
 	}
 
-	storedProduct, err := s.ProductRepository.StoreProduct(newProduct)
+	var storedProduct *models.Product
+	var appError *errors.CustomError
 
-	if err != nil {
-		return nil, errors.Internal("Failed to create product", err.Error())
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		if appError = s.EntitlementService.EnforceQuota(tx, merchantID, constants.SubscriptionProductSlot); appError != nil {
+			return appError
+		}
+
+		stored, err := s.ProductRepository.WithTx(tx).StoreProduct(newProduct)
+		if err != nil {
+			appError = errors.Internal("Failed to create product", err.Error())
+			return err
+		}
+
+		storedProduct = stored
+		return nil
+	})
+
+	if appError != nil {
+		return nil, appError
+	}
+
+	if txErr != nil {
+		return nil, errors.Internal("Failed to create product", txErr.Error())
 	}
 
 	return storedProduct, nil
@@ -81,11 +119,46 @@ func (s *ProductServiceInstance) GetProductByID(productID string) (*models.Produ
 	return product, nil
 }
 
+// Get a published product by its ID
+// This function retrieves a product for the storefront, products hidden beyond the product slot quota shared by the merchants of their owner are not found
+// It returns the product and an error if any
+func (s *ProductServiceInstance) GetPublishedProductByID(productID string) (*models.Product, *errors.CustomError) {
+	merchant, err := s.ProductRepository.FindMerchantByProductID(productID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NotFound("Product not found")
+		}
+
+		return nil, errors.Internal("Failed to retrieve product", err.Error())
+	}
+
+	publishLimit, appError := s.getPublishLimit(merchant.ID)
+	if appError != nil {
+		return nil, appError
+	}
+
+	product, err := s.ProductRepository.FindPublishedProductByID(productID, publishLimit)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NotFound("Product not found")
+		}
+
+		return nil, errors.Internal("Failed to retrieve product", err.Error())
+	}
+
+	return product, nil
+}
+
 // Get products by merchant username
-// This function retrieves all products associated with a specific merchant username
+// This function retrieves the published products associated with a specific merchant username
 // It returns a slice of products and an error if any
 func (s *ProductServiceInstance) GetProductsByMerchantUsername(username string) ([]*models.Product, *errors.CustomError) {
-	products, err := s.ProductRepository.FindProductsByMerchantUsername(username)
+	publishLimit, appError := s.getPublishLimitByUsername(username)
+	if appError != nil {
+		return nil, appError
+	}
+
+	products, err := s.ProductRepository.FindProductsByMerchantUsername(username, publishLimit)
 	if err != nil {
 		return nil, errors.NotFound(fmt.Sprintf("Products not found for merchant username %s", username))
 	}
@@ -129,8 +202,12 @@ func (s *ProductServiceInstance) GetAllProducts(params *query.QueryParams) ([]*m
 // This function retrieves popular products based on interaction metrics
 // It returns a slice of popular products and an error if any
 func (s *ProductServiceInstance) GetPopularProducts(username string) ([]*models.Product, *errors.CustomError) {
+	publishLimit, appError := s.getPublishLimitByUsername(username)
+	if appError != nil {
+		return nil, appError
+	}
 
-	products, err := s.ProductMetricRepository.GetPopularProductsByMerchant(username)
+	products, err := s.ProductMetricRepository.GetPopularProductsByMerchant(username, publishLimit)
 
 	if err != nil {
 		return nil, errors.Internal("Failed to retrieve popular products", err.Error())
@@ -143,8 +220,12 @@ func (s *ProductServiceInstance) GetPopularProducts(username string) ([]*models.
 // This function retrieves the most recently added products
 // It returns a slice of recent products and an error if any
 func (s *ProductServiceInstance) GetRecentProducts(username string) ([]*models.Product, *errors.CustomError) {
+	publishLimit, appError := s.getPublishLimitByUsername(username)
+	if appError != nil {
+		return nil, appError
+	}
 
-	products, err := s.ProductRepository.FindRecentProducts(username)
+	products, err := s.ProductRepository.FindRecentProducts(username, publishLimit)
 
 	if err != nil {
 		return nil, errors.Internal("Failed to retrieve recent products", err.Error())
@@ -177,6 +258,10 @@ func (s *ProductServiceInstance) UpdateProduct(updatedProduct *dtos.UpdateProduc
 	}
 
 	if updatedProduct.Price != nil {
+		if updatedProduct.Price.IsNegative() {
+			return nil, errors.BadRequest("Price must be greater than or equal to 0", nil)
+		}
+
 		product.Price = *updatedProduct.Price
 	}
 
@@ -205,6 +290,48 @@ func (s *ProductServiceInstance) UpdateProductPhotos(product *models.Product) *e
 	return nil
 }
 
+// Add a photo to a product
+// This function checks the photos per product quota of the merchant before storing the photo
+// It returns a forbidden error when the product already has every photo the quota allows, or an error if any
+func (s *ProductServiceInstance) AddProductPhoto(product *models.Product, photoPath string) *errors.CustomError {
+	if appError := s.EntitlementService.EnforceCount(product.MerchantID, constants.SubscriptionPhotosPerProduct, int64(len(product.Photos)+1)); appError != nil {
+		return appError
+	}
+
+	product.Photos.AddPhoto(photoPath)
+
+	return s.UpdateProductPhotos(product)
+}
+
+// Get the number of products a merchant can publish on the storefront
+// Products beyond the product slot quota of the owner are hidden, a negative limit publishes every product
+func (s *ProductServiceInstance) getPublishLimit(merchantID string) (int64, *errors.CustomError) {
+	ownerEntitlements, appError := s.EntitlementService.ResolveMerchantEntitlements(merchantID)
+	if appError != nil {
+		return 0, appError
+	}
+
+	if ownerEntitlements.Unrestricted {
+		return -1, nil
+	}
+
+	return ownerEntitlements.Get(constants.SubscriptionProductSlot).Limit, nil
+}
+
+// Get the number of products the merchant with the given username can publish on the storefront
+func (s *ProductServiceInstance) getPublishLimitByUsername(username string) (int64, *errors.CustomError) {
+	merchant, err := s.MerchantRepository.FindByUsername(username)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, nil
+		}
+
+		return 0, errors.Internal("Failed to retrieve merchant", err.Error())
+	}
+
+	return s.getPublishLimit(merchant.ID)
+}
+
 // Delete a product
 // This function deletes a product from the repository by its ID
 // It returns an error if any
@@ -0,0 +1,386 @@
+package services
+
+import (
+	stderr "errors"
+	"regexp"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/money"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromoCodeService interface {
+	GetPromoCodes() ([]*models.PromoCode, *errors.CustomError)
+	GetPromoCodeByID(id uint32) (*models.PromoCode, *errors.CustomError)
+	CreatePromoCode(dto *dtos.CreatePromoCodeDTO) (*models.PromoCode, *errors.CustomError)
+	UpdatePromoCode(id uint32, dto *dtos.UpdatePromoCodeDTO) (*models.PromoCode, *errors.CustomError)
+	DeletePromoCode(id uint32) *errors.CustomError
+	GetRedeemablePromoCode(userID uint32, code string, subscription *models.Subscription) (*models.PromoCode, *errors.CustomError)
+	QuotePromoCode(userID uint32, dto *dtos.ValidatePromoCodeDTO) (*dtos.PromoCodeQuoteDTO, *errors.CustomError)
+}
+
+type PromoCodeServiceInstance struct {
+	PromoCodeRepository    repositories.PromoCodeRepository
+	SubscriptionRepository repositories.SubscriptionRepository
+}
+
+func NewPromoCodeService(
+	promoCodeRepository repositories.PromoCodeRepository,
+	subscriptionRepository repositories.SubscriptionRepository,
+) PromoCodeService {
+	return &PromoCodeServiceInstance{
+		PromoCodeRepository:    promoCodeRepository,
+		SubscriptionRepository: subscriptionRepository,
+	}
+}
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,50}$`)
+
+// Normalize a promo code the way it is stored, codes are case insensitive
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Get every promo code including the inactive ones, for admins
+func (s *PromoCodeServiceInstance) GetPromoCodes() ([]*models.PromoCode, *errors.CustomError) {
+	promoCodes, err := s.PromoCodeRepository.FindAll()
+	if err != nil {
+		return nil, errors.Internal("Failed to get promo codes", err.Error())
+	}
+
+	return promoCodes, nil
+}
+
+func (s *PromoCodeServiceInstance) GetPromoCodeByID(id uint32) (*models.PromoCode, *errors.CustomError) {
+	promoCode, err := s.PromoCodeRepository.FindByID(id)
+	if err != nil {
+		if stderr.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFound("Promo code not found")
+		}
+
+		return nil, errors.Internal("Failed to get promo code", err.Error())
+	}
+
+	return promoCode, nil
+}
+
+// Create a new promo code
+// The code is stored in upper case and must be unique. Subscriptions are stored by family,
+// so the code keeps applying when a subscription is replaced by a newer version
+// It returns the created promo code
+func (s *PromoCodeServiceInstance) CreatePromoCode(dto *dtos.CreatePromoCodeDTO) (*models.PromoCode, *errors.CustomError) {
+	code := normalizePromoCode(dto.Code)
+	if !promoCodePattern.MatchString(code) {
+		return nil, errors.BadRequest("Invalid promo code", "Code must only contain letters, digits, dashes and underscores")
+	}
+
+	if _, err := s.PromoCodeRepository.FindByCode(code); err == nil {
+		return nil, errors.Conflict("Promo code already exists", map[string]any{
+			"code": code,
+		})
+	} else if !stderr.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Internal("Failed to get promo code", err.Error())
+	}
+
+	familyIDs, appError := s.subscriptionFamilyIDs(dto.SubscriptionIDs)
+	if appError != nil {
+		return nil, appError
+	}
+
+	promoCode := &models.PromoCode{
+		Code:                  code,
+		Description:           dto.Description,
+		Type:                  dto.Type,
+		PercentOff:            dto.PercentOff,
+		GrantedDays:           dto.GrantedDays,
+		SubscriptionFamilyIDs: familyIDs,
+		MaxRedemptions:        dto.MaxRedemptions,
+		MaxRedemptionsPerUser: 1,
+		StartsAt:              dto.StartsAt,
+		EndsAt:                dto.EndsAt,
+		IsActive:              dto.IsActive == nil || *dto.IsActive,
+	}
+
+	if dto.AmountOff != nil {
+		promoCode.AmountOff = *dto.AmountOff
+	}
+	if dto.MaxRedemptionsPerUser != nil {
+		promoCode.MaxRedemptionsPerUser = *dto.MaxRedemptionsPerUser
+	}
+
+	if err := validatePromoCode(promoCode); err != nil {
+		return nil, err
+	}
+
+	if err := s.PromoCodeRepository.StorePromoCode(promoCode); err != nil {
+		return nil, errors.Internal("Failed to create promo code", err.Error())
+	}
+
+	return promoCode, nil
+}
+
+// Update a promo code
+// Only the given fields are changed, the code and its type cannot be changed since orders refer to them
+// It returns the updated promo code
+func (s *PromoCodeServiceInstance) UpdatePromoCode(id uint32, dto *dtos.UpdatePromoCodeDTO) (*models.PromoCode, *errors.CustomError) {
+	promoCode, appError := s.GetPromoCodeByID(id)
+	if appError != nil {
+		return nil, appError
+	}
+
+	if dto.Description != nil {
+		promoCode.Description = *dto.Description
+	}
+	if dto.PercentOff != nil {
+		promoCode.PercentOff = *dto.PercentOff
+	}
+	if dto.AmountOff != nil {
+		promoCode.AmountOff = *dto.AmountOff
+	}
+	if dto.GrantedDays != nil {
+		promoCode.GrantedDays = *dto.GrantedDays
+	}
+	if dto.SubscriptionIDs != nil {
+		familyIDs, appError := s.subscriptionFamilyIDs(*dto.SubscriptionIDs)
+		if appError != nil {
+			return nil, appError
+		}
+
+		promoCode.SubscriptionFamilyIDs = familyIDs
+	}
+	if dto.MaxRedemptions != nil {
+		promoCode.MaxRedemptions = dto.MaxRedemptions
+		if *dto.MaxRedemptions == 0 {
+			promoCode.MaxRedemptions = nil
+		}
+	}
+	if dto.MaxRedemptionsPerUser != nil {
+		promoCode.MaxRedemptionsPerUser = *dto.MaxRedemptionsPerUser
+	}
+	if dto.StartsAt != nil {
+		promoCode.StartsAt = dto.StartsAt
+	}
+	if dto.EndsAt != nil {
+		promoCode.EndsAt = dto.EndsAt
+	}
+	if dto.IsActive != nil {
+		promoCode.IsActive = *dto.IsActive
+	}
+
+	if err := validatePromoCode(promoCode); err != nil {
+		return nil, err
+	}
+
+	if err := s.PromoCodeRepository.UpdatePromoCode(promoCode); err != nil {
+		return nil, errors.Internal("Failed to update promo code", err.Error())
+	}
+
+	return promoCode, nil
+}
+
+// Delete a promo code
+// The orders that redeemed the code keep their discount, the code can no longer be redeemed
+func (s *PromoCodeServiceInstance) DeletePromoCode(id uint32) *errors.CustomError {
+	if _, appError := s.GetPromoCodeByID(id); appError != nil {
+		return appError
+	}
+
+	if err := s.PromoCodeRepository.DeletePromoCode(id); err != nil {
+		return errors.Internal("Failed to delete promo code", err.Error())
+	}
+
+	return nil
+}
+
+// Get a promo code a user wants to redeem on a subscription
+// The limits are checked again when the order is stored, since another order may redeem the code in between
+// It returns the promo code or an error explaining why it cannot be redeemed
+func (s *PromoCodeServiceInstance) GetRedeemablePromoCode(userID uint32, code string, subscription *models.Subscription) (*models.PromoCode, *errors.CustomError) {
+	promoCode, err := s.PromoCodeRepository.FindByCode(normalizePromoCode(code))
+	if err != nil {
+		if stderr.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.BadRequest("Promo code is not valid", "The promo code does not exist")
+		}
+
+		return nil, errors.Internal("Failed to get promo code", err.Error())
+	}
+
+	if appError := verifyPromoCodeRedeemable(s.PromoCodeRepository, promoCode, userID, subscription, time.Now()); appError != nil {
+		return nil, appError
+	}
+
+	return promoCode, nil
+}
+
+// Quote a promo code on a subscription before it is purchased
+// It returns the price of the subscription with the discount of the code, or an error if the code cannot be redeemed
+func (s *PromoCodeServiceInstance) QuotePromoCode(userID uint32, dto *dtos.ValidatePromoCodeDTO) (*dtos.PromoCodeQuoteDTO, *errors.CustomError) {
+	subscription, err := s.SubscriptionRepository.FindByID(dto.SubscriptionID)
+	if err != nil {
+		if stderr.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFound("Subscription not found")
+		}
+
+		return nil, errors.Internal("Failed to get subscription", err.Error())
+	}
+
+	promoCode, appError := s.GetRedeemablePromoCode(userID, dto.Code, subscription)
+	if appError != nil {
+		return nil, appError
+	}
+
+	price := subscription.Price.RoundToMajor()
+	discount := promoCode.Discount(price)
+
+	return &dtos.PromoCodeQuoteDTO{
+		Code:            promoCode.Code,
+		Type:            promoCode.Type,
+		SubscriptionID:  subscription.ID,
+		Price:           price,
+		Discount:        discount,
+		DiscountedPrice: price.Sub(discount),
+		GrantedDays:     promoCode.GrantedDays,
+	}, nil
+}
+
+// Convert subscription IDs to the IDs of their families
+// It returns an error if a subscription does not exist
+func (s *PromoCodeServiceInstance) subscriptionFamilyIDs(subscriptionIDs []uint32) (models.IDArray, *errors.CustomError) {
+	familyIDs := make(models.IDArray, 0, len(subscriptionIDs))
+	seen := make(map[uint32]bool)
+
+	for _, subscriptionID := range subscriptionIDs {
+		subscription, err := s.SubscriptionRepository.FindByID(subscriptionID)
+		if err != nil {
+			if stderr.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.BadRequest("Invalid promo code", map[string]any{
+					"subscription_id": subscriptionID,
+					"message":         "Subscription not found",
+				})
+			}
+
+			return nil, errors.Internal("Failed to get subscription", err.Error())
+		}
+
+		if familyID := subscription.Family(); !seen[familyID] {
+			seen[familyID] = true
+			familyIDs = append(familyIDs, familyID)
+		}
+	}
+
+	return familyIDs, nil
+}
+
+// Check the discount and limits of a promo code are consistent with its type
+// The settings of the other types are cleared, so a code only ever applies its own kind of discount
+func validatePromoCode(promoCode *models.PromoCode) *errors.CustomError {
+	switch promoCode.Type {
+	case models.PromoCodeTypePercent:
+		if promoCode.PercentOff < 1 || promoCode.PercentOff > 99 {
+			return errors.BadRequest("Invalid promo code", "Percent off must be between 1 and 99")
+		}
+
+		promoCode.AmountOff = money.Zero(money.IDR)
+		promoCode.GrantedDays = 0
+	case models.PromoCodeTypeFixed:
+		if !promoCode.AmountOff.IsPositive() {
+			return errors.BadRequest("Invalid promo code", "Amount off must be greater than 0")
+		}
+
+		if promoCode.AmountOff.CurrencyCode() != money.IDR || !promoCode.AmountOff.IsWholeMajor() {
+			return errors.BadRequest("Invalid promo code", "Amount off must be in whole rupiah")
+		}
+
+		promoCode.PercentOff = 0
+		promoCode.GrantedDays = 0
+	case models.PromoCodeTypeVoucher:
+		if promoCode.GrantedDays <= 0 {
+			return errors.BadRequest("Invalid promo code", "A voucher must grant at least 1 day")
+		}
+
+		if len(promoCode.SubscriptionFamilyIDs) != 1 {
+			return errors.BadRequest("Invalid promo code", "A voucher must be restricted to exactly one subscription")
+		}
+
+		promoCode.PercentOff = 0
+		promoCode.AmountOff = money.Zero(money.IDR)
+	default:
+		return errors.BadRequest("Invalid promo code", "Type must be one of percent, fixed or voucher")
+	}
+
+	if promoCode.MaxRedemptions != nil && *promoCode.MaxRedemptions < 1 {
+		return errors.BadRequest("Invalid promo code", "Max redemptions must be at least 1")
+	}
+
+	if promoCode.MaxRedemptionsPerUser < 1 {
+		return errors.BadRequest("Invalid promo code", "Max redemptions per user must be at least 1")
+	}
+
+	if promoCode.StartsAt != nil && promoCode.EndsAt != nil && !promoCode.EndsAt.After(*promoCode.StartsAt) {
+		return errors.BadRequest("Invalid promo code", "End time must be after the start time")
+	}
+
+	return nil
+}
+
+// Check a user can redeem a promo code on a subscription
+// promoCodeRepository counts the redemptions, it must be bound to the transaction that locked the code
+// to enforce the limits when the order is stored
+// It returns an error explaining why the code cannot be redeemed
+func verifyPromoCodeRedeemable(promoCodeRepository repositories.PromoCodeRepository, promoCode *models.PromoCode, userID uint32, subscription *models.Subscription, now time.Time) *errors.CustomError {
+	invalid := func(reason string) *errors.CustomError {
+		return errors.BadRequest("Promo code is not valid", reason)
+	}
+
+	if !promoCode.IsRedeemableAt(now) {
+		return invalid("The promo code is not active")
+	}
+
+	if subscription == nil || !promoCode.AppliesTo(subscription) {
+		return invalid("The promo code does not apply to this subscription")
+	}
+
+	price := subscription.Price.RoundToMajor()
+	if !price.IsPositive() {
+		return invalid("The promo code does not apply to free subscriptions")
+	}
+
+	if !promoCode.IsVoucher() {
+		discount := promoCode.Discount(price)
+
+		if !discount.IsPositive() {
+			return invalid("The promo code does not discount this subscription")
+		}
+
+		if discount.Cmp(price) >= 0 {
+			return invalid("The discount cannot cover the whole price")
+		}
+	}
+
+	if promoCode.MaxRedemptions != nil {
+		count, err := promoCodeRepository.CountRedemptions(promoCode.ID)
+		if err != nil {
+			return errors.Internal("Failed to verify promo code", err.Error())
+		}
+
+		if count >= int64(*promoCode.MaxRedemptions) {
+			return invalid("The promo code has reached its usage limit")
+		}
+	}
+
+	count, err := promoCodeRepository.CountUserRedemptions(promoCode.ID, userID)
+	if err != nil {
+		return errors.Internal("Failed to verify promo code", err.Error())
+	}
+
+	if count >= int64(promoCode.MaxRedemptionsPerUser) {
+		return invalid("The promo code was already used")
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+)
+
+func TestPromoCodeDiscount(t *testing.T) {
+	price := money.FromMajor(30000, money.IDR)
+
+	t.Run("Should round a percent discount to whole rupiah", func(t *testing.T) {
+		promoCode := &models.PromoCode{Type: models.PromoCodeTypePercent, PercentOff: 33}
+
+		if discount := promoCode.Discount(price); !discount.Equal(money.FromMajor(9900, money.IDR)) {
+			t.Errorf("Expected IDR 9900.00, got %s", discount)
+		}
+	})
+
+	t.Run("Should never discount more than the price", func(t *testing.T) {
+		promoCode := &models.PromoCode{Type: models.PromoCodeTypeFixed, AmountOff: money.FromMajor(50000, money.IDR)}
+
+		if discount := promoCode.Discount(price); !discount.Equal(price) {
+			t.Errorf("Expected IDR 30000.00, got %s", discount)
+		}
+	})
+
+	t.Run("Should not discount a price in another currency", func(t *testing.T) {
+		promoCode := &models.PromoCode{Type: models.PromoCodeTypeFixed, AmountOff: money.FromMajor(5, money.USD)}
+
+		if discount := promoCode.Discount(price); !discount.IsZero() {
+			t.Errorf("Expected IDR 0.00, got %s", discount)
+		}
+	})
+}
+
+func TestVerifyPromoCodeRedeemable(t *testing.T) {
+	catalog := newFakeSubscriptionRepository()
+	business := catalog.subscriptions[3]
+	now := time.Now()
+
+	launch := func() *models.PromoCode {
+		return &models.PromoCode{ID: 1, Code: "LAUNCH50", Type: models.PromoCodeTypePercent, PercentOff: 50, MaxRedemptionsPerUser: 1, IsActive: true}
+	}
+
+	t.Run("Should accept a code that applies to the subscription", func(t *testing.T) {
+		promoCode := launch()
+		promoCode.SubscriptionFamilyIDs = models.IDArray{3}
+
+		if err := verifyPromoCodeRedeemable(newFakePromoCodeRepository(promoCode), promoCode, 10, business, now); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a code restricted to other subscriptions", func(t *testing.T) {
+		promoCode := launch()
+		promoCode.SubscriptionFamilyIDs = models.IDArray{2}
+
+		if err := verifyPromoCodeRedeemable(newFakePromoCodeRepository(promoCode), promoCode, 10, business, now); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a code outside of its validity window", func(t *testing.T) {
+		promoCode := launch()
+		endsAt := now.Add(-time.Hour)
+		promoCode.EndsAt = &endsAt
+
+		if err := verifyPromoCodeRedeemable(newFakePromoCodeRepository(promoCode), promoCode, 10, business, now); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a code that reached its usage limits", func(t *testing.T) {
+		promoCode := launch()
+		maxRedemptions := 2
+		promoCode.MaxRedemptions = &maxRedemptions
+
+		repository := newFakePromoCodeRepository(promoCode)
+		repository.redemptions[1] = []uint32{10}
+
+		if err := verifyPromoCodeRedeemable(repository, promoCode, 10, business, now); err == nil || err.Details != "The promo code was already used" {
+			t.Errorf("Expected The promo code was already used, got %v", err)
+		}
+
+		if err := verifyPromoCodeRedeemable(repository, promoCode, 20, business, now); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		repository.redemptions[1] = append(repository.redemptions[1], 20)
+
+		if err := verifyPromoCodeRedeemable(repository, promoCode, 30, business, now); err == nil || err.Details != "The promo code has reached its usage limit" {
+			t.Errorf("Expected The promo code has reached its usage limit, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a discount that covers the whole price", func(t *testing.T) {
+		promoCode := &models.PromoCode{ID: 1, Type: models.PromoCodeTypeFixed, AmountOff: money.FromMajor(30000, money.IDR), MaxRedemptionsPerUser: 1, IsActive: true}
+
+		if err := verifyPromoCodeRedeemable(newFakePromoCodeRepository(promoCode), promoCode, 10, business, now); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
+
+func TestCreatePromoCode(t *testing.T) {
+	newService := func() *PromoCodeServiceInstance {
+		return &PromoCodeServiceInstance{
+			PromoCodeRepository:    newFakePromoCodeRepository(),
+			SubscriptionRepository: newFakeSubscriptionRepository(),
+		}
+	}
+
+	t.Run("Should store the code in upper case and restrict it by family", func(t *testing.T) {
+		promoCode, err := newService().CreatePromoCode(&dtos.CreatePromoCodeDTO{
+			Code:            " launch50 ",
+			Type:            models.PromoCodeTypePercent,
+			PercentOff:      50,
+			GrantedDays:     30,
+			SubscriptionIDs: []uint32{2, 3, 3},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if promoCode.Code != "LAUNCH50" {
+			t.Errorf("Expected LAUNCH50, got %s", promoCode.Code)
+		}
+
+		if len(promoCode.SubscriptionFamilyIDs) != 2 || promoCode.SubscriptionFamilyIDs[0] != 2 || promoCode.SubscriptionFamilyIDs[1] != 3 {
+			t.Errorf("Expected [2 3], got %v", promoCode.SubscriptionFamilyIDs)
+		}
+
+		if promoCode.GrantedDays != 0 {
+			t.Errorf("Expected 0, got %d", promoCode.GrantedDays)
+		}
+
+		if promoCode.MaxRedemptionsPerUser != 1 {
+			t.Errorf("Expected 1, got %d", promoCode.MaxRedemptionsPerUser)
+		}
+
+		if !promoCode.IsActive {
+			t.Errorf("Expected true, got %t", promoCode.IsActive)
+		}
+	})
+
+	t.Run("Should refuse a duplicate code", func(t *testing.T) {
+		service := newService()
+		request := &dtos.CreatePromoCodeDTO{Code: "LAUNCH50", Type: models.PromoCodeTypePercent, PercentOff: 50}
+
+		if _, err := service.CreatePromoCode(request); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := service.CreatePromoCode(request); err == nil || err.Code != 409 {
+			t.Errorf("Expected conflict error, got %v", err)
+		}
+	})
+
+	t.Run("Should restrict a voucher to exactly one subscription", func(t *testing.T) {
+		if _, err := newService().CreatePromoCode(&dtos.CreatePromoCodeDTO{Code: "FREEMONTH", Type: models.PromoCodeTypeVoucher, GrantedDays: 30}); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
+
+func TestPrepareOrderItemsWithPromoCode(t *testing.T) {
+	t.Run("Should charge PPN on the discounted price", func(t *testing.T) {
+		service := &SubscriptionOrderServiceInstance{}
+		subscription := newFakeSubscriptionRepository().subscriptions[2]
+		promoCode := &models.PromoCode{Code: "LAUNCH50", Type: models.PromoCodeTypePercent, PercentOff: 50}
+
+		items, err := service.PrepareOrderItems(subscription, promoCode, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(items) != 3 {
+			t.Fatalf("Expected 3 items, got %d", len(items))
+		}
+
+		if items[1].Type != models.OrderItemTypeDiscount {
+			t.Errorf("Expected %s, got %s", models.OrderItemTypeDiscount, items[1].Type)
+		}
+
+		if !items[1].Amount.Equal(money.FromMajor(-5000, money.IDR)) {
+			t.Errorf("Expected IDR -5000.00, got %s", items[1].Amount)
+		}
+
+		if !items[2].Amount.Equal(money.FromMajor(550, money.IDR)) {
+			t.Errorf("Expected IDR 550.00, got %s", items[2].Amount)
+		}
+	})
+}
+
+func TestRedeemVoucher(t *testing.T) {
+	t.Run("Should grant the days of the voucher without a payment", func(t *testing.T) {
+		voucher := &models.PromoCode{ID: 1, Code: "FREEMONTH", Type: models.PromoCodeTypeVoucher, GrantedDays: 60, SubscriptionFamilyIDs: models.IDArray{3}, MaxRedemptionsPerUser: 1, IsActive: true}
+		promoCodes := newFakePromoCodeRepository(voucher)
+		subscriptions := newFakeSubscriptionRepository(freeTierUserSubscription(10))
+
+		service := &SubscriptionOrderServiceInstance{
+			SubscriptionOrderRepository: &fakeSubscriptionOrderRepository{promoCodes: promoCodes},
+			SubscriptionRepository:      subscriptions,
+			PromoCodeRepository:         promoCodes,
+			TransactionManager:          &fakeTransactionManager{},
+		}
+
+		order, err := service.RedeemVoucher(10, subscriptions.subscriptions[3], voucher)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if order.Type != models.OrderTypeVoucher {
+			t.Errorf("Expected %s, got %s", models.OrderTypeVoucher, order.Type)
+		}
+
+		if !order.Amount.IsZero() {
+			t.Errorf("Expected IDR 0.00, got %s", order.Amount)
+		}
+
+		if order.Status != "settled" {
+			t.Errorf("Expected settled, got %s", order.Status)
+		}
+
+		if order.GrantedDays != 60 {
+			t.Errorf("Expected 60, got %d", order.GrantedDays)
+		}
+
+		granted := subscriptions.userSubscriptions[len(subscriptions.userSubscriptions)-1]
+		if granted.SubID != 3 {
+			t.Errorf("Expected 3, got %d", granted.SubID)
+		}
+
+		if expected := granted.StartedAt.AddDate(0, 0, 60); !granted.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, granted.ExpiredAt)
+		}
+
+		if _, err := service.RedeemVoucher(10, subscriptions.subscriptions[3], voucher); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"crypto/rand"
+	stderr "errors"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/config"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReferralService interface {
+	GetReferrer(code string) (*models.User, *errors.CustomError)
+	CreateReferral(referrer *models.User, referred *models.User) *errors.CustomError
+	GetReferralSummary(userID uint32) (*dtos.ReferralSummaryDTO, *errors.CustomError)
+}
+
+type ReferralServiceInstance struct {
+	UserRepository     repositories.UserRepository
+	ReferralRepository repositories.ReferralRepository
+}
+
+func NewReferralService(userRepository repositories.UserRepository, referralRepository repositories.ReferralRepository) ReferralService {
+	return &ReferralServiceInstance{
+		UserRepository:     userRepository,
+		ReferralRepository: referralRepository,
+	}
+}
+
+// Referral codes leave out the characters that are easily mistaken for one another
+const referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const referralCodeLength = 8
+
+// Mailboxes anyone can sign up for, sharing one of these domains does not tie two users together
+var publicEmailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+	"yahoo.com":      true,
+	"yahoo.co.id":    true,
+	"ymail.com":      true,
+	"outlook.com":    true,
+	"hotmail.com":    true,
+	"live.com":       true,
+	"icloud.com":     true,
+	"proton.me":      true,
+	"protonmail.com": true,
+}
+
+// Generate a new random referral code
+func newReferralCode() (string, error) {
+	random := make([]byte, referralCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, referralCodeLength)
+	for i, b := range random {
+		code[i] = referralCodeAlphabet[int(b)%len(referralCodeAlphabet)]
+	}
+
+	return string(code), nil
+}
+
+// Get the number of days a referrer is rewarded with, read from REFERRAL_REWARD_DAYS
+func referralRewardDays() int {
+	return config.GetEnvAsInt("REFERRAL_REWARD_DAYS", 14)
+}
+
+// Get the user a referral code belongs to
+// It returns the referrer or a bad request error if the code does not exist
+func (s *ReferralServiceInstance) GetReferrer(code string) (*models.User, *errors.CustomError) {
+	referrer, err := s.UserRepository.FindByReferralCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		if stderr.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.BadRequest("Invalid referral code", "The referral code does not exist")
+		}
+
+		return nil, errors.Internal("Failed to get referral code", err.Error())
+	}
+
+	return referrer, nil
+}
+
+// Record that a user signed up with the referral code of another user
+// A referral that trips a fraud guard is still recorded, as rejected, so the user cannot tell which guard it tripped
+func (s *ReferralServiceInstance) CreateReferral(referrer *models.User, referred *models.User) *errors.CustomError {
+	referral := &models.Referral{
+		ReferrerID: referrer.ID,
+		ReferredID: referred.ID,
+		Status:     models.ReferralStatusPending,
+	}
+
+	if reason := referralRejectReason(referrer, referred); reason != "" {
+		referral.Reject(reason)
+	}
+
+	if err := s.ReferralRepository.StoreReferral(referral); err != nil {
+		return errors.Internal("Failed to create referral", err.Error())
+	}
+
+	return nil
+}
+
+// Get the referral code of a user and the users they referred
+// It returns the summary or an error if the user does not exist
+func (s *ReferralServiceInstance) GetReferralSummary(userID uint32) (*dtos.ReferralSummaryDTO, *errors.CustomError) {
+	user, err := s.UserRepository.FindByID(userID)
+	if err != nil {
+		if stderr.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NotFound("User not found")
+		}
+
+		return nil, errors.Internal("Failed to get user", err.Error())
+	}
+
+	referrals, err := s.ReferralRepository.FindByReferrerID(userID)
+	if err != nil {
+		return nil, errors.Internal("Failed to get referrals", err.Error())
+	}
+
+	summary := &dtos.ReferralSummaryDTO{
+		ReferralCode:   user.ReferralCode,
+		RewardDays:     referralRewardDays(),
+		TotalReferrals: len(referrals),
+		Referrals:      make([]dtos.ReferralDTO, 0, len(referrals)),
+	}
+
+	for _, referral := range referrals {
+		switch referral.Status {
+		case models.ReferralStatusPending:
+			summary.PendingReferrals++
+		case models.ReferralStatusRewarded:
+			summary.RewardedReferrals++
+			summary.EarnedDays += int(referral.RewardDays)
+		}
+
+		name := ""
+		if referral.Referred != nil {
+			if fields := strings.Fields(referral.Referred.Name); len(fields) > 0 {
+				name = fields[0]
+			}
+		}
+
+		summary.Referrals = append(summary.Referrals, dtos.ReferralDTO{
+			ID:         referral.ID,
+			Name:       name,
+			Status:     referral.Status,
+			RewardDays: referral.RewardDays,
+			RewardedAt: referral.RewardedAt,
+			CreatedAt:  referral.CreatedAt,
+		})
+	}
+
+	return summary, nil
+}
+
+// Check a referral for signs of a user referring themselves
+// The same mailbox, the same phone number or the same private email domain point to the same person or organization
+// It returns the reason the referral is rejected, or an empty string if it looks genuine
+func referralRejectReason(referrer *models.User, referred *models.User) string {
+	if referrer.ID == referred.ID || normalizeTrialEmail(referrer.Email) == normalizeTrialEmail(referred.Email) {
+		return models.ReferralRejectSelfReferral
+	}
+
+	if phone := normalizePhone(referrer.Phone); phone != "" && phone == normalizePhone(referred.Phone) {
+		return models.ReferralRejectSamePhone
+	}
+
+	if domain := emailDomain(referrer.Email); domain != "" && !publicEmailDomains[domain] && domain == emailDomain(referred.Email) {
+		return models.ReferralRejectSameEmailDomain
+	}
+
+	return ""
+}
+
+// Normalize a phone number to its national digits, so +62812, 62812 and 0812 are the same number
+func normalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	if strings.HasPrefix(digits, "62") {
+		return digits[2:]
+	}
+
+	return strings.TrimPrefix(digits, "0")
+}
+
+// Get the lowercased domain of an email address
+func emailDomain(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+
+	return email[at+1:]
+}
+
+// Reward the referrer of a user whose paid order settled
+// The referral is rewarded once, a paid subscription of the referrer is extended by the reward days.
+// A referrer without a paid subscription gets the reward days of the subscription the referred user paid for.
+// A referrer rewarded REFERRAL_MAX_REWARDS_PER_MONTH times over the last month is not rewarded again
+// The repositories must be bound to the transaction that settles the order
+func rewardReferrer(referralRepository repositories.ReferralRepository, subscriptionRepository repositories.SubscriptionRepository, order *models.SubscriptionOrder, now time.Time) error {
+	referral, err := referralRepository.FindPendingByReferredIDForUpdate(order.UserID)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if referral.Referrer == nil {
+		referral.Reject(models.ReferralRejectReferrerRemoved)
+		return referralRepository.UpdateReferral(referral)
+	}
+
+	if limit := config.GetEnvAsInt("REFERRAL_MAX_REWARDS_PER_MONTH", 10); limit > 0 {
+		rewarded, err := referralRepository.CountRewardedSince(referral.ReferrerID, now.AddDate(0, -1, 0))
+		if err != nil {
+			return err
+		}
+
+		if rewarded >= int64(limit) {
+			referral.Reject(models.ReferralRejectRewardLimit)
+			return referralRepository.UpdateReferral(referral)
+		}
+	}
+
+	days := referralRewardDays()
+
+	userSubscription, startFrom, err := creditReferralDays(subscriptionRepository, referral.ReferrerID, order, days, now)
+	if err != nil {
+		return err
+	}
+
+	referral.Status = models.ReferralStatusRewarded
+	referral.RewardDays = int16(days)
+	referral.OrderID = &order.ID
+	referral.UserSubscriptionID = &userSubscription.ID
+	referral.RewardedAt = &now
+
+	if err := referralRepository.UpdateReferral(referral); err != nil {
+		return err
+	}
+
+	return subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+		UserID:             userSubscription.UserID,
+		UserSubscriptionID: userSubscription.ID,
+		SubscriptionID:     userSubscription.SubID,
+		OrderID:            &order.ID,
+		Event:              models.SubscriptionEventReferralReward,
+		StartedAt:          startFrom,
+		ExpiredAt:          userSubscription.ExpiredAt,
+	})
+}
+
+// Credit a referrer with reward days
+// It returns the user subscription the days were added to and the time the days start from
+func creditReferralDays(subscriptionRepository repositories.SubscriptionRepository, referrerID uint32, order *models.SubscriptionOrder, days int, now time.Time) (*models.UserSubscription, time.Time, error) {
+	current, err := subscriptionRepository.FindActiveUserSubscription(referrerID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, now, err
+	}
+
+	if current != nil && current.Sub.Price.IsPositive() && !current.IsTrial() && current.ExpiredAt.After(now) {
+		userSubscription, err := subscriptionRepository.FindUserSubscriptionForUpdate(current.ID)
+		if err != nil {
+			return nil, now, err
+		}
+
+		startFrom := userSubscription.ExpiredAt
+		userSubscription.ExpiredAt = startFrom.AddDate(0, 0, days)
+
+		if err := subscriptionRepository.UpdateUserSubscription(userSubscription); err != nil {
+			return nil, now, err
+		}
+
+		return userSubscription, startFrom, nil
+	}
+
+	if err := subscriptionRepository.DeactivateUserSubscriptions(referrerID); err != nil {
+		return nil, now, err
+	}
+
+	userSubscription := &models.UserSubscription{
+		UserID:        referrerID,
+		SubID:         order.SubscriptionID,
+		StartedAt:     now,
+		ExpiredAt:     now.AddDate(0, 0, days),
+		IsActive:      true,
+		PaymentStatus: "referral",
+	}
+
+	if err := subscriptionRepository.SubscribeUser(userSubscription); err != nil {
+		return nil, now, err
+	}
+
+	return userSubscription, now, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReferralRejectReason(t *testing.T) {
+	referrer := &models.User{ID: 10, Email: "Jane.Doe@gmail.com", Phone: "+62 812-3456-7890"}
+
+	t.Run("Should accept users who only share a public email domain", func(t *testing.T) {
+		if reason := referralRejectReason(referrer, &models.User{ID: 20, Email: "john@gmail.com", Phone: "081298765432"}); reason != "" {
+			t.Errorf("Expected no reason, got %s", reason)
+		}
+	})
+
+	t.Run("Should reject an alias of the referrer mailbox", func(t *testing.T) {
+		if reason := referralRejectReason(referrer, &models.User{ID: 20, Email: "janedoe+2@gmail.com", Phone: "081298765432"}); reason != models.ReferralRejectSelfReferral {
+			t.Errorf("Expected %s, got %s", models.ReferralRejectSelfReferral, reason)
+		}
+	})
+
+	t.Run("Should reject the same phone number in another format", func(t *testing.T) {
+		if reason := referralRejectReason(referrer, &models.User{ID: 20, Email: "john@gmail.com", Phone: "081234567890"}); reason != models.ReferralRejectSamePhone {
+			t.Errorf("Expected %s, got %s", models.ReferralRejectSamePhone, reason)
+		}
+	})
+
+	t.Run("Should reject users of the same private email domain", func(t *testing.T) {
+		colleague := &models.User{ID: 30, Email: "agus@senkou.co.id", Phone: "6281111111111"}
+
+		if reason := referralRejectReason(colleague, &models.User{ID: 20, Email: "Budi@Senkou.co.id", Phone: "6282222222222"}); reason != models.ReferralRejectSameEmailDomain {
+			t.Errorf("Expected %s, got %s", models.ReferralRejectSameEmailDomain, reason)
+		}
+	})
+}
+
+func TestRewardReferrer(t *testing.T) {
+	order := &models.SubscriptionOrder{ID: uuid.New(), UserID: 20, SubscriptionID: 3, Type: models.OrderTypeNew}
+
+	t.Run("Should extend the paid subscription of the referrer", func(t *testing.T) {
+		expiredAt := time.Now().AddDate(0, 0, 10)
+		subscriptions := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: expiredAt, IsActive: true,
+			Sub: models.Subscription{ID: 2, Price: money.FromMajor(10000, money.IDR)},
+		})
+		referrals := newFakeReferralRepository(&models.Referral{ID: 1, ReferrerID: 10, ReferredID: 20, Status: models.ReferralStatusPending})
+
+		if err := rewardReferrer(referrals, subscriptions, order, time.Now()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if extended := subscriptions.userSubscriptions[0]; !extended.ExpiredAt.Equal(expiredAt.AddDate(0, 0, 14)) {
+			t.Errorf("Expected %v, got %v", expiredAt.AddDate(0, 0, 14), extended.ExpiredAt)
+		}
+
+		referral := referrals.referrals[0]
+		if referral.Status != models.ReferralStatusRewarded {
+			t.Errorf("Expected %s, got %s", models.ReferralStatusRewarded, referral.Status)
+		}
+
+		if referral.RewardDays != 14 {
+			t.Errorf("Expected 14, got %d", referral.RewardDays)
+		}
+
+		if referral.OrderID == nil || *referral.OrderID != order.ID {
+			t.Errorf("Expected %s, got %v", order.ID, referral.OrderID)
+		}
+
+		if events := historyEvents(subscriptions.histories); len(events) != 1 || events[0] != models.SubscriptionEventReferralReward {
+			t.Errorf("Expected [%s], got %v", models.SubscriptionEventReferralReward, events)
+		}
+	})
+
+	t.Run("Should grant the referred subscription to a referrer on the free tier", func(t *testing.T) {
+		subscriptions := newFakeSubscriptionRepository(freeTierUserSubscription(10))
+		referrals := newFakeReferralRepository(&models.Referral{ID: 1, ReferrerID: 10, ReferredID: 20, Status: models.ReferralStatusPending})
+
+		if err := rewardReferrer(referrals, subscriptions, order, time.Now()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		granted := subscriptions.userSubscriptions[len(subscriptions.userSubscriptions)-1]
+		if granted.UserID != 10 {
+			t.Errorf("Expected 10, got %d", granted.UserID)
+		}
+
+		if granted.SubID != 3 {
+			t.Errorf("Expected 3, got %d", granted.SubID)
+		}
+
+		if !granted.IsActive {
+			t.Errorf("Expected true, got %t", granted.IsActive)
+		}
+
+		if expected := granted.StartedAt.AddDate(0, 0, 14); !granted.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, granted.ExpiredAt)
+		}
+
+		if subscriptions.userSubscriptions[0].IsActive {
+			t.Errorf("Expected false, got %t", subscriptions.userSubscriptions[0].IsActive)
+		}
+	})
+
+	t.Run("Should stop rewarding a referrer over the monthly limit", func(t *testing.T) {
+		t.Setenv("REFERRAL_MAX_REWARDS_PER_MONTH", "1")
+
+		rewardedAt := time.Now().AddDate(0, 0, -3)
+		subscriptions := newFakeSubscriptionRepository(freeTierUserSubscription(10))
+		referrals := newFakeReferralRepository(
+			&models.Referral{ID: 1, ReferrerID: 10, ReferredID: 30, Status: models.ReferralStatusRewarded, RewardedAt: &rewardedAt},
+			&models.Referral{ID: 2, ReferrerID: 10, ReferredID: 20, Status: models.ReferralStatusPending},
+		)
+
+		if err := rewardReferrer(referrals, subscriptions, order, time.Now()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		referral := referrals.referrals[1]
+		if referral.Status != models.ReferralStatusRejected {
+			t.Errorf("Expected %s, got %s", models.ReferralStatusRejected, referral.Status)
+		}
+
+		if referral.RejectReason == nil || *referral.RejectReason != models.ReferralRejectRewardLimit {
+			t.Errorf("Expected %s, got %v", models.ReferralRejectRewardLimit, referral.RejectReason)
+		}
+
+		if len(subscriptions.userSubscriptions) != 1 {
+			t.Errorf("Expected 1, got %d", len(subscriptions.userSubscriptions))
+		}
+	})
+
+	t.Run("Should only reward a referral once", func(t *testing.T) {
+		subscriptions := newFakeSubscriptionRepository(freeTierUserSubscription(10))
+		referrals := newFakeReferralRepository(&models.Referral{ID: 1, ReferrerID: 10, ReferredID: 20, Status: models.ReferralStatusPending})
+
+		for i := 0; i < 2; i++ {
+			if err := rewardReferrer(referrals, subscriptions, order, time.Now()); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		if len(subscriptions.histories) != 1 {
+			t.Errorf("Expected 1, got %d", len(subscriptions.histories))
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/platform/entitlements"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const secondsPerDay = int64(24 * time.Hour / time.Second)
+
+type SubscriptionChangeService interface {
+	PreviewSubscriptionChange(userID uint32, subID uint32) (*dtos.SubscriptionChangePreviewDTO, *errors.CustomError)
+	VerifyCanSubscribe(userID uint32, subscription *models.Subscription) *errors.CustomError
+}
+
+type SubscriptionChangeServiceInstance struct {
+	SubscriptionRepository repositories.SubscriptionRepository
+	MerchantRepository     repositories.MerchantRepository
+	ProductRepository      repositories.ProductRepository
+	CategoryRepository     repositories.CategoryRepository
+}
+
+func NewSubscriptionChangeService(
+	subscriptionRepository repositories.SubscriptionRepository,
+	merchantRepository repositories.MerchantRepository,
+	productRepository repositories.ProductRepository,
+	categoryRepository repositories.CategoryRepository,
+) SubscriptionChangeService {
+	return &SubscriptionChangeServiceInstance{
+		SubscriptionRepository: subscriptionRepository,
+		MerchantRepository:     merchantRepository,
+		ProductRepository:      productRepository,
+		CategoryRepository:     categoryRepository,
+	}
+}
+
+// Preview a change of the active paid subscription of a user to another subscription
+// The unused share of the current subscription is credited at its daily price. When the remaining period costs more
+// on the new subscription, the change is an upgrade that charges the difference and keeps the current expiry.
+// Otherwise the change is a downgrade that charges the new subscription in full and starts when the current one expires,
+// in which case the quotas the merchants of the user would exceed and the features they would lose are listed
+// It returns the preview or an error if the subscription cannot be changed
+func (s *SubscriptionChangeServiceInstance) PreviewSubscriptionChange(userID uint32, subID uint32) (*dtos.SubscriptionChangePreviewDTO, *errors.CustomError) {
+	current, err := s.SubscriptionRepository.FindActiveUserSubscription(userID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NotFound("Active subscription not found")
+	}
+	if err != nil {
+		return nil, errors.Internal("Failed to get active subscription", err.Error())
+	}
+
+	if !current.Sub.Price.IsPositive() {
+		return nil, errors.BadRequest("Subscription cannot be changed", "Subscribe to a paid subscription to leave the free tier")
+	}
+
+	if current.IsTrial() {
+		return nil, errors.BadRequest("Subscription cannot be changed", "Subscribe to a subscription to keep it after the trial")
+	}
+
+	if current.SubID == subID {
+		return nil, errors.BadRequest("Subscription cannot be changed", "Renew the current subscription to extend it")
+	}
+
+	if _, err := s.SubscriptionRepository.FindScheduledUserSubscription(userID); err == nil {
+		return nil, errors.BadRequest("Subscription cannot be changed", "A subscription change is already scheduled")
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, errors.Internal("Failed to get scheduled subscription", err.Error())
+	}
+
+	currentSubscription, err := s.SubscriptionRepository.FindByID(current.SubID)
+	if err != nil {
+		return nil, errors.Internal("Failed to get current subscription", err.Error())
+	}
+
+	target, err := s.SubscriptionRepository.FindByID(subID)
+	if err != nil {
+		return nil, errors.NotFound("Subscription not found")
+	}
+
+	if target.IsRetired() {
+		return nil, errors.BadRequest("Subscription cannot be changed", "The subscription is no longer offered")
+	}
+
+	if target.Family() == currentSubscription.Family() {
+		return nil, errors.BadRequest("Subscription cannot be changed", "Renew the current subscription to move to its latest version")
+	}
+
+	if !target.Price.IsPositive() {
+		return nil, errors.BadRequest("Subscription cannot be changed", "The subscription moves to the free tier when it expires without a renewal")
+	}
+
+	if currentSubscription.Duration <= 0 || target.Duration <= 0 {
+		return nil, errors.Internal("Failed to preview subscription change", "Subscription has an invalid duration")
+	}
+
+	now := time.Now()
+	remaining := current.ExpiredAt.Sub(now)
+	if remaining <= 0 {
+		return nil, errors.BadRequest("Subscription cannot be changed", "The current subscription has expired")
+	}
+
+	remainingSeconds := int64(remaining / time.Second)
+	credit := currentSubscription.Price.MulRatio(remainingSeconds, int64(currentSubscription.Duration)*secondsPerDay)
+	cost := target.Price.MulRatio(remainingSeconds, int64(target.Duration)*secondsPerDay)
+
+	preview := &dtos.SubscriptionChangePreviewDTO{
+		UserSubscriptionID:  current.ID,
+		CurrentSubscription: currentSubscription,
+		TargetSubscription:  target,
+		RemainingDays:       int((remaining + 24*time.Hour - 1) / (24 * time.Hour)),
+		Credit:              credit.RoundToMajor(),
+		QuotaEffects:        make([]dtos.SubscriptionQuotaEffectDTO, 0),
+		LostFeatures:        make([]string, 0),
+	}
+
+	if difference := cost.Sub(credit).RoundToMajor(); difference.IsPositive() {
+		preview.Type = models.OrderTypeUpgrade
+		preview.Amount = difference
+		preview.EffectiveAt = now
+		preview.ExpiredAt = current.ExpiredAt
+
+		return preview, nil
+	}
+
+	preview.Type = models.OrderTypeDowngrade
+	preview.Amount = target.Price.RoundToMajor()
+	preview.EffectiveAt = current.ExpiredAt
+	preview.ExpiredAt = current.ExpiredAt.AddDate(0, 0, int(target.Duration))
+	preview.LostFeatures = lostFeatures(currentSubscription, target)
+
+	quotaEffects, appError := s.quotaEffects(userID, target)
+	if appError != nil {
+		return nil, appError
+	}
+	preview.QuotaEffects = quotaEffects
+
+	return preview, nil
+}
+
+// Verify a user can buy a subscription outright
+// A retired subscription can no longer be bought. A user on a paid subscription can only buy the same subscription again,
+// other subscriptions go through a change and a newer version of the same subscription through a renewal
+// It returns an error if the subscription cannot be bought or the user has to change or renew the subscription instead
+func (s *SubscriptionChangeServiceInstance) VerifyCanSubscribe(userID uint32, subscription *models.Subscription) *errors.CustomError {
+	if subscription.IsRetired() {
+		return errors.BadRequest("Subscription is no longer offered", "Subscribe to the latest version of the subscription")
+	}
+
+	current, err := s.SubscriptionRepository.FindActiveUserSubscription(userID)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Internal("Failed to get active subscription", err.Error())
+	}
+
+	// A trial ends as soon as the user pays for any subscription
+	if current.SubID == subscription.ID || current.IsTrial() || !current.Sub.Price.IsPositive() || !current.ExpiredAt.After(time.Now()) {
+		return nil
+	}
+
+	if current.Sub.Family() == subscription.Family() {
+		return errors.BadRequest("User already has an active subscription", "Renew the current subscription to move to its latest version")
+	}
+
+	return errors.BadRequest("User already has an active subscription", "Change the current subscription to upgrade or downgrade")
+}
+
+// List the quotas the merchants of a user exceed on a subscription
+func (s *SubscriptionChangeServiceInstance) quotaEffects(userID uint32, target *models.Subscription) ([]dtos.SubscriptionQuotaEffectDTO, *errors.CustomError) {
+	effects := make([]dtos.SubscriptionQuotaEffectDTO, 0)
+
+	merchants, err := s.MerchantRepository.FindByUserID(userID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Internal("Failed to get merchants", err.Error())
+	}
+
+	targetEntitlements := entitlements.New(userID, target)
+	productLimit := targetEntitlements.Get(constants.SubscriptionProductSlot).Limit
+	categoryLimit := targetEntitlements.Get(constants.SubscriptionCategoryLimit).Limit
+
+	for _, merchant := range merchants {
+		products, err := s.ProductRepository.CountProductsByMerchantID(merchant.ID)
+		if err != nil {
+			return nil, errors.Internal("Failed to count products", err.Error())
+		}
+
+		if products > productLimit {
+			effects = append(effects, dtos.SubscriptionQuotaEffectDTO{
+				MerchantID: merchant.ID,
+				Plan:       string(constants.SubscriptionProductSlot),
+				Used:       int(products),
+				Limit:      int(productLimit),
+				OverQuota:  int(products - productLimit),
+			})
+		}
+
+		categories, err := s.CategoryRepository.CountCategoriesByMerchantID(merchant.ID)
+		if err != nil {
+			return nil, errors.Internal("Failed to count categories", err.Error())
+		}
+
+		if categories > categoryLimit {
+			effects = append(effects, dtos.SubscriptionQuotaEffectDTO{
+				MerchantID: merchant.ID,
+				Plan:       string(constants.SubscriptionCategoryLimit),
+				Used:       int(categories),
+				Limit:      int(categoryLimit),
+				OverQuota:  int(categories - categoryLimit),
+			})
+		}
+	}
+
+	return effects, nil
+}
+
+// List the features enabled on the current subscription that are not enabled on the target subscription
+func lostFeatures(current *models.Subscription, target *models.Subscription) []string {
+	currentEntitlements := entitlements.New(0, current)
+	targetEntitlements := entitlements.New(0, target)
+
+	features := make([]string, 0)
+	for _, definition := range entitlements.Definitions() {
+		if definition.Kind == entitlements.KindFlag && currentEntitlements.Feature(definition.Key) && !targetEntitlements.Feature(definition.Key) {
+			features = append(features, string(definition.Key))
+		}
+	}
+
+	return features
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+)
+
+func TestPreviewSubscriptionChange(t *testing.T) {
+	t.Run("Should charge the prorated difference of an upgrade", func(t *testing.T) {
+		expiredAt := time.Now().AddDate(0, 0, 14)
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: expiredAt, IsActive: true,
+			Sub: models.Subscription{ID: 2, Price: money.FromMajor(10000, money.IDR)},
+		})
+		service := &SubscriptionChangeServiceInstance{SubscriptionRepository: repository}
+
+		preview, err := service.PreviewSubscriptionChange(10, 3)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if preview.Type != models.OrderTypeUpgrade {
+			t.Errorf("Expected %s, got %s", models.OrderTypeUpgrade, preview.Type)
+		}
+
+		if !preview.Amount.Equal(money.FromMajor(10000, money.IDR)) {
+			t.Errorf("Expected IDR 10000.00, got %s", preview.Amount)
+		}
+
+		if !preview.ExpiredAt.Equal(expiredAt) {
+			t.Errorf("Expected %v, got %v", expiredAt, preview.ExpiredAt)
+		}
+
+		if preview.RemainingDays != 14 {
+			t.Errorf("Expected 14, got %d", preview.RemainingDays)
+		}
+
+		if preview.RequiresConfirmation() {
+			t.Errorf("Expected false, got %t", preview.RequiresConfirmation())
+		}
+	})
+
+	t.Run("Should schedule a downgrade and list the quotas and features it takes away", func(t *testing.T) {
+		expiredAt := time.Now().AddDate(0, 0, 14)
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 3, ExpiredAt: expiredAt, IsActive: true,
+			Sub: models.Subscription{ID: 3, Price: money.FromMajor(30000, money.IDR)},
+		})
+		service := &SubscriptionChangeServiceInstance{
+			SubscriptionRepository: repository,
+			MerchantRepository:     &fakeMerchantRepository{merchants: []*models.Merchant{{ID: "merchant-a"}, {ID: "merchant-b"}}},
+			ProductRepository:      &fakeProductRepository{products: map[string]int{"merchant-a": 120, "merchant-b": 20}},
+			CategoryRepository:     &fakeCategoryRepository{categories: map[string]int{"merchant-a": 4, "merchant-b": 12}},
+		}
+
+		preview, err := service.PreviewSubscriptionChange(10, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if preview.Type != models.OrderTypeDowngrade {
+			t.Errorf("Expected %s, got %s", models.OrderTypeDowngrade, preview.Type)
+		}
+
+		if !preview.EffectiveAt.Equal(expiredAt) {
+			t.Errorf("Expected %v, got %v", expiredAt, preview.EffectiveAt)
+		}
+
+		if !preview.Amount.Equal(money.FromMajor(10000, money.IDR)) {
+			t.Errorf("Expected IDR 10000.00, got %s", preview.Amount)
+		}
+
+		if len(preview.QuotaEffects) != 2 {
+			t.Fatalf("Expected 2 quota effects, got %d", len(preview.QuotaEffects))
+		}
+
+		products := preview.QuotaEffects[0]
+		if products.MerchantID != "merchant-a" || products.Plan != string(constants.SubscriptionProductSlot) || products.OverQuota != 20 {
+			t.Errorf("Expected %s over by 20 on merchant-a, got %s over by %d on %s", constants.SubscriptionProductSlot, products.Plan, products.OverQuota, products.MerchantID)
+		}
+
+		categories := preview.QuotaEffects[1]
+		if categories.MerchantID != "merchant-b" || categories.Plan != string(constants.SubscriptionCategoryLimit) || categories.OverQuota != 2 {
+			t.Errorf("Expected %s over by 2 on merchant-b, got %s over by %d on %s", constants.SubscriptionCategoryLimit, categories.Plan, categories.OverQuota, categories.MerchantID)
+		}
+
+		if len(preview.LostFeatures) != 1 || preview.LostFeatures[0] != string(constants.SubscriptionInteractionMetrics) {
+			t.Errorf("Expected [%s], got %v", constants.SubscriptionInteractionMetrics, preview.LostFeatures)
+		}
+
+		if !preview.RequiresConfirmation() {
+			t.Errorf("Expected true, got %t", preview.RequiresConfirmation())
+		}
+	})
+
+	t.Run("Should refuse a change while another one is scheduled", func(t *testing.T) {
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{
+				ID: 1, UserID: 10, SubID: 3, ExpiredAt: time.Now().AddDate(0, 0, 14), IsActive: true,
+				Sub: models.Subscription{ID: 3, Price: money.FromMajor(30000, money.IDR)},
+			},
+			&models.UserSubscription{ID: 2, UserID: 10, SubID: 2, IsScheduled: true},
+		)
+		service := &SubscriptionChangeServiceInstance{SubscriptionRepository: repository}
+
+		if _, err := service.PreviewSubscriptionChange(10, 2); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
+
+func TestVerifyCanSubscribe(t *testing.T) {
+	t.Run("Should send users on another paid subscription to the change flow", func(t *testing.T) {
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: time.Now().AddDate(0, 0, 14), IsActive: true,
+			Sub: models.Subscription{ID: 2, Price: money.FromMajor(10000, money.IDR)},
+		})
+		service := &SubscriptionChangeServiceInstance{SubscriptionRepository: repository}
+
+		if err := service.VerifyCanSubscribe(10, repository.subscriptions[3]); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+
+		if err := service.VerifyCanSubscribe(10, repository.subscriptions[2]); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should let free tier users subscribe", func(t *testing.T) {
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 1, ExpiredAt: time.Now().AddDate(100, 0, 0), IsActive: true,
+			Sub: models.Subscription{ID: 1, Price: money.Zero(money.IDR)},
+		})
+		service := &SubscriptionChangeServiceInstance{SubscriptionRepository: repository}
+
+		if err := service.VerifyCanSubscribe(10, repository.subscriptions[3]); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a subscription that is no longer offered", func(t *testing.T) {
+		repository := newFakeSubscriptionRepository()
+		service := &SubscriptionChangeServiceInstance{SubscriptionRepository: repository}
+
+		retiredAt := time.Now()
+		repository.subscriptions[3].RetiredAt = &retiredAt
+
+		if err := service.VerifyCanSubscribe(10, repository.subscriptions[3]); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should send users on an older version of a subscription to the renewal", func(t *testing.T) {
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: time.Now().AddDate(0, 0, 14), IsActive: true,
+			Sub: models.Subscription{ID: 2, FamilyID: 2, Price: money.FromMajor(10000, money.IDR)},
+		})
+		service := &SubscriptionChangeServiceInstance{SubscriptionRepository: repository}
+
+		version := &models.Subscription{ID: 4, FamilyID: 2, Version: 2, Price: money.FromMajor(12000, money.IDR), Duration: 28}
+
+		expected := "Renew the current subscription to move to its latest version"
+		if err := service.VerifyCanSubscribe(10, version); err == nil || err.Details != expected {
+			t.Errorf("Expected %s, got %v", expected, err)
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/config"
+	"senkou-catalyst-be/utils/mailer"
+	"senkou-catalyst-be/utils/queue"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+const (
+	TaskProcessSubscriptionExpiries = "subscription:process_expiries"
+	TaskSendExpiryReminder          = "subscription:send_expiry_reminder"
+)
+
+const (
+	expiryReminderTemplate = "subscription-expiry-reminder.html"
+	trialEndingTemplate    = "subscription-trial-ending.html"
+)
+
+type SubscriptionExpiryService interface {
+	ProcessSubscriptionExpiries(ctx context.Context) (*dtos.SubscriptionExpiryResultDTO, *errors.CustomError)
+	HandleProcessSubscriptionExpiries(ctx context.Context, task *asynq.Task) error
+	HandleSendExpiryReminder(ctx context.Context, task *asynq.Task) error
+}
+
+type SubscriptionExpiryServiceInstance struct {
+	SubscriptionRepository repositories.SubscriptionRepository
+	TransactionManager     repositories.TransactionManager
+	QueueService           *queue.QueueService
+	ReminderDays           []int
+	BatchSize              int
+}
+
+func NewSubscriptionExpiryService(
+	subscriptionRepository repositories.SubscriptionRepository,
+	transactionManager repositories.TransactionManager,
+	queueService *queue.QueueService,
+) SubscriptionExpiryService {
+	return &SubscriptionExpiryServiceInstance{
+		SubscriptionRepository: subscriptionRepository,
+		TransactionManager:     transactionManager,
+		QueueService:           queueService,
+		ReminderDays:           []int{7, 1},
+		BatchSize:              config.GetEnvAsInt("SUBSCRIPTION_EXPIRY_BATCH_SIZE", 100),
+	}
+}
+
+// Process the expiry of user subscriptions
+// This function queues a reminder email for paid subscriptions that expire within each of ReminderDays,
+// then deactivates the subscriptions past their expiry and records the expiry in the subscription history.
+// Users with a scheduled downgrade move to the scheduled subscription, everyone else moves back to the free tier
+// It returns a summary of the processing
+func (s *SubscriptionExpiryServiceInstance) ProcessSubscriptionExpiries(ctx context.Context) (*dtos.SubscriptionExpiryResultDTO, *errors.CustomError) {
+	now := time.Now()
+	result := new(dtos.SubscriptionExpiryResultDTO)
+
+	if appError := s.sendReminders(ctx, now, result); appError != nil {
+		return nil, appError
+	}
+
+	expiredSubscriptions, err := s.SubscriptionRepository.FindExpiredUserSubscriptions(now, s.BatchSize)
+	if err != nil {
+		return nil, errors.Internal("Failed to get expired subscriptions", err.Error())
+	}
+
+	for _, userSubscription := range expiredSubscriptions {
+		if ctx.Err() != nil {
+			break
+		}
+
+		expired, appError := s.expire(userSubscription.ID, now)
+		if appError != nil {
+			log.Printf("Failed to expire user subscription %d: %v", userSubscription.ID, appError.Details)
+			result.Failed++
+			continue
+		}
+
+		// The subscription was renewed or replaced since it was loaded
+		if !expired {
+			continue
+		}
+
+		result.Expired++
+	}
+
+	return result, nil
+}
+
+// Queue the reminders of subscriptions that are about to expire
+// Each subscription gets the reminder of the smallest number of days that is not less than the days it has left,
+// so a subscription bought with 3 days left is only reminded 1 day before it expires
+func (s *SubscriptionExpiryServiceInstance) sendReminders(ctx context.Context, now time.Time, result *dtos.SubscriptionExpiryResultDTO) *errors.CustomError {
+	for i, days := range s.ReminderDays {
+		from := now
+		if i+1 < len(s.ReminderDays) {
+			from = now.AddDate(0, 0, s.ReminderDays[i+1])
+		}
+
+		expiringSubscriptions, err := s.SubscriptionRepository.FindExpiringUserSubscriptions(from, now.AddDate(0, 0, days), s.BatchSize)
+		if err != nil {
+			return errors.Internal("Failed to get expiring subscriptions", err.Error())
+		}
+
+		for _, userSubscription := range expiringSubscriptions {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			stored, err := s.SubscriptionRepository.StoreExpiryReminder(&models.SubscriptionExpiryReminder{
+				UserSubscriptionID: userSubscription.ID,
+				ExpiredAt:          userSubscription.ExpiredAt,
+				DaysBefore:         days,
+			})
+			if err != nil {
+				log.Printf("Failed to store expiry reminder of user subscription %d: %v", userSubscription.ID, err)
+				result.Failed++
+				continue
+			}
+
+			// The reminder was already sent by a previous run
+			if !stored {
+				continue
+			}
+
+			if appError := s.queueExpiryReminder(userSubscription, days); appError != nil {
+				log.Printf("Failed to queue expiry reminder of user subscription %d: %v", userSubscription.ID, appError.Details)
+				result.Failed++
+				continue
+			}
+
+			result.Reminded++
+		}
+	}
+
+	return nil
+}
+
+// Queue the reminder email of a user subscription
+func (s *SubscriptionExpiryServiceInstance) queueExpiryReminder(userSubscription *models.UserSubscription, days int) *errors.CustomError {
+	job := s.QueueService.NewJobBuilder(TaskSendExpiryReminder).
+		WithPayload(map[string]interface{}{
+			"user_subscription_id": userSubscription.ID,
+			"expired_at":           userSubscription.ExpiredAt.Format(time.RFC3339Nano),
+			"days_before":          days,
+		}).
+		WithPriority(queue.PriorityLow).
+		WithMaxRetry(5).
+		WithTimeout(time.Minute)
+
+	if _, err := job.Enqueue(context.Background()); err != nil {
+		return errors.Internal("Failed to queue expiry reminder", err.Error())
+	}
+
+	return nil
+}
+
+// Deactivate an expired user subscription and record the expiry in its history
+// A trial that was not paid for is recorded as ended instead of expired.
+// The subscription is locked first, so a renewal settled in the meantime is never undone.
+// A downgrade scheduled after the subscription is activated in the same transaction, otherwise the user
+// is moved back to the free tier, so a failure leaves the subscription active for the next run to retry.
+// Without a free tier the subscription is still deactivated and the user is left without a subscription
+// It returns false when the subscription is not active or not expired anymore
+func (s *SubscriptionExpiryServiceInstance) expire(userSubscriptionID uint32, now time.Time) (bool, *errors.CustomError) {
+	expired := false
+
+	if err := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		subscriptionRepository := s.SubscriptionRepository.WithTx(tx)
+
+		userSubscription, err := subscriptionRepository.FindUserSubscriptionForUpdate(userSubscriptionID)
+		if err != nil {
+			return err
+		}
+
+		if !userSubscription.IsActive || userSubscription.ExpiredAt.After(now) {
+			return nil
+		}
+
+		scheduled, err := subscriptionRepository.FindScheduledUserSubscription(userSubscription.UserID)
+		if err == gorm.ErrRecordNotFound {
+			scheduled = nil
+		} else if err != nil {
+			return err
+		}
+
+		userSubscription.IsActive = false
+		if err := subscriptionRepository.UpdateUserSubscription(userSubscription); err != nil {
+			return err
+		}
+
+		event := models.SubscriptionEventExpired
+		if userSubscription.IsTrial() {
+			event = models.SubscriptionEventTrialEnded
+		}
+
+		if err := subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+			UserID:             userSubscription.UserID,
+			UserSubscriptionID: userSubscription.ID,
+			SubscriptionID:     userSubscription.SubID,
+			Event:              event,
+			StartedAt:          userSubscription.StartedAt,
+			ExpiredAt:          userSubscription.ExpiredAt,
+		}); err != nil {
+			return err
+		}
+
+		expired = true
+
+		if scheduled == nil {
+			return fallBackToFreeTier(subscriptionRepository, userSubscription.UserID, now)
+		}
+
+		scheduled.IsScheduled = false
+		scheduled.IsActive = true
+		if err := subscriptionRepository.UpdateUserSubscription(scheduled); err != nil {
+			return err
+		}
+
+		return subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+			UserID:             scheduled.UserID,
+			UserSubscriptionID: scheduled.ID,
+			SubscriptionID:     scheduled.SubID,
+			Event:              models.SubscriptionEventDowngraded,
+			StartedAt:          scheduled.StartedAt,
+			ExpiredAt:          scheduled.ExpiredAt,
+		})
+	}); err != nil {
+		return false, errors.Internal("Failed to expire user subscription", err.Error())
+	}
+
+	return expired, nil
+}
+
+// Handle the periodic expiry task
+// It returns an error so the task is retried when the subscriptions cannot be loaded
+func (s *SubscriptionExpiryServiceInstance) HandleProcessSubscriptionExpiries(ctx context.Context, task *asynq.Task) error {
+	result, err := s.ProcessSubscriptionExpiries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to process subscription expiries: %s", err.Error())
+	}
+
+	log.Printf(
+		"Processed subscription expiries: reminded=%d expired=%d failed=%d",
+		result.Reminded, result.Expired, result.Failed,
+	)
+
+	return nil
+}
+
+// Handle the expiry reminder email task
+// The reminder is skipped when the subscription was renewed or deactivated after it was queued
+// It returns an error so the task is retried when the email could not be sent
+func (s *SubscriptionExpiryServiceInstance) HandleSendExpiryReminder(ctx context.Context, task *asynq.Task) error {
+	var payload struct {
+		UserSubscriptionID uint32    `json:"user_subscription_id"`
+		ExpiredAt          time.Time `json:"expired_at"`
+		DaysBefore         int       `json:"days_before"`
+	}
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal expiry reminder payload: %w", err)
+	}
+
+	userSubscription, err := s.SubscriptionRepository.FindUserSubscriptionByID(payload.UserSubscriptionID)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user subscription %d: %w", payload.UserSubscriptionID, err)
+	}
+
+	if !userSubscription.IsActive || !userSubscription.ExpiredAt.Equal(payload.ExpiredAt) {
+		return nil
+	}
+
+	mailerService, err := mailer.NewMailerService()
+	if err != nil {
+		return fmt.Errorf("failed to initialize mailer service: %w", err)
+	}
+
+	template, subject, templateData := expiryReminderEmail(userSubscription, payload.DaysBefore)
+	if !mailerService.TemplateExists(template) {
+		return fmt.Errorf("email template not found: %s", template)
+	}
+
+	if err := mailerService.SendTemplate(
+		userSubscription.User.Email,
+		subject,
+		template,
+		templateData,
+	); err != nil {
+		return fmt.Errorf("failed to send expiry reminder to %s: %w", userSubscription.User.Email, err)
+	}
+
+	log.Printf("Successfully sent expiry reminder of user subscription %d to %s", userSubscription.ID, userSubscription.User.Email)
+	return nil
+}
+
+// Build the reminder email of a user subscription
+// A trial is reminded that it ends and sends the user to subscribe, a paid subscription that it expires and sends the user to renew
+// It returns the template, the subject and the data of the email
+func expiryReminderEmail(userSubscription *models.UserSubscription, daysBefore int) (string, string, map[string]interface{}) {
+	frontendURL := config.GetEnv("APP_FE_URL", "http://localhost:5173")
+
+	templateData := map[string]interface{}{
+		"UserName":         userSubscription.User.Name,
+		"SubscriptionName": userSubscription.Sub.Name,
+		"DaysBefore":       daysBefore,
+		"ExpiredAt":        userSubscription.ExpiredAt.Format("02 January 2006"),
+		"Price":            userSubscription.Sub.Price.Format(),
+		"SupportEmail":     config.GetEnv("SUPPORT_EMAIL", "support@catalyst.com"),
+	}
+
+	if userSubscription.IsTrial() {
+		templateData["SubscribeLink"] = fmt.Sprintf("%s/subscription/subscribe/%d", frontendURL, userSubscription.SubID)
+
+		subject := fmt.Sprintf("Catalyst - Your %s trial ends in %d days", userSubscription.Sub.Name, daysBefore)
+		if daysBefore == 1 {
+			subject = fmt.Sprintf("Catalyst - Your %s trial ends tomorrow", userSubscription.Sub.Name)
+		}
+
+		return trialEndingTemplate, subject, templateData
+	}
+
+	templateData["RenewLink"] = frontendURL + "/subscription/renew"
+
+	subject := fmt.Sprintf("Catalyst - Your %s subscription expires in %d days", userSubscription.Sub.Name, daysBefore)
+	if daysBefore == 1 {
+		subject = fmt.Sprintf("Catalyst - Your %s subscription expires tomorrow", userSubscription.Sub.Name)
+	}
+
+	return expiryReminderTemplate, subject, templateData
+}
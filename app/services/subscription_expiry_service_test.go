@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestProcessSubscriptionExpiries(t *testing.T) {
+	t.Run("Should deactivate expired subscriptions and move their users to the free tier", func(t *testing.T) {
+		now := time.Now()
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 2, StartedAt: now.AddDate(0, 0, -30), ExpiredAt: now.Add(-time.Hour), IsActive: true},
+			&models.UserSubscription{ID: 2, UserID: 20, SubID: 2, StartedAt: now.AddDate(0, 0, -10), ExpiredAt: now.AddDate(0, 0, 20), IsActive: true},
+		)
+		service := &SubscriptionExpiryServiceInstance{
+			SubscriptionRepository: repository,
+			TransactionManager:     &fakeTransactionManager{},
+			BatchSize:              100,
+		}
+
+		result, err := service.ProcessSubscriptionExpiries(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Expired != 1 {
+			t.Errorf("Expected 1 expired, got %d", result.Expired)
+		}
+
+		if result.Failed != 0 {
+			t.Errorf("Expected 0 failed, got %d", result.Failed)
+		}
+
+		if repository.userSubscriptions[0].IsActive {
+			t.Errorf("Expected false, got %t", repository.userSubscriptions[0].IsActive)
+		}
+
+		if !repository.userSubscriptions[1].IsActive {
+			t.Errorf("Expected true, got %t", repository.userSubscriptions[1].IsActive)
+		}
+
+		if len(repository.userSubscriptions) != 3 {
+			t.Fatalf("Expected 3 user subscriptions, got %d", len(repository.userSubscriptions))
+		}
+
+		freeTier := repository.userSubscriptions[2]
+		if freeTier.UserID != 10 {
+			t.Errorf("Expected 10, got %d", freeTier.UserID)
+		}
+
+		if freeTier.SubID != 1 {
+			t.Errorf("Expected 1, got %d", freeTier.SubID)
+		}
+
+		if !freeTier.IsActive {
+			t.Errorf("Expected true, got %t", freeTier.IsActive)
+		}
+
+		if len(repository.histories) != 1 {
+			t.Fatalf("Expected 1 history entry, got %d", len(repository.histories))
+		}
+
+		if repository.histories[0].Event != models.SubscriptionEventExpired {
+			t.Errorf("Expected %s, got %s", models.SubscriptionEventExpired, repository.histories[0].Event)
+		}
+
+		if repository.histories[0].UserSubscriptionID != 1 {
+			t.Errorf("Expected 1, got %d", repository.histories[0].UserSubscriptionID)
+		}
+	})
+
+	t.Run("Should move users with a scheduled downgrade to the scheduled subscription", func(t *testing.T) {
+		now := time.Now()
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 3, ExpiredAt: now.Add(-time.Hour), IsActive: true},
+			&models.UserSubscription{ID: 2, UserID: 10, SubID: 2, StartedAt: now.Add(-time.Hour), ExpiredAt: now.AddDate(0, 0, 28), IsScheduled: true},
+		)
+		service := &SubscriptionExpiryServiceInstance{
+			SubscriptionRepository: repository,
+			TransactionManager:     &fakeTransactionManager{},
+			BatchSize:              100,
+		}
+
+		result, err := service.ProcessSubscriptionExpiries(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Expired != 1 {
+			t.Errorf("Expected 1 expired, got %d", result.Expired)
+		}
+
+		if repository.userSubscriptions[0].IsActive {
+			t.Errorf("Expected false, got %t", repository.userSubscriptions[0].IsActive)
+		}
+
+		scheduled := repository.userSubscriptions[1]
+		if !scheduled.IsActive {
+			t.Errorf("Expected true, got %t", scheduled.IsActive)
+		}
+
+		if scheduled.IsScheduled {
+			t.Errorf("Expected false, got %t", scheduled.IsScheduled)
+		}
+
+		if len(repository.userSubscriptions) != 2 {
+			t.Errorf("Expected 2 user subscriptions, got %d", len(repository.userSubscriptions))
+		}
+
+		if len(repository.histories) != 2 {
+			t.Fatalf("Expected 2 history entries, got %d", len(repository.histories))
+		}
+
+		if repository.histories[1].Event != models.SubscriptionEventDowngraded {
+			t.Errorf("Expected %s, got %s", models.SubscriptionEventDowngraded, repository.histories[1].Event)
+		}
+	})
+
+	t.Run("Should leave a subscription renewed since it was loaded", func(t *testing.T) {
+		now := time.Now()
+		renewed := &models.UserSubscription{ID: 1, UserID: 10, SubID: 2, ExpiredAt: now.AddDate(0, 0, 30), IsActive: true}
+		repository := newFakeSubscriptionRepository(renewed)
+		repository.staleExpired = []*models.UserSubscription{
+			{ID: 1, UserID: 10, SubID: 2, ExpiredAt: now.Add(-time.Hour), IsActive: true},
+		}
+		service := &SubscriptionExpiryServiceInstance{
+			SubscriptionRepository: repository,
+			TransactionManager:     &fakeTransactionManager{},
+			BatchSize:              100,
+		}
+
+		result, err := service.ProcessSubscriptionExpiries(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Expired != 0 {
+			t.Errorf("Expected 0 expired, got %d", result.Expired)
+		}
+
+		if !renewed.IsActive {
+			t.Errorf("Expected true, got %t", renewed.IsActive)
+		}
+
+		if len(repository.userSubscriptions) != 1 {
+			t.Errorf("Expected 1 user subscription, got %d", len(repository.userSubscriptions))
+		}
+
+		if len(repository.histories) != 0 {
+			t.Errorf("Expected 0 history entries, got %d", len(repository.histories))
+		}
+	})
+
+	t.Run("Should deactivate an expired subscription without a free tier to move to", func(t *testing.T) {
+		now := time.Now()
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 2, StartedAt: now.AddDate(0, 0, -30), ExpiredAt: now.Add(-time.Hour), IsActive: true},
+		)
+		repository.freeTierErr = gorm.ErrRecordNotFound
+		service := &SubscriptionExpiryServiceInstance{
+			SubscriptionRepository: repository,
+			TransactionManager:     &fakeTransactionManager{},
+			BatchSize:              100,
+		}
+
+		result, err := service.ProcessSubscriptionExpiries(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Expired != 1 {
+			t.Errorf("Expected 1 expired, got %d", result.Expired)
+		}
+
+		if result.Failed != 0 {
+			t.Errorf("Expected 0 failed, got %d", result.Failed)
+		}
+
+		if repository.userSubscriptions[0].IsActive {
+			t.Errorf("Expected false, got %t", repository.userSubscriptions[0].IsActive)
+		}
+
+		if len(repository.userSubscriptions) != 1 {
+			t.Errorf("Expected 1 user subscription, got %d", len(repository.userSubscriptions))
+		}
+
+		if len(repository.histories) != 1 {
+			t.Fatalf("Expected 1 history entry, got %d", len(repository.histories))
+		}
+
+		if repository.histories[0].Event != models.SubscriptionEventExpired {
+			t.Errorf("Expected %s, got %s", models.SubscriptionEventExpired, repository.histories[0].Event)
+		}
+	})
+
+	t.Run("Should remind each subscription once in the window of its days left", func(t *testing.T) {
+		now := time.Now()
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 2, ExpiredAt: now.AddDate(0, 0, 5), IsActive: true},
+			&models.UserSubscription{ID: 2, UserID: 20, SubID: 2, ExpiredAt: now.Add(12 * time.Hour), IsActive: true},
+		)
+		service := &SubscriptionExpiryServiceInstance{
+			SubscriptionRepository: repository,
+			TransactionManager:     &fakeTransactionManager{},
+			ReminderDays:           []int{7, 1},
+			BatchSize:              100,
+		}
+
+		// The fake reports every reminder as already sent, so nothing is queued
+		result, err := service.ProcessSubscriptionExpiries(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Reminded != 0 {
+			t.Errorf("Expected 0 reminded, got %d", result.Reminded)
+		}
+
+		if result.Failed != 0 {
+			t.Errorf("Expected 0 failed, got %d", result.Failed)
+		}
+
+		if len(repository.reminders) != 2 {
+			t.Fatalf("Expected 2 reminders, got %d", len(repository.reminders))
+		}
+
+		if repository.reminders[0].UserSubscriptionID != 1 {
+			t.Errorf("Expected 1, got %d", repository.reminders[0].UserSubscriptionID)
+		}
+
+		if repository.reminders[0].DaysBefore != 7 {
+			t.Errorf("Expected 7, got %d", repository.reminders[0].DaysBefore)
+		}
+
+		if repository.reminders[1].UserSubscriptionID != 2 {
+			t.Errorf("Expected 2, got %d", repository.reminders[1].UserSubscriptionID)
+		}
+
+		if repository.reminders[1].DaysBefore != 1 {
+			t.Errorf("Expected 1, got %d", repository.reminders[1].DaysBefore)
+		}
+	})
+}
+
+func TestActivateRenewal(t *testing.T) {
+	t.Run("Should extend a renewed subscription from its current expiry", func(t *testing.T) {
+		expiredAt := time.Now().AddDate(0, 0, 3)
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 2, ExpiredAt: expiredAt, IsActive: true},
+		)
+		service := &SubscriptionOrderServiceInstance{}
+
+		renewsID := uint32(1)
+		order := &models.SubscriptionOrder{
+			UserID:             10,
+			SubscriptionID:     2,
+			Type:               models.OrderTypeRenewal,
+			UserSubscriptionID: &renewsID,
+			Amount:             money.FromMajor(100000, money.IDR),
+		}
+
+		if err := service.activateSubscription(repository, order); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		expected := expiredAt.AddDate(0, 0, 28)
+		if !repository.userSubscriptions[0].ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, repository.userSubscriptions[0].ExpiredAt)
+		}
+
+		if len(repository.histories) != 1 {
+			t.Fatalf("Expected 1 history entry, got %d", len(repository.histories))
+		}
+
+		if repository.histories[0].Event != models.SubscriptionEventRenewed {
+			t.Errorf("Expected %s, got %s", models.SubscriptionEventRenewed, repository.histories[0].Event)
+		}
+
+		if !repository.histories[0].StartedAt.Equal(expiredAt) {
+			t.Errorf("Expected %v, got %v", expiredAt, repository.histories[0].StartedAt)
+		}
+	})
+
+	t.Run("Should start a new period when the renewed subscription already expired", func(t *testing.T) {
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 2, ExpiredAt: time.Now().AddDate(0, 0, -1), IsActive: false},
+			&models.UserSubscription{ID: 2, UserID: 10, SubID: 1, ExpiredAt: time.Now().AddDate(100, 0, 0), IsActive: true},
+		)
+		service := &SubscriptionOrderServiceInstance{}
+
+		renewsID := uint32(1)
+		order := &models.SubscriptionOrder{
+			UserID:             10,
+			SubscriptionID:     2,
+			Type:               models.OrderTypeRenewal,
+			UserSubscriptionID: &renewsID,
+		}
+
+		if err := service.activateSubscription(repository, order); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(repository.userSubscriptions) != 3 {
+			t.Fatalf("Expected 3 user subscriptions, got %d", len(repository.userSubscriptions))
+		}
+
+		if repository.userSubscriptions[1].IsActive {
+			t.Errorf("Expected false, got %t", repository.userSubscriptions[1].IsActive)
+		}
+
+		started := repository.userSubscriptions[2]
+		if !started.IsActive {
+			t.Errorf("Expected true, got %t", started.IsActive)
+		}
+
+		if started.SubID != 2 {
+			t.Errorf("Expected 2, got %d", started.SubID)
+		}
+
+		if expected := started.StartedAt.AddDate(0, 0, 28); !started.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, started.ExpiredAt)
+		}
+	})
+}
+
+func TestActivatePlanChange(t *testing.T) {
+	t.Run("Should replace an upgraded subscription and keep its expiry", func(t *testing.T) {
+		expiredAt := time.Now().AddDate(0, 0, 14)
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 2, ExpiredAt: expiredAt, IsActive: true},
+		)
+		service := &SubscriptionOrderServiceInstance{}
+
+		userSubscriptionID := uint32(1)
+		order := &models.SubscriptionOrder{
+			UserID:             10,
+			SubscriptionID:     3,
+			Type:               models.OrderTypeUpgrade,
+			UserSubscriptionID: &userSubscriptionID,
+			CreatedAt:          time.Now(),
+		}
+
+		if err := service.activateSubscription(repository, order); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(repository.userSubscriptions) != 2 {
+			t.Fatalf("Expected 2 user subscriptions, got %d", len(repository.userSubscriptions))
+		}
+
+		if repository.userSubscriptions[0].IsActive {
+			t.Errorf("Expected false, got %t", repository.userSubscriptions[0].IsActive)
+		}
+
+		upgraded := repository.userSubscriptions[1]
+		if !upgraded.IsActive {
+			t.Errorf("Expected true, got %t", upgraded.IsActive)
+		}
+
+		if upgraded.SubID != 3 {
+			t.Errorf("Expected 3, got %d", upgraded.SubID)
+		}
+
+		if !upgraded.ExpiredAt.Equal(expiredAt) {
+			t.Errorf("Expected %v, got %v", expiredAt, upgraded.ExpiredAt)
+		}
+
+		if len(repository.histories) != 1 || repository.histories[0].Event != models.SubscriptionEventUpgraded {
+			t.Errorf("Expected [%s], got %v", models.SubscriptionEventUpgraded, historyEvents(repository.histories))
+		}
+	})
+
+	t.Run("Should schedule a downgrade after the current subscription", func(t *testing.T) {
+		expiredAt := time.Now().AddDate(0, 0, 10)
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 3, ExpiredAt: expiredAt, IsActive: true},
+		)
+		service := &SubscriptionOrderServiceInstance{}
+
+		userSubscriptionID := uint32(1)
+		order := &models.SubscriptionOrder{
+			UserID:             10,
+			SubscriptionID:     2,
+			Type:               models.OrderTypeDowngrade,
+			UserSubscriptionID: &userSubscriptionID,
+		}
+
+		if err := service.activateSubscription(repository, order); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(repository.userSubscriptions) != 2 {
+			t.Fatalf("Expected 2 user subscriptions, got %d", len(repository.userSubscriptions))
+		}
+
+		if !repository.userSubscriptions[0].IsActive {
+			t.Errorf("Expected true, got %t", repository.userSubscriptions[0].IsActive)
+		}
+
+		scheduled := repository.userSubscriptions[1]
+		if scheduled.IsActive {
+			t.Errorf("Expected false, got %t", scheduled.IsActive)
+		}
+
+		if !scheduled.IsScheduled {
+			t.Errorf("Expected true, got %t", scheduled.IsScheduled)
+		}
+
+		if !scheduled.StartedAt.Equal(expiredAt) {
+			t.Errorf("Expected %v, got %v", expiredAt, scheduled.StartedAt)
+		}
+
+		if expected := expiredAt.AddDate(0, 0, 28); !scheduled.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, scheduled.ExpiredAt)
+		}
+
+		if len(repository.histories) != 1 || repository.histories[0].Event != models.SubscriptionEventDowngradeScheduled {
+			t.Errorf("Expected [%s], got %v", models.SubscriptionEventDowngradeScheduled, historyEvents(repository.histories))
+		}
+	})
+}
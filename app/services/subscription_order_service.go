@@ -1,60 +1,1197 @@
 package services
 
 import (
+	"fmt"
+	"log"
+	"math"
 	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/integrations/midtrans"
 	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/config"
+	"senkou-catalyst-be/utils/money"
+	"senkou-catalyst-be/utils/query"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type SubscriptionOrderService interface {
-	CreateNewSubscriptionOrder(userID uint32, subID uint32, request *dtos.CreateSubscriptionOrderDTO) *errors.CustomError
-	GetOrderByUserAndSubscription(orderID uint32, userID uint32) (*models.SubscriptionOrder, *errors.CustomError)
+	PrepareOrderItems(subscription *models.Subscription, promoCode *models.PromoCode, paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError)
+	CreateNewSubscriptionOrder(userID uint32, subscription *models.Subscription, promoCode *models.PromoCode, transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError)
+	RedeemVoucher(userID uint32, subscription *models.Subscription, promoCode *models.PromoCode) (*models.SubscriptionOrder, *errors.CustomError)
+	GetRenewableSubscription(userID uint32) (*models.UserSubscription, *models.Subscription, *errors.CustomError)
+	CreateRenewalSubscriptionOrder(userSubscription *models.UserSubscription, subscription *models.Subscription, transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError)
+	PrepareChangeOrderItems(preview *dtos.SubscriptionChangePreviewDTO, paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError)
+	CreateChangeSubscriptionOrder(userID uint32, preview *dtos.SubscriptionChangePreviewDTO, transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError)
+	GetPendingUserOrder(userID uint32) (*models.SubscriptionOrder, *errors.CustomError)
 	UpdateSubscriptionOrder(orderID string, request *dtos.UpdateSubscriptionOrderDTO) *errors.CustomError
+	CancelSubscriptionOrder(userID uint32, orderID string) (*models.SubscriptionOrder, *errors.CustomError)
+	RefundPaymentTransaction(transactionID string, request *dtos.RefundPaymentDTO, refundedBy uint32) (*models.PaymentRefund, *errors.CustomError)
+	GetUserOrders(userID uint32, params *query.QueryParams) ([]*models.SubscriptionOrder, *query.PaginationResponse, *errors.CustomError)
+	GetUserOrder(userID uint32, orderID string) (*models.SubscriptionOrder, *errors.CustomError)
 }
 
 type SubscriptionOrderServiceInstance struct {
 	SubscriptionOrderRepository  repositories.SubscriptionOrderRepository
 	PaymentTransactionRepository repositories.PaymentTransactionRepository
+	PaymentRefundRepository      repositories.PaymentRefundRepository
+	SubscriptionRepository       repositories.SubscriptionRepository
+	PromoCodeRepository          repositories.PromoCodeRepository
+	ReferralRepository           repositories.ReferralRepository
+	TransactionManager           repositories.TransactionManager
+	PaymentService               PaymentService
+	InvoiceService               InvoiceService
 }
 
 func NewSubscriptionOrderService(
 	subscriptionOrderRepository repositories.SubscriptionOrderRepository,
+	paymentTransactionRepository repositories.PaymentTransactionRepository,
+	paymentRefundRepository repositories.PaymentRefundRepository,
+	subscriptionRepository repositories.SubscriptionRepository,
+	promoCodeRepository repositories.PromoCodeRepository,
+	referralRepository repositories.ReferralRepository,
+	transactionManager repositories.TransactionManager,
+	paymentService PaymentService,
+	invoiceService InvoiceService,
 ) SubscriptionOrderService {
 	return &SubscriptionOrderServiceInstance{
-		SubscriptionOrderRepository: subscriptionOrderRepository,
+		SubscriptionOrderRepository:  subscriptionOrderRepository,
+		PaymentTransactionRepository: paymentTransactionRepository,
+		PaymentRefundRepository:      paymentRefundRepository,
+		SubscriptionRepository:       subscriptionRepository,
+		PromoCodeRepository:          promoCodeRepository,
+		ReferralRepository:           referralRepository,
+		TransactionManager:           transactionManager,
+		PaymentService:               paymentService,
+		InvoiceService:               invoiceService,
 	}
 }
 
-func (s *SubscriptionOrderServiceInstance) CreateNewSubscriptionOrder(userID uint32, subID uint32, request *dtos.CreateSubscriptionOrderDTO) *errors.CustomError {
+// Prepare the line items of a subscription order
+// This function prices the order from the subscription itself and adds PPN as a separate line
+// The PPN percentage is read from PAYMENT_PPN_PERCENTAGE and amounts are rounded half away from zero to whole rupiah
+// The discount of the promo code, if any, is a negative line and PPN is charged on the discounted price.
+// The fee of the payment method, if any, is charged on top of the subtotal as another line
+// It returns the items or an error if the subscription cannot be purchased
+func (s *SubscriptionOrderServiceInstance) PrepareOrderItems(subscription *models.Subscription, promoCode *models.PromoCode, paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError) {
+	if subscription == nil {
+		return nil, errors.BadRequest("Subscription is required", nil)
+	}
 
-	orderID := uuid.New()
+	price := subscription.Price.RoundToMajor()
+	if !price.IsPositive() {
+		return nil, errors.BadRequest("Subscription cannot be purchased", "Free subscriptions do not require a payment")
+	}
+
+	if promoCode != nil && promoCode.IsVoucher() {
+		return nil, errors.BadRequest("Promo code is not valid", "A voucher is redeemed without a payment")
+	}
+
+	return prepareOrderItems(subscription.Name, price, promoCode, paymentMethod), nil
+}
+
+// Prepare the line items of a subscription change order
+// An upgrade charges the prorated difference of the remaining period, a downgrade charges the new subscription in full.
+// PPN and the fee of the payment method are added the same way as for any other order
+// It returns the items or an error if the change does not need a payment
+func (s *SubscriptionOrderServiceInstance) PrepareChangeOrderItems(preview *dtos.SubscriptionChangePreviewDTO, paymentMethod *midtrans.PaymentMethodConfig) ([]models.SubscriptionOrderItem, *errors.CustomError) {
+	if preview == nil || preview.CurrentSubscription == nil || preview.TargetSubscription == nil {
+		return nil, errors.BadRequest("Subscription change is required", nil)
+	}
+
+	if preview.Type != models.OrderTypeUpgrade {
+		return s.PrepareOrderItems(preview.TargetSubscription, nil, paymentMethod)
+	}
+
+	price := preview.Amount.RoundToMajor()
+	if !price.IsPositive() {
+		return nil, errors.BadRequest("Subscription cannot be changed", "The upgrade does not require a payment")
+	}
+
+	name := fmt.Sprintf("Upgrade from %s to %s (prorated)", preview.CurrentSubscription.Name, preview.TargetSubscription.Name)
+
+	return prepareOrderItems(name, price, nil, paymentMethod), nil
+}
+
+// Build the line items of an order charging a whole rupiah price
+// The discount of the promo code, if any, is subtracted from the price as a negative line.
+// PPN is added as a separate line and the fee of the payment method, if any, is charged on top of the subtotal
+func prepareOrderItems(name string, price money.Money, promoCode *models.PromoCode, paymentMethod *midtrans.PaymentMethodConfig) []models.SubscriptionOrderItem {
+	items := []models.SubscriptionOrderItem{
+		{
+			Type:     models.OrderItemTypeSubscription,
+			Name:     name,
+			Quantity: 1,
+			Price:    price,
+			Amount:   price,
+		},
+	}
+
+	taxable := price
+	if promoCode != nil {
+		if discount := promoCode.Discount(price); discount.IsPositive() {
+			items = append(items, models.SubscriptionOrderItem{
+				Type:     models.OrderItemTypeDiscount,
+				Name:     fmt.Sprintf("Promo code %s", promoCode.Code),
+				Quantity: 1,
+				Price:    money.Zero(price.CurrencyCode()).Sub(discount),
+				Amount:   money.Zero(price.CurrencyCode()).Sub(discount),
+			})
+
+			taxable = price.Sub(discount)
+		}
+	}
+
+	ppnPercentage := config.GetEnvAsInt("PAYMENT_PPN_PERCENTAGE", 11)
+	if ppn := taxable.Percent(float64(ppnPercentage)).RoundToMajor(); ppn.IsPositive() {
+		items = append(items, models.SubscriptionOrderItem{
+			Type:     models.OrderItemTypeTax,
+			Name:     fmt.Sprintf("PPN %d%%", ppnPercentage),
+			Quantity: 1,
+			Price:    ppn,
+			Amount:   ppn,
+		})
+	}
+
+	if paymentMethod != nil {
+		subtotal := money.Zero(price.CurrencyCode())
+		for _, item := range items {
+			subtotal = subtotal.Add(item.Amount)
+		}
+
+		if fee := paymentMethod.CalculateFee(subtotal); fee.IsPositive() {
+			items = append(items, models.SubscriptionOrderItem{
+				Type:     models.OrderItemTypeFee,
+				Name:     fmt.Sprintf("%s fee", paymentMethod.Name),
+				Quantity: 1,
+				Price:    fee,
+				Amount:   fee,
+			})
+		}
+	}
+
+	return items
+}
+
+// Create a new subscription order
+// This function stores an order and its items linked to the payment transaction that was charged for it.
+// An order placed with a promo code redeems it, the code is locked and its limits are checked again
+// so concurrent orders cannot redeem it more often than allowed
+// It returns the created order or an error if the order could not be stored
+func (s *SubscriptionOrderServiceInstance) CreateNewSubscriptionOrder(userID uint32, subscription *models.Subscription, promoCode *models.PromoCode, transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError) {
+	if subscription == nil {
+		return nil, errors.BadRequest("Subscription is required", nil)
+	}
 
 	newOrder := &models.SubscriptionOrder{
-		ID:             orderID,
 		UserID:         userID,
-		SubscriptionID: subID,
+		SubscriptionID: subscription.ID,
+		Type:           models.OrderTypeNew,
 	}
 
-	if err := s.SubscriptionOrderRepository.StoreNewSubscriptionOrder(newOrder); err != nil {
-		return errors.Internal("Failed to create subscription order", err.Error())
+	if promoCode == nil {
+		return s.storeSubscriptionOrder(s.SubscriptionOrderRepository, newOrder, transaction, items)
 	}
 
-	return nil
+	newOrder.PromoCodeID = &promoCode.ID
+	newOrder.Discount = money.Zero(subscription.Price.CurrencyCode())
+	for _, item := range items {
+		if item.Type == models.OrderItemTypeDiscount {
+			newOrder.Discount = newOrder.Discount.Sub(item.Amount)
+		}
+	}
+
+	var appError *errors.CustomError
+	var order *models.SubscriptionOrder
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		promoCodeRepository := s.PromoCodeRepository.WithTx(tx)
+
+		if appError = s.lockPromoCode(promoCodeRepository, promoCode.ID, userID, subscription); appError != nil {
+			return fmt.Errorf("promo code %s cannot be redeemed", promoCode.Code)
+		}
+
+		order, appError = s.storeSubscriptionOrder(s.SubscriptionOrderRepository.WithTx(tx), newOrder, transaction, items)
+		if appError != nil {
+			return fmt.Errorf("failed to store order")
+		}
+
+		return nil
+	})
+
+	if appError != nil {
+		return nil, appError
+	}
+
+	if txErr != nil {
+		return nil, errors.Internal("Failed to create subscription order", txErr.Error())
+	}
+
+	return order, nil
+}
+
+// Redeem a voucher for a subscription
+// A voucher order is settled right away without a payment and grants the days of the voucher,
+// extending the subscription when the user already has it or replacing the current subscription otherwise
+// It returns the settled order or an error if the voucher cannot be redeemed
+func (s *SubscriptionOrderServiceInstance) RedeemVoucher(userID uint32, subscription *models.Subscription, promoCode *models.PromoCode) (*models.SubscriptionOrder, *errors.CustomError) {
+	if subscription == nil || promoCode == nil {
+		return nil, errors.BadRequest("Voucher is required", nil)
+	}
+
+	if !promoCode.IsVoucher() {
+		return nil, errors.BadRequest("Promo code is not valid", "The promo code is not a voucher")
+	}
+
+	var appError *errors.CustomError
+
+	order := &models.SubscriptionOrder{
+		ID:             uuid.New(),
+		UserID:         userID,
+		SubscriptionID: subscription.ID,
+		Type:           models.OrderTypeVoucher,
+		Amount:         money.Zero(subscription.Price.CurrencyCode()),
+		PromoCodeID:    &promoCode.ID,
+		Discount:       subscription.Price.RoundToMajor(),
+		GrantedDays:    promoCode.GrantedDays,
+		Status:         string(midtrans.PaymentStatusSettled),
+		Subscription:   subscription,
+	}
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		if appError = s.lockPromoCode(s.PromoCodeRepository.WithTx(tx), promoCode.ID, userID, subscription); appError != nil {
+			return fmt.Errorf("voucher %s cannot be redeemed", promoCode.Code)
+		}
+
+		if err := s.SubscriptionOrderRepository.WithTx(tx).StoreNewSubscriptionOrder(order); err != nil {
+			appError = errors.Internal("Failed to redeem voucher", err.Error())
+			return err
+		}
+
+		if err := s.activateSubscription(s.SubscriptionRepository.WithTx(tx), order); err != nil {
+			appError = errors.Internal("Failed to activate user subscription", err.Error())
+			return err
+		}
+
+		return nil
+	})
+
+	if appError != nil {
+		return nil, appError
+	}
+
+	if txErr != nil {
+		return nil, errors.Internal("Failed to redeem voucher", txErr.Error())
+	}
+
+	return order, nil
 }
 
-func (s *SubscriptionOrderServiceInstance) GetOrderByUserAndSubscription(orderID uint32, userID uint32) (*models.SubscriptionOrder, *errors.CustomError) {
-	subscriptionOrder, err := s.SubscriptionOrderRepository.FindOrderByUserAndSubscription(userID, orderID)
+// Lock a promo code until the end of the transaction and check the user can still redeem it
+func (s *SubscriptionOrderServiceInstance) lockPromoCode(promoCodeRepository repositories.PromoCodeRepository, promoCodeID uint32, userID uint32, subscription *models.Subscription) *errors.CustomError {
+	promoCode, err := promoCodeRepository.FindByIDForUpdate(promoCodeID)
+	if err == gorm.ErrRecordNotFound {
+		return errors.BadRequest("Promo code is not valid", "The promo code does not exist")
+	}
+	if err != nil {
+		return errors.Internal("Failed to get promo code", err.Error())
+	}
+
+	return verifyPromoCodeRedeemable(promoCodeRepository, promoCode, userID, subscription, time.Now())
+}
+
+// Get the subscription of a user that can be renewed
+// Only the active paid subscription of a user can be renewed, the free tier never expires.
+// A subscription that was replaced by a newer version is renewed onto the version that is on offer
+// It returns the user subscription and the subscription the renewal is for, or an error if there is nothing to renew
+func (s *SubscriptionOrderServiceInstance) GetRenewableSubscription(userID uint32) (*models.UserSubscription, *models.Subscription, *errors.CustomError) {
+	userSubscription, err := s.SubscriptionRepository.FindActiveUserSubscription(userID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, errors.NotFound("Active subscription not found")
+	}
 	if err != nil {
+		return nil, nil, errors.Internal("Failed to get active subscription", err.Error())
+	}
+
+	if !userSubscription.Sub.Price.IsPositive() {
+		return nil, nil, errors.BadRequest("Subscription cannot be renewed", "Free subscriptions do not need to be renewed")
+	}
+
+	if userSubscription.IsTrial() {
+		return nil, nil, errors.BadRequest("Subscription cannot be renewed", "Subscribe to the subscription to keep it after the trial")
+	}
+
+	if _, err := s.SubscriptionRepository.FindScheduledUserSubscription(userID); err == nil {
+		return nil, nil, errors.BadRequest("Subscription cannot be renewed", "The subscription changes to the scheduled subscription when it expires")
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, nil, errors.Internal("Failed to get scheduled subscription", err.Error())
+	}
+
+	subscription := &userSubscription.Sub
+	if subscription.IsRetired() {
+		latest, err := s.SubscriptionRepository.FindCurrentVersion(subscription.Family())
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, errors.BadRequest("Subscription cannot be renewed", "The subscription is no longer offered")
+		}
+		if err != nil {
+			return nil, nil, errors.Internal("Failed to get latest subscription version", err.Error())
+		}
+
+		if !latest.Price.IsPositive() {
+			return nil, nil, errors.BadRequest("Subscription cannot be renewed", "The subscription is no longer offered")
+		}
+
+		subscription = latest
+	}
+
+	return userSubscription, subscription, nil
+}
+
+// Create a renewal order of a user subscription
+// Once settled, the renewal extends the user subscription from its current expiry instead of from the payment date.
+// subscription is the version the user subscription is renewed onto
+// It returns the created order or an error if the order could not be stored
+func (s *SubscriptionOrderServiceInstance) CreateRenewalSubscriptionOrder(userSubscription *models.UserSubscription, subscription *models.Subscription, transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError) {
+	if userSubscription == nil || subscription == nil {
+		return nil, errors.BadRequest("User subscription is required", nil)
+	}
+
+	return s.storeSubscriptionOrder(s.SubscriptionOrderRepository, &models.SubscriptionOrder{
+		UserID:             userSubscription.UserID,
+		SubscriptionID:     subscription.ID,
+		Type:               models.OrderTypeRenewal,
+		UserSubscriptionID: &userSubscription.ID,
+	}, transaction, items)
+}
+
+// Create the order of a subscription change
+// Once settled, an upgrade replaces the current user subscription right away while a downgrade
+// is scheduled to start when the current user subscription expires
+// It returns the created order or an error if the order could not be stored
+func (s *SubscriptionOrderServiceInstance) CreateChangeSubscriptionOrder(userID uint32, preview *dtos.SubscriptionChangePreviewDTO, transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError) {
+	if preview == nil || preview.TargetSubscription == nil {
+		return nil, errors.BadRequest("Subscription change is required", nil)
+	}
+
+	userSubscriptionID := preview.UserSubscriptionID
+
+	return s.storeSubscriptionOrder(s.SubscriptionOrderRepository, &models.SubscriptionOrder{
+		UserID:             userID,
+		SubscriptionID:     preview.TargetSubscription.ID,
+		Type:               preview.Type,
+		UserSubscriptionID: &userSubscriptionID,
+	}, transaction, items)
+}
+
+// Store a pending order and its items linked to the payment transaction that was charged for it
+func (s *SubscriptionOrderServiceInstance) storeSubscriptionOrder(orderRepository repositories.SubscriptionOrderRepository, newOrder *models.SubscriptionOrder, transaction *models.PaymentTransaction, items []models.SubscriptionOrderItem) (*models.SubscriptionOrder, *errors.CustomError) {
+	if transaction == nil {
+		return nil, errors.BadRequest("Payment transaction is required", nil)
+	}
+
+	orderID := uuid.New()
+
+	for i := range items {
+		items[i].ID = uuid.New()
+		items[i].OrderID = orderID
+	}
+
+	newOrder.ID = orderID
+	newOrder.Items = items
+	newOrder.PaymentTransactionID = &transaction.ID
+	newOrder.Amount = transaction.Amount
+	newOrder.Status = string(midtrans.PaymentStatusPending)
+
+	if err := orderRepository.StoreNewSubscriptionOrder(newOrder); err != nil {
+		return nil, errors.Internal("Failed to create subscription order", err.Error())
+	}
+
+	return newOrder, nil
+}
+
+// Get the pending order of a user
+// A user has at most one pending order at a time, whatever subscription it is for
+// It returns the order or a not found error if the user has no pending order
+func (s *SubscriptionOrderServiceInstance) GetPendingUserOrder(userID uint32) (*models.SubscriptionOrder, *errors.CustomError) {
+	subscriptionOrder, err := s.SubscriptionOrderRepository.FindPendingOrderByUser(userID)
+	if err == gorm.ErrRecordNotFound {
 		return nil, errors.NotFound("Subscription order not found")
 	}
+	if err != nil {
+		return nil, errors.Internal("Failed to get subscription order", err.Error())
+	}
 
 	return subscriptionOrder, nil
 }
 
+// Build the order update of a normalized payment update
+// It is used for both payment notifications and status checks of every payment gateway
+func NewUpdateSubscriptionOrderDTO(update *gateway.PaymentUpdate) *dtos.UpdateSubscriptionOrderDTO {
+	status := string(update.Status)
+
+	request := &dtos.UpdateSubscriptionOrderDTO{
+		OrderID:           update.OrderID,
+		FraudStatus:       update.FraudStatus,
+		TransactionStatus: &status,
+		TransactionTime:   update.TransactionTime,
+		SettlementTime:    update.SettlementTime,
+		ExpiryTime:        update.ExpiryTime,
+	}
+
+	if update.TransactionID != "" {
+		request.TransactionID = &update.TransactionID
+	}
+
+	if update.SignatureKey != "" {
+		request.SignatureKey = &update.SignatureKey
+	}
+
+	return request
+}
+
+// Update a subscription order from a payment notification
+// This function updates the payment transaction, transitions the order and activates
+// or extends the user subscription when the payment is settled, all in one database transaction.
+// A settled payment also rewards the referrer of a user on their first paid order
+// The payment status only moves forward, an update that would move it backward is refused with a conflict
+// The orderID is the order ID sent to the payment gateway, which is the payment transaction ID
+// It returns an error if any step fails, in which case nothing is persisted
 func (s *SubscriptionOrderServiceInstance) UpdateSubscriptionOrder(orderID string, request *dtos.UpdateSubscriptionOrderDTO) *errors.CustomError {
+	if request == nil || request.TransactionStatus == nil {
+		return errors.BadRequest("Transaction status is required", nil)
+	}
+
+	status := midtrans.ParseStatus(*request.TransactionStatus)
+
+	var appError *errors.CustomError
+	var settledOrderID string
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		transactionRepository := s.PaymentTransactionRepository.WithTx(tx)
+		orderRepository := s.SubscriptionOrderRepository.WithTx(tx)
+		subscriptionRepository := s.SubscriptionRepository.WithTx(tx)
+
+		transaction, err := transactionRepository.FindByOrderIDForUpdate(orderID)
+		if err != nil {
+			appError = errors.NotFound("Transaction not found")
+			return err
+		}
+
+		// Notifications can arrive late or out of order, never move a payment backward
+		currentStatus := midtrans.ParseStatus(transaction.Status)
+		if !currentStatus.CanTransitionTo(status) {
+			appError = errors.Conflict("Payment status cannot change", map[string]any{
+				"current_status":  currentStatus,
+				"received_status": status,
+			})
+			return fmt.Errorf("illegal payment status transition from %s to %s", currentStatus, status)
+		}
+
+		transaction.Status = string(status)
+		if request.FraudStatus != "" {
+			transaction.FraudStatus = request.FraudStatus
+		}
+		if request.TransactionID != nil {
+			transaction.TransactionID = request.TransactionID
+		}
+		if request.SignatureKey != nil {
+			transaction.SignatureKey = request.SignatureKey
+		}
+		if request.TransactionTime != nil {
+			transaction.TransactionTime = request.TransactionTime
+		}
+		if request.SettlementTime != nil {
+			transaction.SettledAt = request.SettlementTime
+		}
+		if request.ExpiryTime != nil {
+			transaction.ExpiredAt = request.ExpiryTime
+		}
+
+		if err := transactionRepository.Update(transaction); err != nil {
+			appError = errors.Internal("Failed to update transaction", err.Error())
+			return err
+		}
+
+		order, err := orderRepository.FindByPaymentTransactionID(orderID)
+		if err == gorm.ErrRecordNotFound {
+			// The payment is not tied to an order, keep the transaction update only
+			return nil
+		}
+		if err != nil {
+			appError = errors.Internal("Failed to get subscription order", err.Error())
+			return err
+		}
+
+		// Only pending orders can move forward, repeated notifications are ignored
+		if order.Status != string(midtrans.PaymentStatusPending) {
+			return nil
+		}
+
+		switch status {
+		case midtrans.PaymentStatusSettled:
+			if err := s.activateSubscription(subscriptionRepository, order); err != nil {
+				appError = errors.Internal("Failed to activate user subscription", err.Error())
+				return err
+			}
+
+			// The first paid order of a referred user rewards their referrer
+			if s.ReferralRepository != nil && order.Amount.IsPositive() {
+				if err := rewardReferrer(s.ReferralRepository.WithTx(tx), subscriptionRepository, order, time.Now()); err != nil {
+					appError = errors.Internal("Failed to reward referrer", err.Error())
+					return err
+				}
+			}
+
+			order.Status = string(midtrans.PaymentStatusSettled)
+			settledOrderID = order.ID.String()
+		case midtrans.PaymentStatusExpired, midtrans.PaymentStatusDenied, midtrans.PaymentStatusCanceled, midtrans.PaymentStatusFailed:
+			order.Status = string(midtrans.PaymentStatusFailed)
+		default:
+			return nil
+		}
+
+		if err := orderRepository.UpdateOrderTransaction(order.ID.String(), &models.SubscriptionOrder{
+			Status: order.Status,
+		}); err != nil {
+			appError = errors.Internal("Failed to update subscription order", err.Error())
+			return err
+		}
+
+		return nil
+	})
+
+	if appError != nil {
+		return appError
+	}
+
+	if txErr != nil {
+		return errors.Internal("Failed to update subscription order", txErr.Error())
+	}
+
+	// The payment is already applied, a missing invoice can still be downloaded later
+	if settledOrderID != "" && s.InvoiceService != nil {
+		if appError := s.InvoiceService.QueueOrderInvoice(settledOrderID); appError != nil {
+			log.Printf("Failed to queue invoice of order %s: %v", settledOrderID, appError.Details)
+		}
+	}
+
+	return nil
+}
+
+// Activate or extend the user subscription of a settled order
+// A renewal extends the renewed subscription from its current expiry as long as it is still active.
+// An upgrade replaces the changed subscription and keeps its expiry, a downgrade is scheduled after it.
+// When the user already has the same subscription active, the expiry is extended from
+// the later of now and the current expiry. Otherwise every active subscription of the user
+// is deactivated and a new one starts now
+func (s *SubscriptionOrderServiceInstance) activateSubscription(subscriptionRepository repositories.SubscriptionRepository, order *models.SubscriptionOrder) error {
+	if order.Subscription == nil {
+		subscription, err := subscriptionRepository.FindByID(order.SubscriptionID)
+		if err != nil {
+			return err
+		}
+
+		order.Subscription = subscription
+	}
+
+	if order.Subscription.Duration <= 0 {
+		return fmt.Errorf("subscription %d has an invalid duration", order.SubscriptionID)
+	}
+
+	now := time.Now()
+	duration := int(order.Subscription.Duration)
+	if order.GrantedDays > 0 {
+		duration = int(order.GrantedDays)
+	}
+
+	if order.UserSubscriptionID != nil {
+		var applied bool
+		var err error
+
+		switch order.Type {
+		case models.OrderTypeRenewal:
+			applied, err = s.renewSubscription(subscriptionRepository, order, duration)
+		case models.OrderTypeUpgrade:
+			applied, err = s.upgradeSubscription(subscriptionRepository, order)
+		case models.OrderTypeDowngrade:
+			applied, err = s.scheduleDowngrade(subscriptionRepository, order, duration)
+		}
+
+		if err != nil || applied {
+			return err
+		}
+	}
+
+	activeSubscription, err := subscriptionRepository.FindActiveUserSubscription(order.UserID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if activeSubscription != nil && activeSubscription.SubID == order.SubscriptionID {
+		startFrom := now
+		if activeSubscription.ExpiredAt.After(now) {
+			startFrom = activeSubscription.ExpiredAt
+		}
+
+		// Paying for the subscription on trial converts the trial and keeps its remaining days
+		converted := activeSubscription.IsTrial()
+		if converted {
+			activeSubscription.TrialConvertedAt = &now
+		}
+
+		activeSubscription.ExpiredAt = startFrom.AddDate(0, 0, duration)
+		activeSubscription.PaymentStatus = string(midtrans.PaymentStatusSettled)
+
+		if err := subscriptionRepository.UpdateUserSubscription(activeSubscription); err != nil {
+			return err
+		}
+
+		if !converted {
+			return nil
+		}
+
+		return subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+			UserID:             activeSubscription.UserID,
+			UserSubscriptionID: activeSubscription.ID,
+			SubscriptionID:     activeSubscription.SubID,
+			OrderID:            &order.ID,
+			Event:              models.SubscriptionEventTrialConverted,
+			StartedAt:          startFrom,
+			ExpiredAt:          activeSubscription.ExpiredAt,
+		})
+	}
+
+	if err := subscriptionRepository.DeactivateUserSubscriptions(order.UserID); err != nil {
+		return err
+	}
+
+	return subscriptionRepository.SubscribeUser(&models.UserSubscription{
+		UserID:        order.UserID,
+		SubID:         order.SubscriptionID,
+		StartedAt:     now,
+		ExpiredAt:     now.AddDate(0, 0, duration),
+		IsActive:      true,
+		PaymentStatus: string(midtrans.PaymentStatusSettled),
+	})
+}
+
+// Extend the user subscription renewed by a settled order
+// The subscription is extended from its current expiry, even when the renewal is paid before or after it.
+// A renewal onto a newer version of the subscription moves the user subscription to that version
+// It returns false when the subscription was already deactivated, in which case the order starts a new period
+func (s *SubscriptionOrderServiceInstance) renewSubscription(subscriptionRepository repositories.SubscriptionRepository, order *models.SubscriptionOrder, duration int) (bool, error) {
+	userSubscription, err := subscriptionRepository.FindUserSubscriptionForUpdate(*order.UserSubscriptionID)
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !userSubscription.IsActive || userSubscription.UserID != order.UserID {
+		return false, nil
+	}
+
+	if userSubscription.SubID != order.SubscriptionID {
+		renewed, err := subscriptionRepository.FindByID(userSubscription.SubID)
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if renewed.Family() != order.Subscription.Family() {
+			return false, nil
+		}
+
+		userSubscription.SubID = order.SubscriptionID
+	}
+
+	startFrom := userSubscription.ExpiredAt
+	userSubscription.ExpiredAt = startFrom.AddDate(0, 0, duration)
+	userSubscription.PaymentStatus = string(midtrans.PaymentStatusSettled)
+
+	if err := subscriptionRepository.UpdateUserSubscription(userSubscription); err != nil {
+		return false, err
+	}
+
+	if err := subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+		UserID:             userSubscription.UserID,
+		UserSubscriptionID: userSubscription.ID,
+		SubscriptionID:     userSubscription.SubID,
+		OrderID:            &order.ID,
+		Event:              models.SubscriptionEventRenewed,
+		StartedAt:          startFrom,
+		ExpiredAt:          userSubscription.ExpiredAt,
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Replace the user subscription upgraded by a settled order
+// The new subscription keeps the expiry of the upgraded one, since only the remaining period was charged.
+// When the upgraded subscription expired while the payment was pending, the remaining period it had
+// when the order was placed starts now instead
+// It returns false when the upgraded subscription belongs to someone else or was replaced, in which case the order starts a new period
+func (s *SubscriptionOrderServiceInstance) upgradeSubscription(subscriptionRepository repositories.SubscriptionRepository, order *models.SubscriptionOrder) (bool, error) {
+	userSubscription, err := subscriptionRepository.FindUserSubscriptionForUpdate(*order.UserSubscriptionID)
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if userSubscription.UserID != order.UserID {
+		return false, nil
+	}
+
+	now := time.Now()
+	expiredAt := userSubscription.ExpiredAt
+
+	if !userSubscription.IsActive || !expiredAt.After(now) {
+		activeSubscription, err := subscriptionRepository.FindActiveUserSubscription(order.UserID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return false, err
+		}
+
+		// The user moved to another paid subscription in the meantime
+		if activeSubscription != nil && activeSubscription.ID != userSubscription.ID && activeSubscription.Sub.Price.IsPositive() {
+			return false, nil
+		}
+
+		expiredAt = now.Add(userSubscription.ExpiredAt.Sub(order.CreatedAt))
+		if !expiredAt.After(now) {
+			return false, nil
+		}
+	}
+
+	if err := subscriptionRepository.DeactivateUserSubscriptions(order.UserID); err != nil {
+		return false, err
+	}
+
+	upgraded := &models.UserSubscription{
+		UserID:        order.UserID,
+		SubID:         order.SubscriptionID,
+		StartedAt:     now,
+		ExpiredAt:     expiredAt,
+		IsActive:      true,
+		PaymentStatus: string(midtrans.PaymentStatusSettled),
+	}
+
+	if err := subscriptionRepository.SubscribeUser(upgraded); err != nil {
+		return false, err
+	}
+
+	if err := subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+		UserID:             upgraded.UserID,
+		UserSubscriptionID: upgraded.ID,
+		SubscriptionID:     upgraded.SubID,
+		OrderID:            &order.ID,
+		Event:              models.SubscriptionEventUpgraded,
+		StartedAt:          upgraded.StartedAt,
+		ExpiredAt:          upgraded.ExpiredAt,
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Schedule the subscription of a settled downgrade order after the user subscription it replaces
+// The scheduled subscription is activated by the expiry job once the current subscription expires
+// It returns false when the replaced subscription is not active anymore, in which case the order starts a new period
+func (s *SubscriptionOrderServiceInstance) scheduleDowngrade(subscriptionRepository repositories.SubscriptionRepository, order *models.SubscriptionOrder, duration int) (bool, error) {
+	userSubscription, err := subscriptionRepository.FindUserSubscriptionForUpdate(*order.UserSubscriptionID)
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !userSubscription.IsActive || userSubscription.UserID != order.UserID || !userSubscription.ExpiredAt.After(time.Now()) {
+		return false, nil
+	}
+
+	scheduled := &models.UserSubscription{
+		UserID:        order.UserID,
+		SubID:         order.SubscriptionID,
+		StartedAt:     userSubscription.ExpiredAt,
+		ExpiredAt:     userSubscription.ExpiredAt.AddDate(0, 0, duration),
+		IsActive:      false,
+		IsScheduled:   true,
+		PaymentStatus: string(midtrans.PaymentStatusSettled),
+	}
+
+	if err := subscriptionRepository.SubscribeUser(scheduled); err != nil {
+		return false, err
+	}
+
+	if err := subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+		UserID:             scheduled.UserID,
+		UserSubscriptionID: scheduled.ID,
+		SubscriptionID:     scheduled.SubID,
+		OrderID:            &order.ID,
+		Event:              models.SubscriptionEventDowngradeScheduled,
+		StartedAt:          scheduled.StartedAt,
+		ExpiredAt:          scheduled.ExpiredAt,
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Cancel a pending subscription order
+// This function cancels the payment on Midtrans and then fails the order the same way
+// a cancel notification would. Only the owner of the order can cancel it
+// It returns the canceled order or an error if the order cannot be canceled
+func (s *SubscriptionOrderServiceInstance) CancelSubscriptionOrder(userID uint32, orderID string) (*models.SubscriptionOrder, *errors.CustomError) {
+	order, err := s.SubscriptionOrderRepository.FindByOrderID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, errors.NotFound("Subscription order not found")
+	}
+
+	if order.Status != string(midtrans.PaymentStatusPending) || order.PaymentTransactionID == nil {
+		return nil, errors.BadRequest("Subscription order cannot be canceled", "Only pending orders can be canceled")
+	}
+
+	transactionID := order.PaymentTransactionID.String()
+
+	if appError := s.PaymentService.CancelPayment(transactionID); appError != nil {
+		return nil, appError
+	}
+
+	cancelStatus := string(midtrans.PaymentStatusCanceled)
+	if appError := s.UpdateSubscriptionOrder(transactionID, &dtos.UpdateSubscriptionOrderDTO{
+		OrderID:           transactionID,
+		TransactionStatus: &cancelStatus,
+	}); appError != nil {
+		return nil, appError
+	}
+
+	order.Status = string(midtrans.PaymentStatusFailed)
+
+	return order, nil
+}
+
+// Refund a settled payment transaction
+// This function refunds the requested amount on Midtrans, or everything that is left when no amount is given.
+// The refund is stored as pending before the gateway is called, so it is never lost and reserves its amount
+// against concurrent refunds. Once the gateway accepts it, the refund is settled, the transaction is marked as
+// (partially) refunded and the user subscription bought with it is shortened proportionally, all in one database transaction.
+// A refund the gateway refuses is marked as failed
+// It returns the recorded refund or an error if the payment cannot be refunded
+func (s *SubscriptionOrderServiceInstance) RefundPaymentTransaction(transactionID string, request *dtos.RefundPaymentDTO, refundedBy uint32) (*models.PaymentRefund, *errors.CustomError) {
+	if request == nil {
+		return nil, errors.BadRequest("Refund request is required", nil)
+	}
+
+	refund, appError := s.reserveRefund(transactionID, request, refundedBy)
+	if appError != nil {
+		return nil, appError
+	}
+
+	if appError := s.PaymentService.RefundPayment(transactionID, refund.RefundKey, refund.Amount, request.Reason); appError != nil {
+		refund.Status = string(midtrans.PaymentStatusFailed)
+		if err := s.PaymentRefundRepository.UpdateRefund(refund); err != nil {
+			log.Printf("Failed to mark payment refund %s as failed: %v", refund.ID, err)
+		}
+
+		return nil, appError
+	}
+
+	if appError := s.settleRefund(transactionID, refund); appError != nil {
+		return nil, appError
+	}
+
+	return refund, nil
+}
+
+// Store a pending refund of a payment transaction
+// The transaction is locked while the amount left to refund is computed, so concurrent refunds
+// never reserve more than the transaction amount between them
+// It returns the pending refund or an error if the payment cannot be refunded
+func (s *SubscriptionOrderServiceInstance) reserveRefund(transactionID string, request *dtos.RefundPaymentDTO, refundedBy uint32) (*models.PaymentRefund, *errors.CustomError) {
+	var refund *models.PaymentRefund
+	var appError *errors.CustomError
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		transactionRepository := s.PaymentTransactionRepository.WithTx(tx)
+		refundRepository := s.PaymentRefundRepository.WithTx(tx)
+
+		transaction, err := transactionRepository.FindByOrderIDForUpdate(transactionID)
+		if err != nil {
+			appError = errors.NotFound("Transaction not found")
+			return err
+		}
+
+		if transaction.Status != string(midtrans.PaymentStatusSettled) && transaction.Status != string(midtrans.PaymentStatusPartiallyRefunded) {
+			appError = errors.BadRequest("Transaction cannot be refunded", "Only settled transactions can be refunded")
+			return nil
+		}
+
+		refundedAmount, err := refundRepository.SumRefundedAmount(transactionID, transaction.Amount.CurrencyCode())
+		if err != nil {
+			appError = errors.Internal("Failed to get refunded amount", err.Error())
+			return err
+		}
+
+		pendingAmount, err := refundRepository.SumPendingRefundAmount(transactionID, transaction.Amount.CurrencyCode())
+		if err != nil {
+			appError = errors.Internal("Failed to get pending refund amount", err.Error())
+			return err
+		}
+
+		remainingAmount := transaction.Amount.Sub(refundedAmount).Sub(pendingAmount)
+
+		// Gateways only refund whole rupiah, so the requested amount is rounded the same way before it is recorded
+		amount := remainingAmount
+		if request.Amount != nil {
+			if !request.Amount.SameCurrency(transaction.Amount) {
+				appError = errors.BadRequest("Invalid refund amount", map[string]any{
+					"currency": transaction.Amount.CurrencyCode(),
+				})
+				return nil
+			}
+
+			amount = request.Amount.RoundToMajor()
+		}
+
+		if !amount.IsPositive() || amount.Cmp(remainingAmount) > 0 {
+			appError = errors.BadRequest("Invalid refund amount", map[string]any{
+				"amount":           amount,
+				"remaining_amount": remainingAmount,
+			})
+			return nil
+		}
+
+		refund = &models.PaymentRefund{
+			ID:                   uuid.New(),
+			PaymentTransactionID: transaction.ID,
+			RefundKey:            uuid.New().String(),
+			Amount:               amount,
+			Reason:               request.Reason,
+			Status:               string(midtrans.PaymentStatusPending),
+		}
+
+		if refundedBy != 0 {
+			refund.RefundedBy = &refundedBy
+		}
+
+		if err := refundRepository.StoreRefund(refund); err != nil {
+			appError = errors.Internal("Failed to store payment refund", err.Error())
+			return err
+		}
+
+		return nil
+	})
+
+	if appError != nil {
+		return nil, appError
+	}
+
+	if txErr != nil {
+		return nil, errors.Internal("Failed to store payment refund", txErr.Error())
+	}
+
+	return refund, nil
+}
+
+// Settle a refund the gateway accepted
+// The refunded amount is summed again under the transaction lock, so the transaction status
+// reflects every refund settled in the meantime
+func (s *SubscriptionOrderServiceInstance) settleRefund(transactionID string, refund *models.PaymentRefund) *errors.CustomError {
+	var appError *errors.CustomError
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		transactionRepository := s.PaymentTransactionRepository.WithTx(tx)
+		refundRepository := s.PaymentRefundRepository.WithTx(tx)
+		orderRepository := s.SubscriptionOrderRepository.WithTx(tx)
+		subscriptionRepository := s.SubscriptionRepository.WithTx(tx)
+
+		transaction, err := transactionRepository.FindByOrderIDForUpdate(transactionID)
+		if err != nil {
+			appError = errors.NotFound("Transaction not found")
+			return err
+		}
+
+		refundedAmount, err := refundRepository.SumRefundedAmount(transactionID, transaction.Amount.CurrencyCode())
+		if err != nil {
+			appError = errors.Internal("Failed to get refunded amount", err.Error())
+			return err
+		}
+
+		isFullRefund := refund.Amount.Add(refundedAmount).Cmp(transaction.Amount) >= 0
+		status := midtrans.PaymentStatusPartiallyRefunded
+		if isFullRefund {
+			status = midtrans.PaymentStatusRefunded
+		}
+
+		refund.Status = string(status)
+		if err := refundRepository.UpdateRefund(refund); err != nil {
+			appError = errors.Internal("Failed to update payment refund", err.Error())
+			return err
+		}
+
+		transaction.Status = string(status)
+		if err := transactionRepository.Update(transaction); err != nil {
+			appError = errors.Internal("Failed to update transaction", err.Error())
+			return err
+		}
+
+		order, err := orderRepository.FindByPaymentTransactionID(transactionID)
+		if err == gorm.ErrRecordNotFound {
+			// The payment is not tied to an order, there is no subscription to revoke
+			return nil
+		}
+		if err != nil {
+			appError = errors.Internal("Failed to get subscription order", err.Error())
+			return err
+		}
+
+		if order.Status != string(midtrans.PaymentStatusSettled) {
+			return nil
+		}
+
+		if err := s.shortenSubscription(subscriptionRepository, order, refund.Amount, isFullRefund); err != nil {
+			appError = errors.Internal("Failed to revoke user subscription", err.Error())
+			return err
+		}
+
+		if !isFullRefund {
+			return nil
+		}
+
+		if err := orderRepository.UpdateOrderTransaction(order.ID.String(), &models.SubscriptionOrder{
+			Status: string(midtrans.PaymentStatusRefunded),
+		}); err != nil {
+			appError = errors.Internal("Failed to update subscription order", err.Error())
+			return err
+		}
+
+		return nil
+	})
+
+	if appError != nil {
+		return appError
+	}
+
+	if txErr != nil {
+		return errors.Internal("Failed to record payment refund", txErr.Error())
+	}
 
 	return nil
 }
+
+// Shorten the user subscription bought by a refunded order
+// The subscription loses the share of the days the order granted that matches the refunded share of the order,
+// or every granted day on a full refund. When nothing is left the subscription is deactivated
+// and the user falls back to the free tier. Subscriptions the user already moved away from are left untouched
+func (s *SubscriptionOrderServiceInstance) shortenSubscription(subscriptionRepository repositories.SubscriptionRepository, order *models.SubscriptionOrder, refundAmount money.Money, isFullRefund bool) error {
+	if order.Subscription == nil {
+		subscription, err := subscriptionRepository.FindByID(order.SubscriptionID)
+		if err != nil {
+			return err
+		}
+
+		order.Subscription = subscription
+	}
+
+	activeSubscription, err := subscriptionRepository.FindActiveUserSubscription(order.UserID)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if activeSubscription.SubID != order.SubscriptionID {
+		return nil
+	}
+
+	granted, err := s.orderGrantedDays(subscriptionRepository, order)
+	if err != nil {
+		return err
+	}
+
+	days := granted
+	if !isFullRefund && order.Amount.IsPositive() {
+		days = int(math.Round(float64(granted) * float64(refundAmount.Amount) / float64(order.Amount.Amount)))
+	}
+
+	now := time.Now()
+	expiredAt := activeSubscription.ExpiredAt.AddDate(0, 0, -days)
+
+	if expiredAt.After(now) {
+		activeSubscription.ExpiredAt = expiredAt
+		return subscriptionRepository.UpdateUserSubscription(activeSubscription)
+	}
+
+	activeSubscription.ExpiredAt = now
+	activeSubscription.IsActive = false
+	activeSubscription.PaymentStatus = string(midtrans.PaymentStatusRefunded)
+
+	if err := subscriptionRepository.UpdateUserSubscription(activeSubscription); err != nil {
+		return err
+	}
+
+	return fallBackToFreeTier(subscriptionRepository, order.UserID, now)
+}
+
+// Count the days a settled order added to the subscription of its user
+// The history entry of the order records the period it granted, which is only the remaining period for a
+// prorated upgrade. Orders that started or extended a period without an entry granted the duration they were
+// activated with, the granted days of a voucher or promo code or the duration of the subscription otherwise
+func (s *SubscriptionOrderServiceInstance) orderGrantedDays(subscriptionRepository repositories.SubscriptionRepository, order *models.SubscriptionOrder) (int, error) {
+	history, err := subscriptionRepository.FindHistoryByOrderID(order.ID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	if history != nil {
+		return int(math.Round(history.ExpiredAt.Sub(history.StartedAt).Hours() / 24)), nil
+	}
+
+	if order.GrantedDays > 0 {
+		return int(order.GrantedDays), nil
+	}
+
+	return int(order.Subscription.Duration), nil
+}
+
+// Get the billing history of a user
+// It returns the orders of the requested page along with their payment transaction and pagination details
+func (s *SubscriptionOrderServiceInstance) GetUserOrders(userID uint32, params *query.QueryParams) ([]*models.SubscriptionOrder, *query.PaginationResponse, *errors.CustomError) {
+	orders, total, err := s.SubscriptionOrderRepository.FindUserOrders(userID, params)
+	if err != nil {
+		return nil, nil, errors.Internal("Failed to get subscription orders", err.Error())
+	}
+
+	pagination := query.CalculatePagination(params.Page, params.Limit, total)
+
+	return orders, pagination, nil
+}
+
+// Get an order of a user
+// Orders of other users are reported as not found
+func (s *SubscriptionOrderServiceInstance) GetUserOrder(userID uint32, orderID string) (*models.SubscriptionOrder, *errors.CustomError) {
+	if _, err := uuid.Parse(orderID); err != nil {
+		return nil, errors.NotFound("Subscription order not found")
+	}
+
+	order, err := s.SubscriptionOrderRepository.FindUserOrder(userID, orderID)
+	if err != nil {
+		return nil, errors.NotFound("Subscription order not found")
+	}
+
+	return order, nil
+}
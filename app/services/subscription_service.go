@@ -3,46 +3,75 @@ package services
 import (
 	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/platform/entitlements"
 	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/money"
+	"sort"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type SubscriptionService interface {
 	CreateNewSubscription(request *dtos.CreateSubscriptionDTO) (*models.Subscription, *errors.CustomError)
 	SubscribeUserToSubscription(userID, subID uint32) *errors.CustomError
-	CreateSubscriptionPlan(request *dtos.CreateSubscriptionPlanDTO, subID uint32) *errors.CustomError
+	CreateSubscriptionPlan(request *dtos.CreateSubscriptionPlanDTO, subID uint32) (*models.Subscription, *errors.CustomError)
+	UpdateSubscriptionPlan(request *dtos.UpdateSubscriptionPlanDTO, subID uint32, planID uint32) (*models.Subscription, *errors.CustomError)
+	DeleteSubscriptionPlan(subID uint32, planID uint32) (*models.Subscription, *errors.CustomError)
 	GetAllSubscriptions() ([]*models.Subscription, *errors.CustomError)
 	GetSubscriptionByID(subID uint32) (*models.Subscription, *errors.CustomError)
+	GetSubscriptionVersions(subID uint32) ([]*models.Subscription, *errors.CustomError)
+	CompareSubscriptions() (*dtos.SubscriptionComparisonDTO, *errors.CustomError)
 	AssignFreeTierSubscription(userID uint32) *errors.CustomError
-	UpdateSubscription(request *dtos.UpdateSubscriptionDTO, subID uint32) *errors.CustomError
+	UpdateSubscription(request *dtos.UpdateSubscriptionDTO, subID uint32) (*models.Subscription, *errors.CustomError)
 	DeleteSubscription(subID uint32) *errors.CustomError
 }
 
 type SubscriptionServiceInstance struct {
 	SubscriptionRepository     repositories.SubscriptionRepository
 	SubscriptionPlanRepository repositories.SubscriptionPlanRepository
+	TransactionManager         repositories.TransactionManager
 }
 
 func NewSubscriptionService(
 	subRepository repositories.SubscriptionRepository,
 	subPlanRepository repositories.SubscriptionPlanRepository,
+	transactionManager repositories.TransactionManager,
 ) SubscriptionService {
 	return &SubscriptionServiceInstance{
 		SubscriptionRepository:     subRepository,
 		SubscriptionPlanRepository: subPlanRepository,
+		TransactionManager:         transactionManager,
 	}
 }
 
 // Create a new subscription
 // This function will create a new subscription and return the created subscription
-// It returns an error if the subscription could not be created
+// It returns an error if a subscription with the same name is already offered or if the subscription could not be created
 func (s *SubscriptionServiceInstance) CreateNewSubscription(request *dtos.CreateSubscriptionDTO) (*models.Subscription, *errors.CustomError) {
+	if appError := validateSubscriptionPrice(request.Price); appError != nil {
+		return nil, appError
+	}
+
+	if appError := s.verifySubscriptionNameAvailable(request.Name, 0); appError != nil {
+		return nil, appError
+	}
+
 	subscription := &models.Subscription{
-		Name:        request.Name,
-		Description: request.Description,
-		Price:       float32(request.Price),
-		Duration:    request.Duration,
+		Name:              request.Name,
+		Description:       request.Description,
+		Price:             *request.Price,
+		Duration:          request.Duration,
+		TrialDays:         request.TrialDays,
+		TrialForPaidUsers: request.TrialForPaidUsers,
+	}
+
+	if appError := validateSubscriptionTrial(subscription); appError != nil {
+		return nil, appError
 	}
 
 	subscription, err := s.SubscriptionRepository.StoreNewSubscription(subscription)
@@ -83,6 +112,7 @@ func (s *SubscriptionServiceInstance) SubscribeUserToSubscription(userID, subID
 
 // Assign a free tier subscription to a user
 // This function will assign a free tier subscription to a user by userID
+// Nothing is assigned when the free tier is already the active subscription of the user
 // It returns an error if the user could not be assigned the subscription
 func (s *SubscriptionServiceInstance) AssignFreeTierSubscription(userID uint32) *errors.CustomError {
 	freeTierSub, err := s.SubscriptionRepository.FindFreeTierSubscription()
@@ -90,6 +120,15 @@ func (s *SubscriptionServiceInstance) AssignFreeTierSubscription(userID uint32)
 		return errors.NotFound("Free tier subscription not found")
 	}
 
+	activeSubscription, err := s.SubscriptionRepository.FindActiveUserSubscription(userID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return errors.Internal("Failed to get active subscription", err.Error())
+	}
+
+	if activeSubscription != nil && activeSubscription.SubID == freeTierSub.ID && activeSubscription.ExpiredAt.After(time.Now()) {
+		return nil
+	}
+
 	if err := s.SubscriptionRepository.SubscribeUser(&models.UserSubscription{
 		UserID:        userID,
 		SubID:         freeTierSub.ID,
@@ -104,24 +143,90 @@ func (s *SubscriptionServiceInstance) AssignFreeTierSubscription(userID uint32)
 	return nil
 }
 
-// Create a new subscription plan
-// This function will create a new subscription plan and return error if it already exists
-func (s *SubscriptionServiceInstance) CreateSubscriptionPlan(request *dtos.CreateSubscriptionPlanDTO, subID uint32) *errors.CustomError {
-	if exists, err := s.SubscriptionPlanRepository.IsPlanExists(subID, request.Name); err != nil || exists {
-		return errors.Conflict("Subscription plan already exists", nil)
+// Move a user back to the free tier after their subscription ended, using the given repository
+// Nothing is assigned when there is no free tier subscription, so the user is left without a subscription
+// It returns an error if the free tier could not be loaded or assigned
+func fallBackToFreeTier(subscriptionRepository repositories.SubscriptionRepository, userID uint32, now time.Time) error {
+	freeTier, err := subscriptionRepository.FindFreeTierSubscription()
+	if err == gorm.ErrRecordNotFound {
+		return nil
 	}
-
-	plan := &models.SubscriptionPlan{
-		SubID: subID,
-		Name:  request.Name,
-		Value: request.Value,
+	if err != nil {
+		return err
 	}
 
-	if err := s.SubscriptionPlanRepository.StoreNewPlan(plan); err != nil {
-		return errors.Internal("Failed to create subscription plan", err.Error())
+	return subscriptionRepository.SubscribeUser(&models.UserSubscription{
+		UserID:        userID,
+		SubID:         freeTier.ID,
+		StartedAt:     now,
+		ExpiredAt:     now.AddDate(100, 0, 0),
+		IsActive:      true,
+		PaymentStatus: string(midtrans.PaymentStatusSettled),
+	})
+}
+
+// Create a new subscription plan
+// The plan must be an entitlement known to the registry with a value of the right kind
+// It returns the subscription on offer after the change, which is a new version when the subscription was already bought
+func (s *SubscriptionServiceInstance) CreateSubscriptionPlan(request *dtos.CreateSubscriptionPlanDTO, subID uint32) (*models.Subscription, *errors.CustomError) {
+	value, appError := validateSubscriptionPlan(request.Name, request.Value)
+	if appError != nil {
+		return nil, appError
 	}
 
-	return nil
+	return s.reviseSubscription(subID, func(subscription *models.Subscription) *errors.CustomError {
+		for _, plan := range subscription.Plans {
+			if plan.Name == request.Name {
+				return errors.Conflict("Subscription plan already exists", nil)
+			}
+		}
+
+		subscription.Plans = append(subscription.Plans, models.SubscriptionPlan{
+			SubID: subscription.ID,
+			Name:  request.Name,
+			Value: value,
+		})
+
+		return nil
+	})
+}
+
+// Update the value of a subscription plan
+// It returns the subscription on offer after the change, which is a new version when the subscription was already bought
+func (s *SubscriptionServiceInstance) UpdateSubscriptionPlan(request *dtos.UpdateSubscriptionPlanDTO, subID uint32, planID uint32) (*models.Subscription, *errors.CustomError) {
+	return s.reviseSubscription(subID, func(subscription *models.Subscription) *errors.CustomError {
+		for i, plan := range subscription.Plans {
+			if plan.ID != planID {
+				continue
+			}
+
+			value, appError := validateSubscriptionPlan(plan.Name, request.Value)
+			if appError != nil {
+				return appError
+			}
+
+			subscription.Plans[i].Value = value
+			return nil
+		}
+
+		return errors.NotFound("Subscription plan not found")
+	})
+}
+
+// Delete a subscription plan
+// The entitlement of a deleted plan falls back to its default value
+// It returns the subscription on offer after the change, which is a new version when the subscription was already bought
+func (s *SubscriptionServiceInstance) DeleteSubscriptionPlan(subID uint32, planID uint32) (*models.Subscription, *errors.CustomError) {
+	return s.reviseSubscription(subID, func(subscription *models.Subscription) *errors.CustomError {
+		for i, plan := range subscription.Plans {
+			if plan.ID == planID {
+				subscription.Plans = append(subscription.Plans[:i], subscription.Plans[i+1:]...)
+				return nil
+			}
+		}
+
+		return errors.NotFound("Subscription plan not found")
+	})
 }
 
 // Get all subscriptions
@@ -136,6 +241,9 @@ func (s *SubscriptionServiceInstance) GetAllSubscriptions() ([]*models.Subscript
 	return subscriptions, nil
 }
 
+// Get a subscription by its ID
+// Retired versions are returned as well, so existing subscribers can still see the terms they bought
+// It returns the subscription along with its plans or an error if it does not exist
 func (s *SubscriptionServiceInstance) GetSubscriptionByID(subID uint32) (*models.Subscription, *errors.CustomError) {
 	subscription, err := s.SubscriptionRepository.FindByID(subID)
 
@@ -146,39 +254,154 @@ func (s *SubscriptionServiceInstance) GetSubscriptionByID(subID uint32) (*models
 	return subscription, nil
 }
 
-// Update an existing subscription
-// This function updates an existing subscription with the provided request data
-// It returns an error if the subscription does not exist as if the update fails
-func (s *SubscriptionServiceInstance) UpdateSubscription(request *dtos.UpdateSubscriptionDTO, subID uint32) *errors.CustomError {
+// Get every version of a subscription
+// It returns the versions of the family of the subscription, oldest first, or an error if the subscription does not exist
+func (s *SubscriptionServiceInstance) GetSubscriptionVersions(subID uint32) ([]*models.Subscription, *errors.CustomError) {
+	subscription, appError := s.GetSubscriptionByID(subID)
+	if appError != nil {
+		return nil, appError
+	}
 
-	if subscription, err := s.SubscriptionRepository.FindByID(subID); err != nil || subscription == nil {
-		return errors.NotFound("Subscription not found")
+	versions, err := s.SubscriptionRepository.FindSubscriptionVersions(subscription.Family())
+	if err != nil {
+		return nil, errors.Internal("Failed to retrieve subscription versions", err.Error())
 	}
 
-	updatedSubscription := &models.Subscription{
-		ID:          subID,
-		Name:        *request.Name,
-		Description: *request.Description,
-		Price:       float32(*request.Price),
-		Duration:    *request.Duration,
+	return versions, nil
+}
+
+// Compare the subscriptions on offer
+// Every feature declared in the entitlements registry is listed for each subscription, cheapest subscription first.
+// Flags are reported as booleans, quotas and sizes in bytes as numbers
+// It returns the comparison and an error if the subscriptions could not be retrieved
+func (s *SubscriptionServiceInstance) CompareSubscriptions() (*dtos.SubscriptionComparisonDTO, *errors.CustomError) {
+	subscriptions, err := s.SubscriptionRepository.FindAllSubscriptionsWithPlans()
+	if err != nil {
+		return nil, errors.Internal("Failed to retrieve subscriptions", err.Error())
 	}
 
-	if _, err := s.SubscriptionRepository.UpdateSubscription(updatedSubscription); err != nil {
-		return errors.Internal("Failed to update subscription", err.Error())
+	sort.SliceStable(subscriptions, func(i, j int) bool {
+		return subscriptions[i].Price.Amount < subscriptions[j].Price.Amount
+	})
+
+	definitions := entitlements.Definitions()
+	comparison := &dtos.SubscriptionComparisonDTO{
+		Features:      make([]dtos.SubscriptionFeatureDTO, 0, len(definitions)),
+		Subscriptions: make([]dtos.SubscriptionComparisonItemDTO, 0, len(subscriptions)),
 	}
 
-	return nil
+	for _, definition := range definitions {
+		comparison.Features = append(comparison.Features, dtos.SubscriptionFeatureDTO{
+			Key:         string(definition.Key),
+			Kind:        string(definition.Kind),
+			Description: definition.Description,
+		})
+	}
+
+	for _, subscription := range subscriptions {
+		granted := entitlements.New(0, subscription)
+		features := make(map[string]any, len(definitions))
+
+		for _, definition := range definitions {
+			entitlement := granted.Get(definition.Key)
+
+			if definition.Kind == entitlements.KindFlag {
+				features[string(definition.Key)] = entitlement.Enabled()
+			} else {
+				features[string(definition.Key)] = entitlement.Limit
+			}
+		}
+
+		comparison.Subscriptions = append(comparison.Subscriptions, dtos.SubscriptionComparisonItemDTO{
+			ID:          subscription.ID,
+			Name:        subscription.Name,
+			Description: subscription.Description,
+			Price:       subscription.Price,
+			Duration:    subscription.Duration,
+			Version:     subscription.Version,
+			Features:    features,
+		})
+	}
+
+	return comparison, nil
+}
+
+// Update an existing subscription
+// Only the fields of the request that are set are changed. Changing the price or the duration of a subscription
+// that was already bought stores a new version of it, while the name and the description are changed in place
+// It returns the subscription on offer after the change or an error if the subscription does not exist or the update fails
+func (s *SubscriptionServiceInstance) UpdateSubscription(request *dtos.UpdateSubscriptionDTO, subID uint32) (*models.Subscription, *errors.CustomError) {
+	if request.Price != nil {
+		if appError := validateSubscriptionPrice(request.Price); appError != nil {
+			return nil, appError
+		}
+	}
+
+	if request.Name != nil {
+		if appError := s.verifySubscriptionNameAvailable(*request.Name, subID); appError != nil {
+			return nil, appError
+		}
+	}
+
+	return s.reviseSubscription(subID, func(subscription *models.Subscription) *errors.CustomError {
+		if request.Name != nil {
+			subscription.Name = *request.Name
+		}
+
+		if request.Description != nil {
+			subscription.Description = *request.Description
+		}
+
+		if request.Price != nil {
+			subscription.Price = *request.Price
+		}
+
+		if request.Duration != nil {
+			subscription.Duration = *request.Duration
+		}
+
+		if request.TrialDays != nil {
+			subscription.TrialDays = *request.TrialDays
+		}
+
+		if request.TrialForPaidUsers != nil {
+			subscription.TrialForPaidUsers = *request.TrialForPaidUsers
+		}
+
+		return validateSubscriptionTrial(subscription)
+	})
 }
 
 // Delete a subscription
-// This function deletes a subscription by its ID
+// A subscription that was already bought is retired instead, so it is no longer offered but
+// its subscribers keep it until it expires. The free tier cannot be deleted
 // It returns an error if the subscription does not exist or if the deletion fails
 func (s *SubscriptionServiceInstance) DeleteSubscription(subID uint32) *errors.CustomError {
-	if subscription, err := s.SubscriptionRepository.FindByID(subID); err != nil || subscription == nil {
+	subscription, err := s.SubscriptionRepository.FindByID(subID)
+	if err != nil || subscription == nil {
 		return errors.NotFound("Subscription not found")
 	}
 
-	subscription := &models.Subscription{ID: subID}
+	if subscription.IsRetired() {
+		return errors.BadRequest("Subscription is no longer offered", nil)
+	}
+
+	if subscription.IsFreeTier() {
+		return errors.BadRequest("The free tier cannot be deleted", "Users fall back to the free tier when their subscription expires")
+	}
+
+	inUse, err := s.SubscriptionRepository.IsSubscriptionInUse(subID)
+	if err != nil {
+		return errors.Internal("Failed to delete subscription", err.Error())
+	}
+
+	if inUse {
+		if err := s.SubscriptionRepository.RetireSubscription(subID, nil, time.Now()); err != nil {
+			return errors.Internal("Failed to retire subscription", err.Error())
+		}
+
+		return nil
+	}
 
 	if err := s.SubscriptionRepository.DeleteSubscription(subscription); err != nil {
 		return errors.Internal("Failed to delete subscription", err.Error())
@@ -186,3 +409,217 @@ func (s *SubscriptionServiceInstance) DeleteSubscription(subID uint32) *errors.C
 
 	return nil
 }
+
+// Apply a change to a subscription on offer
+// The change is applied in place while the subscription was never bought. Once it was, a change of the price,
+// the duration or the plans stores a new version with the change applied and retires the current one, so its
+// subscribers keep the terms they bought until they renew. The free tier is never bought and always changes in place
+// It returns the subscription on offer after the change
+func (s *SubscriptionServiceInstance) reviseSubscription(subID uint32, change func(subscription *models.Subscription) *errors.CustomError) (*models.Subscription, *errors.CustomError) {
+	var revised *models.Subscription
+	var appError *errors.CustomError
+
+	txErr := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		subscriptionRepository := s.SubscriptionRepository.WithTx(tx)
+		planRepository := s.SubscriptionPlanRepository.WithTx(tx)
+
+		current, err := subscriptionRepository.FindByIDForUpdate(subID)
+		if err == gorm.ErrRecordNotFound {
+			appError = errors.NotFound("Subscription not found")
+			return err
+		}
+		if err != nil {
+			appError = errors.Internal("Failed to get subscription", err.Error())
+			return err
+		}
+
+		if current.IsRetired() {
+			appError = errors.BadRequest("Subscription is no longer offered", map[string]any{
+				"superseded_by_id": current.SupersededByID,
+			})
+			return appError
+		}
+
+		next := *current
+		next.Plans = append([]models.SubscriptionPlan(nil), current.Plans...)
+
+		if appError = change(&next); appError != nil {
+			return appError
+		}
+
+		if current.IsFreeTier() && !next.IsFreeTier() {
+			appError = errors.BadRequest("The free tier must stay free and keep its name", nil)
+			return appError
+		}
+
+		inUse := false
+		if !current.IsFreeTier() && subscriptionTermsChanged(current, &next) {
+			inUse, err = subscriptionRepository.IsSubscriptionInUse(current.ID)
+			if err != nil {
+				appError = errors.Internal("Failed to update subscription", err.Error())
+				return err
+			}
+		}
+
+		if !inUse {
+			if _, err := subscriptionRepository.UpdateSubscription(&next); err != nil {
+				appError = errors.Internal("Failed to update subscription", err.Error())
+				return err
+			}
+
+			if subscriptionPlansChanged(current.Plans, next.Plans) {
+				if err := planRepository.ReplacePlans(next.ID, next.Plans); err != nil {
+					appError = errors.Internal("Failed to update subscription plans", err.Error())
+					return err
+				}
+			}
+
+			revised = &next
+			return nil
+		}
+
+		version := next
+		version.ID = 0
+		version.FamilyID = current.Family()
+		version.Version = current.Version + 1
+		version.CreatedAt = time.Time{}
+		version.UpdatedAt = time.Time{}
+		version.Plans = make([]models.SubscriptionPlan, 0, len(next.Plans))
+
+		for _, plan := range next.Plans {
+			version.Plans = append(version.Plans, models.SubscriptionPlan{Name: plan.Name, Value: plan.Value})
+		}
+
+		if err := subscriptionRepository.StoreSubscriptionVersion(&version); err != nil {
+			appError = errors.Internal("Failed to store subscription version", err.Error())
+			return err
+		}
+
+		if err := subscriptionRepository.RetireSubscription(current.ID, &version.ID, time.Now()); err != nil {
+			appError = errors.Internal("Failed to retire subscription", err.Error())
+			return err
+		}
+
+		revised = &version
+		return nil
+	})
+
+	if appError != nil {
+		return nil, appError
+	}
+
+	if txErr != nil {
+		return nil, errors.Internal("Failed to update subscription", txErr.Error())
+	}
+
+	return revised, nil
+}
+
+// Check that no other subscription on offer has a name
+// exceptID is the subscription that is being renamed, if any
+func (s *SubscriptionServiceInstance) verifySubscriptionNameAvailable(name string, exceptID uint32) *errors.CustomError {
+	subscription, err := s.SubscriptionRepository.FindCurrentSubscriptionByName(name)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Internal("Failed to check subscription name", err.Error())
+	}
+
+	if subscription.ID != exceptID {
+		return errors.Conflict("Subscription already exists", map[string]any{
+			"name": name,
+		})
+	}
+
+	return nil
+}
+
+// Check whether a change of a subscription changes the terms it is sold with
+func subscriptionTermsChanged(current *models.Subscription, next *models.Subscription) bool {
+	return !current.Price.Equal(next.Price) ||
+		current.Duration != next.Duration ||
+		subscriptionPlansChanged(current.Plans, next.Plans)
+}
+
+// Check whether two sets of plans grant something different
+func subscriptionPlansChanged(current []models.SubscriptionPlan, next []models.SubscriptionPlan) bool {
+	if len(current) != len(next) {
+		return true
+	}
+
+	values := make(map[string]string, len(current))
+	for _, plan := range current {
+		values[plan.Name] = plan.Value
+	}
+
+	for _, plan := range next {
+		if value, ok := values[plan.Name]; !ok || value != plan.Value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check a subscription plan is an entitlement known to the registry with a valid value
+// It returns the trimmed value or an error listing the known entitlements
+func validateSubscriptionPlan(name string, value string) (string, *errors.CustomError) {
+	definition, ok := entitlements.Lookup(constants.SubscriptionPlan(name))
+	if !ok {
+		keys := make([]string, 0)
+		for _, definition := range entitlements.Definitions() {
+			keys = append(keys, string(definition.Key))
+		}
+
+		return "", errors.BadRequest("Unknown subscription plan", map[string]any{
+			"name":  name,
+			"known": keys,
+		})
+	}
+
+	value = strings.TrimSpace(value)
+	if _, err := definition.Parse(value); err != nil {
+		return "", errors.BadRequest("Invalid subscription plan value", map[string]any{
+			"name":  name,
+			"kind":  definition.Kind,
+			"error": err.Error(),
+		})
+	}
+
+	return value, nil
+}
+
+// Check a subscription price can be charged
+// Subscriptions are paid through Indonesian payment gateways, so prices must be whole rupiah
+func validateSubscriptionPrice(price *money.Money) *errors.CustomError {
+	if price == nil {
+		return errors.BadRequest("Price is required", nil)
+	}
+
+	if price.IsNegative() {
+		return errors.BadRequest("Price must be greater than or equal to 0", nil)
+	}
+
+	if price.CurrencyCode() != money.IDR || !price.IsWholeMajor() {
+		return errors.BadRequest("Price must be in whole rupiah", map[string]any{
+			"price": price,
+		})
+	}
+
+	return nil
+}
+
+// Validate the trial of a subscription
+// Only paid subscriptions can be tried, the free tier needs no trial
+func validateSubscriptionTrial(subscription *models.Subscription) *errors.CustomError {
+	if subscription.TrialDays < 0 {
+		return errors.BadRequest("Trial days must not be negative", nil)
+	}
+
+	if subscription.TrialDays > 0 && !subscription.Price.IsPositive() {
+		return errors.BadRequest("Only paid subscriptions can offer a trial", nil)
+	}
+
+	return nil
+}
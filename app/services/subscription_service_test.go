@@ -0,0 +1,342 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+)
+
+func newVersionedSubscriptionService(inUse ...uint32) (*SubscriptionServiceInstance, *fakeSubscriptionRepository, *fakeSubscriptionPlanRepository) {
+	repository := newFakeSubscriptionRepository()
+	for _, id := range inUse {
+		repository.inUse[id] = true
+	}
+
+	planRepository := &fakeSubscriptionPlanRepository{replaced: make(map[uint32][]models.SubscriptionPlan)}
+
+	service := &SubscriptionServiceInstance{
+		SubscriptionRepository:     repository,
+		SubscriptionPlanRepository: planRepository,
+		TransactionManager:         &fakeTransactionManager{},
+	}
+
+	return service, repository, planRepository
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	t.Run("Should store a new version when the price of a bought subscription changes", func(t *testing.T) {
+		service, repository, _ := newVersionedSubscriptionService(2)
+		repository.subscriptions[2].Version = 1
+
+		price := money.FromMajor(15000, money.IDR)
+		version, err := service.UpdateSubscription(&dtos.UpdateSubscriptionDTO{Price: &price}, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if version.ID != 4 {
+			t.Errorf("Expected 4, got %d", version.ID)
+		}
+
+		if version.FamilyID != 2 {
+			t.Errorf("Expected 2, got %d", version.FamilyID)
+		}
+
+		if version.Version != 2 {
+			t.Errorf("Expected 2, got %d", version.Version)
+		}
+
+		if !version.Price.Equal(price) {
+			t.Errorf("Expected IDR 15000.00, got %s", version.Price)
+		}
+
+		if len(version.Plans) != 3 {
+			t.Fatalf("Expected 3 plans, got %d", len(version.Plans))
+		}
+
+		if version.Plans[0].ID != 0 {
+			t.Errorf("Expected 0, got %d", version.Plans[0].ID)
+		}
+
+		bought := repository.subscriptions[2]
+		if !bought.IsRetired() {
+			t.Errorf("Expected true, got %t", bought.IsRetired())
+		}
+
+		if bought.SupersededByID == nil {
+			t.Fatal("Expected 4, got nil")
+		}
+
+		if *bought.SupersededByID != 4 {
+			t.Errorf("Expected 4, got %d", *bought.SupersededByID)
+		}
+
+		if !bought.Price.Equal(money.FromMajor(10000, money.IDR)) {
+			t.Errorf("Expected IDR 10000.00, got %s", bought.Price)
+		}
+	})
+
+	t.Run("Should change a subscription that was never bought in place", func(t *testing.T) {
+		service, repository, _ := newVersionedSubscriptionService()
+
+		duration := int16(30)
+		subscription, err := service.UpdateSubscription(&dtos.UpdateSubscriptionDTO{Duration: &duration}, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if subscription.ID != 2 {
+			t.Errorf("Expected 2, got %d", subscription.ID)
+		}
+
+		if subscription.Duration != 30 {
+			t.Errorf("Expected 30, got %d", subscription.Duration)
+		}
+
+		if len(repository.updated) != 1 {
+			t.Errorf("Expected 1 update, got %d", len(repository.updated))
+		}
+	})
+
+	t.Run("Should rename a bought subscription in place and leave the other fields untouched", func(t *testing.T) {
+		service, repository, _ := newVersionedSubscriptionService(2)
+
+		name := "Creator"
+		subscription, err := service.UpdateSubscription(&dtos.UpdateSubscriptionDTO{Name: &name}, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if subscription.ID != 2 {
+			t.Errorf("Expected 2, got %d", subscription.ID)
+		}
+
+		if subscription.Name != "Creator" {
+			t.Errorf("Expected Creator, got %s", subscription.Name)
+		}
+
+		if subscription.Duration != 28 {
+			t.Errorf("Expected 28, got %d", subscription.Duration)
+		}
+
+		if repository.subscriptions[2].IsRetired() {
+			t.Errorf("Expected false, got %t", repository.subscriptions[2].IsRetired())
+		}
+	})
+
+	t.Run("Should refuse a name another subscription on offer has", func(t *testing.T) {
+		service, _, _ := newVersionedSubscriptionService()
+
+		name := "Business"
+		if _, err := service.UpdateSubscription(&dtos.UpdateSubscriptionDTO{Name: &name}, 2); err == nil || err.Code != 409 {
+			t.Errorf("Expected conflict error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse to change a version that is no longer offered", func(t *testing.T) {
+		service, repository, _ := newVersionedSubscriptionService()
+
+		retiredAt := time.Now()
+		repository.subscriptions[2].RetiredAt = &retiredAt
+
+		duration := int16(30)
+		if _, err := service.UpdateSubscription(&dtos.UpdateSubscriptionDTO{Duration: &duration}, 2); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should keep the free tier free", func(t *testing.T) {
+		service, _, _ := newVersionedSubscriptionService()
+
+		price := money.FromMajor(5000, money.IDR)
+		if _, err := service.UpdateSubscription(&dtos.UpdateSubscriptionDTO{Price: &price}, 1); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
+
+func TestSubscriptionPlans(t *testing.T) {
+	t.Run("Should store a new version when a plan of a bought subscription changes", func(t *testing.T) {
+		service, repository, planRepository := newVersionedSubscriptionService(3)
+		repository.subscriptions[3].Plans[0].ID = 7
+
+		version, err := service.UpdateSubscriptionPlan(&dtos.UpdateSubscriptionPlanDTO{Value: " 800 "}, 3, 7)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if version.ID != 4 {
+			t.Errorf("Expected 4, got %d", version.ID)
+		}
+
+		if version.Plans[0].Value != "800" {
+			t.Errorf("Expected 800, got %s", version.Plans[0].Value)
+		}
+
+		if repository.subscriptions[3].Plans[0].Value != "500" {
+			t.Errorf("Expected 500, got %s", repository.subscriptions[3].Plans[0].Value)
+		}
+
+		if len(planRepository.replaced) != 0 {
+			t.Errorf("Expected 0 replaced plans, got %d", len(planRepository.replaced))
+		}
+	})
+
+	t.Run("Should replace the plans of a subscription that was never bought", func(t *testing.T) {
+		service, _, planRepository := newVersionedSubscriptionService()
+
+		subscription, err := service.CreateSubscriptionPlan(&dtos.CreateSubscriptionPlanDTO{
+			Name:  string(constants.SubscriptionPhotoSize),
+			Value: "2MB",
+		}, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if subscription.ID != 2 {
+			t.Errorf("Expected 2, got %d", subscription.ID)
+		}
+
+		if len(planRepository.replaced[2]) != 4 {
+			t.Errorf("Expected 4 plans, got %d", len(planRepository.replaced[2]))
+		}
+	})
+
+	t.Run("Should refuse plans unknown to the registry and values of the wrong kind", func(t *testing.T) {
+		service, _, _ := newVersionedSubscriptionService()
+
+		if _, err := service.CreateSubscriptionPlan(&dtos.CreateSubscriptionPlanDTO{Name: "Subscription-Unknown", Value: "1"}, 2); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+
+		if _, err := service.CreateSubscriptionPlan(&dtos.CreateSubscriptionPlanDTO{
+			Name:  string(constants.SubscriptionMerchantTemplateCustomize),
+			Value: "sometimes",
+		}, 2); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a plan the subscription already has", func(t *testing.T) {
+		service, _, _ := newVersionedSubscriptionService()
+
+		if _, err := service.CreateSubscriptionPlan(&dtos.CreateSubscriptionPlanDTO{
+			Name:  string(constants.SubscriptionAnalytics),
+			Value: "false",
+		}, 2); err == nil || err.Code != 409 {
+			t.Errorf("Expected conflict error, got %v", err)
+		}
+	})
+}
+
+func TestCompareSubscriptions(t *testing.T) {
+	t.Run("Should list typed feature values of every subscription on offer, cheapest first", func(t *testing.T) {
+		service, _, _ := newVersionedSubscriptionService()
+
+		comparison, err := service.CompareSubscriptions()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		ids := make([]uint32, 0, len(comparison.Subscriptions))
+		for _, subscription := range comparison.Subscriptions {
+			ids = append(ids, subscription.ID)
+		}
+
+		if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+			t.Fatalf("Expected [1 2 3], got %v", ids)
+		}
+
+		business := comparison.Subscriptions[2].Features
+		if products := business[string(constants.SubscriptionProductSlot)]; products != int64(500) {
+			t.Errorf("Expected 500, got %v", products)
+		}
+
+		if metrics := business[string(constants.SubscriptionInteractionMetrics)]; metrics != true {
+			t.Errorf("Expected true, got %v", metrics)
+		}
+
+		free := comparison.Subscriptions[0].Features
+		if analytics := free[string(constants.SubscriptionAnalytics)]; analytics != false {
+			t.Errorf("Expected false, got %v", analytics)
+		}
+
+		if photoSize := free[string(constants.SubscriptionPhotoSize)]; photoSize != int64(1<<20) {
+			t.Errorf("Expected 1048576, got %v", photoSize)
+		}
+
+		if len(comparison.Features) != len(free) {
+			t.Errorf("Expected %d features, got %d", len(free), len(comparison.Features))
+		}
+	})
+}
+
+func TestRenewSubscriptionVersion(t *testing.T) {
+	t.Run("Should renew a retired version onto the version on offer", func(t *testing.T) {
+		retiredAt := time.Now()
+		latestID := uint32(4)
+
+		expiredAt := time.Now().AddDate(0, 0, 3)
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: expiredAt, IsActive: true,
+			Sub: models.Subscription{ID: 2, FamilyID: 2, Price: money.FromMajor(10000, money.IDR), RetiredAt: &retiredAt, SupersededByID: &latestID},
+		})
+		repository.subscriptions[2].FamilyID = 2
+		repository.subscriptions[2].RetiredAt = &retiredAt
+		repository.subscriptions[4] = &models.Subscription{ID: 4, FamilyID: 2, Version: 2, Name: "Content Creator", Price: money.FromMajor(12000, money.IDR), Duration: 28}
+
+		service := &SubscriptionOrderServiceInstance{SubscriptionRepository: repository}
+
+		userSubscription, subscription, err := service.GetRenewableSubscription(10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if subscription.ID != 4 {
+			t.Fatalf("Expected 4, got %d", subscription.ID)
+		}
+
+		order := &models.SubscriptionOrder{
+			UserID:             10,
+			SubscriptionID:     subscription.ID,
+			Subscription:       subscription,
+			Type:               models.OrderTypeRenewal,
+			UserSubscriptionID: &userSubscription.ID,
+		}
+
+		applied, renewErr := service.renewSubscription(repository, order, 28)
+		if renewErr != nil {
+			t.Fatalf("Expected no error, got %v", renewErr)
+		}
+
+		if !applied {
+			t.Fatalf("Expected true, got %t", applied)
+		}
+
+		if userSubscription.SubID != 4 {
+			t.Errorf("Expected 4, got %d", userSubscription.SubID)
+		}
+
+		if expected := expiredAt.AddDate(0, 0, 28); !userSubscription.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, userSubscription.ExpiredAt)
+		}
+	})
+
+	t.Run("Should refuse to renew a subscription that is no longer offered at all", func(t *testing.T) {
+		retiredAt := time.Now()
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: time.Now().AddDate(0, 0, 3), IsActive: true,
+			Sub: models.Subscription{ID: 2, FamilyID: 2, Price: money.FromMajor(10000, money.IDR), RetiredAt: &retiredAt},
+		})
+		repository.subscriptions[2].RetiredAt = &retiredAt
+
+		service := &SubscriptionOrderServiceInstance{SubscriptionRepository: repository}
+
+		if _, _, err := service.GetRenewableSubscription(10); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/repositories"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionTrialService interface {
+	StartTrial(userID uint32, subID uint32) (*models.UserSubscription, *errors.CustomError)
+}
+
+type SubscriptionTrialServiceInstance struct {
+	UserRepository              repositories.UserRepository
+	SubscriptionRepository      repositories.SubscriptionRepository
+	SubscriptionOrderRepository repositories.SubscriptionOrderRepository
+	TransactionManager          repositories.TransactionManager
+}
+
+func NewSubscriptionTrialService(
+	userRepository repositories.UserRepository,
+	subscriptionRepository repositories.SubscriptionRepository,
+	subscriptionOrderRepository repositories.SubscriptionOrderRepository,
+	transactionManager repositories.TransactionManager,
+) SubscriptionTrialService {
+	return &SubscriptionTrialServiceInstance{
+		UserRepository:              userRepository,
+		SubscriptionRepository:      subscriptionRepository,
+		SubscriptionOrderRepository: subscriptionOrderRepository,
+		TransactionManager:          transactionManager,
+	}
+}
+
+// Start the trial of a subscription for a user
+// A trial is offered once per user and per email, to users with a verified email on the free tier.
+// Unless the subscription offers its trial to paid users too, users who ever paid for a subscription are not eligible.
+// The trial replaces the free tier until it ends, after which the user moves back to the free tier unless they subscribe
+// It returns the trial or an error if the user is not eligible
+func (s *SubscriptionTrialServiceInstance) StartTrial(userID uint32, subID uint32) (*models.UserSubscription, *errors.CustomError) {
+	subscription, err := s.SubscriptionRepository.FindByID(subID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NotFound("Subscription not found")
+	}
+	if err != nil {
+		return nil, errors.Internal("Failed to get subscription", err.Error())
+	}
+
+	if !subscription.OffersTrial() {
+		return nil, errors.BadRequest("Subscription does not offer a trial", nil)
+	}
+
+	user, err := s.UserRepository.FindByID(userID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NotFound("User not found")
+	}
+	if err != nil {
+		return nil, errors.Internal("Failed to get user", err.Error())
+	}
+
+	if !user.MustVerifyEmail() {
+		return nil, errors.Forbidden("Not eligible for a trial").WithDetails("Verify your email address to start a trial")
+	}
+
+	current, err := s.SubscriptionRepository.FindActiveUserSubscription(userID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.Internal("Failed to get active subscription", err.Error())
+	}
+
+	if current != nil && current.Sub.Price.IsPositive() && current.ExpiredAt.After(time.Now()) {
+		return nil, errors.BadRequest("User already has an active subscription", "Trials are only available on the free tier")
+	}
+
+	email := normalizeTrialEmail(user.Email)
+
+	used, err := s.SubscriptionRepository.HasUsedTrial(userID, email)
+	if err != nil {
+		return nil, errors.Internal("Failed to verify trial eligibility", err.Error())
+	}
+
+	if used {
+		return nil, errors.Forbidden("Not eligible for a trial").WithDetails("A trial was already used by this account or email address")
+	}
+
+	if !subscription.TrialForPaidUsers {
+		paid, err := s.SubscriptionOrderRepository.HasPaidOrder(userID)
+		if err != nil {
+			return nil, errors.Internal("Failed to verify trial eligibility", err.Error())
+		}
+
+		if paid {
+			return nil, errors.Forbidden("Not eligible for a trial").WithDetails("Trials are only available to new subscribers")
+		}
+	}
+
+	now := time.Now()
+	trialEndsAt := now.AddDate(0, 0, int(subscription.TrialDays))
+
+	trial := &models.UserSubscription{
+		UserID:        userID,
+		SubID:         subscription.ID,
+		StartedAt:     now,
+		ExpiredAt:     trialEndsAt,
+		IsActive:      true,
+		PaymentStatus: "trial",
+		TrialEndsAt:   &trialEndsAt,
+		TrialEmail:    &email,
+	}
+
+	if err := s.TransactionManager.WithinTransaction(func(tx *gorm.DB) error {
+		subscriptionRepository := s.SubscriptionRepository.WithTx(tx)
+
+		if err := subscriptionRepository.DeactivateUserSubscriptions(userID); err != nil {
+			return err
+		}
+
+		if err := subscriptionRepository.SubscribeUser(trial); err != nil {
+			return err
+		}
+
+		return subscriptionRepository.StoreHistory(&models.SubscriptionHistory{
+			UserID:             userID,
+			UserSubscriptionID: trial.ID,
+			SubscriptionID:     trial.SubID,
+			Event:              models.SubscriptionEventTrialStarted,
+			StartedAt:          trial.StartedAt,
+			ExpiredAt:          trial.ExpiredAt,
+		})
+	}); err != nil {
+		return nil, errors.Internal("Failed to start trial", err.Error())
+	}
+
+	trial.Sub = *subscription
+
+	return trial, nil
+}
+
+// Normalize an email so the aliases of a mailbox share one trial
+// The address is lowercased and a +tag is dropped, Gmail addresses also ignore dots
+func normalizeTrialEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+
+	local, domain := email[:at], email[at+1:]
+
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+
+	return local + "@" + domain
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+	"time"
+)
+
+func newTrialService(userSubscriptions ...*models.UserSubscription) (*SubscriptionTrialServiceInstance, *fakeSubscriptionRepository, *fakeSubscriptionOrderRepository) {
+	verifiedAt := time.Now().AddDate(0, -1, 0)
+
+	repository := newFakeSubscriptionRepository(userSubscriptions...)
+	repository.subscriptions[3].TrialDays = 14
+
+	orderRepository := &fakeSubscriptionOrderRepository{}
+
+	service := &SubscriptionTrialServiceInstance{
+		UserRepository: &fakeUserRepository{users: map[uint32]*models.User{
+			10: {ID: 10, Email: "Jane.Doe+shop@gmail.com", EmailVerifiedAt: &verifiedAt},
+			20: {ID: 20, Email: "jane@example.com"},
+		}},
+		SubscriptionRepository:      repository,
+		SubscriptionOrderRepository: orderRepository,
+		TransactionManager:          &fakeTransactionManager{},
+	}
+
+	return service, repository, orderRepository
+}
+
+func TestStartTrial(t *testing.T) {
+	t.Run("Should replace the free tier with a trial that ends after the trial days", func(t *testing.T) {
+		service, repository, _ := newTrialService(freeTierUserSubscription(10))
+
+		trial, err := service.StartTrial(10, 3)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !trial.IsTrial() {
+			t.Fatalf("Expected true, got %t", trial.IsTrial())
+		}
+
+		if !trial.IsActive {
+			t.Errorf("Expected true, got %t", trial.IsActive)
+		}
+
+		if trial.SubID != 3 {
+			t.Errorf("Expected 3, got %d", trial.SubID)
+		}
+
+		if trial.TrialEmail == nil {
+			t.Fatal("Expected janedoe@gmail.com, got nil")
+		}
+
+		if *trial.TrialEmail != "janedoe@gmail.com" {
+			t.Errorf("Expected janedoe@gmail.com, got %s", *trial.TrialEmail)
+		}
+
+		if expected := trial.StartedAt.AddDate(0, 0, 14); !trial.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, trial.ExpiredAt)
+		}
+
+		if !trial.ExpiredAt.Equal(*trial.TrialEndsAt) {
+			t.Errorf("Expected %v, got %v", trial.ExpiredAt, *trial.TrialEndsAt)
+		}
+
+		if repository.userSubscriptions[0].IsActive {
+			t.Errorf("Expected false, got %t", repository.userSubscriptions[0].IsActive)
+		}
+
+		if len(repository.histories) != 1 || repository.histories[0].Event != models.SubscriptionEventTrialStarted {
+			t.Errorf("Expected [%s], got %v", models.SubscriptionEventTrialStarted, historyEvents(repository.histories))
+		}
+	})
+
+	t.Run("Should refuse a subscription without a trial", func(t *testing.T) {
+		service, _, _ := newTrialService(freeTierUserSubscription(10))
+
+		if _, err := service.StartTrial(10, 2); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a user without a verified email", func(t *testing.T) {
+		service, _, _ := newTrialService(freeTierUserSubscription(20))
+
+		if _, err := service.StartTrial(20, 3); err == nil || err.Code != 403 {
+			t.Errorf("Expected forbidden error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a user on a paid subscription", func(t *testing.T) {
+		service, _, _ := newTrialService(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 2, ExpiredAt: time.Now().AddDate(0, 0, 14), IsActive: true,
+			Sub: models.Subscription{ID: 2, Price: money.FromMajor(10000, money.IDR)},
+		})
+
+		if _, err := service.StartTrial(10, 3); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse a second trial of the same email", func(t *testing.T) {
+		email := "janedoe@gmail.com"
+		trialEndsAt := time.Now().AddDate(0, -2, 0)
+
+		service, _, _ := newTrialService(
+			freeTierUserSubscription(10),
+			&models.UserSubscription{ID: 2, UserID: 30, SubID: 3, ExpiredAt: trialEndsAt, TrialEndsAt: &trialEndsAt, TrialEmail: &email},
+		)
+
+		if _, err := service.StartTrial(10, 3); err == nil || err.Code != 403 {
+			t.Errorf("Expected forbidden error, got %v", err)
+		}
+	})
+
+	t.Run("Should only offer the trial to paid users when the subscription allows it", func(t *testing.T) {
+		service, repository, orderRepository := newTrialService(freeTierUserSubscription(10))
+		orderRepository.paid = true
+
+		if _, err := service.StartTrial(10, 3); err == nil || err.Code != 403 {
+			t.Errorf("Expected forbidden error, got %v", err)
+		}
+
+		repository.subscriptions[3].TrialForPaidUsers = true
+
+		if _, err := service.StartTrial(10, 3); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestNormalizeTrialEmail(t *testing.T) {
+	t.Run("Should share one trial between the aliases of a mailbox", func(t *testing.T) {
+		cases := map[string]string{
+			" Jane.Doe+shop@GoogleMail.com ": "janedoe@gmail.com",
+			"jane.doe+shop@example.com":      "jane.doe@example.com",
+			"not-an-email":                   "not-an-email",
+		}
+
+		for email, expected := range cases {
+			if normalized := normalizeTrialEmail(email); normalized != expected {
+				t.Errorf("Expected %s, got %s", expected, normalized)
+			}
+		}
+	})
+}
+
+func TestTrialConversion(t *testing.T) {
+	t.Run("Should convert a trial paid for and keep its remaining days", func(t *testing.T) {
+		trialEndsAt := time.Now().AddDate(0, 0, 5)
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 3, ExpiredAt: trialEndsAt, IsActive: true, TrialEndsAt: &trialEndsAt},
+		)
+		service := &SubscriptionOrderServiceInstance{}
+
+		order := &models.SubscriptionOrder{UserID: 10, SubscriptionID: 3, Type: models.OrderTypeNew}
+		if err := service.activateSubscription(repository, order); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		converted := repository.userSubscriptions[0]
+		if converted.IsTrial() {
+			t.Errorf("Expected false, got %t", converted.IsTrial())
+		}
+
+		if expected := trialEndsAt.AddDate(0, 0, 28); !converted.ExpiredAt.Equal(expected) {
+			t.Errorf("Expected %v, got %v", expected, converted.ExpiredAt)
+		}
+
+		if len(repository.histories) != 1 || repository.histories[0].Event != models.SubscriptionEventTrialConverted {
+			t.Errorf("Expected [%s], got %v", models.SubscriptionEventTrialConverted, historyEvents(repository.histories))
+		}
+	})
+
+	t.Run("Should let a user on a trial subscribe to another subscription", func(t *testing.T) {
+		trialEndsAt := time.Now().AddDate(0, 0, 5)
+		repository := newFakeSubscriptionRepository(&models.UserSubscription{
+			ID: 1, UserID: 10, SubID: 3, ExpiredAt: trialEndsAt, IsActive: true, TrialEndsAt: &trialEndsAt,
+			Sub: models.Subscription{ID: 3, Price: money.FromMajor(30000, money.IDR)},
+		})
+		service := &SubscriptionChangeServiceInstance{SubscriptionRepository: repository}
+
+		if err := service.VerifyCanSubscribe(10, repository.subscriptions[2]); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if _, err := service.PreviewSubscriptionChange(10, 2); err == nil || err.Code != 400 {
+			t.Errorf("Expected bad request error, got %v", err)
+		}
+	})
+
+	t.Run("Should record the end of a trial that was not paid for", func(t *testing.T) {
+		trialEndsAt := time.Now().Add(-time.Hour)
+		repository := newFakeSubscriptionRepository(
+			&models.UserSubscription{ID: 1, UserID: 10, SubID: 3, ExpiredAt: trialEndsAt, IsActive: true, TrialEndsAt: &trialEndsAt},
+		)
+		service := &SubscriptionExpiryServiceInstance{SubscriptionRepository: repository, TransactionManager: &fakeTransactionManager{}}
+
+		expired, err := service.expire(1, time.Now())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !expired {
+			t.Fatalf("Expected true, got %t", expired)
+		}
+
+		if len(repository.histories) != 1 || repository.histories[0].Event != models.SubscriptionEventTrialEnded {
+			t.Errorf("Expected [%s], got %v", models.SubscriptionEventTrialEnded, historyEvents(repository.histories))
+		}
+	})
+}
+
+func TestExpiryReminderEmail(t *testing.T) {
+	t.Run("Should remind a trial to subscribe", func(t *testing.T) {
+		trialEndsAt := time.Now().AddDate(0, 0, 1)
+		userSubscription := &models.UserSubscription{ID: 1, SubID: 3, ExpiredAt: trialEndsAt, TrialEndsAt: &trialEndsAt, Sub: models.Subscription{Name: "Business"}}
+
+		template, subject, data := expiryReminderEmail(userSubscription, 1)
+		if template != trialEndingTemplate {
+			t.Errorf("Expected %s, got %s", trialEndingTemplate, template)
+		}
+
+		if subject != "Catalyst - Your Business trial ends tomorrow" {
+			t.Errorf("Expected Catalyst - Your Business trial ends tomorrow, got %s", subject)
+		}
+
+		if data["SubscribeLink"] == nil {
+			t.Error("Expected a SubscribeLink, got nil")
+		}
+	})
+
+	t.Run("Should remind a paid subscription to renew", func(t *testing.T) {
+		userSubscription := &models.UserSubscription{ID: 1, SubID: 3, ExpiredAt: time.Now().AddDate(0, 0, 7), Sub: models.Subscription{Name: "Business"}}
+
+		template, subject, data := expiryReminderEmail(userSubscription, 7)
+		if template != expiryReminderTemplate {
+			t.Errorf("Expected %s, got %s", expiryReminderTemplate, template)
+		}
+
+		if subject != "Catalyst - Your Business subscription expires in 7 days" {
+			t.Errorf("Expected Catalyst - Your Business subscription expires in 7 days, got %s", subject)
+		}
+
+		if data["RenewLink"] == nil {
+			t.Error("Expected a RenewLink, got nil")
+		}
+	})
+}
@@ -68,6 +68,13 @@ func (s *UserServiceInstance) Create(user *models.User, merchant *models.Merchan
 		}
 	}
 
+	referralCode, err := newReferralCode()
+	if err != nil {
+		return nil, errors.Internal("Failed to generate referral code", err.Error())
+	}
+
+	user.ReferralCode = referralCode
+
 	createdUser, err := s.UserRepository.Create(user)
 	if err != nil {
 		return nil, errors.Internal("Failed to create user", err.Error())
@@ -98,6 +105,13 @@ func (s *UserServiceInstance) Create(user *models.User, merchant *models.Merchan
 
 func (s *UserServiceInstance) CreateOAuth(user *models.User, oauthRequest *dtos.CreateOAuthAccountDTO, merchant *models.Merchant) (*models.User, *errors.CustomError) {
 
+	referralCode, err := newReferralCode()
+	if err != nil {
+		return nil, errors.Internal("Failed to generate referral code", err.Error())
+	}
+
+	user.ReferralCode = referralCode
+
 	createdUser, err := s.UserRepository.Create(user)
 	if err != nil {
 		return nil, errors.Internal("Failed to create user", err.Error())
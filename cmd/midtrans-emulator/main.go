@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"senkou-catalyst-be/integrations/midtrans/emulator"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Run a local Midtrans Core API emulator, or drive the transactions of a running one
+//
+//	go run ./cmd/midtrans-emulator serve [-addr :8090] [-notification-url URL]
+//	go run ./cmd/midtrans-emulator settlement|expire|deny|cancel|notify -order ORDER_ID [-url http://localhost:8090]
+//	go run ./cmd/midtrans-emulator list [-url http://localhost:8090]
+//
+// Point the application to the emulator with MIDTRANS_BASE_URL=http://localhost:8090
+func main() {
+	// The emulator does not need every variable of the application, so a missing .env is fine
+	_ = godotenv.Load()
+
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = serve(args)
+	case "list":
+		err = list(args)
+	case emulator.StatusSettlement, emulator.StatusExpire, emulator.StatusDeny, emulator.StatusCancel, "notify":
+		err = trigger(command, args)
+	default:
+		err = fmt.Errorf("unknown command: %s", command)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", getEnv("MIDTRANS_EMULATOR_ADDR", ":8090"), "address the emulator listens on")
+	notificationURL := flags.String("notification-url", getEnv("MIDTRANS_EMULATOR_NOTIFICATION_URL", defaultNotificationURL()), "URL the payment notifications are sent to")
+	flags.Parse(args)
+
+	e := emulator.New(os.Getenv("MIDTRANS_SERVER_KEY"))
+	e.NotificationURL = *notificationURL
+
+	log.Printf("Midtrans emulator listening on %s, notifications are sent to %s", *addr, e.NotificationURL)
+
+	return http.ListenAndServe(*addr, e.Handler())
+}
+
+func list(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	url := flags.String("url", emulatorURL(), "URL of the running emulator")
+	flags.Parse(args)
+
+	return call(http.MethodGet, *url+"/emulator/transactions")
+}
+
+// Move a transaction of the running emulator to a new status and notify the application
+// The notify action sends the notification of the current status again, like a Midtrans retry
+func trigger(action string, args []string) error {
+	flags := flag.NewFlagSet(action, flag.ExitOnError)
+	url := flags.String("url", emulatorURL(), "URL of the running emulator")
+	orderID := flags.String("order", "", "order ID of the transaction")
+	flags.Parse(args)
+
+	if *orderID == "" {
+		return fmt.Errorf("the -order flag is required")
+	}
+
+	return call(http.MethodPost, fmt.Sprintf("%s/emulator/transactions/%s/%s", *url, *orderID, action))
+}
+
+// Call the emulator with the server key and print its answer
+func call(method, url string) error {
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+
+	serverKey := os.Getenv("MIDTRANS_SERVER_KEY")
+	if serverKey == "" {
+		serverKey = emulator.DefaultServerKey
+	}
+	request.SetBasicAuth(serverKey, "")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(body))
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("emulator answered with status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+func emulatorURL() string {
+	addr := getEnv("MIDTRANS_EMULATOR_ADDR", ":8090")
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+
+	return "http://" + addr
+}
+
+func defaultNotificationURL() string {
+	return strings.TrimRight(getEnv("APP_URL", "http://localhost:8080"), "/") + emulator.DefaultNotificationPath
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
@@ -62,9 +62,11 @@ func main() {
 func register(c *SeederContext) {
 	c.Register("user_seeder", seeder.SeedUsers)
 	c.Register("subscription_seeder", seeder.SeedSubscriptions)
+	c.Register("payment_method_seeder", seeder.SeedPaymentMethods)
 
 	seederPool := []SeederClosure{
 		seeder.SeedSubscriptions,
+		seeder.SeedPaymentMethods,
 		seeder.SeedUsers,
 	}
 
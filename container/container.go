@@ -4,6 +4,7 @@ import (
 	"log"
 	"senkou-catalyst-be/app/controllers"
 	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/utils/config"
 	"senkou-catalyst-be/utils/queue"
 )
 
@@ -17,11 +18,19 @@ type Container struct {
 	OAuthController              *controllers.OAuthController
 	SubscriptionController       *controllers.SubscriptionController
 	PaymentMethodsController     *controllers.PaymentMethodsController
+	PromoCodeController          *controllers.PromoCodeController
 	PaymentController            *controllers.PaymentController
 	StorageController            *controllers.StorageController
+	AnalyticsController          *controllers.AnalyticsController
+	EntitlementController        *controllers.EntitlementController
 	UserService                  services.UserService
 	ProductService               services.ProductService
 	QueueService                 *queue.QueueService
+	PaymentReconciliationService services.PaymentReconciliationService
+	InvoiceService               services.InvoiceService
+	SubscriptionExpiryService    services.SubscriptionExpiryService
+	EntitlementService           services.EntitlementService
+	AuthService                  services.AuthService
 }
 
 func (c *Container) StartQueueService() {
@@ -29,6 +38,8 @@ func (c *Container) StartQueueService() {
 
 		// Register handlers
 		c.QueueService.RegisterEmailHandlers()
+		c.registerPaymentTasks()
+		c.registerSubscriptionTasks()
 
 		go func() {
 			if err := c.QueueService.Start(); err != nil {
@@ -39,3 +50,50 @@ func (c *Container) StartQueueService() {
 		log.Println("Queue service started with email handlers")
 	}
 }
+
+// Register the payment tasks and schedule the periodic ones
+// The reconciliation schedule can be changed with PAYMENT_RECONCILE_CRON
+func (c *Container) registerPaymentTasks() {
+	if c.InvoiceService != nil {
+		c.QueueService.RegisterHandlerFunc(
+			services.TaskSendOrderInvoice,
+			c.InvoiceService.HandleSendOrderInvoice,
+		)
+	}
+
+	if c.PaymentReconciliationService == nil {
+		return
+	}
+
+	c.QueueService.RegisterHandlerFunc(
+		services.TaskReconcilePendingPayments,
+		c.PaymentReconciliationService.HandleReconcilePendingPayments,
+	)
+
+	cronspec := config.GetEnv("PAYMENT_RECONCILE_CRON", "*/10 * * * *")
+	if _, err := c.QueueService.SchedulePeriodicTask(cronspec, services.TaskReconcilePendingPayments, nil); err != nil {
+		log.Printf("Failed to schedule payment reconciliation: %v", err)
+	}
+}
+
+// Register the subscription tasks and schedule the periodic ones
+// The expiry schedule can be changed with SUBSCRIPTION_EXPIRY_CRON
+func (c *Container) registerSubscriptionTasks() {
+	if c.SubscriptionExpiryService == nil {
+		return
+	}
+
+	c.QueueService.RegisterHandlerFunc(
+		services.TaskSendExpiryReminder,
+		c.SubscriptionExpiryService.HandleSendExpiryReminder,
+	)
+	c.QueueService.RegisterHandlerFunc(
+		services.TaskProcessSubscriptionExpiries,
+		c.SubscriptionExpiryService.HandleProcessSubscriptionExpiries,
+	)
+
+	cronspec := config.GetEnv("SUBSCRIPTION_EXPIRY_CRON", "0 * * * *")
+	if _, err := c.QueueService.SchedulePeriodicTask(cronspec, services.TaskProcessSubscriptionExpiries, nil); err != nil {
+		log.Printf("Failed to schedule subscription expiries: %v", err)
+	}
+}
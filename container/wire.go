@@ -6,7 +6,9 @@ package container
 import (
 	"senkou-catalyst-be/app/controllers"
 	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/integrations/gateway"
 	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/integrations/xendit"
 	"senkou-catalyst-be/platform/config"
 	"senkou-catalyst-be/repositories"
 
@@ -36,6 +38,14 @@ var RepositorySet = wire.NewSet(
 	repositories.NewSubscriptionPlanRepository,
 	repositories.NewSubscriptionOrderRepository,
 	repositories.NewPaymentTransactionRepository,
+	repositories.NewPaymentRefundRepository,
+	repositories.NewPaymentNotificationRepository,
+	repositories.NewAnalyticsRepository,
+	repositories.NewPaymentMethodRepository,
+	repositories.NewPromoCodeRepository,
+	repositories.NewReferralRepository,
+	repositories.NewPasswordResetRepository,
+	repositories.NewTransactionManager,
 )
 
 var ServiceSet = wire.NewSet(
@@ -48,8 +58,18 @@ var ServiceSet = wire.NewSet(
 	services.NewAuthService,
 	services.NewSubscriptionService,
 	services.NewSubscriptionOrderService,
+	services.NewSubscriptionChangeService,
+	services.NewSubscriptionTrialService,
+	services.NewEntitlementService,
 	services.NewPaymentMethodsService,
+	services.NewPromoCodeService,
+	services.NewReferralService,
 	services.NewPaymentService,
+	services.NewPaymentNotificationService,
+	services.NewPaymentReconciliationService,
+	services.NewInvoiceService,
+	services.NewSubscriptionExpiryService,
+	services.NewAnalyticsService,
 	mailerUtil.NewMailerService,
 )
 
@@ -63,8 +83,11 @@ var ControllerSet = wire.NewSet(
 	controllers.NewOAuthController,
 	controllers.NewSubscriptionController,
 	controllers.NewPaymentMethodsController,
+	controllers.NewPromoCodeController,
 	controllers.NewPaymentController,
 	controllers.NewStorageController,
+	controllers.NewAnalyticsController,
+	controllers.NewEntitlementController,
 )
 
 func ProvideJWTManager() (*authUtil.JWTManager, error) {
@@ -80,13 +103,28 @@ func ProvideMidtransClient() (*midtrans.MidtransClient, error) {
 	return midtrans.NewMidtransClient(), nil
 }
 
-func ProvideMidtransBuilder(client *midtrans.MidtransClient) *midtrans.PaymentBuilder {
-	return midtrans.NewPaymentBuilder(client)
+// Provide the payment gateways of every configured provider
+// Midtrans is always available, Xendit needs XENDIT_SECRET_KEY and the fake gateway
+// is only registered when PAYMENT_FAKE_GATEWAY_ENABLED is set for local development
+func ProvidePaymentGateways(midtransClient *midtrans.MidtransClient) (*gateway.Registry, error) {
+	gateways := []gateway.PaymentGateway{
+		gateway.NewMidtransGateway(midtransClient),
+	}
+
+	if xenditClient := xendit.NewXenditClient(); xenditClient.IsConfigured() {
+		gateways = append(gateways, gateway.NewXenditGateway(xenditClient))
+	}
+
+	if configUtil.GetEnvAsBool("PAYMENT_FAKE_GATEWAY_ENABLED", false) {
+		gateways = append(gateways, gateway.NewFakeGateway(configUtil.GetEnv("PAYMENT_FAKE_GATEWAY_TOKEN", "")))
+	}
+
+	return gateway.NewRegistry(gateways...), nil
 }
 
 var MidtransSet = wire.NewSet(
 	ProvideMidtransClient,
-	ProvideMidtransBuilder,
+	ProvidePaymentGateways,
 )
 
 func ProvideQueueService() (*queue.QueueService, error) {
@@ -104,6 +142,7 @@ func InitializeUserController() (*controllers.UserController, error) {
 		RepositorySet,
 		ServiceSet,
 		ControllerSet,
+		UtilSet,
 		QueueSet,
 	)
 	return nil, nil
@@ -193,12 +232,46 @@ func InitializePaymentController() (*controllers.PaymentController, error) {
 		ServiceSet,
 		ControllerSet,
 		MidtransSet,
+		QueueSet,
 	)
 	return nil, nil
 }
 
 func InitializePaymentMethodsController() (*controllers.PaymentMethodsController, error) {
 	wire.Build(
+		DatabaseSet,
+		RepositorySet,
+		ServiceSet,
+		ControllerSet,
+		MidtransSet,
+	)
+	return nil, nil
+}
+
+func InitializePromoCodeController() (*controllers.PromoCodeController, error) {
+	wire.Build(
+		DatabaseSet,
+		RepositorySet,
+		ServiceSet,
+		ControllerSet,
+	)
+	return nil, nil
+}
+
+func InitializeAnalyticsController() (*controllers.AnalyticsController, error) {
+	wire.Build(
+		DatabaseSet,
+		RepositorySet,
+		ServiceSet,
+		ControllerSet,
+	)
+	return nil, nil
+}
+
+func InitializeEntitlementController() (*controllers.EntitlementController, error) {
+	wire.Build(
+		DatabaseSet,
+		RepositorySet,
 		ServiceSet,
 		ControllerSet,
 	)
@@ -238,13 +311,18 @@ func InitializeSubscriptionOrderService() (services.SubscriptionOrderService, fu
 		DatabaseSet,
 		RepositorySet,
 		ServiceSet,
+		MidtransSet,
+		QueueSet,
 	)
 	return nil, nil, nil
 }
 
 func InitializePaymentMethodsService() (services.PaymentMethodsService, func(), error) {
 	wire.Build(
+		DatabaseSet,
+		RepositorySet,
 		ServiceSet,
+		MidtransSet,
 	)
 	return nil, nil, nil
 }
@@ -283,11 +361,19 @@ func NewContainer(
 	oauthController *controllers.OAuthController,
 	subscriptionController *controllers.SubscriptionController,
 	paymentMethodsController *controllers.PaymentMethodsController,
+	promoCodeController *controllers.PromoCodeController,
 	paymentController *controllers.PaymentController,
 	storageController *controllers.StorageController,
+	analyticsController *controllers.AnalyticsController,
+	entitlementController *controllers.EntitlementController,
 	userService services.UserService,
 	productService services.ProductService,
 	queueService *queue.QueueService,
+	paymentReconciliationService services.PaymentReconciliationService,
+	invoiceService services.InvoiceService,
+	subscriptionExpiryService services.SubscriptionExpiryService,
+	entitlementService services.EntitlementService,
+	authService services.AuthService,
 ) *Container {
 	return &Container{
 		UserController:               userController,
@@ -299,10 +385,18 @@ func NewContainer(
 		OAuthController:              oauthController,
 		SubscriptionController:       subscriptionController,
 		PaymentMethodsController:     paymentMethodsController,
+		PromoCodeController:          promoCodeController,
 		PaymentController:            paymentController,
 		StorageController:            storageController,
+		AnalyticsController:          analyticsController,
+		EntitlementController:        entitlementController,
 		UserService:                  userService,
 		ProductService:               productService,
 		QueueService:                 queueService,
+		PaymentReconciliationService: paymentReconciliationService,
+		InvoiceService:               invoiceService,
+		SubscriptionExpiryService:    subscriptionExpiryService,
+		EntitlementService:           entitlementService,
+		AuthService:                  authService,
 	}
 }
@@ -10,7 +10,9 @@ import (
 	"github.com/google/wire"
 	"senkou-catalyst-be/app/controllers"
 	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/integrations/gateway"
 	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/integrations/xendit"
 	"senkou-catalyst-be/platform/config"
 	"senkou-catalyst-be/repositories"
 	"senkou-catalyst-be/utils/auth"
@@ -37,8 +39,18 @@ func InitializeUserController() (*controllers.UserController, error) {
 	merchantService := services.NewMerchantService(merchantRepository, productRepository, categoryRepository)
 	subscriptionRepository := repositories.NewSubscriptionRepository(db)
 	subscriptionPlanRepository := repositories.NewSubscriptionPlanRepository(db)
-	subscriptionService := services.NewSubscriptionService(subscriptionRepository, subscriptionPlanRepository)
-	userController := controllers.NewUserController(userService, merchantService, subscriptionService)
+	transactionManager := repositories.NewTransactionManager(db)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepository, subscriptionPlanRepository, transactionManager)
+	referralRepository := repositories.NewReferralRepository(db)
+	referralService := services.NewReferralService(userRepository, referralRepository)
+	authRepository := repositories.NewAuthRepository(db)
+	passwordResetRepository := repositories.NewPasswordResetRepository(db)
+	jwtManager, err := ProvideJWTManager()
+	if err != nil {
+		return nil, err
+	}
+	authService := services.NewAuthService(authRepository, userRepository, passwordResetRepository, jwtManager, transactionManager, queueService)
+	userController := controllers.NewUserController(userService, merchantService, subscriptionService, referralService, authService)
 	return userController, nil
 }
 
@@ -59,9 +71,13 @@ func InitializeProductController() (*controllers.ProductController, error) {
 	productRepository := repositories.NewProductRepository(db)
 	userRepository := repositories.NewUserRepository(db)
 	productInteractionRepository := repositories.NewProductInteractionRepository(db)
-	productService := services.NewProductService(productRepository, userRepository, productInteractionRepository)
-	oAuthRepository := repositories.NewOAuthRepository(db)
 	merchantRepository := repositories.NewMerchantRepository(db)
+	transactionManager := repositories.NewTransactionManager(db)
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
+	categoryRepository := repositories.NewCategoryRepository(db)
+	entitlementService := services.NewEntitlementService(userRepository, subscriptionRepository, merchantRepository, productRepository, categoryRepository)
+	productService := services.NewProductService(productRepository, userRepository, productInteractionRepository, merchantRepository, transactionManager, entitlementService)
+	oAuthRepository := repositories.NewOAuthRepository(db)
 	emailActivationRepository := repositories.NewEmailActivationRepository(db)
 	queueService, err := ProvideQueueService()
 	if err != nil {
@@ -77,8 +93,12 @@ func InitializeCategoryController() (*controllers.CategoryController, error) {
 	db := config.GetDB()
 	categoryRepository := repositories.NewCategoryRepository(db)
 	merchantRepository := repositories.NewMerchantRepository(db)
-	categoryService := services.NewCategoryService(categoryRepository, merchantRepository)
+	transactionManager := repositories.NewTransactionManager(db)
+	userRepository := repositories.NewUserRepository(db)
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
 	productRepository := repositories.NewProductRepository(db)
+	entitlementService := services.NewEntitlementService(userRepository, subscriptionRepository, merchantRepository, productRepository, categoryRepository)
+	categoryService := services.NewCategoryService(categoryRepository, merchantRepository, transactionManager, entitlementService)
 	merchantService := services.NewMerchantService(merchantRepository, productRepository, categoryRepository)
 	categoryController := controllers.NewCategoryController(categoryService, merchantService)
 	return categoryController, nil
@@ -95,19 +115,21 @@ func InitializePredefinedCategoryController() (*controllers.PredefinedCategoryCo
 func InitializeAuthController() (*controllers.AuthController, error) {
 	db := config.GetDB()
 	authRepository := repositories.NewAuthRepository(db)
+	userRepository := repositories.NewUserRepository(db)
+	passwordResetRepository := repositories.NewPasswordResetRepository(db)
 	jwtManager, err := ProvideJWTManager()
 	if err != nil {
 		return nil, err
 	}
-	authService := services.NewAuthService(authRepository, jwtManager)
-	userRepository := repositories.NewUserRepository(db)
-	oAuthRepository := repositories.NewOAuthRepository(db)
-	merchantRepository := repositories.NewMerchantRepository(db)
-	emailActivationRepository := repositories.NewEmailActivationRepository(db)
+	transactionManager := repositories.NewTransactionManager(db)
 	queueService, err := ProvideQueueService()
 	if err != nil {
 		return nil, err
 	}
+	authService := services.NewAuthService(authRepository, userRepository, passwordResetRepository, jwtManager, transactionManager, queueService)
+	oAuthRepository := repositories.NewOAuthRepository(db)
+	merchantRepository := repositories.NewMerchantRepository(db)
+	emailActivationRepository := repositories.NewEmailActivationRepository(db)
 	userService := services.NewUserService(userRepository, oAuthRepository, merchantRepository, emailActivationRepository, queueService)
 	authController := controllers.NewAuthController(authService, userService)
 	return authController, nil
@@ -125,11 +147,13 @@ func InitializeOAuthController() (*controllers.OAuthController, error) {
 	}
 	userService := services.NewUserService(userRepository, oAuthRepository, merchantRepository, emailActivationRepository, queueService)
 	authRepository := repositories.NewAuthRepository(db)
+	passwordResetRepository := repositories.NewPasswordResetRepository(db)
 	jwtManager, err := ProvideJWTManager()
 	if err != nil {
 		return nil, err
 	}
-	authService := services.NewAuthService(authRepository, jwtManager)
+	transactionManager := repositories.NewTransactionManager(db)
+	authService := services.NewAuthService(authRepository, userRepository, passwordResetRepository, jwtManager, transactionManager, queueService)
 	oAuthController := controllers.NewOAuthController(userService, authService)
 	return oAuthController, nil
 }
@@ -147,16 +171,32 @@ func InitializeSubscriptionController() (*controllers.SubscriptionController, er
 	userService := services.NewUserService(userRepository, oAuthRepository, merchantRepository, emailActivationRepository, queueService)
 	subscriptionRepository := repositories.NewSubscriptionRepository(db)
 	subscriptionPlanRepository := repositories.NewSubscriptionPlanRepository(db)
-	subscriptionService := services.NewSubscriptionService(subscriptionRepository, subscriptionPlanRepository)
+	transactionManager := repositories.NewTransactionManager(db)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepository, subscriptionPlanRepository, transactionManager)
 	subscriptionOrderRepository := repositories.NewSubscriptionOrderRepository(db)
-	subscriptionOrderService := services.NewSubscriptionOrderService(subscriptionOrderRepository)
+	paymentTransactionRepository := repositories.NewPaymentTransactionRepository(db)
+	paymentRefundRepository := repositories.NewPaymentRefundRepository(db)
+	promoCodeRepository := repositories.NewPromoCodeRepository(db)
+	referralRepository := repositories.NewReferralRepository(db)
 	midtransClient, err := ProvideMidtransClient()
 	if err != nil {
 		return nil, err
 	}
-	paymentTransactionRepository := repositories.NewPaymentTransactionRepository(db)
-	paymentService := services.NewPaymentService(midtransClient, paymentTransactionRepository)
-	subscriptionController := controllers.NewSubscriptionController(userService, subscriptionService, subscriptionOrderService, paymentService)
+	registry, err := ProvidePaymentGateways(midtransClient)
+	if err != nil {
+		return nil, err
+	}
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+	paymentMethodsService := services.NewPaymentMethodsService(paymentMethodRepository, registry)
+	paymentService := services.NewPaymentService(registry, paymentTransactionRepository, paymentMethodsService)
+	invoiceService := services.NewInvoiceService(subscriptionOrderRepository, queueService)
+	subscriptionOrderService := services.NewSubscriptionOrderService(subscriptionOrderRepository, paymentTransactionRepository, paymentRefundRepository, subscriptionRepository, promoCodeRepository, referralRepository, transactionManager, paymentService, invoiceService)
+	productRepository := repositories.NewProductRepository(db)
+	categoryRepository := repositories.NewCategoryRepository(db)
+	subscriptionChangeService := services.NewSubscriptionChangeService(subscriptionRepository, merchantRepository, productRepository, categoryRepository)
+	subscriptionTrialService := services.NewSubscriptionTrialService(userRepository, subscriptionRepository, subscriptionOrderRepository, transactionManager)
+	promoCodeService := services.NewPromoCodeService(promoCodeRepository, subscriptionRepository)
+	subscriptionController := controllers.NewSubscriptionController(userService, subscriptionService, subscriptionOrderService, subscriptionChangeService, subscriptionTrialService, promoCodeService, paymentService, paymentMethodsService, invoiceService)
 	return subscriptionController, nil
 }
 
@@ -165,19 +205,78 @@ func InitializePaymentController() (*controllers.PaymentController, error) {
 	if err != nil {
 		return nil, err
 	}
+	registry, err := ProvidePaymentGateways(midtransClient)
+	if err != nil {
+		return nil, err
+	}
 	db := config.GetDB()
 	paymentTransactionRepository := repositories.NewPaymentTransactionRepository(db)
-	paymentService := services.NewPaymentService(midtransClient, paymentTransactionRepository)
-	paymentController := controllers.NewPaymentController(paymentService)
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+	paymentMethodsService := services.NewPaymentMethodsService(paymentMethodRepository, registry)
+	paymentService := services.NewPaymentService(registry, paymentTransactionRepository, paymentMethodsService)
+	paymentNotificationRepository := repositories.NewPaymentNotificationRepository(db)
+	subscriptionOrderRepository := repositories.NewSubscriptionOrderRepository(db)
+	paymentRefundRepository := repositories.NewPaymentRefundRepository(db)
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
+	promoCodeRepository := repositories.NewPromoCodeRepository(db)
+	referralRepository := repositories.NewReferralRepository(db)
+	transactionManager := repositories.NewTransactionManager(db)
+	queueService, err := ProvideQueueService()
+	if err != nil {
+		return nil, err
+	}
+	invoiceService := services.NewInvoiceService(subscriptionOrderRepository, queueService)
+	subscriptionOrderService := services.NewSubscriptionOrderService(subscriptionOrderRepository, paymentTransactionRepository, paymentRefundRepository, subscriptionRepository, promoCodeRepository, referralRepository, transactionManager, paymentService, invoiceService)
+	paymentNotificationService := services.NewPaymentNotificationService(paymentNotificationRepository, paymentService, subscriptionOrderService)
+	paymentController := controllers.NewPaymentController(paymentService, paymentNotificationService, subscriptionOrderService)
 	return paymentController, nil
 }
 
 func InitializePaymentMethodsController() (*controllers.PaymentMethodsController, error) {
-	paymentMethodsService := services.NewPaymentMethodsService()
+	db := config.GetDB()
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+	midtransClient, err := ProvideMidtransClient()
+	if err != nil {
+		return nil, err
+	}
+	registry, err := ProvidePaymentGateways(midtransClient)
+	if err != nil {
+		return nil, err
+	}
+	paymentMethodsService := services.NewPaymentMethodsService(paymentMethodRepository, registry)
 	paymentMethodsController := controllers.NewPaymentMethodsController(paymentMethodsService)
 	return paymentMethodsController, nil
 }
 
+func InitializePromoCodeController() (*controllers.PromoCodeController, error) {
+	db := config.GetDB()
+	promoCodeRepository := repositories.NewPromoCodeRepository(db)
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
+	promoCodeService := services.NewPromoCodeService(promoCodeRepository, subscriptionRepository)
+	promoCodeController := controllers.NewPromoCodeController(promoCodeService)
+	return promoCodeController, nil
+}
+
+func InitializeAnalyticsController() (*controllers.AnalyticsController, error) {
+	db := config.GetDB()
+	analyticsRepository := repositories.NewAnalyticsRepository(db)
+	analyticsService := services.NewAnalyticsService(analyticsRepository)
+	analyticsController := controllers.NewAnalyticsController(analyticsService)
+	return analyticsController, nil
+}
+
+func InitializeEntitlementController() (*controllers.EntitlementController, error) {
+	db := config.GetDB()
+	userRepository := repositories.NewUserRepository(db)
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
+	merchantRepository := repositories.NewMerchantRepository(db)
+	productRepository := repositories.NewProductRepository(db)
+	categoryRepository := repositories.NewCategoryRepository(db)
+	entitlementService := services.NewEntitlementService(userRepository, subscriptionRepository, merchantRepository, productRepository, categoryRepository)
+	entitlementController := controllers.NewEntitlementController(entitlementService)
+	return entitlementController, nil
+}
+
 func InitializeUserService() (services.UserService, func(), error) {
 	db := config.GetDB()
 	userRepository := repositories.NewUserRepository(db)
@@ -198,7 +297,12 @@ func InitializeProductService() (services.ProductService, func(), error) {
 	productRepository := repositories.NewProductRepository(db)
 	userRepository := repositories.NewUserRepository(db)
 	productInteractionRepository := repositories.NewProductInteractionRepository(db)
-	productService := services.NewProductService(productRepository, userRepository, productInteractionRepository)
+	merchantRepository := repositories.NewMerchantRepository(db)
+	transactionManager := repositories.NewTransactionManager(db)
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
+	categoryRepository := repositories.NewCategoryRepository(db)
+	entitlementService := services.NewEntitlementService(userRepository, subscriptionRepository, merchantRepository, productRepository, categoryRepository)
+	productService := services.NewProductService(productRepository, userRepository, productInteractionRepository, merchantRepository, transactionManager, entitlementService)
 	return productService, func() {
 	}, nil
 }
@@ -214,13 +318,45 @@ func InitializeProductInteractionService() (services.ProductInteractionService,
 func InitializeSubscriptionOrderService() (services.SubscriptionOrderService, func(), error) {
 	db := config.GetDB()
 	subscriptionOrderRepository := repositories.NewSubscriptionOrderRepository(db)
-	subscriptionOrderService := services.NewSubscriptionOrderService(subscriptionOrderRepository)
+	paymentTransactionRepository := repositories.NewPaymentTransactionRepository(db)
+	paymentRefundRepository := repositories.NewPaymentRefundRepository(db)
+	subscriptionRepository := repositories.NewSubscriptionRepository(db)
+	promoCodeRepository := repositories.NewPromoCodeRepository(db)
+	referralRepository := repositories.NewReferralRepository(db)
+	transactionManager := repositories.NewTransactionManager(db)
+	midtransClient, err := ProvideMidtransClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	registry, err := ProvidePaymentGateways(midtransClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+	paymentMethodsService := services.NewPaymentMethodsService(paymentMethodRepository, registry)
+	paymentService := services.NewPaymentService(registry, paymentTransactionRepository, paymentMethodsService)
+	queueService, err := ProvideQueueService()
+	if err != nil {
+		return nil, nil, err
+	}
+	invoiceService := services.NewInvoiceService(subscriptionOrderRepository, queueService)
+	subscriptionOrderService := services.NewSubscriptionOrderService(subscriptionOrderRepository, paymentTransactionRepository, paymentRefundRepository, subscriptionRepository, promoCodeRepository, referralRepository, transactionManager, paymentService, invoiceService)
 	return subscriptionOrderService, func() {
 	}, nil
 }
 
 func InitializePaymentMethodsService() (services.PaymentMethodsService, func(), error) {
-	paymentMethodsService := services.NewPaymentMethodsService()
+	db := config.GetDB()
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+	midtransClient, err := ProvideMidtransClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	registry, err := ProvidePaymentGateways(midtransClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	paymentMethodsService := services.NewPaymentMethodsService(paymentMethodRepository, registry)
 	return paymentMethodsService, func() {
 	}, nil
 }
@@ -230,9 +366,15 @@ func InitializePaymentService() (services.PaymentService, func(), error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	registry, err := ProvidePaymentGateways(midtransClient)
+	if err != nil {
+		return nil, nil, err
+	}
 	db := config.GetDB()
 	paymentTransactionRepository := repositories.NewPaymentTransactionRepository(db)
-	paymentService := services.NewPaymentService(midtransClient, paymentTransactionRepository)
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+	paymentMethodsService := services.NewPaymentMethodsService(paymentMethodRepository, registry)
+	paymentService := services.NewPaymentService(registry, paymentTransactionRepository, paymentMethodsService)
 	return paymentService, func() {
 	}, nil
 }
@@ -253,40 +395,65 @@ func InitializeContainer() (*Container, error) {
 	merchantService := services.NewMerchantService(merchantRepository, productRepository, categoryRepository)
 	subscriptionRepository := repositories.NewSubscriptionRepository(db)
 	subscriptionPlanRepository := repositories.NewSubscriptionPlanRepository(db)
-	subscriptionService := services.NewSubscriptionService(subscriptionRepository, subscriptionPlanRepository)
-	userController := controllers.NewUserController(userService, merchantService, subscriptionService)
+	transactionManager := repositories.NewTransactionManager(db)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepository, subscriptionPlanRepository, transactionManager)
+	referralRepository := repositories.NewReferralRepository(db)
+	referralService := services.NewReferralService(userRepository, referralRepository)
+	authRepository := repositories.NewAuthRepository(db)
+	passwordResetRepository := repositories.NewPasswordResetRepository(db)
+	jwtManager, err := ProvideJWTManager()
+	if err != nil {
+		return nil, err
+	}
+	authService := services.NewAuthService(authRepository, userRepository, passwordResetRepository, jwtManager, transactionManager, queueService)
+	userController := controllers.NewUserController(userService, merchantService, subscriptionService, referralService, authService)
 	productInteractionRepository := repositories.NewProductInteractionRepository(db)
 	productInteractionService := services.NewProductInteractionService(productInteractionRepository)
 	merchantController := controllers.NewMerchantController(merchantService, productInteractionService)
-	productService := services.NewProductService(productRepository, userRepository, productInteractionRepository)
+	entitlementService := services.NewEntitlementService(userRepository, subscriptionRepository, merchantRepository, productRepository, categoryRepository)
+	productService := services.NewProductService(productRepository, userRepository, productInteractionRepository, merchantRepository, transactionManager, entitlementService)
 	productController := controllers.NewProductController(productService, userService, productInteractionService)
-	categoryService := services.NewCategoryService(categoryRepository, merchantRepository)
+	categoryService := services.NewCategoryService(categoryRepository, merchantRepository, transactionManager, entitlementService)
 	categoryController := controllers.NewCategoryController(categoryService, merchantService)
 	predefinedCategoryRepository := repositories.NewPredefinedCategoryRepository(db)
 	predefinedCategoryService := services.NewPredefinedCategoryService(predefinedCategoryRepository)
 	predefinedCategoryController := controllers.NewPredefinedCategoryController(predefinedCategoryService)
-	authRepository := repositories.NewAuthRepository(db)
-	jwtManager, err := ProvideJWTManager()
-	if err != nil {
-		return nil, err
-	}
-	authService := services.NewAuthService(authRepository, jwtManager)
 	authController := controllers.NewAuthController(authService, userService)
 	oAuthController := controllers.NewOAuthController(userService, authService)
 	subscriptionOrderRepository := repositories.NewSubscriptionOrderRepository(db)
-	subscriptionOrderService := services.NewSubscriptionOrderService(subscriptionOrderRepository)
+	paymentTransactionRepository := repositories.NewPaymentTransactionRepository(db)
+	paymentRefundRepository := repositories.NewPaymentRefundRepository(db)
+	promoCodeRepository := repositories.NewPromoCodeRepository(db)
 	midtransClient, err := ProvideMidtransClient()
 	if err != nil {
 		return nil, err
 	}
-	paymentTransactionRepository := repositories.NewPaymentTransactionRepository(db)
-	paymentService := services.NewPaymentService(midtransClient, paymentTransactionRepository)
-	subscriptionController := controllers.NewSubscriptionController(userService, subscriptionService, subscriptionOrderService, paymentService)
-	paymentMethodsService := services.NewPaymentMethodsService()
+	registry, err := ProvidePaymentGateways(midtransClient)
+	if err != nil {
+		return nil, err
+	}
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+	paymentMethodsService := services.NewPaymentMethodsService(paymentMethodRepository, registry)
+	paymentService := services.NewPaymentService(registry, paymentTransactionRepository, paymentMethodsService)
+	invoiceService := services.NewInvoiceService(subscriptionOrderRepository, queueService)
+	subscriptionOrderService := services.NewSubscriptionOrderService(subscriptionOrderRepository, paymentTransactionRepository, paymentRefundRepository, subscriptionRepository, promoCodeRepository, referralRepository, transactionManager, paymentService, invoiceService)
+	subscriptionChangeService := services.NewSubscriptionChangeService(subscriptionRepository, merchantRepository, productRepository, categoryRepository)
+	subscriptionTrialService := services.NewSubscriptionTrialService(userRepository, subscriptionRepository, subscriptionOrderRepository, transactionManager)
+	promoCodeService := services.NewPromoCodeService(promoCodeRepository, subscriptionRepository)
+	subscriptionController := controllers.NewSubscriptionController(userService, subscriptionService, subscriptionOrderService, subscriptionChangeService, subscriptionTrialService, promoCodeService, paymentService, paymentMethodsService, invoiceService)
 	paymentMethodsController := controllers.NewPaymentMethodsController(paymentMethodsService)
-	paymentController := controllers.NewPaymentController(paymentService)
+	promoCodeController := controllers.NewPromoCodeController(promoCodeService)
+	paymentNotificationRepository := repositories.NewPaymentNotificationRepository(db)
+	paymentNotificationService := services.NewPaymentNotificationService(paymentNotificationRepository, paymentService, subscriptionOrderService)
+	paymentController := controllers.NewPaymentController(paymentService, paymentNotificationService, subscriptionOrderService)
 	storageController := controllers.NewStorageController()
-	container := NewContainer(userController, merchantController, productController, categoryController, predefinedCategoryController, authController, oAuthController, subscriptionController, paymentMethodsController, paymentController, storageController, userService, productService, queueService)
+	analyticsRepository := repositories.NewAnalyticsRepository(db)
+	analyticsService := services.NewAnalyticsService(analyticsRepository)
+	analyticsController := controllers.NewAnalyticsController(analyticsService)
+	entitlementController := controllers.NewEntitlementController(entitlementService)
+	paymentReconciliationService := services.NewPaymentReconciliationService(registry, paymentTransactionRepository, subscriptionOrderService)
+	subscriptionExpiryService := services.NewSubscriptionExpiryService(subscriptionRepository, transactionManager, queueService)
+	container := NewContainer(userController, merchantController, productController, categoryController, predefinedCategoryController, authController, oAuthController, subscriptionController, paymentMethodsController, promoCodeController, paymentController, storageController, analyticsController, entitlementController, userService, productService, queueService, paymentReconciliationService, invoiceService, subscriptionExpiryService, entitlementService, authService)
 	return container, nil
 }
 
@@ -294,11 +461,11 @@ func InitializeContainer() (*Container, error) {
 
 var DatabaseSet = wire.NewSet(config.GetDB)
 
-var RepositorySet = wire.NewSet(repositories.NewUserRepository, repositories.NewMerchantRepository, repositories.NewEmailActivationRepository, repositories.NewProductRepository, repositories.NewProductInteractionRepository, repositories.NewCategoryRepository, repositories.NewPredefinedCategoryRepository, repositories.NewAuthRepository, repositories.NewOAuthRepository, repositories.NewSubscriptionRepository, repositories.NewSubscriptionPlanRepository, repositories.NewSubscriptionOrderRepository, repositories.NewPaymentTransactionRepository)
+var RepositorySet = wire.NewSet(repositories.NewUserRepository, repositories.NewMerchantRepository, repositories.NewEmailActivationRepository, repositories.NewProductRepository, repositories.NewProductInteractionRepository, repositories.NewCategoryRepository, repositories.NewPredefinedCategoryRepository, repositories.NewAuthRepository, repositories.NewOAuthRepository, repositories.NewSubscriptionRepository, repositories.NewSubscriptionPlanRepository, repositories.NewSubscriptionOrderRepository, repositories.NewPaymentTransactionRepository, repositories.NewPaymentRefundRepository, repositories.NewPaymentNotificationRepository, repositories.NewAnalyticsRepository, repositories.NewPaymentMethodRepository, repositories.NewPromoCodeRepository, repositories.NewReferralRepository, repositories.NewPasswordResetRepository, repositories.NewTransactionManager)
 
-var ServiceSet = wire.NewSet(services.NewUserService, services.NewMerchantService, services.NewProductService, services.NewProductInteractionService, services.NewCategoryService, services.NewPredefinedCategoryService, services.NewAuthService, services.NewSubscriptionService, services.NewSubscriptionOrderService, services.NewPaymentMethodsService, services.NewPaymentService, mailer.NewMailerService)
+var ServiceSet = wire.NewSet(services.NewUserService, services.NewMerchantService, services.NewProductService, services.NewProductInteractionService, services.NewCategoryService, services.NewPredefinedCategoryService, services.NewAuthService, services.NewSubscriptionService, services.NewSubscriptionOrderService, services.NewSubscriptionChangeService, services.NewSubscriptionTrialService, services.NewEntitlementService, services.NewPaymentMethodsService, services.NewPromoCodeService, services.NewReferralService, services.NewPaymentService, services.NewPaymentNotificationService, services.NewPaymentReconciliationService, services.NewInvoiceService, services.NewSubscriptionExpiryService, services.NewAnalyticsService, mailer.NewMailerService)
 
-var ControllerSet = wire.NewSet(controllers.NewUserController, controllers.NewMerchantController, controllers.NewProductController, controllers.NewCategoryController, controllers.NewPredefinedCategoryController, controllers.NewAuthController, controllers.NewOAuthController, controllers.NewSubscriptionController, controllers.NewPaymentMethodsController, controllers.NewPaymentController, controllers.NewStorageController)
+var ControllerSet = wire.NewSet(controllers.NewUserController, controllers.NewMerchantController, controllers.NewProductController, controllers.NewCategoryController, controllers.NewPredefinedCategoryController, controllers.NewAuthController, controllers.NewOAuthController, controllers.NewSubscriptionController, controllers.NewPaymentMethodsController, controllers.NewPromoCodeController, controllers.NewPaymentController, controllers.NewStorageController, controllers.NewAnalyticsController, controllers.NewEntitlementController)
 
 func ProvideJWTManager() (*auth.JWTManager, error) {
 	secret := config2.MustGetEnv("AUTH_SECRET")
@@ -313,13 +480,26 @@ func ProvideMidtransClient() (*midtrans.MidtransClient, error) {
 	return midtrans.NewMidtransClient(), nil
 }
 
-func ProvideMidtransBuilder(client *midtrans.MidtransClient) *midtrans.PaymentBuilder {
-	return midtrans.NewPaymentBuilder(client)
+// Provide the payment gateways of every configured provider
+// Midtrans is always available, Xendit needs XENDIT_SECRET_KEY and the fake gateway
+// is only registered when PAYMENT_FAKE_GATEWAY_ENABLED is set for local development
+func ProvidePaymentGateways(midtransClient *midtrans.MidtransClient) (*gateway.Registry, error) {
+	gateways := []gateway.PaymentGateway{gateway.NewMidtransGateway(midtransClient)}
+
+	if xenditClient := xendit.NewXenditClient(); xenditClient.IsConfigured() {
+		gateways = append(gateways, gateway.NewXenditGateway(xenditClient))
+	}
+
+	if config2.GetEnvAsBool("PAYMENT_FAKE_GATEWAY_ENABLED", false) {
+		gateways = append(gateways, gateway.NewFakeGateway(config2.GetEnv("PAYMENT_FAKE_GATEWAY_TOKEN", "")))
+	}
+
+	return gateway.NewRegistry(gateways...), nil
 }
 
 var MidtransSet = wire.NewSet(
 	ProvideMidtransClient,
-	ProvideMidtransBuilder,
+	ProvidePaymentGateways,
 )
 
 func ProvideQueueService() (*queue.QueueService, error) {
@@ -341,11 +521,19 @@ func NewContainer(
 	oauthController *controllers.OAuthController,
 	subscriptionController *controllers.SubscriptionController,
 	paymentMethodsController *controllers.PaymentMethodsController,
+	promoCodeController *controllers.PromoCodeController,
 	paymentController *controllers.PaymentController,
 	storageController *controllers.StorageController,
+	analyticsController *controllers.AnalyticsController,
+	entitlementController *controllers.EntitlementController,
 	userService services.UserService,
 	productService services.ProductService,
 	queueService *queue.QueueService,
+	paymentReconciliationService services.PaymentReconciliationService,
+	invoiceService services.InvoiceService,
+	subscriptionExpiryService services.SubscriptionExpiryService,
+	entitlementService services.EntitlementService,
+	authService services.AuthService,
 ) *Container {
 	return &Container{
 		UserController:               userController,
@@ -357,10 +545,18 @@ func NewContainer(
 		OAuthController:              oauthController,
 		SubscriptionController:       subscriptionController,
 		PaymentMethodsController:     paymentMethodsController,
+		PromoCodeController:          promoCodeController,
 		PaymentController:            paymentController,
 		StorageController:            storageController,
+		AnalyticsController:          analyticsController,
+		EntitlementController:        entitlementController,
 		UserService:                  userService,
 		ProductService:               productService,
 		QueueService:                 queueService,
+		PaymentReconciliationService: paymentReconciliationService,
+		InvoiceService:               invoiceService,
+		SubscriptionExpiryService:    subscriptionExpiryService,
+		EntitlementService:           entitlementService,
+		AuthService:                  authService,
 	}
 }
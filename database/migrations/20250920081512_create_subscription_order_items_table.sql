@@ -0,0 +1,47 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS subscription_order_items (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    price NUMERIC(15, 2) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify order foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_subscription_order_items_order'
+        ) THEN
+            ALTER TABLE subscription_order_items
+                ADD CONSTRAINT fk_subscription_order_items_order
+                FOREIGN KEY (order_id) REFERENCES subscription_orders(id)
+                ON DELETE CASCADE;
+        END IF;
+
+        -- Verify order index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_subscription_order_items_order_id'
+        ) THEN
+            CREATE INDEX idx_subscription_order_items_order_id ON subscription_order_items(order_id);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+ALTER TABLE subscription_order_items
+    DROP CONSTRAINT IF EXISTS fk_subscription_order_items_order;
+
+DROP INDEX IF EXISTS idx_subscription_order_items_order_id;
+
+DROP TABLE IF EXISTS subscription_order_items;
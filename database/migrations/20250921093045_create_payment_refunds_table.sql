@@ -0,0 +1,63 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id UUID PRIMARY KEY,
+    payment_transaction_id UUID NOT NULL,
+    refund_key VARCHAR(100) NOT NULL UNIQUE,
+    amount NUMERIC(15, 2) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    refunded_by INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify payment transaction foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_payment_refunds_payment_transaction'
+        ) THEN
+            ALTER TABLE payment_refunds
+                ADD CONSTRAINT fk_payment_refunds_payment_transaction
+                FOREIGN KEY (payment_transaction_id) REFERENCES payment_transactions(id)
+                ON DELETE CASCADE;
+        END IF;
+
+        -- Verify refunded by foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_payment_refunds_refunded_by'
+        ) THEN
+            ALTER TABLE payment_refunds
+                ADD CONSTRAINT fk_payment_refunds_refunded_by
+                FOREIGN KEY (refunded_by) REFERENCES users(id)
+                ON DELETE SET NULL;
+        END IF;
+
+        -- Verify payment transaction index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_payment_refunds_payment_transaction_id'
+        ) THEN
+            CREATE INDEX idx_payment_refunds_payment_transaction_id ON payment_refunds(payment_transaction_id);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+ALTER TABLE payment_refunds
+    DROP CONSTRAINT IF EXISTS fk_payment_refunds_payment_transaction;
+
+ALTER TABLE payment_refunds
+    DROP CONSTRAINT IF EXISTS fk_payment_refunds_refunded_by;
+
+DROP INDEX IF EXISTS idx_payment_refunds_payment_transaction_id;
+
+DROP TABLE IF EXISTS payment_refunds;
@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE payment_transactions
+    ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'midtrans';
+
+-- migrate:down
+ALTER TABLE payment_transactions
+    DROP COLUMN IF EXISTS provider;
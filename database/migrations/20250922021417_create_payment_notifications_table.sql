@@ -0,0 +1,63 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS payment_notifications (
+    id UUID PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    payment_transaction_id UUID,
+    transaction_id VARCHAR(100),
+    transaction_status VARCHAR(20),
+    dedupe_key VARCHAR(64),
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify payment transaction foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_payment_notifications_payment_transaction'
+        ) THEN
+            ALTER TABLE payment_notifications
+                ADD CONSTRAINT fk_payment_notifications_payment_transaction
+                FOREIGN KEY (payment_transaction_id) REFERENCES payment_transactions(id)
+                ON DELETE SET NULL;
+        END IF;
+
+        -- Verify payment transaction index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_payment_notifications_payment_transaction_id'
+        ) THEN
+            CREATE INDEX idx_payment_notifications_payment_transaction_id ON payment_notifications(payment_transaction_id);
+        END IF;
+
+        -- Verify dedupe key index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_payment_notifications_dedupe_key'
+        ) THEN
+            CREATE INDEX idx_payment_notifications_dedupe_key ON payment_notifications(dedupe_key);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+ALTER TABLE payment_notifications
+    DROP CONSTRAINT IF EXISTS fk_payment_notifications_payment_transaction;
+
+DROP INDEX IF EXISTS idx_payment_notifications_payment_transaction_id;
+
+DROP INDEX IF EXISTS idx_payment_notifications_dedupe_key;
+
+DROP TABLE IF EXISTS payment_notifications;
@@ -0,0 +1,11 @@
+-- migrate:up
+ALTER TABLE subscription_orders
+    ADD COLUMN IF NOT EXISTS invoice_number VARCHAR(30) UNIQUE,
+    ADD COLUMN IF NOT EXISTS invoice_path VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS invoice_sent_at TIMESTAMP DEFAULT NULL;
+
+-- migrate:down
+ALTER TABLE subscription_orders
+    DROP COLUMN IF EXISTS invoice_number,
+    DROP COLUMN IF EXISTS invoice_path,
+    DROP COLUMN IF EXISTS invoice_sent_at;
@@ -0,0 +1,41 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS payment_methods (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    payment_type VARCHAR(50) NOT NULL,
+    payment_channel VARCHAR(50) NOT NULL UNIQUE,
+    provider VARCHAR(20) NOT NULL DEFAULT 'midtrans',
+    min_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    max_amount NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    description VARCHAR(255),
+    logo_url TEXT,
+    expiry_duration INT NOT NULL DEFAULT 0,
+    expiry_unit VARCHAR(10),
+    va_number_min_length INT NOT NULL DEFAULT 0,
+    va_number_max_length INT NOT NULL DEFAULT 0,
+    fee_flat NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    fee_percentage NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    is_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    display_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify enabled methods index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_payment_methods_is_enabled_display_order'
+        ) THEN
+            CREATE INDEX idx_payment_methods_is_enabled_display_order ON payment_methods(is_enabled, display_order);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_payment_methods_is_enabled_display_order;
+
+DROP TABLE IF EXISTS payment_methods;
@@ -0,0 +1,46 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS payment_method_maintenance_windows (
+    id SERIAL PRIMARY KEY,
+    payment_method_id INT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_payment_method_maintenance_windows_range CHECK (ends_at > starts_at)
+);
+
+DO $$
+    BEGIN
+        -- Verify payment method foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_payment_method_maintenance_windows_payment_method'
+        ) THEN
+            ALTER TABLE payment_method_maintenance_windows
+                ADD CONSTRAINT fk_payment_method_maintenance_windows_payment_method
+                FOREIGN KEY (payment_method_id) REFERENCES payment_methods(id)
+                ON DELETE CASCADE;
+        END IF;
+
+        -- Verify payment method index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_payment_method_maintenance_windows_payment_method_id'
+        ) THEN
+            CREATE INDEX idx_payment_method_maintenance_windows_payment_method_id ON payment_method_maintenance_windows(payment_method_id, ends_at);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+ALTER TABLE payment_method_maintenance_windows
+    DROP CONSTRAINT IF EXISTS fk_payment_method_maintenance_windows_payment_method;
+
+DROP INDEX IF EXISTS idx_payment_method_maintenance_windows_payment_method_id;
+
+DROP TABLE IF EXISTS payment_method_maintenance_windows;
@@ -0,0 +1,51 @@
+-- migrate:up
+DO $$
+    BEGIN
+
+        -- Verify every price is a plain decimal amount with at most two decimals
+        -- If not, abort the migration so the prices can be fixed by hand instead of being rounded silently
+        IF EXISTS (
+            SELECT 1
+            FROM products
+            WHERE CASE
+                WHEN TRIM(price) ~ '^[0-9]+(\.[0-9]+)?$'
+                    THEN TRIM(price)::NUMERIC * 100 <> TRUNC(TRIM(price)::NUMERIC * 100)
+                ELSE TRUE
+            END
+        ) THEN
+            RAISE EXCEPTION 'products.price contains values that cannot be converted to minor units exactly';
+        END IF;
+    END;
+$$;
+
+ALTER TABLE products
+    RENAME COLUMN price TO price_amount;
+
+ALTER TABLE products
+    ALTER COLUMN price_amount TYPE BIGINT USING (TRIM(price_amount)::NUMERIC * 100)::BIGINT,
+    ADD COLUMN IF NOT EXISTS price_currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- migrate:down
+DO $$
+    BEGIN
+
+        -- Verify every price is in rupiah, the old column has no currency
+        IF EXISTS (
+            SELECT 1
+            FROM products
+            WHERE price_currency <> 'IDR'
+        ) THEN
+            RAISE EXCEPTION 'products contains prices in other currencies than IDR';
+        END IF;
+    END;
+$$;
+
+ALTER TABLE products
+    ALTER COLUMN price_amount TYPE VARCHAR(30) USING CASE
+        WHEN price_amount % 100 = 0 THEN (price_amount / 100)::TEXT
+        ELSE (price_amount / 100.0)::NUMERIC(15, 2)::TEXT
+    END,
+    DROP COLUMN IF EXISTS price_currency;
+
+ALTER TABLE products
+    RENAME COLUMN price_amount TO price;
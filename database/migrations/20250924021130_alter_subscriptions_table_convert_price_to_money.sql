@@ -0,0 +1,29 @@
+-- migrate:up
+ALTER TABLE subscriptions
+    RENAME COLUMN price TO price_amount;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN price_amount TYPE BIGINT USING (price_amount * 100)::BIGINT,
+    ADD COLUMN IF NOT EXISTS price_currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- migrate:down
+DO $$
+    BEGIN
+
+        -- Verify every price is in rupiah and fits the old column
+        IF EXISTS (
+            SELECT 1
+            FROM subscriptions
+            WHERE price_currency <> 'IDR' OR ABS(price_amount) >= 10000000000
+        ) THEN
+            RAISE EXCEPTION 'subscriptions contains prices that cannot be converted back to DECIMAL(10, 2)';
+        END IF;
+    END;
+$$;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN price_amount TYPE DECIMAL(10, 2) USING price_amount / 100.0,
+    DROP COLUMN IF EXISTS price_currency;
+
+ALTER TABLE subscriptions
+    RENAME COLUMN price_amount TO price;
@@ -0,0 +1,11 @@
+-- migrate:up
+ALTER TABLE payment_transactions
+    ALTER COLUMN amount TYPE BIGINT USING (amount * 100)::BIGINT,
+    ALTER COLUMN currency TYPE CHAR(3) USING UPPER(TRIM(currency)),
+    ALTER COLUMN currency SET DEFAULT 'IDR';
+
+-- migrate:down
+ALTER TABLE payment_transactions
+    ALTER COLUMN amount TYPE NUMERIC(15, 2) USING amount / 100.0,
+    ALTER COLUMN currency TYPE VARCHAR(10),
+    ALTER COLUMN currency DROP DEFAULT;
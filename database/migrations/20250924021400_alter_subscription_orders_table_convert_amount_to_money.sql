@@ -0,0 +1,23 @@
+-- migrate:up
+ALTER TABLE subscription_orders
+    ALTER COLUMN amount TYPE BIGINT USING (amount * 100)::BIGINT,
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- migrate:down
+DO $$
+    BEGIN
+
+        -- Verify every amount is in rupiah and fits the old column
+        IF EXISTS (
+            SELECT 1
+            FROM subscription_orders
+            WHERE currency <> 'IDR' OR ABS(amount) >= 10000000000
+        ) THEN
+            RAISE EXCEPTION 'subscription_orders contains amounts that cannot be converted back to NUMERIC(10, 2)';
+        END IF;
+    END;
+$$;
+
+ALTER TABLE subscription_orders
+    ALTER COLUMN amount TYPE NUMERIC(10, 2) USING amount / 100.0,
+    DROP COLUMN IF EXISTS currency;
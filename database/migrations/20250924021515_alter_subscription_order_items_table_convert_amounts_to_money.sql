@@ -0,0 +1,33 @@
+-- migrate:up
+ALTER TABLE subscription_order_items
+    RENAME COLUMN price TO price_amount;
+
+ALTER TABLE subscription_order_items
+    ALTER COLUMN price_amount TYPE BIGINT USING (price_amount * 100)::BIGINT,
+    ALTER COLUMN amount TYPE BIGINT USING (amount * 100)::BIGINT,
+    ADD COLUMN IF NOT EXISTS price_currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- migrate:down
+DO $$
+    BEGIN
+
+        -- Verify every amount is in rupiah, the old columns have no currency
+        IF EXISTS (
+            SELECT 1
+            FROM subscription_order_items
+            WHERE price_currency <> 'IDR' OR currency <> 'IDR'
+        ) THEN
+            RAISE EXCEPTION 'subscription_order_items contains amounts in other currencies than IDR';
+        END IF;
+    END;
+$$;
+
+ALTER TABLE subscription_order_items
+    ALTER COLUMN price_amount TYPE NUMERIC(15, 2) USING price_amount / 100.0,
+    ALTER COLUMN amount TYPE NUMERIC(15, 2) USING amount / 100.0,
+    DROP COLUMN IF EXISTS price_currency,
+    DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE subscription_order_items
+    RENAME COLUMN price_amount TO price;
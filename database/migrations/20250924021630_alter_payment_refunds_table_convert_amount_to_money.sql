@@ -0,0 +1,23 @@
+-- migrate:up
+ALTER TABLE payment_refunds
+    ALTER COLUMN amount TYPE BIGINT USING (amount * 100)::BIGINT,
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- migrate:down
+DO $$
+    BEGIN
+
+        -- Verify every refund is in rupiah, the old column has no currency
+        IF EXISTS (
+            SELECT 1
+            FROM payment_refunds
+            WHERE currency <> 'IDR'
+        ) THEN
+            RAISE EXCEPTION 'payment_refunds contains amounts in other currencies than IDR';
+        END IF;
+    END;
+$$;
+
+ALTER TABLE payment_refunds
+    ALTER COLUMN amount TYPE NUMERIC(15, 2) USING amount / 100.0,
+    DROP COLUMN IF EXISTS currency;
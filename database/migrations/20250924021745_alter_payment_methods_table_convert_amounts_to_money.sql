@@ -0,0 +1,53 @@
+-- migrate:up
+ALTER TABLE payment_methods
+    ALTER COLUMN min_amount DROP DEFAULT,
+    ALTER COLUMN max_amount DROP DEFAULT,
+    ALTER COLUMN fee_flat DROP DEFAULT;
+
+ALTER TABLE payment_methods
+    RENAME COLUMN fee_flat TO fee_flat_amount;
+
+ALTER TABLE payment_methods
+    ALTER COLUMN min_amount TYPE BIGINT USING (min_amount * 100)::BIGINT,
+    ALTER COLUMN max_amount TYPE BIGINT USING (max_amount * 100)::BIGINT,
+    ALTER COLUMN fee_flat_amount TYPE BIGINT USING (fee_flat_amount * 100)::BIGINT,
+    ALTER COLUMN min_amount SET DEFAULT 0,
+    ALTER COLUMN max_amount SET DEFAULT 0,
+    ALTER COLUMN fee_flat_amount SET DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS min_currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD COLUMN IF NOT EXISTS max_currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD COLUMN IF NOT EXISTS fee_flat_currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- migrate:down
+DO $$
+    BEGIN
+
+        -- Verify every amount is in rupiah, the old columns have no currency
+        IF EXISTS (
+            SELECT 1
+            FROM payment_methods
+            WHERE min_currency <> 'IDR' OR max_currency <> 'IDR' OR fee_flat_currency <> 'IDR'
+        ) THEN
+            RAISE EXCEPTION 'payment_methods contains amounts in other currencies than IDR';
+        END IF;
+    END;
+$$;
+
+ALTER TABLE payment_methods
+    ALTER COLUMN min_amount DROP DEFAULT,
+    ALTER COLUMN max_amount DROP DEFAULT,
+    ALTER COLUMN fee_flat_amount DROP DEFAULT;
+
+ALTER TABLE payment_methods
+    ALTER COLUMN min_amount TYPE NUMERIC(15, 2) USING min_amount / 100.0,
+    ALTER COLUMN max_amount TYPE NUMERIC(15, 2) USING max_amount / 100.0,
+    ALTER COLUMN fee_flat_amount TYPE NUMERIC(15, 2) USING fee_flat_amount / 100.0,
+    ALTER COLUMN min_amount SET DEFAULT 0,
+    ALTER COLUMN max_amount SET DEFAULT 0,
+    ALTER COLUMN fee_flat_amount SET DEFAULT 0,
+    DROP COLUMN IF EXISTS min_currency,
+    DROP COLUMN IF EXISTS max_currency,
+    DROP COLUMN IF EXISTS fee_flat_currency;
+
+ALTER TABLE payment_methods
+    RENAME COLUMN fee_flat_amount TO fee_flat;
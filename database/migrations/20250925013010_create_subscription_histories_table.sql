@@ -0,0 +1,61 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS subscription_histories (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    user_subscription_id INT NOT NULL,
+    subscription_id INT NOT NULL,
+    order_id UUID,
+    event VARCHAR(20) NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    expired_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify user foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_subscription_histories_user'
+        ) THEN
+            ALTER TABLE subscription_histories
+                ADD CONSTRAINT fk_subscription_histories_user
+                FOREIGN KEY (user_id) REFERENCES users(id)
+                ON DELETE CASCADE;
+        END IF;
+
+        -- Verify user subscription foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_subscription_histories_user_subscription'
+        ) THEN
+            ALTER TABLE subscription_histories
+                ADD CONSTRAINT fk_subscription_histories_user_subscription
+                FOREIGN KEY (user_subscription_id) REFERENCES user_subscriptions(id)
+                ON DELETE CASCADE;
+        END IF;
+
+        -- Verify user index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_subscription_histories_user_id'
+        ) THEN
+            CREATE INDEX idx_subscription_histories_user_id ON subscription_histories(user_id, created_at);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+ALTER TABLE subscription_histories
+    DROP CONSTRAINT IF EXISTS fk_subscription_histories_user,
+    DROP CONSTRAINT IF EXISTS fk_subscription_histories_user_subscription;
+
+DROP INDEX IF EXISTS idx_subscription_histories_user_id;
+
+DROP TABLE IF EXISTS subscription_histories;
@@ -0,0 +1,42 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS subscription_expiry_reminders (
+    id SERIAL PRIMARY KEY,
+    user_subscription_id INT NOT NULL,
+    expired_at TIMESTAMP NOT NULL,
+    days_before INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify user subscription foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_subscription_expiry_reminders_user_subscription'
+        ) THEN
+            ALTER TABLE subscription_expiry_reminders
+                ADD CONSTRAINT fk_subscription_expiry_reminders_user_subscription
+                FOREIGN KEY (user_subscription_id) REFERENCES user_subscriptions(id)
+                ON DELETE CASCADE;
+        END IF;
+
+        -- A reminder is sent once per expiry date of a user subscription
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_subscription_expiry_reminders_unique'
+        ) THEN
+            CREATE UNIQUE INDEX idx_subscription_expiry_reminders_unique ON subscription_expiry_reminders(user_subscription_id, expired_at, days_before);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+ALTER TABLE subscription_expiry_reminders
+    DROP CONSTRAINT IF EXISTS fk_subscription_expiry_reminders_user_subscription;
+
+DROP INDEX IF EXISTS idx_subscription_expiry_reminders_unique;
+
+DROP TABLE IF EXISTS subscription_expiry_reminders;
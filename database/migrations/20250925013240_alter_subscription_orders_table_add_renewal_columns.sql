@@ -0,0 +1,29 @@
+-- migrate:up
+ALTER TABLE subscription_orders
+    ADD COLUMN IF NOT EXISTS type VARCHAR(20) NOT NULL DEFAULT 'new',
+    ADD COLUMN IF NOT EXISTS renews_user_subscription_id INT DEFAULT NULL;
+
+DO $$
+    BEGIN
+        -- Verify renewed user subscription foreign key constraint is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_subscription_orders_renews_user_subscription'
+        ) THEN
+            ALTER TABLE subscription_orders
+                ADD CONSTRAINT fk_subscription_orders_renews_user_subscription
+                FOREIGN KEY (renews_user_subscription_id) REFERENCES user_subscriptions(id)
+                ON DELETE SET NULL;
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+ALTER TABLE subscription_orders
+    DROP CONSTRAINT IF EXISTS fk_subscription_orders_renews_user_subscription;
+
+ALTER TABLE subscription_orders
+    DROP COLUMN IF EXISTS type,
+    DROP COLUMN IF EXISTS renews_user_subscription_id;
@@ -0,0 +1,44 @@
+-- migrate:up
+-- Renewals and plan changes both refer to the user subscription they act on
+DO $$
+    BEGIN
+        IF EXISTS (
+            SELECT 1
+            FROM information_schema.columns
+            WHERE table_name = 'subscription_orders' AND column_name = 'renews_user_subscription_id'
+        ) THEN
+            ALTER TABLE subscription_orders RENAME COLUMN renews_user_subscription_id TO user_subscription_id;
+        END IF;
+
+        IF EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_subscription_orders_renews_user_subscription'
+        ) THEN
+            ALTER TABLE subscription_orders
+                RENAME CONSTRAINT fk_subscription_orders_renews_user_subscription TO fk_subscription_orders_user_subscription;
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DO $$
+    BEGIN
+        IF EXISTS (
+            SELECT 1
+            FROM information_schema.columns
+            WHERE table_name = 'subscription_orders' AND column_name = 'user_subscription_id'
+        ) THEN
+            ALTER TABLE subscription_orders RENAME COLUMN user_subscription_id TO renews_user_subscription_id;
+        END IF;
+
+        IF EXISTS (
+            SELECT 1
+            FROM pg_constraint
+            WHERE conname = 'fk_subscription_orders_user_subscription'
+        ) THEN
+            ALTER TABLE subscription_orders
+                RENAME CONSTRAINT fk_subscription_orders_user_subscription TO fk_subscription_orders_renews_user_subscription;
+        END IF;
+    END;
+$$;
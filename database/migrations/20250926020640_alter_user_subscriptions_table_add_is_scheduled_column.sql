@@ -0,0 +1,23 @@
+-- migrate:up
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS is_scheduled BOOLEAN NOT NULL DEFAULT FALSE;
+
+DO $$
+    BEGIN
+        -- Verify scheduled subscription index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_user_subscriptions_scheduled'
+        ) THEN
+            CREATE INDEX idx_user_subscriptions_scheduled ON user_subscriptions(user_id) WHERE is_scheduled;
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_user_subscriptions_scheduled;
+
+ALTER TABLE user_subscriptions
+    DROP COLUMN IF EXISTS is_scheduled;
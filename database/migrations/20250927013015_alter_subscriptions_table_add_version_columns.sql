@@ -0,0 +1,32 @@
+-- migrate:up
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS family_id INTEGER,
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS superseded_by_id INTEGER REFERENCES subscriptions(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS retired_at TIMESTAMP;
+
+-- Every existing subscription is the first version of its own family
+UPDATE subscriptions SET family_id = id WHERE family_id IS NULL;
+
+DO $$
+    BEGIN
+        -- Verify subscription family index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_subscriptions_family_id'
+        ) THEN
+            CREATE INDEX idx_subscriptions_family_id ON subscriptions(family_id, version);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_subscriptions_family_id;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS retired_at,
+    DROP COLUMN IF EXISTS superseded_by_id,
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,12 @@
+-- migrate:up
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS trial_days SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS trial_for_paid_users BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Offer the 14 days trial of the Business subscription on offer
+UPDATE subscriptions SET trial_days = 14 WHERE name = 'Business' AND retired_at IS NULL AND deleted_at IS NULL;
+
+-- migrate:down
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS trial_for_paid_users,
+    DROP COLUMN IF EXISTS trial_days;
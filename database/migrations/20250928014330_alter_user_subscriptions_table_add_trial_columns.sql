@@ -0,0 +1,38 @@
+-- migrate:up
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS trial_ends_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS trial_converted_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS trial_email VARCHAR(255);
+
+DO $$
+    BEGIN
+        -- Verify trial user index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_user_subscriptions_trial_user_id'
+        ) THEN
+            CREATE UNIQUE INDEX idx_user_subscriptions_trial_user_id ON user_subscriptions(user_id) WHERE trial_ends_at IS NOT NULL;
+        END IF;
+
+        -- Verify trial email index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_user_subscriptions_trial_email'
+        ) THEN
+            CREATE UNIQUE INDEX idx_user_subscriptions_trial_email ON user_subscriptions(trial_email) WHERE trial_ends_at IS NOT NULL;
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_user_subscriptions_trial_email;
+DROP INDEX IF EXISTS idx_user_subscriptions_trial_user_id;
+
+ALTER TABLE user_subscriptions
+    DROP COLUMN IF EXISTS trial_email,
+    DROP COLUMN IF EXISTS trial_converted_at,
+    DROP COLUMN IF EXISTS trial_ends_at;
@@ -0,0 +1,39 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    description VARCHAR(255),
+    type VARCHAR(20) NOT NULL,
+    percent_off SMALLINT NOT NULL DEFAULT 0,
+    amount_off_amount BIGINT NOT NULL DEFAULT 0,
+    amount_off_currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    granted_days SMALLINT NOT NULL DEFAULT 0,
+    subscription_family_ids JSON NOT NULL DEFAULT '[]',
+    max_redemptions INT,
+    max_redemptions_per_user INT NOT NULL DEFAULT 1,
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify promo code index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_promo_codes_code'
+        ) THEN
+            CREATE UNIQUE INDEX idx_promo_codes_code ON promo_codes(code) WHERE deleted_at IS NULL;
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_promo_codes_code;
+
+DROP TABLE IF EXISTS promo_codes;
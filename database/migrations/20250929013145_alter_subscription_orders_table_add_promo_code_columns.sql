@@ -0,0 +1,29 @@
+-- migrate:up
+ALTER TABLE subscription_orders
+    ADD COLUMN IF NOT EXISTS promo_code_id INTEGER REFERENCES promo_codes(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS discount_currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD COLUMN IF NOT EXISTS granted_days SMALLINT NOT NULL DEFAULT 0;
+
+DO $$
+    BEGIN
+        -- Verify promo code redemption index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_subscription_orders_promo_code_id'
+        ) THEN
+            CREATE INDEX idx_subscription_orders_promo_code_id ON subscription_orders(promo_code_id, user_id) WHERE promo_code_id IS NOT NULL;
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_subscription_orders_promo_code_id;
+
+ALTER TABLE subscription_orders
+    DROP COLUMN IF EXISTS granted_days,
+    DROP COLUMN IF EXISTS discount_currency,
+    DROP COLUMN IF EXISTS discount_amount,
+    DROP COLUMN IF EXISTS promo_code_id;
@@ -0,0 +1,30 @@
+-- migrate:up
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS referral_code VARCHAR(12);
+
+UPDATE users
+SET referral_code = UPPER(SUBSTRING(REPLACE(gen_random_uuid()::text, '-', ''), 1, 8))
+WHERE referral_code IS NULL;
+
+ALTER TABLE users
+    ALTER COLUMN referral_code SET NOT NULL;
+
+DO $$
+    BEGIN
+        -- Verify referral code index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_users_referral_code'
+        ) THEN
+            CREATE UNIQUE INDEX idx_users_referral_code ON users(referral_code);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_users_referral_code;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS referral_code;
@@ -0,0 +1,44 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS referrals (
+    id SERIAL PRIMARY KEY,
+    referrer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    referred_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reject_reason VARCHAR(50),
+    reward_days SMALLINT NOT NULL DEFAULT 0,
+    order_id UUID REFERENCES subscription_orders(id) ON DELETE SET NULL,
+    user_subscription_id INTEGER,
+    rewarded_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify referred user index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_referrals_referred_id'
+        ) THEN
+            CREATE UNIQUE INDEX idx_referrals_referred_id ON referrals(referred_id);
+        END IF;
+
+        -- Verify referrer index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_referrals_referrer_id_status'
+        ) THEN
+            CREATE INDEX idx_referrals_referrer_id_status ON referrals(referrer_id, status);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_referrals_referrer_id_status;
+DROP INDEX IF EXISTS idx_referrals_referred_id;
+
+DROP TABLE IF EXISTS referrals;
@@ -0,0 +1,48 @@
+-- migrate:up
+-- Refresh tokens were stored in plain text and are not tied to a session
+-- They cannot be migrated, the users have to log in again
+DELETE FROM user_has_tokens;
+
+ALTER TABLE user_has_tokens
+    DROP COLUMN IF EXISTS token,
+    ADD COLUMN IF NOT EXISTS session_id UUID NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64) NOT NULL,
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NOT NULL,
+    ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMP;
+
+DO $$
+    BEGIN
+        -- Verify token hash index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_user_has_tokens_token_hash'
+        ) THEN
+            CREATE UNIQUE INDEX idx_user_has_tokens_token_hash ON user_has_tokens(token_hash);
+        END IF;
+
+        -- Verify session index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_user_has_tokens_session_id'
+        ) THEN
+            CREATE INDEX idx_user_has_tokens_session_id ON user_has_tokens(session_id);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_user_has_tokens_session_id;
+DROP INDEX IF EXISTS idx_user_has_tokens_token_hash;
+
+DELETE FROM user_has_tokens;
+
+ALTER TABLE user_has_tokens
+    DROP COLUMN IF EXISTS rotated_at,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS token_hash,
+    DROP COLUMN IF EXISTS session_id,
+    ADD COLUMN IF NOT EXISTS token VARCHAR(255) NOT NULL;
@@ -0,0 +1,42 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+DO $$
+    BEGIN
+        -- Verify token hash index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_password_reset_tokens_token_hash'
+        ) THEN
+            CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
+        END IF;
+
+        -- Verify user index is not exists
+        -- If already exists, skip the migration to avoid errors
+        IF NOT EXISTS (
+            SELECT 1
+            FROM pg_indexes
+            WHERE indexname = 'idx_password_reset_tokens_user_id_created_at'
+        ) THEN
+            CREATE INDEX idx_password_reset_tokens_user_id_created_at ON password_reset_tokens(user_id, created_at);
+        END IF;
+    END;
+$$;
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_password_reset_tokens_user_id_created_at;
+DROP INDEX IF EXISTS idx_password_reset_tokens_token_hash;
+
+DROP TABLE IF EXISTS password_reset_tokens;
@@ -140,6 +140,168 @@ CREATE SEQUENCE public.oauth_accounts_id_seq
 ALTER SEQUENCE public.oauth_accounts_id_seq OWNED BY public.oauth_accounts.id;
 
 
+--
+-- Name: password_reset_tokens; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.password_reset_tokens (
+    id integer NOT NULL,
+    user_id integer NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    used_at timestamp without time zone,
+    ip_address character varying(45) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamp without time zone
+);
+
+
+--
+-- Name: password_reset_tokens_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.password_reset_tokens_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: password_reset_tokens_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.password_reset_tokens_id_seq OWNED BY public.password_reset_tokens.id;
+
+
+--
+-- Name: payment_method_maintenance_windows; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payment_method_maintenance_windows (
+    id integer NOT NULL,
+    payment_method_id integer NOT NULL,
+    starts_at timestamp without time zone NOT NULL,
+    ends_at timestamp without time zone NOT NULL,
+    reason character varying(255),
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_payment_method_maintenance_windows_range CHECK ((ends_at > starts_at))
+);
+
+
+--
+-- Name: payment_method_maintenance_windows_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.payment_method_maintenance_windows_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: payment_method_maintenance_windows_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.payment_method_maintenance_windows_id_seq OWNED BY public.payment_method_maintenance_windows.id;
+
+
+--
+-- Name: payment_methods; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payment_methods (
+    id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    payment_type character varying(50) NOT NULL,
+    payment_channel character varying(50) NOT NULL,
+    provider character varying(20) DEFAULT 'midtrans'::character varying NOT NULL,
+    min_amount bigint DEFAULT 0 NOT NULL,
+    max_amount bigint DEFAULT 0 NOT NULL,
+    description character varying(255),
+    logo_url text,
+    expiry_duration integer DEFAULT 0 NOT NULL,
+    expiry_unit character varying(10),
+    va_number_min_length integer DEFAULT 0 NOT NULL,
+    va_number_max_length integer DEFAULT 0 NOT NULL,
+    fee_flat_amount bigint DEFAULT 0 NOT NULL,
+    fee_percentage numeric(5,2) DEFAULT 0 NOT NULL,
+    is_enabled boolean DEFAULT true NOT NULL,
+    display_order integer DEFAULT 0 NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    min_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
+    max_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
+    fee_flat_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL
+);
+
+
+--
+-- Name: payment_methods_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.payment_methods_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: payment_methods_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.payment_methods_id_seq OWNED BY public.payment_methods.id;
+
+
+--
+-- Name: payment_notifications; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payment_notifications (
+    id uuid NOT NULL,
+    provider character varying(20) NOT NULL,
+    payment_transaction_id uuid,
+    transaction_id character varying(100),
+    transaction_status character varying(20),
+    dedupe_key character varying(64),
+    payload text NOT NULL,
+    status character varying(20) NOT NULL,
+    error text,
+    attempts integer DEFAULT 0 NOT NULL,
+    processed_at timestamp without time zone,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+
+--
+-- Name: payment_refunds; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.payment_refunds (
+    id uuid NOT NULL,
+    payment_transaction_id uuid NOT NULL,
+    refund_key character varying(100) NOT NULL,
+    amount bigint NOT NULL,
+    reason character varying(255) NOT NULL,
+    status character varying(20) NOT NULL,
+    refunded_by integer,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    currency character(3) DEFAULT 'IDR'::bpchar NOT NULL
+);
+
+
 --
 -- Name: payment_transactions; Type: TABLE; Schema: public; Owner: -
 --
@@ -149,8 +311,8 @@ CREATE TABLE public.payment_transactions (
     payment_type character varying(50) NOT NULL,
     payment_channel character varying(50) NOT NULL,
     fraud_status character varying(20),
-    amount numeric(15,2) NOT NULL,
-    currency character varying(10) NOT NULL,
+    amount bigint NOT NULL,
+    currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
     status character varying(20) NOT NULL,
     transaction_id character varying(100),
     transaction_time timestamp without time zone,
@@ -159,7 +321,8 @@ CREATE TABLE public.payment_transactions (
     settled_at timestamp without time zone,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
-    deleted_at timestamp without time zone
+    deleted_at timestamp without time zone,
+    provider character varying(20) DEFAULT 'midtrans'::character varying NOT NULL
 );
 
 
@@ -242,16 +405,101 @@ CREATE TABLE public.products (
     merchant_id character(16) NOT NULL,
     category_id integer,
     title character varying(150) NOT NULL,
-    price character varying(30) NOT NULL,
+    price_amount bigint NOT NULL,
     description text,
     affiliate_url text NOT NULL,
     photos json,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamp without time zone,
+    price_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL
+);
+
+
+--
+-- Name: promo_codes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.promo_codes (
+    id integer NOT NULL,
+    code character varying(50) NOT NULL,
+    description character varying(255),
+    type character varying(20) NOT NULL,
+    percent_off smallint DEFAULT 0 NOT NULL,
+    amount_off_amount bigint DEFAULT 0 NOT NULL,
+    amount_off_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
+    granted_days smallint DEFAULT 0 NOT NULL,
+    subscription_family_ids json DEFAULT '[]'::json NOT NULL,
+    max_redemptions integer,
+    max_redemptions_per_user integer DEFAULT 1 NOT NULL,
+    starts_at timestamp without time zone,
+    ends_at timestamp without time zone,
+    is_active boolean DEFAULT true NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     deleted_at timestamp without time zone
 );
 
 
+--
+-- Name: promo_codes_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.promo_codes_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: promo_codes_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.promo_codes_id_seq OWNED BY public.promo_codes.id;
+
+
+--
+-- Name: referrals; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.referrals (
+    id integer NOT NULL,
+    referrer_id integer NOT NULL,
+    referred_id integer NOT NULL,
+    status character varying(20) DEFAULT 'pending'::character varying NOT NULL,
+    reject_reason character varying(50),
+    reward_days smallint DEFAULT 0 NOT NULL,
+    order_id uuid,
+    user_subscription_id integer,
+    rewarded_at timestamp without time zone,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+
+--
+-- Name: referrals_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.referrals_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: referrals_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.referrals_id_seq OWNED BY public.referrals.id;
+
+
 --
 -- Name: schema_migrations; Type: TABLE; Schema: public; Owner: -
 --
@@ -261,6 +509,95 @@ CREATE TABLE public.schema_migrations (
 );
 
 
+--
+-- Name: subscription_expiry_reminders; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.subscription_expiry_reminders (
+    id integer NOT NULL,
+    user_subscription_id integer NOT NULL,
+    expired_at timestamp without time zone NOT NULL,
+    days_before integer NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+
+--
+-- Name: subscription_expiry_reminders_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.subscription_expiry_reminders_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: subscription_expiry_reminders_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.subscription_expiry_reminders_id_seq OWNED BY public.subscription_expiry_reminders.id;
+
+
+--
+-- Name: subscription_histories; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.subscription_histories (
+    id integer NOT NULL,
+    user_id integer NOT NULL,
+    user_subscription_id integer NOT NULL,
+    subscription_id integer NOT NULL,
+    order_id uuid,
+    event character varying(20) NOT NULL,
+    started_at timestamp without time zone NOT NULL,
+    expired_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+
+--
+-- Name: subscription_histories_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.subscription_histories_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: subscription_histories_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.subscription_histories_id_seq OWNED BY public.subscription_histories.id;
+
+
+--
+-- Name: subscription_order_items; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.subscription_order_items (
+    id uuid NOT NULL,
+    order_id uuid NOT NULL,
+    type character varying(20) NOT NULL,
+    name character varying(100) NOT NULL,
+    quantity integer DEFAULT 1 NOT NULL,
+    price_amount bigint NOT NULL,
+    amount bigint NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    price_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
+    currency character(3) DEFAULT 'IDR'::bpchar NOT NULL
+);
+
+
 --
 -- Name: subscription_orders; Type: TABLE; Schema: public; Owner: -
 --
@@ -270,11 +607,21 @@ CREATE TABLE public.subscription_orders (
     user_id integer NOT NULL,
     subscription_id integer NOT NULL,
     payment_transaction_id uuid NOT NULL,
-    amount numeric(10,2) NOT NULL,
+    amount bigint NOT NULL,
     status character varying(50) DEFAULT 'pending'::character varying,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
-    deleted_at timestamp without time zone
+    deleted_at timestamp without time zone,
+    invoice_number character varying(30),
+    invoice_path character varying(255),
+    invoice_sent_at timestamp without time zone,
+    currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
+    type character varying(20) DEFAULT 'new'::character varying NOT NULL,
+    user_subscription_id integer,
+    promo_code_id integer,
+    discount_amount bigint DEFAULT 0 NOT NULL,
+    discount_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
+    granted_days smallint DEFAULT 0 NOT NULL
 );
 
 
@@ -320,12 +667,19 @@ ALTER SEQUENCE public.subscription_plans_id_seq OWNED BY public.subscription_pla
 CREATE TABLE public.subscriptions (
     id integer NOT NULL,
     name character varying(100) NOT NULL,
-    price numeric(10,2) NOT NULL,
+    price_amount bigint NOT NULL,
     description text,
     duration smallint NOT NULL,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
-    deleted_at timestamp without time zone
+    deleted_at timestamp without time zone,
+    price_currency character(3) DEFAULT 'IDR'::bpchar NOT NULL,
+    family_id integer,
+    version integer DEFAULT 1 NOT NULL,
+    superseded_by_id integer,
+    retired_at timestamp without time zone,
+    trial_days smallint DEFAULT 0 NOT NULL,
+    trial_for_paid_users boolean DEFAULT false NOT NULL
 );
 
 
@@ -356,10 +710,13 @@ ALTER SEQUENCE public.subscriptions_id_seq OWNED BY public.subscriptions.id;
 CREATE TABLE public.user_has_tokens (
     id integer NOT NULL,
     user_id integer NOT NULL,
-    token character varying(255) NOT NULL,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
-    deleted_at timestamp without time zone
+    deleted_at timestamp without time zone,
+    session_id uuid NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    rotated_at timestamp without time zone
 );
 
 
@@ -383,6 +740,24 @@ CREATE SEQUENCE public.user_has_tokens_id_seq
 ALTER SEQUENCE public.user_has_tokens_id_seq OWNED BY public.user_has_tokens.id;
 
 
+--
+-- Name: user_sessions; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.user_sessions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    user_id integer NOT NULL,
+    user_agent character varying(512) DEFAULT ''::character varying NOT NULL,
+    ip_address character varying(45) DEFAULT ''::character varying NOT NULL,
+    last_used_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    revoked_at timestamp without time zone,
+    revoke_reason character varying(50),
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+
 --
 -- Name: user_subscriptions; Type: TABLE; Schema: public; Owner: -
 --
@@ -397,7 +772,11 @@ CREATE TABLE public.user_subscriptions (
     payment_status character varying(50) DEFAULT 'pending'::character varying,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
-    deleted_at timestamp without time zone
+    deleted_at timestamp without time zone,
+    is_scheduled boolean DEFAULT false NOT NULL,
+    trial_ends_at timestamp without time zone,
+    trial_converted_at timestamp without time zone,
+    trial_email character varying(255)
 );
 
 
@@ -436,7 +815,8 @@ CREATE TABLE public.users (
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
     deleted_at timestamp without time zone,
     email_verified_at timestamp without time zone,
-    is_oauth boolean DEFAULT false
+    is_oauth boolean DEFAULT false,
+    referral_code character varying(12) NOT NULL
 );
 
 
@@ -482,52 +862,101 @@ ALTER TABLE ONLY public.oauth_accounts ALTER COLUMN id SET DEFAULT nextval('publ
 
 
 --
--- Name: predefined_categories id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: password_reset_tokens id; Type: DEFAULT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.predefined_categories ALTER COLUMN id SET DEFAULT nextval('public.predefined_categories_id_seq'::regclass);
+ALTER TABLE ONLY public.password_reset_tokens ALTER COLUMN id SET DEFAULT nextval('public.password_reset_tokens_id_seq'::regclass);
 
 
 --
--- Name: product_metrics id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: payment_method_maintenance_windows id; Type: DEFAULT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.product_metrics ALTER COLUMN id SET DEFAULT nextval('public.product_metrics_id_seq'::regclass);
+ALTER TABLE ONLY public.payment_method_maintenance_windows ALTER COLUMN id SET DEFAULT nextval('public.payment_method_maintenance_windows_id_seq'::regclass);
 
 
 --
--- Name: subscription_plans id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: payment_methods id; Type: DEFAULT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.subscription_plans ALTER COLUMN id SET DEFAULT nextval('public.subscription_plans_id_seq'::regclass);
+ALTER TABLE ONLY public.payment_methods ALTER COLUMN id SET DEFAULT nextval('public.payment_methods_id_seq'::regclass);
 
 
 --
--- Name: subscriptions id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: predefined_categories id; Type: DEFAULT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.subscriptions ALTER COLUMN id SET DEFAULT nextval('public.subscriptions_id_seq'::regclass);
+ALTER TABLE ONLY public.predefined_categories ALTER COLUMN id SET DEFAULT nextval('public.predefined_categories_id_seq'::regclass);
 
 
 --
--- Name: user_has_tokens id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: product_metrics id; Type: DEFAULT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.user_has_tokens ALTER COLUMN id SET DEFAULT nextval('public.user_has_tokens_id_seq'::regclass);
+ALTER TABLE ONLY public.product_metrics ALTER COLUMN id SET DEFAULT nextval('public.product_metrics_id_seq'::regclass);
 
 
 --
--- Name: user_subscriptions id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: promo_codes id; Type: DEFAULT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.user_subscriptions ALTER COLUMN id SET DEFAULT nextval('public.user_subscriptions_id_seq'::regclass);
+ALTER TABLE ONLY public.promo_codes ALTER COLUMN id SET DEFAULT nextval('public.promo_codes_id_seq'::regclass);
 
 
 --
--- Name: users id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: referrals id; Type: DEFAULT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+ALTER TABLE ONLY public.referrals ALTER COLUMN id SET DEFAULT nextval('public.referrals_id_seq'::regclass);
+
+
+--
+-- Name: subscription_expiry_reminders id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_expiry_reminders ALTER COLUMN id SET DEFAULT nextval('public.subscription_expiry_reminders_id_seq'::regclass);
+
+
+--
+-- Name: subscription_histories id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_histories ALTER COLUMN id SET DEFAULT nextval('public.subscription_histories_id_seq'::regclass);
+
+
+--
+-- Name: subscription_plans id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_plans ALTER COLUMN id SET DEFAULT nextval('public.subscription_plans_id_seq'::regclass);
+
+
+--
+-- Name: subscriptions id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscriptions ALTER COLUMN id SET DEFAULT nextval('public.subscriptions_id_seq'::regclass);
+
+
+--
+-- Name: user_has_tokens id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_has_tokens ALTER COLUMN id SET DEFAULT nextval('public.user_has_tokens_id_seq'::regclass);
+
+
+--
+-- Name: user_subscriptions id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_subscriptions ALTER COLUMN id SET DEFAULT nextval('public.user_subscriptions_id_seq'::regclass);
+
+
+--
+-- Name: users id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
 
 
 --
@@ -586,6 +1015,62 @@ ALTER TABLE ONLY public.oauth_accounts
     ADD CONSTRAINT oauth_accounts_user_id_key UNIQUE (user_id);
 
 
+--
+-- Name: password_reset_tokens password_reset_tokens_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.password_reset_tokens
+    ADD CONSTRAINT password_reset_tokens_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: payment_method_maintenance_windows payment_method_maintenance_windows_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_method_maintenance_windows
+    ADD CONSTRAINT payment_method_maintenance_windows_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: payment_methods payment_methods_payment_channel_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_methods
+    ADD CONSTRAINT payment_methods_payment_channel_key UNIQUE (payment_channel);
+
+
+--
+-- Name: payment_methods payment_methods_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_methods
+    ADD CONSTRAINT payment_methods_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: payment_notifications payment_notifications_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_notifications
+    ADD CONSTRAINT payment_notifications_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: payment_refunds payment_refunds_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_refunds
+    ADD CONSTRAINT payment_refunds_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: payment_refunds payment_refunds_refund_key_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_refunds
+    ADD CONSTRAINT payment_refunds_refund_key_key UNIQUE (refund_key);
+
+
 --
 -- Name: payment_transactions payment_transactions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -634,6 +1119,22 @@ ALTER TABLE ONLY public.products
     ADD CONSTRAINT products_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: promo_codes promo_codes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.promo_codes
+    ADD CONSTRAINT promo_codes_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: referrals referrals_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.referrals
+    ADD CONSTRAINT referrals_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -642,6 +1143,38 @@ ALTER TABLE ONLY public.schema_migrations
     ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
 
 
+--
+-- Name: subscription_expiry_reminders subscription_expiry_reminders_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_expiry_reminders
+    ADD CONSTRAINT subscription_expiry_reminders_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: subscription_histories subscription_histories_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_histories
+    ADD CONSTRAINT subscription_histories_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: subscription_order_items subscription_order_items_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_order_items
+    ADD CONSTRAINT subscription_order_items_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: subscription_orders subscription_orders_invoice_number_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_orders
+    ADD CONSTRAINT subscription_orders_invoice_number_key UNIQUE (invoice_number);
+
+
 --
 -- Name: subscription_orders subscription_orders_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -674,6 +1207,14 @@ ALTER TABLE ONLY public.user_has_tokens
     ADD CONSTRAINT user_has_tokens_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: user_sessions user_sessions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_sessions
+    ADD CONSTRAINT user_sessions_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: user_subscriptions user_subscriptions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -712,6 +1253,55 @@ CREATE INDEX idx_categories_merchant_id ON public.categories USING btree (mercha
 CREATE INDEX idx_merchants_owner_id ON public.merchants USING btree (owner_id);
 
 
+--
+-- Name: idx_password_reset_tokens_token_hash; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON public.password_reset_tokens USING btree (token_hash);
+
+
+--
+-- Name: idx_password_reset_tokens_user_id_created_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_password_reset_tokens_user_id_created_at ON public.password_reset_tokens USING btree (user_id, created_at);
+
+
+--
+-- Name: idx_payment_method_maintenance_windows_payment_method_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payment_method_maintenance_windows_payment_method_id ON public.payment_method_maintenance_windows USING btree (payment_method_id, ends_at);
+
+
+--
+-- Name: idx_payment_methods_is_enabled_display_order; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payment_methods_is_enabled_display_order ON public.payment_methods USING btree (is_enabled, display_order);
+
+
+--
+-- Name: idx_payment_notifications_dedupe_key; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payment_notifications_dedupe_key ON public.payment_notifications USING btree (dedupe_key);
+
+
+--
+-- Name: idx_payment_notifications_payment_transaction_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payment_notifications_payment_transaction_id ON public.payment_notifications USING btree (payment_transaction_id);
+
+
+--
+-- Name: idx_payment_refunds_payment_transaction_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_payment_refunds_payment_transaction_id ON public.payment_refunds USING btree (payment_transaction_id);
+
+
 --
 -- Name: idx_product_metrics_product_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -733,6 +1323,48 @@ CREATE INDEX idx_products_category_id ON public.products USING btree (category_i
 CREATE INDEX idx_products_merchant_id ON public.products USING btree (merchant_id);
 
 
+--
+-- Name: idx_promo_codes_code; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_promo_codes_code ON public.promo_codes USING btree (code) WHERE (deleted_at IS NULL);
+
+
+--
+-- Name: idx_referrals_referred_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_referrals_referred_id ON public.referrals USING btree (referred_id);
+
+
+--
+-- Name: idx_referrals_referrer_id_status; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_referrals_referrer_id_status ON public.referrals USING btree (referrer_id, status);
+
+
+--
+-- Name: idx_subscription_expiry_reminders_unique; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_subscription_expiry_reminders_unique ON public.subscription_expiry_reminders USING btree (user_subscription_id, expired_at, days_before);
+
+
+--
+-- Name: idx_subscription_histories_user_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_subscription_histories_user_id ON public.subscription_histories USING btree (user_id, created_at);
+
+
+--
+-- Name: idx_subscription_order_items_order_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_subscription_order_items_order_id ON public.subscription_order_items USING btree (order_id);
+
+
 --
 -- Name: idx_subscription_orders_payment_transaction_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -740,6 +1372,13 @@ CREATE INDEX idx_products_merchant_id ON public.products USING btree (merchant_i
 CREATE INDEX idx_subscription_orders_payment_transaction_id ON public.subscription_orders USING btree (payment_transaction_id);
 
 
+--
+-- Name: idx_subscription_orders_promo_code_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_subscription_orders_promo_code_id ON public.subscription_orders USING btree (promo_code_id, user_id) WHERE (promo_code_id IS NOT NULL);
+
+
 --
 -- Name: idx_subscription_orders_subscription_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -761,6 +1400,41 @@ CREATE INDEX idx_subscription_orders_user_id ON public.subscription_orders USING
 CREATE INDEX idx_subscription_plans_sub_id ON public.subscription_plans USING btree (sub_id);
 
 
+--
+-- Name: idx_subscriptions_family_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_subscriptions_family_id ON public.subscriptions USING btree (family_id, version);
+
+
+--
+-- Name: idx_user_has_tokens_session_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_user_has_tokens_session_id ON public.user_has_tokens USING btree (session_id);
+
+
+--
+-- Name: idx_user_has_tokens_token_hash; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_user_has_tokens_token_hash ON public.user_has_tokens USING btree (token_hash);
+
+
+--
+-- Name: idx_user_sessions_user_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_user_sessions_user_id ON public.user_sessions USING btree (user_id);
+
+
+--
+-- Name: idx_user_subscriptions_scheduled; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_user_subscriptions_scheduled ON public.user_subscriptions USING btree (user_id) WHERE is_scheduled;
+
+
 --
 -- Name: idx_user_subscriptions_sub_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -768,6 +1442,20 @@ CREATE INDEX idx_subscription_plans_sub_id ON public.subscription_plans USING bt
 CREATE INDEX idx_user_subscriptions_sub_id ON public.user_subscriptions USING btree (sub_id);
 
 
+--
+-- Name: idx_user_subscriptions_trial_email; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_user_subscriptions_trial_email ON public.user_subscriptions USING btree (trial_email) WHERE (trial_ends_at IS NOT NULL);
+
+
+--
+-- Name: idx_user_subscriptions_trial_user_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_user_subscriptions_trial_user_id ON public.user_subscriptions USING btree (user_id) WHERE (trial_ends_at IS NOT NULL);
+
+
 --
 -- Name: idx_user_subscriptions_user_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -775,6 +1463,13 @@ CREATE INDEX idx_user_subscriptions_sub_id ON public.user_subscriptions USING bt
 CREATE INDEX idx_user_subscriptions_user_id ON public.user_subscriptions USING btree (user_id);
 
 
+--
+-- Name: idx_users_referral_code; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX idx_users_referral_code ON public.users USING btree (referral_code);
+
+
 --
 -- Name: email_activation_tokens fk_activation_user; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -799,6 +1494,38 @@ ALTER TABLE ONLY public.merchants
     ADD CONSTRAINT fk_merchant_owner FOREIGN KEY (owner_id) REFERENCES public.users(id) ON DELETE SET NULL;
 
 
+--
+-- Name: payment_method_maintenance_windows fk_payment_method_maintenance_windows_payment_method; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_method_maintenance_windows
+    ADD CONSTRAINT fk_payment_method_maintenance_windows_payment_method FOREIGN KEY (payment_method_id) REFERENCES public.payment_methods(id) ON DELETE CASCADE;
+
+
+--
+-- Name: payment_notifications fk_payment_notifications_payment_transaction; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_notifications
+    ADD CONSTRAINT fk_payment_notifications_payment_transaction FOREIGN KEY (payment_transaction_id) REFERENCES public.payment_transactions(id) ON DELETE SET NULL;
+
+
+--
+-- Name: payment_refunds fk_payment_refunds_payment_transaction; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_refunds
+    ADD CONSTRAINT fk_payment_refunds_payment_transaction FOREIGN KEY (payment_transaction_id) REFERENCES public.payment_transactions(id) ON DELETE CASCADE;
+
+
+--
+-- Name: payment_refunds fk_payment_refunds_refunded_by; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.payment_refunds
+    ADD CONSTRAINT fk_payment_refunds_refunded_by FOREIGN KEY (refunded_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
 --
 -- Name: product_metrics fk_product; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -823,6 +1550,38 @@ ALTER TABLE ONLY public.products
     ADD CONSTRAINT fk_products_merchant FOREIGN KEY (merchant_id) REFERENCES public.merchants(id) ON DELETE CASCADE;
 
 
+--
+-- Name: subscription_expiry_reminders fk_subscription_expiry_reminders_user_subscription; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_expiry_reminders
+    ADD CONSTRAINT fk_subscription_expiry_reminders_user_subscription FOREIGN KEY (user_subscription_id) REFERENCES public.user_subscriptions(id) ON DELETE CASCADE;
+
+
+--
+-- Name: subscription_histories fk_subscription_histories_user; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_histories
+    ADD CONSTRAINT fk_subscription_histories_user FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: subscription_histories fk_subscription_histories_user_subscription; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_histories
+    ADD CONSTRAINT fk_subscription_histories_user_subscription FOREIGN KEY (user_subscription_id) REFERENCES public.user_subscriptions(id) ON DELETE CASCADE;
+
+
+--
+-- Name: subscription_order_items fk_subscription_order_items_order; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_order_items
+    ADD CONSTRAINT fk_subscription_order_items_order FOREIGN KEY (order_id) REFERENCES public.subscription_orders(id) ON DELETE CASCADE;
+
+
 --
 -- Name: subscription_orders fk_subscription_orders_payment_transaction; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -847,6 +1606,14 @@ ALTER TABLE ONLY public.subscription_orders
     ADD CONSTRAINT fk_subscription_orders_user FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: subscription_orders fk_subscription_orders_user_subscription; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_orders
+    ADD CONSTRAINT fk_subscription_orders_user_subscription FOREIGN KEY (user_subscription_id) REFERENCES public.user_subscriptions(id) ON DELETE SET NULL;
+
+
 --
 -- Name: subscription_plans fk_subscription_plans_subscription; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -887,6 +1654,70 @@ ALTER TABLE ONLY public.user_subscriptions
     ADD CONSTRAINT fk_user_subscriptions_user FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: password_reset_tokens password_reset_tokens_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.password_reset_tokens
+    ADD CONSTRAINT password_reset_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: referrals referrals_order_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.referrals
+    ADD CONSTRAINT referrals_order_id_fkey FOREIGN KEY (order_id) REFERENCES public.subscription_orders(id) ON DELETE SET NULL;
+
+
+--
+-- Name: referrals referrals_referred_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.referrals
+    ADD CONSTRAINT referrals_referred_id_fkey FOREIGN KEY (referred_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: referrals referrals_referrer_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.referrals
+    ADD CONSTRAINT referrals_referrer_id_fkey FOREIGN KEY (referrer_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: subscription_orders subscription_orders_promo_code_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscription_orders
+    ADD CONSTRAINT subscription_orders_promo_code_id_fkey FOREIGN KEY (promo_code_id) REFERENCES public.promo_codes(id) ON DELETE SET NULL;
+
+
+--
+-- Name: subscriptions subscriptions_superseded_by_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.subscriptions
+    ADD CONSTRAINT subscriptions_superseded_by_id_fkey FOREIGN KEY (superseded_by_id) REFERENCES public.subscriptions(id) ON DELETE SET NULL;
+
+
+--
+-- Name: user_has_tokens user_has_tokens_session_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_has_tokens
+    ADD CONSTRAINT user_has_tokens_session_id_fkey FOREIGN KEY (session_id) REFERENCES public.user_sessions(id) ON DELETE CASCADE;
+
+
+--
+-- Name: user_sessions user_sessions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_sessions
+    ADD CONSTRAINT user_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
 --
 -- PostgreSQL database dump complete
 --
@@ -915,4 +1746,33 @@ INSERT INTO public.schema_migrations (version) VALUES
     ('20250909025514'),
     ('20250914022649'),
     ('20250914040119'),
-    ('20250914091132');
+    ('20250914091132'),
+    ('20250920081512'),
+    ('20250921093045'),
+    ('20250921140210'),
+    ('20250922021417'),
+    ('20250922064530'),
+    ('20250923014210'),
+    ('20250923014530'),
+    ('20250924021005'),
+    ('20250924021130'),
+    ('20250924021245'),
+    ('20250924021400'),
+    ('20250924021515'),
+    ('20250924021630'),
+    ('20250924021745'),
+    ('20250925013010'),
+    ('20250925013125'),
+    ('20250925013240'),
+    ('20250926020515'),
+    ('20250926020640'),
+    ('20250927013015'),
+    ('20250928014210'),
+    ('20250928014330'),
+    ('20250929013020'),
+    ('20250929013145'),
+    ('20250930011205'),
+    ('20250930011420'),
+    ('20251001020115'),
+    ('20251001020340'),
+    ('20251002031540');
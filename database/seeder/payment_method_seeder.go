@@ -0,0 +1,46 @@
+package seeder
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/repositories"
+
+	"gorm.io/gorm"
+)
+
+// Seed the payment methods from the embedded payment-methods.yaml
+// Channels that already exist are skipped, so changes made by admins are kept
+// The display order follows the order of the YAML file
+func SeedPaymentMethods(db *gorm.DB) error {
+	paymentMethodRepository := repositories.NewPaymentMethodRepository(db)
+
+	defaults, err := midtrans.GetDefaultPaymentMethods()
+	if err != nil {
+		return err
+	}
+
+	for i, method := range defaults {
+		if err := paymentMethodRepository.StoreIfChannelNotExists(&models.PaymentMethod{
+			Name:              method.Name,
+			PaymentType:       method.PaymentType,
+			Channel:           method.Channel,
+			Provider:          method.Provider,
+			MinAmount:         method.MinAmount,
+			MaxAmount:         method.MaxAmount,
+			Description:       method.Description,
+			LogoURL:           method.LogoURL,
+			ExpiryDuration:    method.ExpiryDuration,
+			ExpiryUnit:        method.ExpiryUnit,
+			VANumberMinLength: method.VANumberMinLength,
+			VANumberMaxLength: method.VANumberMaxLength,
+			FeeFlat:           method.FeeFlat,
+			FeePercentage:     method.FeePercentage,
+			IsEnabled:         true,
+			DisplayOrder:      (i + 1) * 10,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
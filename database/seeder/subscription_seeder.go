@@ -4,6 +4,7 @@ import (
 	"errors"
 	"senkou-catalyst-be/app/models"
 	"senkou-catalyst-be/repositories"
+	"senkou-catalyst-be/utils/money"
 
 	"gorm.io/gorm"
 )
@@ -11,8 +12,9 @@ import (
 type SubscriptionData struct {
 	Name        string
 	Description string
-	Price       float64
+	Price       int64
 	Duration    int
+	TrialDays   int
 	Plans       []PlanData
 }
 
@@ -27,7 +29,7 @@ func SeedSubscriptions(db *gorm.DB) error {
 
 	subscriptions := []SubscriptionData{
 		{
-			Name:        "Free tier",
+			Name:        models.FreeTierSubscriptionName,
 			Description: "The minimal subscription plan",
 			Price:       0,
 			Duration:    28,
@@ -37,6 +39,8 @@ func SeedSubscriptions(db *gorm.DB) error {
 				{"Subscription-Analytics", "false"},
 				{"Subscription-Interaction-Metrics", "false"},
 				{"Subscription-Merchant-Template-Customize", "false"},
+				{"Subscription-Photo-Size", "1MB"},
+				{"Subscription-Photos-Per-Product", "5"},
 			},
 		},
 		{
@@ -50,6 +54,8 @@ func SeedSubscriptions(db *gorm.DB) error {
 				{"Subscription-Analytics", "true"},
 				{"Subscription-Interaction-Metrics", "false"},
 				{"Subscription-Merchant-Template-Customize", "true"},
+				{"Subscription-Photo-Size", "2MB"},
+				{"Subscription-Photos-Per-Product", "8"},
 			},
 		},
 		{
@@ -57,12 +63,15 @@ func SeedSubscriptions(db *gorm.DB) error {
 			Description: "Subscription plan for businesses",
 			Price:       30000,
 			Duration:    28,
+			TrialDays:   14,
 			Plans: []PlanData{
 				{"Subscription-Product-Slot", "99999"},
 				{"Subscription-Category-Limit", "999"},
 				{"Subscription-Analytics", "true"},
 				{"Subscription-Interaction-Metrics", "true"},
 				{"Subscription-Merchant-Template-Customize", "true"},
+				{"Subscription-Photo-Size", "4MB"},
+				{"Subscription-Photos-Per-Product", "10"},
 			},
 		},
 	}
@@ -85,8 +94,9 @@ func createOrGetSubscription(repo repositories.SubscriptionRepository, data Subs
 	sub := &models.Subscription{
 		Name:        data.Name,
 		Description: data.Description,
-		Price:       float32(data.Price),
+		Price:       money.FromMajor(data.Price, money.IDR),
 		Duration:    int16(data.Duration),
+		TrialDays:   int16(data.TrialDays),
 	}
 
 	return repo.StoreNewSubscription(sub)
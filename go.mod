@@ -15,6 +15,8 @@ require (
 
 require (
 	github.com/aws/aws-sdk-go-v2 v1.37.2
+	github.com/hibiken/asynq v0.25.1
+	github.com/markbates/goth v1.82.0
 	github.com/midtrans/midtrans-go v1.3.8
 )
 
@@ -30,12 +32,12 @@ require (
 	github.com/gorilla/mux v1.6.2 // indirect
 	github.com/gorilla/securecookie v1.1.1 // indirect
 	github.com/gorilla/sessions v1.1.1 // indirect
-	github.com/hibiken/asynq v0.25.1 // indirect
-	github.com/markbates/goth v1.82.0 // indirect
+	github.com/philhofer/fwd v1.1.3-0.20240916144458-20a13a1f6b7c // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/redis/go-redis/v9 v9.7.0 // indirect
 	github.com/robfig/cron/v3 v3.0.1 // indirect
 	github.com/spf13/cast v1.7.0 // indirect
+	github.com/tinylib/msgp v1.2.5 // indirect
 	golang.org/x/oauth2 v0.27.0 // indirect
 	golang.org/x/time v0.8.0 // indirect
 	google.golang.org/protobuf v1.35.2 // indirect
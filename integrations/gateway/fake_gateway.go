@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/utils/money"
+	"sync"
+	"time"
+)
+
+const fakeNotificationTokenHeader = "x-fake-notification-token"
+
+// An in memory payment gateway for tests and local development
+// Payments never leave the process, their status is driven by SetStatus and
+// notifications are trusted when they carry the configured token
+type FakeGateway struct {
+	NotificationToken string
+
+	mu       sync.Mutex
+	payments map[string]*PaymentUpdate
+	refunds  map[string][]RefundRequest
+}
+
+func NewFakeGateway(notificationToken string) *FakeGateway {
+	return &FakeGateway{
+		NotificationToken: notificationToken,
+		payments:          make(map[string]*PaymentUpdate),
+		refunds:           make(map[string][]RefundRequest),
+	}
+}
+
+func (g *FakeGateway) Provider() Provider {
+	return ProviderFake
+}
+
+// Charge a payment, the payment stays pending until its status is changed
+func (g *FakeGateway) Charge(request *ChargeRequest) (*ChargeResult, error) {
+	if len(request.Items) == 0 {
+		return nil, &Error{StatusCode: http.StatusBadRequest, Message: "at least one item is required"}
+	}
+
+	amount := money.Zero(request.Items[0].Price.CurrencyCode())
+	for _, item := range request.Items {
+		if !item.Price.SameCurrency(amount) {
+			return nil, &Error{StatusCode: http.StatusBadRequest, Message: "every item must have the same currency"}
+		}
+
+		amount = amount.Add(item.Price.Mul(int64(item.Quantity)))
+	}
+
+	now := time.Now()
+	payment := &PaymentUpdate{
+		OrderID:         request.OrderID,
+		TransactionID:   "fake-" + request.OrderID,
+		Status:          midtrans.PaymentStatusPending,
+		RawStatus:       string(midtrans.PaymentStatusPending),
+		GrossAmount:     amount,
+		TransactionTime: &now,
+		ExpiryTime:      methodExpiry(request.Method, now),
+	}
+
+	g.AddPayment(payment)
+
+	return &ChargeResult{
+		TransactionID:   payment.TransactionID,
+		Status:          payment.Status,
+		TransactionTime: payment.TransactionTime,
+		Instruction: &midtrans.PaymentInstruction{
+			OrderID:       payment.OrderID,
+			TransactionID: payment.TransactionID,
+			PaymentType:   request.Method.PaymentType,
+			Channel:       request.Method.Channel,
+			Status:        string(payment.Status),
+			GrossAmount:   amount.Decimal(),
+			Currency:      amount.CurrencyCode(),
+			VANumber:      request.VANumber,
+			ExpiresAt:     payment.ExpiryTime,
+		},
+	}, nil
+}
+
+func (g *FakeGateway) Status(reference Reference) (*PaymentUpdate, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, ok := g.payments[reference.OrderID]
+	if !ok {
+		return nil, &Error{StatusCode: http.StatusNotFound, Message: "payment not found"}
+	}
+
+	status := *payment
+	return &status, nil
+}
+
+func (g *FakeGateway) Cancel(reference Reference) error {
+	return g.closePending(reference, midtrans.PaymentStatusCanceled)
+}
+
+func (g *FakeGateway) Expire(reference Reference) error {
+	return g.closePending(reference, midtrans.PaymentStatusExpired)
+}
+
+// Refund a settled payment, refunds beyond the paid amount are rejected
+func (g *FakeGateway) Refund(reference Reference, request *RefundRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, ok := g.payments[reference.OrderID]
+	if !ok {
+		return &Error{StatusCode: http.StatusNotFound, Message: "payment not found"}
+	}
+
+	if payment.Status != midtrans.PaymentStatusSettled && payment.Status != midtrans.PaymentStatusPartiallyRefunded {
+		return &Error{StatusCode: http.StatusPreconditionFailed, Message: "payment is not settled"}
+	}
+
+	refunded := money.Zero(payment.GrossAmount.CurrencyCode())
+	for _, refund := range g.refunds[reference.OrderID] {
+		if refund.RefundKey == request.RefundKey {
+			return nil
+		}
+
+		refunded = refunded.Add(refund.Amount)
+	}
+
+	if !request.Amount.SameCurrency(payment.GrossAmount) {
+		return &Error{StatusCode: http.StatusBadRequest, Message: "refund currency does not match the payment"}
+	}
+
+	if refunded.Add(request.Amount).Cmp(payment.GrossAmount) > 0 {
+		return &Error{StatusCode: http.StatusPreconditionFailed, Message: "refund amount exceeds the paid amount"}
+	}
+
+	g.refunds[reference.OrderID] = append(g.refunds[reference.OrderID], *request)
+
+	payment.Status = midtrans.PaymentStatusPartiallyRefunded
+	if refunded.Add(request.Amount).Cmp(payment.GrossAmount) >= 0 {
+		payment.Status = midtrans.PaymentStatusRefunded
+	}
+	payment.RawStatus = string(payment.Status)
+
+	return nil
+}
+
+// Verify the token of a fake notification
+func (g *FakeGateway) VerifyNotification(request *NotificationRequest) error {
+	if g.NotificationToken == "" || request.Header(fakeNotificationTokenHeader) != g.NotificationToken {
+		return errors.New("invalid notification token")
+	}
+
+	return nil
+}
+
+// Parse a fake notification
+// The body has the order_id, transaction_id, status and gross_amount of the payment
+// The gross amount is either a money object or a number of rupiah
+func (g *FakeGateway) ParseNotification(request *NotificationRequest) (*PaymentUpdate, error) {
+	var notification struct {
+		OrderID       string      `json:"order_id"`
+		TransactionID string      `json:"transaction_id"`
+		Status        string      `json:"status"`
+		GrossAmount   money.Money `json:"gross_amount"`
+	}
+
+	if err := json.Unmarshal(request.Body, &notification); err != nil {
+		return nil, fmt.Errorf("invalid notification body: %w", err)
+	}
+
+	return &PaymentUpdate{
+		OrderID:       notification.OrderID,
+		TransactionID: notification.TransactionID,
+		Status:        midtrans.ParseStatus(notification.Status),
+		RawStatus:     notification.Status,
+		GrossAmount:   notification.GrossAmount,
+	}, nil
+}
+
+// Add a payment to the gateway, replacing any payment with the same order ID
+func (g *FakeGateway) AddPayment(payment *PaymentUpdate) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.payments[payment.OrderID] = payment
+}
+
+// Change the status of a payment as if the customer acted on it
+func (g *FakeGateway) SetStatus(orderID string, status midtrans.PaymentStatus) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if payment, ok := g.payments[orderID]; ok {
+		payment.Status = status
+		payment.RawStatus = string(status)
+
+		if status == midtrans.PaymentStatusSettled {
+			now := time.Now()
+			payment.SettlementTime = &now
+		}
+	}
+}
+
+// Get the refunds made for a payment
+func (g *FakeGateway) Refunds(orderID string) []RefundRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]RefundRequest(nil), g.refunds[orderID]...)
+}
+
+func (g *FakeGateway) closePending(reference Reference, status midtrans.PaymentStatus) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payment, ok := g.payments[reference.OrderID]
+	if !ok {
+		return &Error{StatusCode: http.StatusNotFound, Message: "payment not found"}
+	}
+
+	if payment.Status != midtrans.PaymentStatusPending {
+		return &Error{StatusCode: http.StatusPreconditionFailed, Message: "payment is not pending"}
+	}
+
+	payment.Status = status
+	payment.RawStatus = string(status)
+
+	return nil
+}
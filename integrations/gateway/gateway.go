@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/utils/money"
+	"time"
+)
+
+type Provider string
+
+const (
+	ProviderMidtrans Provider = "midtrans"
+	ProviderXendit   Provider = "xendit"
+	ProviderFake     Provider = "fake"
+)
+
+// A payment provider that charges customers and reports the outcome of their payments
+// Every implementation speaks in normalized payment statuses so callers never deal with
+// the vocabulary of a specific provider
+type PaymentGateway interface {
+	Provider() Provider
+	Charge(request *ChargeRequest) (*ChargeResult, error)
+	Status(reference Reference) (*PaymentUpdate, error)
+	Cancel(reference Reference) error
+	Expire(reference Reference) error
+	Refund(reference Reference, request *RefundRequest) error
+	VerifyNotification(request *NotificationRequest) error
+	ParseNotification(request *NotificationRequest) (*PaymentUpdate, error)
+}
+
+// Identifies a payment on the provider side
+// OrderID is the payment transaction ID, TransactionID the ID assigned by the provider
+type Reference struct {
+	OrderID       string
+	TransactionID string
+}
+
+type ChargeRequest struct {
+	OrderID  string
+	Customer *models.User
+	Method   midtrans.PaymentMethodConfig
+	Items    []dtos.PaymentItemDTO
+	VANumber string
+}
+
+type ChargeResult struct {
+	TransactionID   string
+	Status          midtrans.PaymentStatus
+	FraudStatus     string
+	TransactionTime *time.Time
+	Instruction     *midtrans.PaymentInstruction
+}
+
+type RefundRequest struct {
+	RefundKey string
+	Amount    money.Money
+	Reason    string
+}
+
+// The raw notification sent by a provider
+type NotificationRequest struct {
+	Body    []byte
+	Headers map[string]string
+}
+
+// Get a header of the notification, header names are case insensitive
+func (r *NotificationRequest) Header(name string) string {
+	if value, ok := r.Headers[name]; ok {
+		return value
+	}
+
+	return r.Headers[http.CanonicalHeaderKey(name)]
+}
+
+// The state of a payment as reported by a status check or a notification
+type PaymentUpdate struct {
+	OrderID         string
+	TransactionID   string
+	Status          midtrans.PaymentStatus
+	RawStatus       string
+	FraudStatus     string
+	GrossAmount     money.Money
+	SignatureKey    string
+	TransactionTime *time.Time
+	SettlementTime  *time.Time
+	ExpiryTime      *time.Time
+}
+
+// An error answered by a payment provider
+// StatusCode follows the HTTP semantics, 4xx means the provider rejected the request
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("payment gateway error %d: %s", e.StatusCode, e.Message)
+}
+
+// Check whether an error means the provider does not know the payment
+func IsNotFound(err error) bool {
+	var gatewayError *Error
+	return errors.As(err, &gatewayError) && gatewayError.StatusCode == http.StatusNotFound
+}
+
+// Check whether an error means the provider rejected the request
+func IsRejected(err error) bool {
+	var gatewayError *Error
+	return errors.As(err, &gatewayError) &&
+		gatewayError.StatusCode >= http.StatusBadRequest &&
+		gatewayError.StatusCode < http.StatusInternalServerError
+}
+
+// Compute when a payment made now with a payment method expires
+// It returns nil when the catalog entry has no expiry configured
+func methodExpiry(pm midtrans.PaymentMethodConfig, now time.Time) *time.Time {
+	if pm.ExpiryDuration <= 0 {
+		return nil
+	}
+
+	var unit time.Duration
+	switch pm.ExpiryUnit {
+	case "second":
+		unit = time.Second
+	case "minute":
+		unit = time.Minute
+	case "hour":
+		unit = time.Hour
+	case "day":
+		unit = 24 * time.Hour
+	default:
+		return nil
+	}
+
+	expiresAt := now.Add(time.Duration(pm.ExpiryDuration) * unit)
+	return &expiresAt
+}
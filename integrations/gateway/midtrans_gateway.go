@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/utils/converter"
+	"time"
+
+	mt "github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+)
+
+type MidtransGateway struct {
+	client  *midtrans.MidtransClient
+	builder *midtrans.PaymentBuilder
+}
+
+func NewMidtransGateway(client *midtrans.MidtransClient) *MidtransGateway {
+	return &MidtransGateway{
+		client:  client,
+		builder: midtrans.NewPaymentBuilder(client),
+	}
+}
+
+func (g *MidtransGateway) Provider() Provider {
+	return ProviderMidtrans
+}
+
+// Charge a payment through the Midtrans Core API
+// It returns the charge result along with the instruction to complete the payment
+func (g *MidtransGateway) Charge(request *ChargeRequest) (*ChargeResult, error) {
+	chargeReq, err := g.builder.BuildChargeRequest(request.Customer, request.Method, request.Items, request.OrderID, &midtrans.ChargeOptions{
+		VANumber: request.VANumber,
+	})
+	if err != nil {
+		return nil, &Error{StatusCode: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	chargeResp, chargeError := g.client.GetCoreAPIClient().ChargeTransaction(chargeReq)
+	if chargeError != nil {
+		return nil, fromMidtransError(chargeError)
+	}
+
+	return &ChargeResult{
+		TransactionID:   chargeResp.TransactionID,
+		Status:          midtrans.ParseStatus(chargeResp.TransactionStatus),
+		FraudStatus:     chargeResp.FraudStatus,
+		TransactionTime: parseMidtransTime(chargeResp.TransactionTime),
+		Instruction:     midtrans.NewPaymentInstruction(request.Method, chargeResp),
+	}, nil
+}
+
+// Check the status of a payment on Midtrans
+func (g *MidtransGateway) Status(reference Reference) (*PaymentUpdate, error) {
+	status, statusError := g.client.GetCoreAPIClient().CheckTransaction(reference.OrderID)
+	if statusError != nil {
+		return nil, fromMidtransError(statusError)
+	}
+
+	grossAmount, _ := midtrans.ParseGrossAmount(status.GrossAmount, status.Currency)
+
+	return &PaymentUpdate{
+		OrderID:         status.OrderID,
+		TransactionID:   status.TransactionID,
+		Status:          midtrans.ParseStatus(status.TransactionStatus),
+		RawStatus:       status.TransactionStatus,
+		FraudStatus:     status.FraudStatus,
+		GrossAmount:     grossAmount,
+		SignatureKey:    status.SignatureKey,
+		TransactionTime: parseMidtransTime(status.TransactionTime),
+		SettlementTime:  parseMidtransTime(status.SettlementTime),
+		ExpiryTime:      parseMidtransTime(status.ExpiryTime),
+	}, nil
+}
+
+func (g *MidtransGateway) Cancel(reference Reference) error {
+	if _, cancelError := g.client.GetCoreAPIClient().CancelTransaction(reference.OrderID); cancelError != nil {
+		return fromMidtransError(cancelError)
+	}
+
+	return nil
+}
+
+func (g *MidtransGateway) Expire(reference Reference) error {
+	if _, expireError := g.client.GetCoreAPIClient().ExpireTransaction(reference.OrderID); expireError != nil {
+		return fromMidtransError(expireError)
+	}
+
+	return nil
+}
+
+// Refund a payment on Midtrans
+// The refund key makes the request idempotent, retrying with the same key never refunds twice
+// Midtrans only refunds whole rupiah, the amount is rounded half away from zero
+func (g *MidtransGateway) Refund(reference Reference, request *RefundRequest) error {
+	if _, refundError := g.client.GetCoreAPIClient().RefundTransaction(reference.OrderID, &coreapi.RefundReq{
+		RefundKey: request.RefundKey,
+		Amount:    request.Amount.Major(),
+		Reason:    request.Reason,
+	}); refundError != nil {
+		return fromMidtransError(refundError)
+	}
+
+	return nil
+}
+
+// Verify the signature key of a Midtrans notification
+// It returns an error if the notification was not signed with the configured server key
+func (g *MidtransGateway) VerifyNotification(request *NotificationRequest) error {
+	notification, err := decodeMidtransNotification(request)
+	if err != nil {
+		return err
+	}
+
+	if !g.client.VerifySignatureKey(
+		notification.OrderID,
+		notification.StatusCode,
+		notification.GrossAmount,
+		notification.SignatureKey,
+	) {
+		return errors.New("invalid notification signature")
+	}
+
+	return nil
+}
+
+func (g *MidtransGateway) ParseNotification(request *NotificationRequest) (*PaymentUpdate, error) {
+	notification, err := decodeMidtransNotification(request)
+	if err != nil {
+		return nil, err
+	}
+
+	grossAmount, err := midtrans.ParseGrossAmount(notification.GrossAmount, notification.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentUpdate{
+		OrderID:         notification.OrderID,
+		TransactionID:   notification.TransactionID,
+		Status:          midtrans.ParseStatus(notification.TransactionStatus),
+		RawStatus:       notification.TransactionStatus,
+		FraudStatus:     notification.FraudStatus,
+		GrossAmount:     grossAmount,
+		SignatureKey:    notification.SignatureKey,
+		TransactionTime: parseMidtransTime(notification.TransactionTime),
+		SettlementTime:  parseMidtransTime(notification.SettlementTime),
+		ExpiryTime:      parseMidtransTime(notification.ExpiryTime),
+	}, nil
+}
+
+func decodeMidtransNotification(request *NotificationRequest) (*dtos.BaseMidtransNotification, error) {
+	notification := new(dtos.BaseMidtransNotification)
+	if err := json.Unmarshal(request.Body, notification); err != nil {
+		return nil, fmt.Errorf("invalid notification body: %w", err)
+	}
+
+	return notification, nil
+}
+
+// Convert a Midtrans error into a gateway error
+func fromMidtransError(err *mt.Error) *Error {
+	statusCode := err.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusBadGateway
+	}
+
+	return &Error{StatusCode: statusCode, Message: err.GetMessage()}
+}
+
+// Parse an optional Midtrans time, invalid or empty values are ignored
+func parseMidtransTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := converter.ParseMidtransTime(value)
+	if err != nil {
+		return nil
+	}
+
+	return &parsed
+}
@@ -0,0 +1,43 @@
+package gateway
+
+import "fmt"
+
+// Holds the payment gateways available to the application, one per provider
+type Registry struct {
+	gateways map[Provider]PaymentGateway
+}
+
+func NewRegistry(gateways ...PaymentGateway) *Registry {
+	registry := &Registry{
+		gateways: make(map[Provider]PaymentGateway, len(gateways)),
+	}
+
+	for _, gateway := range gateways {
+		registry.gateways[gateway.Provider()] = gateway
+	}
+
+	return registry
+}
+
+// Get the gateway of a provider
+// An empty provider resolves to Midtrans, the provider of every payment made before gateways were introduced
+// It returns an error if the provider is not registered
+func (r *Registry) Get(provider Provider) (PaymentGateway, error) {
+	if provider == "" {
+		provider = ProviderMidtrans
+	}
+
+	gateway, ok := r.gateways[provider]
+	if !ok {
+		return nil, fmt.Errorf("payment gateway %s is not configured", provider)
+	}
+
+	return gateway, nil
+}
+
+// Check whether the gateway of a provider is registered
+// An empty provider resolves to Midtrans like in Get
+func (r *Registry) Has(provider Provider) bool {
+	_, err := r.Get(provider)
+	return err == nil
+}
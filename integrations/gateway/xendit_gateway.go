@@ -0,0 +1,345 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/integrations/xendit"
+	"senkou-catalyst-be/utils/money"
+	"strings"
+	"time"
+)
+
+const xenditCallbackTokenHeader = "x-callback-token"
+
+type XenditGateway struct {
+	client *xendit.XenditClient
+}
+
+func NewXenditGateway(client *xendit.XenditClient) *XenditGateway {
+	return &XenditGateway{
+		client: client,
+	}
+}
+
+func (g *XenditGateway) Provider() Provider {
+	return ProviderXendit
+}
+
+// Charge a payment through the Xendit payment request API
+// The order ID is used as the reference ID of both the payment request and its payment method
+// Xendit only accepts whole rupiah, prices are never rounded here so the charge cannot differ from the order
+// It returns the charge result along with the instruction to complete the payment
+func (g *XenditGateway) Charge(request *ChargeRequest) (*ChargeResult, error) {
+	if len(request.Items) == 0 {
+		return nil, &Error{StatusCode: http.StatusBadRequest, Message: "at least one item is required"}
+	}
+
+	paymentMethod, err := g.buildPaymentMethod(request)
+	if err != nil {
+		return nil, &Error{StatusCode: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	items := make([]xendit.PaymentRequestItem, 0, len(request.Items))
+	amount := money.Zero(money.IDR)
+
+	for _, item := range request.Items {
+		if item.Price.CurrencyCode() != money.IDR || !item.Price.IsWholeMajor() {
+			return nil, &Error{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("item %s must be priced in whole rupiah", item.ID)}
+		}
+
+		// A discount is a negative line, which Xendit only accepts as a discount item
+		itemType := "DIGITAL_SERVICE"
+		if item.Price.IsNegative() {
+			itemType = "DISCOUNT"
+		}
+
+		items = append(items, xendit.PaymentRequestItem{
+			ReferenceID:   item.ID,
+			Name:          item.Name,
+			NetUnitAmount: float64(item.Price.Major()),
+			Quantity:      item.Quantity,
+			Currency:      money.IDR,
+			Category:      "SUBSCRIPTION",
+			Type:          itemType,
+		})
+
+		amount = amount.Add(item.Price.Mul(int64(item.Quantity)))
+	}
+
+	paymentRequest, err := g.client.CreatePaymentRequest(&xendit.CreatePaymentRequest{
+		ReferenceID:   request.OrderID,
+		Amount:        float64(amount.Major()),
+		Currency:      "IDR",
+		Country:       "ID",
+		PaymentMethod: *paymentMethod,
+		Items:         items,
+	})
+	if err != nil {
+		return nil, fromXenditError(err)
+	}
+
+	return &ChargeResult{
+		TransactionID:   paymentRequest.ID,
+		Status:          parseXenditStatus(paymentRequest.Status),
+		TransactionTime: paymentRequest.Created,
+		Instruction:     newXenditInstruction(request.Method, paymentRequest),
+	}, nil
+}
+
+// Check the status of a payment request on Xendit
+// Payment requests are looked up by the Xendit ID, an unknown ID is reported as not found
+func (g *XenditGateway) Status(reference Reference) (*PaymentUpdate, error) {
+	paymentRequest, err := g.getPaymentRequest(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &PaymentUpdate{
+		OrderID:         paymentRequest.ReferenceID,
+		TransactionID:   paymentRequest.ID,
+		Status:          parseXenditStatus(paymentRequest.Status),
+		RawStatus:       paymentRequest.Status,
+		GrossAmount:     money.FromFloat(paymentRequest.Amount, paymentRequest.Currency),
+		TransactionTime: paymentRequest.Created,
+		ExpiryTime:      paymentMethodExpiry(&paymentRequest.PaymentMethod),
+	}
+
+	if update.Status == midtrans.PaymentStatusSettled {
+		update.SettlementTime = paymentRequest.Updated
+	}
+
+	return update, nil
+}
+
+// Cancel a pending payment by expiring its payment method
+// Xendit has no cancel operation for payment requests, an expired payment method can no longer be paid
+func (g *XenditGateway) Cancel(reference Reference) error {
+	return g.Expire(reference)
+}
+
+func (g *XenditGateway) Expire(reference Reference) error {
+	paymentRequest, err := g.getPaymentRequest(reference)
+	if err != nil {
+		return err
+	}
+
+	if _, err := g.client.ExpirePaymentMethod(paymentRequest.PaymentMethod.ID); err != nil {
+		return fromXenditError(err)
+	}
+
+	return nil
+}
+
+// Refund a payment on Xendit
+// The refund key is sent as the idempotency key so retrying never refunds twice
+// Xendit only refunds whole rupiah, the amount is rounded half away from zero
+func (g *XenditGateway) Refund(reference Reference, request *RefundRequest) error {
+	if reference.TransactionID == "" {
+		return &Error{StatusCode: http.StatusNotFound, Message: "payment request ID is required"}
+	}
+
+	if _, err := g.client.CreateRefund(&xendit.CreateRefundRequest{
+		PaymentRequestID: reference.TransactionID,
+		ReferenceID:      request.RefundKey,
+		Amount:           float64(request.Amount.Major()),
+		Currency:         request.Amount.CurrencyCode(),
+		Reason:           xendit.RefundReasonOthers,
+		Metadata: map[string]string{
+			"reason": request.Reason,
+		},
+	}, request.RefundKey); err != nil {
+		return fromXenditError(err)
+	}
+
+	return nil
+}
+
+// Verify the callback token of a Xendit notification
+// It returns an error if the token does not match the configured callback token
+func (g *XenditGateway) VerifyNotification(request *NotificationRequest) error {
+	if !g.client.VerifyCallbackToken(request.Header(xenditCallbackTokenHeader)) {
+		return errors.New("invalid callback token")
+	}
+
+	return nil
+}
+
+func (g *XenditGateway) ParseNotification(request *NotificationRequest) (*PaymentUpdate, error) {
+	callback := new(xendit.PaymentCallback)
+	if err := json.Unmarshal(request.Body, callback); err != nil {
+		return nil, fmt.Errorf("invalid notification body: %w", err)
+	}
+
+	if callback.Data.ReferenceID == "" {
+		return nil, errors.New("notification reference ID is required")
+	}
+
+	update := &PaymentUpdate{
+		OrderID:         callback.Data.ReferenceID,
+		TransactionID:   callback.Data.PaymentRequestID,
+		Status:          parseXenditStatus(callback.Data.Status),
+		RawStatus:       callback.Data.Status,
+		GrossAmount:     money.FromFloat(callback.Data.Amount, callback.Data.Currency),
+		TransactionTime: callback.Data.Created,
+	}
+
+	if update.Status == midtrans.PaymentStatusSettled {
+		update.SettlementTime = callback.Data.Updated
+	}
+
+	return update, nil
+}
+
+func (g *XenditGateway) getPaymentRequest(reference Reference) (*xendit.PaymentRequest, error) {
+	if reference.TransactionID == "" {
+		return nil, &Error{StatusCode: http.StatusNotFound, Message: "payment request ID is required"}
+	}
+
+	paymentRequest, err := g.client.GetPaymentRequest(reference.TransactionID)
+	if err != nil {
+		return nil, fromXenditError(err)
+	}
+
+	return paymentRequest, nil
+}
+
+// Build the payment method of a payment request from the catalog entry
+// It returns an error if the payment type is not supported by Xendit
+func (g *XenditGateway) buildPaymentMethod(request *ChargeRequest) (*xendit.PaymentMethod, error) {
+	channelCode := strings.ToUpper(request.Method.Channel)
+	expiresAt := methodExpiry(request.Method, time.Now())
+
+	paymentMethod := &xendit.PaymentMethod{
+		Reusability: "ONE_TIME_USE",
+		ReferenceID: request.OrderID,
+	}
+
+	switch {
+	case request.Method.PaymentType == "bank_transfer":
+		paymentMethod.Type = xendit.PaymentMethodTypeVirtualAccount
+		paymentMethod.VirtualAccount = &xendit.VirtualAccount{
+			ChannelCode: channelCode,
+			ChannelProperties: xendit.VirtualAccountChannelProperties{
+				CustomerName:         request.Customer.Name,
+				VirtualAccountNumber: request.VANumber,
+				ExpiresAt:            expiresAt,
+			},
+		}
+	case strings.HasPrefix(request.Method.PaymentType, "e_wallet_"):
+		paymentMethod.Type = xendit.PaymentMethodTypeEwallet
+		paymentMethod.Ewallet = &xendit.Ewallet{
+			ChannelCode: channelCode,
+			ChannelProperties: xendit.EwalletChannelProperties{
+				MobileNumber: formatMobileNumber(request.Customer.Phone),
+			},
+		}
+	case request.Method.PaymentType == "qris":
+		paymentMethod.Type = xendit.PaymentMethodTypeQRCode
+		paymentMethod.QRCode = &xendit.QRCode{
+			ChannelProperties: &xendit.QRCodeChannelProperties{
+				ExpiresAt: expiresAt,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unsupported payment method: %s", request.Method.PaymentType)
+	}
+
+	return paymentMethod, nil
+}
+
+// Build a payment instruction from a Xendit payment request
+func newXenditInstruction(pm midtrans.PaymentMethodConfig, paymentRequest *xendit.PaymentRequest) *midtrans.PaymentInstruction {
+	instruction := &midtrans.PaymentInstruction{
+		OrderID:       paymentRequest.ReferenceID,
+		TransactionID: paymentRequest.ID,
+		PaymentType:   pm.PaymentType,
+		Channel:       pm.Channel,
+		Status:        string(parseXenditStatus(paymentRequest.Status)),
+		GrossAmount:   money.FromFloat(paymentRequest.Amount, paymentRequest.Currency).Decimal(),
+		Currency:      paymentRequest.Currency,
+		ExpiresAt:     paymentMethodExpiry(&paymentRequest.PaymentMethod),
+	}
+
+	if va := paymentRequest.PaymentMethod.VirtualAccount; va != nil {
+		instruction.VANumber = va.ChannelProperties.VirtualAccountNumber
+	}
+
+	if qr := paymentRequest.PaymentMethod.QRCode; qr != nil && qr.ChannelProperties != nil {
+		instruction.QRString = qr.ChannelProperties.QRString
+	}
+
+	for _, action := range paymentRequest.Actions {
+		switch action.URLType {
+		case "DEEPLINK", "MOBILE":
+			if instruction.DeeplinkURL == "" {
+				instruction.DeeplinkURL = action.URL
+			}
+		}
+
+		if action.QRCode != "" && instruction.QRString == "" {
+			instruction.QRString = action.QRCode
+		}
+	}
+
+	return instruction
+}
+
+// Get the expiry of a payment method, if Xendit reported one
+func paymentMethodExpiry(paymentMethod *xendit.PaymentMethod) *time.Time {
+	if paymentMethod.VirtualAccount != nil && paymentMethod.VirtualAccount.ChannelProperties.ExpiresAt != nil {
+		return paymentMethod.VirtualAccount.ChannelProperties.ExpiresAt
+	}
+
+	if paymentMethod.QRCode != nil && paymentMethod.QRCode.ChannelProperties != nil {
+		return paymentMethod.QRCode.ChannelProperties.ExpiresAt
+	}
+
+	return nil
+}
+
+// Normalize a Xendit status
+func parseXenditStatus(status string) midtrans.PaymentStatus {
+	switch strings.ToUpper(status) {
+	case xendit.StatusPending, xendit.StatusRequiresAction, xendit.StatusAcceptingPayments, xendit.StatusActive:
+		return midtrans.PaymentStatusPending
+	case xendit.StatusSucceeded, xendit.StatusPaid:
+		return midtrans.PaymentStatusSettled
+	case xendit.StatusExpired, xendit.StatusInactive:
+		return midtrans.PaymentStatusExpired
+	case xendit.StatusCanceled, xendit.StatusVoided:
+		return midtrans.PaymentStatusCanceled
+	case xendit.StatusRefunded:
+		return midtrans.PaymentStatusRefunded
+	case xendit.StatusPartiallyRefunded:
+		return midtrans.PaymentStatusPartiallyRefunded
+	default:
+		return midtrans.PaymentStatusFailed
+	}
+}
+
+// Format a local phone number in the E.164 format required by Xendit e-wallets
+func formatMobileNumber(phone string) string {
+	phone = strings.NewReplacer(" ", "", "-", "").Replace(phone)
+
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		return phone
+	case strings.HasPrefix(phone, "0"):
+		return "+62" + strings.TrimPrefix(phone, "0")
+	default:
+		return "+" + phone
+	}
+}
+
+// Convert a Xendit error into a gateway error
+func fromXenditError(err error) error {
+	var apiError *xendit.Error
+	if errors.As(err, &apiError) {
+		return &Error{StatusCode: apiError.StatusCode, Message: apiError.Message}
+	}
+
+	return &Error{StatusCode: http.StatusBadGateway, Message: err.Error()}
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/integrations/xendit"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+)
+
+func TestXenditGateway(t *testing.T) {
+	var received xendit.CreatePaymentRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/payment_requests":
+			json.NewDecoder(r.Body).Decode(&received)
+
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":           "pr-123",
+				"reference_id": received.ReferenceID,
+				"amount":       received.Amount,
+				"currency":     "IDR",
+				"status":       "PENDING",
+				"payment_method": map[string]any{
+					"id":   "pm-123",
+					"type": "VIRTUAL_ACCOUNT",
+					"virtual_account": map[string]any{
+						"channel_code": "BSI",
+						"channel_properties": map[string]any{
+							"virtual_account_number": "9999000001",
+						},
+					},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error_code": "DATA_NOT_FOUND",
+				"message":    "Payment request not found",
+			})
+		}
+	}))
+	defer server.Close()
+
+	gateway := NewXenditGateway(xendit.NewXenditClientWithBaseURL("xnd_development_test", "callback-token", server.URL))
+
+	t.Run("Should charge a virtual account and return its number", func(t *testing.T) {
+		result, err := gateway.Charge(&ChargeRequest{
+			OrderID:  "order-1",
+			Customer: &models.User{Name: "Senkou", Email: "senkou@example.com", Phone: "08123456789"},
+			Method:   midtrans.PaymentMethodConfig{PaymentType: "bank_transfer", Channel: "bsi", Provider: "xendit"},
+			Items:    []dtos.PaymentItemDTO{{ID: "subscription", Name: "Pro", Price: money.FromMajor(10000, money.IDR), Quantity: 1}, {ID: "tax", Name: "PPN 11%", Price: money.FromMajor(1100, money.IDR), Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if received.Amount != 11100 || received.PaymentMethod.VirtualAccount.ChannelCode != "BSI" {
+			t.Errorf("Expected a BSI payment request of 11100, got %+v", received)
+		}
+
+		if result.TransactionID != "pr-123" || result.Status != midtrans.PaymentStatusPending {
+			t.Errorf("Expected pending payment request pr-123, got %+v", result)
+		}
+
+		if result.Instruction.VANumber != "9999000001" {
+			t.Errorf("Expected VA number 9999000001, got %s", result.Instruction.VANumber)
+		}
+	})
+
+	t.Run("Should report unknown payment requests as not found", func(t *testing.T) {
+		_, err := gateway.Status(Reference{OrderID: "order-2", TransactionID: "pr-unknown"})
+		if !IsNotFound(err) {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("Should verify the callback token", func(t *testing.T) {
+		if err := gateway.VerifyNotification(&NotificationRequest{Headers: map[string]string{"X-Callback-Token": "callback-token"}}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if err := gateway.VerifyNotification(&NotificationRequest{Headers: map[string]string{"X-Callback-Token": "forged"}}); err == nil {
+			t.Error("Expected error for a forged callback token")
+		}
+	})
+
+	t.Run("Should parse a succeeded payment callback", func(t *testing.T) {
+		update, err := gateway.ParseNotification(&NotificationRequest{Body: []byte(`{
+			"event": "payment.succeeded",
+			"data": {
+				"payment_request_id": "pr-123",
+				"reference_id": "order-1",
+				"status": "SUCCEEDED",
+				"amount": 11100,
+				"currency": "IDR",
+				"updated": "2025-09-21T10:00:00Z"
+			}
+		}`)})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if update.OrderID != "order-1" || update.Status != midtrans.PaymentStatusSettled || !update.GrossAmount.Equal(money.FromMajor(11100, money.IDR)) {
+			t.Errorf("Expected settled payment of order-1, got %+v", update)
+		}
+
+		if update.SettlementTime == nil {
+			t.Error("Expected settlement time to be parsed")
+		}
+	})
+}
+
+func TestFormatMobileNumber(t *testing.T) {
+	t.Run("Should format a local number", func(t *testing.T) {
+		if number := formatMobileNumber("0812-3456-789"); number != "+628123456789" {
+			t.Errorf("Expected +628123456789, got %s", number)
+		}
+	})
+
+	t.Run("Should keep an international number", func(t *testing.T) {
+		if number := formatMobileNumber("+628123456789"); number != "+628123456789" {
+			t.Errorf("Expected +628123456789, got %s", number)
+		}
+	})
+}
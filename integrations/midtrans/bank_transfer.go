@@ -1,6 +1,12 @@
 package midtrans
 
-import "github.com/midtrans/midtrans-go/coreapi"
+import (
+	"errors"
+	"strings"
+
+	mt "github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+)
 
 type BankChannel string
 
@@ -12,13 +18,56 @@ const (
 	BankChannelPermata BankChannel = "permata"
 )
 
-func setBankTransferParams(chargeReq *coreapi.ChargeReq, channel BankChannel) error {
+// Permata only accepts an uppercase recipient name of at most 20 characters
+const permataRecipientNameMaxLength = 20
+
+// Set the bank transfer parameters of a charge request
+// Mandiri does not use bank_transfer but its bill payment (echannel) product
+// The VA number is optional, when empty Midtrans generates one for the transaction
+func setBankTransferParams(chargeReq *coreapi.ChargeReq, channel BankChannel, vaNumber string, recipientName string) error {
 	switch channel {
 	case BankChannelBCA:
 		chargeReq.BankTransfer = &coreapi.BankTransferDetails{
-			Bank: "bca",
+			Bank:     mt.BankBca,
+			VaNumber: vaNumber,
+		}
+	case BankChannelBNI:
+		chargeReq.BankTransfer = &coreapi.BankTransferDetails{
+			Bank:     mt.BankBni,
+			VaNumber: vaNumber,
+		}
+	case BankChannelBRI:
+		chargeReq.BankTransfer = &coreapi.BankTransferDetails{
+			Bank:     mt.BankBri,
+			VaNumber: vaNumber,
 		}
+	case BankChannelPermata:
+		chargeReq.BankTransfer = &coreapi.BankTransferDetails{
+			Bank:     mt.BankPermata,
+			VaNumber: vaNumber,
+			Permata: &coreapi.PermataBankTransferDetail{
+				RecipientName: formatPermataRecipientName(recipientName),
+			},
+		}
+	case BankChannelMandiri:
+		chargeReq.PaymentType = coreapi.PaymentTypeEChannel
+		chargeReq.EChannel = &coreapi.EChannelDetail{
+			BillInfo1: "Payment:",
+			BillInfo2: "Senkou Catalyst",
+			BillKey:   vaNumber,
+		}
+	default:
+		return errors.New("unsupported bank channel: " + string(channel))
 	}
 
 	return nil
 }
+
+func formatPermataRecipientName(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if len(name) > permataRecipientNameMaxLength {
+		name = name[:permataRecipientNameMaxLength]
+	}
+
+	return name
+}
@@ -3,13 +3,20 @@ package midtrans
 import (
 	"errors"
 	"fmt"
+	"senkou-catalyst-be/app/dtos"
 	"senkou-catalyst-be/app/models"
 	"senkou-catalyst-be/utils/config"
+	"senkou-catalyst-be/utils/money"
 
 	mt "github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
 )
 
+// Extra options of a charge request that depend on the customer input
+type ChargeOptions struct {
+	VANumber string
+}
+
 type PaymentBuilder struct {
 	client *MidtransClient
 }
@@ -20,8 +27,43 @@ func NewPaymentBuilder(client *MidtransClient) *PaymentBuilder {
 	}
 }
 
-func (b *PaymentBuilder) BuildChargeRequest(user *models.User, pm PaymentMethodConfig, amount float64, orderID string) (*coreapi.ChargeReq, error) {
-	grossAmount := int64(amount)
+// Build a charge request
+// The gross amount is the sum of the item details so the receipt always matches the charge
+// Midtrans only accepts whole rupiah, prices are never rounded here so the charge cannot differ from the order
+// It returns an error if the items are empty, a price is not in whole rupiah or the payment method is not supported
+func (b *PaymentBuilder) BuildChargeRequest(user *models.User, pm PaymentMethodConfig, items []dtos.PaymentItemDTO, orderID string, options *ChargeOptions) (*coreapi.ChargeReq, error) {
+	if options == nil {
+		options = &ChargeOptions{}
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("at least one item is required")
+	}
+
+	itemDetails := make([]mt.ItemDetails, 0, len(items))
+	var grossAmount int64
+
+	for _, item := range items {
+		if item.Price.CurrencyCode() != money.IDR {
+			return nil, fmt.Errorf("item %s is priced in %s, only IDR is supported", item.ID, item.Price.CurrencyCode())
+		}
+
+		if !item.Price.IsWholeMajor() {
+			return nil, fmt.Errorf("item %s is priced at %s, only whole rupiah are supported", item.ID, item.Price.Decimal())
+		}
+
+		price := item.Price.Major()
+		quantity := int32(item.Quantity)
+
+		itemDetails = append(itemDetails, mt.ItemDetails{
+			ID:    item.ID,
+			Name:  item.Name,
+			Price: price,
+			Qty:   quantity,
+		})
+
+		grossAmount += price * int64(quantity)
+	}
 
 	chargeReq := &coreapi.ChargeReq{
 		PaymentType: b.getPaymentType(pm.PaymentType),
@@ -29,6 +71,7 @@ func (b *PaymentBuilder) BuildChargeRequest(user *models.User, pm PaymentMethodC
 			OrderID:  orderID,
 			GrossAmt: grossAmount,
 		},
+		Items: &itemDetails,
 		CustomerDetails: &mt.CustomerDetails{
 			FName: user.Name,
 			Email: user.Email,
@@ -40,7 +83,14 @@ func (b *PaymentBuilder) BuildChargeRequest(user *models.User, pm PaymentMethodC
 		chargeReq.CustomField1 = &notificationUrl
 	}
 
-	if err := b.setPaymentMethodParams(chargeReq, &pm); err != nil {
+	if pm.ExpiryDuration > 0 && pm.ExpiryUnit != "" {
+		chargeReq.CustomExpiry = &coreapi.CustomExpiry{
+			ExpiryDuration: pm.ExpiryDuration,
+			Unit:           pm.ExpiryUnit,
+		}
+	}
+
+	if err := b.setPaymentMethodParams(chargeReq, &pm, user, options); err != nil {
 		return nil, fmt.Errorf("failed to set payment method params: %w", err)
 	}
 
@@ -66,10 +116,10 @@ func (b *PaymentBuilder) getPaymentType(paymentType string) coreapi.CoreapiPayme
 	return ""
 }
 
-func (b *PaymentBuilder) setPaymentMethodParams(chargeReq *coreapi.ChargeReq, pm *PaymentMethodConfig) error {
+func (b *PaymentBuilder) setPaymentMethodParams(chargeReq *coreapi.ChargeReq, pm *PaymentMethodConfig, user *models.User, options *ChargeOptions) error {
 	switch pm.PaymentType {
 	case "bank_transfer":
-		return setBankTransferParams(chargeReq, BankChannel(pm.Channel))
+		return setBankTransferParams(chargeReq, BankChannel(pm.Channel), options.VANumber, user.Name)
 	case "e_wallet_dana":
 		return setDanaParams(chargeReq)
 	case "e_wallet_gopay":
@@ -85,5 +135,5 @@ func (b *PaymentBuilder) setPaymentMethodParams(chargeReq *coreapi.ChargeReq, pm
 
 func (b *PaymentBuilder) getNotificationURL() string {
 	baseURL := config.GetEnv("APP_URL", "http://localhost:8080")
-	return fmt.Sprintf("%s/api/v1/payments/notifications", baseURL)
+	return fmt.Sprintf("%s/api/v1/payments/notifications/midtrans", baseURL)
 }
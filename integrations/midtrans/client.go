@@ -1,7 +1,9 @@
 package midtrans
 
 import (
+	"io"
 	"senkou-catalyst-be/utils/config"
+	"strings"
 
 	m "github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
@@ -16,6 +18,7 @@ type MidtransClient struct {
 func NewMidtransClient() *MidtransClient {
 	serverKey := config.MustGetEnv("MIDTRANS_SERVER_KEY")
 	env := config.GetEnv("MIDTRANS_ENVIRONMENT", "sandbox")
+	baseURL := config.GetEnv("MIDTRANS_BASE_URL", "")
 
 	var environment m.EnvironmentType
 
@@ -25,9 +28,24 @@ func NewMidtransClient() *MidtransClient {
 		environment = m.Sandbox
 	}
 
+	return NewMidtransClientWithBaseURL(serverKey, environment, baseURL)
+}
+
+// Create a Midtrans client that talks to a custom base URL
+// This is used to point the client to a local emulator or a fake server in tests
+// An empty base URL keeps the default Midtrans URL of the environment
+func NewMidtransClientWithBaseURL(serverKey string, environment m.EnvironmentType, baseURL string) *MidtransClient {
 	coreAPIClient := new(coreapi.Client)
 	coreAPIClient.New(serverKey, environment)
 
+	if baseURL != "" {
+		coreAPIClient.HttpClient = &baseURLHttpClient{
+			next:           coreAPIClient.HttpClient,
+			defaultBaseURL: environment.BaseUrl(),
+			baseURL:        strings.TrimRight(baseURL, "/"),
+		}
+	}
+
 	return &MidtransClient{
 		CoreAPI:     *coreAPIClient,
 		ServerKey:   serverKey,
@@ -42,3 +60,18 @@ func (mc *MidtransClient) GetCoreAPIClient() *coreapi.Client {
 func (mc *MidtransClient) GetCurrentEnvironment() m.EnvironmentType {
 	return mc.Environment
 }
+
+// baseURLHttpClient rewrites the Midtrans base URL of every request before sending it
+type baseURLHttpClient struct {
+	next           m.HttpClient
+	defaultBaseURL string
+	baseURL        string
+}
+
+func (c *baseURLHttpClient) Call(method string, url string, apiKey *string, options *m.ConfigOptions, body io.Reader, result interface{}) *m.Error {
+	if strings.HasPrefix(url, c.defaultBaseURL) {
+		url = c.baseURL + strings.TrimPrefix(url, c.defaultBaseURL)
+	}
+
+	return c.next.Call(method, url, apiKey, options, body, result)
+}
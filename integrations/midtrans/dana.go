@@ -7,10 +7,5 @@ import (
 func setDanaParams(chargeReq *coreapi.ChargeReq) error {
 	chargeReq.PaymentType = "qris"
 
-	chargeReq.CustomExpiry = &coreapi.CustomExpiry{
-		Unit:           "minute",
-		ExpiryDuration: 15,
-	}
-
 	return nil
 }
@@ -0,0 +1,432 @@
+package emulator
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/midtrans/midtrans-go/coreapi"
+)
+
+// The company prefix the Midtrans sandbox puts in front of the VA numbers of every bank
+// and the total length of the resulting VA number
+var vaNumberFormats = map[string]struct {
+	Prefix string
+	Length int
+}{
+	"bca":     {Prefix: "12345", Length: 11},
+	"bni":     {Prefix: "9888", Length: 16},
+	"bri":     {Prefix: "88888", Length: 18},
+	"permata": {Prefix: "8778", Length: 16},
+	"cimb":    {Prefix: "1899", Length: 16},
+	"bsi":     {Prefix: "9347", Length: 16},
+}
+
+// The biller code of Mandiri bill payments on the Midtrans sandbox
+const mandiriBillerCode = "70012"
+
+// Default expiry of every payment type when the charge has no custom expiry
+var defaultExpiries = map[string]time.Duration{
+	"bank_transfer": 24 * time.Hour,
+	"echannel":      24 * time.Hour,
+	"qris":          15 * time.Minute,
+	"gopay":         15 * time.Minute,
+	"shopeepay":     5 * time.Minute,
+}
+
+var expiryUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// Create a pending transaction from a charge request
+// It returns an error with the Midtrans status code if the request would be rejected by Midtrans
+func (e *Emulator) charge(request *coreapi.ChargeReq, notificationURL string) (*Transaction, *apiError) {
+	orderID := request.TransactionDetails.OrderID
+	if orderID == "" {
+		return nil, &apiError{StatusCode: http.StatusBadRequest, Message: "transaction_details.order_id is required"}
+	}
+
+	if request.TransactionDetails.GrossAmt <= 0 {
+		return nil, &apiError{StatusCode: http.StatusBadRequest, Message: "transaction_details.gross_amount must be greater than 0"}
+	}
+
+	if request.Items != nil {
+		var itemsAmount int64
+		for _, item := range *request.Items {
+			itemsAmount += item.Price * int64(item.Qty)
+		}
+
+		if itemsAmount != request.TransactionDetails.GrossAmt {
+			return nil, &apiError{StatusCode: http.StatusBadRequest, Message: "transaction_details.gross_amount is not equal to the sum of item_details"}
+		}
+	}
+
+	now := e.now()
+	transaction := &Transaction{
+		OrderID:         orderID,
+		TransactionID:   uuid.NewString(),
+		PaymentType:     string(request.PaymentType),
+		GrossAmount:     request.TransactionDetails.GrossAmt,
+		Currency:        "IDR",
+		Status:          StatusPending,
+		FraudStatus:     "accept",
+		NotificationURL: notificationURL,
+		TransactionTime: now,
+	}
+
+	switch request.PaymentType {
+	case coreapi.PaymentTypeBankTransfer:
+		if request.BankTransfer == nil {
+			return nil, &apiError{StatusCode: http.StatusBadRequest, Message: "bank_transfer.bank is required"}
+		}
+
+		bank := strings.ToLower(string(request.BankTransfer.Bank))
+		vaNumber, err := newVANumber(bank, request.BankTransfer.VaNumber)
+		if err != nil {
+			return nil, &apiError{StatusCode: http.StatusBadRequest, Message: err.Error()}
+		}
+
+		transaction.Bank = bank
+		transaction.VANumber = vaNumber
+	case coreapi.PaymentTypeEChannel:
+		billKey := ""
+		if request.EChannel != nil {
+			billKey = request.EChannel.BillKey
+		}
+		if billKey == "" {
+			billKey = randomDigits(12)
+		}
+
+		transaction.BillerCode = mandiriBillerCode
+		transaction.BillKey = billKey
+	case coreapi.PaymentTypeQris, coreapi.PaymentTypeGopay:
+		transaction.QRString = newQRString(transaction.TransactionID, transaction.GrossAmount)
+	case coreapi.PaymentTypeShopeepay:
+	default:
+		return nil, &apiError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("payment_type %s is not supported by the emulator", request.PaymentType)}
+	}
+
+	transaction.ExpiryTime = now.Add(chargeExpiry(request))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.transactions[orderID]; exists {
+		return nil, &apiError{
+			StatusCode: http.StatusNotAcceptable,
+			Message:    "The request could not be completed due to a conflict with the current state of the target resource, please try again",
+		}
+	}
+
+	e.transactions[orderID] = transaction
+
+	copied := *transaction
+	return &copied, nil
+}
+
+// Refund a settled transaction
+// Retrying with the same refund key returns the first refund without refunding twice
+func (e *Emulator) refund(orderID string, request *coreapi.RefundReq) (*coreapi.RefundResponse, *apiError) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	transaction, ok := e.transactions[orderID]
+	if !ok {
+		return nil, errTransactionNotFound
+	}
+
+	if transaction.Status != StatusSettlement && transaction.Status != StatusPartialRefund {
+		return nil, &apiError{StatusCode: http.StatusPreconditionFailed, Message: "Transaction status cannot be updated"}
+	}
+
+	var refunded int64
+	for index, refund := range transaction.Refunds {
+		if request.RefundKey != "" && refund.RefundKey == request.RefundKey {
+			return refundResponse(transaction, index), nil
+		}
+
+		refunded += refund.Amount
+	}
+
+	amount := request.Amount
+	if amount == 0 {
+		amount = transaction.GrossAmount - refunded
+	}
+
+	if amount <= 0 || refunded+amount > transaction.GrossAmount {
+		return nil, &apiError{StatusCode: http.StatusPreconditionFailed, Message: "Refund amount exceeds the remaining amount of the transaction"}
+	}
+
+	refundKey := request.RefundKey
+	if refundKey == "" {
+		refundKey = uuid.NewString()
+	}
+
+	transaction.Refunds = append(transaction.Refunds, RefundItem{
+		RefundKey: refundKey,
+		Amount:    amount,
+		Reason:    request.Reason,
+		CreatedAt: e.now(),
+	})
+
+	transaction.Status = StatusPartialRefund
+	if refunded+amount == transaction.GrossAmount {
+		transaction.Status = StatusRefund
+	}
+
+	return refundResponse(transaction, len(transaction.Refunds)-1), nil
+}
+
+// Build the response of a charge, cancel or expire request
+func (e *Emulator) chargeResponse(transaction *Transaction, baseURL string) *coreapi.ChargeResponse {
+	response := &coreapi.ChargeResponse{
+		StatusCode:        statusCode(transaction.Status),
+		StatusMessage:     statusMessage(transaction),
+		TransactionID:     transaction.TransactionID,
+		OrderID:           transaction.OrderID,
+		GrossAmount:       formatAmount(transaction.GrossAmount),
+		Currency:          transaction.Currency,
+		PaymentType:       transaction.PaymentType,
+		TransactionTime:   formatTime(transaction.TransactionTime),
+		TransactionStatus: transaction.Status,
+		FraudStatus:       transaction.FraudStatus,
+		ExpiryTime:        formatTime(transaction.ExpiryTime),
+		BillerCode:        transaction.BillerCode,
+		BillKey:           transaction.BillKey,
+		QRString:          transaction.QRString,
+	}
+
+	switch {
+	case transaction.Bank == "permata":
+		response.PermataVaNumber = transaction.VANumber
+	case transaction.VANumber != "":
+		response.VaNumbers = []coreapi.VANumber{{Bank: transaction.Bank, VANumber: transaction.VANumber}}
+	}
+
+	switch transaction.PaymentType {
+	case string(coreapi.PaymentTypeQris):
+		response.Acquirer = "gopay"
+		response.Actions = []coreapi.Action{
+			{Name: "generate-qr-code", Method: http.MethodGet, URL: qrCodeURL(baseURL, transaction)},
+		}
+	case string(coreapi.PaymentTypeGopay):
+		response.Actions = []coreapi.Action{
+			{Name: "generate-qr-code", Method: http.MethodGet, URL: qrCodeURL(baseURL, transaction)},
+			{Name: "deeplink-redirect", Method: http.MethodGet, URL: "gojek://gopay/merchanttransfer?tref=" + transaction.TransactionID + "&amount=" + fmt.Sprint(transaction.GrossAmount)},
+		}
+	case string(coreapi.PaymentTypeShopeepay):
+		response.Actions = []coreapi.Action{
+			{Name: "deeplink-redirect", Method: http.MethodGet, URL: "https://wsa.uat.wallet.airpay.co.id/universal-link/wallet/pay?reference=" + transaction.TransactionID},
+		}
+	}
+
+	return response
+}
+
+// Build the response of a status request
+func (e *Emulator) statusResponse(transaction *Transaction) *coreapi.TransactionStatusResponse {
+	response := &coreapi.TransactionStatusResponse{
+		StatusCode:        statusCode(transaction.Status),
+		StatusMessage:     "Success, transaction is found",
+		TransactionID:     transaction.TransactionID,
+		OrderID:           transaction.OrderID,
+		GrossAmount:       formatAmount(transaction.GrossAmount),
+		Currency:          transaction.Currency,
+		PaymentType:       transaction.PaymentType,
+		TransactionTime:   formatTime(transaction.TransactionTime),
+		TransactionStatus: transaction.Status,
+		FraudStatus:       transaction.FraudStatus,
+		ExpiryTime:        formatTime(transaction.ExpiryTime),
+		BillerCode:        transaction.BillerCode,
+		BillKey:           transaction.BillKey,
+		MerchantID:        "G000000000",
+	}
+
+	response.SignatureKey = signature(transaction, response.StatusCode, e.ServerKey)
+
+	if transaction.SettlementTime != nil {
+		response.SettlementTime = formatTime(*transaction.SettlementTime)
+	}
+
+	switch {
+	case transaction.Bank == "permata":
+		response.PermataVaNumber = transaction.VANumber
+	case transaction.VANumber != "":
+		response.VaNumbers = []coreapi.VANumber{{Bank: transaction.Bank, VANumber: transaction.VANumber}}
+	}
+
+	var refunded int64
+	for _, refund := range transaction.Refunds {
+		refunded += refund.Amount
+		response.Refunds = append(response.Refunds, coreapi.RefundDetails{
+			RefundKey:    refund.RefundKey,
+			RefundAmount: formatAmount(refund.Amount),
+			Reason:       refund.Reason,
+			CreatedAt:    formatTime(refund.CreatedAt),
+		})
+	}
+
+	if refunded > 0 {
+		response.RefundAmount = formatAmount(refunded)
+	}
+
+	return response
+}
+
+func refundResponse(transaction *Transaction, index int) *coreapi.RefundResponse {
+	refund := transaction.Refunds[index]
+
+	return &coreapi.RefundResponse{
+		StatusCode:           "200",
+		StatusMessage:        "Success, refund request is approved",
+		TransactionID:        transaction.TransactionID,
+		OrderID:              transaction.OrderID,
+		GrossAmount:          formatAmount(transaction.GrossAmount),
+		Currency:             transaction.Currency,
+		PaymentType:          transaction.PaymentType,
+		TransactionTime:      formatTime(transaction.TransactionTime),
+		TransactionStatus:    transaction.Status,
+		FraudStatus:          transaction.FraudStatus,
+		MerchantID:           "G000000000",
+		RefundChargebackID:   index + 1,
+		RefundChargebackUUID: uuid.NewSHA1(uuid.NameSpaceOID, []byte(transaction.OrderID+refund.RefundKey)).String(),
+		RefundAmount:         formatAmount(refund.Amount),
+		RefundKey:            refund.RefundKey,
+	}
+}
+
+// Get the Midtrans status code of a transaction status
+func statusCode(status string) string {
+	switch status {
+	case StatusPending:
+		return "201"
+	case StatusDeny:
+		return "202"
+	case StatusExpire:
+		return "407"
+	default:
+		return "200"
+	}
+}
+
+func statusMessage(transaction *Transaction) string {
+	switch transaction.Status {
+	case StatusPending:
+		return "Success, transaction is created"
+	case StatusCancel:
+		return "Success, transaction is canceled"
+	case StatusExpire:
+		return "Success, transaction is expired"
+	default:
+		return "Success, transaction is found"
+	}
+}
+
+// Compute how long a charge stays payable
+func chargeExpiry(request *coreapi.ChargeReq) time.Duration {
+	if request.CustomExpiry != nil && request.CustomExpiry.ExpiryDuration > 0 {
+		if unit, ok := expiryUnits[request.CustomExpiry.Unit]; ok {
+			return time.Duration(request.CustomExpiry.ExpiryDuration) * unit
+		}
+	}
+
+	if expiry, ok := defaultExpiries[string(request.PaymentType)]; ok {
+		return expiry
+	}
+
+	return 24 * time.Hour
+}
+
+// Generate the VA number of a bank transfer
+// A custom VA number is kept as is, otherwise a random number in the sandbox format of the bank is generated
+func newVANumber(bank, customVANumber string) (string, error) {
+	if customVANumber != "" {
+		for _, r := range customVANumber {
+			if r < '0' || r > '9' {
+				return "", fmt.Errorf("bank_transfer.va_number must only contain digits")
+			}
+		}
+
+		return customVANumber, nil
+	}
+
+	format, ok := vaNumberFormats[bank]
+	if !ok {
+		return "", fmt.Errorf("bank_transfer.bank %s is not supported", bank)
+	}
+
+	return format.Prefix + randomDigits(format.Length-len(format.Prefix)), nil
+}
+
+// Build a dynamic QRIS payload following the EMVCo merchant presented mode
+// The payload ends with the CRC16 checksum so QR readers accept it
+func newQRString(transactionID string, amount int64) string {
+	reference := strings.ReplaceAll(transactionID, "-", "")
+	if len(reference) > 25 {
+		reference = reference[:25]
+	}
+
+	payload := emvField("00", "01") +
+		emvField("01", "12") +
+		emvField("26", emvField("00", "COM.GO-JEK.WWW")+emvField("01", "936009140000000000")+emvField("02", "G000000000")+emvField("03", "UMI")) +
+		emvField("51", emvField("00", "ID.CO.QRIS.WWW")+emvField("02", "ID1020000000000")+emvField("03", "UMI")) +
+		emvField("52", "5815") +
+		emvField("53", "360") +
+		emvField("54", fmt.Sprint(amount)) +
+		emvField("58", "ID") +
+		emvField("59", "SENKOU CATALYST") +
+		emvField("60", "JAKARTA") +
+		emvField("62", emvField("05", reference)) +
+		"6304"
+
+	return payload + fmt.Sprintf("%04X", crc16CCITT([]byte(payload)))
+}
+
+// Encode an EMVCo field as its ID, two digit length and value
+func emvField(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// Compute the CRC16/CCITT-FALSE checksum used by QRIS
+func crc16CCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+func qrCodeURL(baseURL string, transaction *Transaction) string {
+	return fmt.Sprintf("%s/v2/qris/%s/qr-code", baseURL, transaction.TransactionID)
+}
+
+// Generate a random number of the given length
+func randomDigits(length int) string {
+	digits := make([]byte, length)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			n = big.NewInt(int64(i % 10))
+		}
+
+		digits[i] = byte('0' + n.Int64())
+	}
+
+	return string(digits)
+}
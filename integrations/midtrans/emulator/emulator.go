@@ -0,0 +1,393 @@
+package emulator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/midtrans/midtrans-go/coreapi"
+)
+
+// The server key used when none is configured
+const DefaultServerKey = "SB-Mid-server-emulator"
+
+// Raw Midtrans transaction statuses
+const (
+	StatusPending       = "pending"
+	StatusSettlement    = "settlement"
+	StatusDeny          = "deny"
+	StatusExpire        = "expire"
+	StatusCancel        = "cancel"
+	StatusRefund        = "refund"
+	StatusPartialRefund = "partial_refund"
+)
+
+// Midtrans prints every time in WIB without a zone
+const midtransTimeLayout = "2006-01-02 15:04:05"
+
+var jakartaLocation = loadJakartaLocation()
+
+// An in memory emulator of the Midtrans Core API
+// It serves the charge, status, cancel, expire and refund endpoints with the same payloads and
+// status codes as the Midtrans sandbox, and fires signed notifications when a transaction changes
+// Requests must authenticate with the server key the same way they do on Midtrans
+type Emulator struct {
+	ServerKey       string
+	NotificationURL string
+	HTTPClient      *http.Client
+
+	mu           sync.Mutex
+	transactions map[string]*Transaction
+	now          func() time.Time
+}
+
+// A transaction as stored by the emulator
+type Transaction struct {
+	OrderID         string       `json:"order_id"`
+	TransactionID   string       `json:"transaction_id"`
+	PaymentType     string       `json:"payment_type"`
+	Bank            string       `json:"bank,omitempty"`
+	GrossAmount     int64        `json:"gross_amount"`
+	Currency        string       `json:"currency"`
+	Status          string       `json:"transaction_status"`
+	FraudStatus     string       `json:"fraud_status"`
+	VANumber        string       `json:"va_number,omitempty"`
+	BillerCode      string       `json:"biller_code,omitempty"`
+	BillKey         string       `json:"bill_key,omitempty"`
+	QRString        string       `json:"qr_string,omitempty"`
+	NotificationURL string       `json:"notification_url,omitempty"`
+	TransactionTime time.Time    `json:"transaction_time"`
+	SettlementTime  *time.Time   `json:"settlement_time,omitempty"`
+	ExpiryTime      time.Time    `json:"expiry_time"`
+	Refunds         []RefundItem `json:"refunds,omitempty"`
+}
+
+type RefundItem struct {
+	RefundKey string    `json:"refund_key"`
+	Amount    int64     `json:"refund_amount"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func New(serverKey string) *Emulator {
+	if serverKey == "" {
+		serverKey = DefaultServerKey
+	}
+
+	return &Emulator{
+		ServerKey:    serverKey,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+		transactions: make(map[string]*Transaction),
+		now:          time.Now,
+	}
+}
+
+// Get the HTTP handler of the emulator
+// The Core API lives under /v2 like on Midtrans, the emulator endpoints used to drive
+// transactions from a CLI or a test live under /emulator
+func (e *Emulator) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v2/charge", e.authenticated(e.handleCharge))
+	mux.HandleFunc("GET /v2/{orderID}/status", e.authenticated(e.handleStatus))
+	mux.HandleFunc("POST /v2/{orderID}/cancel", e.authenticated(e.handleCancel))
+	mux.HandleFunc("POST /v2/{orderID}/expire", e.authenticated(e.handleExpire))
+	mux.HandleFunc("POST /v2/{orderID}/refund", e.authenticated(e.handleRefund))
+	mux.HandleFunc("POST /v2/{orderID}/refund/online/direct", e.authenticated(e.handleRefund))
+	mux.HandleFunc("GET /v2/qris/{transactionID}/qr-code", e.handleQRCode)
+
+	mux.HandleFunc("GET /emulator/transactions", e.authenticated(e.handleListTransactions))
+	mux.HandleFunc("POST /emulator/transactions/{orderID}/{action}", e.authenticated(e.handleTrigger))
+
+	return mux
+}
+
+// Get a copy of a stored transaction
+func (e *Emulator) Transaction(orderID string) (*Transaction, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	transaction, ok := e.transactions[orderID]
+	if !ok {
+		return nil, false
+	}
+
+	copied := *transaction
+	copied.Refunds = append([]RefundItem(nil), transaction.Refunds...)
+
+	return &copied, true
+}
+
+// Get a copy of every stored transaction
+func (e *Emulator) Transactions() []Transaction {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	transactions := make([]Transaction, 0, len(e.transactions))
+	for _, transaction := range e.transactions {
+		transactions = append(transactions, *transaction)
+	}
+
+	return transactions
+}
+
+// Settle a pending transaction as if the customer paid it, then notify the application
+func (e *Emulator) Settle(orderID string) (*http.Response, error) {
+	return e.Trigger(orderID, StatusSettlement)
+}
+
+// Expire a pending transaction, then notify the application
+func (e *Emulator) Expire(orderID string) (*http.Response, error) {
+	return e.Trigger(orderID, StatusExpire)
+}
+
+// Deny a pending transaction as if the fraud detection rejected it, then notify the application
+func (e *Emulator) Deny(orderID string) (*http.Response, error) {
+	return e.Trigger(orderID, StatusDeny)
+}
+
+// Move a transaction to a new status and send the signed notification of the change
+// Only pending transactions can be settled, expired, denied or canceled, like on Midtrans
+// It returns the response of the application to the notification
+func (e *Emulator) Trigger(orderID string, status string) (*http.Response, error) {
+	if _, err := e.transition(orderID, status); err != nil {
+		return nil, err
+	}
+
+	return e.Notify(orderID)
+}
+
+// Change the status of a pending transaction
+func (e *Emulator) transition(orderID string, status string) (*Transaction, *apiError) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	transaction, ok := e.transactions[orderID]
+	if !ok {
+		return nil, errTransactionNotFound
+	}
+
+	switch status {
+	case StatusSettlement, StatusExpire, StatusDeny, StatusCancel:
+	default:
+		return nil, &apiError{StatusCode: http.StatusBadRequest, Message: "Unsupported transaction status " + status}
+	}
+
+	if transaction.Status != StatusPending {
+		return nil, &apiError{StatusCode: http.StatusPreconditionFailed, Message: "Transaction status cannot be updated"}
+	}
+
+	transaction.Status = status
+
+	switch status {
+	case StatusSettlement:
+		settledAt := e.now()
+		transaction.SettlementTime = &settledAt
+	case StatusDeny:
+		transaction.FraudStatus = "deny"
+	}
+
+	copied := *transaction
+	return &copied, nil
+}
+
+// Wrap a handler so it only serves requests authenticated with the server key
+// Midtrans expects the server key as the basic auth username with an empty password
+func (e *Emulator) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(e.ServerKey+":"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != expected {
+			writeError(w, &apiError{
+				StatusCode: http.StatusUnauthorized,
+				Message:    "Access denied due to unauthorized transaction, please check client or server key",
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (e *Emulator) handleCharge(w http.ResponseWriter, r *http.Request) {
+	request := new(coreapi.ChargeReq)
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		writeError(w, &apiError{StatusCode: http.StatusBadRequest, Message: "Invalid JSON body: " + err.Error()})
+		return
+	}
+
+	// Midtrans lets a charge override the notification URL configured on the dashboard
+	transaction, apiErr := e.charge(request, r.Header.Get("X-Override-Notification"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	writeJSON(w, e.chargeResponse(transaction, baseURL(r)))
+}
+
+func (e *Emulator) handleStatus(w http.ResponseWriter, r *http.Request) {
+	transaction, ok := e.Transaction(r.PathValue("orderID"))
+	if !ok {
+		writeError(w, errTransactionNotFound)
+		return
+	}
+
+	writeJSON(w, e.statusResponse(transaction))
+}
+
+func (e *Emulator) handleCancel(w http.ResponseWriter, r *http.Request) {
+	e.handleClose(w, r, StatusCancel)
+}
+
+func (e *Emulator) handleExpire(w http.ResponseWriter, r *http.Request) {
+	e.handleClose(w, r, StatusExpire)
+}
+
+// Cancel or expire a pending transaction through the Core API
+// Midtrans notifies the application of the change just like any other status change
+func (e *Emulator) handleClose(w http.ResponseWriter, r *http.Request, status string) {
+	orderID := r.PathValue("orderID")
+
+	transaction, apiErr := e.transition(orderID, status)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	response := e.chargeResponse(transaction, baseURL(r))
+	go e.notifyInBackground(orderID)
+
+	writeJSON(w, response)
+}
+
+func (e *Emulator) handleRefund(w http.ResponseWriter, r *http.Request) {
+	request := new(coreapi.RefundReq)
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		writeError(w, &apiError{StatusCode: http.StatusBadRequest, Message: "Invalid JSON body: " + err.Error()})
+		return
+	}
+
+	orderID := r.PathValue("orderID")
+
+	response, apiErr := e.refund(orderID, request)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	go e.notifyInBackground(orderID)
+
+	writeJSON(w, response)
+}
+
+// Serve a placeholder image for the QR code URL returned by QRIS and GoPay charges
+// The QR string itself is a valid QRIS payload that can be rendered by any client
+func (e *Emulator) handleQRCode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="240" height="240"><rect width="240" height="240" fill="#fff" stroke="#000"/><text x="120" y="120" text-anchor="middle" font-size="12">QRIS %s</text></svg>`, r.PathValue("transactionID"))
+}
+
+func (e *Emulator) handleListTransactions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"status_code":  "200",
+		"transactions": e.Transactions(),
+	})
+}
+
+// Drive a transaction from outside, e.g. POST /emulator/transactions/{orderID}/settlement
+// The emulator answers with the response of the application to the notification
+func (e *Emulator) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	orderID := r.PathValue("orderID")
+	action := r.PathValue("action")
+
+	if action != "notify" {
+		if _, apiErr := e.transition(orderID, action); apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+	}
+
+	response, err := e.Notify(orderID)
+	if err != nil {
+		writeError(w, &apiError{StatusCode: http.StatusBadGateway, Message: err.Error()})
+		return
+	}
+	defer response.Body.Close()
+
+	transaction, _ := e.Transaction(orderID)
+
+	writeJSON(w, map[string]any{
+		"status_code":         "200",
+		"status_message":      "Notification sent",
+		"transaction_status":  transaction.Status,
+		"notification_status": response.StatusCode,
+		"notification_url":    e.notificationURL(transaction),
+	})
+}
+
+// An error answered by the emulator
+// Midtrans answers most errors with HTTP 200 and the actual code in status_code
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("midtrans emulator error %d: %s", e.StatusCode, e.Message)
+}
+
+var errTransactionNotFound = &apiError{
+	StatusCode: http.StatusNotFound,
+	Message:    "Transaction doesn't exist.",
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.StatusCode)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"status_code":    fmt.Sprintf("%d", err.StatusCode),
+		"status_message": err.Message,
+		"id":             "",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	json.NewEncoder(w).Encode(body)
+}
+
+// Get the public URL of the emulator from a request
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+// Format a time the way Midtrans does
+func formatTime(t time.Time) string {
+	return t.In(jakartaLocation).Format(midtransTimeLayout)
+}
+
+// Format an amount the way Midtrans does, e.g. "10000.00"
+func formatAmount(amount int64) string {
+	return fmt.Sprintf("%d.00", amount)
+}
+
+func loadJakartaLocation() *time.Location {
+	location, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.FixedZone("WIB", 7*60*60)
+	}
+
+	return location
+}
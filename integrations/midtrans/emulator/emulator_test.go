@@ -0,0 +1,264 @@
+package emulator
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"senkou-catalyst-be/integrations/gateway"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/utils/money"
+	"strconv"
+	"strings"
+	"testing"
+
+	m "github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+)
+
+func newTestEmulator(t *testing.T) (*Emulator, *coreapi.Client, *midtrans.MidtransClient) {
+	t.Helper()
+
+	e := New(DefaultServerKey)
+	server := httptest.NewServer(e.Handler())
+	t.Cleanup(server.Close)
+
+	client := midtrans.NewMidtransClientWithBaseURL(DefaultServerKey, m.Sandbox, server.URL)
+
+	return e, client.GetCoreAPIClient(), client
+}
+
+func newChargeRequest(orderID string, paymentType coreapi.CoreapiPaymentType, bank m.Bank) *coreapi.ChargeReq {
+	request := &coreapi.ChargeReq{
+		PaymentType: paymentType,
+		TransactionDetails: m.TransactionDetails{
+			OrderID:  orderID,
+			GrossAmt: 111000,
+		},
+		Items: &[]m.ItemDetails{
+			{ID: "subscription", Name: "Pro", Price: 100000, Qty: 1},
+			{ID: "tax", Name: "PPN 11%", Price: 11000, Qty: 1},
+		},
+	}
+
+	if paymentType == coreapi.PaymentTypeBankTransfer {
+		request.BankTransfer = &coreapi.BankTransferDetails{Bank: bank}
+	}
+
+	return request
+}
+
+func TestCharge(t *testing.T) {
+	_, client, _ := newTestEmulator(t)
+
+	t.Run("Should issue a BCA virtual account number", func(t *testing.T) {
+		response, err := client.ChargeTransaction(newChargeRequest("order-bca", coreapi.PaymentTypeBankTransfer, m.BankBca))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if response.TransactionStatus != StatusPending {
+			t.Errorf("Expected %s, got %s", StatusPending, response.TransactionStatus)
+		}
+
+		if len(response.VaNumbers) != 1 {
+			t.Fatalf("Expected 1, got %d", len(response.VaNumbers))
+		}
+
+		number := response.VaNumbers[0].VANumber
+		if !strings.HasPrefix(number, "12345") {
+			t.Errorf("Expected 12345, got %s", number[:min(len(number), 5)])
+		}
+
+		if len(number) != 11 {
+			t.Errorf("Expected 11, got %d", len(number))
+		}
+	})
+
+	t.Run("Should issue a QRIS string with a valid checksum", func(t *testing.T) {
+		response, err := client.ChargeTransaction(newChargeRequest("order-qris", coreapi.PaymentTypeQris, ""))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		qr := response.QRString
+		if !strings.HasPrefix(qr, "000201") || len(qr) < 8 {
+			t.Fatalf("Expected 000201, got %s", qr)
+		}
+
+		checksum := strings.ToUpper(strconv.FormatUint(uint64(crc16CCITT([]byte(qr[:len(qr)-4]))), 16))
+		if strings.Repeat("0", 4-len(checksum))+checksum != qr[len(qr)-4:] {
+			t.Errorf("Expected %v, got %v", checksum, qr[len(qr)-4:])
+		}
+	})
+
+	t.Run("Should reject an amount that does not match the items", func(t *testing.T) {
+		request := newChargeRequest("order-mismatch", coreapi.PaymentTypeQris, "")
+		request.TransactionDetails.GrossAmt = 1
+
+		if _, err := client.ChargeTransaction(request); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Should reject a duplicated order ID", func(t *testing.T) {
+		if _, err := client.ChargeTransaction(newChargeRequest("order-bca", coreapi.PaymentTypeBankTransfer, m.BankBca)); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+}
+
+func TestTransactionLifecycle(t *testing.T) {
+	e, client, _ := newTestEmulator(t)
+
+	t.Run("Should report the status of a pending transaction", func(t *testing.T) {
+		if _, err := client.ChargeTransaction(newChargeRequest("order-status", coreapi.PaymentTypeBankTransfer, m.BankBni)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		status, err := client.CheckTransaction("order-status")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if status.TransactionStatus != StatusPending {
+			t.Errorf("Expected %s, got %s", StatusPending, status.TransactionStatus)
+		}
+
+		if status.GrossAmount != "111000.00" {
+			t.Errorf("Expected 111000.00, got %s", status.GrossAmount)
+		}
+	})
+
+	t.Run("Should cancel a pending transaction only once", func(t *testing.T) {
+		if _, err := client.CancelTransaction("order-status"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if transaction, _ := e.Transaction("order-status"); transaction.Status != StatusCancel {
+			t.Errorf("Expected %v, got %v", StatusCancel, transaction.Status)
+		}
+
+		if _, err := client.CancelTransaction("order-status"); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Should refund a settled transaction once per refund key", func(t *testing.T) {
+		if _, err := client.ChargeTransaction(newChargeRequest("order-refund", coreapi.PaymentTypeQris, "")); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := e.transition("order-refund", StatusSettlement); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		request := &coreapi.RefundReq{RefundKey: "refund-1", Amount: 11000, Reason: "Tax refund"}
+		for i := 0; i < 2; i++ {
+			if _, err := client.RefundTransaction("order-refund", request); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		transaction, _ := e.Transaction("order-refund")
+		if transaction.Status != StatusPartialRefund {
+			t.Errorf("Expected %s, got %s", StatusPartialRefund, transaction.Status)
+		}
+
+		if len(transaction.Refunds) != 1 {
+			t.Errorf("Expected 1, got %d", len(transaction.Refunds))
+		}
+
+		if _, err := client.RefundTransaction("order-refund", &coreapi.RefundReq{RefundKey: "refund-2", Amount: 200000}); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Should return not found for an unknown order", func(t *testing.T) {
+		if _, err := client.CheckTransaction("order-unknown"); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+}
+
+func TestAuthentication(t *testing.T) {
+	e := New(DefaultServerKey)
+	server := httptest.NewServer(e.Handler())
+	defer server.Close()
+
+	t.Run("Should reject a request signed with another server key", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/v2/order-1/status", nil)
+		request.SetBasicAuth("SB-Mid-server-other", "")
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected %v, got %v", http.StatusUnauthorized, response.StatusCode)
+		}
+	})
+}
+
+func TestNotify(t *testing.T) {
+	e, client, midtransClient := newTestEmulator(t)
+	midtransGateway := gateway.NewMidtransGateway(midtransClient)
+
+	var received []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	e.NotificationURL = receiver.URL + DefaultNotificationPath
+
+	t.Run("Should send a settlement notification the gateway accepts", func(t *testing.T) {
+		if _, err := client.ChargeTransaction(newChargeRequest("order-settle", coreapi.PaymentTypeBankTransfer, m.BankPermata)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		response, err := e.Settle("order-settle")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		response.Body.Close()
+
+		notification := &gateway.NotificationRequest{Body: received}
+		if err := midtransGateway.VerifyNotification(notification); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		update, err := midtransGateway.ParseNotification(notification)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if update.OrderID != "order-settle" {
+			t.Errorf("Expected order-settle, got %s", update.OrderID)
+		}
+
+		if update.Status != midtrans.PaymentStatusSettled {
+			t.Errorf("Expected %s, got %s", midtrans.PaymentStatusSettled, update.Status)
+		}
+
+		if !update.GrossAmount.Equal(money.FromMajor(111000, money.IDR)) {
+			t.Errorf("Expected IDR 111000.00, got %s", update.GrossAmount)
+		}
+	})
+
+	t.Run("Should not settle a transaction twice", func(t *testing.T) {
+		if _, err := e.Settle("order-settle"); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+}
+
+func TestCRC16CCITT(t *testing.T) {
+	t.Run("Should match the CRC-16/CCITT-FALSE check value", func(t *testing.T) {
+		if result := crc16CCITT([]byte("123456789")); result != 0x29B1 {
+			t.Errorf("Expected %X, got %X", 0x29B1, result)
+		}
+	})
+}
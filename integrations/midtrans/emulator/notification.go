@@ -0,0 +1,135 @@
+package emulator
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// The notification route of the application, relative to its base URL
+const DefaultNotificationPath = "/api/v1/payments/notifications"
+
+// Send the notification of the current state of a transaction to the application
+// The notification is signed with the server key exactly like Midtrans signs it
+// It returns the response of the application, the caller must close its body
+func (e *Emulator) Notify(orderID string) (*http.Response, error) {
+	transaction, ok := e.Transaction(orderID)
+	if !ok {
+		return nil, errTransactionNotFound
+	}
+
+	url := e.notificationURL(transaction)
+	if url == "" {
+		return nil, fmt.Errorf("no notification URL configured for order %s", orderID)
+	}
+
+	body, err := json.Marshal(e.NotificationPayload(transaction))
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	return e.HTTPClient.Do(request)
+}
+
+// Build the signed notification body of a transaction
+func (e *Emulator) NotificationPayload(transaction *Transaction) map[string]any {
+	code := statusCode(transaction.Status)
+
+	payload := map[string]any{
+		"transaction_time":   formatTime(transaction.TransactionTime),
+		"transaction_status": transaction.Status,
+		"transaction_id":     transaction.TransactionID,
+		"status_message":     "midtrans payment notification",
+		"status_code":        code,
+		"signature_key":      signature(transaction, code, e.ServerKey),
+		"payment_type":       transaction.PaymentType,
+		"order_id":           transaction.OrderID,
+		"merchant_id":        "G000000000",
+		"gross_amount":       formatAmount(transaction.GrossAmount),
+		"fraud_status":       transaction.FraudStatus,
+		"expiry_time":        formatTime(transaction.ExpiryTime),
+		"currency":           transaction.Currency,
+	}
+
+	if transaction.SettlementTime != nil {
+		payload["settlement_time"] = formatTime(*transaction.SettlementTime)
+	}
+
+	switch {
+	case transaction.Bank == "permata":
+		payload["permata_va_number"] = transaction.VANumber
+	case transaction.VANumber != "":
+		payload["va_numbers"] = []map[string]string{
+			{"bank": transaction.Bank, "va_number": transaction.VANumber},
+		}
+	}
+
+	if transaction.BillKey != "" {
+		payload["biller_code"] = transaction.BillerCode
+		payload["bill_key"] = transaction.BillKey
+	}
+
+	if len(transaction.Refunds) > 0 {
+		var refunded int64
+		refunds := make([]map[string]any, 0, len(transaction.Refunds))
+
+		for index, refund := range transaction.Refunds {
+			refunded += refund.Amount
+			refunds = append(refunds, map[string]any{
+				"refund_chargeback_id": index + 1,
+				"refund_amount":        formatAmount(refund.Amount),
+				"refund_key":           refund.RefundKey,
+				"reason":               refund.Reason,
+				"created_at":           formatTime(refund.CreatedAt),
+			})
+		}
+
+		payload["refunds"] = refunds
+		payload["refund_amount"] = formatAmount(refunded)
+	}
+
+	return payload
+}
+
+// Send a notification without waiting for the caller, failures are only logged
+// Midtrans notifies asynchronously after cancel, expire and refund requests
+func (e *Emulator) notifyInBackground(orderID string) {
+	response, err := e.Notify(orderID)
+	if err != nil {
+		log.Printf("Failed to notify order %s: %v", orderID, err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		log.Printf("Notification of order %s was answered with status %d", orderID, response.StatusCode)
+	}
+}
+
+// Get where the notifications of a transaction are sent
+// A URL overridden by the charge request wins over the URL configured on the emulator
+func (e *Emulator) notificationURL(transaction *Transaction) string {
+	if transaction.NotificationURL != "" {
+		return transaction.NotificationURL
+	}
+
+	return e.NotificationURL
+}
+
+// Sign a transaction with SHA512(order_id + status_code + gross_amount + server_key)
+// This mirrors midtrans.GenerateSignatureKey, the emulator does not depend on the application
+// config so it can run without a .env file
+func signature(transaction *Transaction, statusCode string, serverKey string) string {
+	hash := sha512.Sum512([]byte(transaction.OrderID + statusCode + formatAmount(transaction.GrossAmount) + serverKey))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,85 @@
+package midtrans
+
+import (
+	"senkou-catalyst-be/utils/converter"
+	"time"
+
+	"github.com/midtrans/midtrans-go/coreapi"
+)
+
+// Action names returned by Midtrans for QR and e-wallet payments
+const (
+	actionGenerateQRCode   = "generate-qr-code"
+	actionDeeplinkRedirect = "deeplink-redirect"
+)
+
+// A provider agnostic description of how the customer completes a payment
+// Only the fields relevant to the chosen payment method are filled
+type PaymentInstruction struct {
+	OrderID       string     `json:"order_id"`
+	TransactionID string     `json:"transaction_id"`
+	PaymentType   string     `json:"payment_type"`
+	Channel       string     `json:"channel"`
+	Status        string     `json:"status"`
+	GrossAmount   string     `json:"gross_amount"`
+	Currency      string     `json:"currency"`
+	VANumber      string     `json:"va_number,omitempty"`
+	BillerCode    string     `json:"biller_code,omitempty"`
+	BillKey       string     `json:"bill_key,omitempty"`
+	PaymentCode   string     `json:"payment_code,omitempty"`
+	QRString      string     `json:"qr_string,omitempty"`
+	QRCodeURL     string     `json:"qr_code_url,omitempty"`
+	DeeplinkURL   string     `json:"deeplink_url,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+// Build a payment instruction from a Midtrans charge response
+// This function flattens the different response shapes of every payment method
+// It returns the normalized payment instruction
+func NewPaymentInstruction(pm PaymentMethodConfig, chargeResp *coreapi.ChargeResponse) *PaymentInstruction {
+	instruction := &PaymentInstruction{
+		PaymentType: pm.PaymentType,
+		Channel:     pm.Channel,
+	}
+
+	if chargeResp == nil {
+		return instruction
+	}
+
+	instruction.OrderID = chargeResp.OrderID
+	instruction.TransactionID = chargeResp.TransactionID
+	instruction.Status = string(ParseStatus(chargeResp.TransactionStatus))
+	instruction.GrossAmount = chargeResp.GrossAmount
+	instruction.Currency = chargeResp.Currency
+	instruction.BillerCode = chargeResp.BillerCode
+	instruction.BillKey = chargeResp.BillKey
+	instruction.PaymentCode = chargeResp.PaymentCode
+	instruction.QRString = chargeResp.QRString
+
+	if chargeResp.PermataVaNumber != "" {
+		instruction.VANumber = chargeResp.PermataVaNumber
+	}
+
+	for _, vaNumber := range chargeResp.VaNumbers {
+		if vaNumber.Bank == pm.Channel || instruction.VANumber == "" {
+			instruction.VANumber = vaNumber.VANumber
+		}
+	}
+
+	for _, action := range chargeResp.Actions {
+		switch action.Name {
+		case actionGenerateQRCode:
+			instruction.QRCodeURL = action.URL
+		case actionDeeplinkRedirect:
+			instruction.DeeplinkURL = action.URL
+		}
+	}
+
+	if chargeResp.ExpiryTime != "" {
+		if expiresAt, err := converter.ParseMidtransTime(chargeResp.ExpiryTime); err == nil {
+			instruction.ExpiresAt = &expiresAt
+		}
+	}
+
+	return instruction
+}
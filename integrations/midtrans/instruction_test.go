@@ -0,0 +1,120 @@
+package midtrans
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"testing"
+
+	"github.com/midtrans/midtrans-go/coreapi"
+)
+
+func TestNewPaymentInstruction(t *testing.T) {
+	t.Run("Should read the VA number of bank transfers", func(t *testing.T) {
+		instruction := NewPaymentInstruction(PaymentMethodConfig{PaymentType: "bank_transfer", Channel: "bni"}, &coreapi.ChargeResponse{
+			TransactionStatus: "pending",
+			VaNumbers:         []coreapi.VANumber{{Bank: "bni", VANumber: "9881234567"}},
+			ExpiryTime:        "2025-09-21 10:00:00",
+		})
+
+		if instruction.VANumber != "9881234567" {
+			t.Errorf("Expected VA number 9881234567, got %s", instruction.VANumber)
+		}
+
+		if instruction.Status != string(PaymentStatusPending) {
+			t.Errorf("Expected pending status, got %s", instruction.Status)
+		}
+
+		if instruction.ExpiresAt == nil {
+			t.Error("Expected expiry time to be parsed")
+		}
+	})
+
+	t.Run("Should read the Permata VA number", func(t *testing.T) {
+		instruction := NewPaymentInstruction(PaymentMethodConfig{PaymentType: "bank_transfer", Channel: "permata"}, &coreapi.ChargeResponse{
+			PermataVaNumber: "8562000000000001",
+		})
+
+		if instruction.VANumber != "8562000000000001" {
+			t.Errorf("Expected Permata VA number, got %s", instruction.VANumber)
+		}
+	})
+
+	t.Run("Should read the Mandiri bill payment codes", func(t *testing.T) {
+		instruction := NewPaymentInstruction(PaymentMethodConfig{PaymentType: "bank_transfer", Channel: "mandiri"}, &coreapi.ChargeResponse{
+			BillerCode: "70012",
+			BillKey:    "990000000001",
+		})
+
+		if instruction.BillerCode != "70012" || instruction.BillKey != "990000000001" {
+			t.Errorf("Expected biller code and bill key, got %s and %s", instruction.BillerCode, instruction.BillKey)
+		}
+	})
+
+	t.Run("Should read the QR code and deeplink actions", func(t *testing.T) {
+		instruction := NewPaymentInstruction(PaymentMethodConfig{PaymentType: "e_wallet_gopay", Channel: "gopay"}, &coreapi.ChargeResponse{
+			Actions: []coreapi.Action{
+				{Name: "generate-qr-code", URL: "https://example.com/qr"},
+				{Name: "deeplink-redirect", URL: "gojek://gopay/merchanttransfer"},
+			},
+		})
+
+		if instruction.QRCodeURL != "https://example.com/qr" {
+			t.Errorf("Expected QR code URL, got %s", instruction.QRCodeURL)
+		}
+
+		if instruction.DeeplinkURL != "gojek://gopay/merchanttransfer" {
+			t.Errorf("Expected deeplink URL, got %s", instruction.DeeplinkURL)
+		}
+	})
+}
+
+func TestValidateVANumber(t *testing.T) {
+	permata := PaymentMethodConfig{Channel: "permata", VANumberMinLength: 10, VANumberMaxLength: 10}
+
+	t.Run("Should accept an empty VA number", func(t *testing.T) {
+		if err := permata.ValidateVANumber(""); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should accept a VA number within the channel length", func(t *testing.T) {
+		if err := permata.ValidateVANumber("1234567890"); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Should reject a VA number outside the channel length", func(t *testing.T) {
+		if err := permata.ValidateVANumber("12345"); err == nil {
+			t.Error("Expected error for a short VA number")
+		}
+	})
+
+	t.Run("Should reject non numeric VA number", func(t *testing.T) {
+		if err := permata.ValidateVANumber("12345abcde"); err == nil {
+			t.Error("Expected error for a non numeric VA number")
+		}
+	})
+
+	t.Run("Should reject custom VA number for unsupported channels", func(t *testing.T) {
+		qris := PaymentMethodConfig{Channel: "qris"}
+
+		if err := qris.ValidateVANumber("123"); err == nil {
+			t.Error("Expected error for a channel without custom VA support")
+		}
+	})
+}
+
+func TestCalculateFee(t *testing.T) {
+	t.Run("Should add the flat fee to the percentage of the amount", func(t *testing.T) {
+		method := PaymentMethodConfig{FeeFlat: money.FromMajor(1000, money.IDR), FeePercentage: 0.7}
+
+		if fee := method.CalculateFee(money.FromMajor(111000, money.IDR)); !fee.Equal(money.FromMajor(1777, money.IDR)) {
+			t.Errorf("Expected IDR 1777.00, got %s", fee)
+		}
+	})
+
+	t.Run("Should not charge a fee when none is configured", func(t *testing.T) {
+		if fee := (PaymentMethodConfig{}).CalculateFee(money.FromMajor(111000, money.IDR)); !fee.IsZero() {
+			t.Errorf("Expected IDR 0.00, got %s", fee)
+		}
+	})
+}
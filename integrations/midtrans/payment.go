@@ -3,6 +3,7 @@ package midtrans
 import (
 	"embed"
 	"fmt"
+	"senkou-catalyst-be/utils/money"
 
 	"gopkg.in/yaml.v2"
 )
@@ -11,64 +12,78 @@ import (
 var paymentMethodsFS embed.FS
 
 type PaymentMethodConfig struct {
-	Name        string  `yaml:"name"          json:"name"`
-	PaymentType string  `yaml:"payment_type"  json:"payment_type"`
-	Channel     string  `yaml:"payment_channel" json:"channel"`
-	MinAmount   float64 `yaml:"min_amount"    json:"min_amount"`
-	MaxAmount   float64 `yaml:"max_amount"    json:"max_amount"`
-	Description string  `yaml:"description"   json:"description"`
-	LogoURL     string  `yaml:"logo_url"      json:"logo_url"`
+	Name              string      `yaml:"name"          json:"name"`
+	PaymentType       string      `yaml:"payment_type"  json:"payment_type"`
+	Channel           string      `yaml:"payment_channel" json:"channel"`
+	Provider          string      `yaml:"provider"      json:"provider"`
+	MinAmount         money.Money `yaml:"min_amount"    json:"min_amount"`
+	MaxAmount         money.Money `yaml:"max_amount"    json:"max_amount"`
+	Description       string      `yaml:"description"   json:"description"`
+	LogoURL           string      `yaml:"logo_url"      json:"logo_url"`
+	ExpiryDuration    int         `yaml:"expiry_duration" json:"expiry_duration,omitempty"`
+	ExpiryUnit        string      `yaml:"expiry_unit"   json:"expiry_unit,omitempty"`
+	VANumberMinLength int         `yaml:"va_number_min_length" json:"va_number_min_length,omitempty"`
+	VANumberMaxLength int         `yaml:"va_number_max_length" json:"va_number_max_length,omitempty"`
+	FeeFlat           money.Money `yaml:"fee_flat"      json:"fee_flat"`
+	FeePercentage     float64     `yaml:"fee_percentage" json:"fee_percentage"`
 }
 
-type PaymentMethodsConfig struct {
-	PaymentMethods []PaymentMethodConfig `yaml:"payment_methods"`
-}
-
-var paymentMethodsConfig *PaymentMethodsConfig
+// Calculate the fee charged to the customer for paying an amount with the payment method
+// The fee is rounded half away from zero to whole units the same way item prices are
+func (pm PaymentMethodConfig) CalculateFee(amount money.Money) money.Money {
+	fee := amount.Percent(pm.FeePercentage)
+	if !pm.FeeFlat.IsZero() {
+		fee = fee.Add(pm.FeeFlat)
+	}
 
-func GetPaymentMethods() ([]PaymentMethodConfig, error) {
-	if paymentMethodsConfig == nil {
-		if err := loadPaymentMethods(); err != nil {
-			return nil, err
-		}
+	if !fee.IsPositive() {
+		return money.Zero(amount.CurrencyCode())
 	}
 
-	return paymentMethodsConfig.PaymentMethods, nil
+	return fee.RoundToMajor()
 }
 
-func GetPaymentMethodsByType(paymentType string) ([]PaymentMethodConfig, error) {
-	allMethods, err := GetPaymentMethods()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get payment methods: %w", err)
+// Validate a custom VA number for the payment method
+// Only channels with a VA number length configured accept a custom VA number
+// It returns an error if the VA number is not accepted by the channel
+func (pm PaymentMethodConfig) ValidateVANumber(vaNumber string) error {
+	if vaNumber == "" {
+		return nil
+	}
+
+	if pm.VANumberMaxLength == 0 {
+		return fmt.Errorf("payment channel %s does not support custom VA numbers", pm.Channel)
 	}
 
-	var filteredMethods []PaymentMethodConfig
-	for _, method := range allMethods {
-		if method.PaymentType == paymentType {
-			filteredMethods = append(filteredMethods, method)
+	for _, r := range vaNumber {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("VA number must only contain digits")
 		}
 	}
 
-	if len(filteredMethods) == 0 {
-		return nil, fmt.Errorf("no payment methods found for type: %s", paymentType)
+	if len(vaNumber) < pm.VANumberMinLength || len(vaNumber) > pm.VANumberMaxLength {
+		return fmt.Errorf("VA number for %s must be %d to %d digits long", pm.Channel, pm.VANumberMinLength, pm.VANumberMaxLength)
 	}
 
-	return filteredMethods, nil
+	return nil
 }
 
-func GetPaymentMethodByChannel(paymentChannel string) (*PaymentMethodConfig, error) {
-	allMethods, err := GetPaymentMethods()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get payment methods: %w", err)
-	}
+type PaymentMethodsConfig struct {
+	PaymentMethods []PaymentMethodConfig `yaml:"payment_methods"`
+}
 
-	for _, method := range allMethods {
-		if method.Channel == paymentChannel {
-			return &method, nil
+var paymentMethodsConfig *PaymentMethodsConfig
+
+// Get the default payment methods embedded in the binary
+// They are only used to seed the payment_methods table, the table is the source of truth at runtime
+func GetDefaultPaymentMethods() ([]PaymentMethodConfig, error) {
+	if paymentMethodsConfig == nil {
+		if err := loadPaymentMethods(); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil, fmt.Errorf("payment method not found for channel: %s", paymentChannel)
+	return paymentMethodsConfig.PaymentMethods, nil
 }
 
 func loadPaymentMethods() error {
@@ -88,29 +103,34 @@ func loadPaymentMethods() error {
 type PaymentStatus string
 
 const (
-	PaymentStatusPending  PaymentStatus = "pending"
-	PaymentStatusSettled  PaymentStatus = "settled"
-	PaymentStatusFailed   PaymentStatus = "failed"
-	PaymentStatusCanceled PaymentStatus = "canceled"
-	PaymentStatusDenied   PaymentStatus = "denied"
-	PaymentStatusExpired  PaymentStatus = "expired"
-	PaymentStatusRefunded PaymentStatus = "refunded"
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusSettled           PaymentStatus = "settled"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusCanceled          PaymentStatus = "canceled"
+	PaymentStatusDenied            PaymentStatus = "denied"
+	PaymentStatusExpired           PaymentStatus = "expired"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
+// Parse a transaction status into a payment status
+// Normalized statuses are accepted as well, so every payment gateway shares the same transitions
 func ParseStatus(status string) PaymentStatus {
 	switch status {
 	case "pending":
 		return PaymentStatusPending
-	case "settlement":
+	case "settlement", string(PaymentStatusSettled):
 		return PaymentStatusSettled
-	case "deny":
+	case "deny", string(PaymentStatusDenied):
 		return PaymentStatusDenied
-	case "expire":
+	case "expire", string(PaymentStatusExpired):
 		return PaymentStatusExpired
-	case "cancel":
+	case "cancel", string(PaymentStatusCanceled):
 		return PaymentStatusCanceled
-	case "refund":
+	case "refund", string(PaymentStatusRefunded):
 		return PaymentStatusRefunded
+	case "partial_refund", string(PaymentStatusPartiallyRefunded):
+		return PaymentStatusPartiallyRefunded
 	default:
 		return PaymentStatusFailed
 	}
@@ -0,0 +1,61 @@
+package midtrans
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"senkou-catalyst-be/utils/config"
+	"senkou-catalyst-be/utils/money"
+	"strings"
+)
+
+// Generate a Midtrans signature key
+// Midtrans signs every notification with SHA512(order_id + status_code + gross_amount + server_key)
+// The gross amount must be passed exactly as Midtrans sent it (e.g. "10000.00")
+func GenerateSignatureKey(orderID, statusCode, grossAmount, serverKey string) string {
+	hash := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// Verify a notification signature key against the configured server key
+// The comparison is done in constant time to avoid leaking the expected signature
+func (mc *MidtransClient) VerifySignatureKey(orderID, statusCode, grossAmount, signatureKey string) bool {
+	if signatureKey == "" {
+		return false
+	}
+
+	expected := GenerateSignatureKey(orderID, statusCode, grossAmount, mc.ServerKey)
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(signatureKey))) == 1
+}
+
+// Parse the gross amount sent by Midtrans
+// Midtrans always sends the amount as a decimal string, e.g. "10000.00", an empty currency means rupiah
+func ParseGrossAmount(grossAmount string, currency string) (money.Money, error) {
+	amount, err := money.Parse(grossAmount, currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("invalid gross amount %q: %w", grossAmount, err)
+	}
+
+	return amount, nil
+}
+
+// Get the list of IPs or CIDR ranges allowed to send payment notifications
+// The list is read from MIDTRANS_NOTIFICATION_ALLOWED_IPS as a comma separated value
+// An empty list means notifications are accepted from any source
+func GetNotificationAllowedIPs() []string {
+	raw := config.GetEnv("MIDTRANS_NOTIFICATION_ALLOWED_IPS", "")
+	if raw == "" {
+		return nil
+	}
+
+	allowedIPs := make([]string, 0)
+	for _, ip := range strings.Split(raw, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			allowedIPs = append(allowedIPs, ip)
+		}
+	}
+
+	return allowedIPs
+}
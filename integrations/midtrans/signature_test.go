@@ -0,0 +1,89 @@
+package midtrans
+
+import (
+	"senkou-catalyst-be/utils/money"
+	"strings"
+	"testing"
+)
+
+const (
+	testOrderID     = "Postman-1578568851"
+	testStatusCode  = "200"
+	testGrossAmount = "10000.00"
+	testServerKey   = "VT-server-HJMpl9HLr_ntOKt5mRONdmKj"
+	testSignature   = "e78e2223638cb60dbdbc88d23deb9b927ac41be7263ab38758605bac834dc25425705543707504bfef0802914cfa3f5f538fa308d1f9086211c420e7892ba2ba"
+)
+
+func TestGenerateSignatureKey(t *testing.T) {
+	t.Run("Should generate SHA512 signature", func(t *testing.T) {
+		result := GenerateSignatureKey(testOrderID, testStatusCode, testGrossAmount, testServerKey)
+
+		if result != testSignature {
+			t.Errorf("Expected %s, got %s", testSignature, result)
+		}
+	})
+
+	t.Run("Should depend on the gross amount format", func(t *testing.T) {
+		result := GenerateSignatureKey(testOrderID, testStatusCode, "10000", testServerKey)
+
+		if result == testSignature {
+			t.Error("Expected a different signature for a different gross amount string")
+		}
+	})
+}
+
+func TestVerifySignatureKey(t *testing.T) {
+	client := &MidtransClient{ServerKey: testServerKey}
+
+	t.Run("Should accept a valid signature", func(t *testing.T) {
+		if !client.VerifySignatureKey(testOrderID, testStatusCode, testGrossAmount, testSignature) {
+			t.Error("Expected signature to be valid")
+		}
+	})
+
+	t.Run("Should accept an uppercase signature", func(t *testing.T) {
+		if !client.VerifySignatureKey(testOrderID, testStatusCode, testGrossAmount, strings.ToUpper(testSignature)) {
+			t.Error("Expected uppercase signature to be valid")
+		}
+	})
+
+	t.Run("Should reject a tampered amount", func(t *testing.T) {
+		if client.VerifySignatureKey(testOrderID, testStatusCode, "1.00", testSignature) {
+			t.Error("Expected signature to be invalid for a tampered amount")
+		}
+	})
+
+	t.Run("Should reject an empty signature", func(t *testing.T) {
+		if client.VerifySignatureKey(testOrderID, testStatusCode, testGrossAmount, "") {
+			t.Error("Expected empty signature to be invalid")
+		}
+	})
+
+	t.Run("Should reject a signature made with another server key", func(t *testing.T) {
+		otherClient := &MidtransClient{ServerKey: "another-key"}
+
+		if otherClient.VerifySignatureKey(testOrderID, testStatusCode, testGrossAmount, testSignature) {
+			t.Error("Expected signature to be invalid for another server key")
+		}
+	})
+}
+
+func TestParseGrossAmount(t *testing.T) {
+	t.Run("Should parse decimal amount", func(t *testing.T) {
+		result, err := ParseGrossAmount("10000.00", "")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if !result.Equal(money.FromMajor(10000, money.IDR)) {
+			t.Errorf("Expected 10000, got %v", result)
+		}
+	})
+
+	t.Run("Should return error for invalid amount", func(t *testing.T) {
+		if _, err := ParseGrossAmount("ten thousand", ""); err == nil {
+			t.Error("Expected error for invalid amount")
+		}
+	})
+}
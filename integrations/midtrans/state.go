@@ -0,0 +1,43 @@
+package midtrans
+
+// The statuses a payment can move to from each status
+// Payments only move forward: a pending payment is settled or closed, a settled payment can only be refunded
+// Closed payments (failed, canceled, denied and expired) and fully refunded payments never change again
+var paymentStatusTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusPending: {
+		PaymentStatusSettled,
+		PaymentStatusFailed,
+		PaymentStatusCanceled,
+		PaymentStatusDenied,
+		PaymentStatusExpired,
+	},
+	PaymentStatusSettled: {
+		PaymentStatusPartiallyRefunded,
+		PaymentStatusRefunded,
+	},
+	PaymentStatusPartiallyRefunded: {
+		PaymentStatusRefunded,
+	},
+}
+
+// Check whether a payment can move from this status to the next one
+// Staying in the same status is always allowed so repeated notifications are harmless
+// Partial refunds can happen more than once, which keeps a partially refunded payment in the same status
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	if s == next {
+		return true
+	}
+
+	for _, status := range paymentStatusTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check whether a payment in this status can never change again
+func (s PaymentStatus) IsFinal() bool {
+	return len(paymentStatusTransitions[s]) == 0
+}
@@ -0,0 +1,49 @@
+package midtrans
+
+import "testing"
+
+func TestPaymentStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     PaymentStatus
+		to       PaymentStatus
+		expected bool
+	}{
+		{"Should settle a pending payment", PaymentStatusPending, PaymentStatusSettled, true},
+		{"Should expire a pending payment", PaymentStatusPending, PaymentStatusExpired, true},
+		{"Should refund a settled payment", PaymentStatusSettled, PaymentStatusRefunded, true},
+		{"Should fully refund a partially refunded payment", PaymentStatusPartiallyRefunded, PaymentStatusRefunded, true},
+		{"Should accept a repeated status", PaymentStatusSettled, PaymentStatusSettled, true},
+		{"Should refuse a pending status after settlement", PaymentStatusSettled, PaymentStatusPending, false},
+		{"Should refuse a settlement after expiry", PaymentStatusExpired, PaymentStatusSettled, false},
+		{"Should refuse to expire a settled payment", PaymentStatusSettled, PaymentStatusExpired, false},
+		{"Should refuse to refund a pending payment", PaymentStatusPending, PaymentStatusRefunded, false},
+		{"Should refuse a settlement after a refund", PaymentStatusRefunded, PaymentStatusSettled, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.from.CanTransitionTo(test.to); result != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestPaymentStatusIsFinal(t *testing.T) {
+	t.Run("Should treat closed payments as final", func(t *testing.T) {
+		for _, status := range []PaymentStatus{PaymentStatusFailed, PaymentStatusCanceled, PaymentStatusDenied, PaymentStatusExpired, PaymentStatusRefunded} {
+			if !status.IsFinal() {
+				t.Errorf("Expected %s to be final", status)
+			}
+		}
+	})
+
+	t.Run("Should not treat open payments as final", func(t *testing.T) {
+		for _, status := range []PaymentStatus{PaymentStatusPending, PaymentStatusSettled, PaymentStatusPartiallyRefunded} {
+			if status.IsFinal() {
+				t.Errorf("Expected %s not to be final", status)
+			}
+		}
+	})
+}
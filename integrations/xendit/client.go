@@ -0,0 +1,188 @@
+package xendit
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"senkou-catalyst-be/utils/config"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.xendit.co"
+
+type XenditClient struct {
+	SecretKey     string
+	CallbackToken string
+	BaseURL       string
+	HTTPClient    *http.Client
+}
+
+func NewXenditClient() *XenditClient {
+	return NewXenditClientWithBaseURL(
+		config.GetEnv("XENDIT_SECRET_KEY", ""),
+		config.GetEnv("XENDIT_CALLBACK_TOKEN", ""),
+		config.GetEnv("XENDIT_BASE_URL", defaultBaseURL),
+	)
+}
+
+// Create a Xendit client that talks to a custom base URL
+// This is used to point the client to a fake server in tests
+// An empty base URL keeps the default Xendit URL
+func NewXenditClientWithBaseURL(secretKey string, callbackToken string, baseURL string) *XenditClient {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
+	return &XenditClient{
+		SecretKey:     secretKey,
+		CallbackToken: callbackToken,
+		BaseURL:       strings.TrimRight(baseURL, "/"),
+		HTTPClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Check whether the client has the credentials to call Xendit
+func (c *XenditClient) IsConfigured() bool {
+	return c.SecretKey != ""
+}
+
+// Create a payment request
+func (c *XenditClient) CreatePaymentRequest(request *CreatePaymentRequest) (*PaymentRequest, error) {
+	paymentRequest := new(PaymentRequest)
+	if err := c.call(http.MethodPost, "/payment_requests", nil, request, paymentRequest); err != nil {
+		return nil, err
+	}
+
+	return paymentRequest, nil
+}
+
+// Get a payment request by its Xendit ID
+func (c *XenditClient) GetPaymentRequest(paymentRequestID string) (*PaymentRequest, error) {
+	paymentRequest := new(PaymentRequest)
+	if err := c.call(http.MethodGet, "/payment_requests/"+paymentRequestID, nil, nil, paymentRequest); err != nil {
+		return nil, err
+	}
+
+	return paymentRequest, nil
+}
+
+// Expire a payment method so it can no longer be paid
+func (c *XenditClient) ExpirePaymentMethod(paymentMethodID string) (*PaymentMethod, error) {
+	paymentMethod := new(PaymentMethod)
+	if err := c.call(http.MethodPost, "/v2/payment_methods/"+paymentMethodID+"/expire", nil, nil, paymentMethod); err != nil {
+		return nil, err
+	}
+
+	return paymentMethod, nil
+}
+
+// Create a refund
+// The idempotency key makes the request safe to retry, Xendit never refunds twice for the same key
+func (c *XenditClient) CreateRefund(request *CreateRefundRequest, idempotencyKey string) (*Refund, error) {
+	refund := new(Refund)
+	if err := c.call(http.MethodPost, "/refunds", map[string]string{"Idempotency-key": idempotencyKey}, request, refund); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// Verify the callback token sent by Xendit in the x-callback-token header
+// It returns false when no callback token is configured
+func (c *XenditClient) VerifyCallbackToken(token string) bool {
+	if c.CallbackToken == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.CallbackToken)) == 1
+}
+
+// Get the list of IPs or CIDR ranges allowed to send payment notifications
+// The list is read from XENDIT_NOTIFICATION_ALLOWED_IPS as a comma separated value
+// An empty list means notifications are accepted from any source
+func GetNotificationAllowedIPs() []string {
+	raw := config.GetEnv("XENDIT_NOTIFICATION_ALLOWED_IPS", "")
+	if raw == "" {
+		return nil
+	}
+
+	allowedIPs := make([]string, 0)
+	for _, ip := range strings.Split(raw, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			allowedIPs = append(allowedIPs, ip)
+		}
+	}
+
+	return allowedIPs
+}
+
+func (c *XenditClient) call(method string, path string, headers map[string]string, body any, result any) error {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+
+		payload = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.SetBasicAuth(c.SecretKey, "")
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return &Error{StatusCode: http.StatusBadGateway, ErrorCode: "REQUEST_FAILED", Message: err.Error()}
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return &Error{StatusCode: http.StatusBadGateway, ErrorCode: "RESPONSE_FAILED", Message: err.Error()}
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		apiError := &Error{StatusCode: res.StatusCode}
+		if err := json.Unmarshal(resBody, apiError); err != nil || apiError.Message == "" {
+			apiError.Message = strings.TrimSpace(string(resBody))
+		}
+
+		return apiError
+	}
+
+	if result == nil || len(resBody) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(resBody, result); err != nil {
+		return fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return nil
+}
+
+// An error answered by the Xendit API
+type Error struct {
+	StatusCode int    `json:"-"`
+	ErrorCode  string `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("xendit error %d %s: %s", e.StatusCode, e.ErrorCode, e.Message)
+}
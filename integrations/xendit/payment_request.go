@@ -0,0 +1,156 @@
+package xendit
+
+import "time"
+
+// Payment method types of the Xendit payment request API
+const (
+	PaymentMethodTypeVirtualAccount = "VIRTUAL_ACCOUNT"
+	PaymentMethodTypeEwallet        = "EWALLET"
+	PaymentMethodTypeQRCode         = "QR_CODE"
+)
+
+// Statuses of Xendit payment requests, payments and payment methods
+const (
+	StatusPending           = "PENDING"
+	StatusRequiresAction    = "REQUIRES_ACTION"
+	StatusAcceptingPayments = "ACCEPTING_PAYMENTS"
+	StatusActive            = "ACTIVE"
+	StatusSucceeded         = "SUCCEEDED"
+	StatusPaid              = "PAID"
+	StatusFailed            = "FAILED"
+	StatusExpired           = "EXPIRED"
+	StatusCanceled          = "CANCELED"
+	StatusVoided            = "VOIDED"
+	StatusInactive          = "INACTIVE"
+	StatusRefunded          = "REFUNDED"
+	StatusPartiallyRefunded = "PARTIALLY_REFUNDED"
+)
+
+// Reasons accepted by the Xendit refund API
+const (
+	RefundReasonOthers       = "OTHERS"
+	RefundReasonCancellation = "CANCELLATION"
+)
+
+type CreatePaymentRequest struct {
+	ReferenceID   string               `json:"reference_id"`
+	Amount        float64              `json:"amount"`
+	Currency      string               `json:"currency"`
+	Country       string               `json:"country"`
+	PaymentMethod PaymentMethod        `json:"payment_method"`
+	Items         []PaymentRequestItem `json:"items,omitempty"`
+	Metadata      map[string]string    `json:"metadata,omitempty"`
+}
+
+type PaymentRequestItem struct {
+	ReferenceID   string  `json:"reference_id"`
+	Name          string  `json:"name"`
+	NetUnitAmount float64 `json:"net_unit_amount"`
+	Quantity      int     `json:"quantity"`
+	Currency      string  `json:"currency"`
+	Category      string  `json:"category"`
+	Type          string  `json:"type"`
+}
+
+type PaymentRequest struct {
+	ID            string        `json:"id"`
+	ReferenceID   string        `json:"reference_id"`
+	Amount        float64       `json:"amount"`
+	Currency      string        `json:"currency"`
+	Country       string        `json:"country"`
+	Status        string        `json:"status"`
+	FailureCode   string        `json:"failure_code,omitempty"`
+	PaymentMethod PaymentMethod `json:"payment_method"`
+	Actions       []Action      `json:"actions,omitempty"`
+	Created       *time.Time    `json:"created,omitempty"`
+	Updated       *time.Time    `json:"updated,omitempty"`
+}
+
+type PaymentMethod struct {
+	ID             string          `json:"id,omitempty"`
+	Type           string          `json:"type"`
+	Reusability    string          `json:"reusability"`
+	ReferenceID    string          `json:"reference_id,omitempty"`
+	Status         string          `json:"status,omitempty"`
+	VirtualAccount *VirtualAccount `json:"virtual_account,omitempty"`
+	Ewallet        *Ewallet        `json:"ewallet,omitempty"`
+	QRCode         *QRCode         `json:"qr_code,omitempty"`
+}
+
+type VirtualAccount struct {
+	ChannelCode       string                          `json:"channel_code"`
+	ChannelProperties VirtualAccountChannelProperties `json:"channel_properties"`
+}
+
+type VirtualAccountChannelProperties struct {
+	CustomerName         string     `json:"customer_name,omitempty"`
+	VirtualAccountNumber string     `json:"virtual_account_number,omitempty"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+}
+
+type Ewallet struct {
+	ChannelCode       string                   `json:"channel_code"`
+	ChannelProperties EwalletChannelProperties `json:"channel_properties"`
+}
+
+type EwalletChannelProperties struct {
+	MobileNumber     string `json:"mobile_number,omitempty"`
+	SuccessReturnURL string `json:"success_return_url,omitempty"`
+	FailureReturnURL string `json:"failure_return_url,omitempty"`
+}
+
+type QRCode struct {
+	ChannelCode       string                   `json:"channel_code,omitempty"`
+	ChannelProperties *QRCodeChannelProperties `json:"channel_properties,omitempty"`
+}
+
+type QRCodeChannelProperties struct {
+	QRString  string     `json:"qr_string,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type Action struct {
+	Action  string `json:"action"`
+	URLType string `json:"url_type"`
+	URL     string `json:"url,omitempty"`
+	QRCode  string `json:"qr_code,omitempty"`
+}
+
+type CreateRefundRequest struct {
+	PaymentRequestID string            `json:"payment_request_id"`
+	ReferenceID      string            `json:"reference_id,omitempty"`
+	Amount           float64           `json:"amount"`
+	Currency         string            `json:"currency"`
+	Reason           string            `json:"reason"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+type Refund struct {
+	ID               string  `json:"id"`
+	PaymentRequestID string  `json:"payment_request_id"`
+	Amount           float64 `json:"amount"`
+	Currency         string  `json:"currency"`
+	Status           string  `json:"status"`
+	Reason           string  `json:"reason"`
+}
+
+// The body of a payment callback
+// Payment events carry the payment in data, payment method events carry the payment method
+type PaymentCallback struct {
+	Event      string              `json:"event"`
+	BusinessID string              `json:"business_id"`
+	Created    *time.Time          `json:"created,omitempty"`
+	Data       PaymentCallbackData `json:"data"`
+}
+
+type PaymentCallbackData struct {
+	ID               string     `json:"id"`
+	PaymentRequestID string     `json:"payment_request_id"`
+	ReferenceID      string     `json:"reference_id"`
+	Status           string     `json:"status"`
+	Amount           float64    `json:"amount"`
+	Currency         string     `json:"currency"`
+	FailureCode      string     `json:"failure_code,omitempty"`
+	Created          *time.Time `json:"created,omitempty"`
+	Updated          *time.Time `json:"updated,omitempty"`
+}
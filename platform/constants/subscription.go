@@ -3,8 +3,11 @@ package constants
 type SubscriptionPlan string
 
 const (
-	SubscriptionProductSlot        SubscriptionPlan = "Subscription-Product-Slot"
-	SubscriptionCategoryLimit      SubscriptionPlan = "Subscription-Category-Limit"
-	SubscriptionAnalytics          SubscriptionPlan = "Subscription-Analytics"
-	SubscriptionInteractionMetrics SubscriptionPlan = "Subscription-Interaction-Metrics"
+	SubscriptionProductSlot               SubscriptionPlan = "Subscription-Product-Slot"
+	SubscriptionCategoryLimit             SubscriptionPlan = "Subscription-Category-Limit"
+	SubscriptionAnalytics                 SubscriptionPlan = "Subscription-Analytics"
+	SubscriptionInteractionMetrics        SubscriptionPlan = "Subscription-Interaction-Metrics"
+	SubscriptionMerchantTemplateCustomize SubscriptionPlan = "Subscription-Merchant-Template-Customize"
+	SubscriptionPhotoSize                 SubscriptionPlan = "Subscription-Photo-Size"
+	SubscriptionPhotosPerProduct          SubscriptionPlan = "Subscription-Photos-Per-Product"
 )
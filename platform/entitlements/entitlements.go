@@ -0,0 +1,230 @@
+package entitlements
+
+import (
+	"fmt"
+	"log"
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/utils/money"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const contextKey = "entitlements"
+
+// The value of an entitlement for a user
+// Limit is the quota or the size in bytes, flags are 1 when enabled and 0 otherwise
+type Entitlement struct {
+	Key   constants.SubscriptionPlan `json:"key"`
+	Kind  Kind                       `json:"kind"`
+	Limit int64                      `json:"limit"`
+}
+
+// Check whether a flag is enabled
+func (e Entitlement) Enabled() bool {
+	return e.Limit > 0
+}
+
+// The effective entitlements of a user, resolved from the active subscription
+type Entitlements struct {
+	UserID           uint32 `json:"user_id"`
+	SubscriptionID   uint32 `json:"subscription_id,omitempty"`
+	SubscriptionName string `json:"subscription_name,omitempty"`
+	// Administrators are not limited by any subscription
+	Unrestricted bool                                       `json:"unrestricted"`
+	Values       map[constants.SubscriptionPlan]Entitlement `json:"entitlements"`
+}
+
+// Resolve the entitlements granted by a subscription
+// Plans that are not declared in the registry are ignored and plans with an invalid value fall back to the default,
+// so a misconfigured subscription never grants more than intended. A nil subscription grants the defaults only
+func New(userID uint32, subscription *models.Subscription) *Entitlements {
+	entitlements := &Entitlements{
+		UserID: userID,
+		Values: make(map[constants.SubscriptionPlan]Entitlement, len(definitions)),
+	}
+
+	for _, definition := range definitions {
+		entitlements.Values[definition.Key] = Entitlement{Key: definition.Key, Kind: definition.Kind, Limit: definition.Default}
+	}
+
+	if subscription == nil {
+		return entitlements
+	}
+
+	entitlements.SubscriptionID = subscription.ID
+	entitlements.SubscriptionName = subscription.Name
+
+	for _, plan := range subscription.Plans {
+		definition, ok := Lookup(constants.SubscriptionPlan(plan.Name))
+		if !ok {
+			continue
+		}
+
+		limit, err := definition.Parse(plan.Value)
+		if err != nil {
+			log.Printf("Ignoring plan of subscription %d: %v", subscription.ID, err)
+			continue
+		}
+
+		entitlements.Values[definition.Key] = Entitlement{Key: definition.Key, Kind: definition.Kind, Limit: limit}
+	}
+
+	return entitlements
+}
+
+// Resolve the entitlements of a user that is not limited by any subscription
+func Unrestricted(userID uint32) *Entitlements {
+	entitlements := New(userID, nil)
+	entitlements.Unrestricted = true
+
+	return entitlements
+}
+
+// Get an entitlement
+// An entitlement that is not declared in the registry is reported with a zero limit
+func (e *Entitlements) Get(key constants.SubscriptionPlan) Entitlement {
+	if entitlement, ok := e.Values[key]; ok {
+		return entitlement
+	}
+
+	return Entitlement{Key: key}
+}
+
+// Check whether a feature is enabled
+func (e *Entitlements) Feature(key constants.SubscriptionPlan) bool {
+	return e.Unrestricted || e.Get(key).Enabled()
+}
+
+// Check that a feature is enabled
+// It returns a denial when the feature is not enabled, or nil when it is
+func (e *Entitlements) RequireFeature(key constants.SubscriptionPlan) *Denial {
+	entitlement := e.Get(key)
+	if e.Unrestricted || (entitlement.Kind == KindFlag && entitlement.Enabled()) {
+		return nil
+	}
+
+	return &Denial{
+		Key:      key,
+		Kind:     KindFlag,
+		Limit:    entitlement.Limit,
+		Required: 1,
+		Reason:   fmt.Sprintf("%s is not included in the current subscription", key),
+	}
+}
+
+// Check that one more item fits in a quota
+// It returns a denial when used already reaches the quota, or nil when there is room left
+func (e *Entitlements) RequireQuota(key constants.SubscriptionPlan, used int64) *Denial {
+	entitlement := e.Get(key)
+	if e.Unrestricted || (entitlement.Kind == KindQuota && used < entitlement.Limit) {
+		return nil
+	}
+
+	return &Denial{
+		Key:      key,
+		Kind:     KindQuota,
+		Limit:    entitlement.Limit,
+		Used:     used,
+		Required: used + 1,
+		Reason:   fmt.Sprintf("%s of %d is used up", key, entitlement.Limit),
+	}
+}
+
+// Check that a number of items fits in a quota at once, e.g. the photos of a product
+// It returns a denial when count is above the quota, or nil when it fits
+func (e *Entitlements) RequireCount(key constants.SubscriptionPlan, count int64) *Denial {
+	entitlement := e.Get(key)
+	if e.Unrestricted || (entitlement.Kind == KindQuota && count <= entitlement.Limit) {
+		return nil
+	}
+
+	return &Denial{
+		Key:      key,
+		Kind:     KindQuota,
+		Limit:    entitlement.Limit,
+		Used:     count,
+		Required: count,
+		Reason:   fmt.Sprintf("%s of %d is exceeded", key, entitlement.Limit),
+	}
+}
+
+// Check that a size fits in a byte size limit
+// It returns a denial when size is above the limit, or nil when it fits
+func (e *Entitlements) RequireBytes(key constants.SubscriptionPlan, size int64) *Denial {
+	entitlement := e.Get(key)
+	if e.Unrestricted || (entitlement.Kind == KindBytes && size <= entitlement.Limit) {
+		return nil
+	}
+
+	return &Denial{
+		Key:      key,
+		Kind:     KindBytes,
+		Limit:    entitlement.Limit,
+		Required: size,
+		Reason:   fmt.Sprintf("%s of %d bytes is exceeded", key, entitlement.Limit),
+	}
+}
+
+// A request refused because of a missing entitlement
+// Required is the smallest limit that would have allowed the request
+// and UpgradeTo lists the subscriptions that grant it, cheapest first
+type Denial struct {
+	Key       constants.SubscriptionPlan `json:"key"`
+	Kind      Kind                       `json:"kind"`
+	Limit     int64                      `json:"limit"`
+	Used      int64                      `json:"used,omitempty"`
+	Required  int64                      `json:"required"`
+	Reason    string                     `json:"reason"`
+	UpgradeTo []UpgradeOption            `json:"upgrade_to"`
+}
+
+// A subscription a user can move to in order to get an entitlement
+type UpgradeOption struct {
+	SubscriptionID uint32      `json:"subscription_id"`
+	Name           string      `json:"name"`
+	Price          money.Money `json:"price"`
+	Limit          int64       `json:"limit"`
+}
+
+// Fill the subscriptions of a catalog that would have allowed a denied request
+// The current subscription of the user is never suggested
+func (d *Denial) WithUpgradeOptions(catalog []*models.Subscription, currentSubscriptionID uint32) *Denial {
+	d.UpgradeTo = make([]UpgradeOption, 0)
+
+	for _, subscription := range catalog {
+		if subscription.ID == currentSubscriptionID {
+			continue
+		}
+
+		entitlement := New(0, subscription).Get(d.Key)
+		if entitlement.Limit < d.Required {
+			continue
+		}
+
+		d.UpgradeTo = append(d.UpgradeTo, UpgradeOption{
+			SubscriptionID: subscription.ID,
+			Name:           subscription.Name,
+			Price:          subscription.Price,
+			Limit:          entitlement.Limit,
+		})
+	}
+
+	sort.SliceStable(d.UpgradeTo, func(i, j int) bool {
+		return d.UpgradeTo[i].Price.Amount < d.UpgradeTo[j].Price.Amount
+	})
+
+	return d
+}
+
+// Get the entitlements resolved earlier in a request
+func FromContext(c *fiber.Ctx) (*Entitlements, bool) {
+	entitlements, ok := c.Locals(contextKey).(*Entitlements)
+	return entitlements, ok && entitlements != nil
+}
+
+// Keep the entitlements resolved in a request, so they are resolved once per request
+func StoreInContext(c *fiber.Ctx, entitlements *Entitlements) {
+	c.Locals(contextKey, entitlements)
+}
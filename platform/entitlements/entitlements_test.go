@@ -0,0 +1,189 @@
+package entitlements
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/utils/money"
+	"testing"
+)
+
+func subscription(id uint32, name string, price int64, plans map[constants.SubscriptionPlan]string) *models.Subscription {
+	subscription := &models.Subscription{ID: id, Name: name, Price: money.FromMajor(price, money.IDR)}
+	for key, value := range plans {
+		subscription.Plans = append(subscription.Plans, models.SubscriptionPlan{Name: string(key), Value: value})
+	}
+
+	return subscription
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Should resolve typed values from the plans of a subscription", func(t *testing.T) {
+		entitlements := New(10, subscription(2, "Content Creator", 10000, map[constants.SubscriptionPlan]string{
+			constants.SubscriptionProductSlot: "100",
+			constants.SubscriptionAnalytics:   "true",
+			constants.SubscriptionPhotoSize:   "2MB",
+		}))
+
+		if limit := entitlements.Get(constants.SubscriptionProductSlot).Limit; limit != 100 {
+			t.Errorf("Expected 100, got %d", limit)
+		}
+
+		if !entitlements.Feature(constants.SubscriptionAnalytics) {
+			t.Errorf("Expected true, got %t", false)
+		}
+
+		if size := entitlements.Get(constants.SubscriptionPhotoSize).Limit; size != 2<<20 {
+			t.Errorf("Expected 2097152, got %d", size)
+		}
+	})
+
+	t.Run("Should fall back to the defaults for missing, invalid and undeclared plans", func(t *testing.T) {
+		entitlements := New(10, subscription(2, "Broken", 10000, map[constants.SubscriptionPlan]string{
+			constants.SubscriptionProductSlot: "lots",
+			constants.SubscriptionAnalytics:   "yes please",
+			"Subscription-Unknown":            "true",
+		}))
+
+		if limit := entitlements.Get(constants.SubscriptionProductSlot).Limit; limit != 0 {
+			t.Errorf("Expected 0, got %d", limit)
+		}
+
+		if entitlements.Feature(constants.SubscriptionAnalytics) {
+			t.Errorf("Expected false, got %t", true)
+		}
+
+		if entitlements.Feature("Subscription-Unknown") {
+			t.Errorf("Expected false, got %t", true)
+		}
+
+		if size := entitlements.Get(constants.SubscriptionPhotoSize).Limit; size != 1<<20 {
+			t.Errorf("Expected 1048576, got %d", size)
+		}
+	})
+}
+
+func TestRequire(t *testing.T) {
+	t.Run("Should fail closed without a subscription", func(t *testing.T) {
+		entitlements := New(10, nil)
+
+		if entitlements.RequireFeature(constants.SubscriptionAnalytics) == nil {
+			t.Error("Expected a denial, got nil")
+		}
+
+		if entitlements.RequireQuota(constants.SubscriptionProductSlot, 0) == nil {
+			t.Error("Expected a denial, got nil")
+		}
+
+		if entitlements.RequireQuota("Subscription-Unknown", 0) == nil {
+			t.Error("Expected a denial, got nil")
+		}
+	})
+
+	t.Run("Should allow a quota until it is used up", func(t *testing.T) {
+		entitlements := New(10, subscription(1, "Free tier", 0, map[constants.SubscriptionPlan]string{
+			constants.SubscriptionProductSlot: "20",
+		}))
+
+		if denial := entitlements.RequireQuota(constants.SubscriptionProductSlot, 19); denial != nil {
+			t.Errorf("Expected nil, got %s", denial.Reason)
+		}
+
+		denial := entitlements.RequireQuota(constants.SubscriptionProductSlot, 20)
+		if denial == nil {
+			t.Fatal("Expected a denial, got nil")
+		}
+
+		if denial.Limit != 20 {
+			t.Errorf("Expected 20, got %d", denial.Limit)
+		}
+
+		if denial.Used != 20 {
+			t.Errorf("Expected 20, got %d", denial.Used)
+		}
+
+		if denial.Required != 21 {
+			t.Errorf("Expected 21, got %d", denial.Required)
+		}
+	})
+
+	t.Run("Should allow a count up to the quota", func(t *testing.T) {
+		entitlements := New(10, nil)
+
+		if denial := entitlements.RequireCount(constants.SubscriptionPhotosPerProduct, 5); denial != nil {
+			t.Errorf("Expected nil, got %s", denial.Reason)
+		}
+
+		denial := entitlements.RequireCount(constants.SubscriptionPhotosPerProduct, 6)
+		if denial == nil {
+			t.Fatal("Expected a denial, got nil")
+		}
+
+		if denial.Limit != 5 {
+			t.Errorf("Expected 5, got %d", denial.Limit)
+		}
+
+		if denial.Required != 6 {
+			t.Errorf("Expected 6, got %d", denial.Required)
+		}
+	})
+
+	t.Run("Should let unrestricted users through", func(t *testing.T) {
+		entitlements := Unrestricted(1)
+
+		if denial := entitlements.RequireFeature(constants.SubscriptionInteractionMetrics); denial != nil {
+			t.Errorf("Expected nil, got %s", denial.Reason)
+		}
+
+		if denial := entitlements.RequireQuota(constants.SubscriptionProductSlot, 1000); denial != nil {
+			t.Errorf("Expected nil, got %s", denial.Reason)
+		}
+	})
+}
+
+func TestWithUpgradeOptions(t *testing.T) {
+	t.Run("Should suggest the subscriptions granting the requirement, cheapest first", func(t *testing.T) {
+		catalog := []*models.Subscription{
+			subscription(3, "Business", 30000, map[constants.SubscriptionPlan]string{constants.SubscriptionProductSlot: "99999"}),
+			subscription(1, "Free tier", 0, map[constants.SubscriptionPlan]string{constants.SubscriptionProductSlot: "20"}),
+			subscription(2, "Content Creator", 10000, map[constants.SubscriptionPlan]string{constants.SubscriptionProductSlot: "100"}),
+		}
+
+		entitlements := New(10, catalog[1])
+		denial := entitlements.RequireQuota(constants.SubscriptionProductSlot, 20).WithUpgradeOptions(catalog, entitlements.SubscriptionID)
+
+		if len(denial.UpgradeTo) != 2 {
+			t.Fatalf("Expected 2, got %d", len(denial.UpgradeTo))
+		}
+
+		if denial.UpgradeTo[0].SubscriptionID != 2 {
+			t.Errorf("Expected 2, got %d", denial.UpgradeTo[0].SubscriptionID)
+		}
+
+		if denial.UpgradeTo[0].Limit != 100 {
+			t.Errorf("Expected 100, got %d", denial.UpgradeTo[0].Limit)
+		}
+
+		if denial.UpgradeTo[1].SubscriptionID != 3 {
+			t.Errorf("Expected 3, got %d", denial.UpgradeTo[1].SubscriptionID)
+		}
+	})
+}
+
+func TestParseByteSize(t *testing.T) {
+	t.Run("Should parse sizes with and without units", func(t *testing.T) {
+		cases := map[string]int64{"1048576": 1 << 20, "512KB": 512 << 10, "2MB": 2 << 20, "1gb": 1 << 30, "10 B": 10}
+		for value, expected := range cases {
+			if size, err := ParseByteSize(value); err != nil || size != expected {
+				t.Errorf("Expected %d, got %d (%v)", expected, size, err)
+			}
+		}
+	})
+
+	t.Run("Should refuse invalid sizes", func(t *testing.T) {
+		for _, value := range []string{"", "-1MB", "MB", "2TB"} {
+			if size, err := ParseByteSize(value); err == nil {
+				t.Errorf("Expected an error, got %d", size)
+			}
+		}
+	})
+}
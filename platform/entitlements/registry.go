@@ -0,0 +1,160 @@
+package entitlements
+
+import (
+	"fmt"
+	"senkou-catalyst-be/platform/constants"
+	"strconv"
+	"strings"
+)
+
+type Kind string
+
+const (
+	// A number of things a user can have, e.g. products
+	KindQuota Kind = "quota"
+	// A feature that is either enabled or not
+	KindFlag Kind = "flag"
+	// A size in bytes, e.g. of an uploaded file
+	KindBytes Kind = "bytes"
+)
+
+// The declaration of an entitlement granted by subscription plans
+// Default is used when a subscription does not declare the plan or declares an invalid value,
+// so it must be the most restrictive value that still makes sense
+type Definition struct {
+	Key         constants.SubscriptionPlan `json:"key"`
+	Kind        Kind                       `json:"kind"`
+	Default     int64                      `json:"default"`
+	Description string                     `json:"description"`
+}
+
+// Every entitlement the application knows about
+// A plan of a subscription that is not declared here grants nothing
+var definitions = []Definition{
+	{
+		Key:         constants.SubscriptionProductSlot,
+		Kind:        KindQuota,
+		Description: "Products a merchant can publish",
+	},
+	{
+		Key:         constants.SubscriptionCategoryLimit,
+		Kind:        KindQuota,
+		Description: "Categories a merchant can create",
+	},
+	{
+		Key:         constants.SubscriptionAnalytics,
+		Kind:        KindFlag,
+		Description: "Merchant overview analytics",
+	},
+	{
+		Key:         constants.SubscriptionInteractionMetrics,
+		Kind:        KindFlag,
+		Description: "Interaction metrics of products",
+	},
+	{
+		Key:         constants.SubscriptionMerchantTemplateCustomize,
+		Kind:        KindFlag,
+		Description: "Customization of the merchant page template",
+	},
+	{
+		Key:         constants.SubscriptionPhotoSize,
+		Kind:        KindBytes,
+		Default:     1 << 20,
+		Description: "Largest product photo that can be uploaded",
+	},
+	{
+		Key:         constants.SubscriptionPhotosPerProduct,
+		Kind:        KindQuota,
+		Default:     5,
+		Description: "Photos a product can have",
+	},
+}
+
+// Get the definitions of every entitlement
+func Definitions() []Definition {
+	return append([]Definition(nil), definitions...)
+}
+
+// Get the definition of an entitlement
+// It returns false when the entitlement is not declared
+func Lookup(key constants.SubscriptionPlan) (Definition, bool) {
+	for _, definition := range definitions {
+		if definition.Key == key {
+			return definition, true
+		}
+	}
+
+	return Definition{}, false
+}
+
+// Parse the value of a subscription plan
+// Flags are "true" or "false", quotas are non-negative integers and
+// byte sizes are non-negative integers with an optional KB, MB or GB suffix
+// It returns an error if the value does not match the kind of the entitlement
+func (d Definition) Parse(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+
+	switch d.Kind {
+	case KindFlag:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid flag %q of %s", value, d.Key)
+		}
+
+		if enabled {
+			return 1, nil
+		}
+
+		return 0, nil
+	case KindQuota:
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 0 {
+			return 0, fmt.Errorf("invalid quota %q of %s", value, d.Key)
+		}
+
+		return limit, nil
+	case KindBytes:
+		size, err := ParseByteSize(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size %q of %s", value, d.Key)
+		}
+
+		return size, nil
+	}
+
+	return 0, fmt.Errorf("unknown kind %q of %s", d.Kind, d.Key)
+}
+
+// Parse a size in bytes, e.g. "1048576", "512KB" or "2MB"
+// Units are powers of 1024
+func ParseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", value)
+	}
+
+	if size > (1<<63-1)/multiplier {
+		return 0, fmt.Errorf("byte size %q is too large", value)
+	}
+
+	return size * multiplier, nil
+}
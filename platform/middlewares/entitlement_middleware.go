@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"fmt"
+	"senkou-catalyst-be/app/services"
+	"senkou-catalyst-be/platform/constants"
+	"senkou-catalyst-be/platform/entitlements"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/utils/response"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Require every given feature to be enabled for the user
+// The request is refused when the entitlements cannot be resolved or any feature is missing
+func RequireFeature(entitlementService services.EntitlementService, features ...constants.SubscriptionPlan) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userEntitlements, appError := ResolveEntitlements(c, entitlementService)
+		if appError != nil {
+			return appError
+		}
+
+		for _, feature := range features {
+			if denial := userEntitlements.RequireFeature(feature); denial != nil {
+				return deny(c, entitlementService, userEntitlements, denial)
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// Require room for one more item in a quota of the user
+// The request is refused when the entitlements or the usage cannot be resolved or the quota is used up
+func RequireQuota(entitlementService services.EntitlementService, quota constants.SubscriptionPlan) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userEntitlements, appError := ResolveEntitlements(c, entitlementService)
+		if appError != nil {
+			return appError
+		}
+
+		if userEntitlements.Unrestricted {
+			return c.Next()
+		}
+
+		used, appError := entitlementService.GetQuotaUsage(userEntitlements.UserID, quota)
+		if appError != nil {
+			return response.InternalError(c, "Failed to verify subscription quota", appError.Details)
+		}
+
+		if denial := userEntitlements.RequireQuota(quota, used); denial != nil {
+			return deny(c, entitlementService, userEntitlements, denial)
+		}
+
+		return c.Next()
+	}
+}
+
+// Require every file uploaded in the given multipart fields to fit in a byte size limit of the user
+// Requests that are not multipart are left to the handler to refuse
+func RequireUploadSize(entitlementService services.EntitlementService, limit constants.SubscriptionPlan, fields ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userEntitlements, appError := ResolveEntitlements(c, entitlementService)
+		if appError != nil {
+			return appError
+		}
+
+		form, formErr := c.MultipartForm()
+		if formErr != nil {
+			return c.Next()
+		}
+
+		for _, field := range fields {
+			for _, file := range form.File[field] {
+				if denial := userEntitlements.RequireBytes(limit, file.Size); denial != nil {
+					return deny(c, entitlementService, userEntitlements, denial)
+				}
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// Resolve the entitlements of the authenticated user
+// The entitlements are resolved once per request and reused by every following check
+// It returns the entitlements or an error to respond with when they cannot be resolved
+func ResolveEntitlements(c *fiber.Ctx, entitlementService services.EntitlementService) (*entitlements.Entitlements, *errors.CustomError) {
+	if userEntitlements, ok := entitlements.FromContext(c); ok {
+		return userEntitlements, nil
+	}
+
+	userIDStr := fmt.Sprintf("%v", c.Locals("userID"))
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if userID == 0 || err != nil {
+		return nil, errors.Unauthorized("You are not authorized to access this resource")
+	}
+
+	userEntitlements, appError := entitlementService.ResolveEntitlements(uint32(userID))
+	if appError != nil {
+		if appError.Code == fiber.StatusNotFound {
+			return nil, errors.Unauthorized("You are not authorized to access this resource")
+		}
+
+		return nil, errors.Internal("Failed to resolve subscription entitlements", appError.Details)
+	}
+
+	entitlements.StoreInContext(c, userEntitlements)
+
+	return userEntitlements, nil
+}
+
+// Refuse a request with the subscriptions that would allow it
+func deny(c *fiber.Ctx, entitlementService services.EntitlementService, userEntitlements *entitlements.Entitlements, denial *entitlements.Denial) error {
+	if appError := entitlementService.AttachUpgradeOptions(userEntitlements, denial); appError != nil {
+		return response.InternalError(c, "Failed to verify subscription entitlements", appError.Details)
+	}
+
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"message": "Your subscription does not include this feature",
+		"error":   denial,
+	})
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// This middleware restricts a route to requests coming from the given IPs or CIDR ranges
+// It is used to make sure callbacks such as payment notifications only come from the provider
+// When no IP is given, every request is allowed to pass through
+func IPWhitelistMiddleware(allowedIPs ...string) fiber.Handler {
+	allowedNets := make([]*net.IPNet, 0, len(allowedIPs))
+
+	for _, allowed := range allowedIPs {
+		if !strings.Contains(allowed, "/") {
+			if strings.Contains(allowed, ":") {
+				allowed += "/128"
+			} else {
+				allowed += "/32"
+			}
+		}
+
+		if _, ipNet, err := net.ParseCIDR(allowed); err == nil {
+			allowedNets = append(allowedNets, ipNet)
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		if len(allowedNets) == 0 {
+			return c.Next()
+		}
+
+		clientIP := net.ParseIP(c.IP())
+
+		if clientIP != nil {
+			for _, ipNet := range allowedNets {
+				if ipNet.Contains(clientIP) {
+					return c.Next()
+				}
+			}
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "You are not allowed to access this resource",
+		})
+	}
+}
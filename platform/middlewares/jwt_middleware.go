@@ -2,15 +2,22 @@ package middlewares
 
 import (
 	"fmt"
+	"senkou-catalyst-be/platform/errors"
 	"senkou-catalyst-be/utils/auth"
 	"senkou-catalyst-be/utils/config"
 	"senkou-catalyst-be/utils/response"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 var jwtSecret []byte
 
+// Verifies that the session an access token belongs to is still active
+type SessionVerifier interface {
+	VerifySession(sessionID uuid.UUID) *errors.CustomError
+}
+
 func init() {
 	jwtSecret = []byte(config.GetEnv("AUTH_SECRET", ""))
 
@@ -22,49 +29,74 @@ func init() {
 // This middleware checks if the request has a valid JWT token that provided by the user
 // If the token is valid, it extracts the payload that contain the user ID and stores it in the context
 // If the token is invalid or missing, it returns a Uauthorized response
-// Generally used to protect routes that require authentication
-var JWTProtected fiber.Handler = func(c *fiber.Ctx) error {
-	token := c.Get("Authorization")
-
-	// Check if the user provide with the Authorization header
-	// If the header is not present, we return an Uauthorized response
-	if token == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "You are not authorized to access this resource",
-		})
-	}
-
-	var authToken string
-
-	_, err := fmt.Sscanf(token, "Bearer %s", &authToken)
-
-	// If the token is not in the correct format, we return an Uauthorized response
-	// The correct format is "Bearer <token>"
-	if err != nil || authToken == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Invalid authorization header format",
-		})
-	}
-
-	jwtManager, managerError := auth.NewJWTManager(string(jwtSecret))
-
-	if managerError != nil {
-		return response.InternalError(c, "Failed to initialize JWT manager", managerError.Error())
+// Generally used to protect routes that require authentication, the session of every token is checked with sessionVerifier
+func JWTProtected(sessionVerifier SessionVerifier) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Get("Authorization")
+
+		// Check if the user provide with the Authorization header
+		// If the header is not present, we return an Uauthorized response
+		if token == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "You are not authorized to access this resource",
+			})
+		}
+
+		var authToken string
+
+		_, err := fmt.Sscanf(token, "Bearer %s", &authToken)
+
+		// If the token is not in the correct format, we return an Uauthorized response
+		// The correct format is "Bearer <token>"
+		if err != nil || authToken == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid authorization header format",
+			})
+		}
+
+		jwtManager, managerError := auth.NewJWTManager(string(jwtSecret))
+
+		if managerError != nil {
+			return response.InternalError(c, "Failed to initialize JWT manager", managerError.Error())
+		}
+
+		// Validate the token and claims or extract the payload inside the token
+		// If the token is valid, we extract the payload that contain the user ID and store
+		// it in the context for further processing
+		claims, err := jwtManager.ValidateToken(authToken)
+
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Cannot continue to process request due to invalid token",
+				"error":   fmt.Sprintf("Token validation failed: %s", err.Error()),
+			})
+		}
+
+		// Every access token is issued for a login and carries the session it belongs to
+		// The session can end before the token expires, when the user logs out or revokes it
+		sessionID, err := uuid.Parse(fmt.Sprintf("%v", claims["sid"]))
+
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Cannot continue to process request due to invalid token",
+				"error":   "Token is not bound to a session",
+			})
+		}
+
+		if appError := sessionVerifier.VerifySession(sessionID); appError != nil {
+			if appError.Code != fiber.StatusUnauthorized {
+				return response.InternalError(c, appError.Message, appError.Details)
+			}
+
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Cannot continue to process request due to invalid token",
+				"error":   appError.Details,
+			})
+		}
+
+		c.Locals("userID", claims["payload"])
+		c.Locals("sessionID", claims["sid"])
+
+		return c.Next()
 	}
-
-	// Validate the token and claims or extract the payload inside the token
-	// If the token is valid, we extract the payload that contain the user ID and store
-	// it in the context for further processing
-	claims, err := jwtManager.ValidateToken(authToken)
-
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Cannot continue to process request due to invalid token",
-			"error":   fmt.Sprintf("Token validation failed: %s", err.Error()),
-		})
-	}
-
-	c.Locals("userID", claims["payload"])
-
-	return c.Next()
 }
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"senkou-catalyst-be/platform/errors"
+	"senkou-catalyst-be/utils/auth"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// A session verifier that only knows whether each session is still active
+type fakeSessionVerifier struct {
+	active map[uuid.UUID]bool
+}
+
+func (s *fakeSessionVerifier) VerifySession(sessionID uuid.UUID) *errors.CustomError {
+	if !s.active[sessionID] {
+		return errors.Unauthorized("Invalid access token").WithDetails("The session has ended")
+	}
+
+	return nil
+}
+
+func TestJWTProtected(t *testing.T) {
+	jwtManager, err := auth.NewJWTManager(string(jwtSecret))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	activeSession := uuid.New()
+	revokedSession := uuid.New()
+
+	sessionVerifier := &fakeSessionVerifier{active: map[uuid.UUID]bool{activeSession: true, revokedSession: false}}
+
+	app := fiber.New()
+	app.Get("/protected", JWTProtected(sessionVerifier), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	request := func(t *testing.T, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		return res.StatusCode
+	}
+
+	t.Run("Should accept an access token of an active session", func(t *testing.T) {
+		accessToken, _ := jwtManager.GenerateSessionToken(10, activeSession.String(), time.Now().Add(time.Hour))
+
+		if status := request(t, accessToken.Token); status != fiber.StatusOK {
+			t.Errorf("Expected 200, got %d", status)
+		}
+	})
+
+	t.Run("Should reject an access token of a revoked session", func(t *testing.T) {
+		accessToken, _ := jwtManager.GenerateSessionToken(10, revokedSession.String(), time.Now().Add(time.Hour))
+
+		if status := request(t, accessToken.Token); status != fiber.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", status)
+		}
+	})
+
+	t.Run("Should reject an access token of an unknown session", func(t *testing.T) {
+		accessToken, _ := jwtManager.GenerateSessionToken(10, uuid.New().String(), time.Now().Add(time.Hour))
+
+		if status := request(t, accessToken.Token); status != fiber.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", status)
+		}
+	})
+
+	t.Run("Should reject an access token without a session", func(t *testing.T) {
+		accessToken, _ := jwtManager.GenerateToken(10, time.Now().Add(time.Hour))
+
+		if status := request(t, accessToken.Token); status != fiber.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", status)
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// This middleware limits how many requests a client IP can make to a route within a window
+// It is used on public routes that can be abused, such as sending password reset emails
+// The counters are kept in memory, so every instance of the API limits its own requests
+func RateLimitMiddleware(max int, window time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: window,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"message": "Too many requests, please try again later",
+			})
+		},
+	})
+}
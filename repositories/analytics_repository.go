@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"fmt"
+	"senkou-catalyst-be/app/dtos"
+	"senkou-catalyst-be/utils/money"
+
+	"gorm.io/gorm"
+)
+
+type AnalyticsRepository interface {
+	GetRevenueSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error)
+	GetRefundSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error)
+	GetConversionSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error)
+	GetSubscriberSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error)
+}
+
+type AnalyticsRepositoryInstance struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &AnalyticsRepositoryInstance{
+		db: db,
+	}
+}
+
+// Statuses of payments that were paid at some point, refunds never undo the revenue of the payment itself
+const paidPaymentStatuses = "('settled', 'partially_refunded', 'refunded')"
+
+// Statuses of refunds the gateway confirmed, pending and failed refunds never left the merchant
+const settledRefundStatuses = "('partially_refunded', 'refunded')"
+
+// The expressions a payment report can be grouped by
+// Payments are joined as pt, their order as so and the subscription of the order as s
+var paymentGroupColumns = map[string]string{
+	"none":            "'all'",
+	"plan":            "COALESCE(s.name, 'unknown')",
+	"payment_type":    "pt.payment_type",
+	"payment_channel": "pt.payment_channel",
+}
+
+// The expressions a subscriber report can be grouped by
+// Subscribers are joined as us, their subscription as s and the last payment of the subscription as lp
+var subscriberGroupColumns = map[string]string{
+	"none":            "'all'",
+	"plan":            "s.name",
+	"payment_type":    "COALESCE(lp.payment_type, 'unknown')",
+	"payment_channel": "COALESCE(lp.payment_channel, 'unknown')",
+}
+
+// Get the gross revenue of paid payments, bucketed by the time they were paid
+// Only payments in the default currency are counted, amounts are summed in minor units
+func (r *AnalyticsRepositoryInstance) GetRevenueSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	group, err := analyticsGroupColumn(paymentGroupColumns, params.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			date_trunc(?, COALESCE(pt.settled_at, pt.updated_at)) AS bucket,
+			%s AS group_value,
+			COALESCE(SUM(pt.amount), 0) AS gross_revenue_amount
+		FROM payment_transactions pt
+			LEFT JOIN subscription_orders so ON so.payment_transaction_id = pt.id
+			LEFT JOIN subscriptions s ON s.id = so.subscription_id
+		WHERE pt.deleted_at IS NULL
+			AND pt.status IN %s
+			AND pt.currency = ?
+			AND COALESCE(pt.settled_at, pt.updated_at) >= ?
+			AND COALESCE(pt.settled_at, pt.updated_at) < ?
+		GROUP BY 1, 2
+	`, group, paidPaymentStatuses)
+
+	rows := make([]dtos.PaymentAnalyticsRow, 0)
+	if err := r.db.Raw(query, params.Interval, money.DefaultCurrency, params.From, params.To).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// Get the refunded amount, bucketed by the time the refunds were made
+// Only refunds in the default currency are counted, amounts are summed in minor units
+func (r *AnalyticsRepositoryInstance) GetRefundSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	group, err := analyticsGroupColumn(paymentGroupColumns, params.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			date_trunc(?, pr.created_at) AS bucket,
+			%s AS group_value,
+			COALESCE(SUM(pr.amount), 0) AS refunds_amount
+		FROM payment_refunds pr
+			JOIN payment_transactions pt ON pt.id = pr.payment_transaction_id
+			LEFT JOIN subscription_orders so ON so.payment_transaction_id = pt.id
+			LEFT JOIN subscriptions s ON s.id = so.subscription_id
+		WHERE pr.status IN %s AND pr.currency = ? AND pr.created_at >= ? AND pr.created_at < ?
+		GROUP BY 1, 2
+	`, group, settledRefundStatuses)
+
+	rows := make([]dtos.PaymentAnalyticsRow, 0)
+	if err := r.db.Raw(query, params.Interval, money.DefaultCurrency, params.From, params.To).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// Get the created payments and how many of them were eventually paid, bucketed by the time they were created
+func (r *AnalyticsRepositoryInstance) GetConversionSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	group, err := analyticsGroupColumn(paymentGroupColumns, params.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			date_trunc(?, pt.created_at) AS bucket,
+			%s AS group_value,
+			COUNT(*) AS created_payments,
+			COUNT(*) FILTER (WHERE pt.status IN %s) AS converted_payments
+		FROM payment_transactions pt
+			LEFT JOIN subscription_orders so ON so.payment_transaction_id = pt.id
+			LEFT JOIN subscriptions s ON s.id = so.subscription_id
+		WHERE pt.deleted_at IS NULL AND pt.created_at >= ? AND pt.created_at < ?
+		GROUP BY 1, 2
+	`, group, paidPaymentStatuses)
+
+	rows := make([]dtos.PaymentAnalyticsRow, 0)
+	if err := r.db.Raw(query, params.Interval, params.From, params.To).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// Get the paid subscribers of every bucket
+// Only subscriptions with a price count as paid. A subscription ends when it expires or, when it was
+// deactivated early by a plan change or a refund, at its last update
+// A subscriber churns when their subscription ended and no other paid subscription of theirs
+// was started within a day of the end
+// Subscribers are grouped by the payment method of the last payment made for their subscription
+// MRR only counts subscriptions priced in the default currency and is rounded half away from zero to the minor unit
+func (r *AnalyticsRepositoryInstance) GetSubscriberSeries(params *dtos.PaymentAnalyticsQuery) ([]dtos.PaymentAnalyticsRow, error) {
+	group, err := analyticsGroupColumn(subscriberGroupColumns, params.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		WITH buckets AS (
+			SELECT
+				bucket_start,
+				LEAST(bucket_start + CAST(? AS interval), CAST(? AS timestamp), NOW()::timestamp) AS bucket_end
+			FROM generate_series(
+				date_trunc(?, CAST(? AS timestamp)),
+				CAST(? AS timestamp) - INTERVAL '1 second',
+				CAST(? AS interval)
+			) AS bucket_start
+		),
+		subscribers AS (
+			SELECT
+				us.id,
+				us.user_id,
+				us.started_at,
+				CASE WHEN NOT us.is_active AND us.updated_at < us.expired_at THEN us.updated_at ELSE us.expired_at END AS ended_at,
+				CASE WHEN s.price_currency = ? THEN s.price_amount * 30.0 / NULLIF(s.duration, 0) END AS monthly_amount,
+				%s AS group_value
+			FROM user_subscriptions us
+				JOIN subscriptions s ON s.id = us.sub_id
+				LEFT JOIN LATERAL (
+					SELECT pt.payment_type, pt.payment_channel
+					FROM subscription_orders so
+						JOIN payment_transactions pt ON pt.id = so.payment_transaction_id
+					WHERE so.user_id = us.user_id AND so.subscription_id = us.sub_id AND pt.status IN %s
+					ORDER BY COALESCE(pt.settled_at, pt.updated_at) DESC
+					LIMIT 1
+				) lp ON TRUE
+			WHERE s.price_amount > 0 AND us.payment_status IN ('settled', 'refunded')
+		),
+		churns AS (
+			SELECT
+				subscribers.*,
+				subscribers.ended_at <= NOW() AND NOT EXISTS (
+					SELECT 1
+					FROM user_subscriptions nus
+						JOIN subscriptions ns ON ns.id = nus.sub_id
+					WHERE nus.user_id = subscribers.user_id
+						AND nus.id <> subscribers.id
+						AND ns.price_amount > 0
+						AND nus.payment_status IN ('settled', 'refunded')
+						AND nus.started_at <= subscribers.ended_at + INTERVAL '1 day'
+						AND nus.expired_at > subscribers.ended_at
+				) AS churned
+			FROM subscribers
+		)
+		SELECT
+			b.bucket_start AS bucket,
+			c.group_value,
+			COUNT(*) FILTER (WHERE c.started_at <= b.bucket_start AND c.ended_at > b.bucket_start) AS starting_subscribers,
+			COUNT(*) FILTER (WHERE c.started_at <= b.bucket_end AND c.ended_at > b.bucket_end) AS active_subscribers,
+			ROUND(COALESCE(SUM(c.monthly_amount) FILTER (WHERE c.started_at <= b.bucket_end AND c.ended_at > b.bucket_end), 0))::BIGINT AS mrr_amount,
+			COUNT(*) FILTER (WHERE c.churned AND c.ended_at >= b.bucket_start AND c.ended_at < b.bucket_end) AS churned_subscribers
+		FROM buckets b
+			CROSS JOIN churns c
+		WHERE b.bucket_start < b.bucket_end
+		GROUP BY b.bucket_start, c.group_value
+	`, group, paidPaymentStatuses)
+
+	interval := "1 " + params.Interval
+
+	rows := make([]dtos.PaymentAnalyticsRow, 0)
+	if err := r.db.Raw(query, interval, params.To, params.Interval, params.From, params.To, interval, money.DefaultCurrency).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// Get the SQL expression of a group, only known groups are allowed since the expression is put into the query
+func analyticsGroupColumn(columns map[string]string, groupBy string) (string, error) {
+	column, ok := columns[groupBy]
+	if !ok {
+		return "", fmt.Errorf("unsupported analytics group: %s", groupBy)
+	}
+
+	return column, nil
+}
@@ -2,14 +2,24 @@ package repositories
 
 import (
 	"senkou-catalyst-be/app/models"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AuthRepository interface {
-	StoreSession(userID uint32, token string) error
-	FindSessionByToken(token string) (*models.UserHasToken, error)
-	DeleteUserSession(userID uint32) error
+	WithTx(tx *gorm.DB) AuthRepository
+	StoreSession(session *models.UserSession) error
+	UpdateSession(session *models.UserSession) error
+	FindSessionByID(id uuid.UUID) (*models.UserSession, error)
+	FindSessionByIDForUpdate(id uuid.UUID) (*models.UserSession, error)
+	FindActiveSessionsByUserID(userID uint32, now time.Time) ([]*models.UserSession, error)
+	RevokeUserSessions(userID uint32, exceptSessionID uuid.UUID, reason string, revokedAt time.Time) (int64, error)
+	StoreToken(token *models.UserHasToken) error
+	UpdateToken(token *models.UserHasToken) error
+	FindTokenByHashForUpdate(tokenHash string) (*models.UserHasToken, error)
 }
 
 type AuthRepositoryInstance struct {
@@ -20,34 +30,102 @@ func NewAuthRepository(db *gorm.DB) AuthRepository {
 	return &AuthRepositoryInstance{DB: db}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *AuthRepositoryInstance) WithTx(tx *gorm.DB) AuthRepository {
+	return &AuthRepositoryInstance{DB: tx}
+}
+
 // Store a new user session
 // This function will store a new user session in the database
 // It returns an error if the session could not be stored
-func (r *AuthRepositoryInstance) StoreSession(userID uint32, token string) error {
-	session := models.UserHasToken{
-		UserID: userID,
-		Token:  token,
+func (r *AuthRepositoryInstance) StoreSession(session *models.UserSession) error {
+	return r.DB.Create(session).Error
+}
+
+func (r *AuthRepositoryInstance) UpdateSession(session *models.UserSession) error {
+	return r.DB.Omit("CreatedAt").Save(session).Error
+}
+
+// Find a user session by ID
+// It returns the session if found, or an error if not found
+func (r *AuthRepositoryInstance) FindSessionByID(id uuid.UUID) (*models.UserSession, error) {
+	session := new(models.UserSession)
+
+	if err := r.DB.Where("id = ?", id).First(session).Error; err != nil {
+		return nil, err
 	}
 
-	return r.DB.Create(&session).Error
+	return session, nil
 }
 
-// Find a user session by token
-// This function will find a user session by token in the database
+// Find a user session by ID and lock it until the end of the transaction
 // It returns the session if found, or an error if not found
-func (r *AuthRepositoryInstance) FindSessionByToken(token string) (*models.UserHasToken, error) {
-	var session models.UserHasToken
-	err := r.DB.Where("token = ?", token).First(&session).Error
-	if err != nil {
+func (r *AuthRepositoryInstance) FindSessionByIDForUpdate(id uuid.UUID) (*models.UserSession, error) {
+	session := new(models.UserSession)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		First(session).Error; err != nil {
 		return nil, err
 	}
 
-	return &session, nil
+	return session, nil
 }
 
-// Delete a user session by user
-// This function will delete a user session by user in the database
-// It returns an error if the session could not be deleted
-func (r *AuthRepositoryInstance) DeleteUserSession(userID uint32) error {
-	return r.DB.Where("user_id = ?", userID).Delete(&models.UserHasToken{}).Error
+// Find the sessions of a user that are neither revoked nor expired
+// Sessions are sorted from the most recently used
+func (r *AuthRepositoryInstance) FindActiveSessionsByUserID(userID uint32, now time.Time) ([]*models.UserSession, error) {
+	sessions := make([]*models.UserSession, 0)
+
+	if err := r.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Revoke every session of a user that is not revoked yet, except the given one
+// Pass uuid.Nil to revoke all of them
+// It returns the number of sessions revoked
+func (r *AuthRepositoryInstance) RevokeUserSessions(userID uint32, exceptSessionID uuid.UUID, reason string, revokedAt time.Time) (int64, error) {
+	result := r.DB.
+		Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL AND id <> ?", userID, exceptSessionID).
+		Updates(map[string]any{
+			"revoked_at":    revokedAt,
+			"revoke_reason": reason,
+			"updated_at":    revokedAt,
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// Store a new refresh token of a user session
+// It returns an error if the token could not be stored
+func (r *AuthRepositoryInstance) StoreToken(token *models.UserHasToken) error {
+	return r.DB.Omit("User").Create(token).Error
+}
+
+func (r *AuthRepositoryInstance) UpdateToken(token *models.UserHasToken) error {
+	return r.DB.Omit("CreatedAt", "User").Save(token).Error
+}
+
+// Find a refresh token by its hash and lock it until the end of the transaction
+// It returns the token if found, or an error if not found
+func (r *AuthRepositoryInstance) FindTokenByHashForUpdate(tokenHash string) (*models.UserHasToken, error) {
+	token := new(models.UserHasToken)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).
+		First(token).Error; err != nil {
+		return nil, err
+	}
+
+	return token, nil
 }
@@ -8,10 +8,12 @@ import (
 )
 
 type CategoryRepository interface {
+	WithTx(tx *gorm.DB) CategoryRepository
 	StoreCategory(category *models.Category) (*models.Category, error)
 	FindCategoryByName(name string, merchantID string) (*models.Category, error)
 	FindCategoryByID(id string) (*models.Category, error)
 	FindAllCategoriesByMerchantID(merchantID string) ([]*models.Category, error)
+	CountCategoriesByMerchantID(merchantID string) (int64, error)
 	FindAllCategoriesByMerchantUsername(username string) ([]*models.Category, error)
 	FindCategoryByNameAndMerchantUsername(name, username string) (*models.Category, error)
 	UpdateCategory(category *models.Category) (*models.Category, error)
@@ -28,6 +30,14 @@ func NewCategoryRepository(db *gorm.DB) CategoryRepository {
 	}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (c *CategoryRepositoryInstance) WithTx(tx *gorm.DB) CategoryRepository {
+	return &CategoryRepositoryInstance{
+		DB: tx,
+	}
+}
+
 // Store a new category
 // This function requires a category model to be passed in, which contains the detail of the category that will be stored.
 // It returns the stored category or an error if the operation fails.
@@ -73,6 +83,18 @@ func (c *CategoryRepositoryInstance) FindAllCategoriesByMerchantID(merchantID st
 	return categories, nil
 }
 
+// Counting the categories of a merchant
+// It returns the number of categories or an error if the operation fails.
+func (c *CategoryRepositoryInstance) CountCategoriesByMerchantID(merchantID string) (int64, error) {
+	var count int64
+
+	if err := c.DB.Model(&models.Category{}).Where("merchant_id = ?", merchantID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 // Finding all categories by merchant username
 // This function requires the merchant username to be passed in.
 // It returns a slice of categories associated with the merchant or an error if the operation fails.
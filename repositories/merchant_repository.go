@@ -5,11 +5,14 @@ import (
 	"senkou-catalyst-be/app/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MerchantRepository interface {
+	WithTx(tx *gorm.DB) MerchantRepository
 	Create(merchant *models.Merchant) (*models.Merchant, error)
 	FindByUserID(userID uint32) ([]*models.Merchant, error)
+	FindByUserIDForUpdate(userID uint32) ([]*models.Merchant, error)
 	FindByID(merchantID string) (*models.Merchant, error)
 	FindOverview(merchantID string) (*dtos.MerchantOverview, error)
 	FindByUsername(username string) (*models.Merchant, error)
@@ -27,6 +30,14 @@ func NewMerchantRepository(db *gorm.DB) MerchantRepository {
 	}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *MerchantRepositoryInstance) WithTx(tx *gorm.DB) MerchantRepository {
+	return &MerchantRepositoryInstance{
+		DB: tx,
+	}
+}
+
 // Create a new merchant
 // This function creates a new merchant in the database
 // It returns the created merchant or an error if the creation fails
@@ -58,6 +69,25 @@ func (r *MerchantRepositoryInstance) FindByUserID(userID uint32) ([]*models.Merc
 	return merchants, nil
 }
 
+// Find merchants by user ID and lock them for update
+// This function must be called within a transaction, the rows stay locked until the transaction ends
+// Rows are locked in ID order so concurrent callers never deadlock on each other
+// It returns a slice of merchants or an error if the retrieval fails
+func (r *MerchantRepositoryInstance) FindByUserIDForUpdate(userID uint32) ([]*models.Merchant, error) {
+	merchants := make([]*models.Merchant, 0)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("owner_id = ?", userID).
+		Omit("Owner").
+		Order("id").
+		Find(&merchants).Error; err != nil {
+		return nil, err
+	}
+
+	return merchants, nil
+}
+
 // Find a merchant by ID
 // This function retrieves a merchant by its ID
 // It returns the merchant or an error if the retrieval fails
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"senkou-catalyst-be/app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasswordResetRepository interface {
+	WithTx(tx *gorm.DB) PasswordResetRepository
+	Create(token *models.PasswordResetToken) error
+	Update(token *models.PasswordResetToken) error
+	FindByTokenHashForUpdate(tokenHash string) (*models.PasswordResetToken, error)
+	CountCreatedSince(userID uint32, since time.Time) (int64, error)
+	InvalidateUserTokens(userID uint32, usedAt time.Time) error
+}
+
+type PasswordResetRepositoryInstance struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &PasswordResetRepositoryInstance{
+		DB: db,
+	}
+}
+
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *PasswordResetRepositoryInstance) WithTx(tx *gorm.DB) PasswordResetRepository {
+	return &PasswordResetRepositoryInstance{
+		DB: tx,
+	}
+}
+
+func (r *PasswordResetRepositoryInstance) Create(token *models.PasswordResetToken) error {
+	return r.DB.Omit("User").Create(token).Error
+}
+
+func (r *PasswordResetRepositoryInstance) Update(token *models.PasswordResetToken) error {
+	return r.DB.Omit("CreatedAt", "User").Save(token).Error
+}
+
+// Find a password reset token by its hash and lock it until the end of the transaction
+// It returns the token if found, or an error if not found
+func (r *PasswordResetRepositoryInstance) FindByTokenHashForUpdate(tokenHash string) (*models.PasswordResetToken, error) {
+	token := new(models.PasswordResetToken)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).
+		First(token).Error; err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Count the password reset tokens issued to a user since the given time
+func (r *PasswordResetRepositoryInstance) CountCreatedSince(userID uint32, since time.Time) (int64, error) {
+	var count int64
+
+	if err := r.DB.
+		Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Mark every unused password reset token of a user as used
+// Only the latest link sent to the user keeps working after a new one is issued
+func (r *PasswordResetRepositoryInstance) InvalidateUserTokens(userID uint32, usedAt time.Time) error {
+	return r.DB.
+		Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Updates(map[string]any{
+			"used_at":    usedAt,
+			"updated_at": usedAt,
+		}).Error
+}
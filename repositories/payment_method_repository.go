@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"senkou-catalyst-be/app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentMethodRepository interface {
+	FindAll() ([]*models.PaymentMethod, error)
+	FindEnabled() ([]*models.PaymentMethod, error)
+	FindByID(id uint32) (*models.PaymentMethod, error)
+	FindByChannel(channel string) (*models.PaymentMethod, error)
+	StorePaymentMethod(method *models.PaymentMethod) error
+	StoreIfChannelNotExists(method *models.PaymentMethod) error
+	UpdatePaymentMethod(method *models.PaymentMethod) error
+	DeletePaymentMethod(id uint32) error
+	StoreMaintenanceWindow(window *models.PaymentMethodMaintenanceWindow) error
+	DeleteMaintenanceWindow(methodID uint32, windowID uint32) (bool, error)
+}
+
+type PaymentMethodRepositoryInstance struct {
+	DB *gorm.DB
+}
+
+func NewPaymentMethodRepository(db *gorm.DB) PaymentMethodRepository {
+	return &PaymentMethodRepositoryInstance{
+		DB: db,
+	}
+}
+
+// Load the maintenance windows that did not end yet, the past ones are only kept as history
+func preloadUpcomingMaintenanceWindows(db *gorm.DB) *gorm.DB {
+	return db.Preload("MaintenanceWindows", func(db *gorm.DB) *gorm.DB {
+		return db.Where("ends_at > ?", time.Now()).Order("starts_at ASC")
+	})
+}
+
+// Find every payment method, including the disabled ones
+// Payment methods are sorted by their display order
+func (r *PaymentMethodRepositoryInstance) FindAll() ([]*models.PaymentMethod, error) {
+	methods := make([]*models.PaymentMethod, 0)
+
+	if err := preloadUpcomingMaintenanceWindows(r.DB).
+		Order("display_order ASC, id ASC").
+		Find(&methods).Error; err != nil {
+		return nil, err
+	}
+
+	return methods, nil
+}
+
+// Find the enabled payment methods sorted by their display order
+// Methods under maintenance are returned as well, along with their maintenance windows
+func (r *PaymentMethodRepositoryInstance) FindEnabled() ([]*models.PaymentMethod, error) {
+	methods := make([]*models.PaymentMethod, 0)
+
+	if err := preloadUpcomingMaintenanceWindows(r.DB).
+		Where("is_enabled = ?", true).
+		Order("display_order ASC, id ASC").
+		Find(&methods).Error; err != nil {
+		return nil, err
+	}
+
+	return methods, nil
+}
+
+func (r *PaymentMethodRepositoryInstance) FindByID(id uint32) (*models.PaymentMethod, error) {
+	method := new(models.PaymentMethod)
+
+	if err := preloadUpcomingMaintenanceWindows(r.DB).
+		Where("id = ?", id).
+		First(method).Error; err != nil {
+		return nil, err
+	}
+
+	return method, nil
+}
+
+func (r *PaymentMethodRepositoryInstance) FindByChannel(channel string) (*models.PaymentMethod, error) {
+	method := new(models.PaymentMethod)
+
+	if err := preloadUpcomingMaintenanceWindows(r.DB).
+		Where("payment_channel = ?", channel).
+		First(method).Error; err != nil {
+		return nil, err
+	}
+
+	return method, nil
+}
+
+func (r *PaymentMethodRepositoryInstance) StorePaymentMethod(method *models.PaymentMethod) error {
+	return r.DB.Omit("MaintenanceWindows").Create(method).Error
+}
+
+// Store a payment method unless its channel already exists
+// This is used to seed the default payment methods without overriding the changes made by admins
+func (r *PaymentMethodRepositoryInstance) StoreIfChannelNotExists(method *models.PaymentMethod) error {
+	return r.DB.
+		Omit("MaintenanceWindows").
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "payment_channel"}}, DoNothing: true}).
+		Create(method).Error
+}
+
+// Update every column of a payment method
+// Zero values are stored as well, so a payment method can be disabled or its fee removed
+func (r *PaymentMethodRepositoryInstance) UpdatePaymentMethod(method *models.PaymentMethod) error {
+	return r.DB.Omit("MaintenanceWindows", "CreatedAt").Save(method).Error
+}
+
+// Delete a payment method along with its maintenance windows
+func (r *PaymentMethodRepositoryInstance) DeletePaymentMethod(id uint32) error {
+	return r.DB.Delete(&models.PaymentMethod{}, id).Error
+}
+
+func (r *PaymentMethodRepositoryInstance) StoreMaintenanceWindow(window *models.PaymentMethodMaintenanceWindow) error {
+	return r.DB.Create(window).Error
+}
+
+// Delete a maintenance window of a payment method
+// It returns false if the payment method has no such maintenance window
+func (r *PaymentMethodRepositoryInstance) DeleteMaintenanceWindow(methodID uint32, windowID uint32) (bool, error) {
+	result := r.DB.
+		Where("id = ? AND payment_method_id = ?", windowID, methodID).
+		Delete(&models.PaymentMethodMaintenanceWindow{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
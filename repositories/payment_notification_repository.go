@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"senkou-catalyst-be/app/models"
+
+	"gorm.io/gorm"
+)
+
+type PaymentNotificationRepository interface {
+	StoreNotification(notification *models.PaymentNotification) error
+	FindByID(notificationID string) (*models.PaymentNotification, error)
+	FindByPaymentTransactionID(paymentTransactionID string) ([]*models.PaymentNotification, error)
+	IsProcessed(dedupeKey string) (bool, error)
+	Update(notification *models.PaymentNotification) error
+}
+
+type PaymentNotificationRepositoryInstance struct {
+	DB *gorm.DB
+}
+
+func NewPaymentNotificationRepository(db *gorm.DB) PaymentNotificationRepository {
+	return &PaymentNotificationRepositoryInstance{
+		DB: db,
+	}
+}
+
+// Store a new raw payment notification
+func (r *PaymentNotificationRepositoryInstance) StoreNotification(notification *models.PaymentNotification) error {
+	if err := r.DB.Create(notification).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Find a payment notification by its ID
+func (r *PaymentNotificationRepositoryInstance) FindByID(notificationID string) (*models.PaymentNotification, error) {
+	notification := new(models.PaymentNotification)
+
+	if err := r.DB.Where("id = ?", notificationID).First(notification).Error; err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+// Find every notification received for a payment transaction
+// The oldest notifications are returned first
+func (r *PaymentNotificationRepositoryInstance) FindByPaymentTransactionID(paymentTransactionID string) ([]*models.PaymentNotification, error) {
+	notifications := make([]*models.PaymentNotification, 0)
+
+	if err := r.DB.
+		Where("payment_transaction_id = ?", paymentTransactionID).
+		Order("created_at ASC").
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// Check whether a notification with the same dedupe key was already applied or ignored
+// Notifications that failed or were rejected do not count, their retries must still be processed
+func (r *PaymentNotificationRepositoryInstance) IsProcessed(dedupeKey string) (bool, error) {
+	var count int64
+
+	if err := r.DB.
+		Model(&models.PaymentNotification{}).
+		Where("dedupe_key = ?", dedupeKey).
+		Where("status IN ?", []string{
+			models.PaymentNotificationStatusProcessed,
+			models.PaymentNotificationStatusIgnored,
+		}).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Update the processing outcome of a payment notification
+func (r *PaymentNotificationRepositoryInstance) Update(notification *models.PaymentNotification) error {
+	if err := r.DB.Save(notification).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/money"
+
+	"gorm.io/gorm"
+)
+
+type PaymentRefundRepository interface {
+	WithTx(tx *gorm.DB) PaymentRefundRepository
+	StoreRefund(refund *models.PaymentRefund) error
+	UpdateRefund(refund *models.PaymentRefund) error
+	FindByPaymentTransactionID(paymentTransactionID string) ([]*models.PaymentRefund, error)
+	SumRefundedAmount(paymentTransactionID string, currency string) (money.Money, error)
+	SumPendingRefundAmount(paymentTransactionID string, currency string) (money.Money, error)
+}
+
+// Statuses of refunds the gateway has not confirmed, pending refunds reserve their amount until it answers
+const (
+	refundStatusPending = "pending"
+	refundStatusFailed  = "failed"
+)
+
+type PaymentRefundRepositoryInstance struct {
+	DB *gorm.DB
+}
+
+func NewPaymentRefundRepository(db *gorm.DB) PaymentRefundRepository {
+	return &PaymentRefundRepositoryInstance{
+		DB: db,
+	}
+}
+
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *PaymentRefundRepositoryInstance) WithTx(tx *gorm.DB) PaymentRefundRepository {
+	return &PaymentRefundRepositoryInstance{
+		DB: tx,
+	}
+}
+
+// Store a new refund of a payment transaction
+func (r *PaymentRefundRepositoryInstance) StoreRefund(refund *models.PaymentRefund) error {
+	if err := r.DB.Create(refund).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Update a refund of a payment transaction
+func (r *PaymentRefundRepositoryInstance) UpdateRefund(refund *models.PaymentRefund) error {
+	if err := r.DB.Save(refund).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Find every refund of a payment transaction
+// The oldest refunds are returned first
+func (r *PaymentRefundRepositoryInstance) FindByPaymentTransactionID(paymentTransactionID string) ([]*models.PaymentRefund, error) {
+	refunds := make([]*models.PaymentRefund, 0)
+
+	if err := r.DB.
+		Where("payment_transaction_id = ?", paymentTransactionID).
+		Order("created_at ASC").
+		Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// Sum the amount already refunded for a payment transaction
+// Only refunds confirmed by the gateway are counted, refunds are always made in the currency of their transaction
+func (r *PaymentRefundRepositoryInstance) SumRefundedAmount(paymentTransactionID string, currency string) (money.Money, error) {
+	var amount int64
+
+	if err := r.DB.
+		Model(&models.PaymentRefund{}).
+		Where("payment_transaction_id = ? AND status NOT IN ?", paymentTransactionID, []string{refundStatusPending, refundStatusFailed}).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&amount).Error; err != nil {
+		return money.Money{}, err
+	}
+
+	return money.New(amount, currency), nil
+}
+
+// Sum the amount of the refunds of a payment transaction that are still waiting for the gateway
+// Refunds are always made in the currency of their transaction
+func (r *PaymentRefundRepositoryInstance) SumPendingRefundAmount(paymentTransactionID string, currency string) (money.Money, error) {
+	var amount int64
+
+	if err := r.DB.
+		Model(&models.PaymentRefund{}).
+		Where("payment_transaction_id = ? AND status = ?", paymentTransactionID, refundStatusPending).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&amount).Error; err != nil {
+		return money.Money{}, err
+	}
+
+	return money.New(amount, currency), nil
+}
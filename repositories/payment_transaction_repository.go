@@ -2,14 +2,20 @@ package repositories
 
 import (
 	"senkou-catalyst-be/app/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentTransactionRepository interface {
+	WithTx(tx *gorm.DB) PaymentTransactionRepository
 	CreateTransaction(transaction *models.PaymentTransaction) error
 	FindByID(transactionID string) (*models.PaymentTransaction, error)
+	FindByOrderID(orderID string) (*models.PaymentTransaction, error)
+	FindByOrderIDForUpdate(orderID string) (*models.PaymentTransaction, error)
 	Update(transaction *models.PaymentTransaction) error
+	FindPendingTransactions(createdBefore time.Time, expiredBefore time.Time, limit int) ([]*models.PaymentTransaction, error)
 }
 
 type PaymentTransactionRepositoryInstance struct {
@@ -22,6 +28,14 @@ func NewPaymentTransactionRepository(db *gorm.DB) PaymentTransactionRepository {
 	}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *PaymentTransactionRepositoryInstance) WithTx(tx *gorm.DB) PaymentTransactionRepository {
+	return &PaymentTransactionRepositoryInstance{
+		DB: tx,
+	}
+}
+
 func (r *PaymentTransactionRepositoryInstance) CreateTransaction(transaction *models.PaymentTransaction) error {
 	if err := r.DB.Create(transaction).Error; err != nil {
 		return err
@@ -39,6 +53,32 @@ func (r *PaymentTransactionRepositoryInstance) FindByID(transactionID string) (*
 	return &transaction, nil
 }
 
+// Find a payment transaction by its order ID
+// The order ID sent to the payment gateway is the payment transaction primary key
+func (r *PaymentTransactionRepositoryInstance) FindByOrderID(orderID string) (*models.PaymentTransaction, error) {
+	var transaction models.PaymentTransaction
+	if err := r.DB.Where("id = ?", orderID).First(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// Find a payment transaction by its order ID and lock the row
+// The order ID sent to the payment gateway is the payment transaction primary key
+// The row stays locked until the surrounding database transaction ends
+func (r *PaymentTransactionRepositoryInstance) FindByOrderIDForUpdate(orderID string) (*models.PaymentTransaction, error) {
+	var transaction models.PaymentTransaction
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", orderID).
+		First(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
 func (r *PaymentTransactionRepositoryInstance) Update(transaction *models.PaymentTransaction) error {
 	if err := r.DB.Save(transaction).Error; err != nil {
 		return err
@@ -46,3 +86,21 @@ func (r *PaymentTransactionRepositoryInstance) Update(transaction *models.Paymen
 
 	return nil
 }
+
+// Find pending transactions that need to be reconciled
+// This function retrieves pending transactions created before createdBefore or already expired before expiredBefore
+// The oldest transactions are returned first, up to limit rows
+func (r *PaymentTransactionRepositoryInstance) FindPendingTransactions(createdBefore time.Time, expiredBefore time.Time, limit int) ([]*models.PaymentTransaction, error) {
+	transactions := make([]*models.PaymentTransaction, 0)
+
+	if err := r.DB.
+		Where("status = ?", "pending").
+		Where("created_at < ? OR (expired_at IS NOT NULL AND expired_at < ?)", createdBefore, expiredBefore).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
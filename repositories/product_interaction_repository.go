@@ -14,7 +14,7 @@ type ProductInteractionRepository interface {
 	StoreProductInteractionLog(interaction *models.ProductMetric) error
 	GetMerchantProductsMetric(merchantID string, params *query.QueryParams) ([]dtos.ProductReport, error)
 	GetMerchantProductsMetricStats(merchantID string, params *query.QueryParams) (*dtos.ProductMetricStats, error)
-	GetPopularProductsByMerchant(merchantID string) ([]*models.Product, error)
+	GetPopularProductsByMerchant(username string, publishLimit int64) ([]*models.Product, error)
 }
 
 type ProductInteractionRepositoryInstance struct {
@@ -94,7 +94,8 @@ func (r *ProductInteractionRepositoryInstance) GetMerchantProductsMetricStats(me
 	return productMetricStats, nil
 }
 
-func (r *ProductInteractionRepositoryInstance) GetPopularProductsByMerchant(merchantID string) ([]*models.Product, error) {
+func (r *ProductInteractionRepositoryInstance) GetPopularProductsByMerchant(username string, publishLimit int64) ([]*models.Product, error) {
+	published, args := publishedProducts("p", publishLimit)
 
 	query := `
 		SELECT
@@ -104,7 +105,7 @@ func (r *ProductInteractionRepositoryInstance) GetPopularProductsByMerchant(merc
 		FROM products p
 			LEFT JOIN product_metrics pm ON pm.product_id = p.id
 			LEFT JOIN merchants m ON m.id = p.merchant_id
-		WHERE m.username = ?
+		WHERE m.username = ? AND ` + published + `
 		GROUP BY p.id, p.title
 		ORDER BY total_clicks DESC, total_views DESC
 		LIMIT 10;
@@ -112,7 +113,7 @@ func (r *ProductInteractionRepositoryInstance) GetPopularProductsByMerchant(merc
 
 	products := make([]*models.Product, 0)
 
-	if err := r.db.Raw(query, merchantID).Scan(&products).Error; err != nil {
+	if err := r.db.Raw(query, append([]any{username}, args...)...).Scan(&products).Error; err != nil {
 		return nil, err
 	}
 
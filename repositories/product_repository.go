@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"errors"
+	"fmt"
 	"senkou-catalyst-be/app/models"
 	"senkou-catalyst-be/utils/query"
 
@@ -9,17 +10,46 @@ import (
 )
 
 type ProductRepository interface {
+	WithTx(tx *gorm.DB) ProductRepository
 	StoreProduct(product *models.Product) (*models.Product, error)
 	FindProductByID(productID string) (*models.Product, error)
 	FindProductsByMerchantID(merchantID string) ([]*models.Product, error)
-	FindProductsByMerchantUsername(username string) ([]*models.Product, error)
+	CountProductsByMerchantID(merchantID string) (int64, error)
+	FindProductsByMerchantUsername(username string, publishLimit int64) ([]*models.Product, error)
+	FindPublishedProductByID(productID string, publishLimit int64) (*models.Product, error)
+	FindMaxPhotosByMerchantID(merchantID string) (int64, error)
 	FindAllProducts(params *query.QueryParams) ([]*models.Product, int64, error)
 	FindMerchantByProductID(productID string) (*models.Merchant, error)
-	FindRecentProducts(username string) ([]*models.Product, error)
+	FindRecentProducts(username string, publishLimit int64) ([]*models.Product, error)
 	UpdateProduct(updatedProduct *models.Product) (*models.Product, error)
 	DeleteProduct(productID string) error
 }
 
+// Products beyond the product slot quota of the owner of their merchant are hidden from the storefront instead of deleted.
+// The quota is shared by every merchant of the owner, so products are ranked across all of them.
+// The oldest products stay published, so an upgrade publishes the hidden products again
+const publishedProductCondition = `%[1]s.id IN (
+	SELECT ranked.id
+	FROM (
+		SELECT published.id, ROW_NUMBER() OVER (PARTITION BY sibling.owner_id ORDER BY published.created_at, published.id) AS position
+		FROM products published
+		JOIN merchants sibling ON sibling.id = published.merchant_id AND sibling.deleted_at IS NULL
+		JOIN merchants merchant ON merchant.owner_id = sibling.owner_id
+		WHERE merchant.id = %[1]s.merchant_id AND published.deleted_at IS NULL
+	) ranked
+	WHERE ranked.position <= ?
+)`
+
+// Build the condition keeping the products of the table aliased as alias that are published within publishLimit
+// A negative publishLimit publishes every product
+func publishedProducts(alias string, publishLimit int64) (string, []any) {
+	if publishLimit < 0 {
+		return "TRUE", nil
+	}
+
+	return fmt.Sprintf(publishedProductCondition, alias), []any{publishLimit}
+}
+
 type ProductRepositoryInstance struct {
 	DB *gorm.DB
 }
@@ -30,6 +60,14 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *ProductRepositoryInstance) WithTx(tx *gorm.DB) ProductRepository {
+	return &ProductRepositoryInstance{
+		DB: tx,
+	}
+}
+
 // Store a new product
 // This function will be used to store a new product in the database
 // It takes a product model and a merchant ID as parameters
@@ -70,17 +108,60 @@ func (r *ProductRepositoryInstance) FindProductsByMerchantID(merchantID string)
 	return products, nil
 }
 
+// Count the products of a merchant
+// It returns the number of products and an error if any
+func (r *ProductRepositoryInstance) CountProductsByMerchantID(merchantID string) (int64, error) {
+	var count int64
+
+	if err := r.DB.Model(&models.Product{}).Where("merchant_id = ?", merchantID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Get a published product by its ID
+// This function retrieves a product by its ID unless it is hidden beyond the product slot quota shared by the merchants of its owner
+// It returns the product and an error if any
+func (r *ProductRepositoryInstance) FindPublishedProductByID(productID string, publishLimit int64) (*models.Product, error) {
+	product := new(models.Product)
+	published, args := publishedProducts("products", publishLimit)
+
+	if err := r.DB.Where("id = ?", productID).Where(published, args...).First(product).Error; err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// Find the largest number of photos of a product of a merchant
+// It returns the number of photos and an error if any
+func (r *ProductRepositoryInstance) FindMaxPhotosByMerchantID(merchantID string) (int64, error) {
+	var count int64
+
+	if err := r.DB.Model(&models.Product{}).
+		Select("COALESCE(MAX(json_array_length(photos)), 0)").
+		Where("merchant_id = ?", merchantID).
+		Scan(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 // Get products by merchant username
-// This function retrieves all products associated with a specific merchant username
-// It takes a merchant username as a parameter
+// This function retrieves the published products associated with a specific merchant username
+// It takes a merchant username and the number of products the merchant can publish as parameters
 // It returns a slice of products and an error if any
-func (r *ProductRepositoryInstance) FindProductsByMerchantUsername(username string) ([]*models.Product, error) {
+func (r *ProductRepositoryInstance) FindProductsByMerchantUsername(username string, publishLimit int64) ([]*models.Product, error) {
 	products := make([]*models.Product, 0)
+	published, args := publishedProducts("products", publishLimit)
 
 	if err := r.DB.Table("products").
 		Select("products.*").
 		Joins("JOIN merchants ON products.merchant_id = merchants.id").
 		Where("merchants.username = ?", username).
+		Where(published, args...).
 		Find(&products).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
@@ -101,7 +182,7 @@ func (r *ProductRepositoryInstance) FindAllProducts(params *query.QueryParams) (
 	queryBuilder := query.NewQueryBuilder(r.DB.Model(&models.Product{})).
 		SetAllowedSorts(map[string]string{
 			"title":      "title",
-			"price":      "price",
+			"price":      "price_amount",
 			"created_at": "created_at",
 		}).
 		SetSearchFields([]string{"title"})
@@ -138,19 +219,22 @@ func (r *ProductRepositoryInstance) FindMerchantByProductID(productID string) (*
 	return merchant, nil
 }
 
-func (r *ProductRepositoryInstance) FindRecentProducts(username string) ([]*models.Product, error) {
+// Get the most recent published products of a merchant
+// It returns a slice of products and an error if any
+func (r *ProductRepositoryInstance) FindRecentProducts(username string, publishLimit int64) ([]*models.Product, error) {
 	products := make([]*models.Product, 0)
+	published, args := publishedProducts("p", publishLimit)
 
 	query := `
 		SELECT p.*
 		FROM products p
 		JOIN merchants m ON p.merchant_id = m.id
-		WHERE m.username = ?
+		WHERE m.username = ? AND ` + published + `
 		ORDER BY p.created_at DESC
 		LIMIT 10
 	`
 
-	if err := r.DB.Raw(query, username).Scan(&products).Error; err != nil {
+	if err := r.DB.Raw(query, append([]any{username}, args...)...).Scan(&products).Error; err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Open the database given by TEST_DATABASE_DSN within a transaction that is rolled back after the test
+// The tables the test needs are created as temporary tables, so the schema of the database is left untouched
+func newTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+
+	for _, statement := range []string{
+		`CREATE TEMPORARY TABLE merchants (id char(16) PRIMARY KEY, owner_id int, deleted_at timestamp)`,
+		`CREATE TEMPORARY TABLE products (id uuid PRIMARY KEY, merchant_id char(16) NOT NULL, title text, created_at timestamp, deleted_at timestamp)`,
+	} {
+		if err := tx.Exec(statement).Error; err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	return tx
+}
+
+func TestFindPublishedProductByID(t *testing.T) {
+	t.Run("Should share the product slots of an owner across their merchants", func(t *testing.T) {
+		db := newTestDB(t)
+		repository := NewProductRepository(db)
+
+		createdAt := time.Now().AddDate(0, 0, -10)
+		for _, statement := range []struct {
+			query string
+			args  []any
+		}{
+			{`INSERT INTO merchants (id, owner_id) VALUES (?, ?), (?, ?), (?, ?)`, []any{"merchant-shop-01", 10, "merchant-shop-02", 10, "merchant-other-1", 20}},
+			{`INSERT INTO products (id, merchant_id, created_at) VALUES (?, ?, ?), (?, ?, ?), (?, ?, ?), (?, ?, ?)`, []any{
+				"00000000-0000-0000-0000-000000000001", "merchant-shop-01", createdAt,
+				"00000000-0000-0000-0000-000000000002", "merchant-shop-02", createdAt.Add(time.Hour),
+				"00000000-0000-0000-0000-000000000003", "merchant-shop-01", createdAt.Add(2 * time.Hour),
+				"00000000-0000-0000-0000-000000000004", "merchant-other-1", createdAt.Add(3 * time.Hour),
+			}},
+		} {
+			if err := db.Exec(statement.query, statement.args...).Error; err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		for _, productID := range []string{
+			"00000000-0000-0000-0000-000000000001",
+			"00000000-0000-0000-0000-000000000002",
+			"00000000-0000-0000-0000-000000000004",
+		} {
+			if _, err := repository.FindPublishedProductByID(productID, 2); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}
+
+		// The second product of the first merchant is the third product of the owner
+		if _, err := repository.FindPublishedProductByID("00000000-0000-0000-0000-000000000003", 2); err != gorm.ErrRecordNotFound {
+			t.Errorf("Expected %v, got %v", gorm.ErrRecordNotFound, err)
+		}
+	})
+}
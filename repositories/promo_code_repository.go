@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"senkou-catalyst-be/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PromoCodeRepository interface {
+	WithTx(tx *gorm.DB) PromoCodeRepository
+	FindAll() ([]*models.PromoCode, error)
+	FindByID(id uint32) (*models.PromoCode, error)
+	FindByIDForUpdate(id uint32) (*models.PromoCode, error)
+	FindByCode(code string) (*models.PromoCode, error)
+	StorePromoCode(promoCode *models.PromoCode) error
+	UpdatePromoCode(promoCode *models.PromoCode) error
+	DeletePromoCode(id uint32) error
+	CountRedemptions(promoCodeID uint32) (int64, error)
+	CountUserRedemptions(promoCodeID uint32, userID uint32) (int64, error)
+}
+
+type PromoCodeRepositoryInstance struct {
+	DB *gorm.DB
+}
+
+func NewPromoCodeRepository(db *gorm.DB) PromoCodeRepository {
+	return &PromoCodeRepositoryInstance{
+		DB: db,
+	}
+}
+
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *PromoCodeRepositoryInstance) WithTx(tx *gorm.DB) PromoCodeRepository {
+	return &PromoCodeRepositoryInstance{
+		DB: tx,
+	}
+}
+
+// Find every promo code, including the inactive ones
+// Promo codes are sorted from the newest
+func (r *PromoCodeRepositoryInstance) FindAll() ([]*models.PromoCode, error) {
+	promoCodes := make([]*models.PromoCode, 0)
+
+	if err := r.DB.Order("id DESC").Find(&promoCodes).Error; err != nil {
+		return nil, err
+	}
+
+	return promoCodes, nil
+}
+
+func (r *PromoCodeRepositoryInstance) FindByID(id uint32) (*models.PromoCode, error) {
+	promoCode := new(models.PromoCode)
+
+	if err := r.DB.Where("id = ?", id).First(promoCode).Error; err != nil {
+		return nil, err
+	}
+
+	return promoCode, nil
+}
+
+// Find a promo code and lock it until the end of the transaction
+// Redemptions of the same code are serialized, so its limits cannot be exceeded by concurrent orders
+func (r *PromoCodeRepositoryInstance) FindByIDForUpdate(id uint32) (*models.PromoCode, error) {
+	promoCode := new(models.PromoCode)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		First(promoCode).Error; err != nil {
+		return nil, err
+	}
+
+	return promoCode, nil
+}
+
+// Find a promo code by its code
+// Codes are stored in upper case, so the given code must be normalized first
+func (r *PromoCodeRepositoryInstance) FindByCode(code string) (*models.PromoCode, error) {
+	promoCode := new(models.PromoCode)
+
+	if err := r.DB.Where("code = ?", code).First(promoCode).Error; err != nil {
+		return nil, err
+	}
+
+	return promoCode, nil
+}
+
+func (r *PromoCodeRepositoryInstance) StorePromoCode(promoCode *models.PromoCode) error {
+	return r.DB.Create(promoCode).Error
+}
+
+// Update every column of a promo code
+// Zero values are stored as well, so a promo code can be deactivated or its limits removed
+func (r *PromoCodeRepositoryInstance) UpdatePromoCode(promoCode *models.PromoCode) error {
+	return r.DB.Omit("CreatedAt").Save(promoCode).Error
+}
+
+// Soft delete a promo code
+// The orders that redeemed it keep referring to it
+func (r *PromoCodeRepositoryInstance) DeletePromoCode(id uint32) error {
+	return r.DB.Delete(&models.PromoCode{}, id).Error
+}
+
+// Count the redemptions of a promo code
+// An order that is pending or was paid counts as a redemption, a failed order does not
+func (r *PromoCodeRepositoryInstance) CountRedemptions(promoCodeID uint32) (int64, error) {
+	var count int64
+
+	if err := r.DB.
+		Model(&models.SubscriptionOrder{}).
+		Where("promo_code_id = ? AND status IN ?", promoCodeID, redeemedOrderStatuses).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Count the redemptions of a promo code by a user
+func (r *PromoCodeRepositoryInstance) CountUserRedemptions(promoCodeID uint32, userID uint32) (int64, error) {
+	var count int64
+
+	if err := r.DB.
+		Model(&models.SubscriptionOrder{}).
+		Where("promo_code_id = ? AND user_id = ? AND status IN ?", promoCodeID, userID, redeemedOrderStatuses).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// The statuses of the orders that used up a redemption of their promo code
+var redeemedOrderStatuses = []string{"pending", "settled", "refunded", "partially_refunded"}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"senkou-catalyst-be/app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReferralRepository interface {
+	WithTx(tx *gorm.DB) ReferralRepository
+	StoreReferral(referral *models.Referral) error
+	UpdateReferral(referral *models.Referral) error
+	FindPendingByReferredIDForUpdate(referredID uint32) (*models.Referral, error)
+	FindByReferrerID(referrerID uint32) ([]*models.Referral, error)
+	CountRewardedSince(referrerID uint32, since time.Time) (int64, error)
+}
+
+type ReferralRepositoryInstance struct {
+	DB *gorm.DB
+}
+
+func NewReferralRepository(db *gorm.DB) ReferralRepository {
+	return &ReferralRepositoryInstance{
+		DB: db,
+	}
+}
+
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *ReferralRepositoryInstance) WithTx(tx *gorm.DB) ReferralRepository {
+	return &ReferralRepositoryInstance{
+		DB: tx,
+	}
+}
+
+func (r *ReferralRepositoryInstance) StoreReferral(referral *models.Referral) error {
+	return r.DB.Create(referral).Error
+}
+
+func (r *ReferralRepositoryInstance) UpdateReferral(referral *models.Referral) error {
+	return r.DB.Omit("CreatedAt", "Referrer", "Referred").Save(referral).Error
+}
+
+// Find the pending referral of a referred user and lock it until the end of the transaction
+// The referrer is preloaded, it is left empty when the referrer was deleted
+func (r *ReferralRepositoryInstance) FindPendingByReferredIDForUpdate(referredID uint32) (*models.Referral, error) {
+	referral := new(models.Referral)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("referred_id = ? AND status = ?", referredID, models.ReferralStatusPending).
+		First(referral).Error; err != nil {
+		return nil, err
+	}
+
+	referrer := new(models.User)
+	if err := r.DB.Where("id = ?", referral.ReferrerID).First(referrer).Error; err == nil {
+		referral.Referrer = referrer
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return referral, nil
+}
+
+// Find the referrals of a referrer along with the referred users
+// Referrals are sorted from the newest
+func (r *ReferralRepositoryInstance) FindByReferrerID(referrerID uint32) ([]*models.Referral, error) {
+	referrals := make([]*models.Referral, 0)
+
+	if err := r.DB.
+		Preload("Referred").
+		Where("referrer_id = ?", referrerID).
+		Order("created_at DESC").
+		Find(&referrals).Error; err != nil {
+		return nil, err
+	}
+
+	return referrals, nil
+}
+
+// Count the referrals of a referrer rewarded since the given time
+func (r *ReferralRepositoryInstance) CountRewardedSince(referrerID uint32, since time.Time) (int64, error) {
+	var count int64
+
+	if err := r.DB.
+		Model(&models.Referral{}).
+		Where("referrer_id = ? AND status = ? AND rewarded_at >= ?", referrerID, models.ReferralStatusRewarded, since).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -2,15 +2,23 @@ package repositories
 
 import (
 	"senkou-catalyst-be/app/models"
+	"senkou-catalyst-be/utils/query"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
 type SubscriptionOrderRepository interface {
+	WithTx(tx *gorm.DB) SubscriptionOrderRepository
 	StoreNewSubscriptionOrder(order *models.SubscriptionOrder) error
-	FindOrderByUserAndSubscription(userID uint32, subID uint32) (*models.SubscriptionOrder, error)
+	FindPendingOrderByUser(userID uint32) (*models.SubscriptionOrder, error)
 	FindByOrderID(orderID string) (*models.SubscriptionOrder, error)
+	FindByPaymentTransactionID(paymentTransactionID string) (*models.SubscriptionOrder, error)
 	UpdateOrderTransaction(orderID string, order *models.SubscriptionOrder) error
+	FindUserOrders(userID uint32, params *query.QueryParams) ([]*models.SubscriptionOrder, int64, error)
+	FindUserOrder(userID uint32, orderID string) (*models.SubscriptionOrder, error)
+	FindInvoiceOrder(orderID string) (*models.SubscriptionOrder, error)
+	HasPaidOrder(userID uint32) (bool, error)
 }
 
 type SubscriptionOrderRepositoryInstance struct {
@@ -23,6 +31,14 @@ func NewSubscriptionOrderRepository(db *gorm.DB) SubscriptionOrderRepository {
 	}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *SubscriptionOrderRepositoryInstance) WithTx(tx *gorm.DB) SubscriptionOrderRepository {
+	return &SubscriptionOrderRepositoryInstance{
+		DB: tx,
+	}
+}
+
 // Store a new subscription order
 func (r *SubscriptionOrderRepositoryInstance) StoreNewSubscriptionOrder(order *models.SubscriptionOrder) error {
 	if err := r.DB.Create(order).Error; err != nil {
@@ -32,10 +48,14 @@ func (r *SubscriptionOrderRepositoryInstance) StoreNewSubscriptionOrder(order *m
 	return nil
 }
 
-func (r *SubscriptionOrderRepositoryInstance) FindOrderByUserAndSubscription(userID uint32, subID uint32) (*models.SubscriptionOrder, error) {
+// Find the latest pending order of a user
+func (r *SubscriptionOrderRepositoryInstance) FindPendingOrderByUser(userID uint32) (*models.SubscriptionOrder, error) {
 	order := new(models.SubscriptionOrder)
 
-	if err := r.DB.Where("user_id = ? AND subscription_id = ?", userID, subID).First(order).Error; err != nil {
+	if err := r.DB.
+		Where("user_id = ? AND status = ?", userID, "pending").
+		Order("created_at DESC").
+		First(order).Error; err != nil {
 		return nil, err
 	}
 
@@ -45,7 +65,22 @@ func (r *SubscriptionOrderRepositoryInstance) FindOrderByUserAndSubscription(use
 func (r *SubscriptionOrderRepositoryInstance) FindByOrderID(orderID string) (*models.SubscriptionOrder, error) {
 	order := new(models.SubscriptionOrder)
 
-	if err := r.DB.Where("order_id = ?", orderID).First(order).Error; err != nil {
+	if err := r.DB.Where("id = ?", orderID).First(order).Error; err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Find a subscription order by its payment transaction
+// The subscription is preloaded so the caller can activate it right away
+func (r *SubscriptionOrderRepositoryInstance) FindByPaymentTransactionID(paymentTransactionID string) (*models.SubscriptionOrder, error) {
+	order := new(models.SubscriptionOrder)
+
+	if err := r.DB.
+		Preload("Subscription").
+		Where("payment_transaction_id = ?", paymentTransactionID).
+		First(order).Error; err != nil {
 		return nil, err
 	}
 
@@ -53,9 +88,123 @@ func (r *SubscriptionOrderRepositoryInstance) FindByOrderID(orderID string) (*mo
 }
 
 func (r *SubscriptionOrderRepositoryInstance) UpdateOrderTransaction(orderID string, order *models.SubscriptionOrder) error {
-	if err := r.DB.Where("id = ?", orderID).Updates(order).Error; err != nil {
+	if err := r.DB.Model(&models.SubscriptionOrder{}).Where("id = ?", orderID).Updates(order).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Find the orders of a user along with their payment transaction
+// Orders can be filtered by status, payment_type and payment_status and by their creation date
+// It returns the orders of the requested page and the total number of matching orders
+func (r *SubscriptionOrderRepositoryInstance) FindUserOrders(userID uint32, params *query.QueryParams) ([]*models.SubscriptionOrder, int64, error) {
+	orders := make([]*models.SubscriptionOrder, 0)
+	var total int64
+
+	// Only known filters are forwarded, the query builder uses filter keys as column names
+	allowedFilters := map[string]string{
+		"status":         "subscription_orders.status",
+		"payment_type":   "payment_transactions.payment_type",
+		"payment_status": "payment_transactions.status",
+	}
+
+	scopedParams := *params
+	scopedParams.Search = ""
+	scopedParams.Filters = make(map[string]string)
+	for key, column := range allowedFilters {
+		if value := params.Filters[key]; value != "" {
+			scopedParams.Filters[column] = value
+		}
+	}
+
+	if strings.ToLower(scopedParams.Order) != "asc" {
+		scopedParams.Order = "desc"
+	}
+
+	baseQuery := r.DB.
+		Model(&models.SubscriptionOrder{}).
+		Joins("LEFT JOIN payment_transactions ON payment_transactions.id = subscription_orders.payment_transaction_id").
+		Where("subscription_orders.user_id = ?", userID)
+
+	if params.DateFrom != nil {
+		baseQuery = baseQuery.Where("subscription_orders.created_at >= ?", *params.DateFrom)
+	}
+	if params.DateTo != nil {
+		baseQuery = baseQuery.Where("subscription_orders.created_at < ?", params.DateTo.AddDate(0, 0, 1))
+	}
+
+	queryBuilder := query.NewQueryBuilder(baseQuery).
+		SetAllowedSorts(map[string]string{
+			"created_at": "subscription_orders.created_at",
+		})
+
+	filteredQuery := queryBuilder.ApplyFiltering(&scopedParams)
+
+	if err := filteredQuery.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	sortedQuery := queryBuilder.ApplySorting(filteredQuery, &scopedParams)
+	paginatedQuery := queryBuilder.ApplyPagination(sortedQuery, &scopedParams)
+
+	if err := paginatedQuery.
+		Select("subscription_orders.*").
+		Preload("PaymentTransaction").
+		Preload("Subscription").
+		Preload("Items").
+		Find(&orders).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// Find an order of a user along with its payment transaction, subscription and items
+func (r *SubscriptionOrderRepositoryInstance) FindUserOrder(userID uint32, orderID string) (*models.SubscriptionOrder, error) {
+	order := new(models.SubscriptionOrder)
+
+	if err := r.DB.
+		Preload("PaymentTransaction").
+		Preload("Subscription").
+		Preload("Items").
+		Where("id = ? AND user_id = ?", orderID, userID).
+		First(order).Error; err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Find an order with everything needed to issue its invoice
+func (r *SubscriptionOrderRepositoryInstance) FindInvoiceOrder(orderID string) (*models.SubscriptionOrder, error) {
+	order := new(models.SubscriptionOrder)
+
+	if err := r.DB.
+		Preload("User").
+		Preload("PaymentTransaction").
+		Preload("Subscription").
+		Preload("Items").
+		Where("id = ?", orderID).
+		First(order).Error; err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Check whether a user ever paid for a subscription
+// A refunded order still counts, so a refund does not make the user eligible for a trial again
+// It returns an error if the query fails
+func (r *SubscriptionOrderRepositoryInstance) HasPaidOrder(userID uint32) (bool, error) {
+	var paid bool
+
+	if err := r.DB.Raw(
+		"SELECT EXISTS (SELECT 1 FROM subscription_orders WHERE user_id = ? AND amount > 0 AND status IN ?)",
+		userID, []string{"settled", "refunded", "partially_refunded"},
+	).Scan(&paid).Error; err != nil {
+		return false, err
+	}
+
+	return paid, nil
+}
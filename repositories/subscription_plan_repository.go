@@ -7,8 +7,10 @@ import (
 )
 
 type SubscriptionPlanRepository interface {
+	WithTx(tx *gorm.DB) SubscriptionPlanRepository
 	StoreNewPlan(plan *models.SubscriptionPlan) error
 	IsPlanExists(subID uint32, planName string) (bool, error)
+	ReplacePlans(subID uint32, plans []models.SubscriptionPlan) error
 }
 
 type SubscriptionPlanRepositoryInstance struct {
@@ -21,6 +23,14 @@ func NewSubscriptionPlanRepository(db *gorm.DB) SubscriptionPlanRepository {
 	}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *SubscriptionPlanRepositoryInstance) WithTx(tx *gorm.DB) SubscriptionPlanRepository {
+	return &SubscriptionPlanRepositoryInstance{
+		DB: tx,
+	}
+}
+
 // Store a new subscription plan
 // This function saves a new subscription plan to the database
 func (r *SubscriptionPlanRepositoryInstance) StoreNewPlan(plan *models.SubscriptionPlan) error {
@@ -44,3 +54,27 @@ func (r *SubscriptionPlanRepositoryInstance) IsPlanExists(subID uint32, planName
 
 	return count > 0, nil
 }
+
+// Replace the plans of a subscription
+// This function deletes every plan of the subscription and stores the given plans instead
+// It returns an error if the plans could not be replaced
+func (r *SubscriptionPlanRepositoryInstance) ReplacePlans(subID uint32, plans []models.SubscriptionPlan) error {
+	if err := r.DB.Where("sub_id = ?", subID).Delete(&models.SubscriptionPlan{}).Error; err != nil {
+		return err
+	}
+
+	if len(plans) == 0 {
+		return nil
+	}
+
+	for i := range plans {
+		plans[i].ID = 0
+		plans[i].SubID = subID
+	}
+
+	if err := r.DB.Omit("Subscription").Create(&plans).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
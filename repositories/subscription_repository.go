@@ -4,19 +4,42 @@ import (
 	"senkou-catalyst-be/app/models"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SubscriptionRepository interface {
+	WithTx(tx *gorm.DB) SubscriptionRepository
 	StoreNewSubscription(subscription *models.Subscription) (*models.Subscription, error)
+	StoreSubscriptionVersion(subscription *models.Subscription) error
 	SubscribeUser(sub *models.UserSubscription) error
 	FindAllSubscriptions() ([]*models.Subscription, error)
+	FindAllSubscriptionsWithPlans() ([]*models.Subscription, error)
 	FindByID(id uint32) (*models.Subscription, error)
+	FindByIDForUpdate(id uint32) (*models.Subscription, error)
+	FindCurrentSubscriptionByName(name string) (*models.Subscription, error)
+	FindCurrentVersion(familyID uint32) (*models.Subscription, error)
+	FindSubscriptionVersions(familyID uint32) ([]*models.Subscription, error)
+	IsSubscriptionInUse(id uint32) (bool, error)
+	RetireSubscription(id uint32, supersededByID *uint32, at time.Time) error
 	FindActiveSubscriptionByUserID(userID uint32) (*models.Subscription, error)
 	FindFreeTierSubscription() (*models.Subscription, error)
 	UpdateSubscription(updatedSubscription *models.Subscription) (*models.Subscription, error)
 	DeleteSubscription(subscription *models.Subscription) error
 	VerifyUserHasActiveSubscription(userID, subID uint32) (bool, error)
+	FindActiveUserSubscription(userID uint32) (*models.UserSubscription, error)
+	UpdateUserSubscription(userSubscription *models.UserSubscription) error
+	DeactivateUserSubscriptions(userID uint32) error
+	FindUserSubscriptionByID(id uint32) (*models.UserSubscription, error)
+	FindUserSubscriptionForUpdate(id uint32) (*models.UserSubscription, error)
+	FindExpiringUserSubscriptions(from time.Time, to time.Time, limit int) ([]*models.UserSubscription, error)
+	FindExpiredUserSubscriptions(at time.Time, limit int) ([]*models.UserSubscription, error)
+	StoreHistory(history *models.SubscriptionHistory) error
+	FindHistoryByOrderID(orderID uuid.UUID) (*models.SubscriptionHistory, error)
+	StoreExpiryReminder(reminder *models.SubscriptionExpiryReminder) (bool, error)
+	FindScheduledUserSubscription(userID uint32) (*models.UserSubscription, error)
+	HasUsedTrial(userID uint32, email string) (bool, error)
 }
 
 type SubscriptionRepositoryInstance struct {
@@ -29,18 +52,44 @@ func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
 	}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *SubscriptionRepositoryInstance) WithTx(tx *gorm.DB) SubscriptionRepository {
+	return &SubscriptionRepositoryInstance{
+		DB: tx,
+	}
+}
+
 // Store a new subscription
-// This function saves a new subscription to the database
+// This function saves a new subscription to the database, unless a subscription with the same name is already offered
+// A new subscription is the first version of its own family
 // It returns an error if the subscription could not be saved
 func (r *SubscriptionRepositoryInstance) StoreNewSubscription(subscription *models.Subscription) (*models.Subscription, error) {
-	result := r.DB.Where("name = ?", subscription.Name).FirstOrCreate(subscription)
+	result := r.DB.Where("name = ? AND retired_at IS NULL", subscription.Name).FirstOrCreate(subscription)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
+	if subscription.FamilyID == 0 {
+		if err := r.DB.Model(subscription).Update("family_id", subscription.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	return subscription, nil
 }
 
+// Store a new version of a subscription
+// This function saves the subscription along with its plans, the family and the version must already be set
+// It returns an error if the subscription could not be saved
+func (r *SubscriptionRepositoryInstance) StoreSubscriptionVersion(subscription *models.Subscription) error {
+	if err := r.DB.Create(subscription).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Subscribe a user to a subscription
 // This function store relation between user and subscription
 // It returns an error if the subscription could not be created
@@ -53,12 +102,25 @@ func (r *SubscriptionRepositoryInstance) SubscribeUser(sub *models.UserSubscript
 }
 
 // Find all the subscriptions
-// This function retrieves all subscriptions from the database
+// This function retrieves all subscriptions that are still offered from the database
 // It returns a slice of subscriptions and an error if any
 func (r *SubscriptionRepositoryInstance) FindAllSubscriptions() ([]*models.Subscription, error) {
 	subscriptions := make([]*models.Subscription, 0)
 
-	if err := r.DB.Find(&subscriptions).Error; err != nil {
+	if err := r.DB.Where("retired_at IS NULL").Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// Find all the subscriptions along with their plans
+// Only the subscriptions that are still offered are returned
+// It returns the subscriptions and an error if any
+func (r *SubscriptionRepositoryInstance) FindAllSubscriptionsWithPlans() ([]*models.Subscription, error) {
+	subscriptions := make([]*models.Subscription, 0)
+
+	if err := r.DB.Preload("Plans").Where("retired_at IS NULL").Order("id").Find(&subscriptions).Error; err != nil {
 		return nil, err
 	}
 
@@ -67,16 +129,18 @@ func (r *SubscriptionRepositoryInstance) FindAllSubscriptions() ([]*models.Subsc
 
 // Find an active subscription by user id
 // This function retrieves an active subscription for a specific user
+// A paid subscription stays active after its expiry until the expiry job deactivates it and assigns the free tier,
+// so a subscription can be returned for at most one run of the job after it expired
 // It returns the subscription and an error if any
 func (r *SubscriptionRepositoryInstance) FindActiveSubscriptionByUserID(userID uint32) (*models.Subscription, error) {
 	userSubscription := new(models.UserSubscription)
 	subscription := new(models.Subscription)
 
-	if err := r.DB.Where("user_id = ? AND is_active = ?", userID, true).First(userSubscription).Error; err != nil {
+	if err := r.DB.Where("user_id = ? AND is_active = ?", userID, true).Order("expired_at DESC").First(userSubscription).Error; err != nil {
 		return nil, err
 	}
 
-	if err := r.DB.Preload("Plans").Find(subscription).Error; err != nil {
+	if err := r.DB.Preload("Plans").First(subscription, userSubscription.SubID).Error; err != nil {
 		return nil, err
 	}
 
@@ -84,32 +148,132 @@ func (r *SubscriptionRepositoryInstance) FindActiveSubscriptionByUserID(userID u
 }
 
 // Find a subscription by its ID
-// This function retrieves a subscription by its ID from the database
+// This function retrieves a subscription by its ID from the database, including its plans
 // It returns the subscription and an error if any
 func (r *SubscriptionRepositoryInstance) FindByID(id uint32) (*models.Subscription, error) {
 	subscription := new(models.Subscription)
-	if err := r.DB.First(subscription, id).Error; err != nil {
+	if err := r.DB.Preload("Plans").First(subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Find a subscription by its ID and lock it for update
+// This function must be called within a transaction, the row stays locked until the transaction ends
+// It returns the subscription along with its plans and an error if any
+func (r *SubscriptionRepositoryInstance) FindByIDForUpdate(id uint32) (*models.Subscription, error) {
+	subscription := new(models.Subscription)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(subscription, id).Error; err != nil {
 		return nil, err
 	}
+
+	if err := r.DB.Where("sub_id = ?", id).Order("id").Find(&subscription.Plans).Error; err != nil {
+		return nil, err
+	}
+
 	return subscription, nil
 }
 
+// Find the subscription on offer with a name
+// It returns the subscription and an error if any
+func (r *SubscriptionRepositoryInstance) FindCurrentSubscriptionByName(name string) (*models.Subscription, error) {
+	subscription := new(models.Subscription)
+
+	if err := r.DB.Where("name = ? AND retired_at IS NULL", name).First(subscription).Error; err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// Find the version of a subscription family that is on offer
+// It returns the subscription along with its plans and an error if any
+func (r *SubscriptionRepositoryInstance) FindCurrentVersion(familyID uint32) (*models.Subscription, error) {
+	subscription := new(models.Subscription)
+
+	if err := r.DB.
+		Preload("Plans").
+		Where("family_id = ? AND retired_at IS NULL", familyID).
+		Order("version DESC").
+		First(subscription).Error; err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// Find every version of a subscription family
+// It returns the subscriptions along with their plans, oldest version first, and an error if any
+func (r *SubscriptionRepositoryInstance) FindSubscriptionVersions(familyID uint32) ([]*models.Subscription, error) {
+	subscriptions := make([]*models.Subscription, 0)
+
+	if err := r.DB.
+		Preload("Plans").
+		Where("family_id = ?", familyID).
+		Order("version ASC").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// Check whether a subscription was ever bought
+// A subscription is in use once a user subscription or an order refers to it, its terms must not change from then on
+// It returns true when the subscription is in use and an error if any
+func (r *SubscriptionRepositoryInstance) IsSubscriptionInUse(id uint32) (bool, error) {
+	var inUse bool
+
+	if err := r.DB.Raw(
+		"SELECT EXISTS (SELECT 1 FROM user_subscriptions WHERE sub_id = ?) OR EXISTS (SELECT 1 FROM subscription_orders WHERE subscription_id = ?)",
+		id, id,
+	).Scan(&inUse).Error; err != nil {
+		return false, err
+	}
+
+	return inUse, nil
+}
+
+// Retire a subscription
+// A retired subscription is no longer offered, supersededByID is the version that replaces it, if any
+// It returns an error if the subscription could not be updated
+func (r *SubscriptionRepositoryInstance) RetireSubscription(id uint32, supersededByID *uint32, at time.Time) error {
+	if err := r.DB.
+		Model(&models.Subscription{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"retired_at":       at,
+			"superseded_by_id": supersededByID,
+			"updated_at":       at,
+		}).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Find a free tier subscription
-// This function retrieve a subscription specific for free tier
+// This function retrieve a subscription specific for free tier, the latest version that is still offered
 // It returns the subscription and an error if any
 func (r *SubscriptionRepositoryInstance) FindFreeTierSubscription() (*models.Subscription, error) {
 	freeTierSubscription := new(models.Subscription)
-	if err := r.DB.Where("name = $1 AND price = $2", "Free tier", 0).First(freeTierSubscription).Error; err != nil {
+	if err := r.DB.
+		Where("name = ? AND price_amount = ? AND retired_at IS NULL", models.FreeTierSubscriptionName, 0).
+		Order("version DESC").
+		First(freeTierSubscription).Error; err != nil {
 		return nil, err
 	}
 	return freeTierSubscription, nil
 }
 
 // Update an existing subscription
-// This function updates an existing subscription in the database
+// This function updates an existing subscription in the database, its plans are left untouched
 // It returns the updated subscription and an error if any
 func (r *SubscriptionRepositoryInstance) UpdateSubscription(updatedSubscription *models.Subscription) (*models.Subscription, error) {
-	if err := r.DB.Save(updatedSubscription).Error; err != nil {
+	if err := r.DB.Omit("Plans").Save(updatedSubscription).Error; err != nil {
 		return nil, err
 	}
 
@@ -148,3 +312,185 @@ func (r *SubscriptionRepositoryInstance) VerifyUserHasActiveSubscription(userID,
 
 	return true, nil
 }
+
+// Find the active user subscription of a user
+// This function retrieves the user subscription row itself, including its subscription
+// It returns the user subscription and an error if any
+func (r *SubscriptionRepositoryInstance) FindActiveUserSubscription(userID uint32) (*models.UserSubscription, error) {
+	userSubscription := new(models.UserSubscription)
+
+	if err := r.DB.
+		Preload("Sub").
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Order("expired_at DESC").
+		First(userSubscription).Error; err != nil {
+		return nil, err
+	}
+
+	return userSubscription, nil
+}
+
+// Update a user subscription
+// This function saves the changes of an existing user subscription
+// It returns an error if the user subscription could not be saved
+func (r *SubscriptionRepositoryInstance) UpdateUserSubscription(userSubscription *models.UserSubscription) error {
+	userSubscription.UpdatedAt = time.Now()
+
+	if err := r.DB.Omit("User", "Sub").Save(userSubscription).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Deactivate every active subscription of a user
+// This function is used before activating a different subscription for the user
+// It returns an error if the user subscriptions could not be updated
+func (r *SubscriptionRepositoryInstance) DeactivateUserSubscriptions(userID uint32) error {
+	if err := r.DB.
+		Model(&models.UserSubscription{}).
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Updates(map[string]any{
+			"is_active":  false,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Find a user subscription by its ID
+// This function retrieves the user subscription along with its user and subscription
+// It returns the user subscription and an error if any
+func (r *SubscriptionRepositoryInstance) FindUserSubscriptionByID(id uint32) (*models.UserSubscription, error) {
+	userSubscription := new(models.UserSubscription)
+
+	if err := r.DB.Preload("User").Preload("Sub").First(userSubscription, id).Error; err != nil {
+		return nil, err
+	}
+
+	return userSubscription, nil
+}
+
+// Find a user subscription by its ID and lock it for update
+// This function must be called within a transaction, the row stays locked until the transaction ends
+// It returns the user subscription and an error if any
+func (r *SubscriptionRepositoryInstance) FindUserSubscriptionForUpdate(id uint32) (*models.UserSubscription, error) {
+	userSubscription := new(models.UserSubscription)
+
+	if err := r.DB.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(userSubscription, id).Error; err != nil {
+		return nil, err
+	}
+
+	return userSubscription, nil
+}
+
+// Find the active paid user subscriptions that expire within a period
+// This function retrieves subscriptions expiring after from and at or before to, soonest first
+// It returns the user subscriptions and an error if any
+func (r *SubscriptionRepositoryInstance) FindExpiringUserSubscriptions(from time.Time, to time.Time, limit int) ([]*models.UserSubscription, error) {
+	userSubscriptions := make([]*models.UserSubscription, 0)
+
+	if err := r.DB.
+		Preload("Sub").
+		Joins("JOIN subscriptions ON subscriptions.id = user_subscriptions.sub_id").
+		Where("user_subscriptions.is_active = ? AND subscriptions.price_amount > 0", true).
+		Where("user_subscriptions.expired_at > ? AND user_subscriptions.expired_at <= ?", from, to).
+		Order("user_subscriptions.expired_at ASC").
+		Limit(limit).
+		Find(&userSubscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	return userSubscriptions, nil
+}
+
+// Find the user subscriptions that are still active past their expiry
+// This function retrieves subscriptions that expired at or before the given time, oldest first
+// It returns the user subscriptions and an error if any
+func (r *SubscriptionRepositoryInstance) FindExpiredUserSubscriptions(at time.Time, limit int) ([]*models.UserSubscription, error) {
+	userSubscriptions := make([]*models.UserSubscription, 0)
+
+	if err := r.DB.
+		Preload("Sub").
+		Where("is_active = ? AND expired_at <= ?", true, at).
+		Order("expired_at ASC").
+		Limit(limit).
+		Find(&userSubscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	return userSubscriptions, nil
+}
+
+// Store a subscription history entry
+// It returns an error if the entry could not be saved
+func (r *SubscriptionRepositoryInstance) StoreHistory(history *models.SubscriptionHistory) error {
+	if err := r.DB.Create(history).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Find the latest subscription history entry recorded for an order
+// It returns the entry and an error if any
+func (r *SubscriptionRepositoryInstance) FindHistoryByOrderID(orderID uuid.UUID) (*models.SubscriptionHistory, error) {
+	history := new(models.SubscriptionHistory)
+
+	if err := r.DB.Where("order_id = ?", orderID).Order("id DESC").First(history).Error; err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Store an expiry reminder unless the same reminder was already stored
+// This function is used to send each reminder once, even when the expiry job runs concurrently
+// It returns true when the reminder was stored and still has to be sent
+func (r *SubscriptionRepositoryInstance) StoreExpiryReminder(reminder *models.SubscriptionExpiryReminder) (bool, error) {
+	result := r.DB.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Find the scheduled user subscription of a user
+// A scheduled subscription is a paid downgrade that starts when the active subscription expires
+// It returns the user subscription along with its subscription and an error if any
+func (r *SubscriptionRepositoryInstance) FindScheduledUserSubscription(userID uint32) (*models.UserSubscription, error) {
+	userSubscription := new(models.UserSubscription)
+
+	if err := r.DB.
+		Preload("Sub").
+		Where("user_id = ? AND is_scheduled = ?", userID, true).
+		Order("started_at ASC").
+		First(userSubscription).Error; err != nil {
+		return nil, err
+	}
+
+	return userSubscription, nil
+}
+
+// Check whether a user or an email already started a trial
+// The email is compared as normalized when the trial was started, so aliases of the same mailbox match
+// It returns an error if the query fails
+func (r *SubscriptionRepositoryInstance) HasUsedTrial(userID uint32, email string) (bool, error) {
+	var used bool
+
+	if err := r.DB.Raw(
+		"SELECT EXISTS (SELECT 1 FROM user_subscriptions WHERE trial_ends_at IS NOT NULL AND (user_id = ? OR trial_email = ?))",
+		userID, email,
+	).Scan(&used).Error; err != nil {
+		return false, err
+	}
+
+	return used, nil
+}
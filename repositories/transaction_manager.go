@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+)
+
+type TransactionManager interface {
+	WithinTransaction(fn func(tx *gorm.DB) error) error
+}
+
+type TransactionManagerInstance struct {
+	DB *gorm.DB
+}
+
+func NewTransactionManager(db *gorm.DB) TransactionManager {
+	return &TransactionManagerInstance{
+		DB: db,
+	}
+}
+
+// Run a function within a database transaction
+// This function commits the transaction when fn returns nil and rolls it back otherwise
+// Repositories can join the transaction by calling their WithTx method with the given tx
+func (m *TransactionManagerInstance) WithinTransaction(fn func(tx *gorm.DB) error) error {
+	return m.DB.Transaction(fn)
+}
@@ -12,7 +12,10 @@ type UserRepository interface {
 	FindAll(params *query.QueryParams) (*[]models.User, int64, error)
 	FindByEmail(email string) (*models.User, error)
 	FindByID(userID uint32) (*models.User, error)
+	FindByReferralCode(code string) (*models.User, error)
 	Update(user *models.User) (*models.User, error)
+	UpdatePassword(userID uint32, password []byte) error
+	WithTx(tx *gorm.DB) UserRepository
 }
 
 type userRepository struct {
@@ -23,6 +26,12 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db}
 }
 
+// Bind the repository to a database transaction
+// This function returns a copy of the repository that runs every query within tx
+func (r *userRepository) WithTx(tx *gorm.DB) UserRepository {
+	return &userRepository{tx}
+}
+
 // Find all users in the database
 // Returns a slice of User models or an error if the operation fails
 func (r *userRepository) FindAll(params *query.QueryParams) (*[]models.User, int64, error) {
@@ -86,6 +95,18 @@ func (r *userRepository) FindByID(userID uint32) (*models.User, error) {
 	return user, nil
 }
 
+// Find a user by its referral code
+// Codes are stored in upper case, so the given code must be normalized first
+func (r *userRepository) FindByReferralCode(code string) (*models.User, error) {
+	user := new(models.User)
+
+	if err := r.db.Where("referral_code = ?", code).First(user).Error; err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 // Update a user in the database
 // Returns the updated user or an error if any
 func (r *userRepository) Update(user *models.User) (*models.User, error) {
@@ -95,3 +116,9 @@ func (r *userRepository) Update(user *models.User) (*models.User, error) {
 
 	return user, nil
 }
+
+// Update the password of a user with an already hashed password
+// Returns an error if the operation fails
+func (r *userRepository) UpdatePassword(userID uint32, password []byte) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("password", password).Error
+}
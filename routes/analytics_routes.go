@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"senkou-catalyst-be/app/controllers"
+	"senkou-catalyst-be/platform/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func InitAnalyticsRoutes(app *fiber.App, analyticsController *controllers.AnalyticsController, jwtProtected fiber.Handler) {
+	app.Get(
+		"/analytics/payments",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		analyticsController.GetPaymentAnalytics,
+	)
+	app.Get(
+		"/analytics/payments/export",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		analyticsController.ExportPaymentAnalytics,
+	)
+}
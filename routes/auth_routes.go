@@ -3,11 +3,13 @@ package routes
 import (
 	"senkou-catalyst-be/app/controllers"
 	"senkou-catalyst-be/platform/middlewares"
+	"senkou-catalyst-be/utils/config"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitAuthRoutes(app *fiber.App, authController *controllers.AuthController) {
+func InitAuthRoutes(app *fiber.App, authController *controllers.AuthController, jwtProtected fiber.Handler) {
 	app.Post(
 		"/auth/login",
 		authController.Login,
@@ -18,7 +20,24 @@ func InitAuthRoutes(app *fiber.App, authController *controllers.AuthController)
 	)
 	app.Delete(
 		"/auth/logout",
-		middlewares.JWTProtected,
+		jwtProtected,
 		authController.Logout,
 	)
+
+	// Limit the password routes per client IP, they send emails and accept guesses of reset tokens
+	passwordRateLimit := middlewares.RateLimitMiddleware(
+		config.GetEnvAsInt("PASSWORD_RESET_RATE_LIMIT", 5),
+		15*time.Minute,
+	)
+
+	app.Post(
+		"/auth/password/forgot",
+		passwordRateLimit,
+		authController.ForgotPassword,
+	)
+	app.Post(
+		"/auth/password/reset",
+		passwordRateLimit,
+		authController.ResetPassword,
+	)
 }
@@ -2,32 +2,29 @@ package routes
 
 import (
 	"senkou-catalyst-be/app/controllers"
-	"senkou-catalyst-be/platform/constants"
-	"senkou-catalyst-be/platform/middlewares"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitCategoryRoutes(app *fiber.App, categoryController *controllers.CategoryController) {
+func InitCategoryRoutes(app *fiber.App, categoryController *controllers.CategoryController, jwtProtected fiber.Handler) {
 	app.Post(
 		"/merchants/:merchantID/categories",
-		middlewares.JWTProtected,
-		middlewares.SubscriptionMiddleware(constants.SubscriptionCategoryLimit),
+		jwtProtected,
 		categoryController.CreateCategory,
 	)
 	app.Get(
 		"/merchants/:merchantID/categories",
-		middlewares.JWTProtected,
+		jwtProtected,
 		categoryController.GetCategories,
 	)
 	app.Put(
 		"/merchants/:merchantID/categories/:categoryID",
-		middlewares.JWTProtected,
+		jwtProtected,
 		categoryController.UpdateCategory,
 	)
 	app.Delete(
 		"/merchants/:merchantID/categories/:categoryID",
-		middlewares.JWTProtected,
+		jwtProtected,
 		categoryController.DeleteCategory,
 	)
 
@@ -36,8 +33,7 @@ func InitCategoryRoutes(app *fiber.App, categoryController *controllers.Category
 	// This is useful for client where merchant ID is not known or just knowing merchant username
 	app.Post(
 		"/merchants/username/:username/categories",
-		middlewares.JWTProtected,
-		middlewares.SubscriptionMiddleware(constants.SubscriptionCategoryLimit),
+		jwtProtected,
 		categoryController.CreateCategoryWithMerchantUsername,
 	)
 
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"senkou-catalyst-be/app/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func InitEntitlementRoutes(app *fiber.App, entitlementController *controllers.EntitlementController, jwtProtected fiber.Handler) {
+	app.Get(
+		"/users/me/entitlements",
+		jwtProtected,
+		entitlementController.GetMyEntitlements,
+	)
+}
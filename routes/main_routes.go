@@ -2,6 +2,7 @@ package routes
 
 import (
 	"senkou-catalyst-be/container"
+	"senkou-catalyst-be/platform/middlewares"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/swagger"
@@ -25,19 +26,27 @@ func InitRoutes(app *fiber.App, deps *container.Container) {
 
 	app.Get("/docs/*", swagger.HandlerDefault)
 
-	InitUserRoutes(app, deps.UserController)
-	InitAuthRoutes(app, deps.AuthController)
+	// Authenticated requests are refused once the session of their access token ends
+	jwtProtected := middlewares.JWTProtected(deps.AuthService)
+
+	InitUserRoutes(app, deps.UserController, jwtProtected)
+	InitAuthRoutes(app, deps.AuthController, jwtProtected)
 	InitOAuthRoutes(app, deps.OAuthController)
-	InitMerchantRoutes(app, deps.MerchantController)
-	InitCategoryRoutes(app, deps.CategoryController)
-	InitPredefinedCategoryRoutes(app, deps.PredefinedCategoryController)
+	InitMerchantRoutes(app, deps.MerchantController, deps.EntitlementService, jwtProtected)
+	InitCategoryRoutes(app, deps.CategoryController, jwtProtected)
+	InitPredefinedCategoryRoutes(app, deps.PredefinedCategoryController, jwtProtected)
 	InitProductRoutes(app, ProductRouteDependencies{
-		ProductController: deps.ProductController,
-		UserService:       deps.UserService,
-		ProductService:    deps.ProductService,
+		ProductController:  deps.ProductController,
+		UserService:        deps.UserService,
+		ProductService:     deps.ProductService,
+		EntitlementService: deps.EntitlementService,
+		JWTProtected:       jwtProtected,
 	})
-	InitSubscriptionRoutes(app, deps.SubscriptionController)
-	InitPaymentMethodsRoutes(app, deps.PaymentMethodsController)
-	InitPaymentRoutes(app, deps.PaymentController)
+	InitSubscriptionRoutes(app, deps.SubscriptionController, jwtProtected)
+	InitPaymentMethodsRoutes(app, deps.PaymentMethodsController, jwtProtected)
+	InitPromoCodeRoutes(app, deps.PromoCodeController, jwtProtected)
+	InitPaymentRoutes(app, deps.PaymentController, jwtProtected)
 	InitStorageRoutes(app, deps.StorageController)
+	InitAnalyticsRoutes(app, deps.AnalyticsController, jwtProtected)
+	InitEntitlementRoutes(app, deps.EntitlementController, jwtProtected)
 }
@@ -2,16 +2,17 @@ package routes
 
 import (
 	"senkou-catalyst-be/app/controllers"
+	"senkou-catalyst-be/app/services"
 	"senkou-catalyst-be/platform/constants"
 	"senkou-catalyst-be/platform/middlewares"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitMerchantRoutes(app *fiber.App, merchantController *controllers.MerchantController) {
+func InitMerchantRoutes(app *fiber.App, merchantController *controllers.MerchantController, entitlementService services.EntitlementService, jwtProtected fiber.Handler) {
 	app.Post(
 		"/merchants",
-		middlewares.JWTProtected,
+		jwtProtected,
 		merchantController.CreateMerchant,
 	)
 
@@ -22,7 +23,7 @@ func InitMerchantRoutes(app *fiber.App, merchantController *controllers.Merchant
 
 	app.Get(
 		"/merchants",
-		middlewares.JWTProtected,
+		jwtProtected,
 		middlewares.RoleMiddleware("admin"),
 		merchantController.GetUserMerchants,
 	)
@@ -34,25 +35,25 @@ func InitMerchantRoutes(app *fiber.App, merchantController *controllers.Merchant
 	// Merchant overview
 	app.Get(
 		"/merchants/:id/overview",
-		middlewares.JWTProtected,
-		middlewares.SubscriptionMiddleware(constants.SubscriptionAnalytics),
+		jwtProtected,
+		middlewares.RequireFeature(entitlementService, constants.SubscriptionAnalytics),
 		merchantController.GetMerchantOverview,
 	)
 	app.Get(
 		"/merchants/:id/products/report",
-		middlewares.JWTProtected,
-		middlewares.SubscriptionMiddleware(constants.SubscriptionAnalytics, constants.SubscriptionInteractionMetrics),
+		jwtProtected,
+		middlewares.RequireFeature(entitlementService, constants.SubscriptionAnalytics, constants.SubscriptionInteractionMetrics),
 		merchantController.GetMerchantProductReport,
 	)
 
 	app.Put(
 		"/merchants/:id",
-		middlewares.JWTProtected,
+		jwtProtected,
 		merchantController.UpdateMerchant,
 	)
 	app.Delete(
 		"/merchants/:id",
-		middlewares.JWTProtected,
+		jwtProtected,
 		merchantController.DeleteMerchant,
 	)
 }
@@ -2,11 +2,12 @@ package routes
 
 import (
 	"senkou-catalyst-be/app/controllers"
+	"senkou-catalyst-be/platform/middlewares"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitPaymentMethodsRoutes(app *fiber.App, controller *controllers.PaymentMethodsController) {
+func InitPaymentMethodsRoutes(app *fiber.App, controller *controllers.PaymentMethodsController, jwtProtected fiber.Handler) {
 	api := app.Group("/payment-methods")
 
 	api.Get(
@@ -21,4 +22,39 @@ func InitPaymentMethodsRoutes(app *fiber.App, controller *controllers.PaymentMet
 		"/type/:type",
 		controller.GetPaymentMethodsByType,
 	)
+
+	manageRoute := api.Group(
+		"/manage",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+	)
+
+	manageRoute.Get(
+		"/",
+		controller.GetPaymentMethods,
+	)
+	manageRoute.Post(
+		"/",
+		controller.CreatePaymentMethod,
+	)
+	manageRoute.Put(
+		"/:methodID",
+		controller.UpdatePaymentMethod,
+	)
+	manageRoute.Delete(
+		"/:methodID",
+		controller.DeletePaymentMethod,
+	)
+	manageRoute.Post(
+		"/:methodID/logo",
+		controller.UploadPaymentMethodLogo,
+	)
+	manageRoute.Post(
+		"/:methodID/maintenance-windows",
+		controller.CreateMaintenanceWindow,
+	)
+	manageRoute.Delete(
+		"/:methodID/maintenance-windows/:windowID",
+		controller.DeleteMaintenanceWindow,
+	)
 }
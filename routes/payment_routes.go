@@ -2,10 +2,56 @@ package routes
 
 import (
 	"senkou-catalyst-be/app/controllers"
+	"senkou-catalyst-be/integrations/midtrans"
+	"senkou-catalyst-be/integrations/xendit"
+	"senkou-catalyst-be/platform/middlewares"
+	"senkou-catalyst-be/utils/config"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitPaymentRoutes(app *fiber.App, paymentController *controllers.PaymentController) {
-	app.Post("/api/v1/payments/notifications", paymentController.PaymentNotifications)
+func InitPaymentRoutes(app *fiber.App, paymentController *controllers.PaymentController, jwtProtected fiber.Handler) {
+	// Payments made before the provider routes were introduced still notify the legacy route
+	app.Post(
+		"/api/v1/payments/notifications",
+		middlewares.IPWhitelistMiddleware(midtrans.GetNotificationAllowedIPs()...),
+		paymentController.MidtransNotifications,
+	)
+	app.Post(
+		"/api/v1/payments/notifications/midtrans",
+		middlewares.IPWhitelistMiddleware(midtrans.GetNotificationAllowedIPs()...),
+		paymentController.MidtransNotifications,
+	)
+	app.Post(
+		"/api/v1/payments/notifications/xendit",
+		middlewares.IPWhitelistMiddleware(xendit.GetNotificationAllowedIPs()...),
+		paymentController.XenditNotifications,
+	)
+
+	// The fake gateway only exists for local development, so its notifications are only accepted when it is enabled
+	if config.GetEnvAsBool("PAYMENT_FAKE_GATEWAY_ENABLED", false) {
+		app.Post(
+			"/api/v1/payments/notifications/fake",
+			paymentController.FakeNotifications,
+		)
+	}
+
+	app.Post(
+		"/payments/notifications/:notificationID/replay",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		paymentController.ReplayPaymentNotification,
+	)
+	app.Get(
+		"/payments/:transactionID/notifications",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		paymentController.GetPaymentNotifications,
+	)
+	app.Post(
+		"/payments/:transactionID/refunds",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		paymentController.RefundPayment,
+	)
 }
@@ -7,7 +7,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitPredefinedCategoryRoutes(app *fiber.App, PDController *controllers.PredefinedCategoryController) {
+func InitPredefinedCategoryRoutes(app *fiber.App, PDController *controllers.PredefinedCategoryController, jwtProtected fiber.Handler) {
 	app.Get(
 		"/predefined-categories",
 		PDController.GetPredefinedCategories,
@@ -15,7 +15,7 @@ func InitPredefinedCategoryRoutes(app *fiber.App, PDController *controllers.Pred
 
 	PDRoute := app.Group(
 		"/predefined-categories",
-		middlewares.JWTProtected,
+		jwtProtected,
 		middlewares.RoleMiddleware("admin"),
 	)
 
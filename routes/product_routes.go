@@ -10,21 +10,26 @@ import (
 )
 
 type ProductRouteDependencies struct {
-	ProductController *controllers.ProductController
-	UserService       services.UserService
-	ProductService    services.ProductService
+	ProductController  *controllers.ProductController
+	UserService        services.UserService
+	ProductService     services.ProductService
+	EntitlementService services.EntitlementService
+	JWTProtected       fiber.Handler
 }
 
 func InitProductRoutes(app *fiber.App, deps ProductRouteDependencies) {
 	app.Post(
 		"/products",
-		middlewares.JWTProtected,
-		middlewares.SubscriptionMiddleware(constants.SubscriptionProductSlot),
+		deps.JWTProtected,
+		// Early rejection before the photos are uploaded, the quota is enforced again when the product is stored
+		middlewares.RequireQuota(deps.EntitlementService, constants.SubscriptionProductSlot),
+		middlewares.RequireUploadSize(deps.EntitlementService, constants.SubscriptionPhotoSize, "photos"),
 		deps.ProductController.CreateProduct,
 	)
 	app.Post(
 		"/products/:productID/photos",
-		middlewares.JWTProtected,
+		deps.JWTProtected,
+		middlewares.RequireUploadSize(deps.EntitlementService, constants.SubscriptionPhotoSize, "photo"),
 		deps.ProductController.UploadProductPhoto,
 	)
 	app.Post(
@@ -34,7 +39,7 @@ func InitProductRoutes(app *fiber.App, deps ProductRouteDependencies) {
 
 	app.Get(
 		"/products",
-		middlewares.JWTProtected,
+		deps.JWTProtected,
 		middlewares.RoleMiddleware("admin"),
 		deps.ProductController.GetAllProducts,
 	)
@@ -57,13 +62,13 @@ func InitProductRoutes(app *fiber.App, deps ProductRouteDependencies) {
 
 	app.Delete(
 		"/products/:productID/photos/*",
-		middlewares.JWTProtected,
+		deps.JWTProtected,
 		deps.ProductController.DeleteProductPhoto,
 	)
 
 	route := app.Group(
 		"/merchants/:merchantID/products/:productID",
-		middlewares.JWTProtected,
+		deps.JWTProtected,
 		middlewares.OwnershipMiddleware(deps.ProductService, deps.UserService),
 	)
 	route.Put(
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"senkou-catalyst-be/app/controllers"
+	"senkou-catalyst-be/platform/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func InitPromoCodeRoutes(app *fiber.App, controller *controllers.PromoCodeController, jwtProtected fiber.Handler) {
+	api := app.Group("/promo-codes")
+
+	api.Post(
+		"/validate",
+		jwtProtected,
+		controller.ValidatePromoCode,
+	)
+
+	manageRoute := api.Group(
+		"/manage",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+	)
+
+	manageRoute.Get(
+		"/",
+		controller.GetPromoCodes,
+	)
+	manageRoute.Post(
+		"/",
+		controller.CreatePromoCode,
+	)
+	manageRoute.Put(
+		"/:promoID",
+		controller.UpdatePromoCode,
+	)
+	manageRoute.Delete(
+		"/:promoID",
+		controller.DeletePromoCode,
+	)
+}
@@ -7,28 +7,105 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitSubscriptionRoutes(app *fiber.App, subscriptionController *controllers.SubscriptionController) {
+func InitSubscriptionRoutes(app *fiber.App, subscriptionController *controllers.SubscriptionController, jwtProtected fiber.Handler) {
 	// Define the routes for subscription
-	// app.Post("/subscriptions", middlewares.JWTProtected, middlewares.RoleMiddleware("admin"), subscriptionController.CreateSubscription)
+	app.Post(
+		"/subscriptions",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		subscriptionController.CreateSubscription,
+	)
 	app.Get(
 		"/subscriptions",
 		subscriptionController.GetSubscriptions,
 	)
+	app.Get(
+		"/subscriptions/compare",
+		subscriptionController.CompareSubscriptions,
+	)
+	app.Get(
+		"/subscriptions/:subID",
+		subscriptionController.GetSubscription,
+	)
+	app.Get(
+		"/subscriptions/:subID/versions",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		subscriptionController.GetSubscriptionVersions,
+	)
 	app.Put(
 		"/subscriptions/:subID",
-		middlewares.JWTProtected,
+		jwtProtected,
 		middlewares.RoleMiddleware("admin"),
 		subscriptionController.UpdateSubscription,
 	)
 	app.Delete(
 		"/subscriptions/:subID",
-		middlewares.JWTProtected,
+		jwtProtected,
 		middlewares.RoleMiddleware("admin"),
 		subscriptionController.DeleteSubscription,
 	)
+	app.Post(
+		"/subscriptions/:subID/plans",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		subscriptionController.CreateSubscriptionPlan,
+	)
+	app.Put(
+		"/subscriptions/:subID/plans/:planID",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		subscriptionController.UpdateSubscriptionPlan,
+	)
+	app.Delete(
+		"/subscriptions/:subID/plans/:planID",
+		jwtProtected,
+		middlewares.RoleMiddleware("admin"),
+		subscriptionController.DeleteSubscriptionPlan,
+	)
 	app.Post(
 		"/subscriptions/:subID/subscribe",
-		middlewares.JWTProtected,
+		jwtProtected,
 		subscriptionController.SubscribeSubscription,
 	)
+	app.Post(
+		"/subscriptions/:subID/trial",
+		jwtProtected,
+		subscriptionController.StartSubscriptionTrial,
+	)
+	app.Post(
+		"/subscriptions/orders/:orderID/cancel",
+		jwtProtected,
+		subscriptionController.CancelSubscriptionOrder,
+	)
+	app.Post(
+		"/users/me/subscription/renew",
+		jwtProtected,
+		subscriptionController.RenewMySubscription,
+	)
+	app.Get(
+		"/users/me/subscription/change/:subID/preview",
+		jwtProtected,
+		subscriptionController.PreviewMySubscriptionChange,
+	)
+	app.Post(
+		"/users/me/subscription/change/:subID",
+		jwtProtected,
+		subscriptionController.ChangeMySubscription,
+	)
+	app.Get(
+		"/users/me/orders",
+		jwtProtected,
+		subscriptionController.GetMyOrders,
+	)
+	app.Get(
+		"/users/me/orders/:orderID",
+		jwtProtected,
+		subscriptionController.GetMyOrder,
+	)
+	app.Get(
+		"/users/me/orders/:orderID/invoice",
+		jwtProtected,
+		subscriptionController.DownloadMyOrderInvoice,
+	)
 }
@@ -2,12 +2,11 @@ package routes
 
 import (
 	"senkou-catalyst-be/app/controllers"
-	"senkou-catalyst-be/platform/middlewares"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitUserRoutes(app *fiber.App, userController *controllers.UserController) {
+func InitUserRoutes(app *fiber.App, userController *controllers.UserController, jwtProtected fiber.Handler) {
 	app.Post(
 		"/users",
 		userController.CreateUser,
@@ -18,12 +17,37 @@ func InitUserRoutes(app *fiber.App, userController *controllers.UserController)
 	)
 	app.Get(
 		"/users",
-		middlewares.JWTProtected,
+		jwtProtected,
 		userController.GetUsers,
 	)
 	app.Get(
 		"/users/me",
-		middlewares.JWTProtected,
+		jwtProtected,
 		userController.GetUserDetail,
 	)
+	app.Get(
+		"/users/me/referrals",
+		jwtProtected,
+		userController.GetMyReferrals,
+	)
+	app.Put(
+		"/users/me/password",
+		jwtProtected,
+		userController.ChangeMyPassword,
+	)
+	app.Get(
+		"/users/me/sessions",
+		jwtProtected,
+		userController.GetMySessions,
+	)
+	app.Delete(
+		"/users/me/sessions",
+		jwtProtected,
+		userController.RevokeMyOtherSessions,
+	)
+	app.Delete(
+		"/users/me/sessions/:sessionID",
+		jwtProtected,
+		userController.RevokeMySession,
+	)
 }
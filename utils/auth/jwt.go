@@ -21,12 +21,25 @@ func NewJWTManager(secret string) (*JWTManager, error) {
 }
 
 func (j *JWTManager) GenerateToken(payload any, expiry time.Time) (*dtos.GeneratedToken, error) {
-	claims := jwt.MapClaims{
+	return j.signToken(jwt.MapClaims{
 		"payload": payload,
 		"exp":     expiry.Unix(),
 		"iat":     time.Now().Unix(),
-	}
+	}, expiry)
+}
+
+// Generate a token that is bound to a user session
+// The session ID is carried in the "sid" claim so the session can be ended from the token
+func (j *JWTManager) GenerateSessionToken(payload any, sessionID string, expiry time.Time) (*dtos.GeneratedToken, error) {
+	return j.signToken(jwt.MapClaims{
+		"payload": payload,
+		"sid":     sessionID,
+		"exp":     expiry.Unix(),
+		"iat":     time.Now().Unix(),
+	}, expiry)
+}
 
+func (j *JWTManager) signToken(claims jwt.MapClaims, expiry time.Time) (*dtos.GeneratedToken, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signedToken, err := token.SignedString(j.Secret)
 	if err != nil {
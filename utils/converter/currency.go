@@ -0,0 +1,12 @@
+package converter
+
+import (
+	"math"
+	"senkou-catalyst-be/utils/money"
+)
+
+// Format an amount in rupiah, e.g. Rp 1.500.000
+// Amounts are rounded to whole rupiah, exact amounts should be formatted with money.Money.Format instead
+func FormatRupiah(amount float64) string {
+	return money.FromMajor(int64(math.Round(amount)), money.IDR).Format()
+}
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"testing"
+)
+
+func TestFormatRupiah(t *testing.T) {
+	t.Run("Should separate thousands with dots", func(t *testing.T) {
+		result := FormatRupiah(1500000)
+
+		if result != "Rp 1.500.000" {
+			t.Errorf("Expected Rp 1.500.000, got %s", result)
+		}
+	})
+
+	t.Run("Should format amounts below a thousand", func(t *testing.T) {
+		result := FormatRupiah(999)
+
+		if result != "Rp 999" {
+			t.Errorf("Expected Rp 999, got %s", result)
+		}
+	})
+
+	t.Run("Should round to whole rupiah", func(t *testing.T) {
+		result := FormatRupiah(11099.6)
+
+		if result != "Rp 11.100" {
+			t.Errorf("Expected Rp 11.100, got %s", result)
+		}
+	})
+
+	t.Run("Should format negative amounts", func(t *testing.T) {
+		result := FormatRupiah(-25000)
+
+		if result != "-Rp 25.000" {
+			t.Errorf("Expected -Rp 25.000, got %s", result)
+		}
+	})
+}
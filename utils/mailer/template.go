@@ -10,6 +10,18 @@ import (
 //go:embed templates/account-activation.html
 var accountActivationTemplate string
 
+//go:embed templates/payment-invoice.html
+var paymentInvoiceTemplate string
+
+//go:embed templates/subscription-expiry-reminder.html
+var subscriptionExpiryReminderTemplate string
+
+//go:embed templates/subscription-trial-ending.html
+var subscriptionTrialEndingTemplate string
+
+//go:embed templates/password-reset.html
+var passwordResetTemplate string
+
 type TemplateManager struct {
 	templates map[string]string
 }
@@ -17,9 +29,12 @@ type TemplateManager struct {
 func NewTemplateManager() *TemplateManager {
 	return &TemplateManager{
 		templates: map[string]string{
-			"account-activation.html": accountActivationTemplate,
+			"account-activation.html":           accountActivationTemplate,
+			"payment-invoice.html":              paymentInvoiceTemplate,
+			"subscription-expiry-reminder.html": subscriptionExpiryReminderTemplate,
+			"subscription-trial-ending.html":    subscriptionTrialEndingTemplate,
+			"password-reset.html":               passwordResetTemplate,
 			// Add more templates here as needed
-			// "password-reset.html": passwordResetTemplate,
 			// "welcome.html": welcomeTemplate,
 		},
 	}
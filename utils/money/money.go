@@ -0,0 +1,453 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Currencies supported by the payment gateways
+const (
+	IDR = "IDR"
+	USD = "USD"
+	SGD = "SGD"
+	MYR = "MYR"
+	EUR = "EUR"
+	JPY = "JPY"
+
+	DefaultCurrency = IDR
+)
+
+// Number of minor unit digits of each currency according to ISO 4217
+var exponents = map[string]int{
+	IDR: 2,
+	USD: 2,
+	SGD: 2,
+	MYR: 2,
+	EUR: 2,
+	JPY: 0,
+}
+
+type locale struct {
+	Symbol            string
+	ThousandSeparator string
+	DecimalSeparator  string
+	HideZeroFraction  bool
+}
+
+// How amounts are shown to users, currencies without a locale are shown with their code
+var locales = map[string]locale{
+	IDR: {Symbol: "Rp ", ThousandSeparator: ".", DecimalSeparator: ",", HideZeroFraction: true},
+	USD: {Symbol: "$", ThousandSeparator: ",", DecimalSeparator: "."},
+}
+
+var (
+	ErrInvalidAmount     = errors.New("money: invalid amount")
+	ErrTooPrecise        = errors.New("money: amount has more decimals than the currency allows")
+	ErrCurrencyMismatch  = errors.New("money: currency mismatch")
+	ErrUnsupportedFormat = errors.New("money: unsupported format")
+)
+
+// An exact amount of money in the minor unit of its currency, e.g. 150000 rupiah is stored as 15000000 sen
+// The zero value is zero in the default currency
+type Money struct {
+	Amount   int64  `gorm:"type:bigint;not null;default:0"`
+	Currency string `gorm:"type:char(3);not null;default:'IDR'"`
+}
+
+// Normalize a currency code, an empty code falls back to the default currency
+func normalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultCurrency
+	}
+
+	return currency
+}
+
+// Get the number of minor unit digits of a currency
+// Unknown currencies are assumed to have two decimals like most ISO 4217 currencies
+func Exponent(currency string) int {
+	if exponent, ok := exponents[normalizeCurrency(currency)]; ok {
+		return exponent
+	}
+
+	return 2
+}
+
+func pow10(exponent int) int64 {
+	result := int64(1)
+	for i := 0; i < exponent; i++ {
+		result *= 10
+	}
+
+	return result
+}
+
+// Create an amount from its minor units
+func New(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: normalizeCurrency(currency)}
+}
+
+// Create an amount from whole major units, e.g. FromMajor(150000, IDR) is Rp 150.000
+func FromMajor(major int64, currency string) Money {
+	currency = normalizeCurrency(currency)
+	return Money{Amount: major * pow10(Exponent(currency)), Currency: currency}
+}
+
+// Create an amount from a floating point number of major units
+// This function is only meant for values coming from external APIs that use floats
+// The value is rounded half away from zero to the minor unit
+func FromFloat(major float64, currency string) Money {
+	currency = normalizeCurrency(currency)
+	return Money{Amount: int64(math.Round(major * float64(pow10(Exponent(currency))))), Currency: currency}
+}
+
+// Parse an exact decimal string of major units, e.g. "150000" or "150000.50"
+// It returns an error when the string is not a plain decimal number or has more decimals than the currency allows
+func Parse(value string, currency string) (Money, error) {
+	currency = normalizeCurrency(currency)
+	exponent := Exponent(currency)
+
+	value = strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if whole == "" || (hasFraction && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+
+	if len(fraction) > exponent {
+		if strings.Trim(fraction[exponent:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q", ErrTooPrecise, value)
+		}
+		fraction = fraction[:exponent]
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get a zero amount of the given currency
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Get the currency of the amount, the zero value is in the default currency
+func (m Money) CurrencyCode() string {
+	return normalizeCurrency(m.Currency)
+}
+
+func (m Money) mustMatch(other Money) {
+	if m.CurrencyCode() != other.CurrencyCode() {
+		panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.CurrencyCode(), other.CurrencyCode()))
+	}
+}
+
+// Check whether two amounts can be added or compared
+func (m Money) SameCurrency(other Money) bool {
+	return m.CurrencyCode() == other.CurrencyCode()
+}
+
+// Add two amounts of the same currency
+// This function panics when the currencies differ, mixing currencies is a programming error
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return New(m.Amount+other.Amount, m.CurrencyCode())
+}
+
+// Subtract an amount of the same currency
+// This function panics when the currencies differ, mixing currencies is a programming error
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return New(m.Amount-other.Amount, m.CurrencyCode())
+}
+
+// Multiply the amount by a whole number, e.g. the quantity of an order item
+func (m Money) Mul(quantity int64) Money {
+	return New(m.Amount*quantity, m.CurrencyCode())
+}
+
+// Get the given percentage of the amount, e.g. Percent(11) for an 11% tax
+// The percentage is taken with up to 4 decimals and the result is rounded half away from zero to the minor unit
+func (m Money) Percent(percentage float64) Money {
+	return m.MulRatio(int64(math.Round(percentage*10000)), 100*10000)
+}
+
+// Multiply the amount by numerator/denominator, e.g. the unused days of a billing period
+// The result is rounded half away from zero to the minor unit
+func (m Money) MulRatio(numerator int64, denominator int64) Money {
+	if denominator == 0 {
+		panic("money: division by zero")
+	}
+
+	product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(numerator))
+	divisor := big.NewInt(denominator)
+
+	quotient, remainder := new(big.Int).QuoRem(product, divisor, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(new(big.Int).Abs(divisor)) >= 0 {
+		if product.Sign()*divisor.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return New(quotient.Int64(), m.CurrencyCode())
+}
+
+// Round the amount half away from zero to whole major units, e.g. to whole rupiah
+// Payment gateways like Midtrans do not accept fractions of a rupiah
+func (m Money) RoundToMajor() Money {
+	return FromMajor(m.Major(), m.CurrencyCode())
+}
+
+// Get the amount in whole major units rounded half away from zero
+func (m Money) Major() int64 {
+	unit := pow10(Exponent(m.CurrencyCode()))
+	whole, remainder := m.Amount/unit, m.Amount%unit
+
+	if remainder*2 >= unit {
+		whole++
+	} else if remainder*2 <= -unit {
+		whole--
+	}
+
+	return whole
+}
+
+// Check whether the amount has no fraction of a major unit
+func (m Money) IsWholeMajor() bool {
+	return m.Amount%pow10(Exponent(m.CurrencyCode())) == 0
+}
+
+// Get the amount in major units as a float
+// This function is only meant for APIs and charts that need floats, never compute with the result
+func (m Money) Float() float64 {
+	return float64(m.Amount) / float64(pow10(Exponent(m.CurrencyCode())))
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Compare two amounts of the same currency
+// It returns -1 when the amount is less than the other, 0 when they are equal and 1 otherwise
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Check whether two amounts have the same value and currency
+func (m Money) Equal(other Money) bool {
+	return m.SameCurrency(other) && m.Amount == other.Amount
+}
+
+// Get the exact decimal representation in major units, e.g. "150000.00"
+// This is the format used by payment gateways and CSV exports
+func (m Money) Decimal() string {
+	exponent := Exponent(m.CurrencyCode())
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(absolute(amount), 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func absolute(amount int64) uint64 {
+	if amount < 0 {
+		return uint64(-(amount + 1)) + 1
+	}
+
+	return uint64(amount)
+}
+
+// Get the amount as shown to users, e.g. "Rp 1.500.000" or "$1,500.50"
+// Currencies without a locale are shown with their code, e.g. "SGD 1,500.50"
+func (m Money) Format() string {
+	currency := m.CurrencyCode()
+	format, ok := locales[currency]
+	if !ok {
+		format = locale{Symbol: currency + " ", ThousandSeparator: ",", DecimalSeparator: "."}
+	}
+
+	decimal := strings.TrimPrefix(m.Decimal(), "-")
+	whole, fraction, _ := strings.Cut(decimal, ".")
+
+	var builder strings.Builder
+	if m.Amount < 0 {
+		builder.WriteString("-")
+	}
+	builder.WriteString(format.Symbol)
+
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			builder.WriteString(format.ThousandSeparator)
+		}
+		builder.WriteRune(digit)
+	}
+
+	if fraction != "" && !(format.HideZeroFraction && strings.Trim(fraction, "0") == "") {
+		builder.WriteString(format.DecimalSeparator)
+		builder.WriteString(fraction)
+	}
+
+	return builder.String()
+}
+
+func (m Money) String() string {
+	return m.CurrencyCode() + " " + m.Decimal()
+}
+
+type jsonMoney struct {
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Formatted string `json:"formatted"`
+}
+
+// Amounts are sent as their minor units along with the currency and the formatted amount
+// e.g. {"amount": 15000000, "currency": "IDR", "formatted": "Rp 150.000"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{
+		Amount:    m.Amount,
+		Currency:  m.CurrencyCode(),
+		Formatted: m.Format(),
+	})
+}
+
+// Amounts are accepted either as an object with their minor units and currency
+// or as a plain number or decimal string of major units in the default currency, e.g. 150000 or "150000.50"
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case len(data) > 0 && data[0] == '{':
+		var value struct {
+			Amount   *json.Number `json:"amount"`
+			Currency string       `json:"currency"`
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		if value.Amount == nil {
+			return fmt.Errorf("%w: amount is required", ErrInvalidAmount)
+		}
+
+		minor, err := value.Amount.Int64()
+		if err != nil {
+			return fmt.Errorf("%w: amount must be a whole number of minor units", ErrInvalidAmount)
+		}
+
+		*m = New(minor, value.Currency)
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+
+		parsed, err := Parse(value, DefaultCurrency)
+		if err != nil {
+			return err
+		}
+
+		*m = parsed
+		return nil
+	default:
+		parsed, err := Parse(string(data), DefaultCurrency)
+		if err != nil {
+			return err
+		}
+
+		*m = parsed
+		return nil
+	}
+}
+
+// Amounts in YAML files are written in major units of the default currency, e.g. fee_flat: 4000
+func (m *Money) UnmarshalYAML(unmarshal func(any) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	parsed, err := Parse(value, DefaultCurrency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Add up amounts of the same currency
+func Sum(currency string, amounts ...Money) Money {
+	total := Zero(currency)
+	for _, amount := range amounts {
+		total = total.Add(amount)
+	}
+
+	return total
+}
@@ -0,0 +1,240 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Should parse whole and fractional amounts exactly", func(t *testing.T) {
+		cases := map[string]int64{
+			"150000":     15000000,
+			"150000.5":   15000050,
+			"150000.50":  15000050,
+			"0.01":       1,
+			"-2500.25":   -250025,
+			"99.900":     9990,
+			" 1000.00 ":  100000,
+			"1500000.00": 150000000,
+		}
+
+		for value, expected := range cases {
+			result, err := Parse(value, IDR)
+			if err != nil {
+				t.Fatalf("Expected %v, got %v", nil, err)
+			}
+
+			if result.Amount != expected || result.Currency != IDR {
+				t.Errorf("Expected %v, got %v", expected, result)
+			}
+		}
+	})
+
+	t.Run("Should reject amounts that cannot be stored exactly", func(t *testing.T) {
+		if _, err := Parse("10.005", IDR); !errors.Is(err, ErrTooPrecise) {
+			t.Errorf("Expected %v, got %v", ErrTooPrecise, err)
+		}
+
+		if _, err := Parse("100.5", JPY); !errors.Is(err, ErrTooPrecise) {
+			t.Errorf("Expected %v, got %v", ErrTooPrecise, err)
+		}
+
+		for _, value := range []string{"", "abc", "1,000", "1.", ".5", "1e5", "99999999999999999999"} {
+			if _, err := Parse(value, IDR); !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("Expected %v for %q, got %v", ErrInvalidAmount, value, err)
+			}
+		}
+	})
+}
+
+func TestFromFloat(t *testing.T) {
+	t.Run("Should round half away from zero to the minor unit", func(t *testing.T) {
+		if result := FromFloat(0.125, IDR); result.Amount != 13 {
+			t.Errorf("Expected %v, got %v", 13, result.Amount)
+		}
+
+		if result := FromFloat(-0.125, IDR); result.Amount != -13 {
+			t.Errorf("Expected %v, got %v", -13, result.Amount)
+		}
+
+		if result := FromFloat(150000, ""); result.Amount != 15000000 || result.Currency != IDR {
+			t.Errorf("Expected IDR 150000.00, got %s", result)
+		}
+	})
+}
+
+func TestArithmetic(t *testing.T) {
+	t.Run("Should take percentages rounded half away from zero", func(t *testing.T) {
+		price := FromMajor(111000, IDR)
+
+		if result := price.Percent(0.7); result.Amount != 77700 {
+			t.Errorf("Expected %v, got %v", 77700, result.Amount)
+		}
+
+		if result := New(5, IDR).Percent(10); result.Amount != 1 {
+			t.Errorf("Expected %v, got %v", 1, result.Amount)
+		}
+
+		if result := New(-5, IDR).Percent(10); result.Amount != -1 {
+			t.Errorf("Expected %v, got %v", -1, result.Amount)
+		}
+	})
+
+	t.Run("Should multiply by ratios without losing precision", func(t *testing.T) {
+		price := FromMajor(100000, IDR)
+
+		if result := price.MulRatio(10, 30); result.Amount != 3333333 {
+			t.Errorf("Expected %v, got %v", 3333333, result.Amount)
+		}
+
+		if result := price.MulRatio(20, 30); result.Amount != 6666667 {
+			t.Errorf("Expected %v, got %v", 6666667, result.Amount)
+		}
+	})
+
+	t.Run("Should round to whole major units", func(t *testing.T) {
+		if result := New(1234550, IDR).RoundToMajor(); result.Amount != 1234600 {
+			t.Errorf("Expected %v, got %v", 1234600, result.Amount)
+		}
+
+		if result := New(-1234550, IDR).Major(); result != -12346 {
+			t.Errorf("Expected %v, got %v", -12346, result)
+		}
+
+		if result := New(1234549, IDR).Major(); result != 12345 {
+			t.Errorf("Expected %v, got %v", 12345, result)
+		}
+	})
+
+	t.Run("Should treat the zero value as the default currency", func(t *testing.T) {
+		total := Money{}.Add(FromMajor(1000, IDR))
+
+		if !total.Equal(FromMajor(1000, IDR)) {
+			t.Errorf("Expected IDR 1000.00, got %s", total)
+		}
+	})
+
+	t.Run("Should refuse to mix currencies", func(t *testing.T) {
+		defer func() {
+			if recovered := recover(); recovered == nil {
+				t.Error("Expected a panic, got nil")
+			}
+		}()
+
+		FromMajor(1, IDR).Add(FromMajor(1, USD))
+	})
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("Should format rupiah with dots and hide a zero fraction", func(t *testing.T) {
+		cases := map[Money]string{
+			FromMajor(1500000, IDR): "Rp 1.500.000",
+			FromMajor(999, IDR):     "Rp 999",
+			New(110996, IDR):        "Rp 1.109,96",
+			New(-150000000, IDR):    "-Rp 1.500.000",
+			{}:                      "Rp 0",
+		}
+
+		for amount, expected := range cases {
+			if result := amount.Format(); result != expected {
+				t.Errorf("Expected %v, got %v", expected, result)
+			}
+		}
+	})
+
+	t.Run("Should format other currencies with their fraction", func(t *testing.T) {
+		if result := New(150050, USD).Format(); result != "$1,500.50" {
+			t.Errorf("Expected $1,500.50, got %s", result)
+		}
+
+		if result := New(150000, SGD).Format(); result != "SGD 1,500.00" {
+			t.Errorf("Expected SGD 1,500.00, got %s", result)
+		}
+
+		if result := New(1500, JPY).Format(); result != "JPY 1,500" {
+			t.Errorf("Expected JPY 1,500, got %s", result)
+		}
+	})
+
+	t.Run("Should write exact decimals", func(t *testing.T) {
+		cases := map[Money]string{
+			FromMajor(150000, IDR): "150000.00",
+			New(5, IDR):            "0.05",
+			New(-5, IDR):           "-0.05",
+			New(1500, JPY):         "1500",
+		}
+
+		for amount, expected := range cases {
+			if result := amount.Decimal(); result != expected {
+				t.Errorf("Expected %v, got %v", expected, result)
+			}
+		}
+	})
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("Should marshal the minor units with the currency", func(t *testing.T) {
+		data, err := json.Marshal(FromMajor(150000, IDR))
+		if err != nil {
+			t.Fatalf("Expected %v, got %v", nil, err)
+		}
+
+		expected := `{"amount":15000000,"currency":"IDR","formatted":"Rp 150.000"}`
+		if string(data) != expected {
+			t.Errorf("Expected %v, got %v", expected, string(data))
+		}
+	})
+
+	t.Run("Should unmarshal objects, numbers and decimal strings", func(t *testing.T) {
+		cases := map[string]Money{
+			`{"amount":15000000,"currency":"IDR"}`: FromMajor(150000, IDR),
+			`{"amount":150050,"currency":"usd"}`:   New(150050, USD),
+			`150000`:                               FromMajor(150000, IDR),
+			`"150000.50"`:                          New(15000050, IDR),
+		}
+
+		for data, expected := range cases {
+			var result Money
+			if err := json.Unmarshal([]byte(data), &result); err != nil {
+				t.Fatalf("Expected %v, got %v", nil, err)
+			}
+
+			if !result.Equal(expected) {
+				t.Errorf("Expected %v, got %v", expected, result)
+			}
+		}
+	})
+
+	t.Run("Should reject fractional minor units and imprecise numbers", func(t *testing.T) {
+		for _, data := range []string{`{"amount":1.5,"currency":"IDR"}`, `{"currency":"IDR"}`, `10.005`} {
+			var result Money
+			if err := json.Unmarshal([]byte(data), &result); err == nil {
+				t.Errorf("Expected %v for %s, got %v", "an error", data, result)
+			}
+		}
+	})
+}
+
+func TestYAML(t *testing.T) {
+	t.Run("Should read amounts as major units", func(t *testing.T) {
+		var result struct {
+			FeeFlat   Money `yaml:"fee_flat"`
+			MinAmount Money `yaml:"min_amount"`
+		}
+
+		if err := yaml.Unmarshal([]byte("fee_flat: 4000\nmin_amount: 10000.50\n"), &result); err != nil {
+			t.Fatalf("Expected %v, got %v", nil, err)
+		}
+
+		if result.FeeFlat.Amount != 400000 {
+			t.Errorf("Expected 400000, got %d", result.FeeFlat.Amount)
+		}
+
+		if result.MinAmount.Amount != 1000050 {
+			t.Errorf("Expected 1000050, got %d", result.MinAmount.Amount)
+		}
+	})
+}
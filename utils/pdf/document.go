@@ -0,0 +1,145 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Font string
+
+const (
+	FontRegular Font = "F1"
+	FontBold    Font = "F2"
+)
+
+// A minimal PDF document made of text and lines
+// Only the standard Helvetica fonts are used so nothing has to be embedded,
+// coordinates start at the top left corner of the page and are expressed in points
+type Document struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+}
+
+func NewDocument() *Document {
+	document := &Document{}
+	document.AddPage()
+
+	return document
+}
+
+// Start a new page, every following drawing goes to this page
+func (d *Document) AddPage() {
+	d.current = new(bytes.Buffer)
+	d.pages = append(d.pages, d.current)
+}
+
+// Write text with its baseline starting at x, y
+func (d *Document) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(d.current, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, PageHeight-y, escape(text))
+}
+
+// Write text with its baseline ending at x, y
+func (d *Document) TextRight(x, y float64, font Font, size float64, text string) {
+	d.Text(x-TextWidth(font, size, text), y, font, size, text)
+}
+
+// Draw a straight line between two points
+func (d *Document) Line(x1, y1, x2, y2 float64, width float64) {
+	fmt.Fprintf(d.current, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Render the document
+// It returns the bytes of the PDF file
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	offsets := make([]int, 0)
+
+	writeObject := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objects 1 to 4 are the catalog, the page tree and both fonts, each page then takes two objects
+	pageIDs := make([]string, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageIDs, " "), len(d.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range d.pages {
+		writeObject(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, 6+i*2,
+		))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xrefOffset := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return out.Bytes()
+}
+
+// Estimate the width of a text in points
+// Widths follow the Helvetica metrics closely enough to right align amounts
+func TextWidth(font Font, size float64, text string) float64 {
+	var units float64
+
+	for _, r := range text {
+		switch {
+		case r == ' ' || r == '.' || r == ',' || r == ':' || r == 'i' || r == 'l' || r == 'I' || r == '/':
+			units += 278
+		case r == 'm' || r == 'M' || r == 'W' || r == 'w':
+			units += 833
+		case r >= 'A' && r <= 'Z':
+			units += 667
+		default:
+			units += 556
+		}
+	}
+
+	if font == FontBold {
+		units *= 1.05
+	}
+
+	return units * size / 1000
+}
+
+// Escape a text for a PDF string
+// Characters outside of Latin-1 cannot be shown by the standard fonts and are replaced
+func escape(text string) string {
+	var builder strings.Builder
+
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			builder.WriteRune('\\')
+			builder.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			builder.WriteRune(' ')
+		case r < 32 || r > 255:
+			builder.WriteRune('?')
+		default:
+			builder.WriteByte(byte(r))
+		}
+	}
+
+	return builder.String()
+}
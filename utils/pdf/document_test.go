@@ -0,0 +1,63 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDocumentBytes(t *testing.T) {
+	document := NewDocument()
+	document.Text(40, 60, FontBold, 18, "Invoice (INV-1)")
+	document.Line(40, 70, 555, 70, 1)
+	document.AddPage()
+	document.TextRight(555, 60, FontRegular, 10, "Rp 11.100")
+
+	output := document.Bytes()
+
+	t.Run("Should write a complete PDF file", func(t *testing.T) {
+		if !bytes.HasPrefix(output, []byte("%PDF-1.4\n")) {
+			t.Error("Expected the PDF header")
+		}
+
+		if !bytes.HasSuffix(output, []byte("%%EOF\n")) {
+			t.Error("Expected the PDF trailer")
+		}
+
+		if !bytes.Contains(output, []byte("/Count 2")) {
+			t.Error("Expected two pages")
+		}
+	})
+
+	t.Run("Should escape parentheses in text", func(t *testing.T) {
+		if !bytes.Contains(output, []byte(`(Invoice \(INV-1\)) Tj`)) {
+			t.Error("Expected escaped text")
+		}
+	})
+
+	t.Run("Should point the cross reference table at every object", func(t *testing.T) {
+		entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(output, -1)
+		if len(entries) != 8 {
+			t.Fatalf("Expected %d, got %d", 8, len(entries))
+		}
+
+		for i, entry := range entries {
+			offset, _ := strconv.Atoi(string(entry[1]))
+			expected := fmt.Sprintf("%d 0 obj", i+1)
+
+			if !bytes.HasPrefix(output[offset:], []byte(expected)) {
+				t.Errorf("Expected %s at offset %d", expected, offset)
+			}
+		}
+	})
+}
+
+func TestEscape(t *testing.T) {
+	t.Run("Should replace characters the standard fonts cannot show", func(t *testing.T) {
+		if result := escape("Café ✓"); result != "Caf\xe9 ?" {
+			t.Errorf("Expected %q, got %q", "Caf\xe9 ?", result)
+		}
+	})
+}
@@ -96,6 +96,7 @@ func (qs *QueueService) RegisterHandlerFunc(taskName string, handler func(contex
 
 func (qs *QueueService) RegisterEmailHandlers() {
 	qs.RegisterHandlerFunc("email:send_activation", qs.handleSendActivationEmail)
+	qs.RegisterHandlerFunc("email:send_password_reset", qs.handleSendPasswordResetEmail)
 }
 
 func (qs *QueueService) Start() error {
@@ -224,3 +225,60 @@ func (qs *QueueService) handleSendActivationEmail(ctx context.Context, task *asy
 	log.Printf("Successfully sent activation email to %s", email)
 	return nil
 }
+
+// handleSendPasswordResetEmail handles sending password reset emails
+func (qs *QueueService) handleSendPasswordResetEmail(ctx context.Context, task *asynq.Task) error {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal password reset email payload: %w", err)
+	}
+
+	email, ok := payload["email"].(string)
+	if !ok {
+		return fmt.Errorf("invalid email in payload")
+	}
+
+	resetLink, ok := payload["reset_link"].(string)
+	if !ok {
+		return fmt.Errorf("invalid reset_link in payload")
+	}
+
+	supportEmail, ok := payload["support_email"].(string)
+	if !ok {
+		return fmt.Errorf("invalid support_email in payload")
+	}
+
+	userName, _ := payload["user_name"].(string)
+	expiresInMinutes, _ := payload["expires_in_minutes"].(float64)
+
+	mailerService, err := mailer.NewMailerService()
+	if err != nil {
+		return fmt.Errorf("failed to initialize mailer service: %w", err)
+	}
+
+	if !mailerService.TemplateExists("password-reset.html") {
+		return fmt.Errorf("email template not found: password-reset.html")
+	}
+
+	templateData := map[string]interface{}{
+		"ResetLink":        resetLink,
+		"SupportEmail":     supportEmail,
+		"UserName":         userName,
+		"ExpiresInMinutes": int(expiresInMinutes),
+	}
+
+	err = mailerService.SendTemplate(
+		email,
+		"Catalyst - Reset Your Password",
+		"password-reset.html",
+		templateData,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to send password reset email to %s: %w", email, err)
+	}
+
+	// The reset link is a credential, it is never written to the logs
+	log.Printf("Successfully sent password reset email to %s", email)
+	return nil
+}
@@ -57,6 +57,8 @@ func GetContentType(filename string) string {
 		return "image/gif"
 	case ".webp":
 		return "image/webp"
+	case ".pdf":
+		return "application/pdf"
 	default:
 		return "application/octet-stream"
 	}
@@ -86,6 +88,22 @@ func UploadFileToStorage(file *multipart.FileHeader, storagePath, prefix string,
 	return uploadedPath, nil
 }
 
+// Upload generated content such as documents to the storage
+// The filename is encrypted the same way as uploaded files
+// It returns the path of the stored file
+func UploadBytesToStorage(data []byte, originalFilename, prefix string, folder *string) (string, error) {
+	encryptedFilename := GenerateEncryptedFilename(originalFilename, prefix)
+
+	ctx := context.Background()
+	uploader := NewUploadService()
+	uploadedPath, err := uploader.UploadBytes(ctx, data, encryptedFilename, GetContentType(originalFilename), folder)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return uploadedPath, nil
+}
+
 func GetFileFromStorage(path string) (string, error) {
 	ctx := context.Background()
 	uploader := NewUploadService()
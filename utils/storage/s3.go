@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"mime/multipart"
@@ -109,6 +110,30 @@ func (s *UploadService) UploadFile(ctx context.Context, file *multipart.FileHead
 	return key, nil
 }
 
+// Upload generated content that does not come from a multipart form
+// It returns the key of the stored object
+func (s *UploadService) UploadBytes(ctx context.Context, data []byte, filename string, contentType string, folder *string) (string, error) {
+	var key string
+	if folder != nil && *folder != "" {
+		key = fmt.Sprintf("%s/%s", *folder, filename)
+	} else {
+		key = filename
+	}
+
+	_, err := s.storage.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
 func (s *UploadService) GetFileURL(ctx context.Context, path string) (string, error) {
 	presignClient := s3.NewPresignClient(s.storage)
 	input := &s3.GetObjectInput{